@@ -0,0 +1,159 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/cert-manager/release/pkg/gcp"
+	"github.com/cert-manager/release/pkg/release"
+)
+
+const (
+	artifactsCommand         = "artifacts"
+	artifactsDescription     = "Inspect and download the artifacts of a staged release"
+	artifactsLongDescription = `
+The 'artifacts' command contains subcommands for inspecting the artifacts of a
+staged release without publishing it, which is useful for ad hoc QA of a staged
+build.
+
+Artifacts can be selected by their kind (such as 'server', 'manifests' or
+'cmctl'), OS and architecture.
+`
+)
+
+// artifactsOptions holds the options shared by the artifacts subcommands for
+// choosing a staged release and selecting artifacts within it.
+type artifactsOptions struct {
+	// The name of the GCS bucket containing the staged release
+	Bucket string
+
+	// ReleaseName is the name of the staged release to inspect.
+	ReleaseName string
+
+	// The type of the staged release - usually one of 'release' or 'devel'
+	ReleaseType string
+
+	// MetadataFileName is the name of the file containing the release
+	// metadata in the root of the staged release.
+	MetadataFileName string
+
+	// Kinds, if set, selects only artifacts of the given kinds.
+	Kinds []string
+
+	// OSes, if set, selects only artifacts built for the given OSes.
+	// Architecture independent artifacts are never selected by OS.
+	OSes []string
+
+	// Arches, if set, selects only artifacts built for the given
+	// architectures. Architecture independent artifacts are never selected
+	// by architecture.
+	Arches []string
+}
+
+func (o *artifactsOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
+	fs.StringVar(&o.Bucket, "bucket", release.DefaultBucketName, "The name of the GCS bucket containing the staged release.")
+	fs.StringVar(&o.ReleaseName, "release-name", "", "Name of the staged release to inspect.")
+	fs.StringVar(&o.ReleaseType, "release-type", release.BuildTypeRelease, "The type of the staged release, usually one of 'release' or 'devel'")
+	fs.StringVar(&o.MetadataFileName, "metadata-file-name", release.MetadataFileName, "The name of the file containing the release metadata in the root of the staged release.")
+	fs.StringSliceVar(&o.Kinds, "kind", nil, "Comma-separated list of artifact kinds to select, e.g. 'server,manifests'. If empty, artifacts of every kind are selected.")
+	fs.StringSliceVar(&o.OSes, "os", nil, "Comma-separated list of OSes to select artifacts for. If empty, artifacts for every OS are selected.")
+	fs.StringSliceVar(&o.Arches, "arch", nil, "Comma-separated list of architectures to select artifacts for. If empty, artifacts for every architecture are selected.")
+	markRequired("release-name")
+}
+
+// attrs returns the options as attributes to be logged by the commands
+// which embed them.
+func (o *artifactsOptions) attrs() []any {
+	return []any{
+		"Bucket", o.Bucket,
+		"ReleaseName", o.ReleaseName,
+		"ReleaseType", o.ReleaseType,
+		"MetadataFileName", o.MetadataFileName,
+		"Kinds", o.Kinds,
+		"OSes", o.OSes,
+		"Arches", o.Arches,
+	}
+}
+
+func artifactsCmd(rootOpts *rootOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   artifactsCommand,
+		Short: artifactsDescription,
+		Long:  artifactsLongDescription,
+	}
+
+	cmd.AddCommand(artifactsListCmd(rootOpts))
+	cmd.AddCommand(artifactsDownloadCmd(rootOpts))
+
+	return cmd
+}
+
+// stagedRelease fetches the staged release described by o.
+func (o *artifactsOptions) stagedRelease(ctx context.Context) (*release.Staged, error) {
+	gcs, err := gcp.NewStorageClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCS client: %w", err)
+	}
+
+	bucket := release.NewBucket(gcs.Bucket(o.Bucket), release.DefaultBucketPathPrefix, o.ReleaseType).WithMetadataFileName(o.MetadataFileName)
+	if err := bucket.CheckAccess(ctx); err != nil {
+		return nil, err
+	}
+
+	staged, err := bucket.GetRelease(ctx, o.ReleaseName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch release: %w", err)
+	}
+
+	return staged, nil
+}
+
+// selectedArtifacts fetches the staged release described by o and returns
+// the artifacts in it selected by o.
+func (o *artifactsOptions) selectedArtifacts(ctx context.Context) ([]release.StagedArtifact, error) {
+	staged, err := o.stagedRelease(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return selectArtifacts(staged.Artifacts(), o.Kinds, o.OSes, o.Arches), nil
+}
+
+// selectArtifacts returns the artifacts which match one of each of the given
+// kinds, OSes and architectures. An empty list matches every artifact.
+// Architecture independent artifacts, which have no OS or architecture, only
+// match if no OSes or architectures are given.
+func selectArtifacts(artifacts []release.StagedArtifact, kinds, oses, arches []string) []release.StagedArtifact {
+	matches := func(allowed []string, value string) bool {
+		return len(allowed) == 0 || sets.NewString(allowed...).Has(value)
+	}
+
+	var selected []release.StagedArtifact
+	for _, a := range artifacts {
+		if matches(kinds, a.Metadata.Kind()) && matches(oses, a.Metadata.OS) && matches(arches, a.Metadata.Architecture) {
+			selected = append(selected, a)
+		}
+	}
+
+	return selected
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+
+	"github.com/cert-manager/release/pkg/release"
+)
+
+const (
+	artifactsDownloadCommand         = "download"
+	artifactsDownloadDescription     = "Download selected artifacts of a staged release"
+	artifactsDownloadLongDescription = `
+The 'artifacts download' command downloads the selected artifacts of a staged
+release into a local directory, verifying each against the checksum recorded in
+the release metadata. Artifacts are not extracted.
+`
+)
+
+var (
+	artifactsDownloadExample = fmt.Sprintf(`
+To download the manifests of a staged release into the directory 'out':
+
+	%s %s %s --release-name v1.3.1-614438aed00e1060870b273f2238794ef69b60ab --kind manifests --output out
+`, rootCommand, artifactsCommand, artifactsDownloadCommand)
+)
+
+type artifactsDownloadOptions struct {
+	artifactsOptions
+
+	// Output is the directory the selected artifacts are downloaded into. It
+	// is created if it doesn't exist.
+	Output string
+}
+
+func (o *artifactsDownloadOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
+	o.artifactsOptions.AddFlags(fs, markRequired)
+	fs.StringVarP(&o.Output, "output", "o", ".", "The directory to download the selected artifacts into. It is created if it doesn't exist.")
+}
+
+func (o *artifactsDownloadOptions) print() {
+	slog.Info("Artifacts download options", append(o.artifactsOptions.attrs(),
+		"Output", o.Output,
+	)...)
+}
+
+func artifactsDownloadCmd(rootOpts *rootOptions) *cobra.Command {
+	o := &artifactsDownloadOptions{}
+	cmd := &cobra.Command{
+		Use:          artifactsDownloadCommand,
+		Short:        artifactsDownloadDescription,
+		Long:         artifactsDownloadLongDescription,
+		Example:      artifactsDownloadExample,
+		SilenceUsage: true,
+		PreRun: func(_ *cobra.Command, _ []string) {
+			o.print()
+			slog.Info("---")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runArtifactsDownload(rootOpts, o)
+		},
+	}
+	o.AddFlags(cmd.Flags(), mustMarkRequired(cmd.MarkFlagRequired))
+	return cmd
+}
+
+func runArtifactsDownload(_ *rootOptions, o *artifactsDownloadOptions) error {
+	ctx := context.Background()
+
+	artifacts, err := o.selectedArtifacts(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(artifacts) == 0 {
+		return fmt.Errorf("no artifacts in staged release %q match the given --kind, --os and --arch", o.ReleaseName)
+	}
+
+	if err := os.MkdirAll(o.Output, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	for _, a := range artifacts {
+		path := filepath.Join(o.Output, a.Metadata.Name)
+
+		slog.Info("Downloading artifact", "artifact", a.Metadata.Name, "path", path)
+		if err := release.DownloadArtifact(ctx, &a, path); err != nil {
+			return fmt.Errorf("failed to download artifact %q: %w", a.Metadata.Name, err)
+		}
+	}
+
+	slog.Info("Downloaded artifacts", "count", len(artifacts), "output", o.Output)
+
+	return nil
+}
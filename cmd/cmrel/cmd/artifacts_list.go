@@ -0,0 +1,186 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+
+	"github.com/cert-manager/release/pkg/release"
+)
+
+const (
+	artifactsListCommand     = "list"
+	artifactsListDescription = "List the artifacts of a staged release"
+)
+
+var (
+	artifactsListExample = fmt.Sprintf(`
+To list every artifact of a staged release:
+
+	%s %s %s --release-name v1.3.1-614438aed00e1060870b273f2238794ef69b60ab
+
+To list only the linux server artifacts, as JSON:
+
+	%s %s %s --release-name v1.3.1-614438aed00e1060870b273f2238794ef69b60ab --kind server --os linux --output json
+`, rootCommand, artifactsCommand, artifactsListCommand, rootCommand, artifactsCommand, artifactsListCommand)
+)
+
+type artifactsListOptions struct {
+	artifactsOptions
+
+	// Output is the format used to print the list of artifacts, one of
+	// 'table', 'yaml' or 'json'.
+	Output string
+}
+
+func (o *artifactsListOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
+	o.artifactsOptions.AddFlags(fs, markRequired)
+	fs.StringVarP(&o.Output, "output", "o", stagedOutputTable, fmt.Sprintf("Output format, one of: %s, %s, %s. Table output is written to stderr along with other logs, all other formats are written to stdout.", stagedOutputTable, stagedOutputYAML, stagedOutputJSON))
+}
+
+func (o *artifactsListOptions) print() {
+	slog.Info("Artifacts list options", append(o.artifactsOptions.attrs(),
+		"Output", o.Output,
+	)...)
+}
+
+func artifactsListCmd(rootOpts *rootOptions) *cobra.Command {
+	o := &artifactsListOptions{}
+	cmd := &cobra.Command{
+		Use:          artifactsListCommand,
+		Short:        artifactsListDescription,
+		Example:      artifactsListExample,
+		SilenceUsage: true,
+		PreRun: func(_ *cobra.Command, _ []string) {
+			o.print()
+			slog.Info("---")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runArtifactsList(rootOpts, o)
+		},
+	}
+	o.AddFlags(cmd.Flags(), mustMarkRequired(cmd.MarkFlagRequired))
+	return cmd
+}
+
+func runArtifactsList(_ *rootOptions, o *artifactsListOptions) error {
+	if o.Output != stagedOutputTable && o.Output != stagedOutputYAML && o.Output != stagedOutputJSON {
+		return fmt.Errorf("unknown output format %q", o.Output)
+	}
+
+	artifacts, err := o.selectedArtifacts(context.Background())
+	if err != nil {
+		return err
+	}
+
+	summaries := []artifactSummary{}
+	for _, a := range artifacts {
+		summaries = append(summaries, summarizeArtifact(a.Metadata))
+	}
+
+	switch o.Output {
+	case stagedOutputTable:
+		logTable(artifactsTable(summaries)...)
+
+		return nil
+
+	case stagedOutputYAML:
+		return writeArtifactsYAML(os.Stdout, summaries)
+
+	case stagedOutputJSON:
+		return writeArtifactsJSON(os.Stdout, summaries)
+
+	default:
+		return fmt.Errorf("unknown output format %q", o.Output)
+	}
+}
+
+// artifactSummary is the structured form of a staged artifact printed by the
+// artifacts list command.
+type artifactSummary struct {
+	Name         string `json:"name"`
+	Kind         string `json:"kind"`
+	OS           string `json:"os,omitempty"`
+	Architecture string `json:"architecture,omitempty"`
+	Size         int64  `json:"size,omitempty"`
+	SHA256       string `json:"sha256"`
+}
+
+func summarizeArtifact(meta release.ArtifactMetadata) artifactSummary {
+	return artifactSummary{
+		Name:         meta.Name,
+		Kind:         meta.Kind(),
+		OS:           meta.OS,
+		Architecture: meta.Architecture,
+		Size:         meta.Size,
+		SHA256:       meta.SHA256,
+	}
+}
+
+// artifactsTable returns the tab-separated lines of the table of artifacts
+// printed by the artifacts list command, including a header.
+func artifactsTable(summaries []artifactSummary) []string {
+	lines := []string{"NAME\tKIND\tOS\tARCH\tSIZE\tSHA256"}
+	for _, s := range summaries {
+		size := ""
+		if s.Size > 0 {
+			size = fmt.Sprint(s.Size)
+		}
+
+		lines = append(lines, fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s", s.Name, s.Kind, s.OS, s.Architecture, size, s.SHA256))
+	}
+
+	return lines
+}
+
+func writeArtifactsYAML(w io.Writer, summaries []artifactSummary) error {
+	// a nil slice would be written as 'null' rather than an empty list
+	if summaries == nil {
+		summaries = []artifactSummary{}
+	}
+
+	out, err := yaml.Marshal(summaries)
+	if err != nil {
+		return fmt.Errorf("failed to encode artifacts as YAML: %w", err)
+	}
+
+	_, err = w.Write(out)
+	return err
+}
+
+func writeArtifactsJSON(w io.Writer, summaries []artifactSummary) error {
+	if summaries == nil {
+		summaries = []artifactSummary{}
+	}
+
+	out, err := json.MarshalIndent(summaries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode artifacts as JSON: %w", err)
+	}
+
+	_, err = fmt.Fprintln(w, string(out))
+	return err
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cert-manager/release/pkg/release"
+)
+
+func TestSelectArtifacts(t *testing.T) {
+	artifact := func(name, os, arch string) release.StagedArtifact {
+		return release.StagedArtifact{Metadata: release.ArtifactMetadata{Name: name, OS: os, Architecture: arch}}
+	}
+
+	artifacts := []release.StagedArtifact{
+		artifact("cert-manager-manifests.tar.gz", "", ""),
+		artifact("cert-manager-server-linux-amd64.tar.gz", "linux", "amd64"),
+		artifact("cert-manager-server-linux-arm64.tar.gz", "linux", "arm64"),
+		artifact("cert-manager-cmctl-darwin-arm64.tar.gz", "darwin", "arm64"),
+		artifact("cert-manager-cmctl-windows-amd64.zip", "windows", "amd64"),
+	}
+
+	tests := map[string]struct {
+		kinds, oses, arches []string
+		expected            []string
+	}{
+		"no filters selects everything": {
+			expected: []string{
+				"cert-manager-manifests.tar.gz",
+				"cert-manager-server-linux-amd64.tar.gz",
+				"cert-manager-server-linux-arm64.tar.gz",
+				"cert-manager-cmctl-darwin-arm64.tar.gz",
+				"cert-manager-cmctl-windows-amd64.zip",
+			},
+		},
+		"by kind": {
+			kinds:    []string{"manifests"},
+			expected: []string{"cert-manager-manifests.tar.gz"},
+		},
+		"by several kinds": {
+			kinds:    []string{"manifests", "cmctl"},
+			expected: []string{"cert-manager-manifests.tar.gz", "cert-manager-cmctl-darwin-arm64.tar.gz", "cert-manager-cmctl-windows-amd64.zip"},
+		},
+		"by architecture excludes architecture independent artifacts": {
+			arches:   []string{"arm64"},
+			expected: []string{"cert-manager-server-linux-arm64.tar.gz", "cert-manager-cmctl-darwin-arm64.tar.gz"},
+		},
+		"by kind, os and architecture": {
+			kinds:    []string{"server"},
+			oses:     []string{"linux"},
+			arches:   []string{"amd64"},
+			expected: []string{"cert-manager-server-linux-amd64.tar.gz"},
+		},
+		"nothing matches": {
+			kinds: []string{"test"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var names []string
+			for _, a := range selectArtifacts(artifacts, test.kinds, test.oses, test.arches) {
+				names = append(names, a.Metadata.Name)
+			}
+
+			if !reflect.DeepEqual(names, test.expected) {
+				t.Errorf("expected %q but got %q", test.expected, names)
+			}
+		})
+	}
+}
+
+func TestArtifactsTable(t *testing.T) {
+	summaries := []artifactSummary{
+		summarizeArtifact(release.ArtifactMetadata{Name: "cert-manager-manifests.tar.gz", SHA256: "abc", Size: 123}),
+		summarizeArtifact(release.ArtifactMetadata{Name: "cert-manager-server-linux-amd64.tar.gz", SHA256: "def", OS: "linux", Architecture: "amd64"}),
+	}
+
+	expected := []string{
+		"NAME\tKIND\tOS\tARCH\tSIZE\tSHA256",
+		"cert-manager-manifests.tar.gz\tmanifests\t\t\t123\tabc",
+		"cert-manager-server-linux-amd64.tar.gz\tserver\tlinux\tamd64\t\tdef",
+	}
+
+	if lines := artifactsTable(summaries); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("unexpected table:\n%q\nexpected:\n%q", lines, expected)
+	}
+}
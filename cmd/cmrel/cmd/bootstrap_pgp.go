@@ -19,13 +19,13 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/spf13/cobra"
 	flag "github.com/spf13/pflag"
-	"google.golang.org/api/cloudbuild/v1"
 
 	"github.com/cert-manager/release/pkg/gcb"
+	"github.com/cert-manager/release/pkg/gcp"
 	"github.com/cert-manager/release/pkg/release"
 )
 
@@ -56,7 +56,8 @@ type bootstrapPGPOptions struct {
 	// projects/<PROJECT_NAME>/locations/<LOCATION>/keyRings/<KEYRING_NAME>/cryptoKeys/<KEY_NAME>/cryptoKeyVersions/<KEY_VERSION>
 	Key string
 
-	// The path to the cloudbuild.yaml file to be invoked
+	// CloudBuildFile, if set, is the path to a cloudbuild.yaml file to use
+	// instead of the one built into cmrel
 	CloudBuildFile string
 
 	// Project names the GCP project in which the GCB job will be run
@@ -65,16 +66,17 @@ type bootstrapPGPOptions struct {
 
 func (o *bootstrapPGPOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
 	fs.StringVar(&o.Key, "key", "", "Full name of the GCP KMS key to use for bootstrapping")
-	fs.StringVar(&o.CloudBuildFile, "cloudbuild", "./gcb/bootstrap-pgp/cloudbuild.yaml", "The path to the cloudbuild.yaml file to be invoked.")
+	fs.StringVar(&o.CloudBuildFile, "cloudbuild", "", "Optional path to a cloudbuild.yaml file to use instead of the one built into cmrel. Only intended for testing changes to the build during development.")
 	fs.StringVar(&o.Project, "project", release.DefaultReleaseProject, "GCP project in which to run the GCB build job.")
 	markRequired("key")
 }
 
 func (o *bootstrapPGPOptions) print() {
-	log.Printf("bootstrap-pgp options:")
-	log.Printf("             Key: %q", o.Key)
-	log.Printf("         Project: %q", o.Project)
-	log.Printf("  CloudBuildFile: %q", o.CloudBuildFile)
+	slog.Info("bootstrap-pgp options",
+		"Key", o.Key,
+		"Project", o.Project,
+		"CloudBuildFile", o.CloudBuildFile,
+	)
 }
 
 func bootstrapPGPCmd(rootOpts *rootOptions) *cobra.Command {
@@ -87,7 +89,7 @@ func bootstrapPGPCmd(rootOpts *rootOptions) *cobra.Command {
 		SilenceUsage: true,
 		PreRun: func(cmd *cobra.Command, args []string) {
 			o.print()
-			log.Printf("---")
+			slog.Info("---")
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runBootstrapPGP(rootOpts, o)
@@ -100,45 +102,41 @@ func bootstrapPGPCmd(rootOpts *rootOptions) *cobra.Command {
 func runBootstrapPGP(rootOpts *rootOptions, o *bootstrapPGPOptions) error {
 	ctx := context.Background()
 
-	log.Printf("Bootstrapping PGP identity from %s", o.Key)
+	slog.Info("Bootstrapping PGP identity", "key", o.Key)
 
-	log.Printf("DEBUG: Loading cloudbuild.yaml file from %q", o.CloudBuildFile)
-
-	build, err := gcb.LoadBuild(o.CloudBuildFile)
+	build, err := gcb.LoadTemplate(gcb.TemplateBootstrapPGP, o.CloudBuildFile)
 	if err != nil {
-		return fmt.Errorf("error loading %q: %w", o.CloudBuildFile, err)
+		return fmt.Errorf("error loading cloudbuild.yaml file: %w", err)
 	}
 
 	build.Substitutions["_KMS_KEY"] = o.Key
 
-	log.Printf("DEBUG: building google cloud build API client")
+	slog.Debug("building google cloud build API client")
 
-	svc, err := cloudbuild.NewService(ctx)
+	svc, err := gcp.NewCloudBuildService(ctx)
 	if err != nil {
 		return fmt.Errorf("error building google cloud build API client: %w", err)
 	}
 
-	log.Printf("Submitting GCB build job...")
+	slog.Info("Submitting GCB build job...")
 	build, err = gcb.SubmitBuild(svc, o.Project, build)
 	if err != nil {
 		return fmt.Errorf("error submitting build to cloud build: %w", err)
 	}
 
-	log.Println("---")
-	log.Printf("Submitted build with name: %q", build.Id)
-	log.Printf("  View logs at: %s", build.LogUrl)
-	log.Printf("  Log bucket: %s", build.LogsBucket)
-	log.Println("---")
-	log.Printf("Waiting for build to complete...")
-	build, err = gcb.WaitForBuild(svc, o.Project, build.Id)
+	slog.Info("---")
+	slog.Info("Submitted build", "build_id", build.Id, "log_url", build.LogUrl, "logs_bucket", build.LogsBucket)
+	slog.Info("---")
+	slog.Info("Waiting for build to complete...")
+	build, err = gcb.WaitForBuild(svc, o.Project, build.Id, 0)
 	if err != nil {
 		return fmt.Errorf("error waiting for cloud build to complete: %w", err)
 	}
 
 	if build.Status == gcb.Success {
-		log.Printf("Completed cloud build job; check log stdout for keys: %s", build.LogUrl)
+		slog.Info("Completed cloud build job; check log stdout for keys", "log_url", build.LogUrl)
 	} else {
-		log.Printf("An error occurred bootstrapping the PGP identity. Check the log files for more information: %s", build.LogUrl)
+		slog.Error("An error occurred bootstrapping the PGP identity. Check the log files for more information", "log_url", build.LogUrl)
 		return fmt.Errorf("bootstrapping PGP identity failed")
 	}
 
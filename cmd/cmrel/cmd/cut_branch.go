@@ -0,0 +1,198 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v35/github"
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"golang.org/x/mod/semver"
+)
+
+const (
+	cutBranchCommand         = "cut-branch"
+	cutBranchDescription     = "Create the release branch for a new minor release series"
+	cutBranchLongDescription = `
+The 'cut-branch' command starts a new minor release series by creating the
+'release-X.Y' branch in the cert-manager repository using the GitHub API.
+
+The branch is created at the current HEAD of the source branch, usually
+'master'. The command fails if the release branch already exists, so an
+existing release branch is never moved.
+
+Unless --nomock is set, the command only logs the branch which would be
+created.
+
+A GITHUB_TOKEN environment variable is required when --nomock is set. The
+token must have permission to create branches in the repository.
+`
+)
+
+var (
+	cutBranchExample = fmt.Sprintf(`
+Create the release-1.16 branch from the HEAD of master:
+
+	%s %s --release-version v1.16 --nomock
+`, rootCommand, cutBranchCommand)
+)
+
+type cutBranchOptions struct {
+	// ReleaseVersion is the version of the new release series, such as
+	// 'v1.16' or 'v1.16.0-alpha.0'. Only the major and minor versions are
+	// used to name the release branch.
+	ReleaseVersion string
+
+	// Org is the GitHub org containing the repository to create the branch in.
+	Org string
+
+	// Repo is the name of the repository to create the branch in.
+	Repo string
+
+	// SourceBranch is the branch whose HEAD the release branch is created at.
+	SourceBranch string
+
+	// NoMock controls whether the release branch is actually created.
+	NoMock bool
+}
+
+func (o *cutBranchOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
+	fs.StringVar(&o.ReleaseVersion, "release-version", "", "The version of the new release series, e.g. 'v1.16'. Only the major and minor versions are used.")
+	fs.StringVar(&o.Org, "org", "cert-manager", "The GitHub org containing the repository to create the release branch in.")
+	fs.StringVar(&o.Repo, "repo", "cert-manager", "The name of the repository to create the release branch in.")
+	fs.StringVar(&o.SourceBranch, "source-branch", "master", "The branch whose HEAD the release branch is created at.")
+	fs.BoolVar(&o.NoMock, "nomock", false, "Whether to actually create the release branch. If false, the command only logs the branch which would be created.")
+	markRequired("release-version")
+}
+
+func (o *cutBranchOptions) print() {
+	slog.Info("Cut branch options",
+		"ReleaseVersion", o.ReleaseVersion,
+		"Org", o.Org,
+		"Repo", o.Repo,
+		"SourceBranch", o.SourceBranch,
+		"NoMock", o.NoMock,
+	)
+}
+
+func cutBranchCmd(rootOpts *rootOptions) *cobra.Command {
+	o := &cutBranchOptions{}
+	cmd := &cobra.Command{
+		Use:          cutBranchCommand,
+		Short:        cutBranchDescription,
+		Long:         cutBranchLongDescription,
+		Example:      cutBranchExample,
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			o.print()
+			slog.Info("---")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCutBranch(rootOpts, o)
+		},
+	}
+
+	o.AddFlags(cmd.Flags(), mustMarkRequired(cmd.MarkFlagRequired))
+
+	return cmd
+}
+
+// gitRefClient is the subset of the GitHub git API needed to create a branch.
+type gitRefClient interface {
+	GetRef(ctx context.Context, owner string, repo string, ref string) (*github.Reference, *github.Response, error)
+	CreateRef(ctx context.Context, owner string, repo string, ref *github.Reference) (*github.Reference, *github.Response, error)
+}
+
+func runCutBranch(rootOpts *rootOptions, o *cutBranchOptions) error {
+	ctx := context.Background()
+
+	branch, err := releaseBranchName(o.ReleaseVersion)
+	if err != nil {
+		return err
+	}
+
+	if !o.NoMock {
+		slog.Info("--nomock flag set to false, not creating release branch", "branch", branch, "repository", o.Org+"/"+o.Repo, "source_branch", o.SourceBranch)
+		return nil
+	}
+
+	if os.Getenv(githubTokenEnvVar) == "" {
+		return fmt.Errorf("%s environment variable not set - a token is required to create a release branch", githubTokenEnvVar)
+	}
+
+	client := newGitHubClient(ctx)
+
+	ref, err := createReleaseBranch(ctx, client.Git, o.Org, o.Repo, o.SourceBranch, branch)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Created release branch", "branch", branch, "repository", o.Org+"/"+o.Repo, "commit", ref.GetObject().GetSHA())
+
+	return nil
+}
+
+// releaseBranchName returns the name of the release branch for the minor
+// release series containing the given version, such as 'release-1.16' for
+// 'v1.16.0-alpha.0'.
+func releaseBranchName(version string) (string, error) {
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+
+	if !semver.IsValid(version) {
+		return "", fmt.Errorf("invalid release version %q", version)
+	}
+
+	return "release-" + strings.TrimPrefix(semver.MajorMinor(version), "v"), nil
+}
+
+// createReleaseBranch creates branch in the given repository at the HEAD of
+// sourceBranch. An error is returned if branch already exists.
+func createReleaseBranch(ctx context.Context, client gitRefClient, org, repo, sourceBranch, branch string) (*github.Reference, error) {
+	_, _, err := client.GetRef(ctx, org, repo, "refs/heads/"+branch)
+	if err == nil {
+		return nil, fmt.Errorf("branch %q already exists in %s/%s", branch, org, repo)
+	}
+
+	var gitHubErr *github.ErrorResponse
+	if !errors.As(err, &gitHubErr) || gitHubErr.Response == nil || gitHubErr.Response.StatusCode != http.StatusNotFound {
+		return nil, fmt.Errorf("failed to check for existing branch %q: %w", branch, err)
+	}
+
+	sourceRef, _, err := client.GetRef(ctx, org, repo, "refs/heads/"+sourceBranch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up source branch %q: %w", sourceBranch, err)
+	}
+
+	ref, _, err := client.CreateRef(ctx, org, repo, &github.Reference{
+		Ref:    github.String("refs/heads/" + branch),
+		Object: sourceRef.GetObject(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create branch %q: %w", branch, err)
+	}
+
+	return ref, nil
+}
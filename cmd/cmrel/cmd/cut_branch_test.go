@@ -0,0 +1,130 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v35/github"
+)
+
+func TestReleaseBranchName(t *testing.T) {
+	tests := map[string]struct {
+		version string
+
+		expectedBranch string
+		expectErr      bool
+	}{
+		"major and minor version": {
+			version:        "v1.16",
+			expectedBranch: "release-1.16",
+		},
+		"prerelease version": {
+			version:        "v1.16.0-alpha.0",
+			expectedBranch: "release-1.16",
+		},
+		"version without v prefix": {
+			version:        "1.16.2",
+			expectedBranch: "release-1.16",
+		},
+		"invalid version": {
+			version:   "latest",
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			branch, err := releaseBranchName(test.version)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if branch != test.expectedBranch {
+				t.Errorf("expected branch %q but got %q", test.expectedBranch, branch)
+			}
+		})
+	}
+}
+
+type fakeGitRefClient struct {
+	refs    map[string]string
+	created []*github.Reference
+}
+
+func (f *fakeGitRefClient) GetRef(ctx context.Context, owner string, repo string, ref string) (*github.Reference, *github.Response, error) {
+	sha, ok := f.refs[ref]
+	if !ok {
+		resp := &http.Response{StatusCode: http.StatusNotFound}
+		return nil, &github.Response{Response: resp}, &github.ErrorResponse{Response: resp}
+	}
+
+	return &github.Reference{Ref: github.String(ref), Object: &github.GitObject{SHA: github.String(sha)}}, nil, nil
+}
+
+func (f *fakeGitRefClient) CreateRef(ctx context.Context, owner string, repo string, ref *github.Reference) (*github.Reference, *github.Response, error) {
+	f.created = append(f.created, ref)
+	return ref, nil, nil
+}
+
+func TestCreateReleaseBranch(t *testing.T) {
+	tests := map[string]struct {
+		refs map[string]string
+
+		expectErr bool
+	}{
+		"creates branch at HEAD of source branch": {
+			refs: map[string]string{"refs/heads/master": "abcdef"},
+		},
+		"branch already exists": {
+			refs:      map[string]string{"refs/heads/master": "abcdef", "refs/heads/release-1.16": "012345"},
+			expectErr: true,
+		},
+		"source branch doesn't exist": {
+			refs:      map[string]string{},
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := &fakeGitRefClient{refs: test.refs}
+
+			_, err := createReleaseBranch(context.TODO(), client, "cert-manager", "cert-manager", "master", "release-1.16")
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if test.expectErr {
+				if len(client.created) != 0 {
+					t.Errorf("expected no branch to be created but got %v", client.created)
+				}
+				return
+			}
+
+			if len(client.created) != 1 {
+				t.Fatalf("expected 1 branch to be created but got %d", len(client.created))
+			}
+
+			if client.created[0].GetRef() != "refs/heads/release-1.16" || client.created[0].GetObject().GetSHA() != "abcdef" {
+				t.Errorf("unexpected branch created: %v", client.created[0])
+			}
+		})
+	}
+}
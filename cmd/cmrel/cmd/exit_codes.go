@@ -0,0 +1,89 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+
+	"github.com/cert-manager/release/pkg/release"
+)
+
+// Exit codes returned by cmrel. These are part of cmrel's public interface,
+// documented in the root command's help, and must not be changed.
+const (
+	// exitCodeGeneric is returned for any error not covered by a more
+	// specific exit code.
+	exitCodeGeneric = 1
+
+	// exitCodeValidation is returned when a release or its inputs fail
+	// validation.
+	exitCodeValidation = 2
+
+	// exitCodeNotFound is returned when a requested release or object does
+	// not exist.
+	exitCodeNotFound = 3
+
+	// exitCodeTransient is returned for errors which are likely to be
+	// resolved by retrying, such as timeouts or server errors.
+	exitCodeTransient = 4
+)
+
+// exitCodeForError returns the exit code that cmrel should exit with when a
+// command fails with the given error.
+func exitCodeForError(err error) int {
+	switch {
+	case errors.Is(err, release.ErrValidationFailed):
+		return exitCodeValidation
+
+	case errors.Is(err, release.ErrReleaseNotFound),
+		errors.Is(err, storage.ErrObjectNotExist),
+		errors.Is(err, storage.ErrBucketNotExist):
+		return exitCodeNotFound
+
+	case isTransientError(err):
+		return exitCodeTransient
+
+	default:
+		return exitCodeGeneric
+	}
+}
+
+// isTransientError returns true if the error is likely to be resolved by
+// retrying the operation which caused it.
+func isTransientError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == http.StatusTooManyRequests || apiErr.Code >= http.StatusInternalServerError
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return netErr.Timeout()
+	}
+
+	return false
+}
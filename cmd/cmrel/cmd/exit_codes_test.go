@@ -0,0 +1,82 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+
+	"github.com/cert-manager/release/pkg/release"
+)
+
+func TestExitCodeForError(t *testing.T) {
+	tests := map[string]struct {
+		err      error
+		expected int
+	}{
+		"generic error": {
+			err:      errors.New("something went wrong"),
+			expected: exitCodeGeneric,
+		},
+		"validation failed": {
+			err:      fmt.Errorf("release failed validation - refusing to publish: %w", release.ErrValidationFailed),
+			expected: exitCodeValidation,
+		},
+		"release not found": {
+			err:      fmt.Errorf("failed to fetch release: %w", fmt.Errorf("no release found in path %q: %w", "stage/gcb/release/v1.2.3-abcdef/", release.ErrReleaseNotFound)),
+			expected: exitCodeNotFound,
+		},
+		"object not found": {
+			err:      fmt.Errorf("failed to read artifact: %w", storage.ErrObjectNotExist),
+			expected: exitCodeNotFound,
+		},
+		"bucket not found": {
+			err:      fmt.Errorf("failed to list releases: %w", storage.ErrBucketNotExist),
+			expected: exitCodeNotFound,
+		},
+		"deadline exceeded": {
+			err:      fmt.Errorf("failed to fetch release: %w", context.DeadlineExceeded),
+			expected: exitCodeTransient,
+		},
+		"server error": {
+			err:      fmt.Errorf("failed to fetch release: %w", &googleapi.Error{Code: http.StatusServiceUnavailable}),
+			expected: exitCodeTransient,
+		},
+		"rate limited": {
+			err:      fmt.Errorf("failed to fetch release: %w", &googleapi.Error{Code: http.StatusTooManyRequests}),
+			expected: exitCodeTransient,
+		},
+		"client error": {
+			err:      fmt.Errorf("failed to fetch release: %w", &googleapi.Error{Code: http.StatusForbidden}),
+			expected: exitCodeGeneric,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if code := exitCodeForError(test.err); code != test.expected {
+				t.Errorf("unexpected exit code for %q: got=%d, exp=%d", test.err, code, test.expected)
+			}
+		})
+	}
+}
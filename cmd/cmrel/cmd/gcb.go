@@ -51,6 +51,8 @@ func gcbCmd(o *rootOptions) *cobra.Command {
 	cmd.AddCommand(gcbStageCmd(o))
 	cmd.AddCommand(gcbPublishCmd(o))
 	cmd.AddCommand(gcbBootstrapPGPCmd(o))
+	cmd.AddCommand(gcbStatusCmd(o))
+	cmd.AddCommand(gcbCancelCmd(o))
 
 	return cmd
 }
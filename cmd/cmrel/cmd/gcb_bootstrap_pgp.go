@@ -19,14 +19,12 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/spf13/cobra"
 	flag "github.com/spf13/pflag"
-	"golang.org/x/oauth2/google"
-	"google.golang.org/api/cloudkms/v1"
-	"google.golang.org/api/option"
 
+	"github.com/cert-manager/release/pkg/gcp"
 	"github.com/cert-manager/release/pkg/sign"
 )
 
@@ -63,8 +61,9 @@ func (o *gcbBootstrapPGPOptions) AddFlags(fs *flag.FlagSet, markRequired func(st
 }
 
 func (o *gcbBootstrapPGPOptions) print() {
-	log.Printf("bootstrap-pgp options:")
-	log.Printf("         Key: %q", o.Key)
+	slog.Info("bootstrap-pgp options",
+		"Key", o.Key,
+	)
 }
 
 func gcbBootstrapPGPCmd(rootOpts *rootOptions) *cobra.Command {
@@ -77,7 +76,7 @@ func gcbBootstrapPGPCmd(rootOpts *rootOptions) *cobra.Command {
 		SilenceUsage: true,
 		PreRun: func(cmd *cobra.Command, args []string) {
 			o.print()
-			log.Printf("---")
+			slog.Info("---")
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runGCBBootstrapPGP(rootOpts, o)
@@ -90,12 +89,7 @@ func gcbBootstrapPGPCmd(rootOpts *rootOptions) *cobra.Command {
 }
 
 func getPEMPubkey(ctx context.Context, key sign.GCPKMSKey) (string, error) {
-	oauthClient, err := google.DefaultClient(ctx, cloudkms.CloudPlatformScope)
-	if err != nil {
-		return "", fmt.Errorf("could not create GCP OAuth2 client: %w", err)
-	}
-
-	svc, err := cloudkms.NewService(ctx, option.WithHTTPClient(oauthClient))
+	svc, err := gcp.NewKMSService(ctx)
 	if err != nil {
 		return "", fmt.Errorf("could not create GCP KMS client: %w", err)
 	}
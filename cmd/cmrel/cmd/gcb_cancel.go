@@ -0,0 +1,105 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+
+	"github.com/cert-manager/release/pkg/gcb"
+	"github.com/cert-manager/release/pkg/gcp"
+	"github.com/cert-manager/release/pkg/release"
+)
+
+const (
+	gcbCancelCommand         = "cancel"
+	gcbCancelDescription     = "Cancel a running Google Cloud Build job."
+	gcbCancelLongDescription = `The cancel command cancels a queued or running Google Cloud Build job by its
+ID, such as a build submitted by the stage, makestage or publish commands.
+
+Build IDs for a release can be found using the 'gcb status' command. Builds
+which have already finished can't be cancelled.`
+)
+
+var gcbCancelExample = fmt.Sprintf(`To cancel a running build:
+
+%s gcb %s --build-id <BUILD_ID>`, rootCommand, gcbCancelCommand)
+
+type gcbCancelOptions struct {
+	// Project is the GCP project containing the GCB job
+	Project string
+
+	// BuildID is the ID of the GCB job to cancel
+	BuildID string
+}
+
+func (o *gcbCancelOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
+	fs.StringVar(&o.Project, "project", release.DefaultReleaseProject, "The GCP project containing the GCB build job.")
+	fs.StringVar(&o.BuildID, "build-id", "", "The ID of the GCB build job to cancel.")
+	markRequired("build-id")
+}
+
+func (o *gcbCancelOptions) print() {
+	slog.Info("gcb cancel options",
+		"Project", o.Project,
+		"BuildID", o.BuildID,
+	)
+}
+
+func gcbCancelCmd(rootOpts *rootOptions) *cobra.Command {
+	o := &gcbCancelOptions{}
+	cmd := &cobra.Command{
+		Use:          gcbCancelCommand,
+		Short:        gcbCancelDescription,
+		Long:         gcbCancelLongDescription,
+		Example:      gcbCancelExample,
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			o.print()
+			slog.Info("---")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGCBCancel(rootOpts, o)
+		},
+	}
+
+	o.AddFlags(cmd.Flags(), mustMarkRequired(cmd.MarkFlagRequired))
+
+	return cmd
+}
+
+func runGCBCancel(rootOpts *rootOptions, o *gcbCancelOptions) error {
+	ctx := context.Background()
+
+	svc, err := gcp.NewCloudBuildService(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating cloudbuild client: %w", err)
+	}
+
+	build, err := gcb.CancelBuild(ctx, svc, o.Project, o.BuildID)
+	if err != nil {
+		return fmt.Errorf("failed to cancel build %q: %w", o.BuildID, err)
+	}
+
+	slog.Info("Cancelled build", "build_id", build.Id, "status", build.Status, "log_url", build.LogUrl)
+
+	return nil
+}
@@ -19,28 +19,41 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
+	"net/http"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
-	"cloud.google.com/go/storage"
-	"github.com/cenkalti/backoff/v5"
+	"github.com/google/go-containerregistry/pkg/authn"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/google/go-github/v35/github"
 	"github.com/spf13/cobra"
 	flag "github.com/spf13/pflag"
-	"golang.org/x/oauth2"
+	"golang.org/x/sync/errgroup"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/utils/pointer"
 
+	"github.com/cert-manager/release/pkg/gcp"
 	"github.com/cert-manager/release/pkg/release"
 	"github.com/cert-manager/release/pkg/release/docker"
 	"github.com/cert-manager/release/pkg/release/helm"
+	"github.com/cert-manager/release/pkg/release/images"
+	"github.com/cert-manager/release/pkg/release/notes"
+	"github.com/cert-manager/release/pkg/release/provenance"
 	"github.com/cert-manager/release/pkg/release/publish/registry"
+	"github.com/cert-manager/release/pkg/release/sbom"
 	"github.com/cert-manager/release/pkg/release/validation"
+	"github.com/cert-manager/release/pkg/retry"
+	"github.com/cert-manager/release/pkg/shell"
 	"github.com/cert-manager/release/pkg/sign"
 	"github.com/cert-manager/release/pkg/sign/cosign"
 )
@@ -53,13 +66,30 @@ The 'gcb publish' subcommand will fetch a staged release from GCS, verify its
 integrity and publish artifacts to public-facing artifact repositories (e.g.
 Quay.io, GitHub releases and the Helm chart repostory).
 
-It requires Docker to be installed and available.
+Images are pushed directly from the staged image tarballs, so a Docker daemon
+isn't required. Registry credentials are read from the Docker config file. If
+--sbom-format is set, syft must be available to generate SBOMs for the release.
 
 The GitHub token to use to create the draft release should be set using the
 GITHUB_TOKEN environment variable.
+
+Container images and manifest lists are signed with the KMS key given by
+--signing-kms-key by default. With --signing-mode=keyless they're instead signed
+using cosign keyless signing, with a certificate issued by Fulcio for the OIDC
+identity the build runs as, and the signatures are uploaded to the Rekor
+transparency log. Other artifacts are always signed with the KMS key.
 `
 )
 
+const (
+	// signingModeKMS signs container images with a GCP KMS key.
+	signingModeKMS = "kms"
+
+	// signingModeKeyless signs container images with cosign keyless signing,
+	// using Fulcio and Rekor.
+	signingModeKeyless = "keyless"
+)
+
 type publishAction func(context.Context, *gcbPublishOptions, *release.Unpacked) error
 
 type gcbPublishOptions struct {
@@ -69,6 +99,43 @@ type gcbPublishOptions struct {
 	// Name of the staged release to publish
 	ReleaseName string
 
+	// MetadataFileName is the name of the file containing the release
+	// metadata in the root of the staged release.
+	MetadataFileName string
+
+	// StrictMetadata, if true, causes release metadata containing unknown
+	// fields to be rejected.
+	StrictMetadata bool
+
+	// ImageTarPrefix and ImageTarSuffix are trimmed from the file names of
+	// image tars in the staged release to determine their component names.
+	ImageTarPrefix string
+	ImageTarSuffix string
+
+	// UnpackParallelism is the number of staged artifacts of each kind to
+	// download and extract concurrently when unpacking the release.
+	UnpackParallelism int
+
+	// CompareToPrevious is the name of a previously staged release which the
+	// release being published is compared against, to catch accidental
+	// regressions such as missing components or architectures.
+	// If empty, no comparison is made.
+	CompareToPrevious string
+
+	// CompareChartsTo is the name of another staged build of the same version
+	// and git ref as the release being published. If set, publishing is
+	// refused unless the Helm charts in both builds are identical.
+	CompareChartsTo string
+
+	// CompareChartsToReleaseType is the type of the staged build named by
+	// CompareChartsTo - usually one of 'release' or 'devel'
+	CompareChartsToReleaseType string
+
+	// AllowMissingProvenance, if true, allows a release built by Bazel to be
+	// published without provenance, in which case its images aren't attested.
+	// Releases built by make are never staged with provenance.
+	AllowMissingProvenance bool
+
 	// NoMock controls whether release artifacts are actually published.
 	// If false, the command will exit after preparing the release for pushing.
 	NoMock bool
@@ -78,6 +145,28 @@ type gcbPublishOptions struct {
 	// It is used as the repository for manifest lists created for artifacts.
 	PublishedImageRepository string
 
+	// PublishedImageMirrorRepositories are additional image repositories
+	// which images and manifest lists are also pushed to and signed in.
+	// Publishing only fails if an image can't be pushed to any repository.
+	PublishedImageMirrorRepositories []string
+
+	// PushConcurrency is the number of images and manifest lists to push
+	// concurrently.
+	PushConcurrency int
+
+	// PushRetryInitialInterval is how long to wait before retrying a failed
+	// push, and how long all pushes are paused for when a registry first
+	// rate limits them. Later waits back off exponentially from it.
+	PushRetryInitialInterval time.Duration
+
+	// PushRetryMaxInterval is the longest wait between attempts to push an
+	// image or manifest list, or for a registry which is rate limiting pushes.
+	PushRetryMaxInterval time.Duration
+
+	// PushRetryMaxTries is the number of attempts made to push each image or
+	// manifest list before giving up.
+	PushRetryMaxTries uint
+
 	// PublishedHelmChartGitHubOwner is the name of the owner of the GitHub repo
 	// for Helm charts.
 	PublishedHelmChartGitHubOwner string
@@ -90,6 +179,19 @@ type gcbPublishOptions struct {
 	// GitHub repository for Helm Charts.
 	PublishedHelmChartGitHubBranch string
 
+	// PublishedHelmChartOCIRegistry is the OCI registry, prefixed with
+	// "oci://", which Helm charts are pushed to by the 'helmchartoci' action.
+	PublishedHelmChartOCIRegistry string
+
+	// PublishedHelmChartRepoBucket is the GCS bucket backing a static Helm
+	// chart repository, which charts are uploaded to and indexed in by the
+	// 'helmchartrepo' action. If empty, the action does nothing.
+	PublishedHelmChartRepoBucket string
+
+	// PublishedHelmChartRepoURL is the URL the Helm chart repository in
+	// PublishedHelmChartRepoBucket is served from.
+	PublishedHelmChartRepoURL string
+
 	// PublishedGitHubOrg is the org of the repository where the release will
 	// be published to.
 	PublishedGitHubOrg string
@@ -98,6 +200,33 @@ type gcbPublishOptions struct {
 	// release will be published to.
 	PublishedGitHubRepo string
 
+	// PublishedCmctlGitHubOrg is the org of the repository where cmctl
+	// binaries in the release will be published to, for versions of
+	// cert-manager which no longer ship cmctl themselves.
+	PublishedCmctlGitHubOrg string
+
+	// PublishedCmctlGitHubRepo is the repo name in PublishedCmctlGitHubOrg
+	// where cmctl binaries in the release will be published to.
+	PublishedCmctlGitHubRepo string
+
+	// SkipReleaseNotes, if true, creates the draft GitHub release with a
+	// placeholder body rather than generating release notes from the PRs
+	// merged since the previous release.
+	SkipReleaseNotes bool
+
+	// PreviousReleaseTag is the tag which release notes are generated from.
+	// If empty, the highest semver tag before the release version is used.
+	PreviousReleaseTag string
+
+	// Prerelease is whether the GitHub release is marked as a prerelease:
+	// "true", "false" or "auto" to mark alpha, beta and rc versions.
+	Prerelease string
+
+	// Latest is whether the GitHub release is marked as the latest release
+	// when it's published: "true", "false" or "auto" to mark it unless it's a
+	// prerelease or a higher version has already been tagged.
+	Latest string
+
 	// SkipSigning, if true, will skip trying to sign artifacts using KMS
 	SkipSigning bool
 
@@ -106,18 +235,127 @@ type gcbPublishOptions struct {
 	// This must be set if SkipSigning is not set to true
 	SigningKMSKey string
 
+	// SigningMode is how container images and manifest lists are signed,
+	// either "kms" to sign with SigningKMSKey or "keyless" to use cosign
+	// keyless signing. It has no effect if SkipSigning is true.
+	SigningMode string
+
+	// KeylessFulcioURL and KeylessRekorURL are the Fulcio and Rekor
+	// instances used when SigningMode is "keyless". If empty, cosign's
+	// default public instances are used.
+	KeylessFulcioURL string
+	KeylessRekorURL  string
+
+	// KeylessServiceAccount, if set, is the GCP service account impersonated
+	// to get an OIDC identity token when SigningMode is "keyless".
+	KeylessServiceAccount string
+
+	// VerifyMetadataSignature, if true, causes the staged release to be
+	// rejected unless its metadata has a valid signature made by
+	// SigningKMSKey. It has no effect if SkipSigning is true.
+	VerifyMetadataSignature bool
+
 	// PublishActions list of publishing actions to take
 	PublishActions []string
 
+	// ResumeFromAction, if set, skips all publish actions which would run
+	// before the named action. It's intended for resuming a publish which
+	// failed part way through.
+	ResumeFromAction string
+
 	// CosignPath points to the location of the cosign binary
 	CosignPath string
 
+	// CosignVersion, if set, is the version of cosign to download and use
+	// instead of the binary at CosignPath. It cannot be used together with
+	// an explicit --cosign-path.
+	CosignVersion string
+
+	// CosignSHA256 is the expected SHA256 sum of the cosign binary downloaded
+	// for CosignVersion. It must be set if CosignVersion is set.
+	CosignSHA256 string
+
+	// SBOMFormat is the format of the SBOMs generated for each container image
+	// and ctl binary in the release, either "spdx-json" or "cyclonedx-json".
+	// If empty, no SBOMs are generated.
+	SBOMFormat string
+
+	// SyftPath points to the location of the syft binary, used to generate
+	// SBOMs.
+	SyftPath string
+
+	// VerifyHelmChart, if true, will run 'helm template' against the published
+	// Helm chart(s) after all publish actions have completed, to check that
+	// the published charts can be fetched and rendered.
+	VerifyHelmChart bool
+
+	// VerifyHelmChartRepo is the URL of the chart repository (or "oci://"
+	// registry reference) that published charts are fetched from when
+	// verifying them. If empty, charts are fetched from wherever this publish
+	// pushed them: the OCI registry if the 'helmchartoci' action ran, else the
+	// chart repository of the 'helmchartrepo' action, else the public chart
+	// repository.
+	// Charts only appear in the public chart repository once the PR made by
+	// the 'helmchartpr' action is merged, so verifying against it must be
+	// done by a later publish run after the merge.
+	VerifyHelmChartRepo string
+
+	// HelmPath points to the location of the helm binary
+	HelmPath string
+
+	// DownloadURLsFormat is the format used when printing the download URLs
+	// and install commands for the published release, either "markdown" or
+	// "json".
+	DownloadURLsFormat string
+
+	// DownloadURLsOutput is an optional path to write the download URLs and
+	// install commands for the published release to.
+	DownloadURLsOutput string
+
+	// DigestsOutput is an optional path to write the digests of the published
+	// images and manifest lists to after publishing.
+	DigestsOutput string
+
+	// PinImageDigests, if true, causes variants of the release manifests and
+	// Helm chart values which reference images by digest to be uploaded to
+	// the GitHub release alongside the original manifests.
+	PinImageDigests bool
+
+	// UploadDigests, if true, uploads the digests of the published images and
+	// manifest lists to the root of the staged release in the bucket.
+	UploadDigests bool
+
 	// manualActionLogger logs to a buffer and is used by publish actions to log any manual
 	// actions that must be taken by the user even after a successful publish is completed.
 	// Get the log contents with ManualActionText()
 	manualActionLogger *log.Logger
 
 	manualActionBuffer bytes.Buffer
+
+	// IgnorePublishState, if true, ignores the progress recorded by any
+	// previous attempt to publish the release and runs every action again.
+	IgnorePublishState bool
+
+	// checkpoint records the progress of publishing so that a failed publish
+	// can be resumed. It is nil if progress isn't being recorded.
+	checkpoint *publishCheckpoint
+
+	// pushedDigests records the digest of each image and manifest list pushed
+	// by this run.
+	pushedDigests pushedDigestRecorder
+
+	// uploadedAssets records the names of the GitHub release assets uploaded
+	// by this run, or found to have been uploaded by a previous run, keyed by
+	// the publish action which uploaded them.
+	uploadedAssets map[string]sets.String
+
+	// sboms holds the SBOMs generated for the release. It is nil if SBOMs
+	// aren't being generated.
+	sboms *releaseSBOMs
+
+	// provenance is the SLSA provenance written when the release was staged.
+	// It is nil if the release was staged without provenance.
+	provenance *provenance.Statement
 }
 
 // NewGCBPublishOptions creates options and initializes loggers correctly
@@ -137,7 +375,7 @@ func (o *gcbPublishOptions) ManualActionText() string {
 // PublishActionList constructs a slice of artifact publishing functions based on the values
 // listed in o.PublishActions.
 func (o *gcbPublishOptions) PublishActionList() ([]publishAction, error) {
-	actionNames, err := canonicalizeAndVerifyPublishActions(o.PublishActions)
+	actionNames, err := o.PublishActionNames()
 	if err != nil {
 		return nil, err
 	}
@@ -156,56 +394,217 @@ func (o *gcbPublishOptions) PublishActionList() ([]publishAction, error) {
 	return actionFuncs, nil
 }
 
+// PublishActionNames returns the canonical names of the publishing actions
+// to take, in the order they'll be run, taking into account both
+// o.PublishActions and o.ResumeFromAction.
+func (o *gcbPublishOptions) PublishActionNames() ([]string, error) {
+	actionNames, err := canonicalizeAndVerifyPublishActions(o.PublishActions)
+	if err != nil {
+		return nil, err
+	}
+
+	return resumePublishActions(actionNames, o.ResumeFromAction)
+}
+
 func (o *gcbPublishOptions) GitHubClient(ctx context.Context) (*github.Client, error) {
 	// construct the GitHub API client
 	// The GITHUB_TOKEN must be a GitHub personal access token with at least
 	// `repo` privileges and the associated user must have permission to create
 	// branches and PRs at the Helm GitHub repository.
-	githubToken := os.Getenv("GITHUB_TOKEN")
-	if githubToken == "" {
-		return nil, fmt.Errorf("GITHUB_TOKEN environment variable not set - a token is always required to create a release")
+	if os.Getenv(githubTokenEnvVar) == "" {
+		return nil, fmt.Errorf("%s environment variable not set - a token is always required to create a release", githubTokenEnvVar)
 	}
 
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: githubToken},
-	)
+	return newGitHubClient(ctx), nil
+}
+
+// publishedImageRepositories returns the image repositories to publish to,
+// starting with the primary repository and followed by any mirrors.
+func (o *gcbPublishOptions) publishedImageRepositories() []string {
+	seen := sets.NewString()
+
+	var repos []string
+	for _, repo := range append([]string{o.PublishedImageRepository}, o.PublishedImageMirrorRepositories...) {
+		repo = strings.TrimSpace(repo)
+		if repo == "" || seen.Has(repo) {
+			continue
+		}
+
+		seen.Insert(repo)
+		repos = append(repos, repo)
+	}
+
+	return repos
+}
+
+// pusher returns a docker.Pusher configured to retry pushes and back off from
+// rate limiting registries as configured in o. Concurrent pushes should share
+// a single pusher, so that they all back off together.
+func (o *gcbPublishOptions) pusher() *docker.Pusher {
+	retryOpts := retry.Options{
+		InitialInterval: o.PushRetryInitialInterval,
+		MaxInterval:     o.PushRetryMaxInterval,
+		MaxTries:        o.PushRetryMaxTries,
+	}
+
+	return docker.NewPusher(retryOpts, docker.NewThrottle(o.PushRetryInitialInterval, o.PushRetryMaxInterval), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+}
+
+// unpackOptions returns the options used to unpack staged releases.
+func (o *gcbPublishOptions) unpackOptions() release.UnpackOptions {
+	return release.UnpackOptions{
+		ImageTarPrefix: o.ImageTarPrefix,
+		ImageTarSuffix: o.ImageTarSuffix,
+		Parallelism:    o.UnpackParallelism,
+	}
+}
 
-	tc := oauth2.NewClient(ctx, ts)
+// signImage returns a function which signs a container image or manifest
+// list reference using cosign, as configured by the signing mode in o.
+func (o *gcbPublishOptions) signImage() (func(ctx context.Context, ref string) error, error) {
+	switch o.SigningMode {
+	case signingModeKMS:
+		parsedKey, err := sign.NewGCPKMSKey(o.SigningKMSKey)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(ctx context.Context, ref string) error {
+			return cosign.Sign(ctx, o.CosignPath, []string{ref}, parsedKey)
+		}, nil
+
+	case signingModeKeyless:
+		opts := cosign.KeylessOptions{
+			FulcioURL:      o.KeylessFulcioURL,
+			RekorURL:       o.KeylessRekorURL,
+			ServiceAccount: o.KeylessServiceAccount,
+		}
+
+		return func(ctx context.Context, ref string) error {
+			return cosign.SignKeyless(ctx, o.CosignPath, []string{ref}, opts)
+		}, nil
 
-	return github.NewClient(tc), nil
+	default:
+		return nil, fmt.Errorf("unknown signing mode %q; must be one of %q or %q", o.SigningMode, signingModeKMS, signingModeKeyless)
+	}
 }
 
 func (o *gcbPublishOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
 	fs.StringVar(&o.Bucket, "bucket", release.DefaultBucketName, "The name of the GCS bucket to stage the release to.")
 	fs.StringVar(&o.ReleaseName, "release-name", "", "Name of the staged release to publish.")
+	fs.StringVar(&o.MetadataFileName, "metadata-file-name", release.MetadataFileName, "The name of the file containing the release metadata in the root of the staged release.")
+	fs.BoolVar(&o.StrictMetadata, "strict-metadata", false, "Reject release metadata which contains unknown fields, rather than ignoring them.")
+	fs.StringVar(&o.ImageTarPrefix, "image-tar-prefix", "", "Prefix to trim from the file names of image tars in the staged release to determine their component names, e.g. 'cert-manager-' for 'cert-manager-controller.tar'.")
+	fs.StringVar(&o.ImageTarSuffix, "image-tar-suffix", "", "Suffix to trim from the file names of image tars in the staged release, after the '.tar' extension, to determine their component names.")
+	fs.IntVar(&o.UnpackParallelism, "unpack-parallelism", runtime.NumCPU(), "The number of staged release artifacts of each kind to download and extract in parallel.")
+	fs.StringVar(&o.CompareToPrevious, "compare-to-previous", "", "Name of a previously staged release to compare the release against before publishing. Publishing is refused if the version does not increase, or if components or architectures present in the previous release are missing.")
+	fs.StringVar(&o.CompareChartsTo, "compare-charts-to", "", "Name of another staged build of the same version and git ref. Publishing is refused unless its Helm charts are byte-for-byte identical to those in the release, which verifies that chart packaging is reproducible.")
+	fs.StringVar(&o.CompareChartsToReleaseType, "compare-charts-to-release-type", release.BuildTypeDevel, "The type of the staged build named by --compare-charts-to, usually one of 'release' or 'devel'")
+	fs.BoolVar(&o.AllowMissingProvenance, "allow-missing-provenance", false, fmt.Sprintf("Allow publishing a release built by Bazel which was staged without %q, in which case its images aren't attested. Releases built by make never have provenance.", release.ProvenanceFileName))
 	fs.BoolVar(&o.NoMock, "nomock", false, "Whether to actually publish the release. If false, the command will exit after preparing the release for pushing.")
 	fs.StringVar(&o.PublishedImageRepository, "published-image-repo", release.DefaultImageRepository, "The docker image repository to push the release images & manifest lists to.")
+	fs.StringSliceVar(&o.PublishedImageMirrorRepositories, "published-image-mirror-repos", nil, "Comma-separated list of additional docker image repositories to push the release images & manifest lists to, alongside --published-image-repo. Publishing only fails if an image can't be pushed to any repository.")
+	fs.IntVar(&o.PushConcurrency, "push-concurrency", 4, "The number of images and manifest lists to push concurrently.")
+	fs.DurationVar(&o.PushRetryInitialInterval, "push-retry-initial-interval", retry.InitialInterval, "How long to wait before retrying a failed push, and to pause all pushes for when a registry starts rate limiting them. Later waits back off exponentially.")
+	fs.DurationVar(&o.PushRetryMaxInterval, "push-retry-max-interval", retry.MaxInterval, "The longest time to wait between attempts to push an image or manifest list, or for a registry which is rate limiting pushes.")
+	fs.UintVar(&o.PushRetryMaxTries, "push-retry-max-tries", retry.MaxTries, "The number of attempts to make to push each image or manifest list before giving up.")
 	fs.StringVar(&o.PublishedHelmChartGitHubOwner, "published-helm-chart-github-owner", release.DefaultHelmChartGitHubOwner, "The name of the owner of the GitHub repo for Helm charts.")
 	fs.StringVar(&o.PublishedHelmChartGitHubRepo, "published-helm-chart-github-repo", release.DefaultHelmChartGitHubRepo, "The name of the GitHub repo for Helm charts.")
 	fs.StringVar(&o.PublishedHelmChartGitHubBranch, "published-helm-chart-github-branch", release.DefaultHelmChartGitHubBranch, "The name of the main branch in the GitHub repository for Helm charts.")
+	fs.StringVar(&o.PublishedHelmChartOCIRegistry, "published-helm-chart-oci-registry", release.DefaultHelmChartOCIRegistry, "The OCI registry, prefixed with 'oci://', to push Helm charts to in the 'helmchartoci' publish action.")
+	fs.StringVar(&o.PublishedHelmChartRepoBucket, "published-helm-chart-repo-bucket", "", "The GCS bucket backing a static Helm chart repository, to upload Helm charts to and add them to the index.yaml of in the 'helmchartrepo' publish action. If empty, the action does nothing.")
+	fs.StringVar(&o.PublishedHelmChartRepoURL, "published-helm-chart-repo-url", release.DefaultHelmChartRepositoryURL, "The URL the Helm chart repository in --published-helm-chart-repo-bucket is served from, used for the chart URLs in its index.")
 	fs.StringVar(&o.PublishedGitHubOrg, "published-github-org", release.DefaultGitHubOrg, "The org of the repository where the release wil be published to.")
 	fs.StringVar(&o.PublishedGitHubRepo, "published-github-repo", release.DefaultGitHubRepo, "The repo name in the provided org where the release will be published to.")
+	fs.StringVar(&o.PublishedCmctlGitHubOrg, "published-cmctl-github-org", release.DefaultGitHubOrg, "The org of the repository where cmctl binaries in the release will be published to by the cmctlgithubrelease action.")
+	fs.StringVar(&o.PublishedCmctlGitHubRepo, "published-cmctl-github-repo", release.DefaultCmctlGitHubRepo, "The repo name in the provided org where cmctl binaries in the release will be published to by the cmctlgithubrelease action.")
+	fs.BoolVar(&o.SkipReleaseNotes, "skip-release-notes", false, "Create the draft GitHub release with a placeholder body, rather than generating release notes from the PRs merged since the previous release.")
+	fs.StringVar(&o.PreviousReleaseTag, "previous-release-tag", "", "The tag to generate release notes from. If empty, the highest semver tag before the release version is used.")
+	fs.StringVar(&o.Prerelease, "prerelease", releaseFlagAuto, "Whether to mark the GitHub release as a prerelease: true, false or auto to mark alpha, beta and rc versions.")
+	fs.StringVar(&o.Latest, "latest", releaseFlagAuto, "Whether the GitHub release should become the latest release when it's published: true, false or auto to mark it as latest unless it's a prerelease or a higher version has already been tagged, such as when patching an older release branch.")
 	fs.StringVar(&o.CosignPath, "cosign-path", "cosign", "Full path to the cosign binary. Defaults to searching in $PATH for a binary called 'cosign'")
+	fs.StringVar(&o.SBOMFormat, "sbom-format", "", fmt.Sprintf("Format of the SBOMs to generate for each container image and ctl binary, which are attached to the pushed images and uploaded to the GitHub release. If empty, no SBOMs are generated. Options: %s", strings.Join(sbom.Formats(), ", ")))
+	fs.StringVar(&o.SyftPath, "syft-path", "syft", "Full path to the syft binary, used to generate SBOMs. Defaults to searching in $PATH for a binary called 'syft'")
+	fs.BoolVar(&o.VerifyHelmChart, "verify-helm-chart", false, "Whether to check that the published Helm chart(s) can be fetched from --verify-helm-chart-repo and rendered using 'helm template' after publishing.")
+	fs.StringVar(&o.VerifyHelmChartRepo, "verify-helm-chart-repo", "", fmt.Sprintf("The chart repository URL (or 'oci://' registry reference) to fetch published Helm charts from when verifying them. If empty, defaults to --published-helm-chart-oci-registry if the 'helmchartoci' action runs, else --published-helm-chart-repo-url if the 'helmchartrepo' action runs, else %q. Charts only appear in %q once the Helm chart PR is merged, so verifying them there must be done after the merge.", release.DefaultHelmChartRepositoryURL, release.DefaultHelmChartRepositoryURL))
+	fs.StringVar(&o.HelmPath, "helm-path", "helm", "Full path to the helm binary. Defaults to searching in $PATH for a binary called 'helm'")
+	fs.StringVar(&o.DownloadURLsFormat, "download-urls-format", downloadURLsFormatMarkdown, fmt.Sprintf("Format used to print the download URLs and install commands for the published release after publishing. Options: %s, %s", downloadURLsFormatMarkdown, downloadURLsFormatJSON))
+	fs.StringVar(&o.DownloadURLsOutput, "download-urls-output", "", "Optional path to write the download URLs and install commands for the published release to after publishing.")
+	fs.StringVar(&o.DigestsOutput, "digests-output", "", "Optional path to write the digests of the published images and manifest lists to, as JSON, after publishing.")
+	fs.BoolVar(&o.PinImageDigests, "pin-image-digests", true, "Upload variants of the release manifests (e.g. cert-manager.digests.yaml) and a Helm values file for each chart (e.g. cert-manager-values.digests.yaml) which reference images by the digests of the pushed manifest lists to the GitHub release.")
+	fs.BoolVar(&o.UploadDigests, "upload-digests", false, fmt.Sprintf("Upload the digests of the published images and manifest lists to %q in the staged release.", release.PublishedDigestsFileName))
+	fs.StringVar(&o.CosignVersion, "cosign-version", "", "Optional version of cosign to download and use, e.g. v2.2.4. Cannot be used with --cosign-path. Downloaded binaries are cached and verified against --cosign-sha256.")
+	fs.StringVar(&o.CosignSHA256, "cosign-sha256", "", "Expected SHA256 sum of the cosign binary downloaded for --cosign-version, for the OS and architecture cmrel is running on.")
 	fs.StringVar(&o.SigningKMSKey, "signing-kms-key", defaultKMSKey, "Full name of the GCP KMS key to use for signing.")
 	fs.BoolVar(&o.SkipSigning, "skip-signing", false, "Skip signing container images.")
-	fs.StringSliceVar(&o.PublishActions, "publish-actions", []string{"*"}, fmt.Sprintf("Comma-separated list of actions to take, or '*' to do everything. Only meaningful if nomock is set. Operations are done in alphabetical order. Actions can be removed with a prefix of '-'. Options: %s", strings.Join(allPublishActionNames(), ", ")))
+	fs.StringVar(&o.SigningMode, "signing-mode", signingModeKMS, fmt.Sprintf("How to sign container images and manifest lists. Options: %s to sign with --signing-kms-key, or %s to use cosign keyless signing with an OIDC identity, uploading signatures to the Rekor transparency log. Other artifacts are always signed with --signing-kms-key.", signingModeKMS, signingModeKeyless))
+	fs.StringVar(&o.KeylessFulcioURL, "keyless-fulcio-url", "", "URL of the Fulcio instance to get signing certificates from when --signing-mode=keyless. Defaults to cosign's public instance.")
+	fs.StringVar(&o.KeylessRekorURL, "keyless-rekor-url", "", "URL of the Rekor transparency log to upload signatures to when --signing-mode=keyless. Defaults to cosign's public instance.")
+	fs.StringVar(&o.KeylessServiceAccount, "keyless-service-account", "", "Optional email of a GCP service account to impersonate to get an OIDC identity token when --signing-mode=keyless, such as the service account the GCB build runs as.")
+	fs.BoolVar(&o.VerifyMetadataSignature, "verify-metadata-signature", true, "Refuse to publish a staged release unless its metadata has a valid signature made by --signing-kms-key. Has no effect if --skip-signing is set. Set to false to publish releases staged before metadata was signed.")
+	fs.StringSliceVar(&o.PublishActions, "publish-actions", []string{"*"}, fmt.Sprintf("Comma-separated list of actions to take, or '*' to do everything. Only meaningful if nomock is set. Actions run after any actions they depend on, such as githubrelease after pushcontainerimages, and otherwise in alphabetical order. Actions can be removed with a prefix of '-'. Options: %s", strings.Join(allPublishActionNames(), ", ")))
+	fs.StringVar(&o.ResumeFromAction, "resume-from-action", "", "If set, skip all publish actions which would run before the named action. Used to resume a publish which previously failed part way through.")
+	fs.BoolVar(&o.IgnorePublishState, "ignore-publish-state", false, fmt.Sprintf("Ignore the progress recorded in %q in the staged release by previous attempts to publish it, and run every publish action again.", release.PublishStateFileName))
 }
 
 func (o *gcbPublishOptions) print() {
-	log.Printf("GCB Publish options:")
-	log.Printf("  Bucket: %q", o.Bucket)
-	log.Printf("  ReleaseName: %q", o.ReleaseName)
-	log.Printf("  NoMock: %t", o.NoMock)
-	log.Printf("  PublishedImageRepo: %q", o.PublishedImageRepository)
-	log.Printf("  PublishedHelmChartGitHubRepo: %q", o.PublishedHelmChartGitHubRepo)
-	log.Printf("  PublishedHelmChartGitHubOwner: %q", o.PublishedHelmChartGitHubOwner)
-	log.Printf("  PublishedHelmChartGitHubBranch: %q", o.PublishedHelmChartGitHubBranch)
-	log.Printf("  PublishedGitHubOrg: %q", o.PublishedGitHubOrg)
-	log.Printf("  PublishedGitHubRepo: %q", o.PublishedGitHubRepo)
-	log.Printf("  CosignPath: %q", o.CosignPath)
-	log.Printf("  SkipSigning: %v", o.SkipSigning)
-	log.Printf("  SigningKMSKey: %q", o.SigningKMSKey)
-	log.Printf("  PublishActions: %q", strings.Join(o.PublishActions, ","))
+	slog.Info("GCB Publish options",
+		"Bucket", o.Bucket,
+		"ReleaseName", o.ReleaseName,
+		"MetadataFileName", o.MetadataFileName,
+		"StrictMetadata", o.StrictMetadata,
+		"ImageTarPrefix", o.ImageTarPrefix,
+		"ImageTarSuffix", o.ImageTarSuffix,
+		"UnpackParallelism", o.UnpackParallelism,
+		"CompareToPrevious", o.CompareToPrevious,
+		"CompareChartsTo", o.CompareChartsTo,
+		"CompareChartsToReleaseType", o.CompareChartsToReleaseType,
+		"AllowMissingProvenance", o.AllowMissingProvenance,
+		"NoMock", o.NoMock,
+		"PublishedImageRepo", o.PublishedImageRepository,
+		"PublishedImageMirrorRepos", o.PublishedImageMirrorRepositories,
+		"PushConcurrency", o.PushConcurrency,
+		"PushRetryInitialInterval", o.PushRetryInitialInterval,
+		"PushRetryMaxInterval", o.PushRetryMaxInterval,
+		"PushRetryMaxTries", o.PushRetryMaxTries,
+		"PublishedHelmChartGitHubRepo", o.PublishedHelmChartGitHubRepo,
+		"PublishedHelmChartGitHubOwner", o.PublishedHelmChartGitHubOwner,
+		"PublishedHelmChartGitHubBranch", o.PublishedHelmChartGitHubBranch,
+		"PublishedHelmChartOCIRegistry", o.PublishedHelmChartOCIRegistry,
+		"PublishedHelmChartRepoBucket", o.PublishedHelmChartRepoBucket,
+		"PublishedHelmChartRepoURL", o.PublishedHelmChartRepoURL,
+		"PublishedGitHubOrg", o.PublishedGitHubOrg,
+		"PublishedGitHubRepo", o.PublishedGitHubRepo,
+		"PublishedCmctlGitHubOrg", o.PublishedCmctlGitHubOrg,
+		"PublishedCmctlGitHubRepo", o.PublishedCmctlGitHubRepo,
+		"SkipReleaseNotes", o.SkipReleaseNotes,
+		"PreviousReleaseTag", o.PreviousReleaseTag,
+		"Prerelease", o.Prerelease,
+		"Latest", o.Latest,
+		"CosignPath", o.CosignPath,
+		"CosignVersion", o.CosignVersion,
+		"CosignSHA256", o.CosignSHA256,
+		"SBOMFormat", o.SBOMFormat,
+		"SyftPath", o.SyftPath,
+		"VerifyHelmChart", o.VerifyHelmChart,
+		"VerifyHelmChartRepo", o.VerifyHelmChartRepo,
+		"HelmPath", o.HelmPath,
+		"DownloadURLsFormat", o.DownloadURLsFormat,
+		"DownloadURLsOutput", o.DownloadURLsOutput,
+		"DigestsOutput", o.DigestsOutput,
+		"PinImageDigests", o.PinImageDigests,
+		"UploadDigests", o.UploadDigests,
+		"SkipSigning", o.SkipSigning,
+		"SigningKMSKey", o.SigningKMSKey,
+		"SigningMode", o.SigningMode,
+		"KeylessFulcioURL", o.KeylessFulcioURL,
+		"KeylessRekorURL", o.KeylessRekorURL,
+		"KeylessServiceAccount", o.KeylessServiceAccount,
+		"VerifyMetadataSignature", o.VerifyMetadataSignature,
+		"PublishActions", o.PublishActions,
+		"ResumeFromAction", o.ResumeFromAction,
+		"IgnorePublishState", o.IgnorePublishState,
+	)
 }
 
 func allPublishActionNames() []string {
@@ -223,9 +622,12 @@ func allPublishActionNames() []string {
 // canonicalizeAndVerifyPublishActions converts a list of raw actions into
 // a slice of canonical action names (whitespace removed, lowercased), returning an error
 // if any of the actions don't correspond to known actions. Supports removing actions via a prefix of "-"
-// Actions are returned in alphabetical order
+// Actions are returned in the order they should be run, as given by orderPublishActions.
+// An error is returned if an action is removed while an action which depends on it is
+// still selected.
 func canonicalizeAndVerifyPublishActions(rawActions []string) ([]string, error) {
 	actions := sets.NewString()
+	removed := sets.NewString()
 
 	for _, rawAction := range rawActions {
 		action := strings.ToLower(strings.TrimSpace(rawAction))
@@ -236,6 +638,7 @@ func canonicalizeAndVerifyPublishActions(rawActions []string) ([]string, error)
 
 		if action == "*" {
 			actions = actions.Insert(allPublishActionNames()...)
+			removed = removed.Delete(allPublishActionNames()...)
 			continue
 		}
 
@@ -246,16 +649,112 @@ func canonicalizeAndVerifyPublishActions(rawActions []string) ([]string, error)
 
 		if strings.HasPrefix(action, "-") {
 			actions = actions.Delete(strings.TrimPrefix(action, "-"))
+			removed = removed.Insert(strings.TrimPrefix(action, "-"))
 		} else {
 			actions = actions.Insert(action)
+			removed = removed.Delete(action)
+		}
+	}
+
+	for _, action := range actions.List() {
+		for _, dep := range publishActionDependencies[action] {
+			if removed.Has(dep) {
+				return nil, fmt.Errorf("cannot remove publish action %q as %q depends on it; also remove %q, or use --resume-from-action=%s if %q already completed", dep, action, action, action, dep)
+			}
+		}
+	}
+
+	return orderPublishActions(actions.List())
+}
+
+// publishActionDependencies maps publish actions to the actions which must run
+// before them if both are selected.
+var publishActionDependencies = map[string][]string{
+	// the GitHub release contains manifests which reference the published
+	// images, so they must exist before it's created
+	"githubrelease": {"pushcontainerimages"},
+}
+
+// orderPublishActions sorts the given canonical actions topologically
+// according to publishActionDependencies, so that every action runs after the
+// selected actions it depends on. Actions which don't depend on each other run
+// in alphabetical order. An error is returned if the dependencies of the
+// selected actions form a cycle.
+func orderPublishActions(actions []string) ([]string, error) {
+	selected := sets.NewString(actions...)
+	done := sets.NewString()
+
+	var ordered []string
+	for done.Len() < selected.Len() {
+		next := ""
+		for _, action := range selected.Difference(done).List() {
+			ready := true
+			for _, dep := range publishActionDependencies[action] {
+				if selected.Has(dep) && !done.Has(dep) {
+					ready = false
+					break
+				}
+			}
+
+			if ready {
+				next = action
+				break
+			}
+		}
+
+		if next == "" {
+			return nil, fmt.Errorf("publish actions %s have circular dependencies", strings.Join(selected.Difference(done).List(), ", "))
+		}
+
+		ordered = append(ordered, next)
+		done.Insert(next)
+	}
+
+	return ordered, nil
+}
+
+// resumePublishActions removes any actions from the given canonical, ordered
+// list of actions which would run before resumeFrom if every action were
+// selected. If resumeFrom is empty, actions are returned unchanged. An error
+// is returned if resumeFrom isn't the name of a known action.
+func resumePublishActions(actions []string, resumeFrom string) ([]string, error) {
+	resumeFrom = strings.ToLower(strings.TrimSpace(resumeFrom))
+	if resumeFrom == "" {
+		return actions, nil
+	}
+
+	if _, ok := publishActionMap[resumeFrom]; !ok {
+		return nil, fmt.Errorf("unknown action %q to resume from; options: %s", resumeFrom, strings.Join(allPublishActionNames(), ", "))
+	}
+
+	allActions, err := orderPublishActions(allPublishActionNames())
+	if err != nil {
+		return nil, err
+	}
+
+	position := map[string]int{}
+	for i, action := range allActions {
+		position[action] = i
+	}
+
+	var remaining []string
+	for _, action := range actions {
+		if position[action] < position[resumeFrom] {
+			slog.Info("Skipping publish action as resuming from a later action", "action", action, "resume_from", resumeFrom)
+			continue
 		}
+
+		remaining = append(remaining, action)
 	}
 
-	return actions.List(), nil
+	return remaining, nil
 }
 
 var publishActionMap map[string]publishAction = map[string]publishAction{
+	"cmctlgithubrelease":  pushCmctlGitHubRelease,
+	"helmchartoci":        pushHelmChartOCI,
 	"helmchartpr":         pushHelmChartPR,
+	"helmchartrepo":       pushHelmChartRepository,
 	"githubrelease":       pushGitHubRelease,
 	"pushcontainerimages": pushContainerImages,
 }
@@ -270,9 +769,13 @@ func gcbPublishCmd(rootOpts *rootOptions) *cobra.Command {
 		SilenceUsage: true,
 		PreRun: func(cmd *cobra.Command, args []string) {
 			o.print()
-			log.Printf("---")
+			slog.Info("---")
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if o.CosignVersion != "" && cmd.Flags().Changed("cosign-path") {
+				return fmt.Errorf("cannot set both --cosign-path and --cosign-version")
+			}
+
 			return runGCBPublish(rootOpts, o)
 		},
 	}
@@ -283,33 +786,102 @@ func gcbPublishCmd(rootOpts *rootOptions) *cobra.Command {
 func runGCBPublish(rootOpts *rootOptions, o *gcbPublishOptions) error {
 	ctx := context.Background()
 
+	if o.DownloadURLsFormat != downloadURLsFormatMarkdown && o.DownloadURLsFormat != downloadURLsFormatJSON {
+		return fmt.Errorf("unknown download URLs format %q", o.DownloadURLsFormat)
+	}
+
+	if o.CosignVersion != "" {
+		slog.Info("Installing cosign", "version", o.CosignVersion)
+		cosignPath, err := cosign.Install(ctx, cosign.HTTPDownloader, cosign.DefaultCacheDir(), o.CosignVersion, o.CosignSHA256)
+		if err != nil {
+			return fmt.Errorf("failed to install cosign: %w", err)
+		}
+
+		slog.Info("Using cosign binary", "path", cosignPath)
+		o.CosignPath = cosignPath
+	}
+
+	if o.SigningMode != signingModeKMS && o.SigningMode != signingModeKeyless {
+		return fmt.Errorf("unknown signing mode %q; must be one of %q or %q", o.SigningMode, signingModeKMS, signingModeKeyless)
+	}
+
+	if _, err := parseReleaseFlag("prerelease", o.Prerelease); err != nil {
+		return err
+	}
+
+	if _, err := parseReleaseFlag("latest", o.Latest); err != nil {
+		return err
+	}
+
 	if o.SigningKMSKey != "" {
 		if _, err := sign.NewGCPKMSKey(o.SigningKMSKey); err != nil {
 			return err
 		}
+	}
 
-		log.Printf("getting cosign version information")
+	if o.SigningKMSKey != "" || (o.SigningMode == signingModeKeyless && !o.SkipSigning) {
+		slog.Info("getting cosign version information")
 		if err := cosign.Version(ctx, o.CosignPath); err != nil {
 			return fmt.Errorf("failed to query cosign version: %w", err)
 		}
 	}
 
+	if o.SBOMFormat != "" {
+		if err := sbom.ValidateFormat(o.SBOMFormat); err != nil {
+			return err
+		}
+
+		slog.Info("getting syft version information")
+		if err := sbom.Version(ctx, o.SyftPath); err != nil {
+			return fmt.Errorf("failed to query syft version: %w", err)
+		}
+	}
+
 	// fetch the staged release from GCS
-	gcs, err := storage.NewClient(ctx)
+	gcs, err := gcp.NewStorageClient(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to create GCS client: %w", err)
 	}
 
-	bucket := release.NewBucket(gcs.Bucket(o.Bucket), release.DefaultBucketPathPrefix, release.BuildTypeRelease)
+	bucket := release.NewBucket(gcs.Bucket(o.Bucket), release.DefaultBucketPathPrefix, release.BuildTypeRelease).WithMetadataFileName(o.MetadataFileName).WithStrictMetadata(o.StrictMetadata)
+	if err := bucket.CheckAccess(ctx); err != nil {
+		return err
+	}
+
+	if o.VerifyMetadataSignature && !o.SkipSigning {
+		parsedKey, err := sign.NewGCPKMSKey(o.SigningKMSKey)
+		if err != nil {
+			return fmt.Errorf("a signing KMS key is required to verify release metadata: %w", err)
+		}
+
+		bucket.WithMetadataVerifier(kmsMetadataVerifier(parsedKey))
+	}
 
 	staged, err := bucket.GetRelease(ctx, o.ReleaseName)
+	if errors.Is(err, release.ErrMetadataSignature) {
+		return fmt.Errorf("failed to fetch release: %w (releases staged with --skip-signing or before metadata was signed can be published with --verify-metadata-signature=false)", err)
+	}
+
 	if err != nil {
 		return fmt.Errorf("failed to fetch release: %w", err)
 	}
 
-	log.Printf("Release with version %q (%s) will be published", staged.Metadata().ReleaseVersion, staged.Metadata().GitCommitRef)
+	slog.Info("Release will be published", "version", staged.Metadata().ReleaseVersion, "git_ref", staged.Metadata().GitCommitRef)
+
+	o.provenance, err = readReleaseProvenance(ctx, bucket, staged)
+	if err != nil {
+		return err
+	}
+
+	if o.provenance == nil {
+		if staged.Metadata().BuildSource != release.BuildSourceMake && !o.AllowMissingProvenance {
+			return fmt.Errorf("release %q was staged without provenance, refusing to publish it without attestations unless --allow-missing-provenance is set: %w", staged.Name(), release.ErrValidationFailed)
+		}
+
+		slog.Warn("release was staged without provenance, so none will be attached to its images", "release", staged.Name())
+	}
 
-	rel, err := release.Unpack(ctx, staged)
+	rel, err := release.UnpackWithOptions(ctx, staged, o.unpackOptions())
 	if err != nil {
 		return fmt.Errorf("failed to unpack staged release: %w", err)
 	}
@@ -324,48 +896,99 @@ func runGCBPublish(rootOpts *rootOptions, o *gcbPublishOptions) error {
 		return fmt.Errorf("failed to validate unpacked release: %w", err)
 	}
 	if len(violations) > 0 {
-		log.Printf("Release validation failed:")
+		slog.Info("Release validation failed:")
 		for _, v := range violations {
-			log.Printf("  - %s", v)
+			slog.Info("  - " + v)
 		}
-		return fmt.Errorf("release failed validation - refusing to publish")
+		return fmt.Errorf("release failed validation - refusing to publish: %w", release.ErrValidationFailed)
 	}
-	log.Printf("Release validation succeeded!")
+	slog.Info("Release validation succeeded!")
 
-	for name, tars := range rel.ComponentImageBundles {
-		log.Printf("Loading release images for component %q into local docker daemon...", name)
-		for _, t := range tars {
-			if err := docker.Load(ctx, t.Filepath()); err != nil {
-				return err
-			}
+	if o.CompareToPrevious != "" {
+		if err := compareToPreviousRelease(ctx, bucket, o.CompareToPrevious, o.unpackOptions(), rel); err != nil {
+			return err
+		}
+	}
+
+	if o.CompareChartsTo != "" {
+		otherBucket := release.NewBucket(gcs.Bucket(o.Bucket), release.DefaultBucketPathPrefix, o.CompareChartsToReleaseType).WithMetadataFileName(o.MetadataFileName).WithStrictMetadata(o.StrictMetadata)
+		if err := compareChartsToBuild(ctx, otherBucket, o.CompareChartsTo, o.unpackOptions(), rel); err != nil {
+			return err
+		}
+	}
+
+	if o.SBOMFormat != "" {
+		sbomDir, err := os.MkdirTemp("", "cmrel-sbom-")
+		if err != nil {
+			return fmt.Errorf("failed to create directory for SBOMs: %w", err)
+		}
+		defer os.RemoveAll(sbomDir)
+
+		o.sboms, err = generateReleaseSBOMs(ctx, o.SyftPath, o.SBOMFormat, sbomDir, rel)
+		if err != nil {
+			return err
 		}
 	}
 
 	if !o.NoMock {
-		log.Printf("--nomock flag set to false, skipping actually publishing the release")
+		slog.Info("--nomock flag set to false, skipping actually publishing the release")
 		return nil
 	}
 
-	log.Printf("!!! Publishing release artifacts to public repositories !!!")
+	slog.Info("!!! Publishing release artifacts to public repositories !!!")
 
 	// TODO: perform check to ensure we have permission to create releases
 
-	publishFuncs, err := o.PublishActionList()
+	publishState := release.NewPublishState()
+	if o.IgnorePublishState {
+		slog.Info("Ignoring any progress recorded by previous attempts to publish the release")
+	} else {
+		publishState, err = bucket.ReadPublishState(ctx, staged.Name())
+		if err != nil {
+			return err
+		}
+	}
+
+	o.checkpoint = newBucketPublishCheckpoint(publishState, bucket, staged.Name())
+
+	if err := runPublishActions(ctx, o, rel); err != nil {
+		return err
+	}
+
+	// the list of actions has already been verified by runPublishActions
+	publishActionNames, _ := o.PublishActionNames()
+
+	if o.VerifyHelmChart {
+		if err := verifyPublishedHelmCharts(ctx, o, rel, o.helmChartVerifyRepo(publishActionNames)); err != nil {
+			return fmt.Errorf("failed to verify published release: %w", err)
+		}
+	}
+
+	downloadURLs, err := buildPublishedURLs(o, rel, publishActionNames).Render(o.DownloadURLsFormat)
 	if err != nil {
-		return fmt.Errorf("failed to parse published artifacts list: %w", err)
+		return err
 	}
 
-	for _, publishFunc := range publishFuncs {
-		err = publishFunc(ctx, o, rel)
+	slog.Info("Download URLs for the published release:")
+	logLines(downloadURLs)
 
-		if err != nil {
-			return errorDuringPublish(err)
+	if o.DownloadURLsOutput != "" {
+		if err := os.WriteFile(o.DownloadURLsOutput, []byte(downloadURLs), 0644); err != nil {
+			return fmt.Errorf("failed to write download URLs to %q: %w", o.DownloadURLsOutput, err)
+		}
+	}
+
+	if (o.DigestsOutput != "" || o.UploadDigests) && sets.NewString(publishActionNames...).Has("pushcontainerimages") {
+		if err := recordPublishedDigests(ctx, o, bucket, staged.Name(), rel); err != nil {
+			return err
 		}
 	}
 
-	log.Println()
-	log.Printf("+++++++++ Publishing release completed successfully! +++++++++")
-	log.Printf("You MUST now perform the following manual tasks:\n%s", o.ManualActionText())
+	o.checkpoint.recordManualActions(ctx, o.ManualActionText())
+
+	slog.Info("+++++++++ Publishing release completed successfully! +++++++++")
+	slog.Info("You MUST now perform the following manual tasks:")
+	logLines(o.ManualActionText())
 
 	return nil
 }
@@ -391,7 +1014,7 @@ func pushHelmChartPR(ctx context.Context, o *gcbPublishOptions, rel *release.Unp
 		return fmt.Errorf("error in preflight checks for Helm GitHub repository: %v", err)
 	}
 
-	log.Printf("Pushing Helm chart(s)")
+	slog.Info("Pushing Helm chart(s)")
 
 	prURLForHelmCharts, err := helmRepo.Publish(ctx, rel.ReleaseName, rel.Charts...)
 	if err != nil {
@@ -403,201 +1026,940 @@ func pushHelmChartPR(ctx context.Context, o *gcbPublishOptions, rel *release.Unp
 	return nil
 }
 
-func pushGitHubRelease(ctx context.Context, o *gcbPublishOptions, rel *release.Unpacked) error {
-	githubClient, err := o.GitHubClient(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to create github client for creating github release: %w", err)
-	}
+// pushHelmChartOCI pushes each of the charts in the release to the configured
+// OCI registry, using credentials from the default keychain.
+func pushHelmChartOCI(ctx context.Context, o *gcbPublishOptions, rel *release.Unpacked) error {
+	slog.Info("Pushing Helm charts to OCI registry", "count", len(rel.Charts), "registry", o.PublishedHelmChartOCIRegistry)
 
-	// open manifest files ahead of time to ensure they are available on disk
-	manifestsByName := map[string]*os.File{}
-	for _, manifest := range rel.YAMLs {
-		f, err := os.Open(manifest.Path())
+	for _, chart := range rel.Charts {
+		if pushed, done := o.checkpoint.item("helmchartoci", "chart:"+chart.PackageFileName()); done {
+			slog.Info("Skipping pushing Helm chart as it was pushed by a previous run", "chart", chart.PackageFileName(), "ref", pushed)
+			continue
+		}
+
+		var pushed string
+		err := retry.Do(ctx, func() error {
+			var err error
+			pushed, err = helm.PushChartOCI(ctx, chart, o.PublishedHelmChartOCIRegistry, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+			return err
+		})
 		if err != nil {
-			return fmt.Errorf("failed to open manifest file to be uploaded: %v", err)
+			return fmt.Errorf("failed to push Helm chart %q to OCI registry: %w", chart.PackageFileName(), err)
 		}
-		defer f.Close()
-		manifestsByName[filepath.Base(manifest.Path())] = f
+
+		slog.Info("Pushed Helm chart", "chart", chart.PackageFileName(), "ref", pushed)
+		o.checkpoint.completeItem(ctx, "helmchartoci", "chart:"+chart.PackageFileName(), pushed)
 	}
 
-	log.Printf("Creating a draft GitHub release %q in repository %s/%s", rel.ReleaseVersion, o.PublishedGitHubOrg, o.PublishedGitHubRepo)
+	return nil
+}
+
+// compareToPreviousRelease fetches and unpacks the named previous release and
+// compares rel against it, returning an error if any violations are found.
+func compareToPreviousRelease(ctx context.Context, bucket *release.Bucket, previousName string, unpackOpts release.UnpackOptions, rel *release.Unpacked) error {
+	slog.Info("Fetching previous release to compare against", "release", previousName)
 
-	defaultReleaseBody := "!!! Update this release note body before publishing this draft release!"
-	githubRelease, resp, err := githubClient.Repositories.CreateRelease(ctx, o.PublishedGitHubOrg, o.PublishedGitHubRepo, &github.RepositoryRelease{
-		TagName:         &rel.ReleaseVersion,
-		TargetCommitish: &rel.GitCommitRef,
-		Name:            &rel.ReleaseVersion,
-		Body:            &defaultReleaseBody,
-		Draft:           pointer.Bool(true),
-		// TODO: determine whether this ReleaseVersion is a 'prerelease'
-		Prerelease: nil,
-	})
+	previousStaged, err := bucket.GetRelease(ctx, previousName)
 	if err != nil {
-		return fmt.Errorf("failed to create GitHub release: %v", err)
+		return fmt.Errorf("failed to fetch previous release: %w", err)
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		return fmt.Errorf("unexpected response code when creating GitHub release %d", resp.StatusCode)
+	previous, err := release.UnpackWithOptions(ctx, previousStaged, unpackOpts)
+	if err != nil {
+		return fmt.Errorf("failed to unpack previous release: %w", err)
 	}
 
-	log.Printf("Uploading %d release manifests to GitHub release", len(manifestsByName))
-	for name, f := range manifestsByName {
-		asset, resp, err := githubClient.Repositories.UploadReleaseAsset(ctx, o.PublishedGitHubOrg, o.PublishedGitHubRepo, *githubRelease.ID, &github.UploadOptions{
-			Name: name,
-		}, f)
-		if err != nil {
-			return fmt.Errorf("failed to upload github release asset: %v", err)
-		}
-		if resp.StatusCode < 200 || resp.StatusCode > 299 {
-			return fmt.Errorf("unexpected response code when uploading github release asset %d", resp.StatusCode)
+	violations, warnings := validation.CompareToPrevious(rel, previous)
+	if len(warnings) > 0 {
+		slog.Warn("Comparison to previous release produced warnings:", "previous_version", previous.ReleaseVersion)
+		for _, w := range warnings {
+			slog.Info("  - " + w)
 		}
-		log.Printf("Uploaded asset %q to GitHub release %q", *asset.Name, *githubRelease.Name)
 	}
 
-	if release.CmctlIsShipped(rel.ReleaseVersion) {
-		// Open ctl binary tar files ahead of time to ensure they are available
-		// on disk.
-		ctlBinariesByName := map[string]*os.File{}
-		for _, ctlBinary := range rel.CtlBinaryBundles {
-			f, err := os.Open(ctlBinary.Filepath())
-			if err != nil {
-				return fmt.Errorf("failed to open manifest file to be uploaded: %v", err)
-			}
-
-			defer f.Close()
-
-			ctlBinariesByName[ctlBinary.ArtifactFilename()] = f
-		}
-
-		log.Printf("Uploading %d release binary tars to GitHub release", len(ctlBinariesByName))
-		for name, f := range ctlBinariesByName {
-			asset, resp, err := githubClient.Repositories.UploadReleaseAsset(ctx, o.PublishedGitHubOrg, o.PublishedGitHubRepo, *githubRelease.ID, &github.UploadOptions{
-				Name: name,
-			}, f)
-			if err != nil {
-				return fmt.Errorf("failed to upload github release asset: %v", err)
-			}
-			if resp.StatusCode < 200 || resp.StatusCode > 299 {
-				return fmt.Errorf("unexpected response code when uploading github release asset %d", resp.StatusCode)
-			}
-			log.Printf("Uploaded asset %q to GitHub release %q", *asset.Name, *githubRelease.Name)
+	if len(violations) > 0 {
+		slog.Error("Comparison to previous release failed:", "previous_version", previous.ReleaseVersion)
+		for _, v := range violations {
+			slog.Info("  - " + v)
 		}
-
+		return fmt.Errorf("release failed comparison to previous release - refusing to publish: %w", release.ErrValidationFailed)
 	}
 
-	o.manualActionLogger.Printf("Update the GitHub release with release notes and hit PUBLISH!")
+	slog.Info("Comparison to previous release succeeded!", "previous_version", previous.ReleaseVersion)
+
 	return nil
 }
 
-const registryWaitTime = time.Second * 2
+// compareChartsToBuild fetches and unpacks the named staged build and checks
+// that its Helm charts are identical to those in rel.
+func compareChartsToBuild(ctx context.Context, bucket *release.Bucket, name string, unpackOpts release.UnpackOptions, rel *release.Unpacked) error {
+	slog.Info("Fetching staged build to compare Helm charts against", "release", name)
 
-func retry(ctx context.Context, f func() error) error {
-	operation := func() (struct{}, error) {
-		err := f()
-		return struct{}{}, err
+	otherStaged, err := bucket.GetRelease(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to fetch staged build: %w", err)
 	}
 
-	_, err := backoff.Retry(ctx, operation, backoff.WithBackOff(backoff.NewConstantBackOff(registryWaitTime)), backoff.WithMaxTries(5))
-
-	return err
-}
-
-func pushContainerImages(ctx context.Context, o *gcbPublishOptions, rel *release.Unpacked) error {
-	log.Printf("Pushing arch-specific docker images")
+	other, err := release.UnpackWithOptions(ctx, otherStaged, unpackOpts)
+	if err != nil {
+		return fmt.Errorf("failed to unpack staged build: %w", err)
+	}
 
-	if o.SigningKMSKey == "" && !o.SkipSigning {
-		return fmt.Errorf("must set signing-kms-key or skip-signing in order to sign images")
+	violations, err := validation.CompareCharts(rel, other)
+	if err != nil {
+		return err
 	}
 
-	var pushedContent []string
+	if len(violations) > 0 {
+		slog.Error("Helm charts differ from staged build:", "release", name)
+		for _, v := range violations {
+			slog.Info("  - " + v)
+		}
+		return fmt.Errorf("helm charts are not reproducible - refusing to publish: %w", release.ErrValidationFailed)
+	}
 
-	for name, tars := range rel.ComponentImageBundles {
-		log.Printf("Pushing release images for component %q", name)
-		for _, t := range tars {
-			imageTag := buildImageTag(o.PublishedImageRepository, name, t.Architecture(), rel.ReleaseVersion)
+	slog.Info("Helm charts are identical to those in staged build", "release", name)
 
-			log.Printf("Tagging %q with new name %q", t.RawImageName(), imageTag)
+	return nil
+}
 
-			if err := docker.Tag(ctx, t.RawImageName(), imageTag); err != nil {
-				return err
-			}
+// helmChartVerifyRepo returns the chart repository URL or OCI registry which
+// published charts are verified against, given the publish actions which ran.
+func (o *gcbPublishOptions) helmChartVerifyRepo(actions []string) string {
+	if o.VerifyHelmChartRepo != "" {
+		return o.VerifyHelmChartRepo
+	}
 
-			if err := retry(ctx, func() error { return docker.Push(ctx, imageTag) }); err != nil {
-				return err
-			}
+	actionSet := sets.NewString(actions...)
 
-			// PublishedTag will be used later to refer to the image under the tag we
-			// actually pushed it under
-			t.PublishedTag = imageTag
+	switch {
+	case actionSet.Has("helmchartoci") && o.PublishedHelmChartOCIRegistry != "":
+		return o.PublishedHelmChartOCIRegistry
 
-			log.Printf("Pushed release image %q", imageTag)
-			pushedContent = append(pushedContent, imageTag)
+	case actionSet.Has("helmchartrepo") && o.PublishedHelmChartRepoBucket != "":
+		return o.PublishedHelmChartRepoURL
 
-			// Wait to avoid being rate limited by the registry
-			time.Sleep(registryWaitTime)
-		}
+	default:
+		return release.DefaultHelmChartRepositoryURL
 	}
+}
 
-	// manifest lists can only be created using the docker CLI after the child
-	// images have been pushed to the registry.
-	// Build them all at once, and push them afterwards to avoid releasing an
-	// incomplete set of manifest lists.
-	var builtManifestLists []string
-	log.Printf("Creating multi-arch manifest lists for image components")
-	for name, tars := range rel.ComponentImageBundles {
-		manifestListName := buildManifestListName(o.PublishedImageRepository, name, rel.ReleaseVersion)
-		if err := registry.CreateManifestList(ctx, manifestListName, tars); err != nil {
-			return err
-		}
+// verifyPublishedHelmCharts checks that each of the charts in the release can
+// be fetched from the given chart repository and rendered by helm.
+func verifyPublishedHelmCharts(ctx context.Context, o *gcbPublishOptions, rel *release.Unpacked, repo string) error {
+	verifier := helm.NewChartVerifier(o.HelmPath, repo, func(ctx context.Context, cmd string, args ...string) error {
+		return shell.Command(ctx, "", cmd, args...)
+	})
 
-		builtManifestLists = append(builtManifestLists, manifestListName)
-	}
+	for _, chart := range rel.Charts {
+		slog.Info("Verifying published Helm chart", "chart", chart.Name(), "version", chart.Version(), "repository", repo)
 
-	log.Printf("Pushing all multi-arch manifest lists")
-	for _, manifestListName := range builtManifestLists {
-		log.Printf("Pushing manifest list %q", manifestListName)
-		if err := retry(ctx, func() error { return docker.PushManifestList(ctx, manifestListName) }); err != nil {
+		if err := verifier.Verify(ctx, chart.Name(), chart.Version()); err != nil {
 			return err
 		}
-
-		pushedContent = append(pushedContent, manifestListName)
-		log.Printf("Pushed multi-arch manifest list %q", manifestListName)
-
-		// Wait to avoid being rate limited by the registry
-		time.Sleep(registryWaitTime)
 	}
 
-	if err := signRegistryContent(ctx, o, pushedContent); err != nil {
-		return fmt.Errorf("failed to sign images: %w", err)
-	}
+	slog.Info("Published Helm chart verification succeeded!")
 
 	return nil
 }
 
-func signRegistryContent(ctx context.Context, o *gcbPublishOptions, allContentToSign []string) error {
-	if o.SkipSigning {
-		log.Println("Skipping signing container images / manifest lists as skip-signing is set")
-		return nil
-	}
+func pushGitHubRelease(ctx context.Context, o *gcbPublishOptions, rel *release.Unpacked) error {
+	githubClient, err := o.GitHubClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create github client for creating github release: %w", err)
+	}
+
+	// the manifests attached to the release reference the published images,
+	// so make sure they can all be pulled before creating it
+	slog.Info("Checking that all images and manifest lists for the release exist", "repository", o.PublishedImageRepository)
+	if err := verifyPublishedImagesExist(ctx, remoteDigest, o.PublishedImageRepository, rel); err != nil {
+		return fmt.Errorf("refusing to create GitHub release: %w", err)
+	}
+
+	// check the manifests and ctl binary tars ahead of time to ensure they
+	// are available on disk
+	assets := gitHubReleaseAssetPaths(rel)
+	for name, path := range assets {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("failed to find file %q to be uploaded: %v", name, err)
+		}
+	}
+
+	workDir, err := os.MkdirTemp("", "cmrel-github-release-")
+	if err != nil {
+		return fmt.Errorf("failed to create directory for checksums and signatures: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	if o.PinImageDigests {
+		slog.Info("Writing digest-pinned variants of manifests and Helm chart values")
+		pinned, err := writeDigestPinnedAssets(ctx, o, remoteDigest, rel, workDir)
+		if err != nil {
+			return fmt.Errorf("failed to pin image digests: %w", err)
+		}
+
+		for name, path := range pinned {
+			if _, ok := assets[name]; ok {
+				return fmt.Errorf("digest-pinned asset %q has the same name as an existing GitHub release asset", name)
+			}
+
+			assets[name] = path
+		}
+	}
+
+	checksumsPath, err := writeGitHubReleaseChecksums(assets, workDir, gitHubReleaseChecksumsFileName)
+	if err != nil {
+		return err
+	}
+
+	assets[gitHubReleaseChecksumsFileName] = checksumsPath
+
+	// sign assets before creating the release so that a signing failure
+	// doesn't leave a partially uploaded release behind
+	target := o.gitHubReleaseRepo()
+
+	signatures, err := signGitHubReleaseAssets(ctx, o, target, assets, workDir)
+	if err != nil {
+		return err
+	}
+
+	prerelease, latest, err := o.gitHubReleaseFlags(ctx, githubClient, target, rel.ReleaseVersion)
+	if err != nil {
+		return err
+	}
+
+	githubRelease, err := createOrResumeGitHubRelease(ctx, o, githubClient, target, func() *github.RepositoryRelease {
+		releaseBody := gitHubReleaseBody(ctx, o, githubClient, rel)
+		return &github.RepositoryRelease{
+			TagName:         &rel.ReleaseVersion,
+			TargetCommitish: &rel.GitCommitRef,
+			Name:            &rel.ReleaseVersion,
+			Body:            &releaseBody,
+			Draft:           pointer.Bool(true),
+			Prerelease:      pointer.Bool(prerelease),
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if !latest {
+		slog.Info("Marking GitHub release so that it won't become the latest release when published", "version", rel.ReleaseVersion)
+		if err := setGitHubReleaseNotLatest(ctx, githubClient, target.org, target.repo, githubRelease.GetID()); err != nil {
+			return err
+		}
+	}
+
+	slog.Info("Uploading release manifests, binary tars and checksums to GitHub release", "count", len(assets))
+	if err := uploadGitHubReleaseAssets(ctx, o, githubClient, target, githubRelease, assets); err != nil {
+		return err
+	}
+
+	slog.Info("Uploading signatures to GitHub release", "count", len(signatures))
+	if err := uploadGitHubReleaseAssets(ctx, o, githubClient, target, githubRelease, signatures); err != nil {
+		return err
+	}
+
+	if err := uploadSBOMAssets(ctx, o, githubClient, githubRelease); err != nil {
+		return err
+	}
+
+	if o.SkipReleaseNotes {
+		o.manualActionLogger.Printf("Update the GitHub release with release notes and hit PUBLISH!")
+	} else {
+		o.manualActionLogger.Printf("Review the generated release notes on the GitHub release and hit PUBLISH!")
+	}
+
+	if !latest {
+		o.manualActionLogger.Printf("Check that 'Set as the latest release' is unchecked before publishing the GitHub release, as %s shouldn't be marked as latest", rel.ReleaseVersion)
+	}
+
+	return nil
+}
+
+// gitHubReleaseFlags returns whether the GitHub release for version should be
+// marked as a prerelease and as the latest release, according to the
+// --prerelease and --latest flags. The tags in the target repository are only
+// listed if --latest is "auto".
+func (o *gcbPublishOptions) gitHubReleaseFlags(ctx context.Context, githubClient *github.Client, target gitHubReleaseRepo, version string) (bool, bool, error) {
+	prerelease, err := parseReleaseFlag("prerelease", o.Prerelease)
+	if err != nil {
+		return false, false, err
+	}
+
+	latest, err := parseReleaseFlag("latest", o.Latest)
+	if err != nil {
+		return false, false, err
+	}
+
+	var tags []string
+	if latest == nil {
+		tags, err = notes.ListTags(ctx, githubClient, target.org, target.repo)
+		if err != nil {
+			return false, false, fmt.Errorf("failed to determine whether %q is the latest release, set --latest to skip this check: %w", version, err)
+		}
+	}
+
+	isPrerelease, isLatest, err := gitHubReleaseFlags(version, prerelease, latest, tags)
+	if err != nil {
+		return false, false, err
+	}
+
+	slog.Info("Computed GitHub release flags", "version", version, "prerelease", isPrerelease, "latest", isLatest)
+
+	return isPrerelease, isLatest, nil
+}
+
+// gitHubReleaseRepo identifies a repository which a draft GitHub release is
+// created in, along with the publish action which records progress in
+// creating it.
+type gitHubReleaseRepo struct {
+	action string
+	org    string
+	repo   string
+}
+
+// gitHubReleaseRepo returns the repository which the cert-manager GitHub
+// release is created in.
+func (o *gcbPublishOptions) gitHubReleaseRepo() gitHubReleaseRepo {
+	return gitHubReleaseRepo{
+		action: "githubrelease",
+		org:    o.PublishedGitHubOrg,
+		repo:   o.PublishedGitHubRepo,
+	}
+}
+
+// gitHubReleaseAssetPaths returns the paths of the YAML manifests and ctl
+// binary bundles in rel which are uploaded to the GitHub release, keyed by
+// asset name.
+func gitHubReleaseAssetPaths(rel *release.Unpacked) map[string]string {
+	assets := map[string]string{}
+	for _, manifest := range rel.YAMLs {
+		assets[filepath.Base(manifest.Path())] = manifest.Path()
+	}
+
+	if release.CmctlIsShipped(rel.ReleaseVersion) {
+		for _, ctlBinary := range rel.CtlBinaryBundles {
+			assets[ctlBinary.ArtifactFilename()] = ctlBinary.Filepath()
+		}
+	}
+
+	return assets
+}
+
+// signGitHubReleaseAssets uses cosign to create a detached signature for each
+// of the given assets with the KMS key, writing them to dir. The signatures
+// are returned keyed by asset name, which is the name of the signed asset
+// with a ".sig" suffix.
+// Assets whose signatures were uploaded by a previous run aren't signed
+// again, and nothing is signed if signing is skipped.
+func signGitHubReleaseAssets(ctx context.Context, o *gcbPublishOptions, target gitHubReleaseRepo, assets map[string]string, dir string) (map[string]string, error) {
+	signatures := map[string]string{}
+
+	if o.SkipSigning {
+		slog.Info("Skipping signing GitHub release assets as skip-signing is set")
+		return signatures, nil
+	}
 
-	log.Println("Signing container images")
+	if o.SigningKMSKey == "" {
+		return nil, fmt.Errorf("must set signing-kms-key or skip-signing in order to sign GitHub release assets")
+	}
 
 	parsedKey, err := sign.NewGCPKMSKey(o.SigningKMSKey)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range sets.StringKeySet(assets).List() {
+		signatureName := cosign.SignatureFileName(name)
+
+		if _, done := o.checkpoint.item(target.action, "asset:"+signatureName); done {
+			slog.Info("Skipping signing as its signature was uploaded by a previous run", "asset", name)
+			continue
+		}
+
+		signaturePath := filepath.Join(dir, signatureName)
+
+		slog.Info("Signing asset", "asset", name)
+		if err := cosign.SignBlob(ctx, o.CosignPath, assets[name], signaturePath, parsedKey); err != nil {
+			return nil, fmt.Errorf("failed to sign GitHub release asset %q: %w", name, err)
+		}
+
+		signatures[signatureName] = signaturePath
+	}
+
+	return signatures, nil
+}
+
+// uploadGitHubReleaseAssets uploads the files at the given paths, keyed by
+// asset name, to the GitHub release in target in alphabetical order, skipping
+// those which were uploaded by a previous run.
+func uploadGitHubReleaseAssets(ctx context.Context, o *gcbPublishOptions, githubClient *github.Client, target gitHubReleaseRepo, githubRelease *github.RepositoryRelease, assets map[string]string) error {
+	for _, name := range sets.StringKeySet(assets).List() {
+		if _, done := o.checkpoint.item(target.action, "asset:"+name); done {
+			slog.Info("Skipping uploading asset as it was uploaded by a previous run", "asset", name)
+			o.recordUploadedAsset(target.action, name)
+			continue
+		}
+
+		if err := uploadGitHubReleaseAsset(ctx, githubClient, target, githubRelease, name, assets[name]); err != nil {
+			return err
+		}
+
+		o.recordUploadedAsset(target.action, name)
+		o.checkpoint.completeItem(ctx, target.action, "asset:"+name, "")
+	}
+
+	return nil
+}
+
+// recordUploadedAsset records that the named asset was uploaded to a GitHub
+// release by the given publish action.
+func (o *gcbPublishOptions) recordUploadedAsset(action, name string) {
+	if o.uploadedAssets == nil {
+		o.uploadedAssets = map[string]sets.String{}
+	}
+
+	if o.uploadedAssets[action] == nil {
+		o.uploadedAssets[action] = sets.NewString()
+	}
+
+	o.uploadedAssets[action].Insert(name)
+}
+
+// uploadedGitHubReleaseAssets returns the names of the assets uploaded to a
+// GitHub release by the given publish action, either by this run or by a
+// previous one, in alphabetical order.
+func (o *gcbPublishOptions) uploadedGitHubReleaseAssets(action string) []string {
+	return sets.NewString(o.checkpoint.itemsWithPrefix(action, "asset:")...).Union(o.uploadedAssets[action]).List()
+}
+
+// uploadGitHubReleaseAsset uploads the file at path to githubRelease as the
+// named asset. Since uploads aren't idempotent, an asset left behind by a
+// failed attempt is reused if it was uploaded completely, and deleted
+// otherwise, before the upload is retried.
+func uploadGitHubReleaseAsset(ctx context.Context, githubClient *github.Client, target gitHubReleaseRepo, githubRelease *github.RepositoryRelease, name, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat file to be uploaded: %v", err)
+	}
+
+	var asset *github.ReleaseAsset
+	attempts := 0
+	err = retry.Do(ctx, func() error {
+		attempts++
+		if attempts > 1 {
+			existing, err := reuseOrDeleteGitHubReleaseAsset(ctx, githubClient, target, githubRelease.GetID(), name, info.Size())
+			if err != nil {
+				return err
+			}
+
+			if existing != nil {
+				asset = existing
+				return nil
+			}
+		}
+
+		// the file is reopened for each attempt, since a failed upload may
+		// have read some of it
+		f, err := os.Open(path)
+		if err != nil {
+			return retry.Permanent(fmt.Errorf("failed to open file to be uploaded: %v", err))
+		}
+		defer f.Close()
+
+		var resp *github.Response
+		asset, resp, err = githubClient.Repositories.UploadReleaseAsset(ctx, target.org, target.repo, *githubRelease.ID, &github.UploadOptions{
+			Name: name,
+		}, f)
+
+		// an earlier attempt which appeared to fail may have uploaded the
+		// asset anyway, in which case GitHub refuses to upload it again
+		if err != nil && resp != nil && resp.StatusCode == http.StatusUnprocessableEntity {
+			existing, lookupErr := reuseOrDeleteGitHubReleaseAsset(ctx, githubClient, target, githubRelease.GetID(), name, info.Size())
+			if lookupErr != nil {
+				return lookupErr
+			}
+
+			if existing != nil {
+				asset = existing
+				return nil
+			}
+
+			// any incomplete asset has now been deleted, so the upload can
+			// be retried
+			return err
+		}
+
+		return retryableGitHubError(resp, err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload github release asset: %v", err)
+	}
+
+	slog.Info("Uploaded asset to GitHub release", "asset", asset.GetName(), "release", githubRelease.GetName())
+	return nil
+}
+
+// reuseOrDeleteGitHubReleaseAsset looks for the named asset in the GitHub
+// release with the given ID. If it was uploaded completely, with the expected
+// size, it is returned so that it can be reused. Otherwise it is deleted, so
+// that it doesn't block the asset being uploaded again, and nil is returned.
+func reuseOrDeleteGitHubReleaseAsset(ctx context.Context, githubClient *github.Client, target gitHubReleaseRepo, releaseID int64, name string, size int64) (*github.ReleaseAsset, error) {
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		assets, resp, err := githubClient.Repositories.ListReleaseAssets(ctx, target.org, target.repo, releaseID, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list assets of GitHub release: %w", err)
+		}
+
+		for _, asset := range assets {
+			if asset.GetName() != name {
+				continue
+			}
+
+			if asset.GetState() == "uploaded" && int64(asset.GetSize()) == size {
+				slog.Info("Reusing asset uploaded to the GitHub release by an earlier attempt", "asset", name)
+				return asset, nil
+			}
+
+			slog.Info("Deleting incomplete asset left on the GitHub release by an earlier attempt", "asset", name)
+			if _, err := githubClient.Repositories.DeleteReleaseAsset(ctx, target.org, target.repo, asset.GetID()); err != nil {
+				return nil, fmt.Errorf("failed to delete incomplete GitHub release asset %q: %w", name, err)
+			}
+
+			return nil, nil
+		}
+
+		if resp.NextPage == 0 {
+			return nil, nil
+		}
+
+		opts.Page = resp.NextPage
+	}
+}
+
+// retryableGitHubError returns the error from a GitHub API request, marked as
+// permanent unless the request may succeed if retried: server errors and rate
+// limiting are retried, but other client errors are not.
+func retryableGitHubError(resp *github.Response, err error) error {
+	if err == nil {
+		if resp != nil && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+			return retry.Permanent(fmt.Errorf("unexpected response code %d", resp.StatusCode))
+		}
+
+		return nil
+	}
+
+	if resp != nil && resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return retry.Permanent(err)
+	}
+
+	return err
+}
+
+// findDraftGitHubRelease returns the draft release for the given tag in
+// target, or nil if there isn't one. Draft releases can't be fetched by tag,
+// so every release is listed.
+func findDraftGitHubRelease(ctx context.Context, githubClient *github.Client, target gitHubReleaseRepo, tag string) (*github.RepositoryRelease, error) {
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		releases, resp, err := githubClient.Repositories.ListReleases(ctx, target.org, target.repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list GitHub releases: %w", err)
+		}
+
+		for _, r := range releases {
+			if r.GetDraft() && r.GetTagName() == tag {
+				return r, nil
+			}
+		}
+
+		if resp.NextPage == 0 {
+			return nil, nil
+		}
+
+		opts.Page = resp.NextPage
+	}
+}
+
+// createOrResumeGitHubRelease creates the draft GitHub release returned by
+// newRelease in target, or fetches the draft release created by a previous
+// run if there was one.
+func createOrResumeGitHubRelease(ctx context.Context, o *gcbPublishOptions, githubClient *github.Client, target gitHubReleaseRepo, newRelease func() *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	if rawID, done := o.checkpoint.item(target.action, "release"); done {
+		id, err := strconv.ParseInt(rawID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GitHub release ID %q recorded by a previous run: %w", rawID, err)
+		}
+
+		slog.Info("Resuming GitHub release created by a previous run", "id", id)
+
+		githubRelease, _, err := githubClient.Repositories.GetRelease(ctx, target.org, target.repo, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch GitHub release created by a previous run: %v", err)
+		}
+
+		return githubRelease, nil
+	}
+
+	draft := newRelease()
+
+	slog.Info("Creating a draft GitHub release", "release", draft.GetName(), "repository", target.org+"/"+target.repo)
+
+	var githubRelease *github.RepositoryRelease
+	attempts := 0
+	err := retry.Do(ctx, func() error {
+		attempts++
+		if attempts > 1 {
+			// creating a release isn't idempotent, and an earlier attempt
+			// which appeared to fail may have created it anyway
+			existing, err := findDraftGitHubRelease(ctx, githubClient, target, draft.GetTagName())
+			if err != nil {
+				return err
+			}
+
+			if existing != nil {
+				slog.Info("Reusing draft GitHub release created by an earlier attempt", "id", existing.GetID())
+				githubRelease = existing
+				return nil
+			}
+		}
+
+		var resp *github.Response
+		var err error
+		githubRelease, resp, err = githubClient.Repositories.CreateRelease(ctx, target.org, target.repo, draft)
+		return retryableGitHubError(resp, err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitHub release: %v", err)
+	}
+
+	o.checkpoint.completeItem(ctx, target.action, "release", strconv.FormatInt(githubRelease.GetID(), 10))
+
+	return githubRelease, nil
+}
+
+// defaultReleaseBody is the body of draft GitHub releases when release notes
+// aren't generated.
+const defaultReleaseBody = "!!! Update this release note body before publishing this draft release!"
+
+// gitHubReleaseBody returns the body of the draft GitHub release for rel,
+// generated from the PRs merged since the previous release unless
+// SkipReleaseNotes is set. Failing to generate release notes doesn't stop the
+// release from being published, so the placeholder body is used instead.
+func gitHubReleaseBody(ctx context.Context, o *gcbPublishOptions, githubClient *github.Client, rel *release.Unpacked) string {
+	if o.SkipReleaseNotes {
+		return defaultReleaseBody
+	}
+
+	previousTag := o.PreviousReleaseTag
+	if previousTag == "" {
+		var err error
+		previousTag, err = notes.FindPreviousTag(ctx, githubClient, o.PublishedGitHubOrg, o.PublishedGitHubRepo, rel.ReleaseVersion)
+		if err != nil {
+			slog.Warn("failed to find the previous release to generate release notes from", "error", err)
+			return defaultReleaseBody
+		}
+	}
+
+	slog.Info("Generating release notes", "from", previousTag, "to", rel.GitCommitRef)
+
+	body, err := notes.Generate(ctx, githubClient, o.PublishedGitHubOrg, o.PublishedGitHubRepo, previousTag, rel.GitCommitRef)
+	if err != nil {
+		slog.Warn("failed to generate release notes", "error", err)
+		return defaultReleaseBody
+	}
+
+	return fmt.Sprintf("!!! Review these generated release notes before publishing this draft release!\n\nChanges since %s:\n\n%s", previousTag, body)
+}
+
+func pushContainerImages(ctx context.Context, o *gcbPublishOptions, rel *release.Unpacked) error {
+	slog.Info("Pushing arch-specific docker images")
+
+	if o.SigningMode == signingModeKMS && o.SigningKMSKey == "" && !o.SkipSigning {
+		return fmt.Errorf("must set signing-kms-key, skip-signing or signing-mode=%s in order to sign images", signingModeKeyless)
+	}
+
+	repos := o.publishedImageRepositories()
+	components := sortedComponentNames(rel)
+
+	pusher := o.pusher()
+
+	// tars and tarComponents hold every image to push and the component it
+	// belongs to, in the order the images are pushed in
+	var tars []*images.Tar
+	var tarComponents []string
+	for _, name := range components {
+		for _, t := range rel.ComponentImageBundles[name] {
+			tars = append(tars, t)
+			tarComponents = append(tarComponents, name)
+		}
+	}
+
+	// pushed records the repositories each image was pushed to. An image
+	// which fails to push to some repositories is still published as long as
+	// it was pushed to at least one.
+	pushed := make([][]string, len(tars))
+
+	slog.Info("Pushing release images", "count", len(tars), "concurrency", o.PushConcurrency)
+	if err := forEachConcurrently(ctx, len(tars), o.PushConcurrency, func(ctx context.Context, i int) error {
+		t, name := tars[i], tarComponents[i]
+
+		img, err := docker.Load(t.Filepath())
+		if err != nil {
+			return err
+		}
+
+		pushed[i], err = forEachRepository(repos, func(repo string) error {
+			return pushImage(ctx, o, pusher, img, buildImageTag(repo, name, t.Architecture(), rel.ReleaseVersion))
+		})
+		if err != nil {
+			return fmt.Errorf("failed to push release image for component %q and architecture %q: %w", name, t.Architecture(), err)
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	pushedRepos := map[*images.Tar][]string{}
+	for i, t := range tars {
+		pushedRepos[t] = pushed[i]
+	}
+
+	// manifest lists are only pushed to repositories which every one of their
+	// child images was pushed to.
+	// Build them all at once, and push them afterwards to avoid releasing an
+	// incomplete set of manifest lists.
+	manifestLists := map[string]v1.ImageIndex{}
+	builtRepos := map[string][]string{}
+	slog.Info("Creating multi-arch manifest lists for image components")
+	for _, name := range components {
+		tars := rel.ComponentImageBundles[name]
+
+		built := reposWithAllImages(repos, tars, pushedRepos)
+		if len(built) == 0 {
+			return fmt.Errorf("failed to create manifest list for component %q: no image repository has every image of the component", name)
+		}
+
+		idx, err := registry.BuildManifestList(tars)
+		if err != nil {
+			return fmt.Errorf("failed to create manifest list for component %q: %w", name, err)
+		}
+
+		manifestLists[name] = idx
+		builtRepos[name] = built
+	}
+
+	slog.Info("Pushing all multi-arch manifest lists")
+	pushedLists := make([][]string, len(components))
+	if err := forEachConcurrently(ctx, len(components), o.PushConcurrency, func(ctx context.Context, i int) error {
+		name := components[i]
+
+		var err error
+		pushedLists[i], err = forEachRepository(builtRepos[name], func(repo string) error {
+			return pushManifestList(ctx, o, pusher, manifestLists[name], buildManifestListName(repo, name, rel.ReleaseVersion))
+		})
+		if err != nil {
+			return fmt.Errorf("failed to push manifest list for component %q: %w", name, err)
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	pushedManifestListRepos := map[string][]string{}
+	for i, name := range components {
+		pushedManifestListRepos[name] = pushedLists[i]
+	}
+
+	// PublishedTag will be used later to refer to each image under the tag we
+	// actually pushed it under, preferring the primary repository
+	for _, name := range components {
+		for _, t := range rel.ComponentImageBundles[name] {
+			t.PublishedTag = buildImageTag(pushedRepos[t][0], name, t.Architecture(), rel.ReleaseVersion)
+		}
+	}
+
+	if err := attachImageSBOMs(ctx, o, rel, pushedRepos); err != nil {
+		return err
+	}
+
+	if err := signRegistryContent(ctx, o, rel, pushedRepos, pushedManifestListRepos); err != nil {
+		return fmt.Errorf("failed to sign images: %w", err)
+	}
+
+	if err := attestRegistryContent(ctx, o, rel, pushedRepos, pushedManifestListRepos); err != nil {
+		return fmt.Errorf("failed to attach provenance to images: %w", err)
+	}
+
+	return nil
+}
+
+// forEachConcurrently calls f with each index from 0 to n-1, with at most
+// concurrency calls running at once. If any call fails, the context passed to
+// the remaining calls is cancelled and the first error is returned.
+func forEachConcurrently(ctx context.Context, n, concurrency int, f func(ctx context.Context, i int) error) error {
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(max(concurrency, 1))
+
+	for i := 0; i < n; i++ {
+		g.Go(func() error {
+			return f(ctx, i)
+		})
+	}
+
+	return g.Wait()
+}
+
+// forEachRepository calls f for each of the given image repositories in turn,
+// returning the repositories for which it succeeded. Failures are logged, and
+// an error is only returned if f failed for every repository.
+func forEachRepository(repos []string, f func(repo string) error) ([]string, error) {
+	if len(repos) == 0 {
+		return nil, fmt.Errorf("no image repositories to publish to")
+	}
+
+	var succeeded []string
+	var errs []error
+	for _, repo := range repos {
+		if err := f(repo); err != nil {
+			slog.Warn("failed to publish to image repository", "repository", repo, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", repo, err))
+			continue
+		}
+
+		succeeded = append(succeeded, repo)
+	}
+
+	if len(succeeded) == 0 {
+		return nil, fmt.Errorf("failed for every image repository: %w", errors.Join(errs...))
+	}
+
+	return succeeded, nil
+}
+
+// reposWithAllImages returns the repositories in repos to which every one of
+// tars was pushed, according to pushedRepos.
+func reposWithAllImages(repos []string, tars []*images.Tar, pushedRepos map[*images.Tar][]string) []string {
+	var complete []string
+	for _, repo := range repos {
+		hasAll := true
+		for _, t := range tars {
+			if !sets.NewString(pushedRepos[t]...).Has(repo) {
+				hasAll = false
+				break
+			}
+		}
+
+		if hasAll {
+			complete = append(complete, repo)
+		} else {
+			slog.Warn("not every image of the component was pushed to the repository, so no manifest list will be created there", "repository", repo)
+		}
+	}
+
+	return complete
+}
+
+// pushImage pushes img under the given tag, unless it was pushed by a
+// previous run, and records the digest it was pushed with.
+func pushImage(ctx context.Context, o *gcbPublishOptions, pusher *docker.Pusher, img v1.Image, imageTag string) error {
+	if _, done := o.checkpoint.item("pushcontainerimages", "image:"+imageTag); done {
+		slog.Info("Skipping pushing release image as it was pushed by a previous run", "image", imageTag)
+		return nil
+	}
+
+	slog.Info("Pushing release image", "image", imageTag)
+
+	digest, err := pusher.Push(ctx, img, imageTag)
 	if err != nil {
 		return err
 	}
 
-	for _, toSign := range allContentToSign {
-		log.Printf("Signing %q", toSign)
-		if err := retry(ctx, func() error { return cosign.Sign(ctx, o.CosignPath, []string{toSign}, parsedKey) }); err != nil {
-			return fmt.Errorf("failed to sign container image / manifest list %q: %w", toSign, err)
+	slog.Info("Pushed release image", "image", imageTag, "digest", digest)
+	o.pushedDigests.record("image:"+imageTag, digest.String())
+	o.checkpoint.completeItem(ctx, "pushcontainerimages", "image:"+imageTag, digest.String())
+
+	return nil
+}
+
+// pushManifestList pushes the manifest list idx under the given name, unless
+// it was pushed by a previous run, and records the digest it was pushed with.
+func pushManifestList(ctx context.Context, o *gcbPublishOptions, pusher *docker.Pusher, idx v1.ImageIndex, manifestListName string) error {
+	if _, done := o.checkpoint.item("pushcontainerimages", "manifestlist:"+manifestListName); done {
+		slog.Info("Skipping pushing manifest list as it was pushed by a previous run", "manifest_list", manifestListName)
+		return nil
+	}
+
+	slog.Info("Pushing manifest list", "manifest_list", manifestListName)
+	digest, err := pusher.PushManifestList(ctx, idx, manifestListName)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Pushed multi-arch manifest list", "manifest_list", manifestListName, "digest", digest)
+	o.pushedDigests.record("manifestlist:"+manifestListName, digest.String())
+	o.checkpoint.completeItem(ctx, "pushcontainerimages", "manifestlist:"+manifestListName, digest.String())
+
+	return nil
+}
+
+// signRegistryContent signs every image and manifest list in rel in each of
+// the repositories it was pushed to. An error is only returned if an image or
+// manifest list couldn't be signed in any repository.
+func signRegistryContent(ctx context.Context, o *gcbPublishOptions, rel *release.Unpacked, pushedRepos map[*images.Tar][]string, pushedManifestListRepos map[string][]string) error {
+	if o.SkipSigning {
+		slog.Info("Skipping signing container images / manifest lists as skip-signing is set")
+		return nil
+	}
+
+	slog.Info("Signing container images", "signing_mode", o.SigningMode)
+
+	signImage, err := o.signImage()
+	if err != nil {
+		return err
+	}
+
+	var signed []string
+	signRef := func(ref string) error {
+		if _, done := o.checkpoint.item("pushcontainerimages", "signature:"+ref); done {
+			slog.Info("Skipping signing as it was signed by a previous run", "ref", ref)
+			signed = append(signed, ref)
+			return nil
+		}
+
+		slog.Info("Signing image", "ref", ref)
+		if err := signImage(ctx, ref); err != nil {
+			return fmt.Errorf("failed to sign container image / manifest list %q: %w", ref, err)
+		}
+
+		o.checkpoint.completeItem(ctx, "pushcontainerimages", "signature:"+ref, "")
+		signed = append(signed, ref)
+
+		return nil
+	}
+
+	for _, name := range sortedComponentNames(rel) {
+		for _, t := range rel.ComponentImageBundles[name] {
+			if _, err := forEachRepository(pushedRepos[t], func(repo string) error {
+				return signRef(buildImageTag(repo, name, t.Architecture(), rel.ReleaseVersion))
+			}); err != nil {
+				return err
+			}
 		}
 
-		// Wait to avoid being rate limited by the registry
-		time.Sleep(registryWaitTime)
+		if _, err := forEachRepository(pushedManifestListRepos[name], func(repo string) error {
+			return signRef(buildManifestListName(repo, name, rel.ReleaseVersion))
+		}); err != nil {
+			return err
+		}
 	}
 
-	log.Printf("Finished signing: %s", strings.Join(allContentToSign, ", "))
+	slog.Info("Finished signing", "refs", signed)
 
 	return nil
 }
@@ -612,7 +1974,23 @@ func buildImageTag(repo, componentName, arch, tag string) string {
 
 func errorDuringPublish(err error) error {
 	if err != nil {
-		log.Printf("ERROR OCCURRED DURING PUBLISHING - INCOMPLETE RELEASE MAY BE PUBLISHED: %v", err)
+		slog.Error("ERROR OCCURRED DURING PUBLISHING - INCOMPLETE RELEASE MAY BE PUBLISHED", "error", err)
 	}
 	return err
 }
+
+// kmsMetadataSigner returns a release.MetadataSigner which creates detached
+// PGP signatures of release metadata using the given KMS key.
+func kmsMetadataSigner(key sign.GCPKMSKey) release.MetadataSigner {
+	return func(ctx context.Context, metadata []byte) ([]byte, error) {
+		return sign.PGPDetachSign(ctx, key, metadata)
+	}
+}
+
+// kmsMetadataVerifier returns a release.MetadataVerifier which checks detached
+// PGP signatures of release metadata made by the given KMS key.
+func kmsMetadataVerifier(key sign.GCPKMSKey) release.MetadataVerifier {
+	return func(ctx context.Context, metadata []byte, signature []byte) error {
+		return sign.PGPVerifyDetached(ctx, key, metadata, signature)
+	}
+}
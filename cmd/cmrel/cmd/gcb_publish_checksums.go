@@ -0,0 +1,80 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// gitHubReleaseChecksumsFileName is the name of the GitHub release asset which
+// lists the SHA256 sum of every manifest and ctl archive uploaded to the
+// release.
+const gitHubReleaseChecksumsFileName = "cert-manager-checksums.txt"
+
+// cmctlGitHubReleaseChecksumsFileName is the name of the asset which lists the
+// SHA256 sum of every cmctl archive uploaded to the cmctl GitHub release.
+const cmctlGitHubReleaseChecksumsFileName = "cmctl-checksums.txt"
+
+// assetChecksums returns the SHA256 sum of each of the files at the given
+// paths, keyed by asset name.
+func assetChecksums(assets map[string]string) (map[string]string, error) {
+	checksums := map[string]string{}
+	for assetName, path := range assets {
+		sum, err := sha256SumFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute checksum of %q: %w", path, err)
+		}
+
+		checksums[assetName] = sum
+	}
+
+	return checksums, nil
+}
+
+// formatChecksumsFile renders the given checksums, keyed by asset name, in the
+// format written by sha256sum and sorted by name, so that downloaded assets
+// can be verified using 'sha256sum --check'.
+func formatChecksumsFile(checksums map[string]string) []byte {
+	var sb strings.Builder
+	for _, name := range sets.StringKeySet(checksums).List() {
+		fmt.Fprintf(&sb, "%s  %s\n", checksums[name], name)
+	}
+
+	return []byte(sb.String())
+}
+
+// writeGitHubReleaseChecksums writes a checksums file with the given name
+// listing the SHA256 sum of each of the given assets into dir, returning its
+// path.
+func writeGitHubReleaseChecksums(assets map[string]string, dir string, fileName string) (string, error) {
+	checksums, err := assetChecksums(assets)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, fileName)
+	if err := os.WriteFile(path, formatChecksumsFile(checksums), 0644); err != nil {
+		return "", fmt.Errorf("failed to write checksums file: %w", err)
+	}
+
+	return path, nil
+}
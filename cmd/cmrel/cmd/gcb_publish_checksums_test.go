@@ -0,0 +1,76 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteGitHubReleaseChecksums(t *testing.T) {
+	dir := t.TempDir()
+
+	assets := map[string]string{}
+	for name, content := range map[string]string{
+		"cert-manager.yaml":        "manifests",
+		"cert-manager.crds.yaml":   "crds",
+		"cmctl-linux-amd64.tar.gz": "",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		assets[name] = path
+	}
+
+	outDir := t.TempDir()
+
+	path, err := writeGitHubReleaseChecksums(assets, outDir, gitHubReleaseChecksumsFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if path != filepath.Join(outDir, gitHubReleaseChecksumsFileName) {
+		t.Errorf("unexpected checksums file path %q", path)
+	}
+
+	checksums, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sums as computed by sha256sum, sorted by asset name
+	expected := "ebd18182ec39993e96b6da4b8ce1aa3944db1d02dd7f6be4f6e28d316643ec71  cert-manager.crds.yaml\n" +
+		"c7af7c7a948db8800f71f26f3c90280cf09dfc3141b72318c5ff31ffc9470a59  cert-manager.yaml\n" +
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  cmctl-linux-amd64.tar.gz\n"
+
+	if string(checksums) != expected {
+		t.Errorf("unexpected checksums file:\ngot=%q\nexp=%q", checksums, expected)
+	}
+}
+
+func TestWriteGitHubReleaseChecksumsMissingAsset(t *testing.T) {
+	assets := map[string]string{
+		"cert-manager.yaml": filepath.Join(t.TempDir(), "missing.yaml"),
+	}
+
+	if _, err := writeGitHubReleaseChecksums(assets, t.TempDir(), gitHubReleaseChecksumsFileName); err == nil {
+		t.Errorf("expected an error for an asset which doesn't exist")
+	}
+}
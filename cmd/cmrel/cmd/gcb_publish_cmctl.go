@@ -0,0 +1,127 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/google/go-github/v35/github"
+	"k8s.io/utils/pointer"
+
+	"github.com/cert-manager/release/pkg/release"
+)
+
+// cmctlGitHubReleaseRepo returns the repository which cmctl binaries are
+// released to for versions of cert-manager which no longer ship cmctl.
+func (o *gcbPublishOptions) cmctlGitHubReleaseRepo() gitHubReleaseRepo {
+	return gitHubReleaseRepo{
+		action: "cmctlgithubrelease",
+		org:    o.PublishedCmctlGitHubOrg,
+		repo:   o.PublishedCmctlGitHubRepo,
+	}
+}
+
+// cmctlGitHubReleaseAssetPaths returns the paths of the ctl binary bundles in
+// rel which are uploaded to the cmctl GitHub release, keyed by asset name.
+// Releases which ship cmctl themselves upload these to the cert-manager
+// GitHub release instead, so nothing is returned for them.
+func cmctlGitHubReleaseAssetPaths(rel *release.Unpacked) map[string]string {
+	assets := map[string]string{}
+	if release.CmctlIsShipped(rel.ReleaseVersion) {
+		return assets
+	}
+
+	for _, ctlBinary := range rel.CtlBinaryBundles {
+		assets[ctlBinary.ArtifactFilename()] = ctlBinary.Filepath()
+	}
+
+	return assets
+}
+
+// pushCmctlGitHubRelease creates a draft GitHub release in the cmctl
+// repository and uploads the ctl binaries in rel to it, along with a checksums
+// file and signatures. It does nothing if rel doesn't contain any ctl binaries
+// destined for the cmctl repository.
+func pushCmctlGitHubRelease(ctx context.Context, o *gcbPublishOptions, rel *release.Unpacked) error {
+	assets := cmctlGitHubReleaseAssetPaths(rel)
+	if len(assets) == 0 {
+		slog.Info("Skipping cmctl GitHub release as the release doesn't contain any cmctl binaries to publish", "repository", o.PublishedCmctlGitHubOrg+"/"+o.PublishedCmctlGitHubRepo)
+		return nil
+	}
+
+	githubClient, err := o.GitHubClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create github client for creating cmctl github release: %w", err)
+	}
+
+	for name, path := range assets {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("failed to find file %q to be uploaded: %v", name, err)
+		}
+	}
+
+	workDir, err := os.MkdirTemp("", "cmrel-cmctl-github-release-")
+	if err != nil {
+		return fmt.Errorf("failed to create directory for checksums and signatures: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	checksumsPath, err := writeGitHubReleaseChecksums(assets, workDir, cmctlGitHubReleaseChecksumsFileName)
+	if err != nil {
+		return err
+	}
+
+	assets[cmctlGitHubReleaseChecksumsFileName] = checksumsPath
+
+	target := o.cmctlGitHubReleaseRepo()
+
+	signatures, err := signGitHubReleaseAssets(ctx, o, target, assets, workDir)
+	if err != nil {
+		return err
+	}
+
+	githubRelease, err := createOrResumeGitHubRelease(ctx, o, githubClient, target, func() *github.RepositoryRelease {
+		// the git ref of rel is a cert-manager commit, so the tag's target is
+		// left to default to the cmctl repository's default branch
+		body := fmt.Sprintf("!!! Update this release note body before publishing this draft release!\n\ncmctl binaries built alongside cert-manager %s.", rel.ReleaseVersion)
+		return &github.RepositoryRelease{
+			TagName: &rel.ReleaseVersion,
+			Name:    &rel.ReleaseVersion,
+			Body:    &body,
+			Draft:   pointer.Bool(true),
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Uploading cmctl binary archives and checksums to GitHub release", "count", len(assets))
+	if err := uploadGitHubReleaseAssets(ctx, o, githubClient, target, githubRelease, assets); err != nil {
+		return err
+	}
+
+	slog.Info("Uploading signatures to cmctl GitHub release", "count", len(signatures))
+	if err := uploadGitHubReleaseAssets(ctx, o, githubClient, target, githubRelease, signatures); err != nil {
+		return err
+	}
+
+	o.manualActionLogger.Printf("Check the tag target of the draft cmctl GitHub release in %s/%s, update its release notes and hit PUBLISH!", o.PublishedCmctlGitHubOrg, o.PublishedCmctlGitHubRepo)
+	return nil
+}
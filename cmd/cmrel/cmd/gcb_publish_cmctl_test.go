@@ -0,0 +1,78 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cert-manager/release/pkg/release"
+	"github.com/cert-manager/release/pkg/release/binaries"
+)
+
+func TestCmctlGitHubReleaseAssetPaths(t *testing.T) {
+	bundles := []binaries.Archive{
+		*binaries.NewArchive("cmctl", "/tmp/cmctl-linux-amd64.tar.gz", "linux", "amd64", release.ArchiveFormatTarGz),
+		*binaries.NewArchive("cmctl", "/tmp/cmctl-windows-amd64.zip", "windows", "amd64", release.ArchiveFormatZip),
+	}
+
+	tests := map[string]struct {
+		version       string
+		bundles       []binaries.Archive
+		expected      map[string]string
+		expectInCMRel bool
+	}{
+		"release which doesn't ship cmctl publishes ctl binaries to the cmctl repo": {
+			version: "v1.15.0",
+			bundles: bundles,
+			expected: map[string]string{
+				"cmctl-linux-amd64.tar.gz": "/tmp/cmctl-linux-amd64.tar.gz",
+				"cmctl-windows-amd64.zip":  "/tmp/cmctl-windows-amd64.zip",
+			},
+		},
+		"release which ships cmctl publishes nothing to the cmctl repo": {
+			version:       "v1.14.5",
+			bundles:       bundles,
+			expected:      map[string]string{},
+			expectInCMRel: true,
+		},
+		"release without ctl binaries publishes nothing": {
+			version:  "v1.15.0",
+			expected: map[string]string{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			rel := &release.Unpacked{
+				ReleaseVersion:   test.version,
+				CtlBinaryBundles: test.bundles,
+			}
+
+			assets := cmctlGitHubReleaseAssetPaths(rel)
+			if !reflect.DeepEqual(assets, test.expected) {
+				t.Errorf("wanted %v but got %v", test.expected, assets)
+			}
+
+			// ctl binaries must be published to exactly one GitHub release
+			_, inCMRel := gitHubReleaseAssetPaths(rel)["cmctl-linux-amd64.tar.gz"]
+			if inCMRel != test.expectInCMRel {
+				t.Errorf("expectInCMRel=%v but got %v", test.expectInCMRel, inCMRel)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/cert-manager/release/pkg/release"
+)
+
+// publishedDigests records the digest of every image and manifest list pushed
+// for a release, in each repository it was pushed to, so that consumers can
+// pin images by digest.
+type publishedDigests struct {
+	ReleaseVersion string `json:"releaseVersion"`
+
+	// Images are the per-architecture images pushed for each component.
+	Images []publishedImageDigest `json:"images"`
+
+	// ManifestLists are the multi-arch manifest lists pushed for each
+	// component.
+	ManifestLists []publishedManifestListDigest `json:"manifestLists"`
+}
+
+type publishedImageDigest struct {
+	Repository   string `json:"repository"`
+	Component    string `json:"component"`
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Tag          string `json:"tag"`
+	Digest       string `json:"digest"`
+}
+
+type publishedManifestListDigest struct {
+	Repository string `json:"repository"`
+	Component  string `json:"component"`
+	Tag        string `json:"tag"`
+	Digest     string `json:"digest"`
+}
+
+// pushedDigestRecorder records the digest of each image and manifest list
+// pushed, keyed by the publish checkpoint item of the push. The zero value is
+// ready to use, and a pushedDigestRecorder is safe for concurrent use.
+type pushedDigestRecorder struct {
+	mu      sync.Mutex
+	digests map[string]string
+}
+
+// record records that the given push item was pushed with digest.
+func (r *pushedDigestRecorder) record(item string, digest string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.digests == nil {
+		r.digests = map[string]string{}
+	}
+
+	r.digests[item] = digest
+}
+
+// digest returns the digest recorded for the given push item, if any.
+func (r *pushedDigestRecorder) digest(item string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	digest, ok := r.digests[item]
+	return digest, ok
+}
+
+// digestResolver returns the digest of the image or manifest list with the
+// given reference in a remote registry.
+type digestResolver func(ctx context.Context, ref string) (string, error)
+
+// remoteDigest resolves the digest of ref by querying its registry, using
+// credentials from the default keychain.
+func remoteDigest(ctx context.Context, ref string) (string, error) {
+	parsed, err := name.ParseReference(ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse image reference %q: %w", ref, err)
+	}
+
+	desc, err := remote.Head(parsed, remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve digest of %q: %w", ref, err)
+	}
+
+	return desc.Digest.String(), nil
+}
+
+// collectPublishedDigests returns the digest of each per-architecture image
+// and manifest list of rel pushed to each of repos, as reported by lookup for
+// the publish checkpoint item of the push. Anything which lookup has no digest
+// for wasn't pushed to that repository, and is omitted.
+func collectPublishedDigests(repos []string, rel *release.Unpacked, lookup func(item string) (string, bool)) *publishedDigests {
+	digests := &publishedDigests{
+		ReleaseVersion: rel.ReleaseVersion,
+		Images:         []publishedImageDigest{},
+		ManifestLists:  []publishedManifestListDigest{},
+	}
+
+	for _, repo := range repos {
+		for _, component := range sets.StringKeySet(rel.ComponentImageBundles).List() {
+			for _, t := range rel.ComponentImageBundles[component] {
+				tag := buildImageTag(repo, component, t.Architecture(), rel.ReleaseVersion)
+
+				digest, ok := lookup("image:" + tag)
+				if !ok {
+					continue
+				}
+
+				digests.Images = append(digests.Images, publishedImageDigest{
+					Repository:   repo,
+					Component:    component,
+					OS:           t.OS(),
+					Architecture: t.Architecture(),
+					Tag:          tag,
+					Digest:       digest,
+				})
+			}
+
+			manifestListName := buildManifestListName(repo, component, rel.ReleaseVersion)
+
+			digest, ok := lookup("manifestlist:" + manifestListName)
+			if !ok {
+				continue
+			}
+
+			digests.ManifestLists = append(digests.ManifestLists, publishedManifestListDigest{
+				Repository: repo,
+				Component:  component,
+				Tag:        manifestListName,
+				Digest:     digest,
+			})
+		}
+	}
+
+	return digests
+}
+
+// verifyPublishedImagesExist checks that every per-architecture image and
+// manifest list of rel can be resolved in repo, returning an error listing
+// any which can't.
+func verifyPublishedImagesExist(ctx context.Context, resolve digestResolver, repo string, rel *release.Unpacked) error {
+	var errs []error
+	for _, component := range sets.StringKeySet(rel.ComponentImageBundles).List() {
+		refs := []string{buildManifestListName(repo, component, rel.ReleaseVersion)}
+		for _, t := range rel.ComponentImageBundles[component] {
+			refs = append(refs, buildImageTag(repo, component, t.Architecture(), rel.ReleaseVersion))
+		}
+
+		for _, ref := range refs {
+			if _, err := resolve(ctx, ref); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%d images or manifest lists are missing from %q: %w", len(errs), repo, errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// JSON returns the indented JSON encoding of the digests.
+func (d *publishedDigests) JSON() ([]byte, error) {
+	out, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode published digests: %w", err)
+	}
+
+	return append(out, '\n'), nil
+}
+
+// recordPublishedDigests records the digests with which the images and
+// manifest lists of rel were pushed to each repository, and writes them to the
+// locations configured in o.
+func recordPublishedDigests(ctx context.Context, o *gcbPublishOptions, bucket *release.Bucket, releaseName string, rel *release.Unpacked) error {
+	slog.Info("Recording digests of published images and manifest lists")
+
+	// digests are taken from the pushes made by this run, falling back to
+	// those recorded in the publish state by a previous run which pushed them
+	lookup := func(item string) (string, bool) {
+		if digest, ok := o.pushedDigests.digest(item); ok {
+			return digest, true
+		}
+
+		digest, done := o.checkpoint.item("pushcontainerimages", item)
+		return digest, done && digest != ""
+	}
+
+	digests := collectPublishedDigests(o.publishedImageRepositories(), rel, lookup)
+	if len(digests.Images) == 0 {
+		return fmt.Errorf("no digests were recorded for the published images")
+	}
+
+	out, err := digests.JSON()
+	if err != nil {
+		return err
+	}
+
+	if o.DigestsOutput != "" {
+		if err := os.WriteFile(o.DigestsOutput, out, 0644); err != nil {
+			return fmt.Errorf("failed to write published digests to %q: %w", o.DigestsOutput, err)
+		}
+
+		slog.Info("Wrote published digests", "path", o.DigestsOutput)
+	}
+
+	if o.UploadDigests {
+		if err := bucket.WriteFile(ctx, releaseName, release.PublishedDigestsFileName, out); err != nil {
+			return fmt.Errorf("failed to upload published digests: %w", err)
+		}
+
+		slog.Info("Uploaded published digests", "object", release.PublishedDigestsFileName, "release", releaseName)
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
+	"github.com/cert-manager/release/pkg/release"
+	"github.com/cert-manager/release/pkg/release/docker"
+	"github.com/cert-manager/release/pkg/release/images"
+	"github.com/cert-manager/release/pkg/retry"
+)
+
+func TestCollectPublishedDigests(t *testing.T) {
+	ctx := context.Background()
+
+	server := httptest.NewServer(registry.New())
+	t.Cleanup(server.Close)
+
+	repo := strings.TrimPrefix(server.URL, "http://") + "/jetstack"
+	mirrorRepo := strings.TrimPrefix(server.URL, "http://") + "/mirror"
+	failedRepo := strings.TrimPrefix(server.URL, "http://") + "/failed"
+
+	const version = "v1.15.0"
+
+	rel := &release.Unpacked{
+		ReleaseVersion:        version,
+		ComponentImageBundles: map[string][]*images.Tar{},
+	}
+
+	o := NewGCBPublishOptions()
+	pusher := docker.NewPusher(retry.DefaultOptions(), nil)
+
+	expected := &publishedDigests{
+		ReleaseVersion: version,
+		Images:         []publishedImageDigest{},
+		ManifestLists:  []publishedManifestListDigest{},
+	}
+
+	// push a fixture image for each component and architecture, followed by
+	// a manifest list for each component, to the primary and mirror
+	// repositories as pushContainerImages would. Nothing is pushed to
+	// failedRepo, as if every push to it had failed.
+	for _, component := range []string{"controller", "webhook"} {
+		for _, arch := range []string{"amd64", "arm64"} {
+			img, err := random.Image(64, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rawTag, err := name.NewTag("quay.io/jetstack/cert-manager-" + component + "-" + arch + ":" + version)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			path := filepath.Join(t.TempDir(), "image.tar")
+			if err := tarball.WriteToFile(path, rawTag, img); err != nil {
+				t.Fatal(err)
+			}
+
+			imageTar, err := images.NewTar(path, "linux", arch)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rel.ComponentImageBundles[component] = append(rel.ComponentImageBundles[component], imageTar)
+
+			for _, r := range []string{repo, mirrorRepo} {
+				if err := pushImage(ctx, o, pusher, img, buildImageTag(r, component, arch, version)); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+
+		idx, err := images.BuildIndex(rel.ComponentImageBundles[component])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, r := range []string{repo, mirrorRepo} {
+			if err := pushManifestList(ctx, o, pusher, idx, buildManifestListName(r, component, version)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// the digests reported by the push path must match those the registry
+	// serves the images under
+	for _, r := range []string{repo, mirrorRepo} {
+		for _, component := range []string{"controller", "webhook"} {
+			for _, imageTar := range rel.ComponentImageBundles[component] {
+				tag := buildImageTag(r, component, imageTar.Architecture(), version)
+				expected.Images = append(expected.Images, publishedImageDigest{
+					Repository:   r,
+					Component:    component,
+					OS:           "linux",
+					Architecture: imageTar.Architecture(),
+					Tag:          tag,
+					Digest:       mustRemoteDigest(t, ctx, tag),
+				})
+			}
+
+			manifestListName := buildManifestListName(r, component, version)
+			expected.ManifestLists = append(expected.ManifestLists, publishedManifestListDigest{
+				Repository: r,
+				Component:  component,
+				Tag:        manifestListName,
+				Digest:     mustRemoteDigest(t, ctx, manifestListName),
+			})
+		}
+	}
+
+	digests := collectPublishedDigests([]string{repo, mirrorRepo, failedRepo}, rel, o.pushedDigests.digest)
+
+	if !reflect.DeepEqual(digests, expected) {
+		t.Errorf("unexpected digests:\ngot=%+v\nexp=%+v", digests, expected)
+	}
+
+	if err := verifyPublishedImagesExist(ctx, remoteDigest, repo, rel); err != nil {
+		t.Errorf("expected all pushed images to exist, got: %v", err)
+	}
+
+	if err := verifyPublishedImagesExist(ctx, remoteDigest, failedRepo, rel); err == nil {
+		t.Errorf("expected an error verifying images which were not pushed")
+	}
+}
+
+// mustRemoteDigest resolves the digest of ref in its registry, failing the
+// test if it can't be resolved.
+func mustRemoteDigest(t *testing.T, ctx context.Context, ref string) string {
+	t.Helper()
+
+	digest, err := remoteDigest(ctx, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return digest
+}
+
+// pushFixture pushes an image or image index to the given tag, returning its
+// digest.
+func pushFixture(t *testing.T, tag string, content remote.Taggable) v1.Hash {
+	t.Helper()
+
+	ref, err := name.NewTag(tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var digest v1.Hash
+	switch c := content.(type) {
+	case v1.Image:
+		if err := remote.Write(ref, c); err != nil {
+			t.Fatal(err)
+		}
+		digest, err = c.Digest()
+	case v1.ImageIndex:
+		if err := remote.WriteIndex(ref, c); err != nil {
+			t.Fatal(err)
+		}
+		digest, err = c.Digest()
+	default:
+		t.Fatalf("unsupported content type %T", content)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return digest
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"cloud.google.com/go/storage"
+
+	"github.com/cert-manager/release/pkg/gcp"
+	"github.com/cert-manager/release/pkg/release"
+	"github.com/cert-manager/release/pkg/release/helm"
+	"github.com/cert-manager/release/pkg/release/manifests"
+	"github.com/cert-manager/release/pkg/retry"
+)
+
+// helmChartRepoChartsDir is the directory of the chart repository bucket that
+// packaged charts and their provenance files are uploaded to. The index is
+// written to the root of the bucket.
+const helmChartRepoChartsDir = "charts"
+
+// pushHelmChartRepository uploads each of the charts in the release to the
+// configured GCS bucket which backs a static Helm chart repository, and adds
+// them to the repository's index.yaml.
+func pushHelmChartRepository(ctx context.Context, o *gcbPublishOptions, rel *release.Unpacked) error {
+	if o.PublishedHelmChartRepoBucket == "" {
+		slog.Info("Skipping publishing Helm charts to a chart repository bucket as published-helm-chart-repo-bucket is not set")
+		return nil
+	}
+
+	gcs, err := gcp.NewStorageClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create GCS client: %w", err)
+	}
+
+	bucket := gcs.Bucket(o.PublishedHelmChartRepoBucket)
+	baseURL := fmt.Sprintf("%s/%s", o.PublishedHelmChartRepoURL, helmChartRepoChartsDir)
+
+	slog.Info("Publishing Helm charts to chart repository bucket", "count", len(rel.Charts), "bucket", o.PublishedHelmChartRepoBucket)
+
+	for _, chart := range rel.Charts {
+		if _, done := o.checkpoint.item("helmchartrepo", "chart:"+chart.PackageFileName()); done {
+			slog.Info("Skipping publishing Helm chart to the chart repository as it was published by a previous run", "chart", chart.PackageFileName())
+			continue
+		}
+
+		files := map[string]string{chart.PackageFileName(): chart.Path()}
+		if provPath := chart.ProvPath(); provPath != nil {
+			files[chart.PackageFileName()+".prov"] = *provPath
+		}
+
+		for name, path := range files {
+			obj := bucket.Object(helmChartRepoChartsDir + "/" + name)
+			if err := retry.Do(ctx, func() error {
+				return uploadImmutableObject(ctx, obj, path)
+			}); err != nil {
+				return fmt.Errorf("failed to upload %q to chart repository bucket: %w", name, err)
+			}
+		}
+
+		if err := retry.Do(ctx, func() error {
+			return addChartToRepositoryIndex(ctx, bucket, chart, baseURL)
+		}); err != nil {
+			return fmt.Errorf("failed to add Helm chart %q to chart repository index: %w", chart.PackageFileName(), err)
+		}
+
+		slog.Info("Published Helm chart", "chart", chart.PackageFileName(), "repository", o.PublishedHelmChartRepoURL)
+		o.checkpoint.completeItem(ctx, "helmchartrepo", "chart:"+chart.PackageFileName(), baseURL+"/"+chart.PackageFileName())
+	}
+
+	return nil
+}
+
+// uploadImmutableObject uploads the file at path to obj, unless obj already
+// exists with the same content. Published charts must never change, so an
+// error is returned if obj exists with different content.
+func uploadImmutableObject(ctx context.Context, obj *storage.ObjectHandle, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return retry.Permanent(err)
+	}
+
+	sum := md5.Sum(content)
+
+	attrs, err := obj.Attrs(ctx)
+	switch {
+	case err == nil:
+		if !bytes.Equal(attrs.MD5, sum[:]) {
+			return retry.Permanent(fmt.Errorf("object %q already exists with different content, refusing to overwrite it", obj.ObjectName()))
+		}
+
+		slog.Info("Object already exists with the same content", "object", obj.ObjectName())
+		return nil
+
+	case !errors.Is(err, storage.ErrObjectNotExist):
+		return err
+	}
+
+	w := obj.If(storage.Conditions{DoesNotExist: true}).NewWriter(ctx)
+	w.MD5 = sum[:]
+
+	if _, err := w.Write(content); err != nil {
+		w.Close()
+		return err
+	}
+
+	return w.Close()
+}
+
+// addChartToRepositoryIndex adds chart to the index.yaml in the root of the
+// chart repository bucket, creating it if it doesn't exist. The index is only
+// written if it hasn't changed since it was read, so that concurrent updates
+// aren't lost; the caller should retry if that fails.
+func addChartToRepositoryIndex(ctx context.Context, bucket *storage.BucketHandle, chart manifests.Chart, baseURL string) error {
+	obj := bucket.Object(helm.RepositoryIndexFileName)
+
+	data, generation, err := readObjectGeneration(ctx, obj)
+	if err != nil {
+		return fmt.Errorf("failed to read chart repository index: %w", err)
+	}
+
+	index, err := helm.ParseRepositoryIndex(data)
+	if err != nil {
+		return retry.Permanent(err)
+	}
+
+	now := time.Now()
+
+	added, err := index.AddChart(chart, baseURL, now)
+	if err != nil {
+		return retry.Permanent(err)
+	}
+
+	if !added {
+		slog.Info("Helm chart is already in the chart repository index", "chart", chart.PackageFileName())
+		return nil
+	}
+
+	out, err := index.Marshal(now)
+	if err != nil {
+		return retry.Permanent(fmt.Errorf("failed to encode chart repository index: %w", err))
+	}
+
+	conditions := storage.Conditions{DoesNotExist: true}
+	if generation != 0 {
+		conditions = storage.Conditions{GenerationMatch: generation}
+	}
+
+	w := obj.If(conditions).NewWriter(ctx)
+	w.ContentType = "application/x-yaml"
+	// the index changes with every release, so mustn't be cached
+	w.CacheControl = "no-cache"
+
+	if _, err := w.Write(out); err != nil {
+		w.Close()
+		return err
+	}
+
+	return w.Close()
+}
+
+// readObjectGeneration returns the content and generation of obj. If obj
+// doesn't exist, no content and a generation of 0 are returned.
+func readObjectGeneration(ctx context.Context, obj *storage.ObjectHandle) ([]byte, int64, error) {
+	r, err := obj.NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, 0, nil
+	}
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return data, r.Attrs.Generation, nil
+}
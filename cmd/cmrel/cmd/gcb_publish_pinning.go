@@ -0,0 +1,223 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+
+	"github.com/cert-manager/release/pkg/release"
+)
+
+// digestPinnedAssetName returns the name of the digest-pinned variant of the
+// GitHub release asset with the given name, e.g. "cert-manager.digests.yaml"
+// for "cert-manager.yaml".
+func digestPinnedAssetName(name string) string {
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + ".digests" + ext
+}
+
+// digestPinnedValuesAssetName returns the name of the Helm values file which
+// pins the images of the named chart by digest.
+func digestPinnedValuesAssetName(chartName string) string {
+	return chartName + "-values.digests.yaml"
+}
+
+// resolveManifestListDigests returns the digest of the manifest list pushed
+// to repo for each image component in rel, keyed by component name.
+func resolveManifestListDigests(ctx context.Context, resolve digestResolver, repo string, rel *release.Unpacked) (map[string]string, error) {
+	digests := map[string]string{}
+	for _, component := range sets.StringKeySet(rel.ComponentImageBundles).List() {
+		digest, err := resolve(ctx, buildManifestListName(repo, component, rel.ReleaseVersion))
+		if err != nil {
+			return nil, err
+		}
+
+		digests[component] = digest
+	}
+
+	return digests, nil
+}
+
+// pinManifestImages rewrites every reference to a manifest list of the given
+// version in repo in the YAML manifest data to include its digest, e.g.
+// "quay.io/jetstack/cert-manager-controller:v1.2.3@sha256:...". References
+// which are already pinned have their digest replaced. It returns false if
+// data contains no references to pin.
+func pinManifestImages(data []byte, repo string, version string, digests map[string]string) ([]byte, bool) {
+	pinned := false
+	for _, component := range sets.StringKeySet(digests).List() {
+		ref := buildManifestListName(repo, component, version)
+
+		// match the whole reference, so that a tag such as "v1.2.3" doesn't
+		// also match "v1.2.3-alpha.0"
+		re := regexp.MustCompile(regexp.QuoteMeta(ref) + `(@sha256:[0-9a-f]{64})?([^\w.\-]|$)`)
+		if !re.Match(data) {
+			continue
+		}
+
+		data = re.ReplaceAll(data, []byte(ref+"@"+digests[component]+"${2}"))
+		pinned = true
+	}
+
+	return data, pinned
+}
+
+// pinChartValues returns a Helm values file which pins each image of repo
+// referenced by the given chart values to its digest, by setting "digest"
+// alongside the "repository" which names the image. The chart's other values
+// are left unchanged, so the result is intended to be passed to helm with
+// "--values" alongside any other values. It returns nil if values contains no
+// images to pin.
+func pinChartValues(values []byte, repo string, digests map[string]string) ([]byte, error) {
+	var parsed map[string]interface{}
+	if err := yaml.Unmarshal(values, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse chart values: %w", err)
+	}
+
+	byRepository := map[string]string{}
+	for component, digest := range digests {
+		byRepository[fmt.Sprintf("%s/cert-manager-%s", repo, component)] = digest
+	}
+
+	overrides := pinValuesMap(parsed, byRepository)
+	if overrides == nil {
+		return nil, nil
+	}
+
+	out, err := yaml.Marshal(overrides)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode digest-pinned chart values: %w", err)
+	}
+
+	return out, nil
+}
+
+// pinValuesMap walks the given chart values, returning the values which set
+// the digest of every image whose repository is a key in byRepository, or nil
+// if there are none. The repository of an image is its "repository" value,
+// prefixed by its "registry" value if set.
+func pinValuesMap(values map[string]interface{}, byRepository map[string]string) map[string]interface{} {
+	overrides := map[string]interface{}{}
+
+	if repository, ok := values["repository"].(string); ok {
+		if registry, ok := values["registry"].(string); ok && registry != "" {
+			repository = registry + "/" + repository
+		}
+
+		if digest, ok := byRepository[repository]; ok {
+			overrides["digest"] = digest
+		}
+	}
+
+	for key, value := range values {
+		child, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if childOverrides := pinValuesMap(child, byRepository); childOverrides != nil {
+			overrides[key] = childOverrides
+		}
+	}
+
+	if len(overrides) == 0 {
+		return nil
+	}
+
+	return overrides
+}
+
+// writeDigestPinnedAssets writes digest-pinned variants of the YAML manifests
+// in rel which reference its images in the published image repository, and a
+// values file pinning the images of each of its Helm charts, to dir. The
+// digests are those of the manifest lists pushed for rel, so they must have
+// been pushed already. The paths of the written files are returned keyed by
+// asset name. Manifests and charts which don't reference any images are
+// skipped.
+func writeDigestPinnedAssets(ctx context.Context, o *gcbPublishOptions, resolve digestResolver, rel *release.Unpacked, dir string) (map[string]string, error) {
+	digests, err := resolveManifestListDigests(ctx, resolve, o.PublishedImageRepository, rel)
+	if err != nil {
+		return nil, err
+	}
+
+	assets := map[string]string{}
+	for _, manifest := range rel.YAMLs {
+		data, err := os.ReadFile(manifest.Path())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest %q: %w", manifest.Path(), err)
+		}
+
+		pinned, ok := pinManifestImages(data, o.PublishedImageRepository, rel.ReleaseVersion, digests)
+		if !ok {
+			continue
+		}
+
+		name := digestPinnedAssetName(filepath.Base(manifest.Path()))
+		if err := writeDigestPinnedAsset(assets, dir, name, pinned); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, chart := range rel.Charts {
+		values, err := chart.Values()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read values of Helm chart %q: %w", chart.Name(), err)
+		}
+
+		pinned, err := pinChartValues(values, o.PublishedImageRepository, digests)
+		if err != nil {
+			return nil, fmt.Errorf("failed to pin images in Helm chart %q: %w", chart.Name(), err)
+		}
+
+		if pinned == nil {
+			continue
+		}
+
+		if err := writeDigestPinnedAsset(assets, dir, digestPinnedValuesAssetName(chart.Name()), pinned); err != nil {
+			return nil, err
+		}
+	}
+
+	return assets, nil
+}
+
+// writeDigestPinnedAsset writes data to the named file in dir, and adds its
+// path to assets.
+func writeDigestPinnedAsset(assets map[string]string, dir string, name string, data []byte) error {
+	if _, ok := assets[name]; ok {
+		return fmt.Errorf("more than one digest-pinned asset is named %q", name)
+	}
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write digest-pinned asset %q: %w", name, err)
+	}
+
+	slog.Info("Wrote digest-pinned asset", "asset", name)
+	assets[name] = path
+
+	return nil
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPinManifestImages(t *testing.T) {
+	controllerDigest := "sha256:" + strings.Repeat("a", 64)
+	webhookDigest := "sha256:" + strings.Repeat("b", 64)
+
+	digests := map[string]string{
+		"controller": controllerDigest,
+		"webhook":    webhookDigest,
+	}
+
+	tests := map[string]struct {
+		manifest string
+
+		expected       string
+		expectedPinned bool
+	}{
+		"images are pinned": {
+			manifest: `image: "quay.io/jetstack/cert-manager-controller:v1.2.3"
+image: quay.io/jetstack/cert-manager-webhook:v1.2.3
+`,
+			expected: `image: "quay.io/jetstack/cert-manager-controller:v1.2.3@` + controllerDigest + `"
+image: quay.io/jetstack/cert-manager-webhook:v1.2.3@` + webhookDigest + `
+`,
+			expectedPinned: true,
+		},
+		"reference at the end of the manifest is pinned": {
+			manifest:       "image: quay.io/jetstack/cert-manager-controller:v1.2.3",
+			expected:       "image: quay.io/jetstack/cert-manager-controller:v1.2.3@" + controllerDigest,
+			expectedPinned: true,
+		},
+		"already pinned image is repinned": {
+			manifest:       "image: quay.io/jetstack/cert-manager-controller:v1.2.3@sha256:" + strings.Repeat("c", 64) + "\n",
+			expected:       "image: quay.io/jetstack/cert-manager-controller:v1.2.3@" + controllerDigest + "\n",
+			expectedPinned: true,
+		},
+		"other versions and repositories are left alone": {
+			manifest: `image: quay.io/jetstack/cert-manager-controller:v1.2.3-alpha.0
+image: ghcr.io/cert-manager/cert-manager-controller:v1.2.3
+`,
+			expected: `image: quay.io/jetstack/cert-manager-controller:v1.2.3-alpha.0
+image: ghcr.io/cert-manager/cert-manager-controller:v1.2.3
+`,
+		},
+		"no images": {
+			manifest: "kind: CustomResourceDefinition\n",
+			expected: "kind: CustomResourceDefinition\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			pinned, ok := pinManifestImages([]byte(test.manifest), "quay.io/jetstack", "v1.2.3", digests)
+			if ok != test.expectedPinned {
+				t.Errorf("expected pinned=%v but got %v", test.expectedPinned, ok)
+			}
+
+			if string(pinned) != test.expected {
+				t.Errorf("wanted manifest:\n%s\nbut got:\n%s", test.expected, pinned)
+			}
+		})
+	}
+}
+
+func TestPinChartValues(t *testing.T) {
+	controllerDigest := "sha256:" + strings.Repeat("a", 64)
+	webhookDigest := "sha256:" + strings.Repeat("b", 64)
+
+	digests := map[string]string{
+		"controller": controllerDigest,
+		"webhook":    webhookDigest,
+	}
+
+	tests := map[string]struct {
+		values string
+
+		expected  string
+		expectErr bool
+	}{
+		"images are pinned": {
+			values: `replicaCount: 1
+image:
+  repository: quay.io/jetstack/cert-manager-controller
+  pullPolicy: IfNotPresent
+webhook:
+  replicaCount: 1
+  image:
+    repository: quay.io/jetstack/cert-manager-webhook
+`,
+			expected: `image:
+  digest: ` + controllerDigest + `
+webhook:
+  image:
+    digest: ` + webhookDigest + `
+`,
+		},
+		"registry and repository are combined": {
+			values: `image:
+  registry: quay.io
+  repository: jetstack/cert-manager-controller
+`,
+			expected: `image:
+  digest: ` + controllerDigest + `
+`,
+		},
+		"images from other repositories are left alone": {
+			values: `image:
+  repository: ghcr.io/cert-manager/cert-manager-controller
+`,
+		},
+		"no images": {
+			values: "replicaCount: 1\n",
+		},
+		"invalid values": {
+			values:    "image: [",
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			pinned, err := pinChartValues([]byte(test.values), "quay.io/jetstack", digests)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if string(pinned) != test.expected {
+				t.Errorf("wanted values:\n%s\nbut got:\n%s", test.expected, pinned)
+			}
+		})
+	}
+}
+
+func TestDigestPinnedAssetName(t *testing.T) {
+	tests := map[string]string{
+		"cert-manager.yaml":      "cert-manager.digests.yaml",
+		"cert-manager.crds.yaml": "cert-manager.crds.digests.yaml",
+	}
+
+	for name, expected := range tests {
+		if got := digestPinnedAssetName(name); got != expected {
+			t.Errorf("wanted digest-pinned name of %q to be %q but got %q", name, expected, got)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/google/go-github/v35/github"
+	"golang.org/x/mod/semver"
+)
+
+// releaseFlagAuto is the value of the --prerelease and --latest flags which
+// derives them from the release version.
+const releaseFlagAuto = "auto"
+
+// parseReleaseFlag parses the value of the --prerelease or --latest flag,
+// returning nil if it should be derived from the release version.
+func parseReleaseFlag(name, value string) (*bool, error) {
+	if value == releaseFlagAuto {
+		return nil, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --%s value %q; must be %q, \"true\" or \"false\"", name, value, releaseFlagAuto)
+	}
+
+	return &b, nil
+}
+
+// gitHubReleaseFlags determines whether the GitHub release for version should
+// be marked as a prerelease and as the latest release, given the overrides
+// from the --prerelease and --latest flags and the tags already in the
+// repository.
+// Unless overridden, alpha, beta and rc versions are prereleases, and a
+// release is the latest unless it's a prerelease or a tag with a higher
+// version exists, such as when releasing a patch for an older release branch.
+// tags are only used if latest isn't overridden, and may be nil in that case.
+func gitHubReleaseFlags(version string, prerelease, latest *bool, tags []string) (bool, bool, error) {
+	isPrerelease := semver.Prerelease(version) != ""
+	if prerelease != nil {
+		isPrerelease = *prerelease
+	}
+
+	if latest != nil {
+		if *latest && isPrerelease {
+			return false, false, fmt.Errorf("prerelease %q can't be marked as the latest release", version)
+		}
+
+		return isPrerelease, *latest, nil
+	}
+
+	if isPrerelease {
+		return true, false, nil
+	}
+
+	for _, tag := range tags {
+		if semver.IsValid(tag) && semver.Prerelease(tag) == "" && semver.Compare(tag, version) > 0 {
+			return false, false, nil
+		}
+	}
+
+	return false, true, nil
+}
+
+// setGitHubReleaseNotLatest stops the given GitHub release from being marked
+// as the latest release when it's published. The version of go-github in use
+// doesn't support the make_latest field, so the request is built by hand.
+// A release is marked as the latest when it's published unless make_latest is
+// false, and drafts can't be explicitly marked as the latest, so there's no
+// equivalent to mark a release as the latest.
+func setGitHubReleaseNotLatest(ctx context.Context, client *github.Client, org, repo string, id int64) error {
+	req, err := client.NewRequest("PATCH", fmt.Sprintf("repos/%s/%s/releases/%d", org, repo, id), map[string]string{
+		"make_latest": "false",
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := client.Do(ctx, req, nil); err != nil {
+		return fmt.Errorf("failed to stop GitHub release from being marked as latest: %w", err)
+	}
+
+	return nil
+}
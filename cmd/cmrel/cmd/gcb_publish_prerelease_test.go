@@ -0,0 +1,156 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-github/v35/github"
+)
+
+func TestGitHubReleaseFlags(t *testing.T) {
+	tags := []string{"v1.14.0", "v1.14.5", "v1.15.0", "v1.15.1", "v1.16.0-alpha.0", "not-a-version"}
+
+	tests := map[string]struct {
+		version    string
+		prerelease string
+		latest     string
+
+		expectPrerelease bool
+		expectLatest     bool
+		expectErr        bool
+	}{
+		"new minor release": {
+			version:      "v1.16.0",
+			expectLatest: true,
+		},
+		"patch of the newest release branch": {
+			version:      "v1.15.2",
+			expectLatest: true,
+		},
+		"patch of an older release branch": {
+			version:      "v1.14.6",
+			expectLatest: false,
+		},
+		"alpha release": {
+			version:          "v1.16.0-alpha.1",
+			expectPrerelease: true,
+		},
+		"release candidate": {
+			version:          "v1.16.0-rc.0",
+			expectPrerelease: true,
+		},
+		"patch of an older release branch forced to be latest": {
+			version:      "v1.14.6",
+			latest:       "true",
+			expectLatest: true,
+		},
+		"release forced to be a prerelease": {
+			version:          "v1.16.0",
+			prerelease:       "true",
+			expectPrerelease: true,
+		},
+		"prerelease forced to be latest": {
+			version:   "v1.16.0-alpha.1",
+			latest:    "true",
+			expectErr: true,
+		},
+		"invalid override": {
+			version:    "v1.16.0",
+			prerelease: "maybe",
+			expectErr:  true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if test.prerelease == "" {
+				test.prerelease = releaseFlagAuto
+			}
+
+			if test.latest == "" {
+				test.latest = releaseFlagAuto
+			}
+
+			prerelease, err := parseReleaseFlag("prerelease", test.prerelease)
+			if err != nil {
+				if !test.expectErr {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			latest, err := parseReleaseFlag("latest", test.latest)
+			if err != nil {
+				if !test.expectErr {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			isPrerelease, isLatest, err := gitHubReleaseFlags(test.version, prerelease, latest, tags)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if isPrerelease != test.expectPrerelease {
+				t.Errorf("expected prerelease=%t but got %t", test.expectPrerelease, isPrerelease)
+			}
+
+			if isLatest != test.expectLatest {
+				t.Errorf("expected latest=%t but got %t", test.expectLatest, isLatest)
+			}
+		})
+	}
+}
+
+func TestSetGitHubReleaseNotLatest(t *testing.T) {
+	var body map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.Path != "/repos/cert-manager/cert-manager/releases/123" {
+			http.Error(w, "unexpected request "+r.Method+" "+r.URL.Path, http.StatusNotFound)
+			return
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	client := github.NewClient(nil)
+	baseURL, err := url.Parse(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.BaseURL = baseURL
+
+	if err := setGitHubReleaseNotLatest(context.TODO(), client, "cert-manager", "cert-manager", 123); err != nil {
+		t.Fatal(err)
+	}
+
+	if body["make_latest"] != "false" {
+		t.Errorf("expected make_latest to be \"false\" but got request body %v", body)
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"cloud.google.com/go/storage"
+
+	"github.com/cert-manager/release/pkg/release"
+	"github.com/cert-manager/release/pkg/release/images"
+	"github.com/cert-manager/release/pkg/release/provenance"
+	"github.com/cert-manager/release/pkg/sign"
+	"github.com/cert-manager/release/pkg/sign/cosign"
+)
+
+// readReleaseProvenance reads the SLSA provenance written by 'gcb stage' for
+// the given staged release, checking that it describes the release's
+// artifacts. If the release was staged without provenance, nil is returned.
+func readReleaseProvenance(ctx context.Context, bucket *release.Bucket, staged *release.Staged) (*provenance.Statement, error) {
+	data, err := bucket.ReadFile(ctx, staged.Name(), release.ProvenanceFileName)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read provenance: %w", err)
+	}
+
+	statement, err := provenance.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := statement.CheckSubjects(staged.Metadata().Artifacts); err != nil {
+		return nil, err
+	}
+
+	return statement, nil
+}
+
+// attestRegistryContent attaches the provenance of the release, signed with
+// the signing key, to every image and manifest list in rel in each of the
+// repositories it was pushed to. Nothing is attested if the release has no
+// provenance or signing is skipped.
+func attestRegistryContent(ctx context.Context, o *gcbPublishOptions, rel *release.Unpacked, pushedRepos map[*images.Tar][]string, pushedManifestListRepos map[string][]string) error {
+	if o.provenance == nil {
+		slog.Info("Skipping attaching provenance to container images / manifest lists as the release has no provenance")
+		return nil
+	}
+
+	if o.SkipSigning {
+		slog.Info("Skipping attaching provenance to container images / manifest lists as skip-signing is set")
+		return nil
+	}
+
+	parsedKey, err := sign.NewGCPKMSKey(o.SigningKMSKey)
+	if err != nil {
+		return err
+	}
+
+	predicate, err := o.provenance.PredicateJSON()
+	if err != nil {
+		return fmt.Errorf("failed to encode provenance predicate: %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "cmrel-provenance-")
+	if err != nil {
+		return fmt.Errorf("failed to create directory for provenance: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	predicatePath := filepath.Join(dir, "predicate.json")
+	if err := os.WriteFile(predicatePath, predicate, 0644); err != nil {
+		return fmt.Errorf("failed to write provenance predicate: %w", err)
+	}
+
+	attestRef := func(ref string) error {
+		if _, done := o.checkpoint.item("pushcontainerimages", "attestation:"+ref); done {
+			slog.Info("Skipping attaching provenance as it was attached by a previous run", "ref", ref)
+			return nil
+		}
+
+		slog.Info("Attaching provenance", "ref", ref)
+		if err := cosign.Attest(ctx, o.CosignPath, ref, predicatePath, provenance.PredicateType, parsedKey); err != nil {
+			return fmt.Errorf("failed to attach provenance to container image / manifest list %q: %w", ref, err)
+		}
+
+		o.checkpoint.completeItem(ctx, "pushcontainerimages", "attestation:"+ref, "")
+
+		return nil
+	}
+
+	for _, name := range sortedComponentNames(rel) {
+		for _, t := range rel.ComponentImageBundles[name] {
+			if _, err := forEachRepository(pushedRepos[t], func(repo string) error {
+				return attestRef(buildImageTag(repo, name, t.Architecture(), rel.ReleaseVersion))
+			}); err != nil {
+				return err
+			}
+		}
+
+		if _, err := forEachRepository(pushedManifestListRepos[name], func(repo string) error {
+			return attestRef(buildManifestListName(repo, name, rel.ReleaseVersion))
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
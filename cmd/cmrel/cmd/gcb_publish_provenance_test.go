@@ -0,0 +1,124 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cert-manager/release/pkg/release"
+	"github.com/cert-manager/release/pkg/release/images"
+	"github.com/cert-manager/release/pkg/release/provenance"
+)
+
+func TestAttestRegistryContent(t *testing.T) {
+	dir := t.TempDir()
+
+	// fake cosign records the image each attestation is made for, along with
+	// the predicate type, and checks that the predicate file exists
+	logPath := filepath.Join(dir, "cosign.log")
+	cosignPath := filepath.Join(dir, "cosign")
+	script := "#!/bin/sh\nwhile [ $# -gt 1 ]; do case \"$1\" in --predicate) test -f \"$2\" || exit 1;; --type) type=\"$2\";; esac; shift; done\necho \"$type $1\" >> " + logPath + "\n"
+	if err := os.WriteFile(cosignPath, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	statement := &provenance.Statement{
+		Type:          provenance.StatementType,
+		PredicateType: provenance.PredicateType,
+	}
+
+	controller := fixtureImageTar(t, "controller", "amd64", "v1.15.0")
+	rel := &release.Unpacked{
+		ReleaseVersion: "v1.15.0",
+		ComponentImageBundles: map[string][]*images.Tar{
+			"controller": {controller},
+		},
+	}
+
+	pushedRepos := map[*images.Tar][]string{controller: {"quay.io/jetstack", "ghcr.io/cert-manager"}}
+	pushedManifestListRepos := map[string][]string{"controller": {"quay.io/jetstack"}}
+
+	tests := map[string]struct {
+		provenance  *provenance.Statement
+		skipSigning bool
+		attested    []string
+
+		expectedAttestations []string
+	}{
+		"every pushed image and manifest list is attested": {
+			provenance: statement,
+			expectedAttestations: []string{
+				provenance.PredicateType + " quay.io/jetstack/cert-manager-controller-amd64:v1.15.0",
+				provenance.PredicateType + " ghcr.io/cert-manager/cert-manager-controller-amd64:v1.15.0",
+				provenance.PredicateType + " quay.io/jetstack/cert-manager-controller:v1.15.0",
+			},
+		},
+		"attestations made by a previous run are skipped": {
+			provenance: statement,
+			attested:   []string{"quay.io/jetstack/cert-manager-controller-amd64:v1.15.0"},
+			expectedAttestations: []string{
+				provenance.PredicateType + " ghcr.io/cert-manager/cert-manager-controller-amd64:v1.15.0",
+				provenance.PredicateType + " quay.io/jetstack/cert-manager-controller:v1.15.0",
+			},
+		},
+		"release without provenance": {
+			provenance: nil,
+		},
+		"signing skipped": {
+			provenance:  statement,
+			skipSigning: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			os.Remove(logPath)
+
+			o := NewGCBPublishOptions()
+			o.CosignPath = cosignPath
+			o.SigningKMSKey = defaultKMSKey
+			o.SkipSigning = test.skipSigning
+			o.provenance = test.provenance
+			o.checkpoint = &publishCheckpoint{
+				state: release.NewPublishState(),
+				save:  func(context.Context, *release.PublishState) error { return nil },
+			}
+
+			for _, ref := range test.attested {
+				o.checkpoint.completeItem(context.TODO(), "pushcontainerimages", "attestation:"+ref, "")
+			}
+
+			if err := attestRegistryContent(context.TODO(), o, rel, pushedRepos, pushedManifestListRepos); err != nil {
+				t.Fatal(err)
+			}
+
+			var attestations []string
+			if data, err := os.ReadFile(logPath); err == nil {
+				attestations = strings.Split(strings.TrimSpace(string(data)), "\n")
+			}
+
+			if !reflect.DeepEqual(attestations, test.expectedAttestations) {
+				t.Errorf("wanted attestations %q but got %q", test.expectedAttestations, attestations)
+			}
+		})
+	}
+}
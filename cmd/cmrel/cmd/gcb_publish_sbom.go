@@ -0,0 +1,162 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sort"
+
+	"github.com/google/go-github/v35/github"
+
+	"github.com/cert-manager/release/pkg/release"
+	"github.com/cert-manager/release/pkg/release/images"
+	"github.com/cert-manager/release/pkg/release/sbom"
+	"github.com/cert-manager/release/pkg/retry"
+	"github.com/cert-manager/release/pkg/sign/cosign"
+)
+
+// releaseSBOMs holds the SBOMs generated for the artifacts of an unpacked
+// release.
+type releaseSBOMs struct {
+	// format is the format of every SBOM, e.g. "spdx-json".
+	format string
+
+	// images maps each image tar to the path of its SBOM.
+	images map[*images.Tar]string
+
+	// assets maps the GitHub release asset name of each SBOM to its path.
+	assets map[string]string
+}
+
+// generateReleaseSBOMs uses syft to generate an SBOM in the given format for
+// each image and ctl binary bundle in rel, writing them to dir.
+func generateReleaseSBOMs(ctx context.Context, syftPath, format, dir string, rel *release.Unpacked) (*releaseSBOMs, error) {
+	sboms := &releaseSBOMs{
+		format: format,
+		images: map[*images.Tar]string{},
+		assets: map[string]string{},
+	}
+
+	generate := func(source, name string) (string, error) {
+		assetName := sbom.FileName(name, format)
+		path := filepath.Join(dir, assetName)
+
+		if _, ok := sboms.assets[assetName]; ok {
+			return "", fmt.Errorf("found more than one artifact with SBOM name %q", assetName)
+		}
+
+		slog.Info("Generating SBOM", "asset", assetName)
+		if err := sbom.Generate(ctx, syftPath, source, format, path); err != nil {
+			return "", fmt.Errorf("failed to generate SBOM for %q: %w", name, err)
+		}
+
+		sboms.assets[assetName] = path
+		return path, nil
+	}
+
+	for _, name := range sortedComponentNames(rel) {
+		for _, t := range rel.ComponentImageBundles[name] {
+			path, err := generate(sbom.DockerArchiveSource(t.Filepath()), fmt.Sprintf("cert-manager-%s-%s", name, t.Architecture()))
+			if err != nil {
+				return nil, err
+			}
+
+			sboms.images[t] = path
+		}
+	}
+
+	if release.CmctlIsShipped(rel.ReleaseVersion) {
+		for _, bundle := range rel.CtlBinaryBundles {
+			if _, err := generate(sbom.FileSource(bundle.Filepath()), bundle.ArtifactFilename()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return sboms, nil
+}
+
+// sortedComponentNames returns the names of the image components in rel in
+// alphabetical order.
+func sortedComponentNames(rel *release.Unpacked) []string {
+	names := make([]string, 0, len(rel.ComponentImageBundles))
+	for name := range rel.ComponentImageBundles {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// attachImageSBOMs uses cosign to attach the SBOM of each image in rel to the
+// image it was generated for, in each of the repositories it was pushed to
+// according to pushedRepos. An error is only returned if an SBOM couldn't be
+// attached in any repository.
+// It does nothing if SBOMs weren't generated.
+func attachImageSBOMs(ctx context.Context, o *gcbPublishOptions, rel *release.Unpacked, pushedRepos map[*images.Tar][]string) error {
+	if o.sboms == nil {
+		return nil
+	}
+
+	slog.Info("Attaching SBOMs to container images")
+
+	for _, name := range sortedComponentNames(rel) {
+		for _, t := range rel.ComponentImageBundles[name] {
+			path, ok := o.sboms.images[t]
+			if !ok {
+				continue
+			}
+
+			if _, err := forEachRepository(pushedRepos[t], func(repo string) error {
+				imageTag := buildImageTag(repo, name, t.Architecture(), rel.ReleaseVersion)
+
+				if _, done := o.checkpoint.item("pushcontainerimages", "sbom:"+imageTag); done {
+					slog.Info("Skipping attaching SBOM as it was attached by a previous run", "image", imageTag)
+					return nil
+				}
+
+				slog.Info("Attaching SBOM", "image", imageTag)
+				if err := retry.Do(ctx, func() error {
+					return cosign.AttachSBOM(ctx, o.CosignPath, imageTag, path, sbom.CosignType(o.sboms.format))
+				}); err != nil {
+					return fmt.Errorf("failed to attach SBOM to %q: %w", imageTag, err)
+				}
+
+				o.checkpoint.completeItem(ctx, "pushcontainerimages", "sbom:"+imageTag, "")
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// uploadSBOMAssets uploads each generated SBOM as an asset of the given GitHub
+// release. It does nothing if SBOMs weren't generated.
+func uploadSBOMAssets(ctx context.Context, o *gcbPublishOptions, githubClient *github.Client, githubRelease *github.RepositoryRelease) error {
+	if o.sboms == nil {
+		return nil
+	}
+
+	slog.Info("Uploading SBOMs to GitHub release", "count", len(o.sboms.assets))
+	return uploadGitHubReleaseAssets(ctx, o, githubClient, o.gitHubReleaseRepo(), githubRelease, o.sboms.assets)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/cert-manager/release/pkg/release"
+	"github.com/cert-manager/release/pkg/release/images"
+)
+
+func TestGenerateReleaseSBOMs(t *testing.T) {
+	dir := t.TempDir()
+
+	syftPath := filepath.Join(dir, "syft")
+	script := "#!/bin/sh\nfor arg; do case \"$arg\" in *=*) out=\"${arg#*=}\";; esac; done\necho '{}' > \"$out\"\n"
+	if err := os.WriteFile(syftPath, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	controller := fixtureImageTar(t, "controller", "amd64", "v1.15.0")
+	webhookAMD64 := fixtureImageTar(t, "webhook", "amd64", "v1.15.0")
+	webhookARM64 := fixtureImageTar(t, "webhook", "arm64", "v1.15.0")
+
+	rel := &release.Unpacked{
+		ReleaseVersion: "v1.15.0",
+		ComponentImageBundles: map[string][]*images.Tar{
+			"controller": {controller},
+			"webhook":    {webhookAMD64, webhookARM64},
+		},
+	}
+
+	sbomDir := t.TempDir()
+
+	sboms, err := generateReleaseSBOMs(context.Background(), syftPath, "spdx-json", sbomDir, rel)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var assetNames []string
+	for name, path := range sboms.assets {
+		assetNames = append(assetNames, name)
+
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected SBOM %q to be written: %v", name, err)
+		}
+	}
+	sort.Strings(assetNames)
+
+	expected := []string{
+		"cert-manager-controller-amd64.spdx.json",
+		"cert-manager-webhook-amd64.spdx.json",
+		"cert-manager-webhook-arm64.spdx.json",
+	}
+
+	if len(assetNames) != len(expected) {
+		t.Fatalf("expected SBOMs %v but got %v", expected, assetNames)
+	}
+
+	for i := range expected {
+		if assetNames[i] != expected[i] {
+			t.Errorf("expected SBOMs %v but got %v", expected, assetNames)
+			break
+		}
+	}
+
+	if sboms.images[webhookARM64] != filepath.Join(sbomDir, "cert-manager-webhook-arm64.spdx.json") {
+		t.Errorf("unexpected SBOM path %q for webhook arm64 image", sboms.images[webhookARM64])
+	}
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cert-manager/release/pkg/release"
+)
+
+// publishCheckpoint records progress through the publish actions, so that
+// re-running a publish which failed part way through skips the steps which
+// had already completed.
+// A nil *publishCheckpoint records nothing and treats every step as
+// incomplete. A publishCheckpoint is safe for concurrent use, so that items
+// of an action can be completed in parallel.
+type publishCheckpoint struct {
+	state *release.PublishState
+	save  func(ctx context.Context, state *release.PublishState) error
+
+	// mu guards state, and serializes saving it
+	mu sync.Mutex
+}
+
+// newBucketPublishCheckpoint returns a checkpoint which stores the publish
+// state in the root of the named staged release.
+func newBucketPublishCheckpoint(state *release.PublishState, bucket *release.Bucket, releaseName string) *publishCheckpoint {
+	return &publishCheckpoint{
+		state: state,
+		save: func(ctx context.Context, state *release.PublishState) error {
+			return bucket.WritePublishState(ctx, releaseName, state)
+		},
+	}
+}
+
+// actionCompleted returns true if the named action has already completed.
+func (c *publishCheckpoint) actionCompleted(action string) bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.state.ActionCompleted(action)
+}
+
+// completeAction records that the named action has completed.
+func (c *publishCheckpoint) completeAction(ctx context.Context, action string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.state.CompleteAction(action)
+	c.persist(ctx)
+}
+
+// item returns the value recorded for the given item of the named action, and
+// whether the item has already been completed.
+func (c *publishCheckpoint) item(action, item string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.state.Item(action, item)
+}
+
+// completeItem records that the given item of the named action has completed,
+// along with a value needed to resume from it.
+func (c *publishCheckpoint) completeItem(ctx context.Context, action, item, value string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.state.CompleteItem(action, item, value)
+	c.persist(ctx)
+}
+
+// itemsWithPrefix returns the completed items of the named action which start
+// with prefix, with the prefix removed, in alphabetical order.
+func (c *publishCheckpoint) itemsWithPrefix(action, prefix string) []string {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	a, ok := c.state.Actions[action]
+	if !ok {
+		return nil
+	}
+
+	var items []string
+	for item := range a.Items {
+		if strings.HasPrefix(item, prefix) {
+			items = append(items, strings.TrimPrefix(item, prefix))
+		}
+	}
+	sort.Strings(items)
+
+	return items
+}
+
+// recordManualActions records the manual actions which must be taken after
+// publishing, so that they can be reported by whoever is waiting for the
+// publish to complete.
+func (c *publishCheckpoint) recordManualActions(ctx context.Context, text string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.state.ManualActions = text
+	c.persist(ctx)
+}
+
+// persist saves the publish state. Failing to save it doesn't affect what has
+// already been published, so errors are logged rather than aborting the
+// publish; at worst, a resumed publish repeats some steps.
+func (c *publishCheckpoint) persist(ctx context.Context) {
+	if err := c.save(ctx, c.state); err != nil {
+		slog.Warn("failed to save publish state", "error", err)
+	}
+}
+
+// runPublishActions runs each of the publish actions configured in o in order,
+// skipping those which have already completed according to o.checkpoint.
+func runPublishActions(ctx context.Context, o *gcbPublishOptions, rel *release.Unpacked) error {
+	publishFuncs, err := o.PublishActionList()
+	if err != nil {
+		return fmt.Errorf("failed to parse published artifacts list: %w", err)
+	}
+
+	// the list of actions has already been verified by PublishActionList
+	publishActionNames, _ := o.PublishActionNames()
+
+	for i, publishFunc := range publishFuncs {
+		name := publishActionNames[i]
+
+		if o.checkpoint.actionCompleted(name) {
+			slog.Info("Skipping publish action as it already completed in a previous run", "action", name)
+			continue
+		}
+
+		slog.Info("Running publish action", "action", name)
+
+		if err := publishFunc(ctx, o, rel); err != nil {
+			return errorDuringPublish(err)
+		}
+
+		o.checkpoint.completeAction(ctx, name)
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/cert-manager/release/pkg/release"
+)
+
+func TestRunPublishActionsCheckpoint(t *testing.T) {
+	var ran []string
+	failing := map[string]bool{}
+
+	recordAction := func(name string) publishAction {
+		return func(ctx context.Context, o *gcbPublishOptions, _ *release.Unpacked) error {
+			// each action publishes two items, and fails between them if
+			// it's marked as failing
+			for _, item := range []string{"first", "second"} {
+				if _, done := o.checkpoint.item(name, item); done {
+					continue
+				}
+
+				if item == "second" && failing[name] {
+					return errors.New("failed to publish " + name)
+				}
+
+				ran = append(ran, name+"/"+item)
+				o.checkpoint.completeItem(ctx, name, item, "")
+			}
+
+			return nil
+		}
+	}
+
+	originalActionMap := publishActionMap
+	t.Cleanup(func() { publishActionMap = originalActionMap })
+
+	publishActionMap = map[string]publishAction{
+		"alpha":   recordAction("alpha"),
+		"bravo":   recordAction("bravo"),
+		"charlie": recordAction("charlie"),
+	}
+
+	var saves int
+	checkpoint := &publishCheckpoint{
+		state: release.NewPublishState(),
+		save: func(context.Context, *release.PublishState) error {
+			saves++
+			return nil
+		},
+	}
+
+	o := NewGCBPublishOptions()
+	o.PublishActions = []string{"*"}
+	o.checkpoint = checkpoint
+
+	// the first attempt fails part way through the second action
+	failing["bravo"] = true
+	if err := runPublishActions(context.TODO(), o, &release.Unpacked{}); err == nil {
+		t.Fatalf("expected the first attempt to fail")
+	}
+
+	if exp := []string{"alpha/first", "alpha/second", "bravo/first"}; !reflect.DeepEqual(ran, exp) {
+		t.Errorf("unexpected steps run by the first attempt:\ngot=%q\nexp=%q", ran, exp)
+	}
+
+	if !checkpoint.state.ActionCompleted("alpha") || checkpoint.state.ActionCompleted("bravo") {
+		t.Errorf("expected only alpha to be completed, got %+v", checkpoint.state.Actions)
+	}
+
+	// the second attempt resumes from the failed step
+	ran = nil
+	failing["bravo"] = false
+	if err := runPublishActions(context.TODO(), o, &release.Unpacked{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp := []string{"bravo/second", "charlie/first", "charlie/second"}; !reflect.DeepEqual(ran, exp) {
+		t.Errorf("unexpected steps run by the second attempt:\ngot=%q\nexp=%q", ran, exp)
+	}
+
+	// a third attempt has nothing left to do
+	ran = nil
+	if err := runPublishActions(context.TODO(), o, &release.Unpacked{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ran) != 0 {
+		t.Errorf("expected no steps to run once every action completed, got %q", ran)
+	}
+
+	// every completed item and action is saved: 6 items and 3 actions
+	if saves != 9 {
+		t.Errorf("expected the publish state to be saved 9 times, got %d", saves)
+	}
+
+	// without a checkpoint, every action runs
+	ran = nil
+	o.checkpoint = nil
+	if err := runPublishActions(context.TODO(), o, &release.Unpacked{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ran) != 6 {
+		t.Errorf("expected every step to run without a checkpoint, got %q", ran)
+	}
+}
@@ -17,10 +17,24 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"strings"
 	"testing"
+
+	"github.com/google/go-github/v35/github"
+
+	"github.com/cert-manager/release/pkg/release"
+	"github.com/cert-manager/release/pkg/release/images"
+	"github.com/cert-manager/release/pkg/sign"
 )
 
 func sortedSlice(s []string) []string {
@@ -29,6 +43,7 @@ func sortedSlice(s []string) []string {
 }
 
 func TestCanonicalizeAndVerifyPublishActions(t *testing.T) {
+	allActionsInOrder := []string{"cmctlgithubrelease", "helmchartoci", "helmchartpr", "helmchartrepo", "pushcontainerimages", "githubrelease"}
 	oneAction := allPublishActionNames()[0]
 	twoAction := allPublishActionNames()[1]
 
@@ -39,12 +54,12 @@ func TestCanonicalizeAndVerifyPublishActions(t *testing.T) {
 	}{
 		"basic case with '*'": {
 			inputActions:   []string{"*"},
-			expectedOutput: sortedSlice(allPublishActionNames()),
+			expectedOutput: allActionsInOrder,
 			expectErr:      false,
 		},
 		"basic case with all action names": {
 			inputActions:   allPublishActionNames(),
-			expectedOutput: sortedSlice(allPublishActionNames()),
+			expectedOutput: allActionsInOrder,
 			expectErr:      false,
 		},
 		"actions form a set": {
@@ -77,6 +92,26 @@ func TestCanonicalizeAndVerifyPublishActions(t *testing.T) {
 			expectedOutput: nil,
 			expectErr:      true,
 		},
+		"dependencies run first": {
+			inputActions:   []string{"githubrelease", "pushcontainerimages"},
+			expectedOutput: []string{"pushcontainerimages", "githubrelease"},
+			expectErr:      false,
+		},
+		"removing a prerequisite of a selected action should error": {
+			inputActions:   []string{"*", "-pushcontainerimages"},
+			expectedOutput: nil,
+			expectErr:      true,
+		},
+		"removing a prerequisite along with the actions depending on it": {
+			inputActions:   []string{"*", "-pushcontainerimages", "-githubrelease"},
+			expectedOutput: []string{"cmctlgithubrelease", "helmchartoci", "helmchartpr", "helmchartrepo"},
+			expectErr:      false,
+		},
+		"a removed prerequisite which is added back": {
+			inputActions:   []string{"-pushcontainerimages", "githubrelease", "pushcontainerimages"},
+			expectedOutput: []string{"pushcontainerimages", "githubrelease"},
+			expectErr:      false,
+		},
 		"action cleanup": {
 			inputActions:   []string{"   " + strings.ToUpper(oneAction) + "   "},
 			expectedOutput: []string{oneAction},
@@ -102,3 +137,676 @@ func TestCanonicalizeAndVerifyPublishActions(t *testing.T) {
 		})
 	}
 }
+
+func TestPublishActionListResumeFromAction(t *testing.T) {
+	var ran []string
+
+	recordAction := func(name string) publishAction {
+		return func(context.Context, *gcbPublishOptions, *release.Unpacked) error {
+			ran = append(ran, name)
+			return nil
+		}
+	}
+
+	originalActionMap := publishActionMap
+	t.Cleanup(func() { publishActionMap = originalActionMap })
+
+	publishActionMap = map[string]publishAction{
+		"alpha":   recordAction("alpha"),
+		"bravo":   recordAction("bravo"),
+		"charlie": recordAction("charlie"),
+	}
+
+	tests := map[string]struct {
+		publishActions   []string
+		resumeFromAction string
+		expectedRun      []string
+		expectErr        bool
+	}{
+		"no resume runs all actions": {
+			publishActions: []string{"*"},
+			expectedRun:    []string{"alpha", "bravo", "charlie"},
+		},
+		"resuming from the first action runs all actions": {
+			publishActions:   []string{"*"},
+			resumeFromAction: "alpha",
+			expectedRun:      []string{"alpha", "bravo", "charlie"},
+		},
+		"resuming from a later action skips earlier actions": {
+			publishActions:   []string{"*"},
+			resumeFromAction: "bravo",
+			expectedRun:      []string{"bravo", "charlie"},
+		},
+		"resuming from the last action runs only the last action": {
+			publishActions:   []string{"*"},
+			resumeFromAction: " CHARLIE ",
+			expectedRun:      []string{"charlie"},
+		},
+		"resuming from a removed action runs the actions after it": {
+			publishActions:   []string{"*", "-bravo"},
+			resumeFromAction: "bravo",
+			expectedRun:      []string{"charlie"},
+		},
+		"resuming from an unknown action should error": {
+			publishActions:   []string{"*"},
+			resumeFromAction: "notanaction",
+			expectErr:        true,
+		},
+		"resuming past all selected actions should error": {
+			publishActions:   []string{"alpha", "bravo"},
+			resumeFromAction: "charlie",
+			expectErr:        true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ran = nil
+
+			o := NewGCBPublishOptions()
+			o.PublishActions = test.publishActions
+			o.ResumeFromAction = test.resumeFromAction
+
+			actions, err := o.PublishActionList()
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			for _, action := range actions {
+				if err := action(context.Background(), o, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if !reflect.DeepEqual(ran, test.expectedRun) {
+				t.Errorf("wanted actions %q to run but got %q", test.expectedRun, ran)
+			}
+		})
+	}
+}
+
+func TestOrderPublishActions(t *testing.T) {
+	originalDependencies := publishActionDependencies
+	t.Cleanup(func() { publishActionDependencies = originalDependencies })
+
+	tests := map[string]struct {
+		dependencies map[string][]string
+		actions      []string
+		expected     []string
+		expectErr    bool
+	}{
+		"no dependencies is alphabetical": {
+			actions:  []string{"alpha", "bravo", "charlie"},
+			expected: []string{"alpha", "bravo", "charlie"},
+		},
+		"dependencies run first": {
+			dependencies: map[string][]string{"alpha": {"charlie"}},
+			actions:      []string{"alpha", "bravo", "charlie"},
+			expected:     []string{"bravo", "charlie", "alpha"},
+		},
+		"transitive dependencies": {
+			dependencies: map[string][]string{"alpha": {"bravo"}, "bravo": {"charlie"}},
+			actions:      []string{"alpha", "bravo", "charlie"},
+			expected:     []string{"charlie", "bravo", "alpha"},
+		},
+		"dependencies which aren't selected are ignored": {
+			dependencies: map[string][]string{"alpha": {"charlie"}},
+			actions:      []string{"alpha", "bravo"},
+			expected:     []string{"alpha", "bravo"},
+		},
+		"cycle should error": {
+			dependencies: map[string][]string{"alpha": {"bravo"}, "bravo": {"alpha"}},
+			actions:      []string{"alpha", "bravo", "charlie"},
+			expectErr:    true,
+		},
+		"no actions": {
+			actions: nil,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			publishActionDependencies = test.dependencies
+
+			ordered, err := orderPublishActions(test.actions)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if !reflect.DeepEqual(ordered, test.expected) {
+				t.Errorf("wanted %q but got %q", test.expected, ordered)
+			}
+		})
+	}
+}
+
+func TestPublishActionDependencies(t *testing.T) {
+	for action, deps := range publishActionDependencies {
+		if _, ok := publishActionMap[action]; !ok {
+			t.Errorf("dependencies listed for unknown publish action %q", action)
+		}
+
+		for _, dep := range deps {
+			if _, ok := publishActionMap[dep]; !ok {
+				t.Errorf("publish action %q depends on unknown action %q", action, dep)
+			}
+		}
+	}
+
+	if _, err := orderPublishActions(allPublishActionNames()); err != nil {
+		t.Errorf("failed to order all publish actions: %v", err)
+	}
+}
+
+func TestResumePublishActionsUsesRunOrder(t *testing.T) {
+	// githubrelease sorts first alphabetically, but runs after images are
+	// pushed, so resuming from it shouldn't run the image push again
+	ordered, err := orderPublishActions(allPublishActionNames())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actions, err := resumePublishActions(ordered, "githubrelease")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"githubrelease"}
+	if !reflect.DeepEqual(actions, expected) {
+		t.Errorf("wanted %q but got %q", expected, actions)
+	}
+}
+
+func TestSignGitHubReleaseAssets(t *testing.T) {
+	dir := t.TempDir()
+
+	// fake cosign writes a signature to the path passed to --output-signature
+	cosignPath := filepath.Join(dir, "cosign")
+	script := "#!/bin/sh\nwhile [ $# -gt 0 ]; do if [ \"$1\" = --output-signature ]; then echo sig > \"$2\"; fi; shift; done\n"
+	if err := os.WriteFile(cosignPath, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	assets := map[string]string{
+		"cert-manager.yaml":        "/tmp/manifests/cert-manager.yaml",
+		"cmctl-linux-amd64.tar.gz": "/tmp/cmctl.tar.gz",
+	}
+
+	tests := map[string]struct {
+		skipSigning   bool
+		signingKMSKey string
+		uploaded      []string
+
+		expectedSignatures []string
+		expectErr          bool
+	}{
+		"all assets are signed": {
+			signingKMSKey:      defaultKMSKey,
+			expectedSignatures: []string{"cert-manager.yaml.sig", "cmctl-linux-amd64.tar.gz.sig"},
+		},
+		"signatures uploaded by a previous run are skipped": {
+			signingKMSKey:      defaultKMSKey,
+			uploaded:           []string{"cert-manager.yaml.sig"},
+			expectedSignatures: []string{"cmctl-linux-amd64.tar.gz.sig"},
+		},
+		"signing skipped": {
+			skipSigning: true,
+		},
+		"no signing key": {
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			o := NewGCBPublishOptions()
+			o.CosignPath = cosignPath
+			o.SkipSigning = test.skipSigning
+			o.SigningKMSKey = test.signingKMSKey
+			o.checkpoint = &publishCheckpoint{
+				state: release.NewPublishState(),
+				save:  func(context.Context, *release.PublishState) error { return nil },
+			}
+
+			for _, uploaded := range test.uploaded {
+				o.checkpoint.completeItem(context.TODO(), "githubrelease", "asset:"+uploaded, "")
+			}
+
+			signatureDir := t.TempDir()
+
+			signatures, err := signGitHubReleaseAssets(context.TODO(), o, o.gitHubReleaseRepo(), assets, signatureDir)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if test.expectErr {
+				return
+			}
+
+			var names []string
+			for name, path := range signatures {
+				names = append(names, name)
+
+				if path != filepath.Join(signatureDir, name) {
+					t.Errorf("unexpected path %q for signature %q", path, name)
+				}
+
+				if _, err := os.Stat(path); err != nil {
+					t.Errorf("expected signature %q to be written: %v", name, err)
+				}
+			}
+
+			if !reflect.DeepEqual(sortedSlice(names), test.expectedSignatures) {
+				t.Errorf("wanted signatures %q but got %q", test.expectedSignatures, names)
+			}
+		})
+	}
+}
+
+func TestUploadedGitHubReleaseAssets(t *testing.T) {
+	o := NewGCBPublishOptions()
+	o.checkpoint = &publishCheckpoint{
+		state: release.NewPublishState(),
+		save:  func(context.Context, *release.PublishState) error { return nil },
+	}
+
+	// assets uploaded by a previous run are only recorded in the publish
+	// state, and other items of the action aren't assets
+	o.checkpoint.completeItem(context.TODO(), "githubrelease", "asset:cert-manager.yaml", "")
+	o.checkpoint.completeItem(context.TODO(), "githubrelease", "release", "123")
+	o.checkpoint.completeItem(context.TODO(), "cmctlgithubrelease", "asset:cmctl-linux-amd64.tar.gz", "")
+
+	o.recordUploadedAsset("githubrelease", "cert-manager.yaml.sig")
+	o.recordUploadedAsset("githubrelease", "cert-manager.yaml")
+
+	expected := []string{"cert-manager.yaml", "cert-manager.yaml.sig"}
+	if got := o.uploadedGitHubReleaseAssets("githubrelease"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("wanted assets %q but got %q", expected, got)
+	}
+}
+
+func TestPublishedImageRepositories(t *testing.T) {
+	o := NewGCBPublishOptions()
+	o.PublishedImageRepository = "quay.io/jetstack"
+	o.PublishedImageMirrorRepositories = []string{"ghcr.io/cert-manager", " quay.io/jetstack", ""}
+
+	expected := []string{"quay.io/jetstack", "ghcr.io/cert-manager"}
+	if got := o.publishedImageRepositories(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("wanted repositories %q but got %q", expected, got)
+	}
+}
+
+func TestHelmChartVerifyRepo(t *testing.T) {
+	tests := map[string]struct {
+		verifyHelmChartRepo string
+		actions             []string
+		expected            string
+	}{
+		"an explicit repository is always used": {
+			verifyHelmChartRepo: "https://example.com/charts",
+			actions:             []string{"helmchartoci"},
+			expected:            "https://example.com/charts",
+		},
+		"charts pushed to an OCI registry are verified there": {
+			actions:  []string{"helmchartoci", "helmchartpr"},
+			expected: "oci://quay.io/jetstack/charts",
+		},
+		"charts uploaded to a chart repository are verified there": {
+			actions:  []string{"helmchartrepo", "helmchartpr"},
+			expected: "https://charts.example.com",
+		},
+		"charts only in a chart PR are verified against the public repository": {
+			actions:  []string{"helmchartpr"},
+			expected: release.DefaultHelmChartRepositoryURL,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			o := NewGCBPublishOptions()
+			o.VerifyHelmChartRepo = test.verifyHelmChartRepo
+			o.PublishedHelmChartOCIRegistry = "oci://quay.io/jetstack/charts"
+			o.PublishedHelmChartRepoBucket = "charts-bucket"
+			o.PublishedHelmChartRepoURL = "https://charts.example.com"
+
+			if got := o.helmChartVerifyRepo(test.actions); got != test.expected {
+				t.Errorf("wanted repository %q but got %q", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestSignImage(t *testing.T) {
+	dir := t.TempDir()
+
+	// fake cosign records its arguments and the service account it was asked
+	// to impersonate
+	argsPath := filepath.Join(dir, "args")
+	cosignPath := filepath.Join(dir, "cosign")
+	script := fmt.Sprintf("#!/bin/sh\necho \"$GOOGLE_SERVICE_ACCOUNT_NAME $*\" > %q\n", argsPath)
+	if err := os.WriteFile(cosignPath, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		signingMode           string
+		keylessServiceAccount string
+
+		expectedArgs string
+		expectErr    bool
+	}{
+		"kms signing": {
+			signingMode:  signingModeKMS,
+			expectedArgs: " sign --key " + defaultKMSKeyCosignFormat(t) + " quay.io/jetstack/cert-manager-controller:v1.2.3",
+		},
+		"keyless signing": {
+			signingMode:  signingModeKeyless,
+			expectedArgs: " sign --yes --tlog-upload=true quay.io/jetstack/cert-manager-controller:v1.2.3",
+		},
+		"keyless signing with a service account": {
+			signingMode:           signingModeKeyless,
+			keylessServiceAccount: "builder@example.iam.gserviceaccount.com",
+			expectedArgs:          "builder@example.iam.gserviceaccount.com sign --yes --tlog-upload=true quay.io/jetstack/cert-manager-controller:v1.2.3",
+		},
+		"unknown signing mode": {
+			signingMode: "magic",
+			expectErr:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("GOOGLE_SERVICE_ACCOUNT_NAME", "")
+
+			o := NewGCBPublishOptions()
+			o.CosignPath = cosignPath
+			o.SigningKMSKey = defaultKMSKey
+			o.SigningMode = test.signingMode
+			o.KeylessServiceAccount = test.keylessServiceAccount
+
+			signImage, err := o.signImage()
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if test.expectErr {
+				return
+			}
+
+			if err := signImage(context.TODO(), "quay.io/jetstack/cert-manager-controller:v1.2.3"); err != nil {
+				t.Fatal(err)
+			}
+
+			args, err := os.ReadFile(argsPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := strings.TrimSuffix(string(args), "\n"); got != test.expectedArgs {
+				t.Errorf("wanted cosign to be called with %q but got %q", test.expectedArgs, got)
+			}
+		})
+	}
+}
+
+// defaultKMSKeyCosignFormat returns defaultKMSKey in the format passed to
+// cosign's --key flag.
+func defaultKMSKeyCosignFormat(t *testing.T) string {
+	key, err := sign.NewGCPKMSKey(defaultKMSKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key.CosignFormat()
+}
+
+func TestForEachRepository(t *testing.T) {
+	tests := map[string]struct {
+		repos   []string
+		failing []string
+
+		expectedSucceeded []string
+		expectErr         bool
+	}{
+		"all repositories succeed": {
+			repos:             []string{"quay.io/jetstack", "ghcr.io/cert-manager"},
+			expectedSucceeded: []string{"quay.io/jetstack", "ghcr.io/cert-manager"},
+		},
+		"primary repository fails": {
+			repos:             []string{"quay.io/jetstack", "ghcr.io/cert-manager"},
+			failing:           []string{"quay.io/jetstack"},
+			expectedSucceeded: []string{"ghcr.io/cert-manager"},
+		},
+		"every repository fails": {
+			repos:     []string{"quay.io/jetstack", "ghcr.io/cert-manager"},
+			failing:   []string{"quay.io/jetstack", "ghcr.io/cert-manager"},
+			expectErr: true,
+		},
+		"no repositories": {
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var called []string
+			succeeded, err := forEachRepository(test.repos, func(repo string) error {
+				called = append(called, repo)
+				for _, failing := range test.failing {
+					if repo == failing {
+						return fmt.Errorf("push to %s failed", repo)
+					}
+				}
+
+				return nil
+			})
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if !reflect.DeepEqual(called, test.repos) {
+				t.Errorf("expected every repository to be tried, but only %q were", called)
+			}
+
+			if !reflect.DeepEqual(succeeded, test.expectedSucceeded) {
+				t.Errorf("wanted succeeded repositories %q but got %q", test.expectedSucceeded, succeeded)
+			}
+		})
+	}
+}
+
+func TestReposWithAllImages(t *testing.T) {
+	amd64 := &images.Tar{}
+	arm64 := &images.Tar{}
+
+	pushedRepos := map[*images.Tar][]string{
+		amd64: {"quay.io/jetstack", "ghcr.io/cert-manager"},
+		arm64: {"quay.io/jetstack"},
+	}
+
+	expected := []string{"quay.io/jetstack"}
+	if got := reposWithAllImages([]string{"quay.io/jetstack", "ghcr.io/cert-manager"}, []*images.Tar{amd64, arm64}, pushedRepos); !reflect.DeepEqual(got, expected) {
+		t.Errorf("wanted repositories %q but got %q", expected, got)
+	}
+}
+
+// newFakeGitHubClient returns a GitHub client which sends every request,
+// including uploads, to handler.
+func newFakeGitHubClient(t *testing.T, handler http.HandlerFunc) *github.Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	baseURL, err := url.Parse(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := github.NewClient(nil)
+	client.BaseURL = baseURL
+	client.UploadURL = baseURL
+
+	return client
+}
+
+func TestFindDraftGitHubRelease(t *testing.T) {
+	target := gitHubReleaseRepo{action: "githubrelease", org: "cert-manager", repo: "cert-manager"}
+
+	client := newFakeGitHubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/repos/cert-manager/cert-manager/releases" {
+			http.Error(w, "unexpected request "+r.Method+" "+r.URL.Path, http.StatusNotFound)
+			return
+		}
+
+		// the draft is on the second page, to check that every page is
+		// searched
+		var releases []*github.RepositoryRelease
+		if r.URL.Query().Get("page") == "2" {
+			releases = []*github.RepositoryRelease{
+				{ID: github.Int64(2), TagName: github.String("v1.15.0"), Draft: github.Bool(true)},
+			}
+		} else {
+			w.Header().Set("Link", `<`+"http://"+r.Host+r.URL.Path+`?page=2>; rel="next"`)
+			releases = []*github.RepositoryRelease{
+				{ID: github.Int64(1), TagName: github.String("v1.15.0"), Draft: github.Bool(false)},
+				{ID: github.Int64(3), TagName: github.String("v1.14.0"), Draft: github.Bool(true)},
+			}
+		}
+
+		if err := json.NewEncoder(w).Encode(releases); err != nil {
+			t.Errorf("failed to encode response: %v", err)
+		}
+	})
+
+	found, err := findDraftGitHubRelease(context.TODO(), client, target, "v1.15.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if found.GetID() != 2 {
+		t.Errorf("expected to find draft release 2 but got %v", found)
+	}
+
+	missing, err := findDraftGitHubRelease(context.TODO(), client, target, "v1.16.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if missing != nil {
+		t.Errorf("expected no draft release but got %v", missing)
+	}
+}
+
+func TestUploadGitHubReleaseAssetReusesExistingAsset(t *testing.T) {
+	target := gitHubReleaseRepo{action: "githubrelease", org: "cert-manager", repo: "cert-manager"}
+
+	path := filepath.Join(t.TempDir(), "cmctl.tar.gz")
+	if err := os.WriteFile(path, []byte("cmctl"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := newFakeGitHubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/cert-manager/cert-manager/releases/123/assets":
+			// the asset already exists, as if an earlier attempt which
+			// appeared to fail had uploaded it
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"message": "Validation Failed", "errors": [{"resource": "ReleaseAsset", "code": "already_exists", "field": "name"}]}`))
+
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/cert-manager/cert-manager/releases/123/assets":
+			assets := []*github.ReleaseAsset{
+				{ID: github.Int64(7), Name: github.String("cmctl.tar.gz"), State: github.String("uploaded"), Size: github.Int(len("cmctl"))},
+			}
+			if err := json.NewEncoder(w).Encode(assets); err != nil {
+				t.Errorf("failed to encode response: %v", err)
+			}
+
+		default:
+			http.Error(w, "unexpected request "+r.Method+" "+r.URL.Path, http.StatusNotFound)
+		}
+	})
+
+	githubRelease := &github.RepositoryRelease{ID: github.Int64(123), Name: github.String("v1.15.0")}
+	if err := uploadGitHubReleaseAsset(context.TODO(), client, target, githubRelease, "cmctl.tar.gz", path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReuseOrDeleteGitHubReleaseAsset(t *testing.T) {
+	target := gitHubReleaseRepo{action: "githubrelease", org: "cert-manager", repo: "cert-manager"}
+
+	tests := map[string]struct {
+		existing []*github.ReleaseAsset
+
+		expectReused  bool
+		expectDeleted bool
+	}{
+		"a completely uploaded asset is reused": {
+			existing: []*github.ReleaseAsset{
+				{ID: github.Int64(7), Name: github.String("cmctl.tar.gz"), State: github.String("uploaded"), Size: github.Int(5)},
+			},
+			expectReused: true,
+		},
+		"an asset still being uploaded is deleted": {
+			existing: []*github.ReleaseAsset{
+				{ID: github.Int64(7), Name: github.String("cmctl.tar.gz"), State: github.String("starter"), Size: github.Int(5)},
+			},
+			expectDeleted: true,
+		},
+		"an asset of the wrong size is deleted": {
+			existing: []*github.ReleaseAsset{
+				{ID: github.Int64(7), Name: github.String("cmctl.tar.gz"), State: github.String("uploaded"), Size: github.Int(2)},
+			},
+			expectDeleted: true,
+		},
+		"other assets are ignored": {
+			existing: []*github.ReleaseAsset{
+				{ID: github.Int64(8), Name: github.String("cmctl.zip"), State: github.String("starter"), Size: github.Int(2)},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var deleted []string
+			client := newFakeGitHubClient(t, func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodGet && r.URL.Path == "/repos/cert-manager/cert-manager/releases/123/assets":
+					if err := json.NewEncoder(w).Encode(test.existing); err != nil {
+						t.Errorf("failed to encode response: %v", err)
+					}
+
+				case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/repos/cert-manager/cert-manager/releases/assets/"):
+					deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/repos/cert-manager/cert-manager/releases/assets/"))
+					w.WriteHeader(http.StatusNoContent)
+
+				default:
+					http.Error(w, "unexpected request "+r.Method+" "+r.URL.Path, http.StatusNotFound)
+				}
+			})
+
+			asset, err := reuseOrDeleteGitHubReleaseAsset(context.TODO(), client, target, 123, "cmctl.tar.gz", 5)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if (asset != nil) != test.expectReused {
+				t.Errorf("expected reused=%v but got asset %v", test.expectReused, asset)
+			}
+
+			var expectedDeleted []string
+			if test.expectDeleted {
+				expectedDeleted = []string{"7"}
+			}
+
+			if !reflect.DeepEqual(deleted, expectedDeleted) {
+				t.Errorf("expected deleted assets %q but got %q", expectedDeleted, deleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/cert-manager/release/pkg/release"
+)
+
+const (
+	downloadURLsFormatMarkdown = "markdown"
+	downloadURLsFormatJSON     = "json"
+)
+
+// publishedURLs lists the consumer-facing locations of a published release,
+// suitable for including in release notes and announcements.
+type publishedURLs struct {
+	ReleaseVersion string `json:"releaseVersion"`
+
+	// GitHubReleaseURL is the URL of the GitHub release page. Only set if the
+	// GitHub release was published.
+	GitHubReleaseURL string `json:"githubReleaseURL,omitempty"`
+
+	// GitHubAssets are the files uploaded to the GitHub release.
+	GitHubAssets []publishedAsset `json:"githubAssets,omitempty"`
+
+	// Images are the multi-arch images pushed to the image repository.
+	Images []publishedImage `json:"images,omitempty"`
+
+	// HelmInstallCommand is a command which can be used to install the
+	// published Helm chart. Only set if the Helm chart was published.
+	HelmInstallCommand string `json:"helmInstallCommand,omitempty"`
+}
+
+type publishedAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type publishedImage struct {
+	Name        string `json:"name"`
+	PullCommand string `json:"pullCommand"`
+}
+
+// buildPublishedURLs derives the download URLs and commands for rel based on
+// the given publish actions.
+func buildPublishedURLs(o *gcbPublishOptions, rel *release.Unpacked, actions []string) *publishedURLs {
+	actionSet := sets.NewString(actions...)
+
+	urls := &publishedURLs{
+		ReleaseVersion: rel.ReleaseVersion,
+	}
+
+	if actionSet.Has("githubrelease") {
+		urls.GitHubReleaseURL = fmt.Sprintf("https://github.com/%s/%s/releases/tag/%s", o.PublishedGitHubOrg, o.PublishedGitHubRepo, rel.ReleaseVersion)
+
+		// only list the assets which were actually uploaded, including any
+		// signatures, SBOMs and digest-pinned manifests
+		assetNames := o.uploadedGitHubReleaseAssets(o.gitHubReleaseRepo().action)
+
+		for _, name := range assetNames {
+			urls.GitHubAssets = append(urls.GitHubAssets, publishedAsset{
+				Name: name,
+				URL:  fmt.Sprintf("https://github.com/%s/%s/releases/download/%s/%s", o.PublishedGitHubOrg, o.PublishedGitHubRepo, rel.ReleaseVersion, name),
+			})
+		}
+	}
+
+	if actionSet.Has("pushcontainerimages") {
+		for _, name := range sets.StringKeySet(rel.ComponentImageBundles).List() {
+			manifestListName := buildManifestListName(o.PublishedImageRepository, name, rel.ReleaseVersion)
+			urls.Images = append(urls.Images, publishedImage{
+				Name:        manifestListName,
+				PullCommand: "docker pull " + manifestListName,
+			})
+		}
+	}
+
+	if actionSet.Has("helmchartpr") && len(rel.Charts) > 0 {
+		chart := rel.Charts[0]
+		urls.HelmInstallCommand = fmt.Sprintf("helm install %s %s --repo %s --version %s --namespace cert-manager --create-namespace", chart.Name(), chart.Name(), release.DefaultHelmChartRepositoryURL, chart.Version())
+	} else if actionSet.Has("helmchartrepo") && o.PublishedHelmChartRepoBucket != "" && len(rel.Charts) > 0 {
+		chart := rel.Charts[0]
+		urls.HelmInstallCommand = fmt.Sprintf("helm install %s %s --repo %s --version %s --namespace cert-manager --create-namespace", chart.Name(), chart.Name(), o.PublishedHelmChartRepoURL, chart.Version())
+	} else if actionSet.Has("helmchartoci") && len(rel.Charts) > 0 {
+		chart := rel.Charts[0]
+		urls.HelmInstallCommand = fmt.Sprintf("helm install %s %s/%s --version %s --namespace cert-manager --create-namespace", chart.Name(), strings.TrimSuffix(o.PublishedHelmChartOCIRegistry, "/"), chart.Name(), chart.Version())
+	}
+
+	return urls
+}
+
+// Markdown renders the URLs as a Markdown block suitable for pasting into
+// release notes.
+func (u *publishedURLs) Markdown() string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "## Downloads for %s\n", u.ReleaseVersion)
+
+	if u.GitHubReleaseURL != "" {
+		fmt.Fprintf(&sb, "\nGitHub release: %s\n", u.GitHubReleaseURL)
+	}
+
+	if len(u.GitHubAssets) > 0 {
+		sb.WriteString("\n### Release assets\n\n")
+		for _, asset := range u.GitHubAssets {
+			fmt.Fprintf(&sb, "- [%s](%s)\n", asset.Name, asset.URL)
+		}
+	}
+
+	if len(u.Images) > 0 {
+		sb.WriteString("\n### Container images\n\n```console\n")
+		for _, image := range u.Images {
+			fmt.Fprintf(&sb, "%s\n", image.PullCommand)
+		}
+		sb.WriteString("```\n")
+	}
+
+	if u.HelmInstallCommand != "" {
+		fmt.Fprintf(&sb, "\n### Helm chart\n\n```console\n%s\n```\n", u.HelmInstallCommand)
+	}
+
+	return sb.String()
+}
+
+// Render returns the URLs in the given format, which must be one of
+// "markdown" or "json".
+func (u *publishedURLs) Render(format string) (string, error) {
+	switch format {
+	case downloadURLsFormatMarkdown:
+		return u.Markdown(), nil
+
+	case downloadURLsFormatJSON:
+		out, err := json.MarshalIndent(u, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal download URLs: %w", err)
+		}
+		return string(out) + "\n", nil
+
+	default:
+		return "", fmt.Errorf("unknown download URLs format %q", format)
+	}
+}
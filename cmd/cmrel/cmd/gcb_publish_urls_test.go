@@ -0,0 +1,258 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cert-manager/release/pkg/release"
+	"github.com/cert-manager/release/pkg/release/binaries"
+	"github.com/cert-manager/release/pkg/release/images"
+	"github.com/cert-manager/release/pkg/release/manifests"
+)
+
+// writeChart writes a minimal packaged Helm chart containing only a
+// Chart.yaml file, and returns a manifests.Chart which refers to it.
+func writeChart(t *testing.T, version string) manifests.Chart {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "cert-manager-"+version+".tgz")
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	chartYAML := []byte(fmt.Sprintf("name: cert-manager\nversion: %s\nappVersion: %s\n", version, version))
+
+	gzw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gzw)
+	if err := tw.WriteHeader(&tar.Header{Name: "cert-manager/Chart.yaml", Mode: 0644, Size: int64(len(chartYAML))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(chartYAML); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	chart, err := manifests.NewChart(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return *chart
+}
+
+func TestBuildPublishedURLs(t *testing.T) {
+	o := &gcbPublishOptions{
+		PublishedImageRepository:      "quay.io/jetstack",
+		PublishedGitHubOrg:            "cert-manager",
+		PublishedGitHubRepo:           "cert-manager",
+		VerifyHelmChartRepo:           "oci://verify.example.com/charts",
+		PublishedHelmChartOCIRegistry: "oci://quay.io/jetstack/charts/",
+		PublishedHelmChartRepoURL:     "https://charts.example.com",
+	}
+
+	fixture := func(version string) *release.Unpacked {
+		return &release.Unpacked{
+			ReleaseVersion: version,
+			Charts:         []manifests.Chart{writeChart(t, version)},
+			YAMLs: []manifests.YAML{
+				*manifests.NewYAML("/tmp/manifests/cert-manager.yaml"),
+				*manifests.NewYAML("/tmp/manifests/cert-manager.crds.yaml"),
+			},
+			CtlBinaryBundles: []binaries.Archive{
+				*binaries.NewArchive("cmctl", "/tmp/cmctl.tar.gz", "linux", "amd64", release.ArchiveFormatForOS("linux")),
+			},
+			ComponentImageBundles: map[string][]*images.Tar{
+				"webhook":    nil,
+				"controller": nil,
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		rel        *release.Unpacked
+		actions    []string
+		uploaded   []string
+		repoBucket string
+
+		expURLs *publishedURLs
+	}{
+		"all actions": {
+			rel:     fixture("v1.14.0"),
+			actions: allPublishActionNames(),
+			uploaded: []string{
+				"cert-manager-checksums.txt", "cert-manager-checksums.txt.sig",
+				"cert-manager.crds.yaml", "cert-manager.crds.yaml.sig",
+				"cert-manager.yaml", "cert-manager.yaml.sig",
+				"cmctl-linux-amd64.tar.gz", "cmctl-linux-amd64.tar.gz.sig",
+				"cmctl-linux-amd64.tar.gz.spdx.json",
+			},
+			expURLs: &publishedURLs{
+				ReleaseVersion:   "v1.14.0",
+				GitHubReleaseURL: "https://github.com/cert-manager/cert-manager/releases/tag/v1.14.0",
+				GitHubAssets: []publishedAsset{
+					{Name: "cert-manager-checksums.txt", URL: "https://github.com/cert-manager/cert-manager/releases/download/v1.14.0/cert-manager-checksums.txt"},
+					{Name: "cert-manager-checksums.txt.sig", URL: "https://github.com/cert-manager/cert-manager/releases/download/v1.14.0/cert-manager-checksums.txt.sig"},
+					{Name: "cert-manager.crds.yaml", URL: "https://github.com/cert-manager/cert-manager/releases/download/v1.14.0/cert-manager.crds.yaml"},
+					{Name: "cert-manager.crds.yaml.sig", URL: "https://github.com/cert-manager/cert-manager/releases/download/v1.14.0/cert-manager.crds.yaml.sig"},
+					{Name: "cert-manager.yaml", URL: "https://github.com/cert-manager/cert-manager/releases/download/v1.14.0/cert-manager.yaml"},
+					{Name: "cert-manager.yaml.sig", URL: "https://github.com/cert-manager/cert-manager/releases/download/v1.14.0/cert-manager.yaml.sig"},
+					{Name: "cmctl-linux-amd64.tar.gz", URL: "https://github.com/cert-manager/cert-manager/releases/download/v1.14.0/cmctl-linux-amd64.tar.gz"},
+					{Name: "cmctl-linux-amd64.tar.gz.sig", URL: "https://github.com/cert-manager/cert-manager/releases/download/v1.14.0/cmctl-linux-amd64.tar.gz.sig"},
+					{Name: "cmctl-linux-amd64.tar.gz.spdx.json", URL: "https://github.com/cert-manager/cert-manager/releases/download/v1.14.0/cmctl-linux-amd64.tar.gz.spdx.json"},
+				},
+				Images: []publishedImage{
+					{Name: "quay.io/jetstack/cert-manager-controller:v1.14.0", PullCommand: "docker pull quay.io/jetstack/cert-manager-controller:v1.14.0"},
+					{Name: "quay.io/jetstack/cert-manager-webhook:v1.14.0", PullCommand: "docker pull quay.io/jetstack/cert-manager-webhook:v1.14.0"},
+				},
+				HelmInstallCommand: "helm install cert-manager cert-manager --repo https://charts.jetstack.io --version v1.14.0 --namespace cert-manager --create-namespace",
+			},
+		},
+		"only uploaded assets are listed": {
+			rel:      fixture("v1.15.0"),
+			actions:  []string{"githubrelease"},
+			uploaded: []string{"cert-manager-checksums.txt", "cert-manager.crds.yaml", "cert-manager.yaml"},
+			expURLs: &publishedURLs{
+				ReleaseVersion:   "v1.15.0",
+				GitHubReleaseURL: "https://github.com/cert-manager/cert-manager/releases/tag/v1.15.0",
+				GitHubAssets: []publishedAsset{
+					{Name: "cert-manager-checksums.txt", URL: "https://github.com/cert-manager/cert-manager/releases/download/v1.15.0/cert-manager-checksums.txt"},
+					{Name: "cert-manager.crds.yaml", URL: "https://github.com/cert-manager/cert-manager/releases/download/v1.15.0/cert-manager.crds.yaml"},
+					{Name: "cert-manager.yaml", URL: "https://github.com/cert-manager/cert-manager/releases/download/v1.15.0/cert-manager.yaml"},
+				},
+			},
+		},
+		"only OCI Helm chart pushed": {
+			rel:     fixture("v1.15.0"),
+			actions: []string{"helmchartoci"},
+			expURLs: &publishedURLs{
+				ReleaseVersion:     "v1.15.0",
+				HelmInstallCommand: "helm install cert-manager oci://quay.io/jetstack/charts/cert-manager --version v1.15.0 --namespace cert-manager --create-namespace",
+			},
+		},
+		"only Helm chart repository bucket published": {
+			rel:        fixture("v1.15.0"),
+			actions:    []string{"helmchartrepo"},
+			repoBucket: "example-charts",
+			expURLs: &publishedURLs{
+				ReleaseVersion:     "v1.15.0",
+				HelmInstallCommand: "helm install cert-manager cert-manager --repo https://charts.example.com --version v1.15.0 --namespace cert-manager --create-namespace",
+			},
+		},
+		"Helm chart repository action without a bucket": {
+			rel:     fixture("v1.15.0"),
+			actions: []string{"helmchartrepo"},
+			expURLs: &publishedURLs{
+				ReleaseVersion: "v1.15.0",
+			},
+		},
+		"only images pushed": {
+			rel:     fixture("v1.15.0"),
+			actions: []string{"pushcontainerimages"},
+			expURLs: &publishedURLs{
+				ReleaseVersion: "v1.15.0",
+				Images: []publishedImage{
+					{Name: "quay.io/jetstack/cert-manager-controller:v1.15.0", PullCommand: "docker pull quay.io/jetstack/cert-manager-controller:v1.15.0"},
+					{Name: "quay.io/jetstack/cert-manager-webhook:v1.15.0", PullCommand: "docker pull quay.io/jetstack/cert-manager-webhook:v1.15.0"},
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			o.PublishedHelmChartRepoBucket = test.repoBucket
+
+			o.uploadedAssets = nil
+			for _, name := range test.uploaded {
+				o.recordUploadedAsset("githubrelease", name)
+			}
+
+			urls := buildPublishedURLs(o, test.rel, test.actions)
+			if !reflect.DeepEqual(urls, test.expURLs) {
+				t.Errorf("unexpected URLs:\ngot=%+v\nexp=%+v", urls, test.expURLs)
+			}
+		})
+	}
+}
+
+func TestPublishedURLsRender(t *testing.T) {
+	urls := &publishedURLs{
+		ReleaseVersion:   "v1.15.0",
+		GitHubReleaseURL: "https://github.com/cert-manager/cert-manager/releases/tag/v1.15.0",
+		GitHubAssets: []publishedAsset{
+			{Name: "cert-manager.yaml", URL: "https://github.com/cert-manager/cert-manager/releases/download/v1.15.0/cert-manager.yaml"},
+		},
+		Images: []publishedImage{
+			{Name: "quay.io/jetstack/cert-manager-controller:v1.15.0", PullCommand: "docker pull quay.io/jetstack/cert-manager-controller:v1.15.0"},
+		},
+		HelmInstallCommand: "helm install cert-manager cert-manager --repo https://charts.jetstack.io --version v1.15.0 --namespace cert-manager --create-namespace",
+	}
+
+	markdown, err := urls.Render(downloadURLsFormatMarkdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expMarkdown := "## Downloads for v1.15.0\n" +
+		"\nGitHub release: https://github.com/cert-manager/cert-manager/releases/tag/v1.15.0\n" +
+		"\n### Release assets\n\n" +
+		"- [cert-manager.yaml](https://github.com/cert-manager/cert-manager/releases/download/v1.15.0/cert-manager.yaml)\n" +
+		"\n### Container images\n\n```console\n" +
+		"docker pull quay.io/jetstack/cert-manager-controller:v1.15.0\n" +
+		"```\n" +
+		"\n### Helm chart\n\n```console\n" +
+		"helm install cert-manager cert-manager --repo https://charts.jetstack.io --version v1.15.0 --namespace cert-manager --create-namespace\n" +
+		"```\n"
+
+	if markdown != expMarkdown {
+		t.Errorf("unexpected markdown:\ngot=%s\nexp=%s", markdown, expMarkdown)
+	}
+
+	jsonOut, err := urls.Render(downloadURLsFormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := &publishedURLs{}
+	if err := json.Unmarshal([]byte(jsonOut), decoded); err != nil {
+		t.Fatalf("failed to decode rendered JSON: %v", err)
+	}
+
+	if !reflect.DeepEqual(decoded, urls) {
+		t.Errorf("rendered JSON did not round trip:\ngot=%+v\nexp=%+v", decoded, urls)
+	}
+
+	if _, err := urls.Render("yaml"); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
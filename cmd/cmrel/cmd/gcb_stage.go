@@ -17,23 +17,36 @@ limitations under the License.
 package cmd
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"runtime"
 	"strings"
+	"sync"
+	"time"
 
 	"cloud.google.com/go/storage"
+	"github.com/blang/semver"
 	"github.com/spf13/cobra"
 	flag "github.com/spf13/pflag"
+	"golang.org/x/sync/errgroup"
+	"k8s.io/apimachinery/pkg/util/sets"
 
+	"github.com/cert-manager/release/pkg/gcp"
 	"github.com/cert-manager/release/pkg/release"
+	"github.com/cert-manager/release/pkg/release/provenance"
+	"github.com/cert-manager/release/pkg/retry"
+	"github.com/cert-manager/release/pkg/shell"
 	"github.com/cert-manager/release/pkg/sign"
 )
 
@@ -49,8 +62,6 @@ a Google Cloud Build started via the 'stage' sub-command.
 `
 )
 
-type postprocessFunc func(string) error
-
 type gcbStageOptions struct {
 	// The name of the GCS bucket to stage the release to.
 	Bucket string
@@ -65,6 +76,10 @@ type gcbStageOptions struct {
 	// repository.
 	ReleaseVersion string
 
+	// AllowVersionMismatch, if true, skips checking that ReleaseVersion
+	// matches the version of the source being built.
+	AllowVersionMismatch bool
+
 	// PublishedImageRepository is the docker repository that will be used for
 	// built artifacts.
 	// This must be set at the time a build is staged as parts of the release
@@ -87,12 +102,59 @@ type gcbStageOptions struct {
 
 	// TargetArches is a comma-separated list of architectures which should be built for in this invocation
 	TargetArches string
+
+	// MetadataFileName is the name of the file the release metadata is
+	// written to in the root of the staged release.
+	MetadataFileName string
+
+	// GzipMetadata, if true, will gzip the release metadata before uploading
+	// it, and add a ".gz" suffix to its file name.
+	GzipMetadata bool
+
+	// ChecksumWorkers is the number of artifacts to compute checksums for in
+	// parallel.
+	ChecksumWorkers int
+
+	// UploadWorkers is the number of artifacts to upload to GCS in parallel.
+	UploadWorkers int
+
+	// SkipBuild, if true, will skip building release artifacts with Bazel and
+	// instead stage pre-built artifacts found in ArtifactsDir.
+	SkipBuild bool
+
+	// ArtifactsDir is the directory containing pre-built release artifacts.
+	// It must be set if SkipBuild is true.
+	ArtifactsDir string
+
+	// VerifyDeterminism, if true, will build release artifacts a second time
+	// using a separate Bazel output base and fail if the checksums of any of
+	// the artifacts differ between the two builds.
+	VerifyDeterminism bool
+
+	// StagedBy identifies the user who requested the release be staged, and
+	// is recorded in the release metadata. If empty, it is detected from the
+	// environment.
+	StagedBy string
+
+	// SourceRepository is the URL of the git repository checked out at
+	// RepoPath, recorded as the source of the build in provenance.
+	SourceRepository string
+
+	// ProvenanceBuilderID identifies the Cloud Build job running the build
+	// in SLSA provenance for the staged artifacts. If empty, no provenance
+	// is generated.
+	ProvenanceBuilderID string
+
+	// ProvenanceInvocationID, if set, identifies this run of the build in
+	// SLSA provenance for the staged artifacts.
+	ProvenanceInvocationID string
 }
 
 func (o *gcbStageOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
 	fs.StringVar(&o.Bucket, "bucket", release.DefaultBucketName, "The name of the GCS bucket to stage the release to.")
 	fs.StringVar(&o.RepoPath, "repo-path", "", "Path to the cert-manager repository stored in disk to be built and published. This must already be checked out at the appropriate revision.")
 	fs.StringVar(&o.ReleaseVersion, "release-version", "", "Optional release version override used to force the version strings used during the release to a specific value.")
+	fs.BoolVar(&o.AllowVersionMismatch, "allow-version-mismatch", false, "Skip checking that --release-version matches the version of the source being built, e.g. to release from a branch whose tags don't follow the usual versioning.")
 	fs.StringVar(&o.PublishedImageRepository, "published-image-repo", release.DefaultImageRepository, "The docker image repository set when building the release.")
 	fs.StringVar(&o.SigningKMSKey, "signing-kms-key", defaultKMSKey, "Full name of the GCP KMS key to use for signing")
 	fs.BoolVar(&o.SkipPush, "skip-push", false, "Skip pushing the staged release to a GCS bucket.")
@@ -105,18 +167,42 @@ func (o *gcbStageOptions) AddFlags(fs *flag.FlagSet, markRequired func(string))
 
 	fs.StringVar(&o.TargetOSes, "target-os", "*", fmt.Sprintf("Comma-separated list of OSes to target, or '*' for all. Options: %s", allOSes))
 	fs.StringVar(&o.TargetArches, "target-arch", "*", fmt.Sprintf("Comma-separated list of arches to target, or '*' for all. Options: %s", allArches))
+	fs.StringVar(&o.MetadataFileName, "metadata-file-name", release.MetadataFileName, "The name of the file the release metadata is written to in the root of the staged release.")
+	fs.BoolVar(&o.SkipBuild, "skip-build", false, "Skip building release artifacts with Bazel, and instead stage pre-built artifacts from --artifacts-dir.")
+	fs.StringVar(&o.ArtifactsDir, "artifacts-dir", "", "Directory containing pre-built release artifacts to stage when --skip-build is set.")
+	fs.StringVar(&o.StagedBy, "staged-by", "", "The user who requested the release be staged, recorded in the release metadata. If not set, it is detected from the $BUILD_REQUESTED_BY or $USER environment variables.")
+	fs.BoolVar(&o.VerifyDeterminism, "verify-determinism", false, "Build release artifacts twice into separate Bazel output directories and fail if any artifact checksums differ.")
+	fs.IntVar(&o.ChecksumWorkers, "checksum-workers", runtime.NumCPU(), "The number of release artifacts to compute checksums for in parallel.")
+	fs.IntVar(&o.UploadWorkers, "upload-workers", 4, "The number of release artifacts to upload to GCS in parallel.")
+	fs.BoolVar(&o.GzipMetadata, "gzip-metadata", false, "Gzip the release metadata before uploading it, adding a '.gz' suffix to the metadata file name.")
+	fs.StringVar(&o.SourceRepository, "source-repo", fmt.Sprintf("https://github.com/%s/%s.git", release.DefaultGitHubOrg, release.DefaultGitHubRepo), "URL of the git repository checked out at --repo-path, recorded as the source of the build in provenance.")
+	fs.StringVar(&o.ProvenanceBuilderID, "provenance-builder-id", "", fmt.Sprintf("ID of the Cloud Build job running the build. If set, SLSA provenance for the staged artifacts is written to %q in the staged release.", release.ProvenanceFileName))
+	fs.StringVar(&o.ProvenanceInvocationID, "provenance-invocation-id", "", "Optional ID of this run of the build, recorded in SLSA provenance for the staged artifacts.")
 }
 
 func (o *gcbStageOptions) print() {
-	log.Printf("GCB Stage options:")
-	log.Printf("  Bucket: %q", o.Bucket)
-	log.Printf("  RepoPath: %q", o.RepoPath)
-	log.Printf("  SkipPush: %v", o.SkipPush)
-	log.Printf("  SkipSigning: %v", o.SkipSigning)
-	log.Printf("  SigningKMSKey: %q", o.SigningKMSKey)
-	log.Printf("  ReleaseVersion: %q", o.ReleaseVersion)
-	log.Printf("  TargetOSes: %q", o.TargetOSes)
-	log.Printf("  TargetArches: %q", o.TargetArches)
+	slog.Info("GCB Stage options",
+		"Bucket", o.Bucket,
+		"RepoPath", o.RepoPath,
+		"SkipPush", o.SkipPush,
+		"SkipSigning", o.SkipSigning,
+		"SigningKMSKey", o.SigningKMSKey,
+		"ReleaseVersion", o.ReleaseVersion,
+		"AllowVersionMismatch", o.AllowVersionMismatch,
+		"TargetOSes", o.TargetOSes,
+		"TargetArches", o.TargetArches,
+		"MetadataFileName", o.MetadataFileName,
+		"GzipMetadata", o.GzipMetadata,
+		"ChecksumWorkers", o.ChecksumWorkers,
+		"UploadWorkers", o.UploadWorkers,
+		"SkipBuild", o.SkipBuild,
+		"ArtifactsDir", o.ArtifactsDir,
+		"VerifyDeterminism", o.VerifyDeterminism,
+		"StagedBy", o.StagedBy,
+		"SourceRepository", o.SourceRepository,
+		"ProvenanceBuilderID", o.ProvenanceBuilderID,
+		"ProvenanceInvocationID", o.ProvenanceInvocationID,
+	)
 }
 
 func gcbStageCmd(rootOpts *rootOptions) *cobra.Command {
@@ -128,7 +214,7 @@ func gcbStageCmd(rootOpts *rootOptions) *cobra.Command {
 		SilenceUsage: true,
 		PreRun: func(cmd *cobra.Command, args []string) {
 			o.print()
-			log.Printf("---")
+			slog.Info("---")
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runGCBStage(rootOpts, o)
@@ -138,8 +224,40 @@ func gcbStageCmd(rootOpts *rootOptions) *cobra.Command {
 	return cmd
 }
 
+// buildSource returns the build source recorded in the metadata of the
+// staged release.
+func (o *gcbStageOptions) buildSource() string {
+	if o.SkipBuild {
+		return release.BuildSourcePrebuilt
+	}
+
+	return release.BuildSourceBazel
+}
+
+// releaseMetadata returns the metadata of the staged release, built from the
+// given git ref with the given artifacts.
+func (o *gcbStageOptions) releaseMetadata(gitRef string, artifacts []release.ArtifactMetadata) release.Metadata {
+	return release.Metadata{
+		SchemaVersion:  release.CurrentMetadataSchemaVersion,
+		ReleaseVersion: o.ReleaseVersion,
+		GitCommitRef:   gitRef,
+		Artifacts:      artifacts,
+		BuildSource:    o.buildSource(),
+		StagedBy:       stagedBy(o.StagedBy, os.Getenv),
+	}
+}
+
 func runGCBStage(rootOpts *rootOptions, o *gcbStageOptions) error {
 	ctx := context.Background()
+	startedOn := time.Now()
+
+	if o.SkipBuild && o.ArtifactsDir == "" {
+		return fmt.Errorf("--artifacts-dir must be set when --skip-build is set")
+	}
+
+	if o.SkipBuild && o.VerifyDeterminism {
+		return fmt.Errorf("--verify-determinism cannot be used with --skip-build")
+	}
 
 	gitRef, err := readGitRef(o.RepoPath)
 	if err != nil {
@@ -152,19 +270,69 @@ func runGCBStage(rootOpts *rootOptions, o *gcbStageOptions) error {
 		}
 	}
 
-	if o.ReleaseVersion != "" {
-		if err := runGit(o.RepoPath, "tag", "-f", o.ReleaseVersion); err != nil {
+	// Build Google Cloud Storage API client for uploading artifacts, and
+	// check the bucket is accessible before spending time on a build
+	var gcs *storage.Client
+	if !o.SkipPush {
+		gcs, err = gcp.NewStorageClient(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to create GCS client: %w", err)
+		}
+
+		if err := release.CheckBucketAccess(ctx, gcs.Bucket(o.Bucket)); err != nil {
 			return err
 		}
-		log.Printf("Tagged git repository at commit %q with version %q", gitRef, o.ReleaseVersion)
 	}
 
-	releaseVersion, err := readBazelVersion(o.RepoPath)
-	if err != nil {
+	var version *bazelVersion
+	if o.SkipBuild {
+		version, err = readPrebuiltVersion(o.ArtifactsDir, o.ReleaseVersion)
+		if err != nil {
+			return err
+		}
+	} else {
+		if o.ReleaseVersion != "" {
+			if o.AllowVersionMismatch {
+				slog.Info("Not checking that the release version matches the version of the source being built", "version", o.ReleaseVersion)
+			} else {
+				sourceVersion, err := readRawBazelVersion(o.RepoPath)
+				if err != nil {
+					return fmt.Errorf("failed to read version of source before tagging: %w", err)
+				}
+
+				warning, err := checkReleaseVersionMatchesSource(o.ReleaseVersion, sourceVersion)
+				if err != nil {
+					return err
+				}
+
+				if warning != "" {
+					slog.Warn(warning)
+				}
+			}
+
+			if err := runGit(o.RepoPath, "tag", "-f", o.ReleaseVersion); err != nil {
+				return err
+			}
+			slog.Info("Tagged git repository", "commit", gitRef, "version", o.ReleaseVersion)
+		}
+
+		version, err = readBazelVersion(o.RepoPath)
+		if err != nil {
+			return fmt.Errorf("failed to read release version: %w", err)
+		}
+	}
+
+	if err := checkVersionMatchesGitRef(version, gitRef); err != nil {
 		return err
 	}
 
-	log.Printf("Building release artifacts with release version %q at ref %q", releaseVersion, gitRef)
+	releaseVersion := version.Version
+
+	if version.Dirty {
+		slog.Warn("release version was computed from a git repository with uncommitted changes", "version", releaseVersion)
+	}
+
+	slog.Info("Building release artifacts", "version", releaseVersion, "git_ref", gitRef)
 
 	outputDir := ""
 	// If --release-version is not explicitly set, we treat this build as a
@@ -175,13 +343,7 @@ func runGCBStage(rootOpts *rootOptions, o *gcbStageOptions) error {
 		outputDir = release.BucketPathForRelease(release.DefaultBucketPathPrefix, release.BuildTypeRelease, releaseVersion, gitRef)
 	}
 
-	log.Printf("Built artifacts will be published to 'gs://%s/%s' once complete", o.Bucket, outputDir)
-
-	// Create a list of built artifacts. For now this is pretty hardcoded and ugly.
-	// In future, we may want to update cert-manager's build system to produce a 'manifest'
-	// of all the artifacts that were built during a `bazel run` invocation.
-	// This will mean we don't have to update this release tool whenever we add an additional
-	// release artifact.
+	slog.Info("Built artifacts will be published once complete", "location", fmt.Sprintf("gs://%s/%s", o.Bucket, outputDir))
 
 	targetOSes, err := release.OSListFromString(o.TargetOSes)
 	if err != nil {
@@ -193,112 +355,152 @@ func runGCBStage(rootOpts *rootOptions, o *gcbStageOptions) error {
 		return fmt.Errorf("invalid --target-arch list: %w", err)
 	}
 
-	var artifacts []release.ArtifactMetadata
+	platforms := targetPlatforms(targetOSes, targetArches)
 
-	for _, osVariant := range targetOSes.List() {
-		for _, arch := range release.ArchitecturesPerOS[osVariant] {
-			if !targetArches.Has(arch) {
-				continue
-			}
+	artifactPath := func(name string) string {
+		return buildArtifactPath(o.RepoPath, "build", "release-tars", name)
+	}
 
-			log.Printf("Building %q target for %q OS for %q architecture", release.TarsBazelTarget, osVariant, arch)
+	if o.SkipBuild {
+		slog.Info("Skipping building release artifacts as --skip-build=true, staging pre-built artifacts", "path", o.ArtifactsDir)
 
-			if err := runBazel(o.RepoPath, bazelBuildEnv(o), "build", "--stamp", platformFlagForOSArch(osVariant, arch), release.TarsBazelTarget); err != nil {
-				return fmt.Errorf("failed building release artifacts for architecture %q: %w", arch, err)
-			}
+		artifactPath = func(name string) string {
+			return filepath.Join(o.ArtifactsDir, name)
+		}
+	} else {
+		if err := bazelBuildReleaseArtifacts(o, platforms); err != nil {
+			return err
+		}
+	}
 
-			if release.IsServerOS(osVariant) {
-				// add an artifact for the arch specific 'server' release tarball
-				serverArtifactName := fmt.Sprintf("cert-manager-server-linux-%s.tar.gz", arch)
-				// Add the arch-specific .tar.gz file to the list of artifacts
-				if err := appendArtifact(&artifacts, o.RepoPath, serverArtifactName, osVariant, arch); err != nil {
-					return err
-				}
-			}
+	builtArtifacts := expectedArtifacts(platforms, releaseVersion, artifactPath)
 
-			if release.IsClientOS(osVariant) && release.CmctlIsShipped(releaseVersion) {
-				// add an artifact for the os and arch specific 'cmctl' and 'kubectl-cert_manager' release tarball
-				for _, kind := range []string{"kubectl-cert_manager", "cmctl"} {
-					clientArtifactName := fmt.Sprintf("cert-manager-%s-%s-%s.tar.gz", kind, osVariant, arch)
-					// Add the arch-specific .tar.gz file to the list of artifacts
-					if err := appendArtifact(&artifacts, o.RepoPath, clientArtifactName, osVariant, arch); err != nil {
-						return err
-					}
-				}
-			}
-		}
+	if err := checkArtifactsExist(builtArtifacts); err != nil {
+		return err
 	}
 
-	manifestPostProcessor := func(path string) error {
-		if o.SkipSigning {
-			log.Println("skipping signing cert-manager-manifests.tar.gz because skip-signing is true")
-			return nil
+	if o.VerifyDeterminism {
+		if err := verifyBuildDeterminism(o, platforms, builtArtifacts); err != nil {
+			return err
 		}
+	}
 
+	// sign 'manifests' (helm chart, k8s YAML manifests), which is always the
+	// last expected artifact
+	if o.SkipSigning {
+		slog.Info("skipping signing cert-manager-manifests.tar.gz because skip-signing is true")
+	} else {
 		parsedKey, err := sign.NewGCPKMSKey(o.SigningKMSKey)
 		if err != nil {
 			return err
 		}
 
-		return sign.CertManagerManifests(ctx, parsedKey, path, o.ReleaseVersion)
+		manifestsPath := builtArtifacts[len(builtArtifacts)-1].path
+
+		// signing modifies the archive in place, so pre-built artifacts are
+		// signed in a copy to leave --artifacts-dir untouched and to stop a
+		// re-run from signing the same archive twice
+		if o.SkipBuild {
+			signDir, err := os.MkdirTemp("", "cmrel-sign-")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(signDir)
+
+			manifestsPath, err = copyFileToDir(manifestsPath, signDir)
+			if err != nil {
+				return fmt.Errorf("failed to copy %q for signing: %w", builtArtifacts[len(builtArtifacts)-1].path, err)
+			}
+
+			builtArtifacts[len(builtArtifacts)-1].path = manifestsPath
+		}
+
+		if err := sign.CertManagerManifests(ctx, parsedKey, manifestsPath, o.ReleaseVersion); err != nil {
+			return fmt.Errorf("failed to sign %q: %w", manifestsPath, err)
+		}
 	}
 
-	// add 'manifests' (helm chart, k8s YAML manifests)
-	if err := appendArtifactWithPostprocess(&artifacts, o.RepoPath, "cert-manager-manifests.tar.gz", "", "", manifestPostProcessor); err != nil {
+	slog.Info("Computing checksums for release artifacts", "count", len(builtArtifacts))
+
+	artifacts, err := computeArtifactChecksums(builtArtifacts, o.ChecksumWorkers)
+	if err != nil {
 		return err
 	}
 
-	meta, err := json.MarshalIndent(release.Metadata{
-		ReleaseVersion: o.ReleaseVersion,
-		GitCommitRef:   gitRef,
-		Artifacts:      artifacts,
-	}, "", " ")
+	meta, err := json.MarshalIndent(o.releaseMetadata(gitRef, artifacts), "", " ")
 	if err != nil {
 		return fmt.Errorf("failed to encode metadata output: %w", err)
 	}
 
-	log.Printf("Built release artifacts for all architectures: %v", artifacts)
+	slog.Info("Built release artifacts for all architectures", "artifacts", artifacts)
+
+	provenanceStatement, err := stageProvenance(o, gitRef, artifacts, startedOn, time.Now())
+	if err != nil {
+		return err
+	}
 
 	if o.SkipPush {
-		log.Printf("Skipping pushing staged release as --skip-push=true")
+		slog.Info("Skipping pushing staged release as --skip-push=true")
 		return nil
 	}
 
-	// Build Google Cloud Storage API client for uploading artifacts
-	gcs, err := storage.NewClient(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to create GCS client: %w", err)
+	// Upload all built release artifacts
+	if err := uploadArtifacts(ctx, gcs.Bucket(o.Bucket), outputDir, builtArtifacts, artifacts, o.UploadWorkers); err != nil {
+		return fmt.Errorf("failed to copy output artifact to GCS staging location: %w", err)
 	}
 
-	// Upload all built release artifacts
-	for _, artifact := range artifacts {
-		filePath := buildArtifactPath(o.RepoPath, "build", "release-tars", artifact.Name)
-		gcsPath := buildObjectName(outputDir, artifact.Name)
-		log.Printf("Uploading artifact %q to GCS at path: %s", artifact, gcsPath)
-		if err := func(filePath, gcsPath string) error {
-			r, err := os.Open(filePath)
-			if err != nil {
-				return err
-			}
-			defer r.Close()
+	// provenance is uploaded before the metadata, so that a staged release
+	// which has metadata always has its provenance
+	if provenanceStatement != nil {
+		slog.Info("Uploading provenance for release artifacts")
+		w := gcs.Bucket(o.Bucket).Object(buildObjectName(outputDir, release.ProvenanceFileName)).NewWriter(ctx)
+		if _, err := w.Write(provenanceStatement); err != nil {
+			w.Close()
+			return fmt.Errorf("failed to write provenance to GCS staging location: %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+	}
 
-			w := gcs.Bucket(o.Bucket).Object(gcsPath).NewWriter(ctx)
-			if _, err := io.Copy(w, r); err != nil {
-				return err
-			}
-			if err := w.Close(); err != nil {
-				return err
-			}
-			log.Printf("Uploaded artifact %q to GCS", artifact)
+	// the metadata signature covers the uncompressed metadata and is uploaded
+	// before it, so that every staged release with metadata can be verified
+	if o.SkipSigning {
+		slog.Info("Skipping signing release metadata because skip-signing is true")
+	} else {
+		parsedKey, err := sign.NewGCPKMSKey(o.SigningKMSKey)
+		if err != nil {
+			return err
+		}
 
-			return nil
-		}(filePath, gcsPath); err != nil {
-			return fmt.Errorf("failed to copy output artifact to GCS staging location: %w", err)
+		signature, err := sign.PGPDetachSign(ctx, parsedKey, meta)
+		if err != nil {
+			return fmt.Errorf("failed to sign release metadata: %w", err)
+		}
+
+		slog.Info("Uploading release metadata signature")
+		w := gcs.Bucket(o.Bucket).Object(buildObjectName(outputDir, o.MetadataFileName+release.MetadataSignatureSuffix)).NewWriter(ctx)
+		if _, err := w.Write(signature); err != nil {
+			w.Close()
+			return fmt.Errorf("failed to write release metadata signature to GCS staging location: %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+	}
+
+	metadataFileName := o.MetadataFileName
+	if o.GzipMetadata {
+		meta, err = gzipBytes(meta)
+		if err != nil {
+			return fmt.Errorf("failed to compress release metadata: %w", err)
 		}
+
+		metadataFileName += release.GzippedMetadataSuffix
 	}
 
-	log.Printf("Uploading release metadata")
-	w := gcs.Bucket(o.Bucket).Object(buildObjectName(outputDir, release.MetadataFileName)).NewWriter(ctx)
+	slog.Info("Uploading release metadata")
+	w := gcs.Bucket(o.Bucket).Object(buildObjectName(outputDir, metadataFileName)).NewWriter(ctx)
 	if _, err := w.Write(meta); err != nil {
 		return fmt.Errorf("failed to write release metadata to GCS staging location: %w", err)
 	}
@@ -306,7 +508,196 @@ func runGCBStage(rootOpts *rootOptions, o *gcbStageOptions) error {
 		return err
 	}
 
-	log.Printf("Successfully staged release with version %q", releaseVersion)
+	slog.Info("Successfully staged release", "version", releaseVersion)
+
+	return nil
+}
+
+// uploadArtifacts uploads each of the built artifacts to the staged release at
+// outputDir in bucket, using a pool of the given number of workers. Failed
+// uploads are retried, and each upload's integrity is verified by GCS.
+func uploadArtifacts(ctx context.Context, bucket *storage.BucketHandle, outputDir string, builtArtifacts []builtArtifact, artifacts []release.ArtifactMetadata, workers int) error {
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(max(workers, 1))
+
+	for i, artifact := range artifacts {
+		filePath := builtArtifacts[i].path
+		gcsPath := buildObjectName(outputDir, artifact.Name)
+
+		g.Go(func() error {
+			slog.Info("Uploading artifact to GCS", "artifact", artifact, "path", gcsPath)
+			if err := release.UploadFile(ctx, bucket.Object(gcsPath), filePath, retry.DefaultOptions()); err != nil {
+				return fmt.Errorf("failed to upload artifact %q: %w", artifact.Name, err)
+			}
+
+			slog.Info("Uploaded artifact to GCS", "artifact", artifact)
+			return nil
+		})
+	}
+
+	return g.Wait()
+}
+
+// stageProvenance returns the encoded SLSA provenance statement for the
+// artifacts built from gitRef, or nil if no builder ID is configured.
+func stageProvenance(o *gcbStageOptions, gitRef string, artifacts []release.ArtifactMetadata, startedOn, finishedOn time.Time) ([]byte, error) {
+	if o.ProvenanceBuilderID == "" {
+		slog.Info("Not generating provenance for release artifacts as --provenance-builder-id is not set")
+		return nil, nil
+	}
+
+	statement, err := provenance.New(provenance.Options{
+		BuilderID:        o.ProvenanceBuilderID,
+		InvocationID:     o.ProvenanceInvocationID,
+		SourceRepository: o.SourceRepository,
+		GitCommitRef:     gitRef,
+		ExternalParameters: map[string]string{
+			"releaseVersion":     o.ReleaseVersion,
+			"publishedImageRepo": o.PublishedImageRepository,
+			"targetOS":           o.TargetOSes,
+			"targetArch":         o.TargetArches,
+		},
+		StartedOn:  startedOn,
+		FinishedOn: finishedOn,
+	}, artifacts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate provenance: %w", err)
+	}
+
+	out, err := json.MarshalIndent(statement, "", " ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode provenance: %w", err)
+	}
+
+	return out, nil
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	gzw := gzip.NewWriter(&buf)
+	if _, err := gzw.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := gzw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// bazelBuildReleaseArtifacts builds the release tarballs for each of the given
+// platforms. Any startupArgs are passed to Bazel before the 'build' command.
+func bazelBuildReleaseArtifacts(o *gcbStageOptions, platforms []osArch, startupArgs ...string) error {
+	for _, p := range platforms {
+		slog.Info("Building release target", "target", release.TarsBazelTarget, "os", p.os, "arch", p.arch)
+
+		args := append(append([]string{}, startupArgs...), "build", "--stamp", platformFlagForOSArch(p.os, p.arch), release.TarsBazelTarget)
+		if err := runBazel(o.RepoPath, bazelBuildEnv(o), args...); err != nil {
+			return fmt.Errorf("failed building release artifacts for architecture %q: %w", p.arch, err)
+		}
+	}
+
+	return nil
+}
+
+// verifyBuildDeterminism rebuilds the given artifacts using a fresh Bazel
+// output base and checks that the checksums of every artifact match those of
+// the original build. The fresh output base and its Bazel server are removed
+// once complete, and the 'bazel-bin' directory is restored to refer to the
+// output of the original build.
+func verifyBuildDeterminism(o *gcbStageOptions, platforms []osArch, artifacts []builtArtifact) error {
+	slog.Info("Computing checksums of first build to verify build determinism")
+
+	first, err := computeArtifactChecksums(artifacts, o.ChecksumWorkers)
+	if err != nil {
+		return err
+	}
+
+	outputBase, err := os.MkdirTemp("", "cmrel-determinism-")
+	if err != nil {
+		return fmt.Errorf("failed to create Bazel output base for second build: %w", err)
+	}
+
+	defer removeBazelOutputBase(o, outputBase)
+
+	slog.Info("Building release artifacts a second time", "output_base", outputBase)
+
+	if err := bazelBuildReleaseArtifacts(o, platforms, "--output_base="+outputBase); err != nil {
+		return err
+	}
+
+	if err := checkArtifactsExist(artifacts); err != nil {
+		return err
+	}
+
+	second, err := computeArtifactChecksums(artifacts, o.ChecksumWorkers)
+	if err != nil {
+		return err
+	}
+
+	if err := compareArtifactChecksums(first, second); err != nil {
+		return err
+	}
+
+	slog.Info("All release artifacts were built deterministically", "count", len(artifacts))
+
+	// the 'bazel-bin' symlink now points into the second output base, which
+	// is about to be removed, so the original build is run again to point it
+	// back at the default output base. Everything is cached, so this is quick.
+	slog.Info("Restoring 'bazel-bin' to the output of the original build")
+
+	if err := bazelBuildReleaseArtifacts(o, platforms); err != nil {
+		return err
+	}
+
+	return checkArtifactsExist(artifacts)
+}
+
+// removeBazelOutputBase shuts down the Bazel server using the given output
+// base and then removes it. Failures are only logged, since they don't affect
+// the artifacts which were built.
+func removeBazelOutputBase(o *gcbStageOptions, outputBase string) {
+	if err := runBazel(o.RepoPath, bazelBuildEnv(o), "--output_base="+outputBase, "shutdown"); err != nil {
+		slog.Warn("failed to shut down Bazel server", "output_base", outputBase, "error", err)
+	}
+
+	if err := os.RemoveAll(outputBase); err != nil {
+		slog.Warn("failed to remove Bazel output base", "output_base", outputBase, "error", err)
+	}
+}
+
+// compareArtifactChecksums returns an error listing each artifact whose
+// checksum differs between two builds, or which is only present in one of them.
+func compareArtifactChecksums(first, second []release.ArtifactMetadata) error {
+	secondSums := map[string]string{}
+	for _, a := range second {
+		secondSums[a.Name] = a.SHA256
+	}
+
+	var problems []string
+	for _, a := range first {
+		sum, ok := secondSums[a.Name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: not produced by second build", a.Name))
+			continue
+		}
+
+		delete(secondSums, a.Name)
+
+		if sum != a.SHA256 {
+			problems = append(problems, fmt.Sprintf("%s: sha256 %s != %s", a.Name, a.SHA256, sum))
+		}
+	}
+
+	for _, name := range sets.StringKeySet(secondSums).List() {
+		problems = append(problems, fmt.Sprintf("%s: not produced by first build", name))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("release artifacts are not deterministic:\n  %s", strings.Join(problems, "\n  "))
+	}
 
 	return nil
 }
@@ -315,36 +706,173 @@ func bazelBuildEnv(opts *gcbStageOptions) []string {
 	return append(os.Environ(), "DOCKER_REGISTRY="+opts.PublishedImageRepository)
 }
 
-// build an artifact using the given name, and append it to the given list after running
-// postprocess to modify it in-place; postprocessing requires the path to the artifact
-func appendArtifactWithPostprocess(artifacts *[]release.ArtifactMetadata, repoPath, name, os, arch string, postprocess postprocessFunc) error {
-	artifactPath := buildArtifactPath(repoPath, "build", "release-tars", name)
+// builtArtifact is an artifact which has been built, but which has not yet had
+// its checksum computed.
+type builtArtifact struct {
+	// path to the artifact on disk
+	path string
 
-	if postprocess != nil {
-		err := postprocess(artifactPath)
-		if err != nil {
-			return fmt.Errorf("failed to postprocess %q: %w", artifactPath, err)
+	// meta is the metadata for the artifact, excluding its checksum
+	meta release.ArtifactMetadata
+}
+
+// osArch is an OS and architecture pair which release artifacts are built for.
+type osArch struct {
+	os, arch string
+}
+
+// targetPlatforms returns each OS and architecture pair which release
+// artifacts should be built for.
+func targetPlatforms(targetOSes, targetArches sets.String) []osArch {
+	var platforms []osArch
+	for _, osVariant := range targetOSes.List() {
+		for _, arch := range release.ArchitecturesPerOS[osVariant] {
+			if !targetArches.Has(arch) {
+				continue
+			}
+
+			platforms = append(platforms, osArch{os: osVariant, arch: arch})
 		}
 	}
 
-	artifactHash, err := sha256SumFile(artifactPath)
-	if err != nil {
-		return fmt.Errorf("failed to compute sha256sum of release artifact %q: %w", artifactPath, err)
+	return platforms
+}
+
+// expectedArtifacts returns the artifacts which a build for the given
+// platforms and release version is expected to produce, using artifactPath to
+// determine where each named artifact is found on disk. The 'manifests'
+// artifact is always last.
+// For now this is pretty hardcoded and ugly. In future, we may want to update
+// cert-manager's build system to produce a 'manifest' of all the artifacts
+// that were built during a `bazel run` invocation.
+func expectedArtifacts(platforms []osArch, releaseVersion string, artifactPath func(name string) string) []builtArtifact {
+	var artifacts []builtArtifact
+
+	add := func(name, os, arch string) {
+		artifacts = append(artifacts, builtArtifact{
+			path: artifactPath(name),
+			meta: release.ArtifactMetadata{
+				Name:         name,
+				OS:           os,
+				Architecture: arch,
+			},
+		})
+	}
+
+	for _, p := range platforms {
+		if release.IsServerOS(p.os) {
+			// add an artifact for the arch specific 'server' release tarball
+			add(fmt.Sprintf("cert-manager-server-linux-%s.tar.gz", p.arch), p.os, p.arch)
+		}
+
+		if release.IsClientOS(p.os) && release.CmctlIsShipped(releaseVersion) {
+			// add an artifact for the os and arch specific 'cmctl' and 'kubectl-cert_manager' release tarball
+			for _, kind := range []string{"kubectl-cert_manager", "cmctl"} {
+				add(fmt.Sprintf("cert-manager-%s-%s-%s.tar.gz", kind, p.os, p.arch), p.os, p.arch)
+			}
+		}
 	}
 
-	*artifacts = append(*artifacts, release.ArtifactMetadata{
-		Name:         name,
-		SHA256:       artifactHash,
-		OS:           os,
-		Architecture: arch,
-	})
+	// add 'manifests' (helm chart, k8s YAML manifests)
+	add("cert-manager-manifests.tar.gz", "", "")
+
+	return artifacts
+}
+
+// checkArtifactsExist returns an error listing any of the given artifacts
+// which are not present on disk.
+func checkArtifactsExist(artifacts []builtArtifact) error {
+	var missing []string
+	for _, a := range artifacts {
+		if _, err := os.Stat(a.path); err != nil {
+			missing = append(missing, a.path)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("expected release artifacts not found: %s", strings.Join(missing, ", "))
+	}
 
 	return nil
 }
 
-// build an artifact using the given name, and append it to the given list
-func appendArtifact(artifacts *[]release.ArtifactMetadata, repoPath, name, os, arch string) error {
-	return appendArtifactWithPostprocess(artifacts, repoPath, name, os, arch, nil)
+// copyFileToDir copies the file at path into dir, keeping its name, and
+// returns the path of the copy.
+func copyFileToDir(path, dir string) (_ string, err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	dstPath := filepath.Join(dir, filepath.Base(path))
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		return "", err
+	}
+
+	defer func() {
+		if closeErr := dst.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return "", err
+	}
+
+	return dstPath, nil
+}
+
+// computeArtifactChecksums computes the sha256 sum of each of the built
+// artifacts using a pool of the given number of workers, returning the
+// metadata for each artifact in the same order as the given artifacts.
+func computeArtifactChecksums(artifacts []builtArtifact, workers int) ([]release.ArtifactMetadata, error) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	sums := make([]string, len(artifacts))
+	errs := make([]error, len(artifacts))
+
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range indexes {
+				sums[i], errs[i] = sha256SumFile(artifacts[i].path)
+			}
+		}()
+	}
+
+	for i := range artifacts {
+		indexes <- i
+	}
+
+	close(indexes)
+	wg.Wait()
+
+	metas := make([]release.ArtifactMetadata, len(artifacts))
+	for i, artifact := range artifacts {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("failed to compute sha256sum of release artifact %q: %w", artifact.path, errs[i])
+		}
+
+		meta := artifact.meta
+		meta.SHA256 = sums[i]
+
+		if err := meta.Validate(); err != nil {
+			return nil, err
+		}
+
+		metas[i] = meta
+	}
+
+	return metas, nil
 }
 
 func platformFlagForOSArch(os, arch string) string {
@@ -364,19 +892,141 @@ func runCmd(wd, cmd string, args ...string) error {
 }
 
 func runCmdWithEnv(wd string, env []string, cmd string, args ...string) error {
-	c := exec.Command(cmd, args...)
-	// redirect all output
-	// TODO: honour --debug flag
-	c.Env = env
-	c.Stdout = os.Stdout
-	c.Stderr = os.Stderr
-	c.Dir = wd
-	return c.Run()
+	return shell.CommandWithEnv(context.Background(), wd, env, cmd, args...)
+}
+
+// bazelVersion is the parsed output of the //:version Bazel target, which is
+// derived from 'git describe'.
+type bazelVersion struct {
+	// Version is the full version string, e.g. "v1.14.0" for a release or
+	// "v1.15.0-alpha.0-12-g1234abcd" for a development build.
+	Version string
+
+	// GitSHA is the abbreviated git commit sha embedded in a development
+	// version. It is empty for versions which exactly match a tag.
+	GitSHA string
+
+	// Dirty is true if the version was computed from a working tree with
+	// uncommitted changes.
+	Dirty bool
+}
+
+// bazelDevVersionRegexp matches the suffix 'git describe' adds to versions of
+// commits which aren't tagged, e.g. "-12-g1234abcd".
+var bazelDevVersionRegexp = regexp.MustCompile(`-[0-9]+-g([0-9a-f]{7,40})$`)
+
+// parseBazelVersion parses and validates the output of the //:version Bazel
+// target, returning an error if the version isn't a semver compliant release
+// or development version.
+func parseBazelVersion(s string) (*bazelVersion, error) {
+	v := &bazelVersion{Version: strings.TrimSpace(s)}
+
+	trimmed := v.Version
+	if strings.HasSuffix(trimmed, "-dirty") {
+		v.Dirty = true
+		trimmed = strings.TrimSuffix(trimmed, "-dirty")
+	}
+
+	if !strings.HasPrefix(trimmed, "v") {
+		return nil, fmt.Errorf("invalid version %q: must have a leading 'v' character", v.Version)
+	}
+
+	parsed, err := semver.Parse(strings.TrimPrefix(trimmed, "v"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid version %q: %w", v.Version, err)
+	}
+
+	if len(parsed.Build) > 0 {
+		return nil, fmt.Errorf("invalid version %q: must not contain build metadata", v.Version)
+	}
+
+	if m := bazelDevVersionRegexp.FindStringSubmatch(trimmed); m != nil {
+		v.GitSHA = m[1]
+	}
+
+	return v, nil
+}
+
+// checkVersionMatchesGitRef returns an error if the git sha embedded in the
+// version doesn't refer to the same commit as gitRef, which could happen if
+// the version was computed from a stale workspace. Versions which don't embed
+// a git sha are not checked.
+func checkVersionMatchesGitRef(v *bazelVersion, gitRef string) error {
+	if v.GitSHA == "" {
+		return nil
+	}
+
+	if !strings.HasPrefix(gitRef, v.GitSHA) {
+		return fmt.Errorf("version %q was built from commit %q, but the repository is at commit %q", v.Version, v.GitSHA, gitRef)
+	}
+
+	return nil
+}
+
+// baseVersion returns the semver version of the most recent tag that the
+// version was derived from, without any development or dirty suffix.
+func (v *bazelVersion) baseVersion() (semver.Version, error) {
+	trimmed := strings.TrimPrefix(strings.TrimSuffix(v.Version, "-dirty"), "v")
+	trimmed = bazelDevVersionRegexp.ReplaceAllString(trimmed, "")
+
+	return semver.Parse(trimmed)
+}
+
+// checkReleaseVersionMatchesSource compares a requested release version with
+// the version computed from the source it is about to be built from, to catch
+// staging a release from the wrong branch. An error is returned if the
+// requested version is older than the source, or belongs to an older minor
+// release. A warning is returned if the requested version starts a newer
+// minor release, which is expected when cutting the first pre-release of a
+// new minor version but is otherwise suspicious, or if the source has no
+// version tag to compare against.
+func checkReleaseVersionMatchesSource(releaseVersion string, sourceVersion string) (string, error) {
+	requested, err := semver.Parse(strings.TrimPrefix(releaseVersion, "v"))
+	if err != nil {
+		return "", fmt.Errorf("invalid release version %q: %w", releaseVersion, err)
+	}
+
+	// without a version tag reachable from the source, 'git describe' only
+	// produces a commit sha, so there's nothing to compare against
+	source, err := parseBazelVersion(sourceVersion)
+	if err != nil {
+		return fmt.Sprintf("not checking release version %q against the source being built, as the source has no version tag: %v", releaseVersion, err), nil
+	}
+
+	current, err := source.baseVersion()
+	if err != nil {
+		return "", fmt.Errorf("invalid source version %q: %w", source.Version, err)
+	}
+
+	if requested.LT(current) {
+		return "", fmt.Errorf("release version %q is older than version %q of the source being built: %w", releaseVersion, source.Version, release.ErrValidationFailed)
+	}
+
+	if requested.Major == current.Major && requested.Minor == current.Minor {
+		return "", nil
+	}
+
+	if requested.Patch == 0 && len(requested.Pre) > 0 {
+		return fmt.Sprintf("release version %q starts a new minor release from source at version %q", releaseVersion, source.Version), nil
+	}
+
+	return "", fmt.Errorf("release version %q does not match version %q of the source being built, check that the correct branch is being released: %w", releaseVersion, source.Version, release.ErrValidationFailed)
 }
 
-// readBazelVersion will build the //:version Bazel target and read the
-// contents of the 'version' file generated.
-func readBazelVersion(wd string) (string, error) {
+// readBazelVersion will build the //:version Bazel target and read and parse
+// the contents of the 'version' file generated.
+func readBazelVersion(wd string) (*bazelVersion, error) {
+	v, err := readRawBazelVersion(wd)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseBazelVersion(v)
+}
+
+// readRawBazelVersion will build the //:version Bazel target and return the
+// contents of the 'version' file generated, without validating them.
+func readRawBazelVersion(wd string) (string, error) {
 	if err := runBazel(wd, nil, "build", "//:version"); err != nil {
 		return "", err
 	}
@@ -385,8 +1035,24 @@ func readBazelVersion(wd string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	vers := strings.TrimSpace(string(vBytes))
-	return vers, nil
+
+	return string(vBytes), nil
+}
+
+// readPrebuiltVersion returns the parsed version of pre-built release
+// artifacts in dir. If releaseVersion is set it is used as the version,
+// otherwise the version is read from a 'version' file in dir.
+func readPrebuiltVersion(dir, releaseVersion string) (*bazelVersion, error) {
+	if releaseVersion != "" {
+		return parseBazelVersion(releaseVersion)
+	}
+
+	vBytes, err := os.ReadFile(filepath.Join(dir, "version"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read version of pre-built artifacts, --release-version must be set if %q does not contain a 'version' file: %w", dir, err)
+	}
+
+	return parseBazelVersion(string(vBytes))
 }
 
 func readGitRef(wd string) (string, error) {
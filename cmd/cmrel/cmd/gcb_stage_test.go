@@ -0,0 +1,517 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/cert-manager/release/pkg/release"
+)
+
+func TestComputeArtifactChecksums(t *testing.T) {
+	dir := t.TempDir()
+
+	var artifacts []builtArtifact
+	for i, arch := range []string{"amd64", "arm", "arm64", "ppc64le", "s390x"} {
+		name := fmt.Sprintf("cert-manager-server-linux-%s.tar.gz", arch)
+		path := filepath.Join(dir, name)
+
+		// give each artifact a different size so that they take different
+		// amounts of time to hash
+		if err := os.WriteFile(path, make([]byte, (i+1)*1024*1024), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		artifacts = append(artifacts, builtArtifact{
+			path: path,
+			meta: release.ArtifactMetadata{Name: name, OS: "linux", Architecture: arch},
+		})
+	}
+
+	manifestsPath := filepath.Join(dir, "cert-manager-manifests.tar.gz")
+	if err := os.WriteFile(manifestsPath, []byte("manifests"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	artifacts = append(artifacts, builtArtifact{
+		path: manifestsPath,
+		meta: release.ArtifactMetadata{Name: "cert-manager-manifests.tar.gz"},
+	})
+
+	serial, err := computeArtifactChecksums(artifacts, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, meta := range serial {
+		expected, err := sha256SumFile(artifacts[i].path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if meta.Name != artifacts[i].meta.Name {
+			t.Errorf("expected artifact %d to be %q, got %q", i, artifacts[i].meta.Name, meta.Name)
+		}
+
+		if meta.SHA256 != expected {
+			t.Errorf("unexpected sha256 for %q: got=%q, exp=%q", meta.Name, meta.SHA256, expected)
+		}
+	}
+
+	for _, workers := range []int{0, 2, 4, 16} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			parallel, err := computeArtifactChecksums(artifacts, workers)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(parallel, serial) {
+				t.Errorf("parallel checksums do not match serial checksums:\ngot=%+v\nexp=%+v", parallel, serial)
+			}
+		})
+	}
+
+	t.Run("missing artifact", func(t *testing.T) {
+		missing := append([]builtArtifact{}, artifacts...)
+		missing = append(missing, builtArtifact{
+			path: filepath.Join(dir, "does-not-exist.tar.gz"),
+			meta: release.ArtifactMetadata{Name: "cert-manager-server-linux-amd64.tar.gz", OS: "linux", Architecture: "amd64"},
+		})
+
+		if _, err := computeArtifactChecksums(missing, 4); err == nil {
+			t.Errorf("expected an error for a missing artifact")
+		}
+	})
+}
+
+func TestStagePrebuiltArtifacts(t *testing.T) {
+	platforms := targetPlatforms(sets.NewString("linux", "windows"), sets.NewString("amd64", "arm64"))
+
+	expPlatforms := []osArch{{os: "linux", arch: "amd64"}, {os: "linux", arch: "arm64"}, {os: "windows", arch: "amd64"}}
+	if !reflect.DeepEqual(platforms, expPlatforms) {
+		t.Fatalf("unexpected target platforms: got=%+v, exp=%+v", platforms, expPlatforms)
+	}
+
+	tests := map[string]struct {
+		releaseVersion string
+		missing        string
+
+		expNames  []string
+		expectErr bool
+	}{
+		"release which ships cmctl": {
+			releaseVersion: "v1.14.0",
+			expNames: []string{
+				"cert-manager-server-linux-amd64.tar.gz",
+				"cert-manager-kubectl-cert_manager-linux-amd64.tar.gz",
+				"cert-manager-cmctl-linux-amd64.tar.gz",
+				"cert-manager-server-linux-arm64.tar.gz",
+				"cert-manager-kubectl-cert_manager-linux-arm64.tar.gz",
+				"cert-manager-cmctl-linux-arm64.tar.gz",
+				"cert-manager-kubectl-cert_manager-windows-amd64.tar.gz",
+				"cert-manager-cmctl-windows-amd64.tar.gz",
+				"cert-manager-manifests.tar.gz",
+			},
+		},
+		"release which doesn't ship cmctl": {
+			releaseVersion: "v1.15.0",
+			expNames: []string{
+				"cert-manager-server-linux-amd64.tar.gz",
+				"cert-manager-server-linux-arm64.tar.gz",
+				"cert-manager-manifests.tar.gz",
+			},
+		},
+		"missing server artifact": {
+			releaseVersion: "v1.15.0",
+			missing:        "cert-manager-server-linux-arm64.tar.gz",
+			expectErr:      true,
+		},
+		"missing manifests artifact": {
+			releaseVersion: "v1.15.0",
+			missing:        "cert-manager-manifests.tar.gz",
+			expectErr:      true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			artifactPath := func(name string) string {
+				return filepath.Join(dir, name)
+			}
+
+			artifacts := expectedArtifacts(platforms, test.releaseVersion, artifactPath)
+
+			for _, a := range artifacts {
+				if a.meta.Name == test.missing {
+					continue
+				}
+
+				if err := os.WriteFile(a.path, []byte(a.meta.Name), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := checkArtifactsExist(artifacts)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if test.expectErr {
+				return
+			}
+
+			metas, err := computeArtifactChecksums(artifacts, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			for _, meta := range metas {
+				names = append(names, meta.Name)
+
+				expectedSum := sha256.Sum256([]byte(meta.Name))
+				if meta.SHA256 != hex.EncodeToString(expectedSum[:]) {
+					t.Errorf("unexpected sha256 for %q: %q", meta.Name, meta.SHA256)
+				}
+			}
+
+			if !reflect.DeepEqual(names, test.expNames) {
+				t.Errorf("unexpected artifacts:\ngot=%q\nexp=%q", names, test.expNames)
+			}
+
+			o := &gcbStageOptions{
+				ReleaseVersion: test.releaseVersion,
+				SkipBuild:      true,
+				ArtifactsDir:   dir,
+				StagedBy:       "release-manager",
+			}
+
+			meta := o.releaseMetadata("abcdef", metas)
+			expMeta := release.Metadata{
+				SchemaVersion:  release.CurrentMetadataSchemaVersion,
+				ReleaseVersion: test.releaseVersion,
+				GitCommitRef:   "abcdef",
+				Artifacts:      metas,
+				BuildSource:    release.BuildSourcePrebuilt,
+				StagedBy:       "release-manager",
+			}
+			if !reflect.DeepEqual(meta, expMeta) {
+				t.Errorf("unexpected metadata:\ngot=%+v\nexp=%+v", meta, expMeta)
+			}
+		})
+	}
+}
+
+func TestStageBuildSource(t *testing.T) {
+	if source := (&gcbStageOptions{}).buildSource(); source != release.BuildSourceBazel {
+		t.Errorf("expected artifacts built by Bazel to have build source %q but got %q", release.BuildSourceBazel, source)
+	}
+
+	if source := (&gcbStageOptions{SkipBuild: true, ArtifactsDir: "/artifacts"}).buildSource(); source != release.BuildSourcePrebuilt {
+		t.Errorf("expected pre-built artifacts to have build source %q but got %q", release.BuildSourcePrebuilt, source)
+	}
+}
+
+func TestCopyFileToDir(t *testing.T) {
+	srcDir := t.TempDir()
+	dstDir := t.TempDir()
+
+	src := filepath.Join(srcDir, "cert-manager-manifests.tar.gz")
+	if err := os.WriteFile(src, []byte("manifests"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := copyFileToDir(src, dstDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exp := filepath.Join(dstDir, "cert-manager-manifests.tar.gz"); dst != exp {
+		t.Errorf("unexpected path of copy: got=%q, exp=%q", dst, exp)
+	}
+
+	// modifying the copy, as signing does, mustn't modify the original
+	if err := os.WriteFile(dst, []byte("signed manifests"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	original, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(original) != "manifests" {
+		t.Errorf("original file was modified: %q", original)
+	}
+}
+
+func TestReadPrebuiltVersion(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := readPrebuiltVersion(dir, ""); err == nil {
+		t.Errorf("expected an error when no version is given and no version file exists")
+	}
+
+	v, err := readPrebuiltVersion(dir, "v1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Version != "v1.2.3" {
+		t.Errorf("expected explicit version to be used, got %q", v.Version)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "version"), []byte("v1.2.3-beta.0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err = readPrebuiltVersion(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Version != "v1.2.3-beta.0" {
+		t.Errorf("expected version to be read from file, got %q", v.Version)
+	}
+}
+
+func TestCompareArtifactChecksums(t *testing.T) {
+	artifact := func(name, sum string) release.ArtifactMetadata {
+		return release.ArtifactMetadata{Name: name, SHA256: sum}
+	}
+
+	tests := map[string]struct {
+		first, second []release.ArtifactMetadata
+		expectErr     bool
+	}{
+		"identical builds": {
+			first:  []release.ArtifactMetadata{artifact("server.tar.gz", "aaaa"), artifact("manifests.tar.gz", "bbbb")},
+			second: []release.ArtifactMetadata{artifact("server.tar.gz", "aaaa"), artifact("manifests.tar.gz", "bbbb")},
+		},
+		"identical builds in a different order": {
+			first:  []release.ArtifactMetadata{artifact("server.tar.gz", "aaaa"), artifact("manifests.tar.gz", "bbbb")},
+			second: []release.ArtifactMetadata{artifact("manifests.tar.gz", "bbbb"), artifact("server.tar.gz", "aaaa")},
+		},
+		"differing checksum": {
+			first:     []release.ArtifactMetadata{artifact("server.tar.gz", "aaaa"), artifact("manifests.tar.gz", "bbbb")},
+			second:    []release.ArtifactMetadata{artifact("server.tar.gz", "aaaa"), artifact("manifests.tar.gz", "cccc")},
+			expectErr: true,
+		},
+		"artifact missing from second build": {
+			first:     []release.ArtifactMetadata{artifact("server.tar.gz", "aaaa"), artifact("manifests.tar.gz", "bbbb")},
+			second:    []release.ArtifactMetadata{artifact("server.tar.gz", "aaaa")},
+			expectErr: true,
+		},
+		"artifact missing from first build": {
+			first:     []release.ArtifactMetadata{artifact("server.tar.gz", "aaaa")},
+			second:    []release.ArtifactMetadata{artifact("server.tar.gz", "aaaa"), artifact("manifests.tar.gz", "bbbb")},
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := compareArtifactChecksums(test.first, test.second)
+			if (err != nil) != test.expectErr {
+				t.Errorf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestParseBazelVersion(t *testing.T) {
+	tests := map[string]struct {
+		version string
+
+		expected  *bazelVersion
+		expectErr bool
+	}{
+		"release version": {
+			version:  "v1.14.0\n",
+			expected: &bazelVersion{Version: "v1.14.0"},
+		},
+		"pre-release version": {
+			version:  "v1.15.0-beta.1",
+			expected: &bazelVersion{Version: "v1.15.0-beta.1"},
+		},
+		"dev version": {
+			version:  "v1.15.0-alpha.0-12-g1234abcd",
+			expected: &bazelVersion{Version: "v1.15.0-alpha.0-12-g1234abcd", GitSHA: "1234abcd"},
+		},
+		"dirty dev version": {
+			version:  "v1.15.0-alpha.0-12-g1234abcd-dirty",
+			expected: &bazelVersion{Version: "v1.15.0-alpha.0-12-g1234abcd-dirty", GitSHA: "1234abcd", Dirty: true},
+		},
+		"dirty release version": {
+			version:  "v1.14.0-dirty",
+			expected: &bazelVersion{Version: "v1.14.0-dirty", Dirty: true},
+		},
+		"missing leading v": {
+			version:   "1.14.0",
+			expectErr: true,
+		},
+		"not semver": {
+			version:   "v1.14",
+			expectErr: true,
+		},
+		"build metadata": {
+			version:   "v1.14.0+abcdef",
+			expectErr: true,
+		},
+		"path separator": {
+			version:   "v1.14.0/../v1.15.0",
+			expectErr: true,
+		},
+		"empty": {
+			version:   "",
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			v, err := parseBazelVersion(test.version)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if !reflect.DeepEqual(v, test.expected) {
+				t.Errorf("unexpected version: got=%+v, exp=%+v", v, test.expected)
+			}
+		})
+	}
+}
+
+func TestCheckVersionMatchesGitRef(t *testing.T) {
+	const gitRef = "1234abcd5678ef901234abcd5678ef901234abcd"
+
+	tests := map[string]struct {
+		version   string
+		expectErr bool
+	}{
+		"release version without a git sha": {
+			version: "v1.14.0",
+		},
+		"dev version with matching sha": {
+			version: "v1.15.0-alpha.0-12-g1234abcd",
+		},
+		"dirty dev version with matching sha": {
+			version: "v1.15.0-alpha.0-12-g1234abcd-dirty",
+		},
+		"dev version with mismatched sha": {
+			version:   "v1.15.0-alpha.0-12-gabcdef12",
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			v, err := parseBazelVersion(test.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = checkVersionMatchesGitRef(v, gitRef)
+			if (err != nil) != test.expectErr {
+				t.Errorf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckReleaseVersionMatchesSource(t *testing.T) {
+	tests := map[string]struct {
+		releaseVersion string
+		sourceVersion  string
+
+		expectWarning bool
+		expectErr     bool
+	}{
+		"release from a tagged commit on the same minor": {
+			releaseVersion: "v1.14.0",
+			sourceVersion:  "v1.14.0-beta.1",
+		},
+		"patch release from a release branch": {
+			releaseVersion: "v1.14.1",
+			sourceVersion:  "v1.14.0-12-g1234abcd",
+		},
+		"re-staging an existing tag": {
+			releaseVersion: "v1.14.0",
+			sourceVersion:  "v1.14.0",
+		},
+		"dirty source on the same minor": {
+			releaseVersion: "v1.14.1",
+			sourceVersion:  "v1.14.0-3-g1234abcd-dirty",
+		},
+		"first pre-release of a new minor": {
+			releaseVersion: "v1.15.0-alpha.0",
+			sourceVersion:  "v1.14.0-alpha.1-200-g1234abcd",
+			expectWarning:  true,
+		},
+		"newer minor release from an older release branch": {
+			releaseVersion: "v1.14.0",
+			sourceVersion:  "v1.13.2-5-g1234abcd",
+			expectErr:      true,
+		},
+		"older version than the source": {
+			releaseVersion: "v1.13.5",
+			sourceVersion:  "v1.14.0-5-g1234abcd",
+			expectErr:      true,
+		},
+		"new major version": {
+			releaseVersion: "v2.0.0",
+			sourceVersion:  "v1.14.0-5-g1234abcd",
+			expectErr:      true,
+		},
+		"invalid release version": {
+			releaseVersion: "v1.14",
+			sourceVersion:  "v1.14.0",
+			expectErr:      true,
+		},
+		"source without a version tag": {
+			releaseVersion: "v1.14.0",
+			sourceVersion:  "1234abcd\n",
+			expectWarning:  true,
+		},
+		"source without a version tag and an invalid release version": {
+			releaseVersion: "v1.14",
+			sourceVersion:  "1234abcd",
+			expectErr:      true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			warning, err := checkReleaseVersionMatchesSource(test.releaseVersion, test.sourceVersion)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if (warning != "") != test.expectWarning {
+				t.Errorf("expectWarning=%v but got warning=%q", test.expectWarning, warning)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"google.golang.org/api/cloudbuild/v1"
+
+	"github.com/cert-manager/release/pkg/gcb"
+	"github.com/cert-manager/release/pkg/gcp"
+	"github.com/cert-manager/release/pkg/release"
+)
+
+const (
+	gcbStatusCommand         = "status"
+	gcbStatusDescription     = "List Google Cloud Build jobs for a release tag."
+	gcbStatusLongDescription = `The status command lists the Google Cloud Build jobs carrying a given
+build tag, along with their status and a link to their logs.
+
+Builds submitted by the stage, makestage and publish commands are tagged so
+they can be found again if the CLI exits before the build completes: staging
+builds are tagged "ref-<git ref>" and publish builds are tagged
+"name-<release name>".
+
+By default only builds which are still queued or running are listed. Builds
+listed here can be cancelled with the 'gcb cancel' command.`
+)
+
+var gcbStatusExample = fmt.Sprintf(`To list in-flight builds staging a given git ref:
+
+%s gcb %s --tag ref-<GIT_REF>
+
+To list all builds which published a given release, including finished ones:
+
+%s gcb %s --tag name-<RELEASE_NAME> --all`, rootCommand, gcbStatusCommand, rootCommand, gcbStatusCommand)
+
+type gcbStatusOptions struct {
+	// Project is the GCP project containing the GCB jobs
+	Project string
+
+	// Tag is the build tag to list GCB jobs for
+	Tag string
+
+	// All lists finished builds as well as in-flight ones
+	All bool
+}
+
+func (o *gcbStatusOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
+	fs.StringVar(&o.Project, "project", release.DefaultReleaseProject, "The GCP project containing the GCB build jobs.")
+	fs.StringVar(&o.Tag, "tag", "", "The build tag to list GCB build jobs for, e.g. 'ref-<git ref>' or 'name-<release name>'.")
+	fs.BoolVar(&o.All, "all", false, "If true, finished builds will be listed as well as those which are queued or running.")
+	markRequired("tag")
+}
+
+func (o *gcbStatusOptions) print() {
+	slog.Info("gcb status options",
+		"Project", o.Project,
+		"Tag", o.Tag,
+		"All", o.All,
+	)
+}
+
+func gcbStatusCmd(rootOpts *rootOptions) *cobra.Command {
+	o := &gcbStatusOptions{}
+	cmd := &cobra.Command{
+		Use:          gcbStatusCommand,
+		Short:        gcbStatusDescription,
+		Long:         gcbStatusLongDescription,
+		Example:      gcbStatusExample,
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			o.print()
+			slog.Info("---")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGCBStatus(rootOpts, o)
+		},
+	}
+
+	o.AddFlags(cmd.Flags(), mustMarkRequired(cmd.MarkFlagRequired))
+
+	return cmd
+}
+
+func runGCBStatus(rootOpts *rootOptions, o *gcbStatusOptions) error {
+	ctx := context.Background()
+
+	svc, err := gcp.NewCloudBuildService(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating cloudbuild client: %w", err)
+	}
+
+	builds, err := gcb.ListBuildsWithTag(ctx, svc, o.Project, o.Tag)
+	if err != nil {
+		return fmt.Errorf("failed to list builds with tag %q: %w", o.Tag, err)
+	}
+
+	if !o.All {
+		builds = inProgressBuilds(builds)
+	}
+
+	if len(builds) == 0 {
+		slog.Info("No matching builds found", "tag", o.Tag)
+		return nil
+	}
+
+	logTable(buildsTable(builds)...)
+
+	return nil
+}
+
+// inProgressBuilds returns only those builds which haven't yet finished.
+func inProgressBuilds(builds []*cloudbuild.Build) []*cloudbuild.Build {
+	var inProgress []*cloudbuild.Build
+	for _, b := range builds {
+		if !gcb.Finished(b.Status) {
+			inProgress = append(inProgress, b)
+		}
+	}
+
+	return inProgress
+}
+
+// buildsTable returns the tab-separated lines of the table of builds printed
+// by the gcb status command, including a header.
+func buildsTable(builds []*cloudbuild.Build) []string {
+	lines := []string{"ID\tSTATUS\tCREATED\tTAGS\tLOGS"}
+	for _, b := range builds {
+		lines = append(lines, fmt.Sprintf("%s\t%s\t%s\t%s\t%s", b.Id, b.Status, b.CreateTime, strings.Join(b.Tags, ","), b.LogUrl))
+	}
+
+	return lines
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/cloudbuild/v1"
+)
+
+func TestInProgressBuilds(t *testing.T) {
+	builds := []*cloudbuild.Build{
+		{Id: "queued", Status: "QUEUED"},
+		{Id: "working", Status: "WORKING"},
+		{Id: "success", Status: "SUCCESS"},
+		{Id: "failure", Status: "FAILURE"},
+		{Id: "cancelled", Status: "CANCELLED"},
+		{Id: "timeout", Status: "TIMEOUT"},
+	}
+
+	var ids []string
+	for _, b := range inProgressBuilds(builds) {
+		ids = append(ids, b.Id)
+	}
+
+	expected := []string{"queued", "working"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected in-progress builds %q but got %q", expected, ids)
+	}
+}
+
+func TestBuildsTable(t *testing.T) {
+	lines := buildsTable([]*cloudbuild.Build{
+		{
+			Id:         "abc",
+			Status:     "WORKING",
+			CreateTime: "2021-01-01T00:00:00Z",
+			Tags:       []string{"cert-manager-release-stage", "ref-abcdef"},
+			LogUrl:     "https://console.cloud.google.com/cloud-build/builds/abc",
+		},
+	})
+
+	expected := []string{
+		"ID\tSTATUS\tCREATED\tTAGS\tLOGS",
+		"abc\tWORKING\t2021-01-01T00:00:00Z\tcert-manager-release-stage,ref-abcdef\thttps://console.cloud.google.com/cloud-build/builds/abc",
+	}
+
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected table %q but got %q", expected, lines)
+	}
+}
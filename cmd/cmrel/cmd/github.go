@@ -0,0 +1,42 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"github.com/google/go-github/v35/github"
+	"golang.org/x/oauth2"
+)
+
+// githubTokenEnvVar is the environment variable containing the GitHub token
+// used to authenticate to the GitHub API.
+const githubTokenEnvVar = "GITHUB_TOKEN"
+
+// newGitHubClient returns a GitHub API client which authenticates using the
+// token in the GITHUB_TOKEN environment variable. If it isn't set, requests are
+// made anonymously and are subject to lower rate limits.
+func newGitHubClient(ctx context.Context) *github.Client {
+	httpClient := http.DefaultClient
+	if token := os.Getenv(githubTokenEnvVar); token != "" {
+		httpClient = oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}))
+	}
+
+	return github.NewClient(httpClient)
+}
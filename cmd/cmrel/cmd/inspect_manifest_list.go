@@ -0,0 +1,233 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+
+	"github.com/cert-manager/release/pkg/gcp"
+	"github.com/cert-manager/release/pkg/release"
+	"github.com/cert-manager/release/pkg/release/images"
+)
+
+const (
+	inspectManifestListCommand         = "inspect-manifest-list"
+	inspectManifestListDescription     = "Assemble and inspect the multi-arch image indexes for a staged release locally"
+	inspectManifestListLongDescription = `
+The 'inspect-manifest-list' command downloads a staged release and assembles an
+OCI image index for each image component from its per-architecture image tars,
+without pushing anything to a registry.
+
+The platform of each entry in each index is printed and checked against the
+expected list of platforms, so that problems with a release's images can be
+caught before 'gcb publish' creates manifest lists from them.
+`
+)
+
+type inspectManifestListOptions struct {
+	// The name of the GCS bucket containing the staged release.
+	Bucket string
+
+	// ReleaseName is the name of the staged release to inspect.
+	ReleaseName string
+
+	// The type of the staged release - usually one of 'release' or 'devel'
+	ReleaseType string
+
+	// MetadataFileName is the name of the file containing the release
+	// metadata in the root of the staged release.
+	MetadataFileName string
+
+	// StrictMetadata, if true, causes release metadata containing unknown
+	// fields to be rejected.
+	StrictMetadata bool
+
+	// ImageTarPrefix and ImageTarSuffix are trimmed from the file names of
+	// image tars in the staged release to determine their component names.
+	ImageTarPrefix string
+	ImageTarSuffix string
+
+	// Components is the list of image components to inspect. If empty, all
+	// image components in the release are inspected.
+	Components []string
+
+	// Platforms is the list of platforms, formatted as "os/arch", which each
+	// image index is expected to contain.
+	Platforms []string
+
+	// PrintManifest, if true, prints the raw OCI index manifest of each
+	// component to stdout.
+	PrintManifest bool
+}
+
+func (o *inspectManifestListOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
+	fs.StringVar(&o.Bucket, "bucket", release.DefaultBucketName, "The name of the GCS bucket containing the staged release.")
+	fs.StringVar(&o.ReleaseName, "release-name", "", "Name of the staged release to inspect.")
+	fs.StringVar(&o.ReleaseType, "release-type", release.BuildTypeRelease, "The type of the staged release, usually one of 'release' or 'devel'")
+	fs.StringVar(&o.MetadataFileName, "metadata-file-name", release.MetadataFileName, "The name of the file containing the release metadata in the root of the staged release.")
+	fs.BoolVar(&o.StrictMetadata, "strict-metadata", false, "Reject release metadata which contains unknown fields, rather than ignoring them.")
+	fs.StringVar(&o.ImageTarPrefix, "image-tar-prefix", "", "Prefix to trim from the file names of image tars in the staged release to determine their component names, e.g. 'cert-manager-' for 'cert-manager-controller.tar'.")
+	fs.StringVar(&o.ImageTarSuffix, "image-tar-suffix", "", "Suffix to trim from the file names of image tars in the staged release, after the '.tar' extension, to determine their component names.")
+	fs.StringSliceVar(&o.Components, "components", []string{}, "Comma-separated list of image components to inspect. Defaults to all image components in the release.")
+	fs.StringSliceVar(&o.Platforms, "platforms", defaultServerPlatforms(), "Comma-separated list of platforms, formatted as 'os/arch', which each image index is expected to contain.")
+	fs.BoolVar(&o.PrintManifest, "print-manifest", false, "If true, print the OCI index manifest of each component to stdout.")
+	markRequired("release-name")
+}
+
+func (o *inspectManifestListOptions) print() {
+	slog.Info("Inspect manifest list options",
+		"Bucket", o.Bucket,
+		"ReleaseName", o.ReleaseName,
+		"ReleaseType", o.ReleaseType,
+		"MetadataFileName", o.MetadataFileName,
+		"StrictMetadata", o.StrictMetadata,
+		"ImageTarPrefix", o.ImageTarPrefix,
+		"ImageTarSuffix", o.ImageTarSuffix,
+		"Components", o.Components,
+		"Platforms", o.Platforms,
+		"PrintManifest", o.PrintManifest,
+	)
+}
+
+func inspectManifestListCmd(rootOpts *rootOptions) *cobra.Command {
+	o := &inspectManifestListOptions{}
+	cmd := &cobra.Command{
+		Use:          inspectManifestListCommand,
+		Short:        inspectManifestListDescription,
+		Long:         inspectManifestListLongDescription,
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			o.print()
+			slog.Info("---")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runInspectManifestList(rootOpts, o)
+		},
+	}
+	o.AddFlags(cmd.Flags(), mustMarkRequired(cmd.MarkFlagRequired))
+	return cmd
+}
+
+// defaultServerPlatforms returns every platform that server images are built
+// for, formatted as "os/arch".
+func defaultServerPlatforms() []string {
+	var platforms []string
+	for osName, arches := range release.ServerPlatforms {
+		for _, arch := range arches {
+			platforms = append(platforms, osName+"/"+arch)
+		}
+	}
+
+	sort.Strings(platforms)
+
+	return platforms
+}
+
+func runInspectManifestList(rootOpts *rootOptions, o *inspectManifestListOptions) error {
+	ctx := context.Background()
+	gcs, err := gcp.NewStorageClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create GCS client: %w", err)
+	}
+
+	bucket := release.NewBucket(gcs.Bucket(o.Bucket), release.DefaultBucketPathPrefix, o.ReleaseType).WithMetadataFileName(o.MetadataFileName).WithStrictMetadata(o.StrictMetadata)
+	if err := bucket.CheckAccess(ctx); err != nil {
+		return err
+	}
+
+	staged, err := bucket.GetRelease(ctx, o.ReleaseName)
+	if err != nil {
+		return fmt.Errorf("failed to fetch release: %w", err)
+	}
+
+	rel, err := release.UnpackWithOptions(ctx, staged, release.UnpackOptions{
+		ImageTarPrefix: o.ImageTarPrefix,
+		ImageTarSuffix: o.ImageTarSuffix,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unpack staged release: %w", err)
+	}
+
+	components := o.Components
+	if len(components) == 0 {
+		for name := range rel.ComponentImageBundles {
+			components = append(components, name)
+		}
+		sort.Strings(components)
+	}
+
+	var failed []string
+	for _, name := range components {
+		tars, ok := rel.ComponentImageBundles[name]
+		if !ok {
+			return fmt.Errorf("release %q does not contain images for component %q", rel.ReleaseName, name)
+		}
+
+		if err := inspectComponentIndex(name, tars, o.Platforms, o.PrintManifest); err != nil {
+			slog.Error("Component failed verification", "component", name, "error", err)
+			failed = append(failed, name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("image indexes for %d component(s) failed verification: %q: %w", len(failed), failed, release.ErrValidationFailed)
+	}
+
+	slog.Info("Image indexes for all components contain the expected platforms", "count", len(components))
+
+	return nil
+}
+
+func inspectComponentIndex(name string, tars []*images.Tar, platforms []string, printManifest bool) error {
+	idx, err := images.BuildIndex(tars)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := idx.IndexManifest()
+	if err != nil {
+		return fmt.Errorf("failed to read index manifest: %w", err)
+	}
+
+	digest, err := idx.Digest()
+	if err != nil {
+		return fmt.Errorf("failed to compute index digest: %w", err)
+	}
+
+	slog.Info("Component image index", "component", name, "digest", digest, "entries", len(manifest.Manifests))
+	for _, desc := range manifest.Manifests {
+		slog.Info("Image index entry", "platform", desc.Platform, "digest", desc.Digest)
+	}
+
+	if printManifest {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(manifest); err != nil {
+			return fmt.Errorf("failed to print index manifest: %w", err)
+		}
+	}
+
+	return images.VerifyIndexPlatforms(idx, platforms)
+}
@@ -19,13 +19,15 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"time"
 
 	"github.com/spf13/cobra"
 	flag "github.com/spf13/pflag"
 	"google.golang.org/api/cloudbuild/v1"
 
 	"github.com/cert-manager/release/pkg/gcb"
+	"github.com/cert-manager/release/pkg/gcp"
 	"github.com/cert-manager/release/pkg/release"
 	"github.com/cert-manager/release/pkg/sign"
 )
@@ -60,7 +62,8 @@ type makeStageOptions struct {
 	// Ref is the git ref to check out when building
 	Ref string
 
-	// The path to the cloudbuild.yaml file to be used
+	// CloudBuildFile, if set, is the path to a cloudbuild.yaml file to use
+	// instead of the one built into cmrel
 	CloudBuildFile string
 
 	// Project is the name of the GCP project to run the GCB job in
@@ -76,6 +79,15 @@ type makeStageOptions struct {
 	// projects/<PROJECT_NAME>/locations/<LOCATION>/keyRings/<KEYRING_NAME>/cryptoKeys/<KEY_NAME>/cryptoKeyVersions/<KEY_VERSION>
 	// This must be set if SkipSigning is not set to true
 	SigningKMSKey string
+
+	// ExpectedBuildDuration is how long the build is expected to take. If
+	// the build is still running after this long, a warning is logged.
+	// Zero disables the warning.
+	ExpectedBuildDuration time.Duration
+
+	// Wait, if true, causes the command to wait for the GCB job to complete.
+	// If false, the command exits once the job has been submitted.
+	Wait bool
 }
 
 func (o *makeStageOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
@@ -83,25 +95,29 @@ func (o *makeStageOptions) AddFlags(fs *flag.FlagSet, markRequired func(string))
 	fs.StringVar(&o.Org, "org", "cert-manager", "Name of the GitHub org to fetch cert-manager sources from.")
 	fs.StringVar(&o.Repo, "repo", "cert-manager", "Name of the GitHub repo to fetch cert-manager sources from.")
 	fs.StringVar(&o.Ref, "ref", "master", "The git ref to build the release from.")
-	fs.StringVar(&o.CloudBuildFile, "cloudbuild", "./gcb/makestage/cloudbuild.yaml", "The path to the cloudbuild.yaml file used to perform the cert-manager crossbuild. "+
-		"The default value assumes that this tool is run from the root of the release repository.")
+	fs.StringVar(&o.CloudBuildFile, "cloudbuild", "", "Optional path to a cloudbuild.yaml file to use instead of the one built into cmrel. Only intended for testing changes to the build during development.")
 	fs.StringVar(&o.Project, "project", release.DefaultReleaseProject, "The GCP project to run the GCB build jobs in.")
 	fs.StringVar(&o.PublishedImageRepository, "published-image-repo", release.DefaultImageRepository, "The docker image repository set when building the release.")
 	fs.StringVar(&o.SigningKMSKey, "signing-kms-key", defaultKMSKey, "Full name of the GCP KMS key to use for signing")
+	fs.DurationVar(&o.ExpectedBuildDuration, "expected-build-duration", gcb.DefaultExpectedBuildDuration, "How long the build is expected to take. A warning is logged if the build is still running after this long. Set to 0 to disable the warning.")
+	fs.BoolVar(&o.Wait, "wait", true, fmt.Sprintf("Whether to wait for the GCB build job to complete. If false, the command exits once the job has been submitted, and '%s %s' can be used to wait for it later.", rootCommand, waitCommand))
 
 	markRequired("ref")
 }
 
 func (o *makeStageOptions) print() {
-	log.Printf("Stage options:")
-	log.Printf("  Bucket: %q", o.Bucket)
-	log.Printf("  Org: %q", o.Org)
-	log.Printf("  Repo: %q", o.Repo)
-	log.Printf("  Ref: %q", o.Ref)
-	log.Printf("  CloudBuildFile: %q", o.CloudBuildFile)
-	log.Printf("  Project: %q", o.Project)
-	log.Printf("  PublishedImageRepo: %q", o.PublishedImageRepository)
-	log.Printf("  SigningKMSKey: %q", o.SigningKMSKey)
+	slog.Info("Stage options",
+		"Bucket", o.Bucket,
+		"Org", o.Org,
+		"Repo", o.Repo,
+		"Ref", o.Ref,
+		"CloudBuildFile", o.CloudBuildFile,
+		"Project", o.Project,
+		"PublishedImageRepo", o.PublishedImageRepository,
+		"SigningKMSKey", o.SigningKMSKey,
+		"ExpectedBuildDuration", o.ExpectedBuildDuration,
+		"Wait", o.Wait,
+	)
 }
 
 func makeStageCmd(rootOpts *rootOptions) *cobra.Command {
@@ -114,7 +130,7 @@ func makeStageCmd(rootOpts *rootOptions) *cobra.Command {
 		SilenceUsage: true,
 		PreRun: func(cmd *cobra.Command, args []string) {
 			o.print()
-			log.Printf("---")
+			slog.Info("---")
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runMakeStage(rootOpts, o)
@@ -135,10 +151,9 @@ func runMakeStage(rootOpts *rootOptions, o *makeStageOptions) error {
 		}
 	}
 
-	log.Printf("Staging build for %s/%s@%s", o.Org, o.Repo, o.Ref)
+	slog.Info("Staging build", "repository", o.Org+"/"+o.Repo, "ref", o.Ref)
 
-	log.Printf("DEBUG: Loading cloudbuild.yaml file from %q", o.CloudBuildFile)
-	build, err := gcb.LoadBuild(o.CloudBuildFile)
+	build, err := gcb.LoadTemplate(gcb.TemplateMakeStage, o.CloudBuildFile)
 	if err != nil {
 		return fmt.Errorf("error loading cloudbuild.yaml file: %w", err)
 	}
@@ -152,36 +167,40 @@ func runMakeStage(rootOpts *rootOptions, o *makeStageOptions) error {
 	build.Substitutions["_RELEASE_TARGET_BUCKET"] = o.Bucket
 	build.Substitutions["_KMS_KEY"] = o.SigningKMSKey
 
-	log.Printf("DEBUG: building google cloud build API client")
-	svc, err := cloudbuild.NewService(ctx)
+	slog.Debug("building google cloud build API client")
+	svc, err := gcp.NewCloudBuildService(ctx)
 	if err != nil {
 		return fmt.Errorf("error building google cloud build API client: %w", err)
 	}
 
-	log.Printf("Submitting GCB build job...")
+	slog.Info("Submitting GCB build job...")
 	build, err = gcb.SubmitBuild(svc, o.Project, build)
 	if err != nil {
 		return fmt.Errorf("error submitting build to cloud build: %w", err)
 	}
 
-	log.Println("---")
-	log.Printf("Submitted build with name: %q", build.Id)
-	log.Printf("  View logs at: %s", build.LogUrl)
-	log.Printf("  Log bucket: %s", build.LogsBucket)
-	log.Println("---")
-	log.Printf("Waiting for build to complete, this may take a while...")
+	slog.Info("---")
+	slog.Info("Submitted build", "build_id", build.Id, "log_url", build.LogUrl, "logs_bucket", build.LogsBucket)
+	slog.Info("---")
+
+	if !o.Wait {
+		logNotWaiting(o.Project, build)
+		return nil
+	}
+
+	slog.Info("Waiting for build to complete, this may take a while...")
 
-	build, err = gcb.WaitForBuild(svc, o.Project, build.Id)
+	build, err = gcb.WaitForBuild(svc, o.Project, build.Id, o.ExpectedBuildDuration)
 	if err != nil {
 		return fmt.Errorf("error waiting for cloud build to complete: %w", err)
 	}
 
 	if build.Status != gcb.Success {
-		log.Printf("An error occurred building the release. Check the log files for more information: %s", build.LogUrl)
+		slog.Error("An error occurred building the release. Check the log files for more information", "log_url", build.LogUrl)
 		return fmt.Errorf("building release with ref %q failed", o.Ref)
 	}
 
-	log.Printf("Release build complete for %s/%s@%s", o.Org, o.Repo, o.Ref)
+	slog.Info("Release build complete", "repository", o.Org+"/"+o.Repo, "ref", o.Ref)
 
 	return nil
 }
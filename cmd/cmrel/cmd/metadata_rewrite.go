@@ -0,0 +1,129 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/cert-manager/release/pkg/gcp"
+	"github.com/cert-manager/release/pkg/release"
+	"github.com/cert-manager/release/pkg/sign"
+)
+
+// metadataRewriteOptions configures how commands which rewrite the metadata
+// of a staged release, such as 'migrate-metadata' and 'repair-metadata', find
+// the release and sign the rewritten metadata.
+type metadataRewriteOptions struct {
+	// The name of the GCS bucket containing the staged release.
+	Bucket string
+
+	// ReleaseName is the name of the staged release whose metadata is
+	// rewritten.
+	ReleaseName string
+
+	// The type of the staged release - usually one of 'release' or 'devel'
+	ReleaseType string
+
+	// MetadataFileName is the name of the file containing the release
+	// metadata in the root of the staged release.
+	MetadataFileName string
+
+	// StrictMetadata, if true, causes release metadata containing unknown
+	// fields to be rejected.
+	StrictMetadata bool
+
+	// SkipSigning, if true, writes the rewritten metadata without a
+	// signature. Releases whose metadata isn't signed can't be published
+	// while metadata signature verification is enabled.
+	SkipSigning bool
+
+	// SigningKMSKey is the full name of the GCP KMS key used to sign the
+	// rewritten metadata.
+	SigningKMSKey string
+}
+
+// AddFlags adds the flags for the options to fs. verb describes what the
+// command does to the release, e.g. "migrate" or "repair".
+func (o *metadataRewriteOptions) AddFlags(fs *flag.FlagSet, markRequired func(string), verb string) {
+	fs.StringVar(&o.Bucket, "bucket", release.DefaultBucketName, "The name of the GCS bucket containing the staged release.")
+	fs.StringVar(&o.ReleaseName, "release-name", "", fmt.Sprintf("Name of the staged release to %s.", verb))
+	fs.StringVar(&o.ReleaseType, "release-type", release.BuildTypeDevel, "The type of the staged release, usually one of 'release' or 'devel'")
+	fs.StringVar(&o.MetadataFileName, "metadata-file-name", release.MetadataFileName, "The name of the file containing the release metadata in the root of the staged release.")
+	fs.BoolVar(&o.StrictMetadata, "strict-metadata", false, "Reject release metadata which contains unknown fields, rather than ignoring them.")
+	fs.BoolVar(&o.SkipSigning, "skip-signing", false, "Write the rewritten metadata without a signature.")
+	fs.StringVar(&o.SigningKMSKey, "signing-kms-key", defaultKMSKey, "Full name of the GCP KMS key used to sign the rewritten metadata.")
+	markRequired("release-name")
+}
+
+// attrs returns the options as attributes to be logged by the commands
+// which embed them.
+func (o *metadataRewriteOptions) attrs() []any {
+	return []any{
+		"Bucket", o.Bucket,
+		"ReleaseName", o.ReleaseName,
+		"ReleaseType", o.ReleaseType,
+		"MetadataFileName", o.MetadataFileName,
+		"StrictMetadata", o.StrictMetadata,
+		"SkipSigning", o.SkipSigning,
+		"SigningKMSKey", o.SigningKMSKey,
+	}
+}
+
+// fetchRelease returns the staged release whose metadata is to be rewritten,
+// along with a bucket which signs any metadata written to it unless signing
+// is skipped.
+func (o *metadataRewriteOptions) fetchRelease(ctx context.Context) (*release.Bucket, *release.Staged, error) {
+	var signer release.MetadataSigner
+	if !o.SkipSigning {
+		parsedKey, err := sign.NewGCPKMSKey(o.SigningKMSKey)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		signer = kmsMetadataSigner(parsedKey)
+	}
+
+	gcs, err := gcp.NewStorageClient(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create GCS client: %w", err)
+	}
+
+	bucket := release.NewBucket(gcs.Bucket(o.Bucket), release.DefaultBucketPathPrefix, o.ReleaseType).WithMetadataFileName(o.MetadataFileName).WithStrictMetadata(o.StrictMetadata).WithMetadataSigner(signer)
+	if err := bucket.CheckAccess(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	staged, err := bucket.GetRelease(ctx, o.ReleaseName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch release: %w", err)
+	}
+
+	return bucket, staged, nil
+}
+
+// logMetadataChanges logs the changes which are required to the metadata of a
+// staged release.
+func logMetadataChanges(changes []string) {
+	slog.Info("The following changes are required to the release metadata:")
+	for _, c := range changes {
+		slog.Info("  - " + c)
+	}
+}
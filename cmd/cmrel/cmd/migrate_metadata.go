@@ -0,0 +1,124 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+
+	"github.com/cert-manager/release/pkg/release"
+)
+
+const (
+	migrateMetadataCommand         = "migrate-metadata"
+	migrateMetadataDescription     = "Upgrade a staged release's metadata to the current schema version"
+	migrateMetadataLongDescription = `
+The 'migrate-metadata' command reads the metadata of a staged release which was
+written by an older version of cmrel, upgrades it to the current schema version
+and writes it back, so that older staged releases remain usable.
+
+Fields added in newer schema versions are filled in where possible, including
+the checksums and sizes of artifacts which are missing them.
+
+Unlike 'repair-metadata', artifacts are only downloaded where needed to fill
+in fields which are missing, and existing checksums are kept as they are.
+
+Pass --dry-run to only print the changes which would be made, and
+--strict-metadata to refuse metadata containing fields this version of cmrel
+doesn't know about.
+
+Unless --skip-signing is set, the migrated metadata is signed with the KMS key
+given by --signing-kms-key so that the release can still be published.
+`
+)
+
+type migrateMetadataOptions struct {
+	metadataRewriteOptions
+
+	// DryRun, if true, will only print the changes which would be made to
+	// the release metadata.
+	DryRun bool
+}
+
+func (o *migrateMetadataOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
+	o.metadataRewriteOptions.AddFlags(fs, markRequired, "migrate")
+	fs.BoolVar(&o.DryRun, "dry-run", false, "Only print the changes which would be made to the release metadata.")
+}
+
+func (o *migrateMetadataOptions) print() {
+	slog.Info("Migrate metadata options", append(o.metadataRewriteOptions.attrs(),
+		"DryRun", o.DryRun,
+	)...)
+}
+
+func migrateMetadataCmd(rootOpts *rootOptions) *cobra.Command {
+	o := &migrateMetadataOptions{}
+	cmd := &cobra.Command{
+		Use:          migrateMetadataCommand,
+		Short:        migrateMetadataDescription,
+		Long:         migrateMetadataLongDescription,
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			o.print()
+			slog.Info("---")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMigrateMetadata(rootOpts, o)
+		},
+	}
+	o.AddFlags(cmd.Flags(), mustMarkRequired(cmd.MarkFlagRequired))
+	return cmd
+}
+
+func runMigrateMetadata(rootOpts *rootOptions, o *migrateMetadataOptions) error {
+	ctx := context.Background()
+
+	bucket, staged, err := o.fetchRelease(ctx)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Migrating release metadata", "release", staged.Name(), "from_schema_version", staged.Metadata().SchemaVersion, "to_schema_version", release.CurrentMetadataSchemaVersion)
+
+	meta, changes, err := release.MigrateMetadata(ctx, staged)
+	if err != nil {
+		return err
+	}
+
+	if len(changes) == 0 {
+		slog.Info("Release metadata is already at the current schema version, nothing to do")
+		return nil
+	}
+
+	logMetadataChanges(changes)
+
+	if o.DryRun {
+		slog.Info("--dry-run set, not rewriting release metadata")
+		return nil
+	}
+
+	if err := bucket.WriteMetadata(ctx, staged.Name(), *meta); err != nil {
+		return err
+	}
+
+	slog.Info("Migrated release metadata", "release", staged.Name())
+
+	return nil
+}
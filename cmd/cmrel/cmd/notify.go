@@ -0,0 +1,111 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/cert-manager/release/pkg/notify"
+)
+
+// notifySlackWebhookEnv is the environment variable the Slack webhook URL is
+// read from if --notify-slack-webhook isn't set, so that it needn't appear in
+// shell history.
+const notifySlackWebhookEnv = "CMREL_NOTIFY_SLACK_WEBHOOK"
+
+// notifyOptions configures notifications sent when a GCB job which a command
+// waited for completes.
+type notifyOptions struct {
+	// NotifySlackWebhook is the URL of a Slack incoming webhook to post
+	// notifications to. If empty, no notifications are sent.
+	NotifySlackWebhook string
+
+	// NotifyOn lists the job outcomes to send notifications for.
+	NotifyOn []string
+}
+
+func (o *notifyOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
+	fs.StringVar(&o.NotifySlackWebhook, "notify-slack-webhook", "", fmt.Sprintf("URL of a Slack incoming webhook to post a message to when the GCB job completes. Defaults to the value of $%s; if neither is set, no notifications are sent.", notifySlackWebhookEnv))
+	fs.StringSliceVar(&o.NotifyOn, "notify-on", notify.Outcomes, fmt.Sprintf("Comma-separated list of job outcomes to send notifications for. Options: %s", strings.Join(notify.Outcomes, ", ")))
+}
+
+// attrs returns the options as attributes to be logged by the commands
+// which embed them.
+func (o *notifyOptions) attrs() []any {
+	// the webhook URL is a secret, so only whether it's set is logged
+	return []any{
+		"NotifySlackWebhookSet", o.slackWebhookURL() != "",
+		"NotifyOn", o.NotifyOn,
+	}
+}
+
+// validate checks that the notification options are valid, before any job is
+// submitted. Notifications are only sent by a command which waits for the job
+// to complete, so an explicitly set webhook is rejected if wait is false.
+func (o *notifyOptions) validate(wait bool) error {
+	if _, err := notify.ParseOutcomes(o.NotifyOn); err != nil {
+		return fmt.Errorf("invalid --notify-on: %w", err)
+	}
+
+	if !wait && o.NotifySlackWebhook != "" {
+		return fmt.Errorf("--notify-slack-webhook can't be used with --wait=false, since nothing would be notified; pass it to '%s %s' instead", rootCommand, waitCommand)
+	}
+
+	return nil
+}
+
+func (o *notifyOptions) slackWebhookURL() string {
+	if o.NotifySlackWebhook != "" {
+		return o.NotifySlackWebhook
+	}
+
+	return os.Getenv(notifySlackWebhookEnv)
+}
+
+// notify posts a notification of e if a webhook is configured and e's outcome
+// is one which should be notified on. A notification which can't be sent
+// doesn't change the outcome of the job, so errors are only logged.
+func (o *notifyOptions) notify(ctx context.Context, e notify.Event) {
+	url := o.slackWebhookURL()
+	if url == "" {
+		return
+	}
+
+	outcomes, err := notify.ParseOutcomes(o.NotifyOn)
+	if err != nil {
+		slog.Warn("not sending notification", "error", err)
+		return
+	}
+
+	if !outcomes.Has(e.Outcome()) {
+		return
+	}
+
+	webhook := &notify.SlackWebhook{URL: url}
+	if err := webhook.Notify(ctx, e); err != nil {
+		slog.Warn("failed to send notification", "error", err)
+		return
+	}
+
+	slog.Info("Sent notification", "outcome", e.Outcome(), "job", e.Job)
+}
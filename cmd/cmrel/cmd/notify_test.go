@@ -0,0 +1,55 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import "testing"
+
+func TestNotifyOptionsValidate(t *testing.T) {
+	tests := map[string]struct {
+		opts      notifyOptions
+		wait      bool
+		expectErr bool
+	}{
+		"webhook while waiting": {
+			opts: notifyOptions{NotifySlackWebhook: "https://hooks.slack.com/services/x", NotifyOn: []string{"success", "failure"}},
+			wait: true,
+		},
+		"webhook without waiting": {
+			opts:      notifyOptions{NotifySlackWebhook: "https://hooks.slack.com/services/x", NotifyOn: []string{"success", "failure"}},
+			wait:      false,
+			expectErr: true,
+		},
+		"no webhook without waiting": {
+			opts: notifyOptions{NotifyOn: []string{"success", "failure"}},
+			wait: false,
+		},
+		"unknown outcome": {
+			opts:      notifyOptions{NotifyOn: []string{"sometimes"}},
+			wait:      true,
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.opts.validate(test.wait)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+		})
+	}
+}
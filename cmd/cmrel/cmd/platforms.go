@@ -0,0 +1,189 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/cert-manager/release/pkg/release"
+)
+
+const (
+	platformsCommand         = "platforms"
+	platformsDescription     = "Print the OSes and architectures which cert-manager releases target"
+	platformsLongDescription = `
+The 'platforms' command prints the OSes and architectures which are built during
+a release. Server platforms are those which docker images are built for, client
+platforms are those which client CLI tools are built for and the combined matrix
+contains every platform which is targeted by either.
+
+Platforms are printed formatted as "os/arch", one per line. Use '--output=json'
+to print them in a structured form which is easier to consume from scripts.
+`
+
+	platformsOutputText = "text"
+	platformsOutputJSON = "json"
+)
+
+var (
+	platformsExample = fmt.Sprintf(`
+To print every platform targeted by a release:
+
+	%s %s
+
+To print the server platforms as a JSON list:
+
+	%s %s --output=json | jq -r '.server[]'
+`, rootCommand, platformsCommand, rootCommand, platformsCommand)
+)
+
+type platformsOptions struct {
+	// Output is the format used to print the platforms, one of "text" or
+	// "json".
+	Output string
+}
+
+func (o *platformsOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
+	fs.StringVarP(&o.Output, "output", "o", platformsOutputText, fmt.Sprintf("Output format, one of: %s, %s. Output is written to stdout.", platformsOutputText, platformsOutputJSON))
+}
+
+func (o *platformsOptions) print() {
+	slog.Info("Platforms options",
+		"Output", o.Output,
+	)
+}
+
+func platformsCmd(rootOpts *rootOptions) *cobra.Command {
+	o := &platformsOptions{}
+	cmd := &cobra.Command{
+		Use:          platformsCommand,
+		Short:        platformsDescription,
+		Long:         platformsLongDescription,
+		Example:      platformsExample,
+		SilenceUsage: true,
+		PreRun: func(_ *cobra.Command, _ []string) {
+			o.print()
+			slog.Info("---")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPlatforms(rootOpts, o)
+		},
+	}
+	o.AddFlags(cmd.Flags(), mustMarkRequired(cmd.MarkFlagRequired))
+	return cmd
+}
+
+func runPlatforms(_ *rootOptions, o *platformsOptions) error {
+	return writePlatforms(os.Stdout, o.Output, currentPlatforms())
+}
+
+// platformsSummary is the structured form of the platforms printed by the
+// platforms command. Each platform is formatted as "os/arch".
+type platformsSummary struct {
+	Server []string `json:"server"`
+	Client []string `json:"client"`
+	All    []string `json:"all"`
+
+	// OSes and Arches list every OS and architecture in the combined matrix.
+	OSes   []string `json:"oses"`
+	Arches []string `json:"arches"`
+}
+
+// currentPlatforms returns the platforms currently targeted by releases.
+func currentPlatforms() platformsSummary {
+	oses := release.AllOSes()
+
+	var all []string
+	for _, osName := range oses.List() {
+		for _, arch := range release.AllArchesForOSes(sets.NewString(osName)).List() {
+			all = append(all, osName+"/"+arch)
+		}
+	}
+
+	return platformsSummary{
+		Server: platformList(release.ServerPlatforms),
+		Client: platformList(release.ClientPlatforms),
+		All:    all,
+		OSes:   oses.List(),
+		Arches: release.AllArchesForOSes(oses).List(),
+	}
+}
+
+// platformList returns the given map of OSes to architectures as a sorted list
+// of platforms formatted as "os/arch".
+func platformList(platforms map[string][]string) []string {
+	var out []string
+	for osName, arches := range platforms {
+		for _, arch := range arches {
+			out = append(out, osName+"/"+arch)
+		}
+	}
+
+	sort.Strings(out)
+
+	return out
+}
+
+// writePlatforms writes the given platforms to w in the given format, which
+// must be one of "text" or "json".
+func writePlatforms(w io.Writer, format string, summary platformsSummary) error {
+	switch format {
+	case platformsOutputText:
+		sections := []struct {
+			name      string
+			platforms []string
+		}{
+			{"Server platforms", summary.Server},
+			{"Client platforms", summary.Client},
+			{"All platforms", summary.All},
+		}
+
+		for i, section := range sections {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+
+			fmt.Fprintf(w, "%s:\n", section.name)
+			for _, platform := range section.platforms {
+				fmt.Fprintf(w, "  %s\n", platform)
+			}
+		}
+
+		return nil
+
+	case platformsOutputJSON:
+		out, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode platforms as JSON: %w", err)
+		}
+
+		_, err = fmt.Fprintf(w, "%s\n", out)
+		return err
+
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
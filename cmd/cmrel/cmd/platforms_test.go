@@ -0,0 +1,94 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWritePlatforms(t *testing.T) {
+	knownServer := []string{"linux/amd64", "linux/arm", "linux/arm64", "linux/ppc64le", "linux/s390x"}
+	knownClient := []string{"darwin/amd64", "darwin/arm64", "linux/amd64", "linux/arm", "linux/arm64", "linux/ppc64le", "linux/s390x", "windows/amd64"}
+
+	summary := currentPlatforms()
+
+	if !reflect.DeepEqual(summary.Server, knownServer) {
+		t.Errorf("unexpected server platforms:\ngot=%q\nexp=%q", summary.Server, knownServer)
+	}
+
+	if !reflect.DeepEqual(summary.Client, knownClient) {
+		t.Errorf("unexpected client platforms:\ngot=%q\nexp=%q", summary.Client, knownClient)
+	}
+
+	if !reflect.DeepEqual(summary.All, knownClient) {
+		t.Errorf("unexpected combined platforms:\ngot=%q\nexp=%q", summary.All, knownClient)
+	}
+
+	if exp := []string{"darwin", "linux", "windows"}; !reflect.DeepEqual(summary.OSes, exp) {
+		t.Errorf("unexpected OSes:\ngot=%q\nexp=%q", summary.OSes, exp)
+	}
+
+	if exp := []string{"amd64", "arm", "arm64", "ppc64le", "s390x"}; !reflect.DeepEqual(summary.Arches, exp) {
+		t.Errorf("unexpected arches:\ngot=%q\nexp=%q", summary.Arches, exp)
+	}
+
+	t.Run("text", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := writePlatforms(&buf, platformsOutputText, summary); err != nil {
+			t.Fatal(err)
+		}
+
+		out := buf.String()
+		for _, section := range []string{"Server platforms:", "Client platforms:", "All platforms:"} {
+			if !strings.Contains(out, section) {
+				t.Errorf("expected output to contain %q:\n%s", section, out)
+			}
+		}
+
+		for _, platform := range knownClient {
+			if !strings.Contains(out, "  "+platform+"\n") {
+				t.Errorf("expected output to list %q:\n%s", platform, out)
+			}
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := writePlatforms(&buf, platformsOutputJSON, summary); err != nil {
+			t.Fatal(err)
+		}
+
+		var decoded platformsSummary
+		if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+			t.Fatalf("failed to decode JSON output: %v\n%s", err, buf.String())
+		}
+
+		if !reflect.DeepEqual(decoded, summary) {
+			t.Errorf("JSON output did not round trip:\ngot=%+v\nexp=%+v", decoded, summary)
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		if err := writePlatforms(&bytes.Buffer{}, "yaml", summary); err == nil {
+			t.Fatal("expected an error for an unknown output format")
+		}
+	})
+}
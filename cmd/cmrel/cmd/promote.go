@@ -0,0 +1,211 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"golang.org/x/mod/semver"
+
+	"github.com/cert-manager/release/pkg/gcp"
+	"github.com/cert-manager/release/pkg/release"
+	"github.com/cert-manager/release/pkg/release/validation"
+	"github.com/cert-manager/release/pkg/sign"
+)
+
+const (
+	promoteCommand         = "promote"
+	promoteDescription     = "Promote a staged devel build to a release without rebuilding it"
+	promoteLongDescription = `
+The 'promote' command copies the artifacts of a staged build, usually of type
+'devel', into the 'release' path of the same bucket so that it can be published
+with 'cmrel publish'.
+
+The metadata of the promoted release is rewritten with the given release
+version, and the checksum and size of every artifact are recomputed as it is
+copied. Promotion fails if any copied artifact doesn't match the checksum
+recorded in the staged build's metadata, or if a release with the same version
+and git ref has already been staged.
+
+Unless --skip-signing is set, the signature of the staged build's metadata is
+verified before it's trusted, and the promoted release's metadata is signed
+with the same KMS key.
+
+The artifacts themselves are copied as-is, so any version information embedded
+in them when they were built is not updated. Only staged builds which were built
+for exactly the given release version, usually from its git tag, can be
+promoted: the staged build is unpacked before anything is copied, and promotion
+fails if its Helm chart version or image tags don't match the release version.
+`
+)
+
+var (
+	promoteExample = fmt.Sprintf(`
+Promote a devel build of the v1.2.3 tag to the release v1.2.3:
+
+	%s %s --release-name v1.2.3-0123456789abcdef --release-version v1.2.3
+`, rootCommand, promoteCommand)
+)
+
+type promoteOptions struct {
+	// The name of the GCS bucket containing the staged build, which the
+	// promoted release is also written to.
+	Bucket string
+
+	// ReleaseName is the name of the staged build to promote.
+	ReleaseName string
+
+	// ReleaseVersion is the version of the promoted release.
+	ReleaseVersion string
+
+	// SourceReleaseType is the type of the staged build to promote, usually
+	// 'devel'.
+	SourceReleaseType string
+
+	// MetadataFileName is the name of the file containing the release
+	// metadata in the root of the staged build.
+	MetadataFileName string
+
+	// StrictMetadata, if true, causes release metadata containing unknown
+	// fields to be rejected.
+	StrictMetadata bool
+
+	// SkipSigning, if true, writes the metadata of the promoted release
+	// without a signature, and doesn't verify the signature of the staged
+	// build's metadata.
+	SkipSigning bool
+
+	// SigningKMSKey is the full name of the GCP KMS key used to verify the
+	// staged build's metadata and to sign the promoted release's metadata.
+	SigningKMSKey string
+}
+
+func (o *promoteOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
+	fs.StringVar(&o.Bucket, "bucket", release.DefaultBucketName, "The name of the GCS bucket containing the staged build.")
+	fs.StringVar(&o.ReleaseName, "release-name", "", "Name of the staged build to promote.")
+	fs.StringVar(&o.ReleaseVersion, "release-version", "", "The version of the promoted release, e.g. 'v1.2.3'.")
+	fs.StringVar(&o.SourceReleaseType, "source-release-type", release.BuildTypeDevel, "The type of the staged build to promote, usually 'devel'.")
+	fs.StringVar(&o.MetadataFileName, "metadata-file-name", release.MetadataFileName, "The name of the file containing the release metadata in the root of the staged build.")
+	fs.BoolVar(&o.StrictMetadata, "strict-metadata", false, "Reject release metadata which contains unknown fields, rather than ignoring them.")
+	fs.BoolVar(&o.SkipSigning, "skip-signing", false, "Don't verify the signature of the staged build's metadata, and write the promoted release's metadata without a signature.")
+	fs.StringVar(&o.SigningKMSKey, "signing-kms-key", defaultKMSKey, "Full name of the GCP KMS key used to verify the staged build's metadata and sign the promoted release's metadata.")
+	markRequired("release-name")
+	markRequired("release-version")
+}
+
+func (o *promoteOptions) print() {
+	slog.Info("Promote options",
+		"Bucket", o.Bucket,
+		"ReleaseName", o.ReleaseName,
+		"ReleaseVersion", o.ReleaseVersion,
+		"SourceReleaseType", o.SourceReleaseType,
+		"MetadataFileName", o.MetadataFileName,
+		"StrictMetadata", o.StrictMetadata,
+		"SkipSigning", o.SkipSigning,
+		"SigningKMSKey", o.SigningKMSKey,
+	)
+}
+
+func promoteCmd(rootOpts *rootOptions) *cobra.Command {
+	o := &promoteOptions{}
+	cmd := &cobra.Command{
+		Use:          promoteCommand,
+		Short:        promoteDescription,
+		Long:         promoteLongDescription,
+		Example:      promoteExample,
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			o.print()
+			slog.Info("---")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPromote(rootOpts, o)
+		},
+	}
+	o.AddFlags(cmd.Flags(), mustMarkRequired(cmd.MarkFlagRequired))
+	return cmd
+}
+
+func runPromote(rootOpts *rootOptions, o *promoteOptions) error {
+	if !semver.IsValid(o.ReleaseVersion) {
+		return fmt.Errorf("invalid --release-version %q: must be a semver version with a leading 'v'", o.ReleaseVersion)
+	}
+
+	if o.SourceReleaseType == release.BuildTypeRelease {
+		return fmt.Errorf("refusing to promote a staged build which is already of type %q", release.BuildTypeRelease)
+	}
+
+	var signer release.MetadataSigner
+	var verifier release.MetadataVerifier
+	if !o.SkipSigning {
+		parsedKey, err := sign.NewGCPKMSKey(o.SigningKMSKey)
+		if err != nil {
+			return err
+		}
+
+		signer = kmsMetadataSigner(parsedKey)
+		verifier = kmsMetadataVerifier(parsedKey)
+	}
+
+	ctx := context.Background()
+	gcs, err := gcp.NewStorageClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create GCS client: %w", err)
+	}
+
+	src := release.NewBucket(gcs.Bucket(o.Bucket), release.DefaultBucketPathPrefix, o.SourceReleaseType).WithMetadataFileName(o.MetadataFileName).WithStrictMetadata(o.StrictMetadata).WithMetadataVerifier(verifier)
+	if err := src.CheckAccess(ctx); err != nil {
+		return err
+	}
+
+	staged, err := src.GetRelease(ctx, o.ReleaseName)
+	if err != nil {
+		return fmt.Errorf("failed to fetch staged build: %w", err)
+	}
+
+	// artifacts aren't rebuilt, so a staged build whose chart and images
+	// carry any other version would be rejected when it's published
+	rel, err := release.Unpack(ctx, staged)
+	if err != nil {
+		return fmt.Errorf("failed to unpack staged build: %w", err)
+	}
+
+	if violations := validation.ValidateVersion(o.ReleaseVersion, rel); len(violations) > 0 {
+		slog.Error("Staged build wasn't built for the release version:", "release", staged.Name(), "version", o.ReleaseVersion)
+		for _, v := range violations {
+			slog.Info("  - " + v)
+		}
+		return fmt.Errorf("refusing to promote staged build %q: %w", staged.Name(), release.ErrValidationFailed)
+	}
+
+	slog.Info("Promoting staged build", "count", len(staged.Metadata().Artifacts), "release", staged.Name(), "version", o.ReleaseVersion)
+
+	dst := release.NewBucket(gcs.Bucket(o.Bucket), release.DefaultBucketPathPrefix, release.BuildTypeRelease).WithMetadataFileName(o.MetadataFileName).WithMetadataSigner(signer)
+
+	name, err := release.Promote(ctx, staged, dst, o.ReleaseVersion)
+	if err != nil {
+		return fmt.Errorf("failed to promote staged build %q: %w", staged.Name(), err)
+	}
+
+	slog.Info("Promoted staged build, which can now be published with --release-name="+name, "release", staged.Name(), "promoted_release", name)
+
+	return nil
+}
@@ -19,16 +19,18 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
+	"time"
 
-	"cloud.google.com/go/storage"
 	"github.com/spf13/cobra"
 	flag "github.com/spf13/pflag"
-	"google.golang.org/api/cloudbuild/v1"
 
 	"github.com/cert-manager/release/pkg/gcb"
+	"github.com/cert-manager/release/pkg/gcp"
+	"github.com/cert-manager/release/pkg/notify"
 	"github.com/cert-manager/release/pkg/release"
+	"github.com/cert-manager/release/pkg/release/sbom"
 	"github.com/cert-manager/release/pkg/sign"
 )
 
@@ -41,6 +43,9 @@ which will consume a staged build, run pre-release checks, push docker images,
 Helm charts, generated static manifests and create a release tag on GitHub.
 
 It can only be run by specifying a previously staged build.
+
+If --notify-slack-webhook is set, a message including the build log URL and
+any manual actions left to take is posted when the job completes.
 `
 	publishExample = ""
 )
@@ -52,7 +57,12 @@ type publishOptions struct {
 	// Name of the staged release to publish
 	ReleaseName string
 
-	// The path to the cloudbuild.yaml file used to perform the cert-manager crossbuild
+	// MetadataFileName is the name of the file containing the release
+	// metadata in the root of the staged release.
+	MetadataFileName string
+
+	// CloudBuildFile, if set, is the path to a cloudbuild.yaml file to use
+	// instead of the one built into cmrel
 	CloudBuildFile string
 
 	// Project to run the GCB job in
@@ -73,6 +83,10 @@ type publishOptions struct {
 	// It is used as the repository for manifest lists created for artifacts.
 	PublishedImageRepository string
 
+	// PublishedImageMirrorRepositories are additional image repositories
+	// which images and manifest lists are also pushed to.
+	PublishedImageMirrorRepositories []string
+
 	// PublishedHelmChartGitHubOwner is the name of the owner of the GitHub repo
 	// for Helm charts.
 	PublishedHelmChartGitHubOwner string
@@ -85,6 +99,19 @@ type publishOptions struct {
 	// GitHub repository for Helm Charts.
 	PublishedHelmChartGitHubBranch string
 
+	// PublishedHelmChartOCIRegistry is the OCI registry, prefixed with
+	// "oci://", which Helm charts are pushed to by the 'helmchartoci' action.
+	PublishedHelmChartOCIRegistry string
+
+	// PublishedHelmChartRepoBucket is the GCS bucket backing a static Helm
+	// chart repository, which charts are uploaded to and indexed in by the
+	// 'helmchartrepo' action. If empty, the action does nothing.
+	PublishedHelmChartRepoBucket string
+
+	// PublishedHelmChartRepoURL is the URL the Helm chart repository in
+	// PublishedHelmChartRepoBucket is served from.
+	PublishedHelmChartRepoURL string
+
 	// PublishedGitHubOrg is the org of the repository where the release will
 	// be published to.
 	PublishedGitHubOrg string
@@ -93,10 +120,86 @@ type publishOptions struct {
 	// release will be published to.
 	PublishedGitHubRepo string
 
+	// PublishedCmctlGitHubOrg is the org of the repository where cmctl
+	// binaries in the release will be published to, for versions of
+	// cert-manager which no longer ship cmctl themselves.
+	PublishedCmctlGitHubOrg string
+
+	// PublishedCmctlGitHubRepo is the repo name in PublishedCmctlGitHubOrg
+	// where cmctl binaries in the release will be published to.
+	PublishedCmctlGitHubRepo string
+
 	// PublishActions is a list of publishing actions which should be taken,
 	// or else "*" - the default - to mean "all actions"
 	PublishActions []string
 
+	// ResumeFromAction, if set, skips all publish actions which would run
+	// before the named action.
+	ResumeFromAction string
+
+	// IgnorePublishState, if true, ignores the progress recorded by any
+	// previous attempt to publish the release and runs every action again.
+	IgnorePublishState bool
+
+	// SkipReleaseNotes, if true, creates the draft GitHub release with a
+	// placeholder body rather than generating release notes.
+	SkipReleaseNotes bool
+
+	// PreviousReleaseTag is the tag which release notes are generated from.
+	// If empty, the highest semver tag before the release version is used.
+	PreviousReleaseTag string
+
+	// Prerelease is whether the GitHub release is marked as a prerelease:
+	// "true", "false" or "auto" to mark alpha, beta and rc versions.
+	Prerelease string
+
+	// Latest is whether the GitHub release is marked as the latest release
+	// when it's published: "true", "false" or "auto" to mark it unless it's a
+	// prerelease or a higher version has already been tagged.
+	Latest string
+
+	// SBOMFormat is the format of the SBOMs generated for each container image
+	// and ctl binary in the release. If empty, no SBOMs are generated.
+	SBOMFormat string
+
+	// VerifyHelmChart, if true, checks that the published Helm chart(s) can
+	// be fetched from the chart repository and rendered once all publish
+	// actions have completed.
+	VerifyHelmChart bool
+
+	// VerifyHelmChartRepo is the URL of the chart repository (or "oci://"
+	// registry reference) that published charts are fetched from when
+	// verifying them. If empty, the publish job picks wherever it pushed the
+	// charts to.
+	VerifyHelmChartRepo string
+
+	// CompareToPrevious is the name of a previously staged release which the
+	// release being published is compared against, to catch accidental
+	// regressions such as missing components or architectures.
+	// If empty, no comparison is made.
+	CompareToPrevious string
+
+	// CompareChartsTo is the name of another staged build of the same version
+	// and git ref as the release being published. If set, publishing is
+	// refused unless the Helm charts in both builds are identical.
+	CompareChartsTo string
+
+	// CompareChartsToReleaseType is the type of the staged build named by
+	// CompareChartsTo - usually one of 'release' or 'devel'
+	CompareChartsToReleaseType string
+
+	// AllowMissingProvenance, if true, allows a release built by Bazel to be
+	// published without provenance, in which case its images aren't attested.
+	AllowMissingProvenance bool
+
+	// UploadDigests, if true, uploads the digests of the published images and
+	// manifest lists to the root of the staged release in the bucket.
+	UploadDigests bool
+
+	// DigestsOutput is an optional path in the publish job's workspace to
+	// write the digests of the published images and manifest lists to.
+	DigestsOutput string
+
 	// SkipSigning, if true, will skip trying to sign artifacts using KMS
 	SkipSigning bool
 
@@ -104,40 +207,114 @@ type publishOptions struct {
 	// projects/<PROJECT_NAME>/locations/<LOCATION>/keyRings/<KEYRING_NAME>/cryptoKeys/<KEY_NAME>/versions/<KEY_VERSION>
 	// This must be set if SkipSigning is not set to true
 	SigningKMSKey string
+
+	// SigningMode is how container images and manifest lists are signed,
+	// either "kms" or "keyless".
+	SigningMode string
+
+	// KeylessServiceAccount, if set, is the GCP service account impersonated
+	// to get an OIDC identity token when SigningMode is "keyless".
+	KeylessServiceAccount string
+
+	// ExpectedBuildDuration is how long the build is expected to take. If
+	// the build is still running after this long, a warning is logged.
+	// Zero disables the warning.
+	ExpectedBuildDuration time.Duration
+
+	// Wait, if true, causes the command to wait for the GCB job to complete.
+	// If false, the command exits once the job has been submitted.
+	Wait bool
+
+	notifyOptions
 }
 
 func (o *publishOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
 	fs.StringVar(&o.Bucket, "bucket", release.DefaultBucketName, "The name of the GCS bucket to publish the release to.")
 	fs.StringVar(&o.ReleaseName, "release-name", "", "Name of the staged release to publish.")
-	fs.StringVar(&o.CloudBuildFile, "cloudbuild", "./gcb/publish/cloudbuild.yaml", "The path to the cloudbuild.yaml file used to publish the release. "+
-		"The default value assumes that this tool is run from the root of the release repository.")
+	fs.StringVar(&o.MetadataFileName, "metadata-file-name", release.MetadataFileName, "The name of the file containing the release metadata in the root of the staged release.")
+	fs.StringVar(&o.CloudBuildFile, "cloudbuild", "", "Optional path to a cloudbuild.yaml file to use instead of the one built into cmrel. Only intended for testing changes to the build during development.")
 	fs.StringVar(&o.Project, "project", release.DefaultReleaseProject, "The GCP project to run the GCB build jobs in.")
 	fs.BoolVar(&o.NoMock, "nomock", false, "Whether to actually publish the release. If false, the command will exit after preparing the release for pushing.")
 	fs.StringVar(&o.PublishedImageRepository, "published-image-repo", release.DefaultImageRepository, "The docker image repository to push the release images & manifest lists to.")
+	fs.StringSliceVar(&o.PublishedImageMirrorRepositories, "published-image-mirror-repos", nil, "Comma-separated list of additional docker image repositories to push the release images & manifest lists to, alongside --published-image-repo. Publishing only fails if an image can't be pushed to any repository.")
 	fs.StringVar(&o.PublishedHelmChartGitHubOwner, "published-helm-chart-github-owner", release.DefaultHelmChartGitHubOwner, "The name of the owner of the GitHub repo for Helm charts.")
 	fs.StringVar(&o.PublishedHelmChartGitHubRepo, "published-helm-chart-github-repo", release.DefaultHelmChartGitHubRepo, "The name of the GitHub repo for Helm charts.")
 	fs.StringVar(&o.PublishedHelmChartGitHubBranch, "published-helm-chart-github-branch", release.DefaultHelmChartGitHubBranch, "The name of the main branch in the GitHub repository for Helm charts.")
+	fs.StringVar(&o.PublishedHelmChartOCIRegistry, "published-helm-chart-oci-registry", release.DefaultHelmChartOCIRegistry, "The OCI registry, prefixed with 'oci://', to push Helm charts to in the 'helmchartoci' publish action.")
+	fs.StringVar(&o.PublishedHelmChartRepoBucket, "published-helm-chart-repo-bucket", "", "The GCS bucket backing a static Helm chart repository, to upload Helm charts to and add them to the index.yaml of in the 'helmchartrepo' publish action. If empty, the action does nothing.")
+	fs.StringVar(&o.PublishedHelmChartRepoURL, "published-helm-chart-repo-url", release.DefaultHelmChartRepositoryURL, "The URL the Helm chart repository in --published-helm-chart-repo-bucket is served from, used for the chart URLs in its index.")
 	fs.StringVar(&o.PublishedGitHubOrg, "published-github-org", release.DefaultGitHubOrg, "The org of the repository where the release wil be published to.")
 	fs.StringVar(&o.PublishedGitHubRepo, "published-github-repo", release.DefaultGitHubRepo, "The repo name in the provided org where the release will be published to.")
+	fs.StringVar(&o.PublishedCmctlGitHubOrg, "published-cmctl-github-org", release.DefaultGitHubOrg, "The org of the repository where cmctl binaries in the release will be published to by the cmctlgithubrelease action.")
+	fs.StringVar(&o.PublishedCmctlGitHubRepo, "published-cmctl-github-repo", release.DefaultCmctlGitHubRepo, "The repo name in the provided org where cmctl binaries in the release will be published to by the cmctlgithubrelease action.")
+	fs.BoolVar(&o.SkipReleaseNotes, "skip-release-notes", false, "Create the draft GitHub release with a placeholder body, rather than generating release notes from the PRs merged since the previous release.")
+	fs.StringVar(&o.PreviousReleaseTag, "previous-release-tag", "", "The tag to generate release notes from. If empty, the highest semver tag before the release version is used.")
+	fs.StringVar(&o.Prerelease, "prerelease", releaseFlagAuto, "Whether to mark the GitHub release as a prerelease: true, false or auto to mark alpha, beta and rc versions.")
+	fs.StringVar(&o.Latest, "latest", releaseFlagAuto, "Whether the GitHub release should become the latest release when it's published: true, false or auto to mark it as latest unless it's a prerelease or a higher version has already been tagged, such as when patching an older release branch.")
+	fs.StringVar(&o.SBOMFormat, "sbom-format", sbom.FormatSPDXJSON, fmt.Sprintf("Format of the SBOMs to generate for each container image and ctl binary, which are attached to the pushed images and uploaded to the GitHub release. If empty, no SBOMs are generated. Options: %s", strings.Join(sbom.Formats(), ", ")))
+	fs.BoolVar(&o.VerifyHelmChart, "verify-helm-chart", false, "Whether to check that the published Helm chart(s) can be fetched from --verify-helm-chart-repo and rendered using 'helm template' after publishing.")
+	fs.StringVar(&o.VerifyHelmChartRepo, "verify-helm-chart-repo", "", fmt.Sprintf("The chart repository URL (or 'oci://' registry reference) to fetch published Helm charts from when verifying them. If empty, defaults to --published-helm-chart-oci-registry if the 'helmchartoci' action runs, else %q. Charts only appear in %q once the Helm chart PR is merged, so verifying them there must be done after the merge.", release.DefaultHelmChartRepositoryURL, release.DefaultHelmChartRepositoryURL))
+	fs.StringVar(&o.CompareToPrevious, "compare-to-previous", "", "Name of a previously staged release to compare the release against before publishing. Publishing is refused if the version does not increase, or if components or architectures present in the previous release are missing.")
+	fs.StringVar(&o.CompareChartsTo, "compare-charts-to", "", "Name of another staged build of the same version and git ref. Publishing is refused unless its Helm charts are byte-for-byte identical to those in the release, which verifies that chart packaging is reproducible.")
+	fs.StringVar(&o.CompareChartsToReleaseType, "compare-charts-to-release-type", release.BuildTypeDevel, "The type of the staged build named by --compare-charts-to, usually one of 'release' or 'devel'")
+	fs.BoolVar(&o.AllowMissingProvenance, "allow-missing-provenance", false, fmt.Sprintf("Allow publishing a release built by Bazel which was staged without %q, in which case its images aren't attested. Releases built by make never have provenance.", release.ProvenanceFileName))
+	fs.BoolVar(&o.UploadDigests, "upload-digests", false, fmt.Sprintf("Upload the digests of the published images and manifest lists to %q in the staged release.", release.PublishedDigestsFileName))
+	fs.StringVar(&o.DigestsOutput, "digests-output", "", "Optional path in the publish job's workspace to write the digests of the published images and manifest lists to, as JSON, after publishing.")
 	fs.StringVar(&o.SigningKMSKey, "signing-kms-key", defaultKMSKey, "Full name of the GCP KMS key to use for signing.")
 	fs.BoolVar(&o.SkipSigning, "skip-signing", false, "Skip signing container images.")
-	fs.StringSliceVar(&o.PublishActions, "publish-actions", []string{"*"}, fmt.Sprintf("Comma-separated list of actions to take, or '*' to do everything. Only meaningful if nomock is set. Order of operations is preserved if given, or is alphabetical by default. Actions can be removed with a prefix of '-'. Options: %s", strings.Join(allPublishActionNames(), ", ")))
+	fs.StringVar(&o.SigningMode, "signing-mode", signingModeKMS, fmt.Sprintf("How to sign container images and manifest lists. Options: %s to sign with --signing-kms-key, or %s to use cosign keyless signing with the build's OIDC identity, uploading signatures to the Rekor transparency log. Other artifacts are always signed with --signing-kms-key.", signingModeKMS, signingModeKeyless))
+	fs.StringVar(&o.KeylessServiceAccount, "keyless-service-account", "", "Optional email of a GCP service account for the build to impersonate to get an OIDC identity token when --signing-mode=keyless.")
+	fs.StringSliceVar(&o.PublishActions, "publish-actions", []string{"*"}, fmt.Sprintf("Comma-separated list of actions to take, or '*' to do everything. Only meaningful if nomock is set. Actions run after any actions they depend on, such as githubrelease after pushcontainerimages, and otherwise in alphabetical order. Actions can be removed with a prefix of '-'. Options: %s", strings.Join(allPublishActionNames(), ", ")))
+	fs.StringVar(&o.ResumeFromAction, "resume-from-action", "", "If set, skip all publish actions which would run before the named action. Used to resume a publish which previously failed part way through.")
+	fs.BoolVar(&o.IgnorePublishState, "ignore-publish-state", false, fmt.Sprintf("Ignore the progress recorded in %q in the staged release by previous attempts to publish it, and run every publish action again.", release.PublishStateFileName))
+	fs.DurationVar(&o.ExpectedBuildDuration, "expected-build-duration", gcb.DefaultExpectedBuildDuration, "How long the build is expected to take. A warning is logged if the build is still running after this long. Set to 0 to disable the warning.")
+	fs.BoolVar(&o.Wait, "wait", true, fmt.Sprintf("Whether to wait for the GCB build job to complete. If false, the command exits once the job has been submitted, and '%s %s' can be used to wait for it later.", rootCommand, waitCommand))
+	o.notifyOptions.AddFlags(fs, markRequired)
 }
 
 func (o *publishOptions) print() {
-	log.Printf("Publish options:")
-	log.Printf("  Bucket: %q", o.Bucket)
-	log.Printf("  ReleaseName: %q", o.ReleaseName)
-	log.Printf("  CloudBuildFile: %q", o.CloudBuildFile)
-	log.Printf("  Project: %q", o.Project)
-	log.Printf("  NoMock: %t", o.NoMock)
-	log.Printf("  PublishedImageRepo: %q", o.PublishedImageRepository)
-	log.Printf("  PublishedHelmChartGitHubRepo: %q", o.PublishedHelmChartGitHubRepo)
-	log.Printf("  PublishedHelmChartGitHubOwner: %q", o.PublishedHelmChartGitHubOwner)
-	log.Printf("  PublishedHelmChartGitHubBranch: %q", o.PublishedHelmChartGitHubBranch)
-	log.Printf("  PublishedGitHubOrg: %q", o.PublishedGitHubOrg)
-	log.Printf("  PublishedGitHubRepo: %q", o.PublishedGitHubRepo)
-	log.Printf("  PublishActions: %q", strings.Join(o.PublishActions, ","))
+	slog.Info("Publish options", append([]any{
+		"Bucket", o.Bucket,
+		"ReleaseName", o.ReleaseName,
+		"MetadataFileName", o.MetadataFileName,
+		"CloudBuildFile", o.CloudBuildFile,
+		"Project", o.Project,
+		"NoMock", o.NoMock,
+		"PublishedImageRepo", o.PublishedImageRepository,
+		"PublishedImageMirrorRepos", o.PublishedImageMirrorRepositories,
+		"PublishedHelmChartGitHubRepo", o.PublishedHelmChartGitHubRepo,
+		"PublishedHelmChartGitHubOwner", o.PublishedHelmChartGitHubOwner,
+		"PublishedHelmChartGitHubBranch", o.PublishedHelmChartGitHubBranch,
+		"PublishedHelmChartOCIRegistry", o.PublishedHelmChartOCIRegistry,
+		"PublishedHelmChartRepoBucket", o.PublishedHelmChartRepoBucket,
+		"PublishedHelmChartRepoURL", o.PublishedHelmChartRepoURL,
+		"PublishedGitHubOrg", o.PublishedGitHubOrg,
+		"PublishedGitHubRepo", o.PublishedGitHubRepo,
+		"PublishedCmctlGitHubOrg", o.PublishedCmctlGitHubOrg,
+		"PublishedCmctlGitHubRepo", o.PublishedCmctlGitHubRepo,
+		"PublishActions", o.PublishActions,
+		"ResumeFromAction", o.ResumeFromAction,
+		"IgnorePublishState", o.IgnorePublishState,
+		"SkipReleaseNotes", o.SkipReleaseNotes,
+		"PreviousReleaseTag", o.PreviousReleaseTag,
+		"Prerelease", o.Prerelease,
+		"Latest", o.Latest,
+		"SBOMFormat", o.SBOMFormat,
+		"VerifyHelmChart", o.VerifyHelmChart,
+		"VerifyHelmChartRepo", o.VerifyHelmChartRepo,
+		"CompareToPrevious", o.CompareToPrevious,
+		"CompareChartsTo", o.CompareChartsTo,
+		"CompareChartsToReleaseType", o.CompareChartsToReleaseType,
+		"AllowMissingProvenance", o.AllowMissingProvenance,
+		"UploadDigests", o.UploadDigests,
+		"DigestsOutput", o.DigestsOutput,
+		"SkipSigning", o.SkipSigning,
+		"SigningKMSKey", o.SigningKMSKey,
+		"SigningMode", o.SigningMode,
+		"KeylessServiceAccount", o.KeylessServiceAccount,
+		"ExpectedBuildDuration", o.ExpectedBuildDuration,
+		"Wait", o.Wait,
+	}, o.notifyOptions.attrs()...)...)
 }
 
 func publishCmd(rootOpts *rootOptions) *cobra.Command {
@@ -150,7 +327,7 @@ func publishCmd(rootOpts *rootOptions) *cobra.Command {
 		SilenceUsage: true,
 		PreRun: func(cmd *cobra.Command, args []string) {
 			o.print()
-			log.Printf("---")
+			slog.Info("---")
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runPublish(rootOpts, o)
@@ -163,7 +340,7 @@ func publishCmd(rootOpts *rootOptions) *cobra.Command {
 func runPublish(rootOpts *rootOptions, o *publishOptions) error {
 	ctx := context.Background()
 
-	gcs, err := storage.NewClient(ctx)
+	gcs, err := gcp.NewStorageClient(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to create GCS client: %w", err)
 	}
@@ -174,65 +351,149 @@ func runPublish(rootOpts *rootOptions, o *publishOptions) error {
 		}
 	}
 
-	bucket := release.NewBucket(gcs.Bucket(o.Bucket), release.DefaultBucketPathPrefix, release.BuildTypeRelease)
+	if o.SigningMode != signingModeKMS && o.SigningMode != signingModeKeyless {
+		return fmt.Errorf("unknown signing mode %q; must be one of %q or %q", o.SigningMode, signingModeKMS, signingModeKeyless)
+	}
+
+	if _, err := parseReleaseFlag("prerelease", o.Prerelease); err != nil {
+		return err
+	}
+
+	if _, err := parseReleaseFlag("latest", o.Latest); err != nil {
+		return err
+	}
+
+	if o.SBOMFormat != "" {
+		if err := sbom.ValidateFormat(o.SBOMFormat); err != nil {
+			return err
+		}
+	}
+
+	if err := o.notifyOptions.validate(o.Wait); err != nil {
+		return err
+	}
+
+	bucket := release.NewBucket(gcs.Bucket(o.Bucket), release.DefaultBucketPathPrefix, release.BuildTypeRelease).WithMetadataFileName(o.MetadataFileName)
+	if err := bucket.CheckAccess(ctx); err != nil {
+		return err
+	}
+
 	rel, err := bucket.GetRelease(ctx, o.ReleaseName)
 	if err != nil {
 		return fmt.Errorf("failed to fetch release: %w", err)
 	}
-	log.Printf("Release with version %q (%s) will be published", rel.Metadata().ReleaseVersion, rel.Metadata().GitCommitRef)
+	slog.Info("Release will be published", "version", rel.Metadata().ReleaseVersion, "git_ref", rel.Metadata().GitCommitRef)
 
-	log.Printf("DEBUG: Loading cloudbuild.yaml file from %q", o.CloudBuildFile)
-	build, err := gcb.LoadBuild(o.CloudBuildFile)
+	build, err := gcb.LoadTemplate(gcb.TemplatePublish, o.CloudBuildFile)
 	if err != nil {
 		return fmt.Errorf("error loading cloudbuild.yaml file: %w", err)
 	}
 
 	// make sure that publish-actions is valid
-	_, err = canonicalizeAndVerifyPublishActions(o.PublishActions)
+	actionNames, err := canonicalizeAndVerifyPublishActions(o.PublishActions)
 	if err != nil {
 		return fmt.Errorf("invalid publish-actions: %w", err)
 	}
 
+	// make sure that resume-from-action is valid and leaves something to do
+	actionNames, err = resumePublishActions(actionNames, o.ResumeFromAction)
+	if err != nil {
+		return fmt.Errorf("invalid resume-from-action: %w", err)
+	}
+
+	if len(actionNames) == 0 {
+		return fmt.Errorf("no publish actions remain after resuming from %q; nothing to do", o.ResumeFromAction)
+	}
+
 	build.Substitutions["_RELEASE_NAME"] = o.ReleaseName
+	build.Substitutions["_METADATA_FILE_NAME"] = o.MetadataFileName
 	build.Substitutions["_RELEASE_BUCKET"] = o.Bucket
 	build.Substitutions["_NO_MOCK"] = fmt.Sprintf("%t", o.NoMock)
 	build.Substitutions["_PUBLISHED_GITHUB_ORG"] = o.PublishedGitHubOrg
 	build.Substitutions["_PUBLISHED_GITHUB_REPO"] = o.PublishedGitHubRepo
+	build.Substitutions["_PUBLISHED_CMCTL_GITHUB_ORG"] = o.PublishedCmctlGitHubOrg
+	build.Substitutions["_PUBLISHED_CMCTL_GITHUB_REPO"] = o.PublishedCmctlGitHubRepo
 	build.Substitutions["_PUBLISHED_HELM_CHART_GITHUB_OWNER"] = o.PublishedHelmChartGitHubOwner
 	build.Substitutions["_PUBLISHED_HELM_CHART_GITHUB_REPO"] = o.PublishedHelmChartGitHubRepo
 	build.Substitutions["_PUBLISHED_HELM_CHART_GITHUB_BRANCH"] = o.PublishedHelmChartGitHubBranch
+	build.Substitutions["_PUBLISHED_HELM_CHART_OCI_REGISTRY"] = o.PublishedHelmChartOCIRegistry
+	build.Substitutions["_PUBLISHED_HELM_CHART_REPO_BUCKET"] = o.PublishedHelmChartRepoBucket
+	build.Substitutions["_PUBLISHED_HELM_CHART_REPO_URL"] = o.PublishedHelmChartRepoURL
 	build.Substitutions["_PUBLISHED_IMAGE_REPO"] = o.PublishedImageRepository
+	build.Substitutions["_PUBLISHED_IMAGE_MIRROR_REPOS"] = strings.Join(o.PublishedImageMirrorRepositories, ",")
 	build.Substitutions["_PUBLISH_ACTIONS"] = strings.Join(o.PublishActions, ",")
+	build.Substitutions["_RESUME_FROM_ACTION"] = o.ResumeFromAction
+	build.Substitutions["_IGNORE_PUBLISH_STATE"] = fmt.Sprintf("%t", o.IgnorePublishState)
+	build.Substitutions["_SKIP_RELEASE_NOTES"] = fmt.Sprintf("%t", o.SkipReleaseNotes)
+	build.Substitutions["_PREVIOUS_RELEASE_TAG"] = o.PreviousReleaseTag
+	build.Substitutions["_PRERELEASE"] = o.Prerelease
+	build.Substitutions["_LATEST"] = o.Latest
+	build.Substitutions["_SBOM_FORMAT"] = o.SBOMFormat
+	build.Substitutions["_VERIFY_HELM_CHART"] = fmt.Sprintf("%t", o.VerifyHelmChart)
+	build.Substitutions["_VERIFY_HELM_CHART_REPO"] = o.VerifyHelmChartRepo
+	build.Substitutions["_COMPARE_TO_PREVIOUS"] = o.CompareToPrevious
+	build.Substitutions["_COMPARE_CHARTS_TO"] = o.CompareChartsTo
+	build.Substitutions["_COMPARE_CHARTS_TO_RELEASE_TYPE"] = o.CompareChartsToReleaseType
+	build.Substitutions["_ALLOW_MISSING_PROVENANCE"] = fmt.Sprintf("%t", o.AllowMissingProvenance)
+	build.Substitutions["_UPLOAD_DIGESTS"] = fmt.Sprintf("%t", o.UploadDigests)
+	build.Substitutions["_DIGESTS_OUTPUT"] = o.DigestsOutput
 	build.Substitutions["_SKIP_SIGNING"] = fmt.Sprintf("%v", o.SkipSigning)
 	build.Substitutions["_KMS_KEY"] = o.SigningKMSKey
+	build.Substitutions["_SIGNING_MODE"] = o.SigningMode
+	build.Substitutions["_KEYLESS_SERVICE_ACCOUNT"] = o.KeylessServiceAccount
 
-	log.Printf("DEBUG: building google cloud build API client")
-	svc, err := cloudbuild.NewService(ctx)
+	slog.Debug("building google cloud build API client")
+	svc, err := gcp.NewCloudBuildService(ctx)
 	if err != nil {
 		return fmt.Errorf("error building google cloud build API client: %w", err)
 	}
 
-	log.Printf("Submitting GCB build job...")
+	slog.Info("Submitting GCB build job...")
 	build, err = gcb.SubmitBuild(svc, o.Project, build)
 	if err != nil {
 		return fmt.Errorf("error submitting build to cloud build: %w", err)
 	}
 
-	log.Println("---")
-	log.Printf("Submitted publish job with name: %q", build.Id)
-	log.Printf("  View logs at: %s", build.LogUrl)
-	log.Printf("  Log bucket: %s", build.LogsBucket)
-	log.Println("---")
-	log.Printf("Waiting for publish job to complete, this may take a while...")
-	build, err = gcb.WaitForBuild(svc, o.Project, build.Id)
+	slog.Info("---")
+	slog.Info("Submitted publish job", "build_id", build.Id, "log_url", build.LogUrl, "logs_bucket", build.LogsBucket)
+	slog.Info("---")
+
+	if !o.Wait {
+		logNotWaiting(o.Project, build)
+		return nil
+	}
+
+	event := notify.Event{
+		Job:            publishCommand,
+		ReleaseVersion: rel.Metadata().ReleaseVersion,
+		ReleaseName:    rel.Name(),
+		LogURL:         build.LogUrl,
+	}
+
+	slog.Info("Waiting for publish job to complete, this may take a while...")
+	build, err = gcb.WaitForBuild(svc, o.Project, build.Id, o.ExpectedBuildDuration)
 	if err != nil {
+		o.notifyOptions.notify(ctx, event)
 		return fmt.Errorf("error waiting for cloud build to complete: %w", err)
 	}
 
+	event.Succeeded = build.Status == gcb.Success
+	if event.Succeeded {
+		// manual actions are recorded in the publish state by the publish job
+		state, err := bucket.ReadPublishState(ctx, rel.Name())
+		if err != nil {
+			slog.Warn("failed to read manual actions from publish state", "error", err)
+		} else {
+			event.ManualActions = state.ManualActions
+		}
+	}
+
+	o.notifyOptions.notify(ctx, event)
+
 	if build.Status == gcb.Success {
-		log.Printf("Release %q published!", rel.Metadata().ReleaseVersion)
+		slog.Info("Release published!", "version", rel.Metadata().ReleaseVersion)
 	} else {
-		log.Printf("An error occurred while publishing the release. Check the log files for more information: %s", build.LogUrl)
+		slog.Error("An error occurred while publishing the release. Check the log files for more information", "log_url", build.LogUrl)
 		return fmt.Errorf("publishing release failed")
 	}
 
@@ -0,0 +1,463 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"text/tabwriter"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-github/v35/github"
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/cert-manager/release/pkg/gcp"
+	"github.com/cert-manager/release/pkg/release"
+)
+
+const (
+	publishStatusCommand         = "publish-status"
+	publishStatusDescription     = "Report which parts of a release have been published"
+	publishStatusLongDescription = `
+The 'publish-status' command reports what has been published for a release
+version, which is useful for working out what's left to do after a publish
+fails part way through. It queries:
+
+- the published image repository for every image and manifest list of the
+  release, and for the cosign signature of each
+- the GitHub releases API for a draft or published release with the version's
+  tag, and for each asset the release should have
+- the Helm charts GitHub repository for the PR adding the release's charts
+
+The expected images and assets are read from the staged release which was
+published. The status of each item is printed as a table on stdout.
+
+The command exits with code 0 if everything has been published, with code 2 if
+anything is missing, unsigned, still a draft or waiting for its PR to be
+merged, and with code 1 if the status of anything couldn't be determined.
+
+If a GITHUB_TOKEN environment variable is set, it is used to query GitHub. A
+token with push access to the release repository is needed to see draft
+releases.
+`
+)
+
+var (
+	publishStatusExample = fmt.Sprintf(`
+To report what has been published for v1.14.0:
+
+	%s %s --release-version=v1.14.0
+`, rootCommand, publishStatusCommand)
+)
+
+// Statuses reported for each item of a release by publish-status.
+const (
+	publishStatusPublished = "published"
+	publishStatusMissing   = "missing"
+	publishStatusUnsigned  = "unsigned"
+	publishStatusDraft     = "draft"
+	publishStatusOpen      = "open"
+	publishStatusMerged    = "merged"
+	publishStatusClosed    = "closed"
+	publishStatusError     = "error"
+)
+
+type publishStatusOptions struct {
+	// The name of the GCS bucket containing the staged release.
+	Bucket string
+
+	// ReleaseVersion is the version of the release to report on.
+	ReleaseVersion string
+
+	// ReleaseName is the name of the staged release which was published. If
+	// empty, the only staged release with ReleaseVersion is used.
+	ReleaseName string
+
+	// PublishedImageRepository is the image repository the release images
+	// are pushed to.
+	PublishedImageRepository string
+
+	// PublishedGitHubOrg and PublishedGitHubRepo identify the GitHub
+	// repository the release is created in.
+	PublishedGitHubOrg  string
+	PublishedGitHubRepo string
+
+	// PublishedHelmChartGitHubOwner and PublishedHelmChartGitHubRepo identify
+	// the GitHub repository Helm chart PRs are opened against.
+	PublishedHelmChartGitHubOwner string
+	PublishedHelmChartGitHubRepo  string
+
+	// SkipHelmChartPR, if true, doesn't report on the Helm chart PR, for
+	// releases whose charts aren't published by PR.
+	SkipHelmChartPR bool
+}
+
+func (o *publishStatusOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
+	fs.StringVar(&o.Bucket, "bucket", release.DefaultBucketName, "The name of the GCS bucket containing the staged release.")
+	fs.StringVar(&o.ReleaseVersion, "release-version", "", "The version of the release to report on, e.g. v1.14.0.")
+	fs.StringVar(&o.ReleaseName, "release-name", "", "Name of the staged release which was published. Defaults to the only staged release with the given version.")
+	fs.StringVar(&o.PublishedImageRepository, "published-image-repo", release.DefaultImageRepository, "The docker image repository the release images & manifest lists are pushed to.")
+	fs.StringVar(&o.PublishedGitHubOrg, "published-github-org", release.DefaultGitHubOrg, "The org of the repository the release is published to.")
+	fs.StringVar(&o.PublishedGitHubRepo, "published-github-repo", release.DefaultGitHubRepo, "The repo name in the provided org the release is published to.")
+	fs.StringVar(&o.PublishedHelmChartGitHubOwner, "published-helm-chart-github-owner", release.DefaultHelmChartGitHubOwner, "The name of the owner of the GitHub repo for Helm charts.")
+	fs.StringVar(&o.PublishedHelmChartGitHubRepo, "published-helm-chart-github-repo", release.DefaultHelmChartGitHubRepo, "The name of the GitHub repo for Helm charts.")
+	fs.BoolVar(&o.SkipHelmChartPR, "skip-helm-chart-pr", false, "Don't report on the Helm chart PR, for releases whose charts aren't published by PR.")
+	markRequired("release-version")
+}
+
+func (o *publishStatusOptions) print() {
+	slog.Info("Publish status options",
+		"Bucket", o.Bucket,
+		"ReleaseVersion", o.ReleaseVersion,
+		"ReleaseName", o.ReleaseName,
+		"PublishedImageRepository", o.PublishedImageRepository,
+		"PublishedGitHubOrg", o.PublishedGitHubOrg,
+		"PublishedGitHubRepo", o.PublishedGitHubRepo,
+		"PublishedHelmChartGitHubOwner", o.PublishedHelmChartGitHubOwner,
+		"PublishedHelmChartGitHubRepo", o.PublishedHelmChartGitHubRepo,
+		"SkipHelmChartPR", o.SkipHelmChartPR,
+	)
+}
+
+func publishStatusCmd(rootOpts *rootOptions) *cobra.Command {
+	o := &publishStatusOptions{}
+	cmd := &cobra.Command{
+		Use:          publishStatusCommand,
+		Short:        publishStatusDescription,
+		Long:         publishStatusLongDescription,
+		Example:      publishStatusExample,
+		SilenceUsage: true,
+		PreRun: func(_ *cobra.Command, _ []string) {
+			o.print()
+			slog.Info("---")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPublishStatus(rootOpts, o)
+		},
+	}
+	o.AddFlags(cmd.Flags(), mustMarkRequired(cmd.MarkFlagRequired))
+	return cmd
+}
+
+// publishStatusEntry is the status of a single published item of a release.
+type publishStatusEntry struct {
+	// Kind is the kind of item, such as "image" or "github-asset".
+	Kind string
+
+	// Name identifies the item, such as an image reference or asset name.
+	Name string
+
+	// Status is one of the publishStatus constants.
+	Status string
+
+	// Detail optionally explains the status, such as the error which
+	// prevented it from being determined.
+	Detail string
+}
+
+// complete returns true if nothing is left to do to publish the item.
+func (e publishStatusEntry) complete() bool {
+	return e.Status == publishStatusPublished || e.Status == publishStatusMerged
+}
+
+// gitHubReleaseFinder returns the draft or published GitHub release with the
+// given tag, or nil if there is no such release.
+type gitHubReleaseFinder func(ctx context.Context, org, repo, tag string) (*github.RepositoryRelease, error)
+
+// pullRequestLister returns every open or closed PR in the given repository
+// whose head branch is head, in the form "owner:branch".
+type pullRequestLister func(ctx context.Context, owner, repo, head string) ([]*github.PullRequest, error)
+
+// publishStatusQueriers are the queries used to determine publish status.
+type publishStatusQueriers struct {
+	resolve      digestResolver
+	signed       signatureChecker
+	findRelease  gitHubReleaseFinder
+	pullRequests pullRequestLister
+}
+
+func runPublishStatus(_ *rootOptions, o *publishStatusOptions) error {
+	ctx := context.Background()
+
+	gcs, err := gcp.NewStorageClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create GCS client: %w", err)
+	}
+
+	bucket := release.NewBucket(gcs.Bucket(o.Bucket), release.DefaultBucketPathPrefix, release.BuildTypeRelease)
+	if err := bucket.CheckAccess(ctx); err != nil {
+		return err
+	}
+
+	staged, err := stagedReleaseForVersion(ctx, bucket, o.ReleaseVersion, o.ReleaseName)
+	if err != nil {
+		return err
+	}
+
+	rel, err := release.Unpack(ctx, staged)
+	if err != nil {
+		return fmt.Errorf("failed to unpack staged release: %w", err)
+	}
+
+	client := newGitHubClient(ctx)
+
+	q := publishStatusQueriers{
+		resolve:      remoteDigest,
+		signed:       remoteSignatureExists,
+		findRelease:  gitHubReleaseFinderForClient(client),
+		pullRequests: pullRequestListerForClient(client),
+	}
+
+	entries := imagePublishStatus(ctx, q, o.PublishedImageRepository, rel)
+	entries = append(entries, gitHubReleasePublishStatus(ctx, q, o.PublishedGitHubOrg, o.PublishedGitHubRepo, rel)...)
+
+	if o.SkipHelmChartPR {
+		slog.Info("Skipping the Helm chart PR as skip-helm-chart-pr is set")
+	} else {
+		entries = append(entries, helmChartPRPublishStatus(ctx, q, o.PublishedHelmChartGitHubOwner, o.PublishedHelmChartGitHubRepo, rel))
+	}
+
+	if err := printPublishStatus(os.Stdout, entries); err != nil {
+		return err
+	}
+
+	return publishStatusResult(o.ReleaseVersion, entries)
+}
+
+// imagePublishStatus reports whether each image and manifest list of rel has
+// been pushed to repo, and whether it has been signed.
+func imagePublishStatus(ctx context.Context, q publishStatusQueriers, repo string, rel *release.Unpacked) []publishStatusEntry {
+	var entries []publishStatusEntry
+
+	for _, ref := range publishedImageRefs(repo, rel) {
+		entry := publishStatusEntry{Kind: "image", Name: ref}
+
+		digest, err := q.resolve(ctx, ref)
+		switch {
+		case isNotFoundError(err):
+			entry.Status = publishStatusMissing
+		case err != nil:
+			entry.Status = publishStatusError
+			entry.Detail = err.Error()
+		default:
+			parsed, err := name.ParseReference(ref)
+			if err != nil {
+				entry.Status = publishStatusError
+				entry.Detail = err.Error()
+				break
+			}
+
+			signed, err := q.signed(ctx, parsed.Context().Name(), digest)
+			switch {
+			case err != nil:
+				entry.Status = publishStatusError
+				entry.Detail = err.Error()
+			case !signed:
+				entry.Status = publishStatusUnsigned
+				entry.Detail = digest
+			default:
+				entry.Status = publishStatusPublished
+				entry.Detail = digest
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// gitHubReleasePublishStatus reports whether the GitHub release for rel has
+// been created and published, and whether each of its assets was uploaded.
+func gitHubReleasePublishStatus(ctx context.Context, q publishStatusQueriers, org, repo string, rel *release.Unpacked) []publishStatusEntry {
+	releaseEntry := publishStatusEntry{Kind: "github-release", Name: fmt.Sprintf("%s/%s@%s", org, repo, rel.ReleaseVersion)}
+
+	githubRelease, err := q.findRelease(ctx, org, repo, rel.ReleaseVersion)
+	switch {
+	case err != nil:
+		releaseEntry.Status = publishStatusError
+		releaseEntry.Detail = err.Error()
+	case githubRelease == nil:
+		releaseEntry.Status = publishStatusMissing
+	case githubRelease.GetDraft():
+		releaseEntry.Status = publishStatusDraft
+		releaseEntry.Detail = githubRelease.GetHTMLURL()
+	default:
+		releaseEntry.Status = publishStatusPublished
+		releaseEntry.Detail = githubRelease.GetHTMLURL()
+	}
+
+	entries := []publishStatusEntry{releaseEntry}
+
+	uploaded := sets.NewString()
+	if githubRelease != nil {
+		for _, asset := range githubRelease.Assets {
+			uploaded.Insert(asset.GetName())
+		}
+	}
+
+	for _, assetName := range sets.StringKeySet(gitHubReleaseAssetPaths(rel)).List() {
+		entry := publishStatusEntry{Kind: "github-asset", Name: assetName, Status: publishStatusMissing}
+
+		switch {
+		case err != nil:
+			entry.Status = publishStatusError
+			entry.Detail = "GitHub release couldn't be fetched"
+		case uploaded.Has(assetName):
+			entry.Status = publishStatusPublished
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// helmChartPRPublishStatus reports on the PR adding the Helm charts of rel to
+// the given charts repository, which is opened from a branch named for the
+// staged release.
+func helmChartPRPublishStatus(ctx context.Context, q publishStatusQueriers, owner, repo string, rel *release.Unpacked) publishStatusEntry {
+	entry := publishStatusEntry{Kind: "helm-chart-pr", Name: fmt.Sprintf("%s/%s:%s", owner, repo, rel.ReleaseName), Status: publishStatusMissing}
+
+	prs, err := q.pullRequests(ctx, owner, repo, owner+":"+rel.ReleaseName)
+	if err != nil {
+		entry.Status = publishStatusError
+		entry.Detail = err.Error()
+		return entry
+	}
+
+	// a PR may have been closed and replaced after a failed publish, so the
+	// most advanced of the PRs is reported
+	rank := map[string]int{publishStatusMissing: 0, publishStatusClosed: 1, publishStatusOpen: 2, publishStatusMerged: 3}
+	for _, pr := range prs {
+		status := publishStatusClosed
+		switch {
+		case pr.GetMerged() || pr.MergedAt != nil:
+			status = publishStatusMerged
+		case pr.GetState() == "open":
+			status = publishStatusOpen
+		}
+
+		if rank[status] > rank[entry.Status] {
+			entry.Status = status
+			entry.Detail = pr.GetHTMLURL()
+		}
+	}
+
+	return entry
+}
+
+// printPublishStatus writes entries to w as a table.
+func printPublishStatus(w io.Writer, entries []publishStatusEntry) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "KIND\tNAME\tSTATUS\tDETAIL")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", e.Kind, e.Name, e.Status, e.Detail)
+	}
+
+	return tw.Flush()
+}
+
+// publishStatusResult returns nil if every entry is complete. Otherwise, a
+// generic error is returned if the status of any entry couldn't be
+// determined, and release.ErrValidationFailed is returned if anything is
+// left to publish.
+func publishStatusResult(version string, entries []publishStatusEntry) error {
+	var errored, incomplete int
+	for _, e := range entries {
+		switch {
+		case e.Status == publishStatusError:
+			errored++
+		case !e.complete():
+			incomplete++
+		}
+	}
+
+	if errored > 0 {
+		return fmt.Errorf("failed to determine the publish status of %d item(s) of release %q", errored, version)
+	}
+
+	if incomplete > 0 {
+		return fmt.Errorf("%d item(s) of release %q have not been published: %w", incomplete, version, release.ErrValidationFailed)
+	}
+
+	slog.Info("Everything has been published for the release", "version", version)
+
+	return nil
+}
+
+// isNotFoundError returns true if err was caused by a registry or the GitHub
+// API responding that the requested object doesn't exist.
+func isNotFoundError(err error) bool {
+	var transportErr *transport.Error
+	if errors.As(err, &transportErr) {
+		return transportErr.StatusCode == http.StatusNotFound
+	}
+
+	var gitHubErr *github.ErrorResponse
+	if errors.As(err, &gitHubErr) {
+		return gitHubErr.Response != nil && gitHubErr.Response.StatusCode == http.StatusNotFound
+	}
+
+	return false
+}
+
+// gitHubReleaseFinderForClient finds releases by listing them, since draft
+// releases can't be fetched by tag.
+func gitHubReleaseFinderForClient(client *github.Client) gitHubReleaseFinder {
+	return func(ctx context.Context, org, repo, tag string) (*github.RepositoryRelease, error) {
+		opts := &github.ListOptions{PerPage: 100}
+		for {
+			releases, resp, err := client.Repositories.ListReleases(ctx, org, repo, opts)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list GitHub releases in %s/%s: %w", org, repo, err)
+			}
+
+			for _, r := range releases {
+				if r.GetTagName() == tag {
+					return r, nil
+				}
+			}
+
+			if resp.NextPage == 0 {
+				return nil, nil
+			}
+
+			opts.Page = resp.NextPage
+		}
+	}
+}
+
+func pullRequestListerForClient(client *github.Client) pullRequestLister {
+	return func(ctx context.Context, owner, repo, head string) ([]*github.PullRequest, error) {
+		prs, _, err := client.PullRequests.List(ctx, owner, repo, &github.PullRequestListOptions{State: "all", Head: head})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list PRs in %s/%s: %w", owner, repo, err)
+		}
+
+		return prs, nil
+	}
+}
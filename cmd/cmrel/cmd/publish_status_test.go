@@ -0,0 +1,282 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-github/v35/github"
+
+	"github.com/cert-manager/release/pkg/release"
+	"github.com/cert-manager/release/pkg/release/images"
+	"github.com/cert-manager/release/pkg/release/manifests"
+)
+
+func TestImagePublishStatus(t *testing.T) {
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rawTag, err := name.NewTag("quay.io/jetstack/cert-manager-controller-amd64:v1.15.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "image.tar")
+	if err := tarball.WriteToFile(path, rawTag, img); err != nil {
+		t.Fatal(err)
+	}
+
+	controller, err := images.NewTar(path, "linux", "amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rel := &release.Unpacked{
+		ReleaseVersion: "v1.15.0",
+		ComponentImageBundles: map[string][]*images.Tar{
+			"controller": {controller},
+		},
+	}
+
+	const (
+		imageRef        = "quay.io/jetstack/cert-manager-controller-amd64:v1.15.0"
+		manifestListRef = "quay.io/jetstack/cert-manager-controller:v1.15.0"
+	)
+
+	tests := map[string]struct {
+		digests    map[string]string
+		signed     map[string]bool
+		resolveErr error
+
+		expected map[string]string
+	}{
+		"everything pushed and signed": {
+			digests:  map[string]string{imageRef: "sha256:aaaa", manifestListRef: "sha256:bbbb"},
+			signed:   map[string]bool{"sha256:aaaa": true, "sha256:bbbb": true},
+			expected: map[string]string{imageRef: publishStatusPublished, manifestListRef: publishStatusPublished},
+		},
+		"manifest list not pushed": {
+			digests:  map[string]string{imageRef: "sha256:aaaa"},
+			signed:   map[string]bool{"sha256:aaaa": true},
+			expected: map[string]string{imageRef: publishStatusPublished, manifestListRef: publishStatusMissing},
+		},
+		"manifest list not signed": {
+			digests:  map[string]string{imageRef: "sha256:aaaa", manifestListRef: "sha256:bbbb"},
+			signed:   map[string]bool{"sha256:aaaa": true},
+			expected: map[string]string{imageRef: publishStatusPublished, manifestListRef: publishStatusUnsigned},
+		},
+		"registry error": {
+			resolveErr: errors.New("connection refused"),
+			expected:   map[string]string{imageRef: publishStatusError, manifestListRef: publishStatusError},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			q := publishStatusQueriers{
+				resolve: func(ctx context.Context, ref string) (string, error) {
+					if test.resolveErr != nil {
+						return "", test.resolveErr
+					}
+
+					digest, ok := test.digests[ref]
+					if !ok {
+						return "", fmt.Errorf("failed to resolve digest of %q: %w", ref, &transport.Error{StatusCode: http.StatusNotFound})
+					}
+
+					return digest, nil
+				},
+				signed: func(ctx context.Context, repo string, digest string) (bool, error) {
+					return test.signed[digest], nil
+				},
+			}
+
+			statuses := map[string]string{}
+			for _, e := range imagePublishStatus(context.TODO(), q, "quay.io/jetstack", rel) {
+				statuses[e.Name] = e.Status
+			}
+
+			if !reflect.DeepEqual(statuses, test.expected) {
+				t.Errorf("wanted %v but got %v", test.expected, statuses)
+			}
+		})
+	}
+}
+
+func TestGitHubReleasePublishStatus(t *testing.T) {
+	rel := &release.Unpacked{
+		ReleaseVersion: "v1.15.0",
+		YAMLs:          []manifests.YAML{*manifests.NewYAML("/tmp/cert-manager.yaml"), *manifests.NewYAML("/tmp/cert-manager.crds.yaml")},
+	}
+
+	tests := map[string]struct {
+		githubRelease *github.RepositoryRelease
+		findErr       error
+
+		expected map[string]string
+	}{
+		"published release with all assets": {
+			githubRelease: &github.RepositoryRelease{
+				Assets: []*github.ReleaseAsset{{Name: github.String("cert-manager.yaml")}, {Name: github.String("cert-manager.crds.yaml")}},
+			},
+			expected: map[string]string{"v1.15.0": publishStatusPublished, "cert-manager.yaml": publishStatusPublished, "cert-manager.crds.yaml": publishStatusPublished},
+		},
+		"draft release missing an asset": {
+			githubRelease: &github.RepositoryRelease{
+				Draft:  github.Bool(true),
+				Assets: []*github.ReleaseAsset{{Name: github.String("cert-manager.yaml")}},
+			},
+			expected: map[string]string{"v1.15.0": publishStatusDraft, "cert-manager.yaml": publishStatusPublished, "cert-manager.crds.yaml": publishStatusMissing},
+		},
+		"no release": {
+			expected: map[string]string{"v1.15.0": publishStatusMissing, "cert-manager.yaml": publishStatusMissing, "cert-manager.crds.yaml": publishStatusMissing},
+		},
+		"GitHub error": {
+			findErr:  errors.New("rate limited"),
+			expected: map[string]string{"v1.15.0": publishStatusError, "cert-manager.yaml": publishStatusError, "cert-manager.crds.yaml": publishStatusError},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			q := publishStatusQueriers{
+				findRelease: func(ctx context.Context, org, repo, tag string) (*github.RepositoryRelease, error) {
+					return test.githubRelease, test.findErr
+				},
+			}
+
+			statuses := map[string]string{}
+			for _, e := range gitHubReleasePublishStatus(context.TODO(), q, "cert-manager", "cert-manager", rel) {
+				key := e.Name
+				if e.Kind == "github-release" {
+					key = rel.ReleaseVersion
+				}
+
+				statuses[key] = e.Status
+			}
+
+			if !reflect.DeepEqual(statuses, test.expected) {
+				t.Errorf("wanted %v but got %v", test.expected, statuses)
+			}
+		})
+	}
+}
+
+func TestHelmChartPRPublishStatus(t *testing.T) {
+	merged := time.Now()
+
+	tests := map[string]struct {
+		prs []*github.PullRequest
+
+		expectedStatus string
+	}{
+		"no PR": {
+			expectedStatus: publishStatusMissing,
+		},
+		"open PR": {
+			prs:            []*github.PullRequest{{State: github.String("open")}},
+			expectedStatus: publishStatusOpen,
+		},
+		"merged PR": {
+			prs:            []*github.PullRequest{{State: github.String("closed"), MergedAt: &merged}},
+			expectedStatus: publishStatusMerged,
+		},
+		"closed PR replaced by an open PR": {
+			prs:            []*github.PullRequest{{State: github.String("open")}, {State: github.String("closed")}},
+			expectedStatus: publishStatusOpen,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var requestedHead string
+			q := publishStatusQueriers{
+				pullRequests: func(ctx context.Context, owner, repo, head string) ([]*github.PullRequest, error) {
+					requestedHead = head
+					return test.prs, nil
+				},
+			}
+
+			entry := helmChartPRPublishStatus(context.TODO(), q, "cert-manager", "jetstack-charts", &release.Unpacked{ReleaseName: "v1.15.0-abcdef"})
+			if entry.Status != test.expectedStatus {
+				t.Errorf("expected status %q but got %q", test.expectedStatus, entry.Status)
+			}
+
+			if requestedHead != "cert-manager:v1.15.0-abcdef" {
+				t.Errorf("unexpected PR head %q", requestedHead)
+			}
+		})
+	}
+}
+
+func TestPublishStatusResult(t *testing.T) {
+	tests := map[string]struct {
+		statuses []string
+
+		expectedExitCode int
+	}{
+		"everything published": {
+			statuses:         []string{publishStatusPublished, publishStatusMerged},
+			expectedExitCode: 0,
+		},
+		"something missing": {
+			statuses:         []string{publishStatusPublished, publishStatusMissing},
+			expectedExitCode: exitCodeValidation,
+		},
+		"PR still open": {
+			statuses:         []string{publishStatusPublished, publishStatusOpen},
+			expectedExitCode: exitCodeValidation,
+		},
+		"status unknown": {
+			statuses:         []string{publishStatusMissing, publishStatusError},
+			expectedExitCode: exitCodeGeneric,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var entries []publishStatusEntry
+			for _, status := range test.statuses {
+				entries = append(entries, publishStatusEntry{Status: status})
+			}
+
+			err := publishStatusResult("v1.15.0", entries)
+
+			exitCode := 0
+			if err != nil {
+				exitCode = exitCodeForError(err)
+			}
+
+			if exitCode != test.expectedExitCode {
+				t.Errorf("expected exit code %d but got %d (err=%v)", test.expectedExitCode, exitCode, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+
+	"github.com/cert-manager/release/pkg/release"
+)
+
+const (
+	repairMetadataCommand         = "repair-metadata"
+	repairMetadataDescription     = "Recompute the checksums in a staged release's metadata"
+	repairMetadataLongDescription = `
+The 'repair-metadata' command downloads each artifact in a staged release,
+recomputes its SHA256 sum and size, and rewrites the release metadata to match.
+The version, git ref, OS and architecture of each artifact are preserved.
+
+This can be used to recover a staged release which can no longer be published
+because artifacts were re-uploaded without updating the release metadata, or
+vice versa.
+
+By default, the changes which would be made are only printed. Pass --confirm to
+rewrite the metadata. Releases of type 'release' are never modified unless
+--allow-release is also set.
+
+Unless --skip-signing is set, the rewritten metadata is signed with the KMS key
+given by --signing-kms-key so that the release can still be published.
+`
+)
+
+type repairMetadataOptions struct {
+	metadataRewriteOptions
+
+	// Confirm must be true for the release metadata to actually be rewritten.
+	Confirm bool
+
+	// AllowRelease must be true for the metadata of a staged release of type
+	// 'release' to be rewritten.
+	AllowRelease bool
+}
+
+func (o *repairMetadataOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
+	o.metadataRewriteOptions.AddFlags(fs, markRequired, "repair")
+	fs.BoolVar(&o.Confirm, "confirm", false, "Rewrite the release metadata. If false, the changes which would be made are only printed.")
+	fs.BoolVar(&o.AllowRelease, "allow-release", false, "Allow rewriting the metadata of a staged release of type 'release'.")
+}
+
+func (o *repairMetadataOptions) print() {
+	slog.Info("Repair metadata options", append(o.metadataRewriteOptions.attrs(),
+		"Confirm", o.Confirm,
+		"AllowRelease", o.AllowRelease,
+	)...)
+}
+
+func repairMetadataCmd(rootOpts *rootOptions) *cobra.Command {
+	o := &repairMetadataOptions{}
+	cmd := &cobra.Command{
+		Use:          repairMetadataCommand,
+		Short:        repairMetadataDescription,
+		Long:         repairMetadataLongDescription,
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			o.print()
+			slog.Info("---")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRepairMetadata(rootOpts, o)
+		},
+	}
+	o.AddFlags(cmd.Flags(), mustMarkRequired(cmd.MarkFlagRequired))
+	return cmd
+}
+
+func runRepairMetadata(rootOpts *rootOptions, o *repairMetadataOptions) error {
+	if o.ReleaseType == release.BuildTypeRelease && !o.AllowRelease {
+		return fmt.Errorf("refusing to repair a staged release of type %q without --allow-release", release.BuildTypeRelease)
+	}
+
+	ctx := context.Background()
+
+	bucket, staged, err := o.fetchRelease(ctx)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Recomputing artifact checksums", "count", len(staged.Metadata().Artifacts), "release", staged.Name())
+
+	meta, changes, err := release.RecomputeArtifactMetadata(ctx, staged)
+	if err != nil {
+		return err
+	}
+
+	if len(changes) == 0 {
+		slog.Info("Release metadata already matches the staged artifacts, nothing to do")
+		return nil
+	}
+
+	logMetadataChanges(changes)
+
+	if !o.Confirm {
+		slog.Info("--confirm not set, not rewriting release metadata")
+		return nil
+	}
+
+	if err := bucket.WriteMetadata(ctx, staged.Name(), *meta); err != nil {
+		return err
+	}
+
+	slog.Info("Rewrote release metadata", "release", staged.Name())
+
+	return nil
+}
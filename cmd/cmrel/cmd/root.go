@@ -18,40 +18,76 @@ package cmd
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 	flag "github.com/spf13/pflag"
+
+	"github.com/cert-manager/release/pkg/gcp"
+	"github.com/cert-manager/release/pkg/logging"
 )
 
 const (
 	rootCommand         = "cmrel"
 	rootDescription     = "cert-manager release management tool"
-	rootDescriptionLong = `Use to prepare, build and publish cert-manager release artifacts.`
+	rootDescriptionLong = `Use to prepare, build and publish cert-manager release artifacts.
+
+All commands exit with one of the following codes:
+
+  0  success
+  1  generic failure
+  2  validation failed, e.g. a release failed validation and was not published
+  3  not found, e.g. the named staged release does not exist
+  4  transient failure, e.g. a timeout or server error; the command can be retried`
 )
 
 type rootOptions struct {
-	// Debug configures whether output from subcommands should be directly
-	// piped to stderr of the process.
+	// Debug configures whether debug logs are written, and whether output
+	// from subcommands should be directly piped to stderr of the process.
 	Debug bool
+
+	// LogFormat is the format logs are written in, one of logging.Formats.
+	LogFormat string
+
+	// GCPCredentialsFile is an optional path to a service account key or
+	// workload identity federation configuration used to authenticate to
+	// GCS, Cloud Build and KMS instead of Application Default Credentials.
+	GCPCredentialsFile string
 }
 
 func (o *rootOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
-	fs.BoolVar(&o.Debug, "debug", false, "If true, output from sub-commands will be directly piped to stderr.")
+	fs.BoolVar(&o.Debug, "debug", false, "If true, debug logs will be written and output from sub-commands will be directly piped to stderr. "+
+		"Otherwise, output from sub-commands is only shown if they fail.")
+	fs.StringVar(&o.LogFormat, "log-format", logging.FormatText, fmt.Sprintf("The format to write logs in. Options: %s", strings.Join(logging.Formats, ", ")))
+	fs.StringVar(&o.GCPCredentialsFile, "gcp-credentials-file", "", "Path to a GCP credentials file used for GCS, Cloud Build and KMS, containing either a service account key or a workload identity federation configuration, such as one created by 'gcloud iam workload-identity-pools create-cred-config' for GitHub Actions OIDC tokens. "+
+		"If not set, Application Default Credentials are used.")
 }
 
 func (o *rootOptions) print() {
-	log.Printf("Root options:")
-	log.Printf("  Debug: %t", o.Debug)
+	slog.Info("Root options",
+		"Debug", o.Debug,
+		"LogFormat", o.LogFormat,
+		"GCPCredentialsFile", o.GCPCredentialsFile,
+	)
 }
 
 func rootCmd(o *rootOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   rootCommand,
 		Short: rootDescription,
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := logging.Configure(os.Stderr, o.LogFormat, o.Debug); err != nil {
+				return err
+			}
+
+			if err := gcp.Configure(cmd.Context(), o.GCPCredentialsFile); err != nil {
+				return err
+			}
+
 			o.print()
+			return nil
 		},
 		Long: rootDescriptionLong,
 	}
@@ -73,6 +109,10 @@ func mustMarkRequired(markRequired func(string) error) func(string) {
 }
 
 func Execute() {
+	// Run the root command's PersistentPreRunE, which configures logging,
+	// even for subcommands which have their own persistent pre-run hooks.
+	cobra.EnableTraverseRunHooks = true
+
 	o := &rootOptions{}
 
 	cmd := rootCmd(o)
@@ -85,9 +125,20 @@ func Execute() {
 	cmd.AddCommand(bootstrapPGPCmd(o))
 	cmd.AddCommand(signCmd(o))
 	cmd.AddCommand(validateGoModCmd(o))
+	cmd.AddCommand(validateLicensesCmd(o))
+	cmd.AddCommand(repairMetadataCmd(o))
+	cmd.AddCommand(inspectManifestListCmd(o))
+	cmd.AddCommand(migrateMetadataCmd(o))
+	cmd.AddCommand(platformsCmd(o))
+	cmd.AddCommand(verifyCmd(o))
+	cmd.AddCommand(publishStatusCmd(o))
+	cmd.AddCommand(promoteCmd(o))
+	cmd.AddCommand(waitCmd(o))
+	cmd.AddCommand(artifactsCmd(o))
+	cmd.AddCommand(cutBranchCmd(o))
 
 	if err := cmd.Execute(); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(exitCodeForError(err))
 	}
 }
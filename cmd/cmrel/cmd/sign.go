@@ -54,7 +54,9 @@ func signCmd(o *rootOptions) *cobra.Command {
 	o.AddFlags(cmd.PersistentFlags(), mustMarkRequired(cmd.MarkPersistentFlagRequired))
 
 	cmd.AddCommand(signHelmCmd(o))
+	cmd.AddCommand(signImagesCmd(o))
 	cmd.AddCommand(signManifestsCmd(o))
+	cmd.AddCommand(signVerifyCmd(o))
 
 	return cmd
 }
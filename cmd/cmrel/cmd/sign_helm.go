@@ -19,7 +19,7 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 
@@ -65,9 +65,10 @@ func (o *signHelmOptions) AddFlags(fs *flag.FlagSet, markRequired func(string))
 }
 
 func (o *signHelmOptions) print() {
-	log.Printf("sign helm options:")
-	log.Printf("        Key: %q", o.Key)
-	log.Printf("  ChartPath: %q", o.ChartPath)
+	slog.Info("sign helm options",
+		"Key", o.Key,
+		"ChartPath", o.ChartPath,
+	)
 }
 
 func signHelmCmd(rootOpts *rootOptions) *cobra.Command {
@@ -80,7 +81,7 @@ func signHelmCmd(rootOpts *rootOptions) *cobra.Command {
 		SilenceUsage: true,
 		PreRun: func(cmd *cobra.Command, args []string) {
 			o.print()
-			log.Printf("---")
+			slog.Info("---")
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runSignHelm(rootOpts, o)
@@ -112,7 +113,7 @@ func runSignHelm(rootOpts *rootOptions, o *signHelmOptions) error {
 		return fmt.Errorf("failed to write %q: %w", provFile, err)
 	}
 
-	log.Printf("wrote signature successfully to %q", provFile)
+	slog.Info("wrote signature successfully", "path", provFile)
 
 	return nil
 }
@@ -0,0 +1,240 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+
+	"github.com/cert-manager/release/pkg/release"
+	"github.com/cert-manager/release/pkg/sign"
+	"github.com/cert-manager/release/pkg/sign/cosign"
+)
+
+const (
+	signImagesCommand         = "images"
+	signImagesDescription     = "Sign the published container images of a release which are missing a cosign signature"
+	signImagesLongDescription = `The images command signs the container images and manifest lists of an
+already-published release using cosign and a GCP KMS key.
+
+Every image and manifest list expected for the release version is checked to
+exist in the registry first; if any are missing, nothing is signed. Images and
+manifest lists which already have a cosign signature are skipped.
+
+This is intended for recovering from a publish run which pushed images but
+failed while signing them, without having to run the publish again.`
+)
+
+var signImagesExample = fmt.Sprintf(`To sign any unsigned images of v1.15.0:
+
+%s %s %s --release-version v1.15.0`, rootCommand, signCommand, signImagesCommand)
+
+type signImagesOptions struct {
+	// ReleaseVersion is the version of the published release whose images
+	// should be signed, e.g. v1.15.0
+	ReleaseVersion string
+
+	// PublishedImageRepository is the docker repository the release's images
+	// and manifest lists were pushed to
+	PublishedImageRepository string
+
+	// Key is the full name of the GCP KMS key to be used, e.g.
+	// projects/<PROJECT_NAME>/locations/<LOCATION>/keyRings/<KEYRING_NAME>/cryptoKeys/<KEY_NAME>/cryptoKeyVersions/<KEY_VERSION>
+	Key string
+
+	// CosignPath points to the location of the cosign binary
+	CosignPath string
+}
+
+func (o *signImagesOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
+	fs.StringVar(&o.ReleaseVersion, "release-version", "", "Version of the published release whose images should be signed, e.g. v1.15.0")
+	fs.StringVar(&o.PublishedImageRepository, "published-image-repo", release.DefaultImageRepository, "The docker image repository the release images & manifest lists were pushed to.")
+	fs.StringVar(&o.Key, "key", defaultKMSKey, "Full name of the GCP KMS key to use for signing")
+	fs.StringVar(&o.CosignPath, "cosign-path", "cosign", "Full path to the cosign binary. Defaults to searching in $PATH for a binary called 'cosign'")
+	markRequired("release-version")
+}
+
+func (o *signImagesOptions) print() {
+	slog.Info("sign images options",
+		"ReleaseVersion", o.ReleaseVersion,
+		"PublishedImageRepository", o.PublishedImageRepository,
+		"Key", o.Key,
+		"CosignPath", o.CosignPath,
+	)
+}
+
+func signImagesCmd(rootOpts *rootOptions) *cobra.Command {
+	o := &signImagesOptions{}
+	cmd := &cobra.Command{
+		Use:          signImagesCommand,
+		Short:        signImagesDescription,
+		Long:         signImagesLongDescription,
+		Example:      signImagesExample,
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			o.print()
+			slog.Info("---")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSignImages(rootOpts, o)
+		},
+	}
+
+	o.AddFlags(cmd.Flags(), mustMarkRequired(cmd.MarkFlagRequired))
+
+	return cmd
+}
+
+func runSignImages(rootOpts *rootOptions, o *signImagesOptions) error {
+	ctx := context.Background()
+
+	parsedKey, err := sign.NewGCPKMSKey(o.Key)
+	if err != nil {
+		return err
+	}
+
+	refs := expectedImageRefs(o.PublishedImageRepository, o.ReleaseVersion)
+
+	slog.Info("Checking images and manifest lists for release", "count", len(refs), "version", o.ReleaseVersion)
+	unsigned, err := findUnsignedImages(ctx, remoteDigest, remoteSignatureExists, refs)
+	if err != nil {
+		return err
+	}
+
+	if len(unsigned) == 0 {
+		slog.Info("All images and manifest lists for the release are already signed", "version", o.ReleaseVersion)
+		return nil
+	}
+
+	for _, ref := range unsigned {
+		slog.Info("Signing image", "ref", ref)
+		if err := cosign.Sign(ctx, o.CosignPath, []string{ref}, parsedKey); err != nil {
+			return fmt.Errorf("failed to sign container image / manifest list %q: %w", ref, err)
+		}
+	}
+
+	slog.Info("Finished signing", "refs", unsigned)
+
+	return nil
+}
+
+// expectedImageRefs returns the tag of every per-architecture image and
+// manifest list which is published to repo for the given release version.
+func expectedImageRefs(repo, releaseVersion string) []string {
+	var refs []string
+	for _, component := range release.ImageComponentNames() {
+		// the ctl image is shipped alongside the cmctl binaries
+		if component == "ctl" && !release.CmctlIsShipped(releaseVersion) {
+			continue
+		}
+
+		for _, arch := range release.ServerPlatforms["linux"] {
+			refs = append(refs, buildImageTag(repo, component, arch, releaseVersion))
+		}
+
+		refs = append(refs, buildManifestListName(repo, component, releaseVersion))
+	}
+
+	return refs
+}
+
+// signatureChecker returns true if the image or manifest list with the given
+// digest in repo has a cosign signature.
+type signatureChecker func(ctx context.Context, repo string, digest string) (bool, error)
+
+// remoteSignatureExists checks for a cosign signature by looking for the tag
+// that cosign pushes signatures to, using credentials from the default
+// keychain.
+func remoteSignatureExists(ctx context.Context, repo string, digest string) (bool, error) {
+	ref := repo + ":" + strings.Replace(digest, ":", "-", 1) + ".sig"
+
+	parsed, err := name.ParseReference(ref)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse signature reference %q: %w", ref, err)
+	}
+
+	_, err = remote.Head(parsed, remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+
+	var transportErr *transport.Error
+	if errors.As(err, &transportErr) && transportErr.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("failed to check for signature %q: %w", ref, err)
+	}
+
+	return true, nil
+}
+
+// findUnsignedImages resolves each of refs and returns the digest references
+// of those without a signature. An error is returned without checking any
+// signatures if any of refs can't be resolved, since that means the release
+// wasn't fully pushed.
+func findUnsignedImages(ctx context.Context, resolve digestResolver, signed signatureChecker, refs []string) ([]string, error) {
+	digests := make([]string, len(refs))
+
+	var errs []error
+	for i, ref := range refs {
+		digest, err := resolve(ctx, ref)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		digests[i] = digest
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("not all images and manifest lists for the release exist in the registry: %w", errors.Join(errs...))
+	}
+
+	var unsigned []string
+	for i, ref := range refs {
+		parsed, err := name.ParseReference(ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse image reference %q: %w", ref, err)
+		}
+
+		repo := parsed.Context().Name()
+
+		ok, err := signed(ctx, repo, digests[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			slog.Info("Skipping image as it's already signed", "ref", ref)
+			continue
+		}
+
+		unsigned = append(unsigned, repo+"@"+digests[i])
+	}
+
+	return unsigned, nil
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+)
+
+func TestExpectedImageRefs(t *testing.T) {
+	tests := map[string]struct {
+		version     string
+		expectCtl   bool
+		expectCount int
+	}{
+		"release shipping ctl": {
+			version:     "v1.14.5",
+			expectCtl:   true,
+			expectCount: 6 * 6,
+		},
+		"release without ctl": {
+			version:     "v1.15.0",
+			expectCtl:   false,
+			expectCount: 5 * 6,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			refs := expectedImageRefs("quay.io/jetstack", test.version)
+
+			if len(refs) != test.expectCount {
+				t.Fatalf("expected %d refs but got %d: %v", test.expectCount, len(refs), refs)
+			}
+
+			hasCtl := false
+			for _, ref := range refs {
+				if !strings.HasSuffix(ref, ":"+test.version) {
+					t.Errorf("ref %q isn't tagged with the release version", ref)
+				}
+
+				if strings.HasPrefix(ref, "quay.io/jetstack/cert-manager-ctl") {
+					hasCtl = true
+				}
+			}
+
+			if hasCtl != test.expectCtl {
+				t.Errorf("expectCtl=%v but got refs %v", test.expectCtl, refs)
+			}
+		})
+	}
+}
+
+func TestFindUnsignedImages(t *testing.T) {
+	tests := map[string]struct {
+		pushed    []string
+		signed    []string
+		expected  []string
+		expectErr bool
+	}{
+		"nothing signed": {
+			pushed:   []string{"cert-manager-controller:v1.15.0", "cert-manager-webhook:v1.15.0"},
+			expected: []string{"cert-manager-controller:v1.15.0", "cert-manager-webhook:v1.15.0"},
+		},
+		"some signed": {
+			pushed:   []string{"cert-manager-controller:v1.15.0", "cert-manager-webhook:v1.15.0"},
+			signed:   []string{"cert-manager-controller:v1.15.0"},
+			expected: []string{"cert-manager-webhook:v1.15.0"},
+		},
+		"all signed": {
+			pushed: []string{"cert-manager-controller:v1.15.0", "cert-manager-webhook:v1.15.0"},
+			signed: []string{"cert-manager-controller:v1.15.0", "cert-manager-webhook:v1.15.0"},
+		},
+		"image missing": {
+			pushed:    []string{"cert-manager-controller:v1.15.0"},
+			expectErr: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			ctx := context.Background()
+
+			server := httptest.NewServer(registry.New())
+			t.Cleanup(server.Close)
+
+			repo := strings.TrimPrefix(server.URL, "http://") + "/jetstack/"
+
+			digests := map[string]string{}
+			for _, ref := range test.pushed {
+				digests[ref] = pushRandomImage(t, repo+ref)
+			}
+
+			for _, ref := range test.signed {
+				imageRepo, _, _ := strings.Cut(ref, ":")
+				pushRandomImage(t, repo+imageRepo+":"+strings.Replace(digests[ref], ":", "-", 1)+".sig")
+			}
+
+			refs := []string{repo + "cert-manager-controller:v1.15.0", repo + "cert-manager-webhook:v1.15.0"}
+
+			unsigned, err := findUnsignedImages(ctx, remoteDigest, remoteSignatureExists, refs)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			var expected []string
+			for _, ref := range test.expected {
+				imageRepo, _, _ := strings.Cut(ref, ":")
+				expected = append(expected, repo+imageRepo+"@"+digests[ref])
+			}
+
+			if !reflect.DeepEqual(unsigned, expected) {
+				t.Errorf("expected unsigned images %v but got %v", expected, unsigned)
+			}
+		})
+	}
+}
+
+// pushRandomImage pushes a random image to the given tag, returning its
+// digest.
+func pushRandomImage(t *testing.T, tag string) string {
+	t.Helper()
+
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pushFixture(t, tag, img).String()
+}
@@ -19,7 +19,7 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/spf13/cobra"
 	flag "github.com/spf13/pflag"
@@ -30,8 +30,9 @@ import (
 const (
 	signManifestsCommand         = "manifests"
 	signManifestsDescription     = "Manually sign helm charts in a cert-manager-manifests.tar.gz artifact in-place, using a GCP KMS key"
-	signManifestsLongDescription = `The manifests command signs a helm chart inside a
-cert-manager-manifests.tar.gz artifact, using KMS rather than a local PGP key.
+	signManifestsLongDescription = `The manifests command signs every helm chart (each file with a ".tgz"
+extension) inside a cert-manager-manifests.tar.gz artifact, using KMS rather
+than a local PGP key.
 
 Mostly, this command is provided in case of an issue with the signing process
 elsewhere. cmrel should attempt to create signatures as part of the normal
@@ -41,7 +42,8 @@ Using this shim rather than "helm package --sign" allows us to use exactly one
 signing key for all kinds of cert-manager artifact, with no member of the team
 having access to the actual private key.
 
-The cert-manager-manifests.tar.gz file has the signature appended to it, in-place.`
+The cert-manager-manifests.tar.gz file has a signature for each chart appended
+to it, in-place.`
 )
 
 var signManifestsExample = fmt.Sprintf(`To sign a manifests bundle at "/tmp/cert-manager-manifests.tar.gz:
@@ -73,11 +75,11 @@ func (o *signManifestsOptions) AddFlags(fs *flag.FlagSet, markRequired func(stri
 }
 
 func (o *signManifestsOptions) print() {
-	log.Printf("sign manifests options:")
-	log.Printf("   Key: %q", o.Key)
-	log.Printf("  Path: %q", o.Path)
-
-	log.Printf("  ReleaseVersion: %q", o.ReleaseVersion)
+	slog.Info("sign manifests options",
+		"Key", o.Key,
+		"Path", o.Path,
+		"ReleaseVersion", o.ReleaseVersion,
+	)
 }
 
 func signManifestsCmd(rootOpts *rootOptions) *cobra.Command {
@@ -90,7 +92,7 @@ func signManifestsCmd(rootOpts *rootOptions) *cobra.Command {
 		SilenceUsage: true,
 		PreRun: func(cmd *cobra.Command, args []string) {
 			o.print()
-			log.Printf("---")
+			slog.Info("---")
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runSignManifests(rootOpts, o)
@@ -115,7 +117,7 @@ func runSignManifests(rootOpts *rootOptions, o *signManifestsOptions) error {
 		return fmt.Errorf("failed to complete signing of %q: %w", o.Path, err)
 	}
 
-	log.Printf("appended signature successfully to %q", o.Path)
+	slog.Info("appended signature successfully", "path", o.Path)
 
 	return nil
 }
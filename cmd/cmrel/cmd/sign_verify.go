@@ -0,0 +1,225 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+
+	"github.com/cert-manager/release/pkg/sign"
+	"github.com/cert-manager/release/pkg/sign/cosign"
+)
+
+const (
+	signVerifyCommand         = "verify"
+	signVerifyDescription     = "Check that signing and verification with a GCP KMS key works end-to-end"
+	signVerifyLongDescription = `The verify command signs a throwaway test payload using a GCP KMS key and
+immediately verifies the signature, both as a PGP signature (as used for Helm
+charts) and as a cosign blob signature (as used for container images).
+
+It's intended as a smoke test to run before a release, to confirm that the
+signing chain works and that the current credentials have permission to use
+the key. Nothing is published.`
+)
+
+var signVerifyExample = fmt.Sprintf(`To check that the default signing key can be used:
+
+%s %s %s
+
+To check a specific key, without cosign:
+
+%s %s %s --key "projects/<PROJECT_NAME>/locations/<LOCATION>/keyRings/<KEYRING_NAME>/cryptoKeys/<KEY_NAME>/cryptoKeyVersions/<KEY_VERSION>" --skip-cosign`, rootCommand, signCommand, signVerifyCommand, rootCommand, signCommand, signVerifyCommand)
+
+type signVerifyOptions struct {
+	// Key is the full name of the GCP KMS key to be used for signing, e.g.
+	// projects/<PROJECT_NAME>/locations/<LOCATION>/keyRings/<KEYRING_NAME>/cryptoKeys/<KEY_NAME>/cryptoKeyVersions/<KEY_VERSION>
+	Key string
+
+	// CosignPath points to the location of the cosign binary
+	CosignPath string
+
+	// SkipCosign, if true, skips the cosign blob signing check
+	SkipCosign bool
+}
+
+func (o *signVerifyOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
+	fs.StringVar(&o.Key, "key", defaultKMSKey, "Full name of the GCP KMS key to check")
+	fs.StringVar(&o.CosignPath, "cosign-path", "cosign", "Full path to the cosign binary. Defaults to searching in $PATH for a binary called 'cosign'")
+	fs.BoolVar(&o.SkipCosign, "skip-cosign", false, "Skip checking cosign blob signing, e.g. if cosign isn't installed.")
+}
+
+func (o *signVerifyOptions) print() {
+	slog.Info("sign verify options",
+		"Key", o.Key,
+		"CosignPath", o.CosignPath,
+		"SkipCosign", o.SkipCosign,
+	)
+}
+
+func signVerifyCmd(rootOpts *rootOptions) *cobra.Command {
+	o := &signVerifyOptions{}
+	cmd := &cobra.Command{
+		Use:          signVerifyCommand,
+		Short:        signVerifyDescription,
+		Long:         signVerifyLongDescription,
+		Example:      signVerifyExample,
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			o.print()
+			slog.Info("---")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSignVerify(rootOpts, o)
+		},
+	}
+
+	o.AddFlags(cmd.Flags(), mustMarkRequired(cmd.MarkFlagRequired))
+
+	return cmd
+}
+
+func runSignVerify(rootOpts *rootOptions, o *signVerifyOptions) error {
+	ctx := context.Background()
+
+	key, err := sign.NewGCPKMSKey(o.Key)
+	if err != nil {
+		return err
+	}
+
+	roundTrips := []signatureRoundTrip{pgpRoundTrip(key)}
+	if o.SkipCosign {
+		slog.Info("Skipping cosign blob signing check as --skip-cosign is set")
+	} else {
+		roundTrips = append(roundTrips, cosignBlobRoundTrip(key, o.CosignPath))
+	}
+
+	payload := []byte(fmt.Sprintf("cert-manager release signing self-test at %s\n", time.Now().UTC().Format(time.RFC3339)))
+
+	for _, rt := range roundTrips {
+		slog.Info("Checking signing", "kind", rt.name, "key", key)
+		if err := runSignatureRoundTrip(ctx, rt, payload); err != nil {
+			return err
+		}
+
+		slog.Info("Signing and verification succeeded", "kind", rt.name)
+	}
+
+	slog.Info("Signing works end-to-end", "key", key)
+	return nil
+}
+
+// signatureRoundTrip signs and verifies payloads with a single kind of
+// signature.
+type signatureRoundTrip struct {
+	// name describes the kind of signature, e.g. "PGP"
+	name string
+
+	// sign returns a signature of the payload
+	sign func(ctx context.Context, payload []byte) ([]byte, error)
+
+	// verify returns an error if signature isn't a valid signature of the
+	// payload
+	verify func(ctx context.Context, payload []byte, signature []byte) error
+}
+
+// runSignatureRoundTrip signs payload and verifies the signature. To check
+// that verification isn't trivially passing, it also checks that the
+// signature is rejected for a tampered copy of the payload.
+func runSignatureRoundTrip(ctx context.Context, rt signatureRoundTrip, payload []byte) error {
+	signature, err := rt.sign(ctx, payload)
+	if err != nil {
+		return fmt.Errorf("failed to create %s signature: %w", rt.name, err)
+	}
+
+	if err := rt.verify(ctx, payload, signature); err != nil {
+		return fmt.Errorf("failed to verify %s signature: %w", rt.name, err)
+	}
+
+	tampered := append(append([]byte{}, payload...), []byte("tampered\n")...)
+
+	slog.Info("Checking that a signature of a tampered payload is rejected; an error is expected", "kind", rt.name)
+	if err := rt.verify(ctx, tampered, signature); err == nil {
+		return fmt.Errorf("%s signature was accepted for a tampered payload; verification is not working", rt.name)
+	}
+
+	return nil
+}
+
+func pgpRoundTrip(key sign.GCPKMSKey) signatureRoundTrip {
+	return signatureRoundTrip{
+		name: "PGP",
+		sign: func(ctx context.Context, payload []byte) ([]byte, error) {
+			return sign.PGPDetachSign(ctx, key, payload)
+		},
+		verify: func(ctx context.Context, payload []byte, signature []byte) error {
+			return sign.PGPVerifyDetached(ctx, key, payload, signature)
+		},
+	}
+}
+
+func cosignBlobRoundTrip(key sign.GCPKMSKey, cosignPath string) signatureRoundTrip {
+	return signatureRoundTrip{
+		name: "cosign blob",
+		sign: func(ctx context.Context, payload []byte) ([]byte, error) {
+			dir, err := os.MkdirTemp("", "cmrel-sign-verify-")
+			if err != nil {
+				return nil, err
+			}
+			defer os.RemoveAll(dir)
+
+			payloadPath := filepath.Join(dir, "payload")
+			signaturePath := filepath.Join(dir, "payload.sig")
+
+			if err := os.WriteFile(payloadPath, payload, 0o644); err != nil {
+				return nil, err
+			}
+
+			if err := cosign.SignBlob(ctx, cosignPath, payloadPath, signaturePath, key); err != nil {
+				return nil, err
+			}
+
+			return os.ReadFile(signaturePath)
+		},
+		verify: func(ctx context.Context, payload []byte, signature []byte) error {
+			dir, err := os.MkdirTemp("", "cmrel-sign-verify-")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(dir)
+
+			payloadPath := filepath.Join(dir, "payload")
+			signaturePath := filepath.Join(dir, "payload.sig")
+
+			if err := os.WriteFile(payloadPath, payload, 0o644); err != nil {
+				return err
+			}
+
+			if err := os.WriteFile(signaturePath, signature, 0o644); err != nil {
+				return err
+			}
+
+			return cosign.VerifyBlob(ctx, cosignPath, payloadPath, signaturePath, key)
+		},
+	}
+}
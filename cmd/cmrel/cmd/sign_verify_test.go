@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+)
+
+func TestRunSignatureRoundTrip(t *testing.T) {
+	// hmacSign is an injected signer which "signs" payloads with an HMAC
+	hmacSign := func(_ context.Context, payload []byte) ([]byte, error) {
+		mac := hmac.New(sha256.New, []byte("test-key"))
+		mac.Write(payload)
+		return mac.Sum(nil), nil
+	}
+
+	hmacVerify := func(ctx context.Context, payload []byte, signature []byte) error {
+		expected, _ := hmacSign(ctx, payload)
+		if !hmac.Equal(expected, signature) {
+			return fmt.Errorf("signature mismatch")
+		}
+		return nil
+	}
+
+	tests := map[string]struct {
+		sign      func(context.Context, []byte) ([]byte, error)
+		verify    func(context.Context, []byte, []byte) error
+		expectErr bool
+	}{
+		"working signer and verifier": {
+			sign:   hmacSign,
+			verify: hmacVerify,
+		},
+		"signer fails, e.g. due to missing permissions": {
+			sign: func(context.Context, []byte) ([]byte, error) {
+				return nil, fmt.Errorf("permission denied")
+			},
+			verify:    hmacVerify,
+			expectErr: true,
+		},
+		"signer produces invalid signatures": {
+			sign: func(context.Context, []byte) ([]byte, error) {
+				return []byte("not a signature"), nil
+			},
+			verify:    hmacVerify,
+			expectErr: true,
+		},
+		"verifier accepts any signature": {
+			sign: hmacSign,
+			verify: func(context.Context, []byte, []byte) error {
+				return nil
+			},
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var signed [][]byte
+
+			rt := signatureRoundTrip{
+				name: "test",
+				sign: func(ctx context.Context, payload []byte) ([]byte, error) {
+					signed = append(signed, payload)
+					return test.sign(ctx, payload)
+				},
+				verify: test.verify,
+			}
+
+			payload := []byte("payload")
+
+			err := runSignatureRoundTrip(context.Background(), rt, payload)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if len(signed) != 1 || !bytes.Equal(signed[0], payload) {
+				t.Errorf("expected the payload to be signed exactly once but got %q", signed)
+			}
+		})
+	}
+}
@@ -18,15 +18,21 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"os"
+	"path"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	flag "github.com/spf13/pflag"
 	"google.golang.org/api/cloudbuild/v1"
 
 	"github.com/cert-manager/release/pkg/gcb"
+	"github.com/cert-manager/release/pkg/gcp"
+	"github.com/cert-manager/release/pkg/notify"
 	"github.com/cert-manager/release/pkg/release"
 	"github.com/cert-manager/release/pkg/sign"
 )
@@ -38,6 +44,15 @@ const (
 Google Cloud Storage bucket. It will create a Google Cloud Build job
 which will run a full cross-build and publish the artifacts to the
 staging release bucket.
+
+If the newest Google Cloud Build job to stage the same release version and git
+ref succeeded, used the same target OSes, target architectures, image
+repository and signing options, and its staged release is still present in the
+bucket, no new job is submitted. Pass --rebuild to force the release to be
+built and staged again.
+
+If --notify-slack-webhook is set, a message including the build log URL is
+posted when the job completes.
 `
 )
 
@@ -69,7 +84,8 @@ type stageOptions struct {
 	// Optional commit ref of cert-manager that should be staged
 	GitRef string
 
-	// The path to the cloudbuild.yaml file used to perform the cert-manager crossbuild
+	// CloudBuildFile, if set, is the path to a cloudbuild.yaml file to use
+	// instead of the one built into cmrel
 	CloudBuildFile string
 
 	// Project is the name of the GCP project to run the GCB job in
@@ -81,6 +97,10 @@ type stageOptions struct {
 	// repository.
 	ReleaseVersion string
 
+	// AllowVersionMismatch, if true, skips checking that ReleaseVersion
+	// matches the version of the source being built.
+	AllowVersionMismatch bool
+
 	// PublishedImageRepository is the docker repository that will be used for
 	// built artifacts.
 	// This must be set at the time a build is staged as parts of the release
@@ -100,6 +120,30 @@ type stageOptions struct {
 
 	// TargetArches is a comma-separated list of architectures which should be built for in this invocation
 	TargetArches string
+
+	// StagedBy identifies the user staging the release, and is recorded in
+	// the release metadata. If empty, it is detected from the environment.
+	StagedBy string
+
+	// MetadataFileName is the name of the file the release metadata is
+	// written to in the root of the staged release.
+	MetadataFileName string
+
+	// ExpectedBuildDuration is how long the build is expected to take. If
+	// the build is still running after this long, a warning is logged.
+	// Zero disables the warning.
+	ExpectedBuildDuration time.Duration
+
+	// Wait, if true, causes the command to wait for the GCB job to complete.
+	// If false, the command exits once the job has been submitted.
+	Wait bool
+
+	// Rebuild, if true, causes a new GCB job to be submitted even if an
+	// existing job has already successfully staged the same release version
+	// and git ref.
+	Rebuild bool
+
+	notifyOptions
 }
 
 func (o *stageOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
@@ -108,10 +152,10 @@ func (o *stageOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
 	fs.StringVar(&o.Repo, "repo", "cert-manager", "Name of the GitHub repo to fetch cert-manager sources from.")
 	fs.StringVar(&o.Branch, "branch", "master", "The git branch to build the release from. If --git-ref is not specified, the HEAD of this branch will be looked up on GitHub.")
 	fs.StringVar(&o.GitRef, "git-ref", "", "The git commit ref of cert-manager that should be staged.")
-	fs.StringVar(&o.CloudBuildFile, "cloudbuild", "./gcb/stage/cloudbuild.yaml", "The path to the cloudbuild.yaml file used to perform the cert-manager crossbuild. "+
-		"The default value assumes that this tool is run from the root of the release repository.")
+	fs.StringVar(&o.CloudBuildFile, "cloudbuild", "", "Optional path to a cloudbuild.yaml file to use instead of the one built into cmrel. Only intended for testing changes to the build during development.")
 	fs.StringVar(&o.Project, "project", release.DefaultReleaseProject, "The GCP project to run the GCB build jobs in.")
 	fs.StringVar(&o.ReleaseVersion, "release-version", "", "Optional release version override used to force the version strings used during the release to a specific value. If not set, build is treated as development build and artifacts staged to 'devel' path.")
+	fs.BoolVar(&o.AllowVersionMismatch, "allow-version-mismatch", false, "Skip checking that --release-version matches the version of the source being built, e.g. to release from a branch whose tags don't follow the usual versioning.")
 	fs.StringVar(&o.PublishedImageRepository, "published-image-repo", release.DefaultImageRepository, "The docker image repository set when building the release.")
 	fs.StringVar(&o.SigningKMSKey, "signing-kms-key", defaultKMSKey, "Full name of the GCP KMS key to use for signing")
 	fs.BoolVar(&o.SkipSigning, "skip-signing", false, "Skip signing release artifacts.")
@@ -123,25 +167,38 @@ func (o *stageOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
 
 	fs.StringVar(&o.TargetOSes, "target-os", "*", fmt.Sprintf("Comma-separated list of OSes to target, or '*' for all. Options: %s", allOSes))
 	fs.StringVar(&o.TargetArches, "target-arch", "*", fmt.Sprintf("Comma-separated list of arches to target, or '*' for all. Options: %s", allArches))
+	fs.StringVar(&o.StagedBy, "staged-by", "", "The user staging the release, recorded in the release metadata. If not set, it is detected from the $BUILD_REQUESTED_BY or $USER environment variables.")
+	fs.StringVar(&o.MetadataFileName, "metadata-file-name", release.MetadataFileName, "The name of the file the release metadata is written to in the root of the staged release.")
+	fs.DurationVar(&o.ExpectedBuildDuration, "expected-build-duration", gcb.DefaultExpectedBuildDuration, "How long the build is expected to take. A warning is logged if the build is still running after this long. Set to 0 to disable the warning.")
+	fs.BoolVar(&o.Wait, "wait", true, fmt.Sprintf("Whether to wait for the GCB build job to complete. If false, the command exits once the job has been submitted, and '%s %s' can be used to wait for it later.", rootCommand, waitCommand))
+	fs.BoolVar(&o.Rebuild, "rebuild", false, "If true, submit a new build even if an existing build has already successfully staged the same release version and git ref.")
+	o.notifyOptions.AddFlags(fs, markRequired)
 
 	markRequired("branch")
 }
 
 func (o *stageOptions) print() {
-	log.Printf("Stage options:")
-	log.Printf("  Bucket: %q", o.Bucket)
-	log.Printf("  Org: %q", o.Org)
-	log.Printf("  Repo: %q", o.Repo)
-	log.Printf("  Branch: %q", o.Branch)
-	log.Printf("  GitRef: %q", o.GitRef)
-	log.Printf("  CloudBuildFile: %q", o.CloudBuildFile)
-	log.Printf("  SkipSigning: %v", o.SkipSigning)
-	log.Printf("  Project: %q", o.Project)
-	log.Printf("  SigningKMSKey: %q", o.SigningKMSKey)
-	log.Printf("  ReleaseVersion: %q", o.ReleaseVersion)
-	log.Printf("  PublishedImageRepo: %q", o.PublishedImageRepository)
-	log.Printf("  TargetOSes: %q", o.TargetOSes)
-	log.Printf("  TargetArches: %q", o.TargetArches)
+	slog.Info("Stage options", append([]any{
+		"Bucket", o.Bucket,
+		"Org", o.Org,
+		"Repo", o.Repo,
+		"Branch", o.Branch,
+		"GitRef", o.GitRef,
+		"CloudBuildFile", o.CloudBuildFile,
+		"SkipSigning", o.SkipSigning,
+		"Project", o.Project,
+		"SigningKMSKey", o.SigningKMSKey,
+		"ReleaseVersion", o.ReleaseVersion,
+		"AllowVersionMismatch", o.AllowVersionMismatch,
+		"PublishedImageRepo", o.PublishedImageRepository,
+		"TargetOSes", o.TargetOSes,
+		"TargetArches", o.TargetArches,
+		"StagedBy", o.StagedBy,
+		"MetadataFileName", o.MetadataFileName,
+		"ExpectedBuildDuration", o.ExpectedBuildDuration,
+		"Wait", o.Wait,
+		"Rebuild", o.Rebuild,
+	}, o.notifyOptions.attrs()...)...)
 }
 
 func stageCmd(rootOpts *rootOptions) *cobra.Command {
@@ -154,7 +211,7 @@ func stageCmd(rootOpts *rootOptions) *cobra.Command {
 		SilenceUsage: true,
 		PreRun: func(cmd *cobra.Command, args []string) {
 			o.print()
-			log.Printf("---")
+			slog.Info("---")
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runStage(rootOpts, o)
@@ -166,7 +223,7 @@ func stageCmd(rootOpts *rootOptions) *cobra.Command {
 
 func runStage(rootOpts *rootOptions, o *stageOptions) error {
 	if o.GitRef == "" {
-		log.Printf("git-ref flag not specified, looking up git commit ref for %s/%s@%s", o.Org, o.Repo, o.Branch)
+		slog.Info("git-ref flag not specified, looking up git commit ref", "repository", o.Org+"/"+o.Repo, "branch", o.Branch)
 		ref, err := release.LookupBranchRef(o.Org, o.Repo, o.Branch)
 		if err != nil {
 			return fmt.Errorf("error looking up git commit ref: %w", err)
@@ -180,10 +237,13 @@ func runStage(rootOpts *rootOptions, o *stageOptions) error {
 		}
 	}
 
-	log.Printf("Staging build for %s/%s@%s", o.Org, o.Repo, o.GitRef)
+	if err := o.notifyOptions.validate(o.Wait); err != nil {
+		return err
+	}
+
+	slog.Info("Staging build", "repository", o.Org+"/"+o.Repo, "git_ref", o.GitRef)
 
-	log.Printf("DEBUG: Loading cloudbuild.yaml file from %q", o.CloudBuildFile)
-	build, err := gcb.LoadBuild(o.CloudBuildFile)
+	build, err := gcb.LoadTemplate(gcb.TemplateStage, o.CloudBuildFile)
 	if err != nil {
 		return fmt.Errorf("error loading cloudbuild.yaml file: %w", err)
 	}
@@ -205,6 +265,7 @@ func runStage(rootOpts *rootOptions, o *stageOptions) error {
 	build.Substitutions["_CM_REPO"] = fmt.Sprintf("https://github.com/%s/%s.git", o.Org, o.Repo)
 	build.Substitutions["_CM_REF"] = o.GitRef
 	build.Substitutions["_RELEASE_VERSION"] = o.ReleaseVersion
+	build.Substitutions["_ALLOW_VERSION_MISMATCH"] = fmt.Sprintf("%t", o.AllowVersionMismatch)
 	build.Substitutions["_RELEASE_BUCKET"] = o.Bucket
 	build.Substitutions["_TAG_RELEASE_BRANCH"] = o.Branch
 	build.Substitutions["_PUBLISHED_IMAGE_REPO"] = o.PublishedImageRepository
@@ -212,6 +273,9 @@ func runStage(rootOpts *rootOptions, o *stageOptions) error {
 	build.Substitutions["_SKIP_SIGNING"] = fmt.Sprintf("%v", o.SkipSigning)
 	build.Substitutions["_TARGET_OSES"] = strings.Join(targetOSes.List(), ",")
 	build.Substitutions["_TARGET_ARCHES"] = strings.Join(targetArches.List(), ",")
+	build.Substitutions["_STAGED_BY"] = stagedBy(o.StagedBy, os.Getenv)
+	build.Substitutions["_METADATA_FILE_NAME"] = o.MetadataFileName
+	build.Tags = append(build.Tags, gcb.TagForReleaseVersion(o.ReleaseVersion, o.GitRef))
 
 	outputDir := ""
 	// If --release-version is not explicitly set, we treat this build as a
@@ -222,37 +286,152 @@ func runStage(rootOpts *rootOptions, o *stageOptions) error {
 		outputDir = release.BucketPathForRelease(release.DefaultBucketPathPrefix, release.BuildTypeRelease, o.ReleaseVersion, o.GitRef)
 	}
 
-	log.Printf("DEBUG: building google cloud build API client")
+	slog.Debug("building google cloud build API client")
 	ctx := context.Background()
-	svc, err := cloudbuild.NewService(ctx)
+	svc, err := gcp.NewCloudBuildService(ctx)
 	if err != nil {
 		return fmt.Errorf("error building google cloud build API client: %w", err)
 	}
 
-	log.Printf("Submitting GCB build job...")
+	if !o.Rebuild {
+		existing, err := findReusableStageBuild(ctx, svc, o, build.Substitutions, outputDir)
+		if err != nil {
+			return fmt.Errorf("error looking for an existing build to reuse (pass --rebuild to skip this check): %w", err)
+		}
+
+		if existing != nil {
+			slog.Info("Found existing successful build, not submitting a new build", "build_id", existing.Id, "repository", o.Org+"/"+o.Repo, "git_ref", o.GitRef, "log_url", existing.LogUrl)
+			slog.Info("Release build already complete - artifacts available", "location", fmt.Sprintf("gs://%s/%s", o.Bucket, outputDir))
+			slog.Info("Pass --rebuild to build and stage the release again")
+			return nil
+		}
+	}
+
+	slog.Info("Submitting GCB build job...")
 	build, err = gcb.SubmitBuild(svc, o.Project, build)
 	if err != nil {
 		return fmt.Errorf("error submitting build to cloud build: %w", err)
 	}
 
-	log.Println("---")
-	log.Printf("Submitted build with name: %q", build.Id)
-	log.Printf("  View logs at: %s", build.LogUrl)
-	log.Printf("  Log bucket: %s", build.LogsBucket)
-	log.Printf("  Once complete, view artifacts at: gs://%s/%s", o.Bucket, outputDir)
-	log.Println("---")
-	log.Printf("Waiting for build to complete, this may take a while...")
-	build, err = gcb.WaitForBuild(svc, o.Project, build.Id)
+	slog.Info("---")
+	slog.Info("Submitted build", "build_id", build.Id, "log_url", build.LogUrl, "logs_bucket", build.LogsBucket, "artifacts_location", fmt.Sprintf("gs://%s/%s", o.Bucket, outputDir))
+	slog.Info("---")
+
+	if !o.Wait {
+		logNotWaiting(o.Project, build)
+		return nil
+	}
+
+	event := notify.Event{
+		Job:            stageCommand,
+		ReleaseVersion: o.ReleaseVersion,
+		LogURL:         build.LogUrl,
+	}
+
+	slog.Info("Waiting for build to complete, this may take a while...")
+	build, err = gcb.WaitForBuild(svc, o.Project, build.Id, o.ExpectedBuildDuration)
 	if err != nil {
+		o.notifyOptions.notify(ctx, event)
 		return fmt.Errorf("error waiting for cloud build to complete: %w", err)
 	}
 
+	event.Succeeded = build.Status == gcb.Success
+	o.notifyOptions.notify(ctx, event)
+
 	if build.Status == gcb.Success {
-		log.Printf("Release build complete - artifacts available at: gs://%s/%s", o.Bucket, outputDir)
+		slog.Info("Release build complete - artifacts available", "location", fmt.Sprintf("gs://%s/%s", o.Bucket, outputDir))
 	} else {
-		log.Printf("An error occurred building the release. Check the log files for more information: %s", build.LogUrl)
+		slog.Error("An error occurred building the release. Check the log files for more information", "log_url", build.LogUrl)
 		return fmt.Errorf("building release tarballs failed")
 	}
 
 	return nil
 }
+
+// reusableStageSubstitutions are the substitutions which must be the same in
+// an existing build as in the requested one for its staged release to be
+// reused, since they change which artifacts are built or what they contain.
+var reusableStageSubstitutions = []string{
+	"_TARGET_OSES",
+	"_TARGET_ARCHES",
+	"_PUBLISHED_IMAGE_REPO",
+	"_SKIP_SIGNING",
+}
+
+// findReusableStageBuild returns the newest successful GCB job which staged
+// the same release version and git ref to outputDir with the same
+// substitutions, or nil if there is no such build or if its staged release is
+// no longer in the bucket.
+func findReusableStageBuild(ctx context.Context, svc *cloudbuild.Service, o *stageOptions, substitutions map[string]string, outputDir string) (*cloudbuild.Build, error) {
+	build, err := gcb.NewestGreenBuild(ctx, svc, o.Project, o.ReleaseVersion, o.GitRef)
+	if err != nil {
+		return nil, err
+	}
+
+	if build == nil {
+		return nil, nil
+	}
+
+	// only the newest build is considered, since its artifacts are the ones
+	// left in the bucket
+	if mismatch := stageSubstitutionMismatch(substitutions, build.Substitutions); mismatch != "" {
+		slog.Info("Found existing successful build, but it was built with different options", "build_id", build.Id, "mismatch", mismatch)
+		return nil, nil
+	}
+
+	gcs, err := gcp.NewStorageClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCS client: %w", err)
+	}
+
+	buildType := release.BuildTypeDevel
+	if o.ReleaseVersion != "" {
+		buildType = release.BuildTypeRelease
+	}
+
+	bucket := release.NewBucket(gcs.Bucket(o.Bucket), release.DefaultBucketPathPrefix, buildType).WithMetadataFileName(o.MetadataFileName)
+	if _, err := bucket.GetRelease(ctx, path.Base(outputDir)); err != nil {
+		if errors.Is(err, release.ErrReleaseNotFound) {
+			slog.Info("Found existing successful build, but its staged release is no longer present", "build_id", build.Id, "location", fmt.Sprintf("gs://%s/%s", o.Bucket, outputDir))
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to check staged release for build %q: %w", build.Id, err)
+	}
+
+	return build, nil
+}
+
+// stageSubstitutionMismatch returns a description of the first of the
+// reusableStageSubstitutions whose value differs between requested and
+// existing, or an empty string if they all match.
+func stageSubstitutionMismatch(requested, existing map[string]string) string {
+	for _, key := range reusableStageSubstitutions {
+		if requested[key] != existing[key] {
+			return fmt.Sprintf("%s=%q, requested %q", key, existing[key], requested[key])
+		}
+	}
+
+	return ""
+}
+
+// stagedByEnvVars are the environment variables checked, in order, to detect
+// the user staging a release if one isn't given explicitly.
+var stagedByEnvVars = []string{"BUILD_REQUESTED_BY", "USER"}
+
+// stagedBy returns the given user if set, and otherwise makes a best-effort
+// attempt to detect the user staging a release from the environment. An empty
+// string is returned if no user can be detected.
+func stagedBy(user string, getenv func(string) string) string {
+	if user != "" {
+		return user
+	}
+
+	for _, name := range stagedByEnvVars {
+		if v := strings.TrimSpace(getenv(name)); v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import "testing"
+
+func TestStagedBy(t *testing.T) {
+	tests := map[string]struct {
+		user string
+		env  map[string]string
+
+		expected string
+	}{
+		"explicit user takes precedence": {
+			user:     "release-manager",
+			env:      map[string]string{"BUILD_REQUESTED_BY": "someone-else", "USER": "root"},
+			expected: "release-manager",
+		},
+		"build requester preferred over local user": {
+			env:      map[string]string{"BUILD_REQUESTED_BY": "release-manager", "USER": "root"},
+			expected: "release-manager",
+		},
+		"local user": {
+			env:      map[string]string{"USER": "release-manager"},
+			expected: "release-manager",
+		},
+		"blank values are ignored": {
+			env:      map[string]string{"BUILD_REQUESTED_BY": " ", "USER": "release-manager"},
+			expected: "release-manager",
+		},
+		"no user detected": {
+			expected: "",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			getenv := func(key string) string {
+				return test.env[key]
+			}
+
+			if user := stagedBy(test.user, getenv); user != test.expected {
+				t.Errorf("unexpected staged by user: got=%q, exp=%q", user, test.expected)
+			}
+		})
+	}
+}
+
+func TestStageSubstitutionMismatch(t *testing.T) {
+	requested := map[string]string{
+		"_TARGET_OSES":          "linux",
+		"_TARGET_ARCHES":        "amd64,arm64",
+		"_PUBLISHED_IMAGE_REPO": "quay.io/jetstack",
+		"_SKIP_SIGNING":         "false",
+		"_STAGED_BY":            "release-manager",
+	}
+
+	tests := map[string]struct {
+		existing map[string]string
+
+		expected string
+	}{
+		"exact match": {
+			existing: map[string]string{
+				"_TARGET_OSES":          "linux",
+				"_TARGET_ARCHES":        "amd64,arm64",
+				"_PUBLISHED_IMAGE_REPO": "quay.io/jetstack",
+				"_SKIP_SIGNING":         "false",
+				"_STAGED_BY":            "someone-else",
+			},
+			expected: "",
+		},
+		"different target arches": {
+			existing: map[string]string{
+				"_TARGET_OSES":          "linux",
+				"_TARGET_ARCHES":        "amd64",
+				"_PUBLISHED_IMAGE_REPO": "quay.io/jetstack",
+				"_SKIP_SIGNING":         "false",
+			},
+			expected: `_TARGET_ARCHES="amd64", requested "amd64,arm64"`,
+		},
+		"different image repository": {
+			existing: map[string]string{
+				"_TARGET_OSES":          "linux",
+				"_TARGET_ARCHES":        "amd64,arm64",
+				"_PUBLISHED_IMAGE_REPO": "gcr.io/jetstack",
+				"_SKIP_SIGNING":         "false",
+			},
+			expected: `_PUBLISHED_IMAGE_REPO="gcr.io/jetstack", requested "quay.io/jetstack"`,
+		},
+		"unsigned build": {
+			existing: map[string]string{
+				"_TARGET_OSES":          "linux",
+				"_TARGET_ARCHES":        "amd64,arm64",
+				"_PUBLISHED_IMAGE_REPO": "quay.io/jetstack",
+				"_SKIP_SIGNING":         "true",
+			},
+			expected: `_SKIP_SIGNING="true", requested "false"`,
+		},
+		"build without substitutions": {
+			expected: `_TARGET_OSES="", requested "linux"`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if mismatch := stageSubstitutionMismatch(requested, test.existing); mismatch != test.expected {
+				t.Errorf("unexpected mismatch: got=%q, exp=%q", mismatch, test.expected)
+			}
+		})
+	}
+}
@@ -18,22 +18,32 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"os"
 	"sort"
+	"strings"
 	"text/tabwriter"
+	"time"
 
-	"cloud.google.com/go/storage"
 	"github.com/spf13/cobra"
 	flag "github.com/spf13/pflag"
 	"golang.org/x/mod/semver"
+	"sigs.k8s.io/yaml"
 
+	"github.com/cert-manager/release/pkg/gcp"
 	"github.com/cert-manager/release/pkg/release"
 )
 
 const (
 	stagedCommand     = "staged"
 	stagedDescription = "List existing staged releases in the GCS bucket, sorted by version."
+
+	stagedOutputTable = "table"
+	stagedOutputYAML  = "yaml"
+	stagedOutputJSON  = "json"
 )
 
 var (
@@ -50,26 +60,26 @@ staged releases, run:
 
 The output is sorted lexicographically using the version string:
 
-    NAME                                                      VERSION
-    v1.0.2-219b7934ac499c7818526597cf635a922bddd22e           v1.0.2
-    v1.0.3-cbd52ed6e9c296012bab87d3877d31e1f1295fa5           v1.0.3
-    v1.0.4-4d870e49b43960fad974487a262395e65da1373e           v1.0.4
-    v1.1.0-7fbdd6487646e812fe74c0c05503805b5d9d4751           v1.1.0
-    v1.1.0-alpha.0-09f043d2c96da68ed8d4f2c71a868fe0846d3669   v1.1.0-alpha.0
-    v1.1.0-alpha.1-fda1c091e3f37046c378bbf832e603284b6db531   v1.1.0-alpha.1
-    v1.1.1-3ac7418070e22c87fae4b22603a6b952f797ae96           v1.1.1
-    v1.2.0-969b678f330c68a6429b7a71b271761c59651a85           v1.2.0
-    v1.2.0-alpha.0-7cef4582ec8e33ff2f3b8dcf15b3f293f6ef82cc   v1.2.0-alpha.0
-    v1.2.0-alpha.1-33f18811909bdd08d39fd8aa3f016734d1393d18   v1.2.0-alpha.1
-    v1.2.0-alpha.2-35febb171706826f27d71af466c624c25733c135   v1.2.0-alpha.2
-    v1.3.0-9c42eeebfd3978531b517277a21e28e3cf90b876           v1.3.0
-    v1.3.0-alpha.0-77b045d159bd20ce0ec454cd79a5edce9187bdd9   v1.3.0-alpha.0
-    v1.3.0-alpha.1-c2c0fdd78131493707050ffa4a7454885d041b08   v1.3.0-alpha.1
-    v1.3.0-beta.0-9f612f0c2eee8390fb730b1aafa592b88d768d15    v1.3.0-beta.0
-    v1.3.1-614438aed00e1060870b273f2238794ef69b60ab           v1.3.1
-    v1.4.0-alpha.1-0ff2b8778c51e6cebe140a6b196e7a9a28cbee87   v1.4.0-alpha.1
-    v1.4.0-alpha.0-8d794c6bcf3bb02b9961bbd40f5b821f5636cceb   v1.4.0-wallrj.1
-    v1.4.0-wallrj.2-0ff2b8778c51e6cebe140a6b196e7a9a28cbee87  v1.4.0-wallrj.2
+    NAME                                                     VERSION         STAGED BY
+    v1.0.2-219b7934ac499c7818526597cf635a922bddd22e          v1.0.2
+    v1.0.3-cbd52ed6e9c296012bab87d3877d31e1f1295fa5          v1.0.3
+    v1.0.4-4d870e49b43960fad974487a262395e65da1373e          v1.0.4
+    v1.1.0-7fbdd6487646e812fe74c0c05503805b5d9d4751          v1.1.0
+    v1.1.0-alpha.0-09f043d2c96da68ed8d4f2c71a868fe0846d3669  v1.1.0-alpha.0
+    v1.1.0-alpha.1-fda1c091e3f37046c378bbf832e603284b6db531  v1.1.0-alpha.1
+    v1.1.1-3ac7418070e22c87fae4b22603a6b952f797ae96          v1.1.1
+    v1.2.0-969b678f330c68a6429b7a71b271761c59651a85          v1.2.0
+    v1.2.0-alpha.0-7cef4582ec8e33ff2f3b8dcf15b3f293f6ef82cc  v1.2.0-alpha.0
+    v1.2.0-alpha.1-33f18811909bdd08d39fd8aa3f016734d1393d18  v1.2.0-alpha.1
+    v1.2.0-alpha.2-35febb171706826f27d71af466c624c25733c135  v1.2.0-alpha.2
+    v1.3.0-9c42eeebfd3978531b517277a21e28e3cf90b876          v1.3.0
+    v1.3.0-alpha.0-77b045d159bd20ce0ec454cd79a5edce9187bdd9  v1.3.0-alpha.0
+    v1.3.0-alpha.1-c2c0fdd78131493707050ffa4a7454885d041b08  v1.3.0-alpha.1
+    v1.3.0-beta.0-9f612f0c2eee8390fb730b1aafa592b88d768d15   v1.3.0-beta.0
+    v1.3.1-614438aed00e1060870b273f2238794ef69b60ab          v1.3.1          release-manager
+    v1.4.0-alpha.1-0ff2b8778c51e6cebe140a6b196e7a9a28cbee87  v1.4.0-alpha.1  release-manager
+    v1.4.0-alpha.0-8d794c6bcf3bb02b9961bbd40f5b821f5636cceb  v1.4.0-wallrj.1 wallrj
+    v1.4.0-wallrj.2-0ff2b8778c51e6cebe140a6b196e7a9a28cbee87 v1.4.0-wallrj.2 wallrj
 
 If you already know the release version (and since you have run 'cmrel stage',
 you probably do), you can select just these versions:
@@ -78,8 +88,8 @@ you probably do), you can select just these versions:
 
 which will only show the releases that you are interested in:
 
-    NAME                                                      VERSION
-    v1.3.1-614438aed00e1060870b273f2238794ef69b60ab           v1.3.1
+    NAME                                            VERSION STAGED BY
+    v1.3.1-614438aed00e1060870b273f2238794ef69b60ab v1.3.1  release-manager
 
 The "release name" that you need to pass as --release-name to 'cmrel publish'
 is the string:
@@ -93,12 +103,33 @@ Note that by default, the command will only show the "release" type, not the
 
 This time, no version will be shown, just the git commit hash:
 
-    NAME                                     VERSION
+    NAME                                     VERSION STAGED BY
     29406bfaa25c33661ff31b4d60a74f7b04ab6f2d
     3c43140e9e7a6fc04e0e7ba0d50faeaa6aea97df
     b95836421f7f3d2bbbebaa4fa3cca7128e3a97ad
     dfafd10391b00d65315624dbbdc840d21735b240
     ece63038d00e62711443a5abbc0e87b15a1367c1
+
+To consume the list of staged releases from other tools, structured output can
+be written to stdout instead of the table:
+
+	cmrel staged --release-version=v1.3.1 --output=yaml
+
+which prints:
+
+    - artifacts:
+      - name: cert-manager-manifests.tar.gz
+        sha256: 2f5b4c6a...
+        size: 123456
+      ...
+      buildSource: make
+      gitCommitRef: 614438aed00e1060870b273f2238794ef69b60ab
+      name: v1.3.1-614438aed00e1060870b273f2238794ef69b60ab
+      releaseVersion: v1.3.1
+      stagedAt: "2021-05-12T10:04:23Z"
+      stagedBy: release-manager
+
+The same fields are written as a JSON array with --output=json.
 `)
 )
 
@@ -117,6 +148,18 @@ type stagedOptions struct {
 
 	// The type of release to list - usually one of 'release' or 'devel'
 	ReleaseType string
+
+	// MetadataFileName is the name of the file containing the release
+	// metadata in the root of each staged release.
+	MetadataFileName string
+
+	// StrictMetadata, if true, causes release metadata containing unknown
+	// fields to be rejected.
+	StrictMetadata bool
+
+	// Output is the format used to print the list of staged releases, one
+	// of 'table', 'yaml' or 'json'.
+	Output string
 }
 
 func (o *stagedOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
@@ -124,14 +167,21 @@ func (o *stagedOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
 	fs.StringVar(&o.GitRef, "git-ref", "", "Optional specific git reference to list staged releases for - if specified, --release-version must also be specified.")
 	fs.StringVar(&o.ReleaseVersion, "release-version", "", "Optional release version override used to force the version strings used during the release to a specific value.")
 	fs.StringVar(&o.ReleaseType, "release-type", "release", "The type of release to list, usually one of 'release' or 'devel'")
+	fs.StringVar(&o.MetadataFileName, "metadata-file-name", release.MetadataFileName, "The name of the file containing the release metadata in the root of each staged release.")
+	fs.BoolVar(&o.StrictMetadata, "strict-metadata", false, "Reject release metadata which contains unknown fields, rather than ignoring them.")
+	fs.StringVarP(&o.Output, "output", "o", stagedOutputTable, fmt.Sprintf("Output format, one of: %s, %s, %s. Table output is written to stderr along with other logs, all other formats are written to stdout.", stagedOutputTable, stagedOutputYAML, stagedOutputJSON))
 }
 
 func (o *stagedOptions) print() {
-	log.Printf("Staged options:")
-	log.Printf("  Bucket: %q", o.Bucket)
-	log.Printf("  GitRef: %q", o.GitRef)
-	log.Printf("  ReleaseVersion: %q", o.ReleaseVersion)
-	log.Printf("  ReleaseType: %q", o.ReleaseType)
+	slog.Info("Staged options",
+		"Bucket", o.Bucket,
+		"GitRef", o.GitRef,
+		"ReleaseVersion", o.ReleaseVersion,
+		"ReleaseType", o.ReleaseType,
+		"MetadataFileName", o.MetadataFileName,
+		"StrictMetadata", o.StrictMetadata,
+		"Output", o.Output,
+	)
 }
 
 func stagedCmd(rootOpts *rootOptions) *cobra.Command {
@@ -143,13 +193,14 @@ func stagedCmd(rootOpts *rootOptions) *cobra.Command {
 		SilenceUsage: true,
 		PreRun: func(_ *cobra.Command, _ []string) {
 			o.print()
-			log.Printf("---")
+			slog.Info("---")
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runStaged(rootOpts, o)
 		},
 	}
 	o.AddFlags(cmd.Flags(), mustMarkRequired(cmd.MarkFlagRequired))
+	cmd.AddCommand(stagedPruneCmd(rootOpts))
 	return cmd
 }
 
@@ -157,38 +208,152 @@ func runStaged(_ *rootOptions, o *stagedOptions) error {
 	if o.ReleaseVersion == "" && o.GitRef != "" {
 		return fmt.Errorf("cannot specify --git-ref without --release-version")
 	}
+	if o.Output != stagedOutputTable && o.Output != stagedOutputYAML && o.Output != stagedOutputJSON {
+		return fmt.Errorf("unknown output format %q", o.Output)
+	}
 	ctx := context.Background()
-	gcs, err := storage.NewClient(ctx)
+	gcs, err := gcp.NewStorageClient(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to create GCS client: %w", err)
 	}
 
-	bucket := release.NewBucket(gcs.Bucket(o.Bucket), release.DefaultBucketPathPrefix, o.ReleaseType)
+	bucket := release.NewBucket(gcs.Bucket(o.Bucket), release.DefaultBucketPathPrefix, o.ReleaseType).WithMetadataFileName(o.MetadataFileName).WithStrictMetadata(o.StrictMetadata)
+	if err := bucket.CheckAccess(ctx); err != nil {
+		return err
+	}
+
 	stagedReleases, err := bucket.ListReleases(ctx, o.ReleaseVersion, o.GitRef)
 	if err != nil {
 		return fmt.Errorf("failed listing staged releases: %w", err)
 	}
 
-	lines := []string{"NAME\tVERSION"}
 	sort.Sort(ByVersion(stagedReleases))
+
+	summaries := []stagedReleaseSummary{}
 	for _, rel := range stagedReleases {
-		vers := rel.Metadata().ReleaseVersion
-		lines = append(lines, fmt.Sprintf("%s\t%s", rel.Name(), vers))
+		summaries = append(summaries, summarizeStagedRelease(rel))
 	}
 
-	logTable(lines...)
+	switch o.Output {
+	case stagedOutputTable:
+		logTable(stagedReleasesTable(summaries)...)
 
-	return nil
+		return nil
+
+	case stagedOutputYAML:
+		return writeStagedReleasesYAML(os.Stdout, summaries)
+
+	case stagedOutputJSON:
+		return writeStagedReleasesJSON(os.Stdout, summaries)
+
+	default:
+		return fmt.Errorf("unknown output format %q", o.Output)
+	}
+}
+
+// stagedReleaseSummary is the structured form of a staged release printed by
+// the staged command.
+type stagedReleaseSummary struct {
+	Name           string `json:"name"`
+	ReleaseVersion string `json:"releaseVersion"`
+	GitCommitRef   string `json:"gitCommitRef"`
+	BuildSource    string `json:"buildSource"`
+	StagedBy       string `json:"stagedBy,omitempty"`
+
+	// StagedAt is the time at which the release finished being staged, if
+	// known.
+	StagedAt *time.Time `json:"stagedAt,omitempty"`
+
+	Artifacts []release.ArtifactMetadata `json:"artifacts"`
+}
+
+// summarizeStagedRelease returns the summary of rel printed by the staged
+// command.
+func summarizeStagedRelease(rel release.Staged) stagedReleaseSummary {
+	meta := rel.Metadata()
+
+	summary := stagedReleaseSummary{
+		Name:           rel.Name(),
+		ReleaseVersion: meta.ReleaseVersion,
+		GitCommitRef:   meta.GitCommitRef,
+		BuildSource:    meta.BuildSource,
+		StagedBy:       meta.StagedBy,
+		Artifacts:      meta.Artifacts,
+	}
+
+	// releases staged before the build source was recorded were built by Bazel
+	if summary.BuildSource == "" {
+		summary.BuildSource = release.BuildSourceBazel
+	}
+
+	if stagedAt := rel.StagedAt(); !stagedAt.IsZero() {
+		utc := stagedAt.UTC()
+		summary.StagedAt = &utc
+	}
+
+	return summary
+}
+
+// stagedReleasesTable returns the tab-separated lines of the table of staged
+// releases printed by the staged command, including a header.
+func stagedReleasesTable(summaries []stagedReleaseSummary) []string {
+	lines := []string{"NAME\tVERSION\tSTAGED BY"}
+	for _, s := range summaries {
+		lines = append(lines, fmt.Sprintf("%s\t%s\t%s", s.Name, s.ReleaseVersion, s.StagedBy))
+	}
+
+	return lines
+}
+
+func writeStagedReleasesYAML(w io.Writer, summaries []stagedReleaseSummary) error {
+	// a nil slice would be written as 'null' rather than an empty list
+	if summaries == nil {
+		summaries = []stagedReleaseSummary{}
+	}
+
+	out, err := yaml.Marshal(summaries)
+	if err != nil {
+		return fmt.Errorf("failed to encode staged releases as YAML: %w", err)
+	}
+
+	_, err = w.Write(out)
+	return err
+}
+
+func writeStagedReleasesJSON(w io.Writer, summaries []stagedReleaseSummary) error {
+	if summaries == nil {
+		summaries = []stagedReleaseSummary{}
+	}
+
+	out, err := json.MarshalIndent(summaries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode staged releases as JSON: %w", err)
+	}
+
+	_, err = fmt.Fprintln(w, string(out))
+	return err
 }
 
 func logTable(lines ...string) {
 	// Observe how the b's and the d's, despite appearing in the
 	// second cell of each line, belong to different columns.
-	w := tabwriter.NewWriter(log.Writer(), 0, 0, 1, ' ', tabwriter.TabIndent)
+	b := &strings.Builder{}
+	w := tabwriter.NewWriter(b, 0, 0, 1, ' ', tabwriter.TabIndent)
 	for _, l := range lines {
 		fmt.Fprintln(w, l)
 	}
 	w.Flush()
+
+	// log each line separately, so that every line of the table is its own
+	// log record
+	logLines(b.String())
+}
+
+// logLines logs each line of text as its own log record.
+func logLines(text string) {
+	for _, l := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		slog.Info(l)
+	}
 }
 
 type ByVersion []release.Staged
@@ -0,0 +1,226 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+
+	"github.com/cert-manager/release/pkg/gcp"
+	"github.com/cert-manager/release/pkg/release"
+)
+
+const (
+	stagedPruneCommand         = "prune"
+	stagedPruneDescription     = "Delete old staged releases from the GCS bucket"
+	stagedPruneLongDescription = `
+The 'staged prune' command deletes staged releases of the given type which
+haven't been modified for longer than --older-than. Directories left behind by
+builds which failed part way through staging, and so have no release metadata,
+are deleted too.
+
+By default, the staged releases which would be deleted are only printed. Pass
+--confirm to delete them. Staged releases of type 'release' are never deleted
+unless --allow-release is also set.
+`
+)
+
+var (
+	stagedPruneExample = fmt.Sprintf(`
+To list the devel builds which haven't been modified in the last 90 days:
+
+	%s staged %s --older-than=90d
+
+To delete them:
+
+	%s staged %s --older-than=90d --confirm
+`, rootCommand, stagedPruneCommand, rootCommand, stagedPruneCommand)
+)
+
+type stagedPruneOptions struct {
+	// The name of the GCS bucket containing the staged releases
+	Bucket string
+
+	// The type of release to prune - usually one of 'release' or 'devel'
+	ReleaseType string
+
+	// OlderThan is the age beyond which staged releases are deleted, either
+	// as a Go duration or as a number of days such as "90d".
+	OlderThan string
+
+	// MetadataFileName is the name of the file containing the release
+	// metadata in the root of each staged release.
+	MetadataFileName string
+
+	// Confirm must be true for staged releases to actually be deleted.
+	Confirm bool
+
+	// AllowRelease must be true for staged releases of type 'release' to be
+	// deleted.
+	AllowRelease bool
+}
+
+func (o *stagedPruneOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
+	fs.StringVar(&o.Bucket, "bucket", release.DefaultBucketName, "The name of the GCS bucket containing the staged releases.")
+	fs.StringVar(&o.ReleaseType, "release-type", release.BuildTypeDevel, "The type of release to prune, usually one of 'release' or 'devel'")
+	fs.StringVar(&o.OlderThan, "older-than", "", "Delete staged releases which haven't been modified for longer than this, e.g. '90d' or '36h'.")
+	fs.StringVar(&o.MetadataFileName, "metadata-file-name", release.MetadataFileName, "The name of the file containing the release metadata in the root of each staged release.")
+	fs.BoolVar(&o.Confirm, "confirm", false, "Delete the staged releases. If false, the staged releases which would be deleted are only printed.")
+	fs.BoolVar(&o.AllowRelease, "allow-release", false, "Allow deleting staged releases of type 'release'.")
+	markRequired("older-than")
+}
+
+func (o *stagedPruneOptions) print() {
+	slog.Info("Staged prune options",
+		"Bucket", o.Bucket,
+		"ReleaseType", o.ReleaseType,
+		"OlderThan", o.OlderThan,
+		"MetadataFileName", o.MetadataFileName,
+		"Confirm", o.Confirm,
+		"AllowRelease", o.AllowRelease,
+	)
+}
+
+func stagedPruneCmd(rootOpts *rootOptions) *cobra.Command {
+	o := &stagedPruneOptions{}
+	cmd := &cobra.Command{
+		Use:          stagedPruneCommand,
+		Short:        stagedPruneDescription,
+		Long:         stagedPruneLongDescription,
+		Example:      stagedPruneExample,
+		SilenceUsage: true,
+		PreRun: func(_ *cobra.Command, _ []string) {
+			o.print()
+			slog.Info("---")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStagedPrune(rootOpts, o)
+		},
+	}
+	o.AddFlags(cmd.Flags(), mustMarkRequired(cmd.MarkFlagRequired))
+	return cmd
+}
+
+func runStagedPrune(_ *rootOptions, o *stagedPruneOptions) error {
+	olderThan, err := parseOlderThan(o.OlderThan)
+	if err != nil {
+		return err
+	}
+
+	if o.ReleaseType == release.BuildTypeRelease && !o.AllowRelease {
+		return fmt.Errorf("refusing to prune staged releases of type %q without --allow-release", release.BuildTypeRelease)
+	}
+
+	ctx := context.Background()
+	gcs, err := gcp.NewStorageClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create GCS client: %w", err)
+	}
+
+	bucket := release.NewBucket(gcs.Bucket(o.Bucket), release.DefaultBucketPathPrefix, o.ReleaseType).WithMetadataFileName(o.MetadataFileName)
+	if err := bucket.CheckAccess(ctx); err != nil {
+		return err
+	}
+
+	dirs, err := bucket.ListReleaseDirectories(ctx)
+	if err != nil {
+		return fmt.Errorf("failed listing staged releases: %w", err)
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	toPrune := releasesToPrune(dirs, cutoff)
+	if len(toPrune) == 0 {
+		slog.Info("No staged releases were last modified before the cutoff, nothing to do", "release_type", o.ReleaseType, "cutoff", cutoff.Format(time.RFC3339))
+		return nil
+	}
+
+	slog.Info("The following staged releases were last modified before the cutoff:", "count", len(toPrune), "release_type", o.ReleaseType, "cutoff", cutoff.Format(time.RFC3339))
+	logTable(prunedReleasesTable(toPrune)...)
+
+	if !o.Confirm {
+		slog.Info("--confirm not set, not deleting staged releases")
+		return nil
+	}
+
+	for _, dir := range toPrune {
+		slog.Info("Deleting staged release", "release", dir.Name)
+		if err := bucket.DeleteRelease(ctx, dir.Name); err != nil {
+			return fmt.Errorf("failed to delete staged release %q: %w", dir.Name, err)
+		}
+	}
+
+	slog.Info("Deleted staged releases", "count", len(toPrune))
+
+	return nil
+}
+
+// parseOlderThan parses the value of --older-than, which is either a Go
+// duration such as "36h" or a whole number of days such as "90d".
+func parseOlderThan(s string) (time.Duration, error) {
+	var d time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid --older-than %q: %w", s, err)
+		}
+
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		d, err = time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid --older-than %q: %w", s, err)
+		}
+	}
+
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid --older-than %q: must be greater than zero", s)
+	}
+
+	return d, nil
+}
+
+// releasesToPrune returns the release directories in dirs which were last
+// modified before cutoff.
+func releasesToPrune(dirs []release.ReleaseDirectory, cutoff time.Time) []release.ReleaseDirectory {
+	var toPrune []release.ReleaseDirectory
+	for _, dir := range dirs {
+		if dir.LastModified.Before(cutoff) {
+			toPrune = append(toPrune, dir)
+		}
+	}
+
+	return toPrune
+}
+
+// prunedReleasesTable returns the tab-separated lines of the table of staged
+// releases to be pruned, including a header.
+func prunedReleasesTable(dirs []release.ReleaseDirectory) []string {
+	lines := []string{"NAME\tLAST MODIFIED\tOBJECTS\tSIZE"}
+	for _, dir := range dirs {
+		lines = append(lines, fmt.Sprintf("%s\t%s\t%d\t%d", dir.Name, dir.LastModified.UTC().Format(time.RFC3339), dir.Objects, dir.Size))
+	}
+
+	return lines
+}
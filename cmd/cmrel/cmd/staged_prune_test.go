@@ -0,0 +1,102 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/release/pkg/release"
+)
+
+func TestParseOlderThan(t *testing.T) {
+	tests := map[string]struct {
+		input     string
+		expected  time.Duration
+		expectErr bool
+	}{
+		"days": {
+			input:    "90d",
+			expected: 90 * 24 * time.Hour,
+		},
+		"go duration": {
+			input:    "36h",
+			expected: 36 * time.Hour,
+		},
+		"empty": {
+			input:     "",
+			expectErr: true,
+		},
+		"invalid days": {
+			input:     "ninetyd",
+			expectErr: true,
+		},
+		"zero": {
+			input:     "0d",
+			expectErr: true,
+		},
+		"negative": {
+			input:     "-1h",
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			d, err := parseOlderThan(test.input)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if d != test.expected {
+				t.Errorf("expected %s but got %s", test.expected, d)
+			}
+		})
+	}
+}
+
+func TestReleasesToPrune(t *testing.T) {
+	cutoff := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	dirs := []release.ReleaseDirectory{
+		{Name: "old", LastModified: cutoff.Add(-time.Hour)},
+		{Name: "at-cutoff", LastModified: cutoff},
+		{Name: "new", LastModified: cutoff.Add(time.Hour)},
+	}
+
+	expected := []release.ReleaseDirectory{dirs[0]}
+
+	if toPrune := releasesToPrune(dirs, cutoff); !reflect.DeepEqual(toPrune, expected) {
+		t.Errorf("unexpected releases to prune:\ngot=%+v\nexp=%+v", toPrune, expected)
+	}
+}
+
+func TestPrunedReleasesTable(t *testing.T) {
+	dirs := []release.ReleaseDirectory{
+		{Name: "29406bfaa25c33661ff31b4d60a74f7b04ab6f2d", LastModified: time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC), Objects: 3, Size: 1024},
+	}
+
+	expected := []string{
+		"NAME\tLAST MODIFIED\tOBJECTS\tSIZE",
+		"29406bfaa25c33661ff31b4d60a74f7b04ab6f2d\t2021-01-02T03:04:05Z\t3\t1024",
+	}
+
+	if lines := prunedReleasesTable(dirs); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("unexpected table:\ngot=%q\nexp=%q", lines, expected)
+	}
+}
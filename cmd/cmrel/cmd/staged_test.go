@@ -0,0 +1,150 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/cert-manager/release/pkg/release"
+)
+
+func TestWriteStagedReleases(t *testing.T) {
+	stagedAt := time.Date(2021, 5, 12, 10, 4, 23, 0, time.UTC)
+
+	tests := map[string]struct {
+		summaries []stagedReleaseSummary
+	}{
+		"no staged releases": {
+			summaries: []stagedReleaseSummary{},
+		},
+		"release builds": {
+			summaries: []stagedReleaseSummary{
+				{
+					Name:           "v1.3.1-614438aed00e1060870b273f2238794ef69b60ab",
+					ReleaseVersion: "v1.3.1",
+					GitCommitRef:   "614438aed00e1060870b273f2238794ef69b60ab",
+					BuildSource:    "make",
+					StagedBy:       "release-manager",
+					StagedAt:       &stagedAt,
+					Artifacts: []release.ArtifactMetadata{
+						{Name: "cert-manager-manifests.tar.gz", SHA256: "abc123", Size: 1024},
+						{Name: "cert-manager-server-linux-amd64.tar.gz", SHA256: "def456", OS: "linux", Architecture: "amd64"},
+					},
+				},
+				{Name: "v1.4.0-alpha.1-0ff2b8778c51e6cebe140a6b196e7a9a28cbee87", ReleaseVersion: "v1.4.0-alpha.1", GitCommitRef: "0ff2b8778c51e6cebe140a6b196e7a9a28cbee87", BuildSource: "bazel"},
+			},
+		},
+		"devel builds without a version": {
+			summaries: []stagedReleaseSummary{
+				{Name: "29406bfaa25c33661ff31b4d60a74f7b04ab6f2d", GitCommitRef: "29406bfaa25c33661ff31b4d60a74f7b04ab6f2d", BuildSource: "bazel"},
+			},
+		},
+	}
+
+	formats := map[string]struct {
+		write  func(io.Writer, []stagedReleaseSummary) error
+		decode func([]byte, any) error
+	}{
+		stagedOutputYAML: {
+			write: writeStagedReleasesYAML,
+			decode: func(data []byte, v any) error {
+				return yaml.UnmarshalStrict(data, v)
+			},
+		},
+		stagedOutputJSON: {
+			write: writeStagedReleasesJSON,
+			decode: func(data []byte, v any) error {
+				dec := json.NewDecoder(bytes.NewReader(data))
+				dec.DisallowUnknownFields()
+				return dec.Decode(v)
+			},
+		},
+	}
+
+	for name, test := range tests {
+		for format, f := range formats {
+			t.Run(name+" as "+format, func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := f.write(&buf, test.summaries); err != nil {
+					t.Fatal(err)
+				}
+
+				var decoded []stagedReleaseSummary
+				if err := f.decode(buf.Bytes(), &decoded); err != nil {
+					t.Fatalf("failed to decode %s output: %v\n%s", format, err, buf.String())
+				}
+
+				if !reflect.DeepEqual(decoded, test.summaries) {
+					t.Errorf("%s output did not round trip:\ngot=%+v\nexp=%+v", format, decoded, test.summaries)
+				}
+			})
+		}
+	}
+}
+
+func TestWriteStagedReleasesEmpty(t *testing.T) {
+	tests := map[string]struct {
+		write func(io.Writer, []stagedReleaseSummary) error
+		exp   string
+	}{
+		stagedOutputYAML: {
+			write: writeStagedReleasesYAML,
+			exp:   "[]\n",
+		},
+		stagedOutputJSON: {
+			write: writeStagedReleasesJSON,
+			exp:   "[]\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := test.write(&buf, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			if buf.String() != test.exp {
+				t.Errorf("expected %q but got %q", test.exp, buf.String())
+			}
+		})
+	}
+}
+
+func TestStagedReleasesTable(t *testing.T) {
+	summaries := []stagedReleaseSummary{
+		{Name: "v1.3.1-614438aed00e1060870b273f2238794ef69b60ab", ReleaseVersion: "v1.3.1", GitCommitRef: "614438aed00e1060870b273f2238794ef69b60ab", StagedBy: "release-manager"},
+		{Name: "29406bfaa25c33661ff31b4d60a74f7b04ab6f2d", GitCommitRef: "29406bfaa25c33661ff31b4d60a74f7b04ab6f2d"},
+	}
+
+	expected := []string{
+		"NAME\tVERSION\tSTAGED BY",
+		"v1.3.1-614438aed00e1060870b273f2238794ef69b60ab\tv1.3.1\trelease-manager",
+		"29406bfaa25c33661ff31b4d60a74f7b04ab6f2d\t\t",
+	}
+
+	if lines := stagedReleasesTable(summaries); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("unexpected table:\ngot=%q\nexp=%q", lines, expected)
+	}
+}
@@ -17,9 +17,10 @@ limitations under the License.
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 	"io/fs"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
@@ -29,6 +30,9 @@ import (
 	"golang.org/x/exp/slices"
 	"golang.org/x/mod/modfile"
 	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+
+	"github.com/cert-manager/release/pkg/release"
 )
 
 const (
@@ -48,7 +52,18 @@ Ensures that:
   used for all subs which also have those dependencies, preventing drift
 - All subs use an invalid version of all internal modules in their go.mod so they're
   forced to rely on replace directives pointing to the local module in the repo
-- All modules declare the same version of Golang`
+- All modules declare the same version of Golang
+- All modules declare the same toolchain as the core module, or none if the core
+  module has none
+- No module requires any of the modules given by --denied-modules
+- No module requires an older version of any of the modules given by
+  --minimum-versions than the minimum version given for it
+
+With --fix, violations are fixed where possible by rewriting the go.mod files of
+submodules before validating: missing or incorrect replace directives are set
+to match the core module, internal modules are required with the dummy version
+and the Go version and toolchain are set to match the core module. Problems with the core
+go.mod file itself must still be fixed by hand.`
 
 	// dummyCoreImportVersion is the expected version string for any import of the core module.
 	// This dummy string makes it clearer that the module is replaced with a local filesystem
@@ -66,7 +81,15 @@ Ensures that:
 var (
 	validateGoModExample = fmt.Sprintf(`To validate a local checkout:
 
-%s %s --path <path-to-checkout>`, rootCommand, validateGoModCommand)
+%s %s --path <path-to-checkout>
+
+To fix any violations which can be fixed automatically, and then validate:
+
+%s %s --path <path-to-checkout> --fix
+
+To forbid requiring k8s.io/kubernetes and require golang.org/x/net v0.23.0 or later:
+
+%s %s --path <path-to-checkout> --denied-modules k8s.io/kubernetes --minimum-versions golang.org/x/net@v0.23.0`, rootCommand, validateGoModCommand, rootCommand, validateGoModCommand, rootCommand, validateGoModCommand)
 )
 
 type validateGoModOptions struct {
@@ -80,6 +103,19 @@ type validateGoModOptions struct {
 	// NoDummyModules is an optional list of modules which are permitted to use a non-dummy
 	// verison of the core module, i.e. to use an actual version instead of dummyCoreImportVersion.
 	NoDummyModules []string
+
+	// DeniedModules is an optional list of modules which no internal module may
+	// require, such as k8s.io/kubernetes.
+	DeniedModules []string
+
+	// MinimumVersions is an optional list of modules and the minimum version
+	// of each which any internal module requiring it must use, in the form
+	// "<module>@<version>".
+	MinimumVersions []string
+
+	// Fix, if true, rewrites go.mod files to fix any violations which can be
+	// fixed automatically before validating them.
+	Fix bool
 }
 
 func (o *validateGoModOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
@@ -91,14 +127,26 @@ func (o *validateGoModOptions) AddFlags(fs *flag.FlagSet, markRequired func(stri
 	fs.StringSliceVar(&o.DirectImportModules, "direct-import-modules", []string{},
 		fmt.Sprintf("Optional comma-separated list of modules which may import internal modules without needing a local filesystem replace. Directly importable modules imply %q too.", noDummyFlag))
 
+	fs.StringSliceVar(&o.DeniedModules, "denied-modules", []string{},
+		"Optional comma-separated list of modules which no module may require, e.g. k8s.io/kubernetes")
+
+	fs.StringSliceVar(&o.MinimumVersions, "minimum-versions", []string{},
+		"Optional comma-separated list of modules and the minimum version of each which modules must require, in the form <module>@<version>, e.g. golang.org/x/net@v0.23.0")
+
+	fs.BoolVar(&o.Fix, "fix", false, "Rewrite go.mod files to fix violations where possible, rather than only reporting them")
+
 	markRequired("path")
 }
 
 func (o *validateGoModOptions) print() {
-	log.Printf("%s options:", validateGoModCommand)
-	log.Printf("                 Path: %q", o.Path)
-	log.Printf("  DirectImportModules: %q", o.DirectImportModules)
-	log.Printf("       NoDummyModules: %q", o.NoDummyModules)
+	slog.Info(validateGoModCommand+" options",
+		"Path", o.Path,
+		"DirectImportModules", o.DirectImportModules,
+		"NoDummyModules", o.NoDummyModules,
+		"DeniedModules", o.DeniedModules,
+		"MinimumVersions", o.MinimumVersions,
+		"Fix", o.Fix,
+	)
 }
 
 func validateGoModCmd(rootOpts *rootOptions) *cobra.Command {
@@ -112,7 +160,7 @@ func validateGoModCmd(rootOpts *rootOptions) *cobra.Command {
 		SilenceUsage: true,
 		PreRun: func(cmd *cobra.Command, args []string) {
 			o.print()
-			log.Printf("---")
+			slog.Info("---")
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runValidateGoMod(rootOpts, o)
@@ -132,6 +180,21 @@ func runValidateGoMod(rootOpts *rootOptions, o *validateGoModOptions) error {
 		return fmt.Errorf("failed to find all submodules in %q: %s", o.Path, err.Error())
 	}
 
+	if o.Fix {
+		if err := allInternalModules.fix(); err != nil {
+			return fmt.Errorf("failed to fix go.mod files: %w", err)
+		}
+
+		fixed, err := allInternalModules.write()
+		if err != nil {
+			return err
+		}
+
+		for _, path := range fixed {
+			slog.Info("fixed go.mod file", "path", path)
+		}
+	}
+
 	if errs := allInternalModules.checkReplaces(); len(errs) > 0 {
 		validationErrors = append(validationErrors, errs...)
 	}
@@ -148,13 +211,25 @@ func runValidateGoMod(rootOpts *rootOptions, o *validateGoModOptions) error {
 		validationErrors = append(validationErrors, errs...)
 	}
 
+	if errs := allInternalModules.checkToolchains(); len(errs) > 0 {
+		validationErrors = append(validationErrors, errs...)
+	}
+
+	if errs := allInternalModules.checkDeniedModules(); len(errs) > 0 {
+		validationErrors = append(validationErrors, errs...)
+	}
+
+	if errs := allInternalModules.checkMinimumVersions(); len(errs) > 0 {
+		validationErrors = append(validationErrors, errs...)
+	}
+
 	if len(validationErrors) > 0 {
-		log.Println("validation failed! errors:")
+		slog.Info("validation failed! errors:")
 		for _, err := range validationErrors {
-			log.Printf("  %s", err.Error())
+			slog.Info("  " + err.Error())
 		}
 
-		return fmt.Errorf("validation failed")
+		return release.ErrValidationFailed
 	}
 
 	return nil
@@ -173,6 +248,12 @@ type internalModuleList struct {
 
 	directImportModules []string
 	noDummyModules      []string
+
+	deniedModules []string
+
+	// minimumVersions maps module paths to the minimum version of that module
+	// which internal modules may require
+	minimumVersions map[string]string
 }
 
 type internalModule struct {
@@ -200,10 +281,18 @@ func findInternalModules(o *validateGoModOptions) (*internalModuleList, error) {
 	iml.internalModuleNames = make(map[string]struct{})
 	iml.directImportModules = o.DirectImportModules
 	iml.noDummyModules = append(o.NoDummyModules, iml.directImportModules...)
+	iml.deniedModules = o.DeniedModules
+
+	minimumVersions, err := parseMinimumVersions(o.MinimumVersions)
+	if err != nil {
+		return nil, err
+	}
+
+	iml.minimumVersions = minimumVersions
 
 	coreModulePath := filepath.Join(o.Path, "go.mod")
 
-	err := filepath.WalkDir(o.Path, func(path string, d fs.DirEntry, err error) error {
+	err = filepath.WalkDir(o.Path, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -401,3 +490,214 @@ func (iml *internalModuleList) checkGoVersions() []error {
 
 	return errs
 }
+
+// checkToolchains ensures that all internal modules declare the same toolchain
+// as the core module, or no toolchain if the core module doesn't declare one
+func (iml *internalModuleList) checkToolchains() []error {
+	coreToolchain := iml.coreModule.toolchain()
+
+	var errs []error
+
+	for _, s := range iml.submodules {
+		if s.toolchain() != coreToolchain {
+			errs = append(errs, fmt.Errorf("module %q has toolchain %q but should have %q to match core go.mod file", s.Name, s.toolchain(), coreToolchain))
+		}
+	}
+
+	return errs
+}
+
+// checkDeniedModules ensures that no internal module requires a denied module
+func (iml *internalModuleList) checkDeniedModules() []error {
+	var errs []error
+
+	for _, m := range iml.modules {
+		for _, requireStmt := range m.Module.Require {
+			if slices.Contains(iml.deniedModules, requireStmt.Mod.Path) {
+				errs = append(errs, fmt.Errorf("module %q requires %q, which is not allowed to be required", m.Name, requireStmt.Mod.Path))
+			}
+		}
+	}
+
+	return errs
+}
+
+// checkMinimumVersions ensures that every internal module which requires a
+// module with a minimum version requires at least that version
+func (iml *internalModuleList) checkMinimumVersions() []error {
+	var errs []error
+
+	for _, m := range iml.modules {
+		for _, requireStmt := range m.Module.Require {
+			minimumVersion, ok := iml.minimumVersions[requireStmt.Mod.Path]
+			if !ok {
+				continue
+			}
+
+			if semver.Compare(requireStmt.Mod.Version, minimumVersion) < 0 {
+				errs = append(errs, fmt.Errorf("module %q requires %q at version %q but the minimum allowed version is %q", m.Name, requireStmt.Mod.Path, requireStmt.Mod.Version, minimumVersion))
+			}
+		}
+	}
+
+	return errs
+}
+
+// toolchain returns the toolchain declared by the module, or an empty string
+// if it doesn't declare one
+func (m *internalModule) toolchain() string {
+	if m.Module.Toolchain == nil {
+		return ""
+	}
+
+	return m.Module.Toolchain.Name
+}
+
+// parseMinimumVersions parses a list of "<module>@<version>" strings into a
+// map of module paths to versions
+func parseMinimumVersions(minimumVersions []string) (map[string]string, error) {
+	parsed := make(map[string]string)
+
+	for _, mv := range minimumVersions {
+		path, version, ok := strings.Cut(mv, "@")
+		if !ok || path == "" {
+			return nil, fmt.Errorf("invalid minimum version %q; should be in the form <module>@<version>", mv)
+		}
+
+		if !semver.IsValid(version) {
+			return nil, fmt.Errorf("invalid minimum version %q; %q is not a valid semantic version", mv, version)
+		}
+
+		parsed[path] = version
+	}
+
+	return parsed, nil
+}
+
+// fix changes the parsed go.mod files of all submodules so that they pass
+// checkReplaces, checkInternalModuleRequirements, checkGoVersions and
+// checkToolchains. The core
+// module is only changed to require internal modules with the dummy version,
+// since it's the source of truth for the other checks. Changes aren't written
+// to disk until write is called.
+func (iml *internalModuleList) fix() error {
+	coreGoVersion := iml.coreModule.Module.Go.Version
+
+	for _, m := range iml.modules {
+		if m != iml.coreModule {
+			if err := iml.fixReplaces(m); err != nil {
+				return err
+			}
+
+			if err := m.Module.AddGoStmt(coreGoVersion); err != nil {
+				return fmt.Errorf("failed to set Go version of module %q: %w", m.Name, err)
+			}
+
+			if coreToolchain := iml.coreModule.toolchain(); coreToolchain == "" {
+				m.Module.DropToolchainStmt()
+			} else if err := m.Module.AddToolchainStmt(coreToolchain); err != nil {
+				return fmt.Errorf("failed to set toolchain of module %q: %w", m.Name, err)
+			}
+		}
+
+		if slices.Contains(iml.noDummyModules, m.Name) {
+			continue
+		}
+
+		for _, requireStmt := range m.Module.Require {
+			_, isInternal := iml.internalModuleNames[requireStmt.Mod.Path]
+			if !isInternal || requireStmt.Mod.Version == dummyCoreImportVersion {
+				continue
+			}
+
+			if err := m.Module.AddRequire(requireStmt.Mod.Path, dummyCoreImportVersion); err != nil {
+				return fmt.Errorf("failed to set version of %q required by module %q: %w", requireStmt.Mod.Path, m.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// fixReplaces sets every replace directive in m for a module in the replace
+// map to the expected replacement, and adds the expected replacement for any
+// required module which m doesn't replace.
+func (iml *internalModuleList) fixReplaces(m *internalModule) error {
+	foundReplaces := make(map[string]struct{})
+
+	for _, replaceStmt := range m.Module.Replace {
+		expectedReplace, exists := iml.replaceMap[replaceStmt.Old.Path]
+		if !exists {
+			continue
+		}
+
+		foundReplaces[replaceStmt.Old.Path] = struct{}{}
+
+		if replaceStmt.New == expectedReplace {
+			continue
+		}
+
+		if err := m.Module.AddReplace(replaceStmt.Old.Path, replaceStmt.Old.Version, expectedReplace.Path, expectedReplace.Version); err != nil {
+			return fmt.Errorf("failed to fix replacement of %q in module %q: %w", replaceStmt.Old.Path, m.Name, err)
+		}
+	}
+
+	for _, requireStmt := range m.Module.Require {
+		expectedReplace, shouldReplace := iml.replaceMap[requireStmt.Mod.Path]
+		if !shouldReplace {
+			continue
+		}
+
+		if _, wasReplaced := foundReplaces[requireStmt.Mod.Path]; wasReplaced {
+			continue
+		}
+
+		if requireStmt.Mod.Path == iml.coreModulePath() && slices.Contains(iml.directImportModules, m.Name) {
+			continue
+		}
+
+		if err := m.Module.AddReplace(requireStmt.Mod.Path, "", expectedReplace.Path, expectedReplace.Version); err != nil {
+			return fmt.Errorf("failed to add replacement of %q to module %q: %w", requireStmt.Mod.Path, m.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// write formats the parsed go.mod file of every internal module and writes it
+// back to disk if it has changed, returning the paths of the files which were
+// written.
+func (iml *internalModuleList) write() ([]string, error) {
+	var written []string
+
+	for _, m := range iml.modules {
+		m.Module.Cleanup()
+
+		formatted, err := m.Module.Format()
+		if err != nil {
+			return nil, fmt.Errorf("failed to format module file %q: %w", m.FullGoModPath, err)
+		}
+
+		info, err := os.Stat(m.FullGoModPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat module file %q: %w", m.FullGoModPath, err)
+		}
+
+		original, err := os.ReadFile(m.FullGoModPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read module file %q: %w", m.FullGoModPath, err)
+		}
+
+		if bytes.Equal(original, formatted) {
+			continue
+		}
+
+		if err := os.WriteFile(m.FullGoModPath, formatted, info.Mode().Perm()); err != nil {
+			return nil, fmt.Errorf("failed to write module file %q: %w", m.FullGoModPath, err)
+		}
+
+		written = append(written, m.FullGoModPath)
+	}
+
+	return written, nil
+}
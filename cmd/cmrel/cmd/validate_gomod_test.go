@@ -0,0 +1,270 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeGoMod writes a go.mod file with the given contents to path within dir.
+func writeGoMod(t *testing.T, dir string, path string, contents string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Join(dir, path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, path, "go.mod"), []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateGoModChecks(t *testing.T) {
+	const core = `module github.com/cert-manager/cert-manager
+
+go 1.22.0
+
+toolchain go1.22.5
+
+require (
+	golang.org/x/net v0.23.0
+	k8s.io/api v0.30.0
+)
+`
+
+	tests := map[string]struct {
+		submodule       string
+		deniedModules   []string
+		minimumVersions []string
+
+		expectedErrs int
+	}{
+		"valid submodule": {
+			submodule: `module github.com/cert-manager/cert-manager/cmctl
+
+go 1.22.0
+
+toolchain go1.22.5
+
+require golang.org/x/net v0.24.0
+`,
+			deniedModules:   []string{"k8s.io/kubernetes"},
+			minimumVersions: []string{"golang.org/x/net@v0.23.0"},
+		},
+		"different toolchain": {
+			submodule: `module github.com/cert-manager/cert-manager/cmctl
+
+go 1.22.0
+
+toolchain go1.22.4
+`,
+			expectedErrs: 1,
+		},
+		"missing toolchain": {
+			submodule: `module github.com/cert-manager/cert-manager/cmctl
+
+go 1.22.0
+`,
+			expectedErrs: 1,
+		},
+		"denied module": {
+			submodule: `module github.com/cert-manager/cert-manager/cmctl
+
+go 1.22.0
+
+toolchain go1.22.5
+
+require k8s.io/kubernetes v1.30.0
+`,
+			deniedModules: []string{"k8s.io/kubernetes"},
+			expectedErrs:  1,
+		},
+		"dependency below minimum version": {
+			submodule: `module github.com/cert-manager/cert-manager/cmctl
+
+go 1.22.0
+
+toolchain go1.22.5
+
+require golang.org/x/net v0.22.0
+`,
+			minimumVersions: []string{"golang.org/x/net@v0.23.0", "k8s.io/api@v0.30.1"},
+			// the core module's k8s.io/api is also too old
+			expectedErrs: 2,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeGoMod(t, dir, "", core)
+			writeGoMod(t, dir, "cmd/ctl", test.submodule)
+
+			iml, err := findInternalModules(&validateGoModOptions{
+				Path:            dir,
+				DeniedModules:   test.deniedModules,
+				MinimumVersions: test.minimumVersions,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var errs []error
+			errs = append(errs, iml.checkToolchains()...)
+			errs = append(errs, iml.checkDeniedModules()...)
+			errs = append(errs, iml.checkMinimumVersions()...)
+
+			if len(errs) != test.expectedErrs {
+				t.Errorf("expected %d validation errors but got %d: %v", test.expectedErrs, len(errs), errs)
+			}
+		})
+	}
+}
+
+func TestParseMinimumVersions(t *testing.T) {
+	tests := map[string]struct {
+		input []string
+
+		expected  map[string]string
+		expectErr bool
+	}{
+		"valid": {
+			input:    []string{"golang.org/x/net@v0.23.0", "k8s.io/api@v0.30.1"},
+			expected: map[string]string{"golang.org/x/net": "v0.23.0", "k8s.io/api": "v0.30.1"},
+		},
+		"none": {
+			expected: map[string]string{},
+		},
+		"missing version": {
+			input:     []string{"golang.org/x/net"},
+			expectErr: true,
+		},
+		"invalid version": {
+			input:     []string{"golang.org/x/net@0.23"},
+			expectErr: true,
+		},
+		"missing module": {
+			input:     []string{"@v0.23.0"},
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			parsed, err := parseMinimumVersions(test.input)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if !test.expectErr && !reflect.DeepEqual(parsed, test.expected) {
+				t.Errorf("wanted %v but got %v", test.expected, parsed)
+			}
+		})
+	}
+}
+
+func TestValidateGoModFix(t *testing.T) {
+	dir := t.TempDir()
+
+	writeGoMod(t, dir, "", `module github.com/cert-manager/cert-manager
+
+go 1.22.0
+
+toolchain go1.22.5
+
+require example.com/dep v1.0.0
+
+replace example.com/dep => example.com/fork v1.0.1
+`)
+
+	// every kind of fixable violation: an old Go version, a missing
+	// toolchain, a third party
+	// replacement which differs from the core module, a missing core module
+	// replacement and a real version of the core module
+	writeGoMod(t, dir, "cmd/controller", `module github.com/cert-manager/cert-manager/controller-binary
+
+go 1.21
+
+require (
+	example.com/dep v1.0.0
+	github.com/cert-manager/cert-manager v1.14.0
+)
+
+replace example.com/dep => example.com/fork v1.0.0
+`)
+
+	// an already valid submodule shouldn't be rewritten
+	valid := `module github.com/cert-manager/cert-manager/webhook-binary
+
+go 1.22.0
+
+toolchain go1.22.5
+
+require github.com/cert-manager/cert-manager v0.0.0-00010101000000-000000000000
+
+replace github.com/cert-manager/cert-manager => ../../
+`
+	writeGoMod(t, dir, "cmd/webhook", valid)
+
+	o := &validateGoModOptions{Path: dir}
+
+	iml, err := findInternalModules(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := iml.fix(); err != nil {
+		t.Fatal(err)
+	}
+
+	written, err := iml.write()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(written) != 1 || written[0] != filepath.Join(dir, "cmd/controller/go.mod") {
+		t.Errorf("expected only the controller go.mod file to be written, but got %q", written)
+	}
+
+	// reload the files to check that what was written passes validation
+	iml, err = findInternalModules(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var errs []error
+	errs = append(errs, iml.checkReplaces()...)
+	errs = append(errs, iml.checkInternalModuleRequirements()...)
+	errs = append(errs, iml.checkGoVersions()...)
+	errs = append(errs, iml.checkToolchains()...)
+
+	for _, err := range errs {
+		t.Errorf("unexpected validation error after fixing: %v", err)
+	}
+
+	webhook, err := os.ReadFile(filepath.Join(dir, "cmd/webhook/go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(webhook) != valid {
+		t.Errorf("expected valid go.mod file to be unchanged but got:\n%s", webhook)
+	}
+}
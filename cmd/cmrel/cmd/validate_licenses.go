@@ -0,0 +1,278 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-github/v35/github"
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"golang.org/x/mod/modfile"
+
+	"github.com/cert-manager/release/pkg/release"
+	"github.com/cert-manager/release/pkg/release/licenses"
+)
+
+const (
+	validateLicensesCommand         = "validate-licenses"
+	validateLicensesDescription     = "Check that the artifacts of a staged release bundle complete license files"
+	validateLicensesLongDescription = `
+The 'validate-licenses' command downloads the selected artifacts of a staged
+release and checks that:
+
+- every artifact contains a LICENSE file
+- every artifact other than the manifests contains a LICENSES file, listing the
+  licenses of the third party Go modules bundled into its binaries
+- every module listed in each LICENSES file is required by the go.mod files of
+  the commit the release was built from, and every direct dependency in those
+  go.mod files is listed
+
+The go.mod files are fetched from GitHub at the commit the release was built
+from, unless --source-path is given to read them from a local checkout. If a
+GITHUB_TOKEN environment variable is set, it is used to query GitHub.
+
+Every artifact is checked, and all violations are reported together.
+`
+)
+
+var (
+	validateLicensesExample = fmt.Sprintf(`
+To check the licenses bundled in every artifact of a staged release:
+
+	%s %s --release-name v1.3.1-614438aed00e1060870b273f2238794ef69b60ab
+
+To check only the server artifacts against the go.mod files of a local checkout
+of cert-manager, in which binaries are built from their own modules:
+
+	%s %s --release-name v1.3.1-614438aed00e1060870b273f2238794ef69b60ab --kind server --source-path ~/cert-manager --go-mod-files go.mod,cmd/controller/go.mod,cmd/webhook/go.mod
+`, rootCommand, validateLicensesCommand, rootCommand, validateLicensesCommand)
+)
+
+type validateLicensesOptions struct {
+	artifactsOptions
+
+	// GitHubOrg and GitHubRepo identify the GitHub repository which go.mod
+	// files are fetched from.
+	GitHubOrg  string
+	GitHubRepo string
+
+	// SourcePath, if set, is the path to a local checkout of the commit the
+	// release was built from, which go.mod files are read from instead of
+	// fetching them from GitHub.
+	SourcePath string
+
+	// GoModFiles are the paths of the go.mod files, relative to the root of
+	// the repository, whose dependencies are compared to the bundled licenses.
+	GoModFiles []string
+
+	// IgnoredModules are modules which are directly required but aren't
+	// expected to have a bundled license, such as modules only imported by
+	// tests.
+	IgnoredModules []string
+}
+
+func (o *validateLicensesOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
+	o.artifactsOptions.AddFlags(fs, markRequired)
+	fs.StringVar(&o.GitHubOrg, "github-org", release.DefaultGitHubOrg, "The org of the repository to fetch go.mod files from.")
+	fs.StringVar(&o.GitHubRepo, "github-repo", release.DefaultGitHubRepo, "The repo name in the provided org to fetch go.mod files from.")
+	fs.StringVar(&o.SourcePath, "source-path", "", "Optional path to a local checkout of the commit the release was built from, to read go.mod files from instead of fetching them from GitHub.")
+	fs.StringSliceVar(&o.GoModFiles, "go-mod-files", []string{"go.mod"}, "Comma-separated list of paths of go.mod files, relative to the root of the repository, whose dependencies are compared to the bundled licenses.")
+	fs.StringSliceVar(&o.IgnoredModules, "ignored-modules", nil, "Comma-separated list of directly required modules which aren't expected to have a bundled license, such as modules only imported by tests.")
+}
+
+func (o *validateLicensesOptions) print() {
+	slog.Info("Validate licenses options", append(o.artifactsOptions.attrs(),
+		"GitHubOrg", o.GitHubOrg,
+		"GitHubRepo", o.GitHubRepo,
+		"SourcePath", o.SourcePath,
+		"GoModFiles", o.GoModFiles,
+		"IgnoredModules", o.IgnoredModules,
+	)...)
+}
+
+func validateLicensesCmd(rootOpts *rootOptions) *cobra.Command {
+	o := &validateLicensesOptions{}
+	cmd := &cobra.Command{
+		Use:          validateLicensesCommand,
+		Short:        validateLicensesDescription,
+		Long:         validateLicensesLongDescription,
+		Example:      validateLicensesExample,
+		SilenceUsage: true,
+		PreRun: func(_ *cobra.Command, _ []string) {
+			o.print()
+			slog.Info("---")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runValidateLicenses(rootOpts, o)
+		},
+	}
+	o.AddFlags(cmd.Flags(), mustMarkRequired(cmd.MarkFlagRequired))
+	return cmd
+}
+
+func runValidateLicenses(_ *rootOptions, o *validateLicensesOptions) error {
+	ctx := context.Background()
+
+	staged, err := o.stagedRelease(ctx)
+	if err != nil {
+		return err
+	}
+
+	artifacts := selectArtifacts(staged.Artifacts(), o.Kinds, o.OSes, o.Arches)
+	if len(artifacts) == 0 {
+		return fmt.Errorf("no artifacts in staged release %q match the given --kind, --os and --arch", o.ReleaseName)
+	}
+
+	fetch := localGoModFetcher(o.SourcePath)
+	if o.SourcePath == "" {
+		slog.Info("Fetching go.mod files", "repository", o.GitHubOrg+"/"+o.GitHubRepo, "git_ref", staged.Metadata().GitCommitRef)
+		fetch = gitHubGoModFetcher(ctx, o.GitHubOrg, o.GitHubRepo, staged.Metadata().GitCommitRef)
+	}
+
+	modFiles, err := loadGoModFiles(ctx, fetch, o.GoModFiles)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "cmrel-validate-licenses-")
+	if err != nil {
+		return fmt.Errorf("failed to create directory for downloading artifacts: %w", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	var violations []string
+	for _, a := range artifacts {
+		path := filepath.Join(dir, a.Metadata.Name)
+
+		slog.Info("Downloading artifact", "artifact", a.Metadata.Name)
+		if err := release.DownloadArtifact(ctx, &a, path); err != nil {
+			return fmt.Errorf("failed to download artifact %q: %w", a.Metadata.Name, err)
+		}
+
+		archive, err := licenses.ScanArchive(path)
+		if err != nil {
+			return fmt.Errorf("failed to scan artifact %q: %w", a.Metadata.Name, err)
+		}
+
+		violations = append(violations, validateArtifactLicenses(a.Metadata, archive, modFiles, o.IgnoredModules)...)
+
+		// artifacts can be large, so don't keep them around once checked
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+
+	if len(violations) > 0 {
+		slog.Info("validation failed! violations:")
+		for _, v := range violations {
+			slog.Info("  " + v)
+		}
+
+		return release.ErrValidationFailed
+	}
+
+	slog.Info("Licenses in all artifacts are complete", "count", len(artifacts))
+
+	return nil
+}
+
+// validateArtifactLicenses returns violations describing any license files
+// which are missing from archive, which is the content of the artifact a, or
+// which don't match the dependencies required by modFiles.
+func validateArtifactLicenses(a release.ArtifactMetadata, archive *licenses.Archive, modFiles []*modfile.File, ignoredModules []string) []string {
+	var violations []string
+
+	if !archive.HasLicense {
+		violations = append(violations, fmt.Sprintf("%s: missing %s file", a.Name, licenses.LicenseFileName))
+	}
+
+	// manifests contain no binaries, so have no third party modules to license
+	if a.Kind() == "manifests" {
+		return violations
+	}
+
+	if archive.Licenses == nil {
+		return append(violations, fmt.Sprintf("%s: missing %s file", a.Name, licenses.LicensesFileName))
+	}
+
+	packages, err := licenses.ParseLicenses(archive.Licenses)
+	if err != nil {
+		return append(violations, fmt.Sprintf("%s: %s", a.Name, err))
+	}
+
+	for _, v := range licenses.CompareToModules(packages, modFiles, ignoredModules) {
+		violations = append(violations, fmt.Sprintf("%s: %s", a.Name, v))
+	}
+
+	return violations
+}
+
+// goModFetcher returns the content of the go.mod file at the given path,
+// relative to the root of a repository.
+type goModFetcher func(ctx context.Context, path string) ([]byte, error)
+
+// localGoModFetcher returns a goModFetcher which reads go.mod files from the
+// repository checked out at root.
+func localGoModFetcher(root string) goModFetcher {
+	return func(_ context.Context, path string) ([]byte, error) {
+		return os.ReadFile(filepath.Join(root, path))
+	}
+}
+
+// gitHubGoModFetcher returns a goModFetcher which fetches go.mod files from
+// the given GitHub repository at ref.
+func gitHubGoModFetcher(ctx context.Context, org, repo, ref string) goModFetcher {
+	client := newGitHubClient(ctx)
+
+	return func(ctx context.Context, path string) ([]byte, error) {
+		r, _, err := client.Repositories.DownloadContents(ctx, org, repo, path, &github.RepositoryContentGetOptions{Ref: ref})
+		if err != nil {
+			return nil, err
+		}
+
+		defer r.Close()
+
+		return io.ReadAll(r)
+	}
+}
+
+// loadGoModFiles fetches and parses the go.mod files at the given paths.
+func loadGoModFiles(ctx context.Context, fetch goModFetcher, paths []string) ([]*modfile.File, error) {
+	var modFiles []*modfile.File
+	for _, path := range paths {
+		data, err := fetch(ctx, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %q: %w", path, err)
+		}
+
+		f, err := modfile.Parse(path, data, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q: %w", path, err)
+		}
+
+		modFiles = append(modFiles, f)
+	}
+
+	return modFiles, nil
+}
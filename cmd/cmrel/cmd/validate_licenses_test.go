@@ -0,0 +1,91 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cert-manager/release/pkg/release"
+	"github.com/cert-manager/release/pkg/release/licenses"
+)
+
+func TestValidateArtifactLicenses(t *testing.T) {
+	dir := t.TempDir()
+	writeGoMod(t, dir, "", `module github.com/cert-manager/cert-manager
+
+go 1.22.0
+
+require github.com/go-logr/logr v1.4.1
+`)
+
+	modFiles, err := loadGoModFiles(context.TODO(), localGoModFetcher(dir), []string{"go.mod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const complete = "github.com/cert-manager/cert-manager,https://example.com/LICENSE,Apache-2.0\ngithub.com/go-logr/logr,https://example.com/LICENSE,Apache-2.0\n"
+
+	server := release.ArtifactMetadata{Name: "cert-manager-server-linux-amd64.tar.gz", OS: "linux", Architecture: "amd64"}
+	manifests := release.ArtifactMetadata{Name: "cert-manager-manifests.tar.gz"}
+
+	tests := map[string]struct {
+		artifact release.ArtifactMetadata
+		archive  *licenses.Archive
+
+		expectedViolations int
+	}{
+		"complete server archive": {
+			artifact: server,
+			archive:  &licenses.Archive{HasLicense: true, Licenses: []byte(complete)},
+		},
+		"server archive missing both files": {
+			artifact:           server,
+			archive:            &licenses.Archive{},
+			expectedViolations: 2,
+		},
+		"server archive missing a direct dependency": {
+			artifact:           server,
+			archive:            &licenses.Archive{HasLicense: true, Licenses: []byte("github.com/cert-manager/cert-manager,https://example.com/LICENSE,Apache-2.0\n")},
+			expectedViolations: 1,
+		},
+		"manifests only need a LICENSE file": {
+			artifact: manifests,
+			archive:  &licenses.Archive{HasLicense: true},
+		},
+		"manifests missing LICENSE file": {
+			artifact:           manifests,
+			archive:            &licenses.Archive{},
+			expectedViolations: 1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			violations := validateArtifactLicenses(test.artifact, test.archive, modFiles, nil)
+			if len(violations) != test.expectedViolations {
+				t.Errorf("expected %d violations but got %d: %q", test.expectedViolations, len(violations), violations)
+			}
+		})
+	}
+}
+
+func TestLoadGoModFilesMissing(t *testing.T) {
+	if _, err := loadGoModFiles(context.TODO(), localGoModFetcher(t.TempDir()), []string{"go.mod"}); err == nil {
+		t.Errorf("expected an error loading a go.mod file which doesn't exist")
+	}
+}
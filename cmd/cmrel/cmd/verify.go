@@ -0,0 +1,451 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/cert-manager/release/pkg/gcp"
+	"github.com/cert-manager/release/pkg/release"
+	"github.com/cert-manager/release/pkg/release/helm"
+	"github.com/cert-manager/release/pkg/shell"
+	"github.com/cert-manager/release/pkg/sign"
+	"github.com/cert-manager/release/pkg/sign/cosign"
+)
+
+const (
+	verifyCommand         = "verify"
+	verifyDescription     = "Verify that a published release is complete and correct"
+	verifyLongDescription = `
+The 'verify' command checks the public artifacts of a published release against
+the staged release it was published from. It checks that:
+
+- the multi-arch manifest list of each image component has been pushed to the
+  published image repository and contains every expected platform
+- every published image and manifest list has a cosign signature which validates
+  against one of the trusted KMS keys
+- every manifest and binary uploaded to the GitHub release matches the staged
+  release byte-for-byte
+- every Helm chart in the release can be fetched from the chart repository and
+  rendered by 'helm template'
+
+Every check is run, and all failures are reported together. The GitHub release
+must have been published; draft releases cannot be verified.
+
+If a GITHUB_TOKEN environment variable is set, it is used to query GitHub.
+`
+)
+
+var (
+	verifyExample = fmt.Sprintf(`
+To verify the published v1.14.0 release:
+
+	%s %s --release-version=v1.14.0
+
+To verify the release against the staged release it was published from, when
+more than one build of that version has been staged:
+
+	%s %s --release-version=v1.14.0 --release-name=v1.14.0-614438aed00e1060870b273f2238794ef69b60ab
+`, rootCommand, verifyCommand, rootCommand, verifyCommand)
+)
+
+type verifyOptions struct {
+	// The name of the GCS bucket containing the staged release.
+	Bucket string
+
+	// ReleaseVersion is the version of the published release to verify.
+	ReleaseVersion string
+
+	// ReleaseName is the name of the staged release which was published. If
+	// empty, the only staged release with ReleaseVersion is used.
+	ReleaseName string
+
+	// PublishedImageRepository is the image repository the release images
+	// were pushed to.
+	PublishedImageRepository string
+
+	// PublishedGitHubOrg and PublishedGitHubRepo identify the GitHub
+	// repository the release was created in.
+	PublishedGitHubOrg  string
+	PublishedGitHubRepo string
+
+	// HelmChartRepo is the chart repository URL, or 'oci://' registry
+	// reference, Helm charts are fetched from.
+	HelmChartRepo string
+
+	// HelmPath is the path to the helm binary.
+	HelmPath string
+
+	// CosignPath is the path to the cosign binary.
+	CosignPath string
+
+	// TrustedKMSKeys are the GCP KMS keys which signatures are verified
+	// against. A signature is valid if it validates against any of them.
+	TrustedKMSKeys []string
+
+	// SkipSignatures, if true, skips verifying image signatures.
+	SkipSignatures bool
+
+	// SkipHelmChart, if true, skips verifying the published Helm charts.
+	SkipHelmChart bool
+}
+
+func (o *verifyOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
+	fs.StringVar(&o.Bucket, "bucket", release.DefaultBucketName, "The name of the GCS bucket containing the staged release.")
+	fs.StringVar(&o.ReleaseVersion, "release-version", "", "The version of the published release to verify, e.g. v1.14.0.")
+	fs.StringVar(&o.ReleaseName, "release-name", "", "Name of the staged release which was published. Defaults to the only staged release with the given version.")
+	fs.StringVar(&o.PublishedImageRepository, "published-image-repo", release.DefaultImageRepository, "The docker image repository the release images & manifest lists were pushed to.")
+	fs.StringVar(&o.PublishedGitHubOrg, "published-github-org", release.DefaultGitHubOrg, "The org of the repository the release was published to.")
+	fs.StringVar(&o.PublishedGitHubRepo, "published-github-repo", release.DefaultGitHubRepo, "The repo name in the provided org the release was published to.")
+	fs.StringVar(&o.HelmChartRepo, "helm-chart-repo", release.DefaultHelmChartRepositoryURL, "The chart repository URL (or 'oci://' registry reference) to fetch published Helm charts from.")
+	fs.StringVar(&o.HelmPath, "helm-path", "helm", "Full path to the helm binary. Defaults to searching in $PATH for a binary called 'helm'")
+	fs.StringVar(&o.CosignPath, "cosign-path", "cosign", "Full path to the cosign binary. Defaults to searching in $PATH for a binary called 'cosign'")
+	fs.StringSliceVar(&o.TrustedKMSKeys, "trusted-kms-keys", []string{defaultKMSKey}, "Comma-separated list of full names of GCP KMS keys to verify signatures against. A signature is valid if it validates against any of the keys.")
+	fs.BoolVar(&o.SkipSignatures, "skip-signatures", false, "Skip verifying the cosign signatures of published images and manifest lists.")
+	fs.BoolVar(&o.SkipHelmChart, "skip-helm-chart", false, "Skip verifying that the Helm charts were published to the chart repository.")
+	markRequired("release-version")
+}
+
+func (o *verifyOptions) print() {
+	slog.Info("Verify options",
+		"Bucket", o.Bucket,
+		"ReleaseVersion", o.ReleaseVersion,
+		"ReleaseName", o.ReleaseName,
+		"PublishedImageRepo", o.PublishedImageRepository,
+		"PublishedGitHubOrg", o.PublishedGitHubOrg,
+		"PublishedGitHubRepo", o.PublishedGitHubRepo,
+		"HelmChartRepo", o.HelmChartRepo,
+		"HelmPath", o.HelmPath,
+		"CosignPath", o.CosignPath,
+		"TrustedKMSKeys", o.TrustedKMSKeys,
+		"SkipSignatures", o.SkipSignatures,
+		"SkipHelmChart", o.SkipHelmChart,
+	)
+}
+
+func verifyCmd(rootOpts *rootOptions) *cobra.Command {
+	o := &verifyOptions{}
+	cmd := &cobra.Command{
+		Use:          verifyCommand,
+		Short:        verifyDescription,
+		Long:         verifyLongDescription,
+		Example:      verifyExample,
+		SilenceUsage: true,
+		PreRun: func(_ *cobra.Command, _ []string) {
+			o.print()
+			slog.Info("---")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runVerify(rootOpts, o)
+		},
+	}
+	o.AddFlags(cmd.Flags(), mustMarkRequired(cmd.MarkFlagRequired))
+	return cmd
+}
+
+func runVerify(_ *rootOptions, o *verifyOptions) error {
+	ctx := context.Background()
+
+	var trustedKeys []string
+	for _, raw := range o.TrustedKMSKeys {
+		key, err := sign.NewGCPKMSKey(raw)
+		if err != nil {
+			return err
+		}
+
+		trustedKeys = append(trustedKeys, key.CosignFormat())
+	}
+
+	if len(trustedKeys) == 0 && !o.SkipSignatures {
+		return fmt.Errorf("must set trusted-kms-keys or skip-signatures in order to verify signatures")
+	}
+
+	gcs, err := gcp.NewStorageClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create GCS client: %w", err)
+	}
+
+	bucket := release.NewBucket(gcs.Bucket(o.Bucket), release.DefaultBucketPathPrefix, release.BuildTypeRelease)
+	if err := bucket.CheckAccess(ctx); err != nil {
+		return err
+	}
+
+	staged, err := stagedReleaseForVersion(ctx, bucket, o.ReleaseVersion, o.ReleaseName)
+	if err != nil {
+		return err
+	}
+
+	rel, err := release.Unpack(ctx, staged)
+	if err != nil {
+		return fmt.Errorf("failed to unpack staged release: %w", err)
+	}
+
+	var failures []error
+
+	slog.Info("Verifying published manifest lists", "repository", o.PublishedImageRepository)
+	failures = append(failures, verifyPublishedManifestLists(ctx, remoteIndexPlatforms, o.PublishedImageRepository, rel)...)
+
+	if o.SkipSignatures {
+		slog.Info("Skipping verification of image signatures as skip-signatures is set")
+	} else {
+		slog.Info("Verifying signatures of published images and manifest lists")
+		if _, err := cosign.VerifyWithAnyKey(ctx, cosign.CLIVerifier(o.CosignPath), publishedImageRefs(o.PublishedImageRepository, rel), trustedKeys); err != nil {
+			failures = append(failures, err)
+		}
+	}
+
+	slog.Info("Verifying assets of GitHub release", "version", o.ReleaseVersion, "repository", o.PublishedGitHubOrg+"/"+o.PublishedGitHubRepo)
+	expectedAssets, err := expectedReleaseAssetChecksums(rel)
+	if err != nil {
+		return err
+	}
+
+	publishedAssets, err := githubReleaseAssetChecksums(ctx, o.PublishedGitHubOrg, o.PublishedGitHubRepo, o.ReleaseVersion)
+	if err != nil {
+		failures = append(failures, err)
+	} else {
+		failures = append(failures, compareReleaseAssetChecksums(expectedAssets, publishedAssets)...)
+	}
+
+	if o.SkipHelmChart {
+		slog.Info("Skipping verification of Helm charts as skip-helm-chart is set")
+	} else {
+		verifier := helm.NewChartVerifier(o.HelmPath, o.HelmChartRepo, func(ctx context.Context, cmd string, args ...string) error {
+			return shell.Command(ctx, "", cmd, args...)
+		})
+
+		for _, chart := range rel.Charts {
+			slog.Info("Verifying published Helm chart", "chart", chart.Name(), "version", chart.Version(), "repository", o.HelmChartRepo)
+			if err := verifier.Verify(ctx, chart.Name(), chart.Version()); err != nil {
+				failures = append(failures, err)
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		slog.Error("Verification of published release failed:", "version", o.ReleaseVersion)
+		for _, f := range failures {
+			slog.Info("  - " + f.Error())
+		}
+
+		return fmt.Errorf("published release %q failed verification: %w", o.ReleaseVersion, release.ErrValidationFailed)
+	}
+
+	slog.Info("Verification of published release succeeded!", "version", o.ReleaseVersion)
+
+	return nil
+}
+
+// stagedReleaseForVersion returns the named staged release, checking that it
+// has the given version. If name is empty, the only staged release with the
+// given version is returned.
+func stagedReleaseForVersion(ctx context.Context, bucket *release.Bucket, version, name string) (*release.Staged, error) {
+	if name != "" {
+		staged, err := bucket.GetRelease(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch release: %w", err)
+		}
+
+		if staged.Metadata().ReleaseVersion != version {
+			return nil, fmt.Errorf("staged release %q has version %q, not %q", name, staged.Metadata().ReleaseVersion, version)
+		}
+
+		return staged, nil
+	}
+
+	stagedReleases, err := bucket.ListReleases(ctx, version, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed listing staged releases: %w", err)
+	}
+
+	var matching []release.Staged
+	for _, rel := range stagedReleases {
+		if rel.Metadata().ReleaseVersion == version {
+			matching = append(matching, rel)
+		}
+	}
+
+	switch len(matching) {
+	case 0:
+		return nil, fmt.Errorf("no staged release found with version %q", version)
+	case 1:
+		return &matching[0], nil
+	default:
+		var names []string
+		for _, rel := range matching {
+			names = append(names, rel.Name())
+		}
+
+		return nil, fmt.Errorf("found %d staged releases with version %q, use --release-name to choose one of: %q", len(matching), version, names)
+	}
+}
+
+// indexPlatformsFetcher returns the platforms, formatted as "os/arch", of the
+// entries in the remote manifest list with the given reference.
+type indexPlatformsFetcher func(ctx context.Context, ref string) ([]string, error)
+
+// remoteIndexPlatforms fetches the manifest list ref from its registry, using
+// credentials from the default keychain.
+func remoteIndexPlatforms(ctx context.Context, ref string) ([]string, error) {
+	parsed, err := name.ParseReference(ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image reference %q: %w", ref, err)
+	}
+
+	index, err := remote.Index(parsed, remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest list %q: %w", ref, err)
+	}
+
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest list %q: %w", ref, err)
+	}
+
+	var platforms []string
+	for _, desc := range manifest.Manifests {
+		if desc.Platform == nil {
+			continue
+		}
+
+		platforms = append(platforms, desc.Platform.OS+"/"+desc.Platform.Architecture)
+	}
+
+	return platforms, nil
+}
+
+// verifyPublishedManifestLists checks that the manifest list of each image
+// component in rel has been published to repo, and that it contains an entry
+// for every platform the component was built for.
+func verifyPublishedManifestLists(ctx context.Context, fetch indexPlatformsFetcher, repo string, rel *release.Unpacked) []error {
+	var failures []error
+
+	for _, component := range sets.StringKeySet(rel.ComponentImageBundles).List() {
+		expected := sets.NewString()
+		for _, t := range rel.ComponentImageBundles[component] {
+			expected.Insert(t.OS() + "/" + t.Architecture())
+		}
+
+		manifestListName := buildManifestListName(repo, component, rel.ReleaseVersion)
+
+		platforms, err := fetch(ctx, manifestListName)
+		if err != nil {
+			failures = append(failures, err)
+			continue
+		}
+
+		if missing := expected.Difference(sets.NewString(platforms...)); missing.Len() > 0 {
+			failures = append(failures, fmt.Errorf("manifest list %q is missing platforms: %q", manifestListName, missing.List()))
+			continue
+		}
+
+		slog.Info("Manifest list contains all expected platforms", "manifest_list", manifestListName, "platforms", expected.Len())
+	}
+
+	return failures
+}
+
+// publishedImageRefs returns every image and manifest list which is pushed
+// and signed when rel is published to repo.
+func publishedImageRefs(repo string, rel *release.Unpacked) []string {
+	var refs []string
+	for _, component := range sets.StringKeySet(rel.ComponentImageBundles).List() {
+		for _, t := range rel.ComponentImageBundles[component] {
+			refs = append(refs, buildImageTag(repo, component, t.Architecture(), rel.ReleaseVersion))
+		}
+
+		refs = append(refs, buildManifestListName(repo, component, rel.ReleaseVersion))
+	}
+
+	return refs
+}
+
+// expectedReleaseAssetChecksums returns the SHA256 sum of each file in rel
+// which is uploaded to the GitHub release, keyed by asset name.
+func expectedReleaseAssetChecksums(rel *release.Unpacked) (map[string]string, error) {
+	return assetChecksums(gitHubReleaseAssetPaths(rel))
+}
+
+// githubReleaseAssetChecksums downloads every asset of the GitHub release
+// with the given tag and returns the SHA256 sum of each, keyed by asset name.
+func githubReleaseAssetChecksums(ctx context.Context, org, repo, tag string) (map[string]string, error) {
+	client := newGitHubClient(ctx)
+
+	githubRelease, _, err := client.Repositories.GetReleaseByTag(ctx, org, repo, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch GitHub release %q: %w", tag, err)
+	}
+
+	checksums := map[string]string{}
+	for _, asset := range githubRelease.Assets {
+		sum, err := func() (string, error) {
+			r, _, err := client.Repositories.DownloadReleaseAsset(ctx, org, repo, asset.GetID(), http.DefaultClient)
+			if err != nil {
+				return "", err
+			}
+
+			defer r.Close()
+
+			hasher := sha256.New()
+			if _, err := io.Copy(hasher, r); err != nil {
+				return "", err
+			}
+
+			return hex.EncodeToString(hasher.Sum(nil)), nil
+		}()
+		if err != nil {
+			return nil, fmt.Errorf("failed to download GitHub release asset %q: %w", asset.GetName(), err)
+		}
+
+		checksums[asset.GetName()] = sum
+	}
+
+	return checksums, nil
+}
+
+// compareReleaseAssetChecksums checks that every expected asset is present in
+// published with a matching checksum. Additional published assets are allowed.
+func compareReleaseAssetChecksums(expected, published map[string]string) []error {
+	var failures []error
+
+	for _, assetName := range sets.StringKeySet(expected).List() {
+		sum, ok := published[assetName]
+		switch {
+		case !ok:
+			failures = append(failures, fmt.Errorf("GitHub release is missing asset %q", assetName))
+		case sum != expected[assetName]:
+			failures = append(failures, fmt.Errorf("GitHub release asset %q has checksum %s but the staged release has %s", assetName, sum, expected[assetName]))
+		default:
+			slog.Info("GitHub release asset matches the staged release", "asset", assetName)
+		}
+	}
+
+	return failures
+}
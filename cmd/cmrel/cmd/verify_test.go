@@ -0,0 +1,194 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
+	"github.com/cert-manager/release/pkg/release"
+	"github.com/cert-manager/release/pkg/release/images"
+)
+
+func TestVerifyPublishedManifestLists(t *testing.T) {
+	ctx := context.Background()
+
+	server := httptest.NewServer(registry.New())
+	t.Cleanup(server.Close)
+
+	repo := strings.TrimPrefix(server.URL, "http://") + "/jetstack"
+
+	const version = "v1.15.0"
+
+	rel := &release.Unpacked{
+		ReleaseVersion:        version,
+		ComponentImageBundles: map[string][]*images.Tar{},
+	}
+
+	for _, component := range []string{"cainjector", "controller", "webhook"} {
+		for _, arch := range []string{"amd64", "arm64"} {
+			rel.ComponentImageBundles[component] = append(rel.ComponentImageBundles[component], fixtureImageTar(t, component, arch, version))
+		}
+	}
+
+	// controller is published correctly, webhook is missing an architecture
+	// and cainjector is never published
+	for component, tars := range map[string][]*images.Tar{
+		"controller": rel.ComponentImageBundles["controller"],
+		"webhook":    rel.ComponentImageBundles["webhook"][:1],
+	} {
+		idx, err := images.BuildIndex(tars)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pushFixture(t, buildManifestListName(repo, component, version), idx)
+	}
+
+	failures := verifyPublishedManifestLists(ctx, remoteIndexPlatforms, repo, rel)
+	if len(failures) != 2 {
+		t.Fatalf("expected 2 failures but got %d: %v", len(failures), failures)
+	}
+
+	if !strings.Contains(failures[0].Error(), "cert-manager-cainjector") {
+		t.Errorf("expected the unpublished cainjector manifest list to fail verification, got: %v", failures[0])
+	}
+
+	if !strings.Contains(failures[1].Error(), "cert-manager-webhook") || !strings.Contains(failures[1].Error(), "linux/arm64") {
+		t.Errorf("expected the webhook manifest list to be missing linux/arm64, got: %v", failures[1])
+	}
+}
+
+// fixtureImageTar writes a random linux image for the given component and
+// architecture to a tarball, as found in a staged release.
+func fixtureImageTar(t *testing.T, component, arch, version string) *images.Tar {
+	t.Helper()
+
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rawTag, err := name.NewTag("quay.io/jetstack/cert-manager-" + component + "-" + arch + ":" + version)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "image.tar")
+	if err := tarball.WriteToFile(path, rawTag, img); err != nil {
+		t.Fatal(err)
+	}
+
+	imageTar, err := images.NewTar(path, "linux", arch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return imageTar
+}
+
+func TestPublishedImageRefs(t *testing.T) {
+	rel := &release.Unpacked{
+		ReleaseVersion: "v1.15.0",
+		ComponentImageBundles: map[string][]*images.Tar{
+			"webhook":    {fixtureImageTar(t, "webhook", "amd64", "v1.15.0")},
+			"controller": {fixtureImageTar(t, "controller", "amd64", "v1.15.0"), fixtureImageTar(t, "controller", "arm64", "v1.15.0")},
+		},
+	}
+
+	expected := []string{
+		"quay.io/jetstack/cert-manager-controller-amd64:v1.15.0",
+		"quay.io/jetstack/cert-manager-controller-arm64:v1.15.0",
+		"quay.io/jetstack/cert-manager-controller:v1.15.0",
+		"quay.io/jetstack/cert-manager-webhook-amd64:v1.15.0",
+		"quay.io/jetstack/cert-manager-webhook:v1.15.0",
+	}
+
+	refs := publishedImageRefs("quay.io/jetstack", rel)
+	if strings.Join(refs, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected image refs:\ngot=%q\nexp=%q", refs, expected)
+	}
+}
+
+func TestCompareReleaseAssetChecksums(t *testing.T) {
+	expected := map[string]string{
+		"cert-manager.yaml":      "aaaa",
+		"cert-manager.crds.yaml": "bbbb",
+	}
+
+	tests := map[string]struct {
+		published    map[string]string
+		expectErrors []string
+	}{
+		"all assets match": {
+			published: map[string]string{
+				"cert-manager.yaml":      "aaaa",
+				"cert-manager.crds.yaml": "bbbb",
+			},
+		},
+		"additional assets are allowed": {
+			published: map[string]string{
+				"cert-manager.yaml":      "aaaa",
+				"cert-manager.crds.yaml": "bbbb",
+				"cert-manager.sbom.json": "cccc",
+			},
+		},
+		"missing asset": {
+			published: map[string]string{
+				"cert-manager.yaml": "aaaa",
+			},
+			expectErrors: []string{`missing asset "cert-manager.crds.yaml"`},
+		},
+		"mismatching checksum": {
+			published: map[string]string{
+				"cert-manager.yaml":      "ffff",
+				"cert-manager.crds.yaml": "bbbb",
+			},
+			expectErrors: []string{`asset "cert-manager.yaml" has checksum ffff`},
+		},
+		"no assets": {
+			published: map[string]string{},
+			expectErrors: []string{
+				`missing asset "cert-manager.crds.yaml"`,
+				`missing asset "cert-manager.yaml"`,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			failures := compareReleaseAssetChecksums(expected, test.published)
+			if len(failures) != len(test.expectErrors) {
+				t.Fatalf("expected %d failures but got %d: %v", len(test.expectErrors), len(failures), failures)
+			}
+
+			for i, f := range failures {
+				if !strings.Contains(f.Error(), test.expectErrors[i]) {
+					t.Errorf("expected failure to contain %q but got: %v", test.expectErrors[i], f)
+				}
+			}
+		})
+	}
+}
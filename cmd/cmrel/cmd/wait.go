@@ -0,0 +1,191 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"google.golang.org/api/cloudbuild/v1"
+
+	"github.com/cert-manager/release/pkg/gcb"
+	"github.com/cert-manager/release/pkg/gcp"
+	"github.com/cert-manager/release/pkg/notify"
+	"github.com/cert-manager/release/pkg/release"
+)
+
+const (
+	waitCommand         = "wait"
+	waitDescription     = "Wait for a previously submitted Google Cloud Build job to complete"
+	waitLongDescription = `
+The 'wait' command reattaches to a Google Cloud Build job which has already
+been submitted, such as one submitted by 'stage', 'makestage' or 'publish'
+with --wait=false, and waits for it to complete.
+
+The command fails if the build doesn't complete successfully.
+
+If --notify-slack-webhook is set, a message including the build log URL is
+posted when the job completes.
+`
+)
+
+var (
+	waitExample = fmt.Sprintf(`
+Submit a staging build without waiting for it, then reattach to it later:
+
+	%s stage --branch master --wait=false
+	%s %s --build-id <BUILD_ID>
+`, rootCommand, rootCommand, waitCommand)
+)
+
+type waitOptions struct {
+	// Project is the GCP project the GCB job is running in.
+	Project string
+
+	// BuildID is the ID of the GCB job to wait for.
+	BuildID string
+
+	// ExpectedBuildDuration is how long the build is expected to take. If
+	// the build is still running after this long, a warning is logged.
+	// Zero disables the warning.
+	ExpectedBuildDuration time.Duration
+
+	notifyOptions
+}
+
+func (o *waitOptions) AddFlags(fs *flag.FlagSet, markRequired func(string)) {
+	fs.StringVar(&o.Project, "project", release.DefaultReleaseProject, "The GCP project the GCB build job is running in.")
+	fs.StringVar(&o.BuildID, "build-id", "", "The ID of the GCB build job to wait for.")
+	fs.DurationVar(&o.ExpectedBuildDuration, "expected-build-duration", gcb.DefaultExpectedBuildDuration, "How long the build is expected to take, measured from when it was submitted. A warning is logged if the build is still running after this long. Set to 0 to disable the warning.")
+	o.notifyOptions.AddFlags(fs, markRequired)
+	markRequired("build-id")
+}
+
+func (o *waitOptions) print() {
+	slog.Info("Wait options", append([]any{
+		"Project", o.Project,
+		"BuildID", o.BuildID,
+		"ExpectedBuildDuration", o.ExpectedBuildDuration,
+	}, o.notifyOptions.attrs()...)...)
+}
+
+func waitCmd(rootOpts *rootOptions) *cobra.Command {
+	o := &waitOptions{}
+	cmd := &cobra.Command{
+		Use:          waitCommand,
+		Short:        waitDescription,
+		Long:         waitLongDescription,
+		Example:      waitExample,
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			o.print()
+			slog.Info("---")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWait(rootOpts, o)
+		},
+	}
+
+	o.AddFlags(cmd.Flags(), mustMarkRequired(cmd.MarkFlagRequired))
+
+	return cmd
+}
+
+func runWait(rootOpts *rootOptions, o *waitOptions) error {
+	if err := o.notifyOptions.validate(true); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	svc, err := gcp.NewCloudBuildService(ctx)
+	if err != nil {
+		return fmt.Errorf("error building google cloud build API client: %w", err)
+	}
+
+	build, err := svc.Projects.Builds.Get(o.Project, o.BuildID).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error fetching build %q: %w", o.BuildID, err)
+	}
+
+	event := buildNotifyEvent(build)
+
+	slog.Info("Waiting for build to complete, this may take a while...", "build_id", o.BuildID)
+	build, err = gcb.WaitForBuild(svc, o.Project, o.BuildID, o.ExpectedBuildDuration)
+	if err != nil {
+		o.notifyOptions.notify(ctx, event)
+		return fmt.Errorf("error waiting for cloud build to complete: %w", err)
+	}
+
+	event.Succeeded = build.Status == gcb.Success
+	o.notifyOptions.notify(ctx, event)
+
+	if build.Status != gcb.Success {
+		slog.Error("Build did not complete successfully. Check the log files for more information", "build_id", build.Id, "status", build.Status, "log_url", build.LogUrl)
+		return fmt.Errorf("build %q failed with status %q", build.Id, build.Status)
+	}
+
+	slog.Info("Build completed successfully", "build_id", build.Id)
+
+	return nil
+}
+
+// buildJobTagPrefix prefixes the tag naming the kind of job in each of the
+// builtin cloudbuild.yaml templates, e.g. "cert-manager-release-stage".
+const buildJobTagPrefix = "cert-manager-release-"
+
+// buildNotifyEvent returns the notification event for the completion of the
+// given build, describing it as well as can be determined from its tags and
+// substitutions. The event isn't marked as succeeded.
+func buildNotifyEvent(build *cloudbuild.Build) notify.Event {
+	job := "GCB"
+	for _, tag := range build.Tags {
+		if strings.HasPrefix(tag, buildJobTagPrefix) {
+			job = strings.TrimPrefix(tag, buildJobTagPrefix)
+			break
+		}
+	}
+
+	return notify.Event{
+		Job:            job,
+		ReleaseVersion: build.Substitutions["_RELEASE_VERSION"],
+		ReleaseName:    build.Substitutions["_RELEASE_NAME"],
+		LogURL:         build.LogUrl,
+	}
+}
+
+// logNotWaiting logs that the command is exiting without waiting for the
+// given build to complete, along with how to reattach to it later.
+func logNotWaiting(project string, build *cloudbuild.Build) {
+	slog.Info("Not waiting for build to complete. To wait for it later, run:")
+	slog.Info("  " + waitCommandLine(project, build.Id))
+}
+
+// waitCommandLine returns the cmrel command line which waits for the given
+// build to complete.
+func waitCommandLine(project string, buildID string) string {
+	cmd := fmt.Sprintf("%s %s --build-id=%s", rootCommand, waitCommand, buildID)
+	if project != release.DefaultReleaseProject {
+		cmd += fmt.Sprintf(" --project=%s", project)
+	}
+
+	return cmd
+}
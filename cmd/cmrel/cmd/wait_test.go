@@ -0,0 +1,103 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/cloudbuild/v1"
+
+	"github.com/cert-manager/release/pkg/notify"
+	"github.com/cert-manager/release/pkg/release"
+)
+
+func TestWaitCommandLine(t *testing.T) {
+	tests := map[string]struct {
+		project  string
+		buildID  string
+		expected string
+	}{
+		"default project": {
+			project:  release.DefaultReleaseProject,
+			buildID:  "abc-123",
+			expected: "cmrel wait --build-id=abc-123",
+		},
+		"other project": {
+			project:  "my-project",
+			buildID:  "abc-123",
+			expected: "cmrel wait --build-id=abc-123 --project=my-project",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := waitCommandLine(test.project, test.buildID); got != test.expected {
+				t.Errorf("expected %q but got %q", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestBuildNotifyEvent(t *testing.T) {
+	tests := map[string]struct {
+		build    *cloudbuild.Build
+		expected notify.Event
+	}{
+		"stage build": {
+			build: &cloudbuild.Build{
+				Tags:          []string{"cert-manager-release-stage", "bazel-6.0.0", "ref-abcdef", "v1.2.3-abcdef"},
+				Substitutions: map[string]string{"_RELEASE_VERSION": "v1.2.3"},
+				LogUrl:        "https://logs/stage",
+			},
+			expected: notify.Event{
+				Job:            "stage",
+				ReleaseVersion: "v1.2.3",
+				LogURL:         "https://logs/stage",
+			},
+		},
+		"publish build": {
+			build: &cloudbuild.Build{
+				Tags:          []string{"cert-manager-release-publish", "name-v1.2.3-abcdef"},
+				Substitutions: map[string]string{"_RELEASE_NAME": "v1.2.3-abcdef"},
+				LogUrl:        "https://logs/publish",
+			},
+			expected: notify.Event{
+				Job:         "publish",
+				ReleaseName: "v1.2.3-abcdef",
+				LogURL:      "https://logs/publish",
+			},
+		},
+		"build from another template": {
+			build: &cloudbuild.Build{
+				LogUrl: "https://logs/other",
+			},
+			expected: notify.Event{
+				Job:    "GCB",
+				LogURL: "https://logs/other",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := buildNotifyEvent(test.build); !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("unexpected event:\ngot=%+v\nexp=%+v", got, test.expected)
+			}
+		})
+	}
+}
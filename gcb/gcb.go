@@ -0,0 +1,27 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gcb holds the Google Cloud Build configs which cmrel submits, so that
+// they're built into the cmrel binary.
+package gcb
+
+import "embed"
+
+// Templates contains the cloudbuild.yaml file for each kind of build, at
+// "<name>/cloudbuild.yaml".
+//
+//go:embed */cloudbuild.yaml
+var Templates embed.FS
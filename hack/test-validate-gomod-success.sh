@@ -35,6 +35,8 @@ $CMREL --debug validate-gomod \
 	--path $BASE \
 	--no-dummy-modules example.com/nodummy \
 	--direct-import-modules example.com/directimport \
+	--denied-modules example.org/denieddependency \
+	--minimum-versions example.org/someotherdependency@v1.5.5 \
 	&>$logsfile && exitcode=$? || exitcode=$?
 
 if [[ $exitcode -ne 0 ]]; then
@@ -34,6 +34,8 @@ BASE="validate-gomod-test/broken"
 $CMREL --debug validate-gomod \
 	--path $BASE \
 	--no-dummy-modules example.com/nodummy \
+	--denied-modules example.org/denieddependency \
+	--minimum-versions example.org/someotherdependency@v1.5.5 \
 	&>$logsfile && exitcode=$? || exitcode=$?
 
 if [[ $exitcode -eq 0 ]]; then
@@ -74,6 +76,12 @@ checkline 'module "example.com/somebinary" requires the core module "example.com
 
 checkline 'module "example.com/nodummy" requires the core module "example.com/core". The core module should have a filesystem replacement'
 
+checkline 'module "example.com/startupapicheck" has toolchain "go1.21.4" but should have "" to match core go.mod file'
+
+checkline 'module "example.com/startupapicheck" requires "example.org/denieddependency", which is not allowed to be required'
+
+checkline 'module "example.com/startupapicheck" requires "example.org/someotherdependency" at version "v1.5.0" but the minimum allowed version is "v1.5.5"'
+
 if [[ $anyerrors -ne 0 ]]; then
 	echo "+++ at least one error was found with validate-gomod output"
 	echo "+++ full logs:"
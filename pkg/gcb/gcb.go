@@ -20,13 +20,17 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"io/fs"
+	"log/slog"
 	"os"
+	"path"
 	"time"
 
 	"google.golang.org/api/cloudbuild/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"sigs.k8s.io/yaml"
+
+	templates "github.com/cert-manager/release/gcb"
 )
 
 const (
@@ -34,6 +38,48 @@ const (
 	Failure = "FAILURE"
 )
 
+// finishedStatuses are the statuses of builds which have stopped running,
+// whether or not they succeeded.
+var finishedStatuses = []string{Success, Failure, "INTERNAL_ERROR", "TIMEOUT", "CANCELLED", "EXPIRED"}
+
+// Finished returns true if a build with the given status has stopped running,
+// including if it was cancelled or timed out.
+func Finished(status string) bool {
+	for _, s := range finishedStatuses {
+		if status == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Names of the cloudbuild.yaml templates built into cmrel.
+const (
+	TemplateStage        = "stage"
+	TemplateMakeStage    = "makestage"
+	TemplatePublish      = "publish"
+	TemplateBootstrapPGP = "bootstrap-pgp"
+)
+
+// LoadTemplate decodes the named cloudbuild.yaml template which is built into
+// cmrel. If override is set, the file at that path is loaded instead, which is
+// intended for testing changes to a template during development.
+func LoadTemplate(name string, override string) (*cloudbuild.Build, error) {
+	if override != "" {
+		slog.Debug("loading cloudbuild.yaml file", "path", override)
+		return LoadBuild(override)
+	}
+
+	slog.Debug("loading built-in cloudbuild.yaml template", "template", name)
+	f, err := fs.ReadFile(templates.Templates, path.Join(name, "cloudbuild.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("unknown cloudbuild.yaml template %q: %w", name, err)
+	}
+
+	return decodeBuild(f)
+}
+
 // LoadBuild will decode a cloudbuild.yaml file into a cloudbuild.Build
 // structure and return it.
 func LoadBuild(filename string) (*cloudbuild.Build, error) {
@@ -42,8 +88,12 @@ func LoadBuild(filename string) (*cloudbuild.Build, error) {
 		return nil, err
 	}
 
+	return decodeBuild(f)
+}
+
+func decodeBuild(data []byte) (*cloudbuild.Build, error) {
 	cb := cloudbuild.Build{}
-	if err := yaml.UnmarshalStrict(f, &cb); err != nil {
+	if err := yaml.UnmarshalStrict(data, &cb); err != nil {
 		return nil, err
 	}
 
@@ -59,7 +109,7 @@ func SubmitBuild(svc *cloudbuild.Service, projectID string, build *cloudbuild.Bu
 		return nil, err
 	}
 
-	log.Printf("DEBUG: decoding build operation metadata")
+	slog.Debug("decoding build operation metadata")
 	metadata := &cloudbuild.BuildOperationMetadata{}
 	if err := json.Unmarshal(op.Metadata, metadata); err != nil {
 		return nil, err
@@ -68,36 +118,103 @@ func SubmitBuild(svc *cloudbuild.Service, projectID string, build *cloudbuild.Bu
 	return metadata.Build, nil
 }
 
+// DefaultExpectedBuildDuration is how long a build is usually expected to
+// take before WaitForBuild logs a warning that it is still running.
+const DefaultExpectedBuildDuration = 90 * time.Minute
+
 // WaitForBuild will wait for the GCB Build with the given ID to complete
 // before returning a final copy of the Build resource.
-func WaitForBuild(svc *cloudbuild.Service, projectID string, id string) (*cloudbuild.Build, error) {
+// If expectedDuration is non-zero and the build is still running after that
+// long, a warning is logged once. The build is not cancelled. The duration is
+// measured from when the build was created, so that it remains accurate when
+// waiting for a build which was submitted earlier.
+func WaitForBuild(svc *cloudbuild.Service, projectID string, id string, expectedDuration time.Duration) (*cloudbuild.Build, error) {
+	w := &buildWaiter{
+		getBuild: func() (*cloudbuild.Build, error) {
+			return svc.Projects.Builds.Get(projectID, id).Do()
+		},
+		poll: func(condition wait.ConditionFunc) error {
+			return wait.PollInfinite(time.Second*5, condition)
+		},
+		now:              time.Now,
+		logger:           slog.Default(),
+		expectedDuration: expectedDuration,
+	}
+
+	return w.wait()
+}
+
+// buildWaiter polls a build until it completes. Its dependencies are
+// pluggable so that polling and the passing of time can be faked in tests.
+type buildWaiter struct {
+	// getBuild fetches an up-to-date copy of the build
+	getBuild func() (*cloudbuild.Build, error)
+
+	// poll calls the given condition until it returns true or an error
+	poll func(condition wait.ConditionFunc) error
+
+	// now returns the current time
+	now func() time.Time
+
+	// logger is used to log progress and warnings
+	logger *slog.Logger
+
+	// expectedDuration, if non-zero, is how long the build is expected to
+	// take before a warning is logged
+	expectedDuration time.Duration
+}
+
+func (w *buildWaiter) wait() (*cloudbuild.Build, error) {
 	var build *cloudbuild.Build
-	var err error
-	err = wait.PollInfinite(time.Second*5, func() (done bool, err error) {
-		build, err = svc.Projects.Builds.Get(projectID, id).Do()
+
+	start := w.now()
+	startKnown := false
+	warned := false
+
+	err := w.poll(func() (done bool, err error) {
+		build, err = w.getBuild()
 		if err != nil {
 			return false, err
 		}
 
-		// TODO: invert this to check for Pending instead
-		if build.Status == Success || build.Status == Failure {
+		if !startKnown {
+			if created, err := time.Parse(time.RFC3339Nano, build.CreateTime); err == nil {
+				start = created
+			}
+			startKnown = true
+		}
+
+		if Finished(build.Status) {
 			return true, nil
 		}
 
-		log.Printf("DEBUG: build %q still in progress...", build.Id)
+		elapsed := w.now().Sub(start)
+		if w.expectedDuration > 0 && elapsed > w.expectedDuration && !warned {
+			w.logger.Warn("build has been running for longer than expected; check the build logs in case something is wrong", "build", build.Id, "elapsed", elapsed.Round(time.Second), "expected", w.expectedDuration)
+			warned = true
+		}
+
+		w.logger.Debug("build still in progress", "build", build.Id)
 		return false, nil
 	})
 	if err != nil {
 		return nil, err
 	}
-	return build, err
+
+	return build, nil
 }
 
 // ListBuildsWithTag will list all Builds that have the given tag value set,
 // paginating through any responses from the GCB API that use pagination.
 func ListBuildsWithTag(ctx context.Context, svc *cloudbuild.Service, projectID string, tag string) ([]*cloudbuild.Build, error) {
+	return listBuilds(ctx, svc, projectID, fmt.Sprintf("tags=%q", tag))
+}
+
+// listBuilds will list all Builds matching the given GCB API filter
+// expression, paginating through any responses that use pagination.
+func listBuilds(ctx context.Context, svc *cloudbuild.Service, projectID string, filter string) ([]*cloudbuild.Build, error) {
 	var builds []*cloudbuild.Build
-	if err := svc.Projects.Builds.List(projectID).Filter("tags="+tag).Pages(ctx, func(resp *cloudbuild.ListBuildsResponse) error {
+	if err := svc.Projects.Builds.List(projectID).Filter(filter).Pages(ctx, func(resp *cloudbuild.ListBuildsResponse) error {
 		builds = append(builds, resp.Builds...)
 		return nil
 	}); err != nil {
@@ -107,16 +224,66 @@ func ListBuildsWithTag(ctx context.Context, svc *cloudbuild.Service, projectID s
 	return builds, nil
 }
 
+// CancelBuild will cancel the GCB Build with the given ID, returning the
+// cancelled Build. An error is returned if the build has already finished.
+func CancelBuild(ctx context.Context, svc *cloudbuild.Service, projectID string, id string) (*cloudbuild.Build, error) {
+	build, err := svc.Projects.Builds.Get(projectID, id).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	if Finished(build.Status) {
+		return nil, fmt.Errorf("build %q has already finished with status %q", id, build.Status)
+	}
+
+	return svc.Projects.Builds.Cancel(projectID, id, &cloudbuild.CancelBuildRequest{}).Context(ctx).Do()
+}
+
 // TagForReleaseVersion will return a tag that should be added to Builds for
-// a given releaseVersion/gitRef pair.
+// a given releaseVersion/gitRef pair. An empty releaseVersion denotes a devel
+// build.
 // This is used to discover existing GCB builds for a release when running the
 // stage command.
 func TagForReleaseVersion(releaseVersion, gitRef string) string {
+	if releaseVersion == "" {
+		releaseVersion = "devel"
+	}
+
 	return fmt.Sprintf("%s-%s", releaseVersion, gitRef)
 }
 
 // NewestGreenBuild will find the newest passing release build for a given
-// release version and git commit ref.
-func NewestGreenBuild(ctx context.Context, svc *cloudbuild.Service, projectID, releaseVersion, gitRef string) *cloudbuild.Build {
-	return nil
+// release version and git commit ref, as tagged using TagForReleaseVersion.
+// If there is no such build, nil is returned.
+func NewestGreenBuild(ctx context.Context, svc *cloudbuild.Service, projectID, releaseVersion, gitRef string) (*cloudbuild.Build, error) {
+	filter := fmt.Sprintf("tags=%q AND status=%q", TagForReleaseVersion(releaseVersion, gitRef), Success)
+
+	builds, err := listBuilds(ctx, svc, projectID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return newestBuild(builds), nil
+}
+
+// newestBuild returns the most recently created of the given builds, or nil
+// if there are none. Builds with an unparseable creation time are treated as
+// older than any other build.
+func newestBuild(builds []*cloudbuild.Build) *cloudbuild.Build {
+	var newest *cloudbuild.Build
+	var newestCreated time.Time
+
+	for _, b := range builds {
+		created, err := time.Parse(time.RFC3339Nano, b.CreateTime)
+		if err != nil {
+			created = time.Time{}
+		}
+
+		if newest == nil || created.After(newestCreated) {
+			newest = b
+			newestCreated = created
+		}
+	}
+
+	return newest
 }
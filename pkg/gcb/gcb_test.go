@@ -0,0 +1,357 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/cloudbuild/v1"
+	"google.golang.org/api/option"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestBuildWaiterExpectedDuration(t *testing.T) {
+	const pollInterval = 10 * time.Minute
+
+	tests := map[string]struct {
+		// statuses is the status of the build returned by each poll
+		statuses         []string
+		createTime       string
+		expectedDuration time.Duration
+		expectWarnings   int
+	}{
+		"build finishing within the expected duration doesn't warn": {
+			statuses:         []string{"WORKING", "WORKING", Success},
+			expectedDuration: 30 * time.Minute,
+			expectWarnings:   0,
+		},
+		"build running longer than the expected duration warns once": {
+			statuses:         []string{"WORKING", "WORKING", "WORKING", "WORKING", "WORKING", Success},
+			expectedDuration: 25 * time.Minute,
+			expectWarnings:   1,
+		},
+		"failed build running longer than the expected duration warns once": {
+			statuses:         []string{"WORKING", "WORKING", "WORKING", "WORKING", Failure},
+			expectedDuration: 15 * time.Minute,
+			expectWarnings:   1,
+		},
+		"cancelled build stops waiting": {
+			statuses:         []string{"WORKING", "CANCELLED"},
+			expectedDuration: 30 * time.Minute,
+			expectWarnings:   0,
+		},
+		"timed out build stops waiting": {
+			statuses:         []string{"QUEUED", "WORKING", "TIMEOUT"},
+			expectedDuration: 30 * time.Minute,
+			expectWarnings:   0,
+		},
+		"build created before waiting started is measured from its creation": {
+			statuses:         []string{"WORKING", Success},
+			createTime:       "2020-12-31T23:00:00Z",
+			expectedDuration: 30 * time.Minute,
+			expectWarnings:   1,
+		},
+		"zero expected duration never warns": {
+			statuses:         []string{"WORKING", "WORKING", "WORKING", "WORKING", "WORKING", Success},
+			expectedDuration: 0,
+			expectWarnings:   0,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+			polls := 0
+			logs := &bytes.Buffer{}
+
+			w := &buildWaiter{
+				getBuild: func() (*cloudbuild.Build, error) {
+					status := test.statuses[polls]
+					polls++
+					return &cloudbuild.Build{Id: "build-id", Status: status, CreateTime: test.createTime}, nil
+				},
+				// fake poller which advances the clock by pollInterval
+				// between each call to the condition
+				poll: func(condition wait.ConditionFunc) error {
+					for {
+						now = now.Add(pollInterval)
+
+						done, err := condition()
+						if err != nil {
+							return err
+						}
+
+						if done {
+							return nil
+						}
+					}
+				},
+				now: func() time.Time {
+					return now
+				},
+				logger:           slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug})),
+				expectedDuration: test.expectedDuration,
+			}
+
+			build, err := w.wait()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if build.Status != test.statuses[len(test.statuses)-1] {
+				t.Errorf("expected final build status %q but got %q", test.statuses[len(test.statuses)-1], build.Status)
+			}
+
+			warnings := strings.Count(logs.String(), "level=WARN")
+			if warnings != test.expectWarnings {
+				t.Errorf("expected %d warnings but got %d: %q", test.expectWarnings, warnings, logs.String())
+			}
+		})
+	}
+}
+
+func TestBuildWaiterError(t *testing.T) {
+	w := &buildWaiter{
+		getBuild: func() (*cloudbuild.Build, error) {
+			return nil, fmt.Errorf("fake error")
+		},
+		poll: func(condition wait.ConditionFunc) error {
+			_, err := condition()
+			return err
+		},
+		now:    time.Now,
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	if _, err := w.wait(); err == nil {
+		t.Errorf("expected an error from a failing build lookup")
+	}
+}
+
+func TestCancelBuild(t *testing.T) {
+	tests := map[string]struct {
+		status          string
+		expectErr       bool
+		expectCancelled bool
+	}{
+		"working build is cancelled": {
+			status:          "WORKING",
+			expectCancelled: true,
+		},
+		"queued build is cancelled": {
+			status:          "QUEUED",
+			expectCancelled: true,
+		},
+		"successful build can't be cancelled": {
+			status:    Success,
+			expectErr: true,
+		},
+		"cancelled build can't be cancelled again": {
+			status:    "CANCELLED",
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			cancelled := false
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := test.status
+
+				switch {
+				case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/projects/test-project/builds/build-id"):
+				case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/projects/test-project/builds/build-id:cancel"):
+					cancelled = true
+					status = "CANCELLED"
+				default:
+					http.Error(w, "unexpected request "+r.Method+" "+r.URL.Path, http.StatusNotFound)
+					return
+				}
+
+				if err := json.NewEncoder(w).Encode(&cloudbuild.Build{Id: "build-id", Status: status}); err != nil {
+					t.Error(err)
+				}
+			}))
+			defer server.Close()
+
+			svc, err := cloudbuild.NewService(ctx, option.WithEndpoint(server.URL), option.WithoutAuthentication())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			build, err := CancelBuild(ctx, svc, "test-project", "build-id")
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if cancelled != test.expectCancelled {
+				t.Errorf("expectCancelled=%v but got cancelled=%v", test.expectCancelled, cancelled)
+			}
+
+			if test.expectErr {
+				return
+			}
+
+			if build.Status != "CANCELLED" {
+				t.Errorf("expected cancelled build to be returned but got status %q", build.Status)
+			}
+		})
+	}
+}
+
+func TestNewestGreenBuild(t *testing.T) {
+	tests := map[string]struct {
+		builds     []*cloudbuild.Build
+		expectedID string
+	}{
+		"no builds": {
+			builds:     nil,
+			expectedID: "",
+		},
+		"newest build is returned regardless of order": {
+			builds: []*cloudbuild.Build{
+				{Id: "older", CreateTime: "2021-01-01T00:00:00Z"},
+				{Id: "newest", CreateTime: "2021-01-03T00:00:00.5Z"},
+				{Id: "middle", CreateTime: "2021-01-02T00:00:00Z"},
+			},
+			expectedID: "newest",
+		},
+		"build with an unparseable creation time is oldest": {
+			builds: []*cloudbuild.Build{
+				{Id: "unknown", CreateTime: "yesterday"},
+				{Id: "known", CreateTime: "2021-01-01T00:00:00Z"},
+			},
+			expectedID: "known",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				expectedFilter := `tags="v1.2.3-abcdef" AND status="SUCCESS"`
+				if filter := r.URL.Query().Get("filter"); filter != expectedFilter {
+					http.Error(w, "unexpected filter "+filter, http.StatusBadRequest)
+					return
+				}
+
+				if err := json.NewEncoder(w).Encode(&cloudbuild.ListBuildsResponse{Builds: test.builds}); err != nil {
+					t.Error(err)
+				}
+			}))
+			defer server.Close()
+
+			svc, err := cloudbuild.NewService(ctx, option.WithEndpoint(server.URL), option.WithoutAuthentication())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			build, err := NewestGreenBuild(ctx, svc, "test-project", "v1.2.3", "abcdef")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			id := ""
+			if build != nil {
+				id = build.Id
+			}
+
+			if id != test.expectedID {
+				t.Errorf("expected build %q but got %q", test.expectedID, id)
+			}
+		})
+	}
+}
+
+func TestTagForReleaseVersion(t *testing.T) {
+	if tag := TagForReleaseVersion("v1.2.3", "abcdef"); tag != "v1.2.3-abcdef" {
+		t.Errorf("unexpected tag %q for a release build", tag)
+	}
+
+	if tag := TagForReleaseVersion("", "abcdef"); tag != "devel-abcdef" {
+		t.Errorf("unexpected tag %q for a devel build", tag)
+	}
+}
+
+func TestLoadTemplate(t *testing.T) {
+	override := filepath.Join(t.TempDir(), "cloudbuild.yaml")
+	if err := os.WriteFile(override, []byte("timeout: 60s\nsubstitutions:\n  _OVERRIDE: \"true\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		name           string
+		override       string
+		expectOverride bool
+		expectErr      bool
+	}{
+		"stage":         {name: TemplateStage},
+		"makestage":     {name: TemplateMakeStage},
+		"publish":       {name: TemplatePublish},
+		"bootstrap-pgp": {name: TemplateBootstrapPGP},
+		"override is loaded instead of the template": {
+			name:           TemplateStage,
+			override:       override,
+			expectOverride: true,
+		},
+		"missing override file": {
+			name:      TemplateStage,
+			override:  filepath.Join(t.TempDir(), "missing.yaml"),
+			expectErr: true,
+		},
+		"unknown template": {
+			name:      "notatemplate",
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			build, err := LoadTemplate(test.name, test.override)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if _, ok := build.Substitutions["_OVERRIDE"]; ok != test.expectOverride {
+				t.Errorf("expectOverride=%v but got substitutions %v", test.expectOverride, build.Substitutions)
+			}
+
+			if !test.expectOverride && len(build.Steps) == 0 {
+				t.Errorf("expected built-in template %q to have steps", test.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gcp creates the Google Cloud API clients used by cmrel. Clients use
+// Application Default Credentials unless a credentials file is configured
+// with Configure, such as a workload identity federation configuration which
+// lets cmrel authenticate using an OIDC token from GitHub Actions.
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"cloud.google.com/go/storage"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/cloudbuild/v1"
+	"google.golang.org/api/cloudkms/v1"
+	"google.golang.org/api/option"
+)
+
+// CredentialsFileEnv is the environment variable from which Application
+// Default Credentials are loaded. It's set by Configure so that subprocesses,
+// such as cosign signing with a KMS key, use the same credentials as cmrel.
+const CredentialsFileEnv = "GOOGLE_APPLICATION_CREDENTIALS"
+
+var (
+	mu              sync.Mutex
+	credentialsFile string
+)
+
+// Configure sets the credentials file used by every client created by this
+// package. The file may contain a service account key or an external account
+// configuration for workload identity federation. If path is empty,
+// Application Default Credentials are used.
+// The file is checked to contain valid credentials before it's used, but no
+// token is requested until a client is used.
+func Configure(ctx context.Context, path string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if path == "" {
+		credentialsFile = ""
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read GCP credentials file: %w", err)
+	}
+
+	if _, err := google.CredentialsFromJSON(ctx, data, cloudkms.CloudPlatformScope); err != nil {
+		return fmt.Errorf("invalid GCP credentials file %q: %w", path, err)
+	}
+
+	if err := os.Setenv(CredentialsFileEnv, path); err != nil {
+		return fmt.Errorf("failed to set %s: %w", CredentialsFileEnv, err)
+	}
+
+	credentialsFile = path
+
+	return nil
+}
+
+// ClientOptions returns the options which configure a Google Cloud API client
+// to use the configured credentials.
+func ClientOptions() []option.ClientOption {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if credentialsFile == "" {
+		return nil
+	}
+
+	return []option.ClientOption{option.WithCredentialsFile(credentialsFile)}
+}
+
+// NewStorageClient returns a GCS client using the configured credentials.
+func NewStorageClient(ctx context.Context) (*storage.Client, error) {
+	return storage.NewClient(ctx, ClientOptions()...)
+}
+
+// NewCloudBuildService returns a Cloud Build client using the configured
+// credentials.
+func NewCloudBuildService(ctx context.Context) (*cloudbuild.Service, error) {
+	return cloudbuild.NewService(ctx, ClientOptions()...)
+}
+
+// NewKMSService returns a Cloud KMS client using the configured credentials.
+func NewKMSService(ctx context.Context) (*cloudkms.Service, error) {
+	return cloudkms.NewService(ctx, append(ClientOptions(), option.WithScopes(cloudkms.CloudPlatformScope))...)
+}
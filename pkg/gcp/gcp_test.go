@@ -0,0 +1,104 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// workloadIdentityConfig is a workload identity federation configuration as
+// created by 'gcloud iam workload-identity-pools create-cred-config'.
+const workloadIdentityConfig = `{
+  "type": "external_account",
+  "audience": "//iam.googleapis.com/projects/123456/locations/global/workloadIdentityPools/github/providers/github",
+  "subject_token_type": "urn:ietf:params:oauth:token-type:jwt",
+  "token_url": "https://sts.googleapis.com/v1/token",
+  "credential_source": {
+    "file": "/var/run/secrets/token"
+  }
+}`
+
+func TestConfigure(t *testing.T) {
+	tests := map[string]struct {
+		contents string
+		missing  bool
+
+		expectErr bool
+	}{
+		"workload identity federation config": {
+			contents: workloadIdentityConfig,
+		},
+		"unknown credentials type": {
+			contents:  `{"type": "unknown"}`,
+			expectErr: true,
+		},
+		"invalid JSON": {
+			contents:  `not json`,
+			expectErr: true,
+		},
+		"missing file": {
+			missing:   true,
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(CredentialsFileEnv, "")
+			t.Cleanup(func() {
+				if err := Configure(context.TODO(), ""); err != nil {
+					t.Fatal(err)
+				}
+			})
+
+			path := filepath.Join(t.TempDir(), "credentials.json")
+			if !test.missing {
+				if err := os.WriteFile(path, []byte(test.contents), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := Configure(context.TODO(), path)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if test.expectErr {
+				if len(ClientOptions()) != 0 {
+					t.Errorf("expected no client options after failing to configure credentials")
+				}
+
+				if os.Getenv(CredentialsFileEnv) != "" {
+					t.Errorf("expected %s not to be set after failing to configure credentials", CredentialsFileEnv)
+				}
+
+				return
+			}
+
+			if len(ClientOptions()) != 1 {
+				t.Errorf("expected a client option for the credentials file, got %d options", len(ClientOptions()))
+			}
+
+			if got := os.Getenv(CredentialsFileEnv); got != path {
+				t.Errorf("expected %s=%q for subprocesses but got %q", CredentialsFileEnv, path, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package logging configures the leveled, structured logger used by cmrel.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// FormatText logs human-readable lines, similar to the standard library
+	// log package but with the level of each record included.
+	FormatText = "text"
+
+	// FormatJSON logs one JSON object per record, for consumption in CI.
+	FormatJSON = "json"
+)
+
+// Formats lists every supported log format.
+var Formats = []string{FormatText, FormatJSON}
+
+// NewHandler returns a slog.Handler which writes records in the given format
+// to w. Debug records are only written if debug is true.
+func NewHandler(w io.Writer, format string, debug bool) (slog.Handler, error) {
+	level := slog.LevelInfo
+	if debug {
+		level = slog.LevelDebug
+	}
+
+	switch format {
+	case FormatText:
+		return &textHandler{mu: &sync.Mutex{}, w: w, level: level}, nil
+
+	case FormatJSON:
+		return slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}), nil
+
+	default:
+		return nil, fmt.Errorf("unknown log format %q, must be one of %q", format, Formats)
+	}
+}
+
+// Configure sets the default slog logger to one using a handler from
+// NewHandler. Output from the standard library log package is also sent to
+// this handler, at info level.
+func Configure(w io.Writer, format string, debug bool) error {
+	h, err := NewHandler(w, format, debug)
+	if err != nil {
+		return err
+	}
+
+	slog.SetDefault(slog.New(h))
+
+	return nil
+}
+
+// textHandler writes each record as a single line of the form:
+//
+//	2006/01/02 15:04:05 LEVEL message key=value...
+type textHandler struct {
+	mu    *sync.Mutex
+	w     io.Writer
+	level slog.Level
+
+	// attrs are preformatted attributes added using WithAttrs
+	attrs string
+
+	// prefix is prepended to the keys of attributes, for groups added
+	// using WithGroup
+	prefix string
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	b := &strings.Builder{}
+
+	if !r.Time.IsZero() {
+		b.WriteString(r.Time.Format("2006/01/02 15:04:05"))
+		b.WriteByte(' ')
+	}
+
+	b.WriteString(r.Level.String())
+	b.WriteByte(' ')
+	b.WriteString(r.Message)
+	b.WriteString(h.attrs)
+
+	r.Attrs(func(a slog.Attr) bool {
+		writeAttr(b, h.prefix, a)
+		return true
+	})
+
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	b := &strings.Builder{}
+	b.WriteString(h.attrs)
+
+	for _, a := range attrs {
+		writeAttr(b, h.prefix, a)
+	}
+
+	h2 := *h
+	h2.attrs = b.String()
+	return &h2
+}
+
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// writeAttr writes a as " key=value", flattening groups into dotted keys.
+// Empty attributes are ignored, as required of slog handlers.
+func writeAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix = prefix + a.Key + "."
+		}
+
+		for _, ga := range a.Value.Group() {
+			writeAttr(b, prefix, ga)
+		}
+
+		return
+	}
+
+	b.WriteByte(' ')
+	b.WriteString(prefix)
+	b.WriteString(a.Key)
+	b.WriteByte('=')
+
+	s := a.Value.String()
+	if a.Value.Kind() == slog.KindTime {
+		s = a.Value.Time().Format(time.RFC3339)
+	}
+
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		s = fmt.Sprintf("%q", s)
+	}
+
+	b.WriteString(s)
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNewHandler(t *testing.T) {
+	tests := map[string]struct {
+		format    string
+		debug     bool
+		log       func(l *slog.Logger)
+		expected  []string
+		expectErr bool
+	}{
+		"text info with attributes": {
+			format: FormatText,
+			log: func(l *slog.Logger) {
+				l.Info("pushed image", "image", "quay.io/jetstack/cert-manager-controller:v1.2.3", "attempt", 2)
+			},
+			expected: []string{"INFO pushed image image=quay.io/jetstack/cert-manager-controller:v1.2.3 attempt=2"},
+		},
+		"text quotes values containing spaces": {
+			format: FormatText,
+			log: func(l *slog.Logger) {
+				l.Warn("retrying", "error", "connection reset by peer", "empty", "")
+			},
+			expected: []string{`WARN retrying error="connection reset by peer" empty=""`},
+		},
+		"text groups and preset attributes are prefixed": {
+			format: FormatText,
+			log: func(l *slog.Logger) {
+				l.With("release", "v1.2.3").WithGroup("build").Info("complete", "id", "abc")
+			},
+			expected: []string{"INFO complete release=v1.2.3 build.id=abc"},
+		},
+		"debug records are dropped without debug": {
+			format: FormatText,
+			log: func(l *slog.Logger) {
+				l.Debug("hidden")
+				l.Info("shown")
+			},
+			expected: []string{"INFO shown"},
+		},
+		"debug records are written with debug": {
+			format: FormatText,
+			debug:  true,
+			log: func(l *slog.Logger) {
+				l.Debug("shown")
+			},
+			expected: []string{"DEBUG shown"},
+		},
+		"json": {
+			format: FormatJSON,
+			log: func(l *slog.Logger) {
+				l.Error("failed", "count", 3)
+			},
+			expected: []string{`"level":"ERROR","msg":"failed","count":3}`},
+		},
+		"unknown format": {
+			format:    "yaml",
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+
+			h, err := NewHandler(buf, test.format, test.debug)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if test.expectErr {
+				return
+			}
+
+			test.log(slog.New(h))
+
+			lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			if len(lines) != len(test.expected) {
+				t.Fatalf("expected %d lines but got %d: %q", len(test.expected), len(lines), lines)
+			}
+
+			for i, line := range lines {
+				if !strings.HasSuffix(line, test.expected[i]) {
+					t.Errorf("expected line %d to end with %q but got %q", i, test.expected[i], line)
+				}
+
+				if test.format == FormatJSON && !json.Valid([]byte(line)) {
+					t.Errorf("expected line %d to be valid JSON but got %q", i, line)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package notify posts messages about the outcome of release jobs to chat
+// webhooks, so that the person running a release doesn't have to watch a
+// terminal until it completes.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	// OnSuccess sends notifications when a job succeeds.
+	OnSuccess = "success"
+
+	// OnFailure sends notifications when a job fails.
+	OnFailure = "failure"
+)
+
+// Outcomes lists every outcome which can be notified on.
+var Outcomes = []string{OnSuccess, OnFailure}
+
+// Event describes the completion of a release job.
+type Event struct {
+	// Job is the kind of job which completed, such as "stage" or "publish".
+	Job string
+
+	// ReleaseVersion is the version of the release the job was for. It may
+	// be empty for development builds.
+	ReleaseVersion string
+
+	// ReleaseName is the name of the staged release the job was for, if
+	// known.
+	ReleaseName string
+
+	// Succeeded is true if the job completed successfully.
+	Succeeded bool
+
+	// LogURL is the URL of the job's build logs.
+	LogURL string
+
+	// ManualActions lists any manual actions the person running the release
+	// must take after the job, one per line.
+	ManualActions string
+}
+
+// Outcome returns OnSuccess or OnFailure, depending on whether the job
+// succeeded.
+func (e Event) Outcome() string {
+	if e.Succeeded {
+		return OnSuccess
+	}
+
+	return OnFailure
+}
+
+// Message returns the text posted to a webhook for e.
+func (e Event) Message() string {
+	var b strings.Builder
+
+	version := e.ReleaseVersion
+	if version == "" {
+		version = "development build"
+	}
+
+	if e.Succeeded {
+		fmt.Fprintf(&b, ":white_check_mark: cert-manager %s job for %s succeeded", e.Job, version)
+	} else {
+		fmt.Fprintf(&b, ":x: cert-manager %s job for %s failed", e.Job, version)
+	}
+
+	if e.ReleaseName != "" {
+		fmt.Fprintf(&b, "\nRelease name: %s", e.ReleaseName)
+	}
+
+	if e.LogURL != "" {
+		fmt.Fprintf(&b, "\nBuild logs: %s", e.LogURL)
+	}
+
+	if actions := strings.TrimSpace(e.ManualActions); actions != "" {
+		fmt.Fprintf(&b, "\nManual actions required:\n%s", actions)
+	}
+
+	return b.String()
+}
+
+// ParseOutcomes validates a list of outcomes to notify on, as given to the
+// --notify-on flag, returning them as a set.
+func ParseOutcomes(outcomes []string) (sets.String, error) {
+	parsed := sets.NewString()
+	for _, outcome := range outcomes {
+		outcome = strings.TrimSpace(outcome)
+		if outcome == "" {
+			continue
+		}
+
+		if !sets.NewString(Outcomes...).Has(outcome) {
+			return nil, fmt.Errorf("unknown notification outcome %q, must be one of %q", outcome, Outcomes)
+		}
+
+		parsed.Insert(outcome)
+	}
+
+	return parsed, nil
+}
+
+// SlackWebhook posts notifications to a Slack incoming webhook. Any webhook
+// accepting a JSON body with a "text" field can be used.
+type SlackWebhook struct {
+	// URL is the URL of the webhook.
+	URL string
+
+	// Client is the HTTP client used to post to the webhook. If nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+}
+
+// Notify posts the message for e to the webhook.
+func (w *SlackWebhook) Notify(ctx context.Context, e Event) error {
+	body, err := json.Marshal(struct {
+		Text string `json:"text"`
+	}{Text: e.Message()})
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create notification request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to post notification: webhook responded with status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseOutcomes(t *testing.T) {
+	tests := map[string]struct {
+		outcomes []string
+
+		expected  []string
+		expectErr bool
+	}{
+		"both outcomes": {
+			outcomes: []string{"success", "failure"},
+			expected: []string{"failure", "success"},
+		},
+		"failure only": {
+			outcomes: []string{" failure "},
+			expected: []string{"failure"},
+		},
+		"empty entries are ignored": {
+			outcomes: []string{""},
+			expected: []string{},
+		},
+		"unknown outcome": {
+			outcomes:  []string{"success", "cancelled"},
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			outcomes, err := ParseOutcomes(test.outcomes)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if test.expectErr {
+				return
+			}
+
+			if got := strings.Join(outcomes.List(), ","); got != strings.Join(test.expected, ",") {
+				t.Errorf("expected outcomes %q but got %q", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestSlackWebhookNotify(t *testing.T) {
+	tests := map[string]struct {
+		status int
+		event  Event
+
+		expectedLines []string
+		expectErr     bool
+	}{
+		"successful publish with manual actions": {
+			status: http.StatusOK,
+			event: Event{
+				Job:            "publish",
+				ReleaseVersion: "v1.15.0",
+				Succeeded:      true,
+				LogURL:         "https://console.cloud.google.com/cloud-build/builds/abc",
+				ManualActions:  "* Review and merge the GitHub PR containing the Helm charts: https://example.com/pr/1\n",
+			},
+			expectedLines: []string{
+				"cert-manager publish job for v1.15.0 succeeded",
+				"Build logs: https://console.cloud.google.com/cloud-build/builds/abc",
+				"* Review and merge the GitHub PR containing the Helm charts: https://example.com/pr/1",
+			},
+		},
+		"failed stage of a development build": {
+			status: http.StatusOK,
+			event:  Event{Job: "stage"},
+			expectedLines: []string{
+				"cert-manager stage job for development build failed",
+			},
+		},
+		"webhook error": {
+			status:    http.StatusForbidden,
+			event:     Event{Job: "stage", Succeeded: true},
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var text string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					Text string `json:"text"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode notification: %v", err)
+				}
+
+				text = body.Text
+				w.WriteHeader(test.status)
+			}))
+			defer server.Close()
+
+			webhook := &SlackWebhook{URL: server.URL}
+
+			err := webhook.Notify(context.TODO(), test.event)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			for _, line := range test.expectedLines {
+				if !strings.Contains(text, line) {
+					t.Errorf("expected notification to contain %q, got:\n%s", line, text)
+				}
+			}
+		})
+	}
+}
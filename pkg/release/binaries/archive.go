@@ -18,8 +18,6 @@ package binaries
 
 import (
 	"fmt"
-	"path/filepath"
-	"strings"
 )
 
 // Archive is used for accessing and interacting with a tar wth a binary file stored on disk.
@@ -38,16 +36,10 @@ type Archive struct {
 	ext string
 }
 
-func NewArchive(name, path, osStr, arch string, rawFilename string) *Archive {
-	// default to .tar.gz, but change to zip if needed
-	ext := ".tar.gz"
-
-	rawExt := strings.ToLower(filepath.Ext(rawFilename))
-
-	if rawExt == ".zip" {
-		ext = rawExt
-	}
-
+// NewArchive returns an Archive for the named binary stored at path. ext is the
+// file extension of the archive format, which should be determined using
+// release.ArchiveFormatForOS.
+func NewArchive(name, path, osStr, arch string, ext string) *Archive {
 	return &Archive{
 		name: name,
 		path: path,
@@ -18,29 +18,124 @@ package release
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
 	"strings"
+	"time"
 
 	"cloud.google.com/go/storage"
-	"github.com/google/martian/log"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 )
 
 type Bucket struct {
 	bucket *storage.BucketHandle
 	prefix string
+
+	// metadataFileName is the name of the file in the root of each staged
+	// release which contains the release metadata.
+	metadataFileName string
+
+	// strictMetadata, if true, causes release metadata containing unknown
+	// fields to be rejected.
+	strictMetadata bool
+
+	// signMetadata, if set, is used to sign metadata written to the bucket.
+	signMetadata MetadataSigner
+
+	// verifyMetadata, if set, is used to verify the signature of metadata
+	// read from the bucket.
+	verifyMetadata MetadataVerifier
 }
 
+// MetadataSigner returns a detached signature of the given release metadata.
+type MetadataSigner func(ctx context.Context, metadata []byte) ([]byte, error)
+
+// MetadataVerifier returns an error if signature isn't a valid detached
+// signature of the given release metadata.
+type MetadataVerifier func(ctx context.Context, metadata []byte, signature []byte) error
+
 func NewBucket(bucket *storage.BucketHandle, prefix, releaseType string) *Bucket {
-	return &Bucket{bucket: bucket, prefix: fmt.Sprintf("%s/%s/", prefix, releaseType)}
+	return &Bucket{bucket: bucket, prefix: fmt.Sprintf("%s/%s/", prefix, releaseType), metadataFileName: MetadataFileName}
+}
+
+// WithMetadataFileName configures the name of the metadata file read from
+// each staged release, for compatibility with release layouts which do not
+// use the default of MetadataFileName. An empty name restores the default.
+func (b *Bucket) WithMetadataFileName(name string) *Bucket {
+	if name == "" {
+		name = MetadataFileName
+	}
+
+	b.metadataFileName = name
+	return b
+}
+
+// WithStrictMetadata configures whether release metadata containing fields
+// which are unknown to this version of cmrel is rejected. By default unknown
+// fields are ignored, for compatibility with metadata written by newer versions.
+func (b *Bucket) WithStrictMetadata(strict bool) *Bucket {
+	b.strictMetadata = strict
+	return b
+}
+
+// WithMetadataSigner configures metadata written by WriteMetadata to be signed
+// using sign, with the signature stored alongside the metadata. A nil signer
+// restores the default of writing metadata without a signature.
+func (b *Bucket) WithMetadataSigner(sign MetadataSigner) *Bucket {
+	b.signMetadata = sign
+	return b
+}
+
+// WithMetadataVerifier configures the signature of each release's metadata to
+// be checked using verify before the metadata is trusted. Releases whose
+// metadata is unsigned or has an invalid signature fail to load with an error
+// wrapping ErrMetadataSignature. A nil verifier restores the default of not
+// checking signatures.
+func (b *Bucket) WithMetadataVerifier(verify MetadataVerifier) *Bucket {
+	b.verifyMetadata = verify
+	return b
+}
+
+// CheckAccess returns an error if the bucket does not exist or cannot be
+// accessed with the current credentials. It's intended to be called before
+// doing any other work, so that a mistyped bucket name or missing permission
+// is reported early with an actionable message.
+func (b *Bucket) CheckAccess(ctx context.Context) error {
+	return CheckBucketAccess(ctx, b.bucket)
+}
+
+// CheckBucketAccess returns an error if the given bucket does not exist or
+// cannot be accessed with the current credentials.
+func CheckBucketAccess(ctx context.Context, bucket *storage.BucketHandle) error {
+	_, err := bucket.Attrs(ctx)
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, storage.ErrBucketNotExist) {
+		return fmt.Errorf("bucket %q does not exist; check the bucket name for typos: %w", bucket.BucketName(), err)
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && (apiErr.Code == http.StatusForbidden || apiErr.Code == http.StatusUnauthorized) {
+		return fmt.Errorf("access to bucket %q was denied; check that the current credentials have permission to use it, or that the bucket name is correct: %w", bucket.BucketName(), err)
+	}
+
+	return fmt.Errorf("failed to check access to bucket %q: %w", bucket.BucketName(), err)
 }
 
 // GetRelease will fetch a single release from the bucket with the given name.
-// A release's name is the name of the directory the metadata.json file for is
-// the release is contained within.
+// A release's name is the name of the directory the metadata file for the
+// release is contained within.
 func (b *Bucket) GetRelease(ctx context.Context, name string) (*Staged, error) {
 	queryPath := b.prefix + name + "/"
 	stagedReleases := map[string][]*storage.ObjectHandle{}
+	stagedAt := map[string]time.Time{}
 	objs := b.bucket.Objects(ctx, &storage.Query{Prefix: queryPath})
 	for {
 		objAttr, err := objs.Next()
@@ -53,19 +148,23 @@ func (b *Bucket) GetRelease(ctx context.Context, name string) (*Staged, error) {
 		obj := b.bucket.Object(objAttr.Name)
 		releaseName := NameForObjectPath(obj.ObjectName(), b.prefix)
 		stagedReleases[releaseName] = append(stagedReleases[releaseName], obj)
+		if b.isMetadataObject(releaseName, objAttr.Name) {
+			stagedAt[releaseName] = objAttr.Updated
+		}
 	}
 	if len(stagedReleases) > 1 {
 		return nil, fmt.Errorf("internal error getting release: multiple releases found")
 	}
 	// iterate over the map. There is at most one element so return in the loop
 	for name, objs := range stagedReleases {
-		rel, err := NewStagedRelease(ctx, name, b.prefix, objs...)
+		rel, err := newStagedRelease(ctx, name, b.prefix, b.metadataFileName, b.strictMetadata, b.verifyMetadata, objs...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load staged release: %w", err)
 		}
+		rel.stagedAt = stagedAt[name]
 		return rel, nil
 	}
-	return nil, fmt.Errorf("no release found in path %q", queryPath)
+	return nil, fmt.Errorf("no release found in path %q: %w", queryPath, ErrReleaseNotFound)
 }
 
 // ListReleases will list releases in a bucket.
@@ -76,6 +175,7 @@ func (b *Bucket) GetRelease(ctx context.Context, name string) (*Staged, error) {
 // Specifying 'gitRef' without 'version' is not supported.
 func (b *Bucket) ListReleases(ctx context.Context, version, gitRef string) ([]Staged, error) {
 	stagedReleases := map[string][]*storage.ObjectHandle{}
+	stagedAt := map[string]time.Time{}
 	objs := b.bucket.Objects(ctx, &storage.Query{Prefix: b.prefix + pathSuffixForVersion(version, gitRef)})
 	for {
 		objAttr, err := objs.Next()
@@ -88,19 +188,90 @@ func (b *Bucket) ListReleases(ctx context.Context, version, gitRef string) ([]St
 		obj := b.bucket.Object(objAttr.Name)
 		releaseName := NameForObjectPath(obj.ObjectName(), b.prefix)
 		stagedReleases[releaseName] = append(stagedReleases[releaseName], obj)
+		if b.isMetadataObject(releaseName, objAttr.Name) {
+			stagedAt[releaseName] = objAttr.Updated
+		}
 	}
 	var staged []Staged
 	for name, objs := range stagedReleases {
-		rel, err := NewStagedRelease(ctx, name, b.prefix, objs...)
+		rel, err := newStagedRelease(ctx, name, b.prefix, b.metadataFileName, b.strictMetadata, b.verifyMetadata, objs...)
 		if err != nil {
-			log.Errorf("Failed to load staged release: %v", err)
+			slog.Warn("failed to load staged release", "release", name, "error", err)
 			continue
 		}
+		rel.stagedAt = stagedAt[name]
 		staged = append(staged, *rel)
 	}
 	return staged, nil
 }
 
+// WriteMetadata will overwrite the metadata file of the named release with
+// the given metadata.
+func (b *Bucket) WriteMetadata(ctx context.Context, name string, meta Metadata) error {
+	metaBytes, err := json.MarshalIndent(meta, "", " ")
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+
+	// the signature is written first, so that a release whose metadata was
+	// written is never left with a stale signature which verifies
+	signatureFileName := b.metadataFileName + MetadataSignatureSuffix
+	if b.signMetadata != nil {
+		signature, err := b.signMetadata(ctx, metaBytes)
+		if err != nil {
+			return fmt.Errorf("failed to sign release metadata: %w", err)
+		}
+
+		if err := b.WriteFile(ctx, name, signatureFileName, signature); err != nil {
+			return fmt.Errorf("failed to write release metadata signature: %w", err)
+		}
+	} else {
+		err := b.bucket.Object(b.prefix + name + "/" + signatureFileName).Delete(ctx)
+		if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+			return fmt.Errorf("failed to delete stale release metadata signature: %w", err)
+		}
+	}
+
+	if err := b.WriteFile(ctx, name, b.metadataFileName, metaBytes); err != nil {
+		return fmt.Errorf("failed to write release metadata: %w", err)
+	}
+
+	return nil
+}
+
+// WriteFile will write data to the file with the given name in the root of
+// the named release, overwriting it if it already exists.
+func (b *Bucket) WriteFile(ctx context.Context, name, fileName string, data []byte) error {
+	w := b.bucket.Object(b.prefix + name + "/" + fileName).NewWriter(ctx)
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+
+	return w.Close()
+}
+
+// ReadFile returns the contents of the file with the given name in the root
+// of the named release. If the file doesn't exist, the returned error wraps
+// storage.ErrObjectNotExist.
+func (b *Bucket) ReadFile(ctx context.Context, name, fileName string) ([]byte, error) {
+	r, err := b.bucket.Object(b.prefix + name + "/" + fileName).NewReader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q from release %q: %w", fileName, name, err)
+	}
+
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+// isMetadataObject returns true if the object with the given name is the
+// metadata file of the named release, or a gzipped copy of it.
+func (b *Bucket) isMetadataObject(releaseName, objName string) bool {
+	fileName := strings.TrimPrefix(objName, b.prefix+releaseName+"/")
+	return fileName == b.metadataFileName || fileName == b.metadataFileName+GzippedMetadataSuffix
+}
+
 // NameForObjectPath will return the name of the release that a given object
 // path is a member of by inspecting the path and trimming the prefix.
 func NameForObjectPath(path, prefix string) string {
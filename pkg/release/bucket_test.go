@@ -0,0 +1,442 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+// stagedReleaseObjects returns the objects making up a staged release with
+// a single manifests artifact, keyed by "<bucket>/<object name>".
+func stagedReleaseObjects(t *testing.T, bucket, releaseDir, metadataFileName string, meta Metadata) map[string][]byte {
+	t.Helper()
+
+	manifests := []byte("manifests")
+	sum := sha256.Sum256(manifests)
+
+	meta.Artifacts = append(meta.Artifacts, ArtifactMetadata{
+		Name:   "cert-manager-manifests.tar.gz",
+		SHA256: hex.EncodeToString(sum[:]),
+	})
+
+	metaBytes, err := json.Marshal(meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.HasSuffix(metadataFileName, GzippedMetadataSuffix) {
+		var buf bytes.Buffer
+		gzw := gzip.NewWriter(&buf)
+		if _, err := gzw.Write(metaBytes); err != nil {
+			t.Fatal(err)
+		}
+		if err := gzw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		metaBytes = buf.Bytes()
+	}
+
+	return map[string][]byte{
+		bucket + "/" + releaseDir + "/" + metadataFileName:           metaBytes,
+		bucket + "/" + releaseDir + "/cert-manager-manifests.tar.gz": manifests,
+	}
+}
+
+func TestBucketGetReleaseMetadataFileName(t *testing.T) {
+	tests := map[string]struct {
+		stagedFileName string
+		readFileName   string
+		expectErr      bool
+	}{
+		"default metadata file name": {
+			stagedFileName: MetadataFileName,
+			readFileName:   "",
+			expectErr:      false,
+		},
+		"custom metadata file name": {
+			stagedFileName: "release-metadata.json",
+			readFileName:   "release-metadata.json",
+			expectErr:      false,
+		},
+		"custom metadata file name not configured when reading": {
+			stagedFileName: "release-metadata.json",
+			readFileName:   "",
+			expectErr:      true,
+		},
+		"gzipped default metadata file": {
+			stagedFileName: MetadataFileName + GzippedMetadataSuffix,
+			readFileName:   "",
+			expectErr:      false,
+		},
+		"gzipped custom metadata file": {
+			stagedFileName: "release-metadata.json.gz",
+			readFileName:   "release-metadata.json",
+			expectErr:      false,
+		},
+		"configured metadata file name not present": {
+			stagedFileName: MetadataFileName,
+			readFileName:   "release-metadata.json",
+			expectErr:      true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			objects := stagedReleaseObjects(t, "test-bucket", "stage/gcb/release/v1.2.3-abcdef", test.stagedFileName, Metadata{
+				ReleaseVersion: "v1.2.3",
+				GitCommitRef:   "abcdef",
+			})
+
+			_, client := newFakeGCS(t, objects)
+
+			bucket := NewBucket(client.Bucket("test-bucket"), "stage/gcb", BuildTypeRelease).WithMetadataFileName(test.readFileName)
+
+			staged, err := bucket.GetRelease(ctx, "v1.2.3-abcdef")
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if test.expectErr {
+				return
+			}
+
+			if staged.Metadata().ReleaseVersion != "v1.2.3" {
+				t.Errorf("unexpected release version %q", staged.Metadata().ReleaseVersion)
+			}
+
+			if len(staged.ArtifactsOfKind("manifests")) != 1 {
+				t.Errorf("expected to find a single manifests artifact, got %d", len(staged.ArtifactsOfKind("manifests")))
+			}
+		})
+	}
+}
+
+func TestBucketGetReleaseStrictMetadata(t *testing.T) {
+	const knownFieldsOnly = `{"releaseVersion":"v1.2.3","gitCommitRef":"abcdef","artifacts":[]}`
+	const unknownField = `{"releaseVersion":"v1.2.3","gitCommitRef":"abcdef","artifacts":[],"gitComitRef":"abcdef"}`
+
+	tests := map[string]struct {
+		metadata  string
+		strict    bool
+		expectErr bool
+	}{
+		"lenient with known fields": {
+			metadata: knownFieldsOnly,
+			strict:   false,
+		},
+		"strict with known fields": {
+			metadata: knownFieldsOnly,
+			strict:   true,
+		},
+		"lenient with unknown field": {
+			metadata: unknownField,
+			strict:   false,
+		},
+		"strict with unknown field": {
+			metadata:  unknownField,
+			strict:    true,
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			_, client := newFakeGCS(t, map[string][]byte{
+				"test-bucket/stage/gcb/release/v1.2.3-abcdef/" + MetadataFileName: []byte(test.metadata),
+			})
+
+			bucket := NewBucket(client.Bucket("test-bucket"), "stage/gcb", BuildTypeRelease).WithStrictMetadata(test.strict)
+
+			staged, err := bucket.GetRelease(ctx, "v1.2.3-abcdef")
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if test.expectErr {
+				return
+			}
+
+			if staged.Metadata().GitCommitRef != "abcdef" {
+				t.Errorf("unexpected git commit ref %q", staged.Metadata().GitCommitRef)
+			}
+		})
+	}
+}
+
+// fakeMetadataSigner signs metadata by hashing it with a secret, which is
+// enough to tell whether metadata was changed after it was signed.
+func fakeMetadataSigner(secret string) MetadataSigner {
+	return func(ctx context.Context, metadata []byte) ([]byte, error) {
+		sum := sha256.Sum256(append([]byte(secret), metadata...))
+		return []byte(hex.EncodeToString(sum[:])), nil
+	}
+}
+
+func fakeMetadataVerifier(secret string) MetadataVerifier {
+	return func(ctx context.Context, metadata []byte, signature []byte) error {
+		expected, _ := fakeMetadataSigner(secret)(ctx, metadata)
+		if !bytes.Equal(expected, signature) {
+			return errors.New("signature mismatch")
+		}
+
+		return nil
+	}
+}
+
+func TestBucketMetadataSignature(t *testing.T) {
+	const releaseDir = "test-bucket/stage/gcb/release/v1.2.3-abcdef/"
+
+	tests := map[string]struct {
+		metadataFileName string
+		// modify changes the bucket after the metadata is signed
+		modify    func(t *testing.T, objects map[string][]byte)
+		expectErr bool
+	}{
+		"signed metadata verifies": {
+			metadataFileName: MetadataFileName,
+		},
+		"signed gzipped metadata verifies against its uncompressed content": {
+			metadataFileName: MetadataFileName + GzippedMetadataSuffix,
+		},
+		"tampered metadata is rejected": {
+			metadataFileName: MetadataFileName,
+			modify: func(t *testing.T, objects map[string][]byte) {
+				objects[releaseDir+MetadataFileName] = bytes.Replace(objects[releaseDir+MetadataFileName], []byte("v1.2.3"), []byte("v1.2.4"), 1)
+			},
+			expectErr: true,
+		},
+		"missing signature is rejected": {
+			metadataFileName: MetadataFileName,
+			modify: func(t *testing.T, objects map[string][]byte) {
+				delete(objects, releaseDir+MetadataFileName+MetadataSignatureSuffix)
+			},
+			expectErr: true,
+		},
+		"signature from another key is rejected": {
+			metadataFileName: MetadataFileName,
+			modify: func(t *testing.T, objects map[string][]byte) {
+				uncompressed := objects[releaseDir+MetadataFileName]
+				signature, _ := fakeMetadataSigner("other")(context.Background(), uncompressed)
+				objects[releaseDir+MetadataFileName+MetadataSignatureSuffix] = signature
+			},
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			objects := stagedReleaseObjects(t, "test-bucket", "stage/gcb/release/v1.2.3-abcdef", test.metadataFileName, Metadata{
+				ReleaseVersion: "v1.2.3",
+				GitCommitRef:   "abcdef",
+			})
+
+			uncompressed := objects[releaseDir+test.metadataFileName]
+			if strings.HasSuffix(test.metadataFileName, GzippedMetadataSuffix) {
+				gzr, err := gzip.NewReader(bytes.NewReader(uncompressed))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				uncompressed, err = io.ReadAll(gzr)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			signature, _ := fakeMetadataSigner("secret")(ctx, uncompressed)
+			objects[releaseDir+MetadataFileName+MetadataSignatureSuffix] = signature
+
+			if test.modify != nil {
+				test.modify(t, objects)
+			}
+
+			_, client := newFakeGCS(t, objects)
+
+			bucket := NewBucket(client.Bucket("test-bucket"), "stage/gcb", BuildTypeRelease).WithMetadataVerifier(fakeMetadataVerifier("secret"))
+
+			_, err := bucket.GetRelease(ctx, "v1.2.3-abcdef")
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if test.expectErr && !errors.Is(err, ErrMetadataSignature) {
+				t.Errorf("expected error to wrap ErrMetadataSignature, got: %v", err)
+			}
+
+			// signatures aren't checked unless a verifier is configured
+			if _, err := NewBucket(client.Bucket("test-bucket"), "stage/gcb", BuildTypeRelease).GetRelease(ctx, "v1.2.3-abcdef"); err != nil {
+				t.Errorf("expected release to load without a verifier, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestBucketWriteMetadataSignature(t *testing.T) {
+	ctx := context.Background()
+
+	fake, client := newFakeGCS(t, stagedReleaseObjects(t, "test-bucket", "stage/gcb/release/v1.2.3-abcdef", MetadataFileName, Metadata{
+		ReleaseVersion: "v1.2.3",
+		GitCommitRef:   "abcdef",
+	}))
+
+	signing := NewBucket(client.Bucket("test-bucket"), "stage/gcb", BuildTypeRelease).
+		WithMetadataSigner(fakeMetadataSigner("secret")).
+		WithMetadataVerifier(fakeMetadataVerifier("secret"))
+
+	staged, err := signing.GetRelease(ctx, "v1.2.3-abcdef")
+	if err == nil {
+		t.Fatalf("expected unsigned release to fail verification")
+	}
+
+	staged, err = NewBucket(client.Bucket("test-bucket"), "stage/gcb", BuildTypeRelease).GetRelease(ctx, "v1.2.3-abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := signing.WriteMetadata(ctx, staged.Name(), staged.Metadata()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := signing.GetRelease(ctx, "v1.2.3-abcdef"); err != nil {
+		t.Fatalf("expected release to verify after signing its metadata, got: %v", err)
+	}
+
+	// rewriting the metadata without a signer mustn't leave the old signature
+	// behind, even though it would no longer verify
+	unsigned := NewBucket(client.Bucket("test-bucket"), "stage/gcb", BuildTypeRelease)
+	if err := unsigned.WriteMetadata(ctx, staged.Name(), staged.Metadata()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := fake.Object("test-bucket/stage/gcb/release/v1.2.3-abcdef/" + MetadataFileName + MetadataSignatureSuffix); ok {
+		t.Errorf("expected stale metadata signature to be deleted")
+	}
+}
+
+func TestBucketStagedAt(t *testing.T) {
+	ctx := context.Background()
+
+	objects := stagedReleaseObjects(t, "test-bucket", "stage/gcb/release/v1.2.3-abcdef", MetadataFileName, Metadata{
+		ReleaseVersion: "v1.2.3",
+		GitCommitRef:   "abcdef",
+	})
+
+	fake, client := newFakeGCS(t, objects)
+
+	stagedAt := time.Date(2021, 5, 12, 10, 4, 23, 0, time.UTC)
+	fake.SetUpdated("test-bucket/stage/gcb/release/v1.2.3-abcdef/"+MetadataFileName, stagedAt)
+	fake.SetUpdated("test-bucket/stage/gcb/release/v1.2.3-abcdef/cert-manager-manifests.tar.gz", stagedAt.Add(-time.Hour))
+
+	bucket := NewBucket(client.Bucket("test-bucket"), "stage/gcb", BuildTypeRelease)
+
+	staged, err := bucket.GetRelease(ctx, "v1.2.3-abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !staged.StagedAt().Equal(stagedAt) {
+		t.Errorf("expected GetRelease to return a release staged at %s but got %s", stagedAt, staged.StagedAt())
+	}
+
+	listed, err := bucket.ListReleases(ctx, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(listed) != 1 {
+		t.Fatalf("expected 1 listed release but got %d", len(listed))
+	}
+
+	if !listed[0].StagedAt().Equal(stagedAt) {
+		t.Errorf("expected ListReleases to return a release staged at %s but got %s", stagedAt, listed[0].StagedAt())
+	}
+}
+
+func TestBucketCheckAccess(t *testing.T) {
+	tests := map[string]struct {
+		// bucketError, if non-zero, is the HTTP status code returned for
+		// every request to the bucket
+		bucketError int
+		expectErr   string
+		notExist    bool
+	}{
+		"accessible bucket": {},
+		"missing bucket": {
+			bucketError: http.StatusNotFound,
+			expectErr:   `bucket "test-bucket" does not exist; check the bucket name for typos`,
+			notExist:    true,
+		},
+		"forbidden bucket": {
+			bucketError: http.StatusForbidden,
+			expectErr:   `access to bucket "test-bucket" was denied`,
+		},
+		"server error": {
+			bucketError: http.StatusInternalServerError,
+			expectErr:   `failed to check access to bucket "test-bucket"`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			fake, client := newFakeGCS(t, nil)
+			if test.bucketError != 0 {
+				fake.SetBucketError("test-bucket", test.bucketError)
+			}
+
+			// the storage client retries server errors until the context
+			// is cancelled, so bound how long the check can take
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+
+			err := NewBucket(client.Bucket("test-bucket"), "stage/gcb", BuildTypeRelease).CheckAccess(ctx)
+			if (err != nil) != (test.expectErr != "") {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr != "", err)
+			}
+
+			if err == nil {
+				return
+			}
+
+			if !strings.HasPrefix(err.Error(), test.expectErr) {
+				t.Errorf("expected error to start with %q but got %q", test.expectErr, err)
+			}
+
+			if errors.Is(err, storage.ErrBucketNotExist) != test.notExist {
+				t.Errorf("expected errors.Is(err, storage.ErrBucketNotExist)=%v for err=%v", test.notExist, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+// Component describes one of the components which can be built as part of a
+// cert-manager release.
+type Component struct {
+	// Name is the name of the component, as used in the names of its release
+	// artifacts.
+	Name string
+
+	// Image is true if the component is shipped as a container image as part
+	// of the 'server' artifacts.
+	Image bool
+
+	// ClientBinary is true if the component is shipped as a standalone CLI
+	// binary archive.
+	ClientBinary bool
+}
+
+// components is the canonical list of cert-manager components. Adding or
+// removing a component should only require changing this list.
+var components = []Component{
+	{Name: "acmesolver", Image: true},
+	{Name: "cainjector", Image: true},
+	{Name: "controller", Image: true},
+	{Name: "ctl", Image: true},
+	{Name: "startupapicheck", Image: true},
+	{Name: "webhook", Image: true},
+	{Name: "cmctl", ClientBinary: true},
+	{Name: "kubectl-cert_manager", ClientBinary: true},
+}
+
+// Components returns all known cert-manager components. Not every component
+// is present in every release; for example the ctl components are only shipped
+// in releases older than v1.15.0.
+func Components() []Component {
+	out := make([]Component, len(components))
+	copy(out, components)
+	return out
+}
+
+// ImageComponentNames returns the names of all components which are shipped as
+// container images.
+func ImageComponentNames() []string {
+	var names []string
+	for _, c := range components {
+		if c.Image {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+// ClientBinaryComponentNames returns the names of all components which are
+// shipped as CLI binary archives.
+func ClientBinaryComponentNames() []string {
+	var names []string
+	for _, c := range components {
+		if c.ClientBinary {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
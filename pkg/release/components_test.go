@@ -0,0 +1,48 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func TestComponents(t *testing.T) {
+	names := sets.NewString()
+	for _, c := range Components() {
+		if names.Has(c.Name) {
+			t.Errorf("component %q is listed more than once", c.Name)
+		}
+		names.Insert(c.Name)
+
+		if c.Image == c.ClientBinary {
+			t.Errorf("component %q must be exactly one of an image or a client binary", c.Name)
+		}
+	}
+
+	expImages := []string{"acmesolver", "cainjector", "controller", "ctl", "startupapicheck", "webhook"}
+	if got := ImageComponentNames(); !reflect.DeepEqual(got, expImages) {
+		t.Errorf("unexpected image components: got=%v, exp=%v", got, expImages)
+	}
+
+	expBinaries := []string{"cmctl", "kubectl-cert_manager"}
+	if got := ClientBinaryComponentNames(); !reflect.DeepEqual(got, expBinaries) {
+		t.Errorf("unexpected client binary components: got=%v, exp=%v", got, expBinaries)
+	}
+}
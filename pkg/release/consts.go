@@ -25,6 +25,27 @@ const (
 	// release.
 	MetadataFileName = "metadata.json"
 
+	// GzippedMetadataSuffix is appended to the name of the metadata file when
+	// it is stored gzipped.
+	GzippedMetadataSuffix = ".gz"
+
+	// MetadataSignatureSuffix is appended to the name of the metadata file to
+	// give the name of the file holding a detached signature of the metadata.
+	// The signature is always of the uncompressed metadata.
+	MetadataSignatureSuffix = ".sig"
+
+	// PublishedDigestsFileName is the name of the file in the root of a
+	// staged release which records the digests of its published images.
+	PublishedDigestsFileName = "published-digests.json"
+
+	// PublishStateFileName is the name of the file in the root of a staged
+	// release which records the progress of publishing it.
+	PublishStateFileName = "publish-state.json"
+
+	// ProvenanceFileName is the name of the file in the root of a staged
+	// release which holds SLSA provenance for its artifacts.
+	ProvenanceFileName = "provenance.intoto.json"
+
 	// TarsBazelTarget is the Bazel target used to build release tar files in
 	// the cert-manager repository.
 	TarsBazelTarget = "//build/release-tars"
@@ -54,6 +75,10 @@ const (
 	// code.
 	DefaultGitHubRepo = "cert-manager"
 
+	// DefaultCmctlGitHubRepo is the default repository containing the cmctl
+	// code, which cmctl has been released from since cert-manager v1.15.
+	DefaultCmctlGitHubRepo = "cmctl"
+
 	// DefaultHelmChartGitHubOwner is the name of the owner of the default
 	// GitHub repository for Helm charts.
 	DefaultHelmChartGitHubOwner = "jetstack"
@@ -66,6 +91,14 @@ const (
 	// repository for Helm charts.
 	DefaultHelmChartGitHubBranch = "main"
 
+	// DefaultHelmChartRepositoryURL is the URL of the public Helm chart
+	// repository that charts are published to.
+	DefaultHelmChartRepositoryURL = "https://charts.jetstack.io"
+
+	// DefaultHelmChartOCIRegistry is the OCI registry which Helm charts are
+	// pushed to by the 'helmchartoci' publish action.
+	DefaultHelmChartOCIRegistry = "oci://quay.io/jetstack/charts"
+
 	// BuildTypeRelease denotes that a build is targeting an actual named
 	// release and is not just a development build that has been created using
 	// the release tool.
@@ -78,6 +111,13 @@ const (
 
 	// BuildSourceMake indicates that the files were built by make, rather than Bazel
 	BuildSourceMake = "make"
+
+	// BuildSourceBazel indicates that the files were built by Bazel
+	BuildSourceBazel = "bazel"
+
+	// BuildSourcePrebuilt indicates that the files were built outside of
+	// cmrel, laid out as Bazel would, and staged with 'gcb stage --skip-build'
+	BuildSourcePrebuilt = "prebuilt"
 )
 
 // BucketPathForRelease will assemble an output directory path for the given
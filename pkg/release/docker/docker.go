@@ -18,45 +18,120 @@ package docker
 
 import (
 	"context"
+	"fmt"
 
-	"github.com/cert-manager/release/pkg/shell"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
+	"github.com/cert-manager/release/pkg/retry"
 )
 
-// Load runs 'docker load' against the named .tar file
-func Load(ctx context.Context, path string) error {
-	return shell.Command(ctx, "", "docker", "load", "-i", path)
+// Load reads the image stored in the named 'docker save' format .tar file.
+// Image layers are read from the file as they're needed, so no docker daemon
+// is required.
+func Load(path string) (v1.Image, error) {
+	img, err := tarball.ImageFromPath(path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load image from %q: %w", path, err)
+	}
+
+	return img, nil
+}
+
+// Push pushes img to its registry under the given tag, retrying on failure,
+// and returns the digest it was pushed with.
+// Any layers which already exist in the repository aren't uploaded again.
+func Push(ctx context.Context, img v1.Image, tag string, opts ...remote.Option) (v1.Hash, error) {
+	return NewPusher(retry.DefaultOptions(), nil, opts...).Push(ctx, img, tag)
 }
 
-// Tag runs `docker tag` which tags the given image with a new tag
-func Tag(ctx context.Context, image string, newTag string) error {
-	return shell.Command(ctx, "", "docker", "tag", image, newTag)
+// PushManifestList pushes the manifest list idx to its registry under the
+// given tag, retrying on failure, and returns the digest it was pushed with.
+// Any images in idx which don't already exist in the repository are pushed
+// along with it.
+func PushManifestList(ctx context.Context, idx v1.ImageIndex, tag string, opts ...remote.Option) (v1.Hash, error) {
+	return NewPusher(retry.DefaultOptions(), nil, opts...).PushManifestList(ctx, idx, tag)
 }
 
-// Push runs 'docker push' with the given image name
-func Push(ctx context.Context, image string) error {
-	return shell.Command(ctx, "", "docker", "push", image)
+// Pusher pushes images and manifest lists to registries. A Pusher is safe for
+// concurrent use, and concurrent pushes which share a Throttle all back off
+// together when a registry starts rate limiting them.
+type Pusher struct {
+	retry    retry.Options
+	throttle *Throttle
+	opts     []remote.Option
 }
 
-// CreateManifestList creates a docker manifest list; see the `docker manifest create`
-// command's `--help` for more information
-func CreateManifestList(ctx context.Context, name string, imageNames []string) error {
-	args := append([]string{"manifest", "create", name}, imageNames...)
-	return shell.Command(ctx, "", "docker", args...)
+// NewPusher returns a Pusher which retries failed pushes according to
+// retryOpts, and which waits for throttle, if non-nil, before every attempt.
+func NewPusher(retryOpts retry.Options, throttle *Throttle, opts ...remote.Option) *Pusher {
+	return &Pusher{retry: retryOpts, throttle: throttle, opts: opts}
+}
+
+// Push pushes img to its registry under the given tag, retrying on failure,
+// and returns the digest it was pushed with.
+// Any layers which already exist in the repository aren't uploaded again.
+func (p *Pusher) Push(ctx context.Context, img v1.Image, tag string) (v1.Hash, error) {
+	ref, err := name.NewTag(tag)
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("failed to parse image tag %q: %w", tag, err)
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("failed to compute digest of image %q: %w", tag, err)
+	}
+
+	opts := append([]remote.Option{remote.WithContext(ctx)}, p.opts...)
+
+	if err := p.do(ctx, func() error {
+		return remote.Write(ref, img, opts...)
+	}); err != nil {
+		return v1.Hash{}, err
+	}
+
+	return digest, nil
 }
 
-// AnnotateManifestList annotates a docker manifest list; see the `docker manifest annotate`
-// command's `--help` for more information
-func AnnotateManifestList(ctx context.Context, manifestName, imageName, os, arch, variant string) error {
-	return shell.Command(ctx, "",
-		"docker", "manifest", "annotate", manifestName, imageName,
-		"--os", os,
-		"--arch", arch,
-		"--variant", variant,
-	)
+// PushManifestList pushes the manifest list idx to its registry under the
+// given tag, retrying on failure, and returns the digest it was pushed with.
+// Any images in idx which don't already exist in the repository are pushed
+// along with it.
+func (p *Pusher) PushManifestList(ctx context.Context, idx v1.ImageIndex, tag string) (v1.Hash, error) {
+	ref, err := name.NewTag(tag)
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("failed to parse manifest list tag %q: %w", tag, err)
+	}
+
+	digest, err := idx.Digest()
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("failed to compute digest of manifest list %q: %w", tag, err)
+	}
+
+	opts := append([]remote.Option{remote.WithContext(ctx)}, p.opts...)
+
+	if err := p.do(ctx, func() error {
+		return remote.WriteIndex(ref, idx, opts...)
+	}); err != nil {
+		return v1.Hash{}, err
+	}
+
+	return digest, nil
 }
 
-// PushManifestList pushes a docker manifest list; see the `docker manifest push`
-// command's `--help` for more information
-func PushManifestList(ctx context.Context, name string) error {
-	return shell.Command(ctx, "", "docker", "manifest", "push", name)
+// do calls f with retries, waiting for the throttle before each attempt and
+// reporting the result of each attempt to it.
+func (p *Pusher) do(ctx context.Context, f func() error) error {
+	return retry.DoWithOptions(ctx, p.retry, func() error {
+		if err := p.throttle.wait(ctx); err != nil {
+			return retry.Permanent(err)
+		}
+
+		err := f()
+		p.throttle.observe(err)
+
+		return err
+	})
 }
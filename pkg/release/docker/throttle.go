@@ -0,0 +1,119 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// Throttle coordinates backing off between concurrent pushes when a registry
+// rate limits them. Each rate limited response pauses every push which shares
+// the Throttle, for a delay which doubles with each consecutive rate limited
+// response up to a maximum, and which is reset by any successful request.
+// A nil *Throttle never waits.
+type Throttle struct {
+	initialDelay time.Duration
+	maxDelay     time.Duration
+
+	// now and after are replaced in tests
+	now   func() time.Time
+	after func(time.Duration) <-chan time.Time
+
+	mu    sync.Mutex
+	delay time.Duration
+	until time.Time
+}
+
+// NewThrottle returns a Throttle whose first pause after being rate limited
+// lasts initialDelay, and whose pauses never last longer than maxDelay.
+func NewThrottle(initialDelay, maxDelay time.Duration) *Throttle {
+	return &Throttle{
+		initialDelay: initialDelay,
+		maxDelay:     maxDelay,
+		now:          time.Now,
+		after:        time.After,
+	}
+}
+
+// wait blocks until any pause caused by a rate limited response has passed,
+// or until ctx is done.
+func (t *Throttle) wait(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	remaining := t.until.Sub(t.now())
+	t.mu.Unlock()
+
+	if remaining <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.after(remaining):
+		return nil
+	}
+}
+
+// observe records the result of a request, pausing all requests if it was
+// rate limited.
+func (t *Throttle) observe(err error) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err == nil {
+		t.delay = 0
+		return
+	}
+
+	if !isRateLimited(err) {
+		return
+	}
+
+	// concurrent requests which were already in flight are often rate limited
+	// together, so only back off further once the current pause has passed
+	now := t.now()
+	if now.Before(t.until) {
+		return
+	}
+
+	t.delay = min(max(2*t.delay, t.initialDelay), t.maxDelay)
+	t.until = now.Add(t.delay)
+
+	slog.Warn("registry is rate limiting requests, pausing all pushes", "delay", t.delay)
+}
+
+// isRateLimited returns true if err is a registry's response saying that too
+// many requests have been made.
+func isRateLimited(err error) bool {
+	var terr *transport.Error
+	return errors.As(err, &terr) && terr.StatusCode == http.StatusTooManyRequests
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+func TestThrottle(t *testing.T) {
+	rateLimited := &transport.Error{StatusCode: http.StatusTooManyRequests}
+	serverError := &transport.Error{StatusCode: http.StatusInternalServerError}
+
+	tests := map[string]struct {
+		// results are observed in turn, advancing the clock by the current
+		// delay after each one
+		results       []error
+		expectedDelay time.Duration
+	}{
+		"no requests": {
+			expectedDelay: 0,
+		},
+		"successful request": {
+			results:       []error{nil},
+			expectedDelay: 0,
+		},
+		"errors other than rate limiting are ignored": {
+			results:       []error{serverError, errors.New("failed")},
+			expectedDelay: 0,
+		},
+		"first rate limited response": {
+			results:       []error{rateLimited},
+			expectedDelay: time.Second,
+		},
+		"consecutive rate limited responses double the delay": {
+			results:       []error{rateLimited, rateLimited, rateLimited},
+			expectedDelay: 4 * time.Second,
+		},
+		"delay is capped": {
+			results:       []error{rateLimited, rateLimited, rateLimited, rateLimited, rateLimited, rateLimited},
+			expectedDelay: 10 * time.Second,
+		},
+		"success resets the delay": {
+			results:       []error{rateLimited, rateLimited, nil, rateLimited},
+			expectedDelay: time.Second,
+		},
+		"wrapped rate limited responses are recognised": {
+			results:       []error{errors.Join(errors.New("push failed"), rateLimited)},
+			expectedDelay: time.Second,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			now := time.Unix(0, 0)
+
+			throttle := NewThrottle(time.Second, 10*time.Second)
+			throttle.now = func() time.Time { return now }
+
+			for _, err := range test.results {
+				throttle.observe(err)
+				now = now.Add(throttle.delay)
+			}
+
+			if throttle.delay != test.expectedDelay {
+				t.Errorf("expected delay %s but got %s", test.expectedDelay, throttle.delay)
+			}
+		})
+	}
+}
+
+func TestThrottleWait(t *testing.T) {
+	now := time.Unix(0, 0)
+
+	var waited []time.Duration
+
+	throttle := NewThrottle(time.Second, 10*time.Second)
+	throttle.now = func() time.Time { return now }
+	throttle.after = func(d time.Duration) <-chan time.Time {
+		waited = append(waited, d)
+		ch := make(chan time.Time, 1)
+		ch <- now.Add(d)
+		return ch
+	}
+
+	ctx := context.Background()
+
+	if err := throttle.wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	throttle.observe(&transport.Error{StatusCode: http.StatusTooManyRequests})
+
+	// requests which were in flight when the first was rate limited don't
+	// extend the pause
+	now = now.Add(500 * time.Millisecond)
+	throttle.observe(&transport.Error{StatusCode: http.StatusTooManyRequests})
+
+	if err := throttle.wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(waited) != 1 || waited[0] != 500*time.Millisecond {
+		t.Errorf("expected a single wait of 500ms but got %v", waited)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	throttle.after = func(d time.Duration) <-chan time.Time { return nil }
+	if err := throttle.wait(cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected waiting with a cancelled context to fail, got: %v", err)
+	}
+
+	var nilThrottle *Throttle
+	if err := nilThrottle.wait(ctx); err != nil {
+		t.Errorf("expected a nil throttle not to wait, got: %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import "errors"
+
+var (
+	// ErrReleaseNotFound is returned when a staged release cannot be found.
+	ErrReleaseNotFound = errors.New("release not found")
+
+	// ErrValidationFailed is returned when a release or its inputs fail
+	// validation, and so the requested operation was refused.
+	ErrValidationFailed = errors.New("validation failed")
+
+	// ErrMetadataSignature is returned when the signature of a staged
+	// release's metadata is missing or doesn't verify, and so its artifact
+	// checksums can't be trusted.
+	ErrMetadataSignature = errors.New("release metadata signature verification failed")
+)
@@ -0,0 +1,290 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/option"
+)
+
+// fakeGCS is a minimal in-memory implementation of the parts of the GCS JSON
+// API used by this package, so that code which reads and writes staged
+// releases can be tested without access to a real bucket.
+type fakeGCS struct {
+	lock    sync.Mutex
+	objects map[string][]byte
+
+	// bucketErrors holds an HTTP status code which is returned for every
+	// request to the named bucket, to simulate missing or forbidden buckets.
+	bucketErrors map[string]int
+
+	// updated holds the time at which each object was last written, keyed
+	// in the same way as objects. Objects without a time report none.
+	updated map[string]time.Time
+
+	// truncateUploads is the number of upcoming uploads which are stored
+	// with their last byte missing, to simulate corruption in transit.
+	truncateUploads int
+}
+
+// newFakeGCS starts a fake GCS server containing the given objects, keyed by
+// "<bucket>/<object name>", and returns a client configured to use it.
+func newFakeGCS(t *testing.T, objects map[string][]byte) (*fakeGCS, *storage.Client) {
+	t.Helper()
+
+	f := &fakeGCS{objects: map[string][]byte{}, bucketErrors: map[string]int{}, updated: map[string]time.Time{}}
+	for name, data := range objects {
+		f.objects[name] = data
+	}
+
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	client, err := storage.NewClient(
+		context.Background(),
+		option.WithEndpoint(server.URL+"/storage/v1/"),
+		option.WithoutAuthentication(),
+		storage.WithJSONReads(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return f, client
+}
+
+// Object returns the content of the named object, keyed by
+// "<bucket>/<object name>".
+func (f *fakeGCS) Object(name string) ([]byte, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	data, ok := f.objects[name]
+	return data, ok
+}
+
+// SetBucketError causes every request to the named bucket to fail with the
+// given HTTP status code.
+func (f *fakeGCS) SetBucketError(bucket string, code int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.bucketErrors[bucket] = code
+}
+
+// TruncateUploads causes the next n uploads to be stored with their last byte
+// missing.
+func (f *fakeGCS) TruncateUploads(n int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.truncateUploads = n
+}
+
+// SetUpdated sets the time at which the named object, keyed by
+// "<bucket>/<object name>", reports that it was last written.
+func (f *fakeGCS) SetUpdated(name string, t time.Time) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.updated[name] = t
+}
+
+func (f *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	path, err := url.PathUnescape(r.URL.EscapedPath())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for bucket, code := range f.bucketErrors {
+		if strings.HasPrefix(path, "/storage/v1/b/"+bucket+"/") || path == "/storage/v1/b/"+bucket || strings.HasPrefix(path, "/upload/storage/v1/b/"+bucket+"/") {
+			writeError(w, code)
+			return
+		}
+	}
+
+	switch {
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "/upload/storage/v1/b/"):
+		bucket := strings.TrimSuffix(strings.TrimPrefix(path, "/upload/storage/v1/b/"), "/o")
+		f.upload(w, r, bucket)
+
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/storage/v1/b/"):
+		bucket, object, _ := strings.Cut(strings.TrimPrefix(path, "/storage/v1/b/"), "/o")
+		object = strings.TrimPrefix(object, "/")
+
+		switch {
+		case object == "" && strings.HasSuffix(path, "/o"):
+			f.list(w, bucket, r.URL.Query().Get("prefix"))
+		case object == "":
+			writeJSON(w, map[string]any{"name": bucket})
+		case r.URL.Query().Get("alt") == "media":
+			data, ok := f.objects[bucket+"/"+object]
+			if !ok {
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		default:
+			data, ok := f.objects[bucket+"/"+object]
+			if !ok {
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+			writeJSON(w, f.objectResource(bucket, object, data))
+		}
+
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/storage/v1/b/"):
+		bucket, object, _ := strings.Cut(strings.TrimPrefix(path, "/storage/v1/b/"), "/o/")
+		if _, ok := f.objects[bucket+"/"+object]; !ok {
+			writeError(w, http.StatusNotFound)
+			return
+		}
+
+		delete(f.objects, bucket+"/"+object)
+		delete(f.updated, bucket+"/"+object)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "unsupported request "+r.Method+" "+path, http.StatusNotImplemented)
+	}
+}
+
+func (f *fakeGCS) list(w http.ResponseWriter, bucket, prefix string) {
+	var names []string
+	for key := range f.objects {
+		if name, ok := strings.CutPrefix(key, bucket+"/"); ok && strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	items := []map[string]any{}
+	for _, name := range names {
+		items = append(items, f.objectResource(bucket, name, f.objects[bucket+"/"+name]))
+	}
+
+	writeJSON(w, map[string]any{"kind": "storage#objects", "items": items})
+}
+
+func (f *fakeGCS) upload(w http.ResponseWriter, r *http.Request, bucket string) {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mr := multipart.NewReader(r.Body, params["boundary"])
+
+	metaPart, err := mr.NextPart()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var meta struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(metaPart).Decode(&meta); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dataPart, err := mr.NextPart()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := io.ReadAll(dataPart)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if f.truncateUploads > 0 && len(data) > 0 {
+		f.truncateUploads--
+		data = data[:len(data)-1]
+	}
+
+	f.objects[bucket+"/"+meta.Name] = data
+
+	writeJSON(w, f.objectResource(bucket, meta.Name, data))
+}
+
+func (f *fakeGCS) objectResource(bucket, name string, data []byte) map[string]any {
+	resource := map[string]any{
+		"kind":   "storage#object",
+		"bucket": bucket,
+		"name":   name,
+		"size":   strconv.Itoa(len(data)),
+	}
+
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)))
+	resource["crc32c"] = base64.StdEncoding.EncodeToString(crc)
+
+	md5Sum := md5.Sum(data)
+	resource["md5Hash"] = base64.StdEncoding.EncodeToString(md5Sum[:])
+
+	if updated, ok := f.updated[bucket+"/"+name]; ok {
+		resource["updated"] = updated.Format(time.RFC3339Nano)
+	}
+
+	return resource
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error in the format returned by the GCS JSON API.
+func writeError(w http.ResponseWriter, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{
+			"code":    code,
+			"message": http.StatusText(code),
+		},
+	})
+}
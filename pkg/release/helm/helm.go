@@ -20,7 +20,7 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"strings"
@@ -151,7 +151,7 @@ func (o *gitHubRepositoryManager) Check(ctx context.Context) (err error) {
 
 // Publish is documented at RepositoryManager.Publish
 func (o *gitHubRepositoryManager) Publish(ctx context.Context, releaseName string, charts ...manifests.Chart) (string, error) {
-	log.Printf("Creating PR for merging Helm charts of %q into %q", releaseName, o.destination())
+	slog.Info("creating PR for merging Helm charts", "release", releaseName, "destination", o.destination())
 
 	// Create a new branch
 	newBranchName := releaseName
@@ -178,7 +178,7 @@ func (o *gitHubRepositoryManager) Publish(ctx context.Context, releaseName strin
 		return "", errors.WithStack(err)
 	}
 	prURL := pr.GetHTMLURL()
-	log.Printf("Created PR: %s ", prURL)
+	slog.Info("created PR", "url", prURL)
 	return prURL, nil
 }
 
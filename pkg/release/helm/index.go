@@ -0,0 +1,181 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/ghodss/yaml"
+
+	"github.com/cert-manager/release/pkg/release/manifests"
+)
+
+const (
+	// RepositoryIndexFileName is the name of the index file served from the
+	// root of a Helm chart repository.
+	RepositoryIndexFileName = "index.yaml"
+
+	// RepositoryIndexAPIVersion is the only supported apiVersion of Helm chart
+	// repository index files.
+	RepositoryIndexAPIVersion = "v1"
+)
+
+// RepositoryIndex is the index file of a Helm chart repository, in the same
+// format as written by 'helm repo index'. Chart versions are kept as generic
+// maps so that any fields written by other tools are preserved when charts
+// are added to an existing index.
+type RepositoryIndex struct {
+	APIVersion  string                      `json:"apiVersion"`
+	Entries     map[string][]map[string]any `json:"entries"`
+	Generated   time.Time                   `json:"generated"`
+	ServerInfo  map[string]any              `json:"serverInfo,omitempty"`
+	PublicKeys  []string                    `json:"publicKeys,omitempty"`
+	Annotations map[string]string           `json:"annotations,omitempty"`
+}
+
+// NewRepositoryIndex returns an empty chart repository index.
+func NewRepositoryIndex() *RepositoryIndex {
+	return &RepositoryIndex{
+		APIVersion: RepositoryIndexAPIVersion,
+		Entries:    map[string][]map[string]any{},
+	}
+}
+
+// ParseRepositoryIndex parses the given chart repository index file. If data
+// is empty, an empty index is returned so that a new repository can be
+// created.
+func ParseRepositoryIndex(data []byte) (*RepositoryIndex, error) {
+	index := NewRepositoryIndex()
+	if len(data) == 0 {
+		return index, nil
+	}
+
+	if err := yaml.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("failed to decode chart repository index: %w", err)
+	}
+
+	if index.APIVersion != RepositoryIndexAPIVersion {
+		return nil, fmt.Errorf("unsupported chart repository index apiVersion %q, expected %q", index.APIVersion, RepositoryIndexAPIVersion)
+	}
+
+	if index.Entries == nil {
+		index.Entries = map[string][]map[string]any{}
+	}
+
+	return index, nil
+}
+
+// AddChart adds the given packaged chart to the index, to be downloaded from
+// baseURL, and records that it was created at the given time. Versions of
+// each chart are kept sorted newest first, as in 'helm repo index'.
+// False is returned if the same chart version is already in the index with
+// the same digest, in which case the index is unchanged. An error is returned
+// if the chart version is already in the index with a different digest, since
+// published chart versions must never change.
+func (i *RepositoryIndex) AddChart(chart manifests.Chart, baseURL string, created time.Time) (bool, error) {
+	digest, err := chartDigest(chart.Path())
+	if err != nil {
+		return false, fmt.Errorf("failed to compute digest of chart %q: %w", chart.Path(), err)
+	}
+
+	for _, existing := range i.Entries[chart.Name()] {
+		if existing["version"] != chart.Version() {
+			continue
+		}
+
+		if existing["digest"] != digest {
+			return false, fmt.Errorf("chart %q version %q is already in the index with digest %v, refusing to replace it with digest %q", chart.Name(), chart.Version(), existing["digest"], digest)
+		}
+
+		return false, nil
+	}
+
+	chartYAML, err := chart.ChartYAML()
+	if err != nil {
+		return false, fmt.Errorf("failed to read Chart.yaml from %q: %w", chart.Path(), err)
+	}
+
+	entry := map[string]any{}
+	if err := yaml.Unmarshal(chartYAML, &entry); err != nil {
+		return false, fmt.Errorf("failed to decode Chart.yaml from %q: %w", chart.Path(), err)
+	}
+
+	// as in 'helm repo index', the version from the chart package is used
+	// so that it matches the package file name
+	entry["version"] = chart.Version()
+	entry["urls"] = []any{strings.TrimSuffix(baseURL, "/") + "/" + chart.PackageFileName()}
+	entry["created"] = created.UTC().Format(time.RFC3339Nano)
+	entry["digest"] = digest
+
+	versions := append(i.Entries[chart.Name()], entry)
+	sortChartVersions(versions)
+	i.Entries[chart.Name()] = versions
+
+	return true, nil
+}
+
+// Marshal encodes the index as YAML, recording that it was generated at the
+// given time.
+func (i *RepositoryIndex) Marshal(generated time.Time) ([]byte, error) {
+	i.Generated = generated.UTC()
+
+	return yaml.Marshal(i)
+}
+
+// sortChartVersions sorts the given chart versions newest first. Versions
+// which aren't valid semver are sorted after all valid versions.
+func sortChartVersions(versions []map[string]any) {
+	sort.SliceStable(versions, func(a, b int) bool {
+		va, errA := semver.ParseTolerant(fmt.Sprint(versions[a]["version"]))
+		vb, errB := semver.ParseTolerant(fmt.Sprint(versions[b]["version"]))
+
+		switch {
+		case errA != nil:
+			return false
+		case errB != nil:
+			return true
+		default:
+			return va.GT(vb)
+		}
+	})
+}
+
+// chartDigest returns the hex-encoded SHA256 sum of the packaged chart at path,
+// as recorded in chart repository indexes.
+func chartDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
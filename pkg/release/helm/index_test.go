@@ -0,0 +1,188 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/release/pkg/release/manifests"
+)
+
+const existingIndex = `apiVersion: v1
+entries:
+  cert-manager:
+  - apiVersion: v1
+    name: cert-manager
+    version: v0.2.0
+    digest: abc
+    urls:
+    - https://charts.jetstack.io/charts/cert-manager-v0.2.0.tgz
+    annotations:
+      artifacthub.io/license: Apache-2.0
+  - apiVersion: v1
+    name: cert-manager
+    version: v0.0.1
+    digest: def
+    urls:
+    - https://charts.jetstack.io/charts/cert-manager-v0.0.1.tgz
+  other-chart:
+  - name: other-chart
+    version: 1.0.0
+generated: "2021-01-01T00:00:00Z"
+`
+
+func TestRepositoryIndexAddChart(t *testing.T) {
+	chart, err := manifests.NewChart("testdata/cert-manager-v0.1.0-test.1.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest, err := chartDigest(chart.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)
+
+	tests := map[string]struct {
+		index string
+		// addTwice adds the chart to the index before the add being tested
+		addTwice       bool
+		expectErr      bool
+		expectAdded    bool
+		expectVersions []string
+	}{
+		"new repository": {
+			index:          "",
+			expectAdded:    true,
+			expectVersions: []string{"v0.1.0-test.1"},
+		},
+		"chart is merged into existing versions, newest first": {
+			index:          existingIndex,
+			expectAdded:    true,
+			expectVersions: []string{"v0.2.0", "v0.1.0-test.1", "v0.0.1"},
+		},
+		"chart already in the index with the same digest": {
+			index:          existingIndex,
+			addTwice:       true,
+			expectAdded:    false,
+			expectVersions: []string{"v0.2.0", "v0.1.0-test.1", "v0.0.1"},
+		},
+		"chart already in the index with a different digest": {
+			index:     strings.Replace(existingIndex, "version: v0.2.0", "version: v0.1.0-test.1", 1),
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			index, err := ParseRepositoryIndex([]byte(test.index))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.addTwice {
+				if _, err := index.AddChart(*chart, "https://charts.jetstack.io/charts", created); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			added, err := index.AddChart(*chart, "https://charts.jetstack.io/charts/", created)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if test.expectErr {
+				return
+			}
+
+			if added != test.expectAdded {
+				t.Errorf("expectAdded=%v but got added=%v", test.expectAdded, added)
+			}
+
+			var versions []string
+			for _, entry := range index.Entries["cert-manager"] {
+				versions = append(versions, entry["version"].(string))
+			}
+
+			if strings.Join(versions, ",") != strings.Join(test.expectVersions, ",") {
+				t.Errorf("expected versions %q but got %q", test.expectVersions, versions)
+			}
+
+			out, err := index.Marshal(created)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			reparsed, err := ParseRepositoryIndex(out)
+			if err != nil {
+				t.Fatalf("failed to parse marshalled index: %v", err)
+			}
+
+			if !reparsed.Generated.Equal(created) {
+				t.Errorf("expected generated time %s but got %s", created, reparsed.Generated)
+			}
+
+			var entry map[string]any
+			for _, e := range reparsed.Entries["cert-manager"] {
+				if e["version"] == "v0.1.0-test.1" {
+					entry = e
+				}
+			}
+
+			if entry == nil {
+				t.Fatalf("expected added chart in marshalled index:\n%s", out)
+			}
+
+			if entry["digest"] != digest {
+				t.Errorf("expected digest %q but got %v", digest, entry["digest"])
+			}
+
+			urls, _ := entry["urls"].([]any)
+			if len(urls) != 1 || urls[0] != "https://charts.jetstack.io/charts/cert-manager-v0.1.0-test.1.tgz" {
+				t.Errorf("unexpected urls %v", entry["urls"])
+			}
+
+			if entry["created"] != "2021-02-03T04:05:06Z" {
+				t.Errorf("unexpected created time %v", entry["created"])
+			}
+
+			if entry["description"] != "A Helm chart for Kubernetes" {
+				t.Errorf("expected fields from Chart.yaml to be included, got %v", entry)
+			}
+
+			if test.index != "" {
+				if len(reparsed.Entries["other-chart"]) != 1 {
+					t.Errorf("expected other charts to be preserved")
+				}
+
+				annotations, _ := reparsed.Entries["cert-manager"][0]["annotations"].(map[string]any)
+				if annotations["artifacthub.io/license"] != "Apache-2.0" {
+					t.Errorf("expected unknown fields of existing entries to be preserved, got %v", reparsed.Entries["cert-manager"][0])
+				}
+			}
+		})
+	}
+}
+
+func TestParseRepositoryIndexAPIVersion(t *testing.T) {
+	if _, err := ParseRepositoryIndex([]byte("apiVersion: v2\nentries: {}\n")); err == nil {
+		t.Errorf("expected an error for an unsupported apiVersion")
+	}
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/cert-manager/release/pkg/release/manifests"
+)
+
+// The media types used by Helm when storing charts in OCI registries.
+// See https://helm.sh/docs/topics/registries/
+const (
+	ChartConfigMediaType     types.MediaType = "application/vnd.cncf.helm.config.v1+json"
+	ChartContentMediaType    types.MediaType = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+	ChartProvenanceMediaType types.MediaType = "application/vnd.cncf.helm.chart.provenance.v1.prov"
+)
+
+// OCIChartReference returns the reference which the given version of the
+// named chart is pushed to in the OCI registry at registryURL, which must be
+// prefixed with "oci://". As in 'helm push', the chart version is used as the
+// tag, with any "+" replaced by "_" since "+" isn't allowed in tags.
+func OCIChartReference(registryURL string, chartName string, version string) (string, error) {
+	if !strings.HasPrefix(registryURL, "oci://") {
+		return "", fmt.Errorf("OCI registry %q must be prefixed with \"oci://\"", registryURL)
+	}
+
+	repo := strings.TrimSuffix(strings.TrimPrefix(registryURL, "oci://"), "/")
+
+	return fmt.Sprintf("%s/%s:%s", repo, chartName, strings.ReplaceAll(version, "+", "_")), nil
+}
+
+// PushChartOCI pushes the given packaged chart, along with its provenance file
+// if it has one, to the OCI registry at registryURL in the same format as
+// 'helm push'. The reference of the pushed chart, including its digest, is
+// returned.
+func PushChartOCI(ctx context.Context, chart manifests.Chart, registryURL string, opts ...remote.Option) (string, error) {
+	rawRef, err := OCIChartReference(registryURL, chart.Name(), chart.Version())
+	if err != nil {
+		return "", err
+	}
+
+	ref, err := name.NewTag(rawRef)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse chart reference %q: %w", rawRef, err)
+	}
+
+	opts = append([]remote.Option{remote.WithContext(ctx)}, opts...)
+
+	chartYAML, err := chart.ChartYAML()
+	if err != nil {
+		return "", fmt.Errorf("failed to read Chart.yaml from %q: %w", chart.Path(), err)
+	}
+
+	config, err := yaml.YAMLToJSON(chartYAML)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert Chart.yaml from %q to JSON: %w", chart.Path(), err)
+	}
+
+	content, err := os.ReadFile(chart.Path())
+	if err != nil {
+		return "", fmt.Errorf("failed to read chart %q: %w", chart.Path(), err)
+	}
+
+	blobs := []chartBlob{
+		{config, ChartConfigMediaType},
+		{content, ChartContentMediaType},
+	}
+
+	if provPath := chart.ProvPath(); provPath != nil {
+		prov, err := os.ReadFile(*provPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read chart provenance file %q: %w", *provPath, err)
+		}
+
+		blobs = append(blobs, chartBlob{prov, ChartProvenanceMediaType})
+	}
+
+	var descriptors []v1.Descriptor
+	for _, blob := range blobs {
+		layer := static.NewLayer(blob.data, blob.mediaType)
+
+		if err := remote.WriteLayer(ref.Context(), layer, opts...); err != nil {
+			return "", fmt.Errorf("failed to push %s blob for chart %q: %w", blob.mediaType, rawRef, err)
+		}
+
+		digest, err := layer.Digest()
+		if err != nil {
+			return "", err
+		}
+
+		descriptors = append(descriptors, v1.Descriptor{
+			MediaType: blob.mediaType,
+			Size:      int64(len(blob.data)),
+			Digest:    digest,
+		})
+	}
+
+	manifest, err := json.Marshal(v1.Manifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIManifestSchema1,
+		Config:        descriptors[0],
+		Layers:        descriptors[1:],
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode manifest for chart %q: %w", rawRef, err)
+	}
+
+	if err := remote.Put(ref, rawManifest(manifest), opts...); err != nil {
+		return "", fmt.Errorf("failed to push manifest for chart %q: %w", rawRef, err)
+	}
+
+	digest, _, err := v1.SHA256(bytes.NewReader(manifest))
+	if err != nil {
+		return "", err
+	}
+
+	return ref.Context().Digest(digest.String()).String(), nil
+}
+
+// chartBlob is the content of a blob pushed as part of a chart.
+type chartBlob struct {
+	data      []byte
+	mediaType types.MediaType
+}
+
+// rawManifest is an OCI manifest which has already been encoded.
+type rawManifest []byte
+
+func (m rawManifest) RawManifest() ([]byte, error) {
+	return m, nil
+}
+
+func (m rawManifest) MediaType() (types.MediaType, error) {
+	return types.OCIManifestSchema1, nil
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/cert-manager/release/pkg/release/manifests"
+)
+
+func TestOCIChartReference(t *testing.T) {
+	tests := map[string]struct {
+		registryURL string
+		version     string
+		expected    string
+		expectErr   bool
+	}{
+		"registry": {
+			registryURL: "oci://quay.io/jetstack/charts",
+			version:     "v1.15.0",
+			expected:    "quay.io/jetstack/charts/cert-manager:v1.15.0",
+		},
+		"trailing slash": {
+			registryURL: "oci://quay.io/jetstack/charts/",
+			version:     "v1.15.0",
+			expected:    "quay.io/jetstack/charts/cert-manager:v1.15.0",
+		},
+		"build metadata": {
+			registryURL: "oci://quay.io/jetstack/charts",
+			version:     "v1.15.0+abc",
+			expected:    "quay.io/jetstack/charts/cert-manager:v1.15.0_abc",
+		},
+		"missing oci prefix": {
+			registryURL: "https://charts.jetstack.io",
+			version:     "v1.15.0",
+			expectErr:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ref, err := OCIChartReference(test.registryURL, "cert-manager", test.version)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if ref != test.expected {
+				t.Errorf("unexpected reference, exp=%q got=%q", test.expected, ref)
+			}
+		})
+	}
+}
+
+func TestPushChartOCI(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	t.Cleanup(server.Close)
+
+	registryURL := "oci://" + strings.TrimPrefix(server.URL, "http://") + "/jetstack/charts"
+
+	chart, err := manifests.NewChart("testdata/cert-manager-v0.1.0-test.1.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pushed, err := PushChartOCI(context.TODO(), *chart, registryURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(pushed, "/jetstack/charts/cert-manager@sha256:") {
+		t.Errorf("expected pushed reference to contain the chart digest, got %q", pushed)
+	}
+
+	rawRef, err := OCIChartReference(registryURL, chart.Name(), chart.Version())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref, err := name.ParseReference(rawRef)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := remote.Image(ref)
+	if err != nil {
+		t.Fatalf("failed to fetch pushed chart: %v", err)
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasSuffix(pushed, digest.String()) {
+		t.Errorf("expected pushed reference %q to have digest %q", pushed, digest)
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if manifest.MediaType != types.OCIManifestSchema1 {
+		t.Errorf("unexpected manifest media type %q", manifest.MediaType)
+	}
+
+	if manifest.Config.MediaType != ChartConfigMediaType {
+		t.Errorf("unexpected config media type %q", manifest.Config.MediaType)
+	}
+
+	config, err := img.RawConfigFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(config), `"version":"`+chart.Version()+`"`) {
+		t.Errorf("expected config to contain the chart metadata as JSON, got %s", config)
+	}
+
+	if len(manifest.Layers) != 1 || manifest.Layers[0].MediaType != ChartContentMediaType {
+		t.Fatalf("expected a single chart content layer, got %+v", manifest.Layers)
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rc, err := layers[0].Compressed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := os.ReadFile(chart.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(content) != string(expected) {
+		t.Errorf("pushed chart content does not match the packaged chart")
+	}
+}
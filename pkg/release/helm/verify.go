@@ -0,0 +1,72 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// CommandRunner runs the given command with the given arguments. It is used to
+// abstract away invocations of the helm CLI so that they can be tested.
+type CommandRunner func(ctx context.Context, cmd string, args ...string) error
+
+// ChartVerifier checks that a published Helm chart can be pulled from a chart
+// repository and rendered by the helm CLI.
+type ChartVerifier struct {
+	helmPath string
+	repoURL  string
+	run      CommandRunner
+}
+
+// NewChartVerifier returns a ChartVerifier which uses the helm binary at
+// helmPath to render charts from the repository at repoURL. repoURL can either
+// be the URL of a classic HTTP chart repository or an OCI registry reference
+// prefixed with "oci://".
+func NewChartVerifier(helmPath string, repoURL string, run CommandRunner) *ChartVerifier {
+	return &ChartVerifier{
+		helmPath: helmPath,
+		repoURL:  repoURL,
+		run:      run,
+	}
+}
+
+// Verify runs 'helm template' against the given version of the named chart as
+// published in the chart repository, returning an error if the chart cannot
+// be fetched or fails to render.
+func (v *ChartVerifier) Verify(ctx context.Context, chartName string, version string) error {
+	if err := v.run(ctx, v.helmPath, v.templateArgs(chartName, version)...); err != nil {
+		return fmt.Errorf("failed to render chart %q with version %q from %q: %w", chartName, version, v.repoURL, err)
+	}
+
+	return nil
+}
+
+// templateArgs constructs the arguments passed to helm to render the given
+// chart version.
+func (v *ChartVerifier) templateArgs(chartName string, version string) []string {
+	args := []string{"template", chartName}
+
+	if strings.HasPrefix(v.repoURL, "oci://") {
+		args = append(args, strings.TrimSuffix(v.repoURL, "/")+"/"+chartName)
+	} else {
+		args = append(args, chartName, "--repo", v.repoURL)
+	}
+
+	return append(args, "--version", version)
+}
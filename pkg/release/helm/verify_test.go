@@ -0,0 +1,83 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestChartVerifierVerify(t *testing.T) {
+	tests := map[string]struct {
+		repoURL string
+		runErr  error
+
+		expCmd  string
+		expArgs []string
+		expErr  bool
+	}{
+		"http chart repository": {
+			repoURL: "https://charts.jetstack.io",
+			expCmd:  "/usr/local/bin/helm",
+			expArgs: []string{"template", "cert-manager", "cert-manager", "--repo", "https://charts.jetstack.io", "--version", "v1.2.3"},
+		},
+		"oci chart repository": {
+			repoURL: "oci://quay.io/jetstack/charts/",
+			expCmd:  "/usr/local/bin/helm",
+			expArgs: []string{"template", "cert-manager", "oci://quay.io/jetstack/charts/cert-manager", "--version", "v1.2.3"},
+		},
+		"failure to render is surfaced": {
+			repoURL: "https://charts.jetstack.io",
+			runErr:  errors.New("exit status 1"),
+			expCmd:  "/usr/local/bin/helm",
+			expArgs: []string{"template", "cert-manager", "cert-manager", "--repo", "https://charts.jetstack.io", "--version", "v1.2.3"},
+			expErr:  true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var gotCmd string
+			var gotArgs []string
+
+			run := func(_ context.Context, cmd string, args ...string) error {
+				gotCmd = cmd
+				gotArgs = args
+				return test.runErr
+			}
+
+			err := NewChartVerifier("/usr/local/bin/helm", test.repoURL, run).Verify(context.TODO(), "cert-manager", "v1.2.3")
+			if (err != nil) != test.expErr {
+				t.Errorf("unexpected error, exp=%t got=%v", test.expErr, err)
+			}
+
+			if test.runErr != nil && !errors.Is(err, test.runErr) {
+				t.Errorf("expected error to wrap %q, got=%v", test.runErr, err)
+			}
+
+			if gotCmd != test.expCmd {
+				t.Errorf("unexpected command, exp=%q got=%q", test.expCmd, gotCmd)
+			}
+
+			if !reflect.DeepEqual(gotArgs, test.expArgs) {
+				t.Errorf("unexpected args, exp=%q got=%q", test.expArgs, gotArgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// BuildIndex assembles an in-memory OCI image index from the given image
+// tars, which are expected to be the per-architecture builds of a single
+// component. The platform of each manifest in the index is taken from the OS
+// and architecture of the Tar, and must match the image config if it sets them.
+func BuildIndex(tars []*Tar) (v1.ImageIndex, error) {
+	if len(tars) == 0 {
+		return nil, fmt.Errorf("cannot build an image index with no images")
+	}
+
+	idx := mutate.IndexMediaType(empty.Index, types.OCIImageIndex)
+
+	seen := map[string]string{}
+
+	for _, t := range tars {
+		platform := &v1.Platform{
+			OS:           t.OS(),
+			Architecture: t.Architecture(),
+		}
+
+		if prev, ok := seen[platform.String()]; ok {
+			return nil, fmt.Errorf("images %q and %q both have platform %s", prev, t.RawImageName(), platform)
+		}
+		seen[platform.String()] = t.RawImageName()
+
+		img, err := tarball.ImageFromPath(t.Filepath(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load image %q from %q: %w", t.RawImageName(), t.Filepath(), err)
+		}
+
+		cfg, err := img.ConfigFile()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config of image %q: %w", t.RawImageName(), err)
+		}
+
+		if cfg.OS != "" && cfg.OS != platform.OS {
+			return nil, fmt.Errorf("image %q has OS %q in its config but was expected to be %q", t.RawImageName(), cfg.OS, platform.OS)
+		}
+
+		if cfg.Architecture != "" && cfg.Architecture != platform.Architecture {
+			return nil, fmt.Errorf("image %q has architecture %q in its config but was expected to be %q", t.RawImageName(), cfg.Architecture, platform.Architecture)
+		}
+
+		idx = mutate.AppendManifests(idx, mutate.IndexAddendum{
+			Add: img,
+			Descriptor: v1.Descriptor{
+				Platform: platform,
+			},
+		})
+	}
+
+	return idx, nil
+}
+
+// IndexPlatforms returns the sorted platforms of each manifest in the given
+// image index, formatted as "os/arch".
+func IndexPlatforms(idx v1.ImageIndex) ([]string, error) {
+	manifest, err := idx.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index manifest: %w", err)
+	}
+
+	var platforms []string
+	for _, desc := range manifest.Manifests {
+		if desc.Platform == nil {
+			return nil, fmt.Errorf("manifest %s in index has no platform", desc.Digest)
+		}
+
+		platforms = append(platforms, desc.Platform.String())
+	}
+
+	sort.Strings(platforms)
+
+	return platforms, nil
+}
+
+// VerifyIndexPlatforms checks that the given image index contains exactly
+// one manifest for each of the expected platforms, formatted as "os/arch".
+func VerifyIndexPlatforms(idx v1.ImageIndex, expected []string) error {
+	platforms, err := IndexPlatforms(idx)
+	if err != nil {
+		return err
+	}
+
+	found := map[string]bool{}
+	for _, p := range platforms {
+		found[p] = true
+	}
+
+	wanted := map[string]bool{}
+	var missing []string
+	for _, p := range expected {
+		wanted[p] = true
+
+		if !found[p] {
+			missing = append(missing, p)
+		}
+	}
+
+	var unexpected []string
+	for _, p := range platforms {
+		if !wanted[p] {
+			unexpected = append(unexpected, p)
+		}
+	}
+
+	sort.Strings(missing)
+
+	var problems []string
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Sprintf("missing platforms: %s", strings.Join(missing, ", ")))
+	}
+
+	if len(unexpected) > 0 {
+		problems = append(problems, fmt.Sprintf("unexpected platforms: %s", strings.Join(unexpected, ", ")))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("image index platforms do not match: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
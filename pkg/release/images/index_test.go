@@ -0,0 +1,155 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+// writeFixtureImage writes a random single-layer image to a docker image tar,
+// setting the given OS and architecture in its config, and returns a Tar
+// for it with the platform given by tarOS and tarArch.
+func writeFixtureImage(t *testing.T, imageName, cfgOS, cfgArch, tarOS, tarArch string) *Tar {
+	t.Helper()
+
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg = cfg.DeepCopy()
+	cfg.OS = cfgOS
+	cfg.Architecture = cfgArch
+
+	img, err = mutate.ConfigFile(img, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag, err := name.NewTag(imageName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "image.tar")
+	if err := tarball.WriteToFile(path, tag, img); err != nil {
+		t.Fatal(err)
+	}
+
+	imageTar, err := NewTar(path, tarOS, tarArch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return imageTar
+}
+
+func TestBuildIndex(t *testing.T) {
+	fixture := func(arches ...string) []*Tar {
+		var tars []*Tar
+		for _, arch := range arches {
+			tars = append(tars, writeFixtureImage(t, "quay.io/jetstack/cert-manager-controller-"+arch+":v1.14.0", "linux", arch, "linux", arch))
+		}
+		return tars
+	}
+
+	tests := map[string]struct {
+		tars     []*Tar
+		expected []string
+
+		expPlatforms []string
+		expBuildErr  bool
+		expVerifyErr bool
+	}{
+		"all expected platforms are present": {
+			tars:         fixture("amd64", "arm64", "s390x"),
+			expected:     []string{"linux/amd64", "linux/arm64", "linux/s390x"},
+			expPlatforms: []string{"linux/amd64", "linux/arm64", "linux/s390x"},
+		},
+		"missing platform": {
+			tars:         fixture("amd64", "arm64"),
+			expected:     []string{"linux/amd64", "linux/arm64", "linux/s390x"},
+			expPlatforms: []string{"linux/amd64", "linux/arm64"},
+			expVerifyErr: true,
+		},
+		"unexpected platform": {
+			tars:         fixture("amd64", "arm64", "ppc64le"),
+			expected:     []string{"linux/amd64", "linux/arm64"},
+			expPlatforms: []string{"linux/amd64", "linux/arm64", "linux/ppc64le"},
+			expVerifyErr: true,
+		},
+		"image config with no platform uses the tar platform": {
+			tars: []*Tar{
+				writeFixtureImage(t, "quay.io/jetstack/cert-manager-controller-amd64:v1.14.0", "", "", "linux", "amd64"),
+			},
+			expected:     []string{"linux/amd64"},
+			expPlatforms: []string{"linux/amd64"},
+		},
+		"image config architecture doesn't match tar": {
+			tars: []*Tar{
+				writeFixtureImage(t, "quay.io/jetstack/cert-manager-controller-amd64:v1.14.0", "linux", "arm64", "linux", "amd64"),
+			},
+			expBuildErr: true,
+		},
+		"duplicate platform": {
+			tars:        append(fixture("amd64"), fixture("amd64")...),
+			expBuildErr: true,
+		},
+		"no images": {
+			expBuildErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			idx, err := BuildIndex(test.tars)
+			if (err != nil) != test.expBuildErr {
+				t.Fatalf("expBuildErr=%v but got err=%v", test.expBuildErr, err)
+			}
+
+			if test.expBuildErr {
+				return
+			}
+
+			platforms, err := IndexPlatforms(idx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(platforms, test.expPlatforms) {
+				t.Errorf("unexpected platforms: got=%q, exp=%q", platforms, test.expPlatforms)
+			}
+
+			err = VerifyIndexPlatforms(idx, test.expected)
+			if (err != nil) != test.expVerifyErr {
+				t.Errorf("expVerifyErr=%v but got err=%v", test.expVerifyErr, err)
+			}
+		})
+	}
+}
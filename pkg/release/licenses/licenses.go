@@ -0,0 +1,252 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package licenses checks that release artifacts bundle the license files
+// required to distribute them.
+package licenses
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	// LicenseFileName is the name of the file containing the license of
+	// cert-manager itself.
+	LicenseFileName = "LICENSE"
+
+	// LicensesFileName is the name of the file listing the license of every
+	// third party Go module bundled into cert-manager binaries, as generated
+	// by go-licenses in CSV format.
+	LicensesFileName = "LICENSES"
+)
+
+// Archive describes the license files found in a release archive.
+type Archive struct {
+	// HasLicense is true if the archive contains a LICENSE file.
+	HasLicense bool
+
+	// Licenses is the content of the LICENSES file in the archive, or nil if
+	// there isn't one.
+	Licenses []byte
+}
+
+// ScanArchive finds the license files in the gzipped tar or zip archive at
+// path, in any directory of the archive. The format of the archive is
+// determined by its extension.
+func ScanArchive(archivePath string) (*Archive, error) {
+	if strings.HasSuffix(archivePath, ".zip") {
+		return scanZip(archivePath)
+	}
+
+	return scanTarGz(archivePath)
+}
+
+func scanTarGz(archivePath string) (*Archive, error) {
+	var archive Archive
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q as a gzip file: %w", archivePath, err)
+	}
+
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %q as a tar archive: %w", archivePath, err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if err := archive.add(header.Name, tr); err != nil {
+			return nil, fmt.Errorf("failed to read %q from %q: %w", header.Name, archivePath, err)
+		}
+	}
+
+	return &archive, nil
+}
+
+func scanZip(archivePath string) (*Archive, error) {
+	var archive Archive
+
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q as a zip archive: %w", archivePath, err)
+	}
+
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		r, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %q in %q: %w", f.Name, archivePath, err)
+		}
+
+		err = archive.add(f.Name, r)
+		r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %q from %q: %w", f.Name, archivePath, err)
+		}
+	}
+
+	return &archive, nil
+}
+
+// add records the file with the given name in the archive if it's a license
+// file.
+func (a *Archive) add(name string, r io.Reader) error {
+	switch path.Base(name) {
+	case LicenseFileName:
+		a.HasLicense = true
+
+	case LicensesFileName:
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+
+		a.Licenses = data
+	}
+
+	return nil
+}
+
+// ParseLicenses returns the Go package paths listed in the given LICENSES
+// file, which has a package path in the first column of each line.
+func ParseLicenses(data []byte) ([]string, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s file: %w", LicensesFileName, err)
+	}
+
+	var packages []string
+	for i, record := range records {
+		pkg := strings.TrimSpace(record[0])
+		if pkg == "" {
+			return nil, fmt.Errorf("line %d of %s file has no package path", i+1, LicensesFileName)
+		}
+
+		packages = append(packages, pkg)
+	}
+
+	return packages, nil
+}
+
+// CompareToModules checks the Go packages listed in a LICENSES file against
+// the dependencies required by the given go.mod files, returning violations
+// describing each mismatch:
+//
+//   - every listed package must belong to a required module or to one of the
+//     modules defined by the go.mod files, so that stale licenses are caught
+//   - every module directly required by the go.mod files must have a package
+//     listed, unless it's in ignoredModules, such as a module only imported by
+//     tests
+func CompareToModules(packages []string, modFiles []*modfile.File, ignoredModules []string) []string {
+	ownModules := sets.NewString()
+	required := sets.NewString()
+	direct := sets.NewString()
+
+	for _, f := range modFiles {
+		ownModules.Insert(f.Module.Mod.Path)
+
+		for _, req := range f.Require {
+			required.Insert(req.Mod.Path)
+			if !req.Indirect {
+				direct.Insert(req.Mod.Path)
+			}
+		}
+	}
+
+	// the core module is often required by submodules, but isn't a third
+	// party dependency
+	direct = direct.Difference(ownModules).Difference(sets.NewString(ignoredModules...))
+
+	var violations []string
+
+	licensed := sets.NewString()
+	for _, pkg := range packages {
+		if moduleForPackage(ownModules, pkg) != "" {
+			continue
+		}
+
+		mod := moduleForPackage(required, pkg)
+		if mod == "" {
+			violations = append(violations, fmt.Sprintf("%s lists %q, which isn't in any required module", LicensesFileName, pkg))
+			continue
+		}
+
+		licensed.Insert(mod)
+	}
+
+	for _, mod := range direct.Difference(licensed).List() {
+		violations = append(violations, fmt.Sprintf("%s has no license for %q, which is a direct dependency", LicensesFileName, mod))
+	}
+
+	return violations
+}
+
+// moduleForPackage returns the longest module path in modules which contains
+// the given package, or an empty string if no module contains it.
+func moduleForPackage(modules sets.String, pkg string) string {
+	candidates := modules.List()
+	sort.Slice(candidates, func(i, j int) bool {
+		return len(candidates[i]) > len(candidates[j])
+	})
+
+	for _, mod := range candidates {
+		if pkg == mod || strings.HasPrefix(pkg, mod+"/") {
+			return mod
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package licenses
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/mod/modfile"
+)
+
+const testLicenses = `github.com/cert-manager/cert-manager,https://github.com/cert-manager/cert-manager/blob/HEAD/LICENSE,Apache-2.0
+github.com/go-logr/logr,https://github.com/go-logr/logr/blob/v1.4.1/LICENSE,Apache-2.0
+golang.org/x/net/http2,https://cs.opensource.google/go/x/net/+/v0.23.0:LICENSE,BSD-3-Clause
+`
+
+func TestScanArchive(t *testing.T) {
+	tests := map[string]struct {
+		files map[string]string
+		zip   bool
+
+		expectedHasLicense bool
+		expectedLicenses   string
+	}{
+		"tar with both files": {
+			files: map[string]string{
+				"cert-manager-server-linux-amd64/LICENSE":  "Apache License",
+				"cert-manager-server-linux-amd64/LICENSES": testLicenses,
+			},
+			expectedHasLicense: true,
+			expectedLicenses:   testLicenses,
+		},
+		"tar without license files": {
+			files: map[string]string{
+				"deploy/manifests/cert-manager.yaml": "kind: Deployment",
+			},
+		},
+		"zip with both files": {
+			files: map[string]string{
+				"LICENSE":        "Apache License",
+				"LICENSES":       testLicenses,
+				"cmctl.exe":      "binary",
+				"docs/README.md": "readme",
+			},
+			zip:                true,
+			expectedHasLicense: true,
+			expectedLicenses:   testLicenses,
+		},
+		"zip with only LICENSE": {
+			files:              map[string]string{"LICENSE": "Apache License"},
+			zip:                true,
+			expectedHasLicense: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var path string
+			if test.zip {
+				path = writeZip(t, test.files)
+			} else {
+				path = writeTarGz(t, test.files)
+			}
+
+			archive, err := ScanArchive(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if archive.HasLicense != test.expectedHasLicense {
+				t.Errorf("expected HasLicense=%v but got %v", test.expectedHasLicense, archive.HasLicense)
+			}
+
+			if string(archive.Licenses) != test.expectedLicenses {
+				t.Errorf("wanted licenses %q but got %q", test.expectedLicenses, archive.Licenses)
+			}
+		})
+	}
+}
+
+func TestParseLicenses(t *testing.T) {
+	packages, err := ParseLicenses([]byte(testLicenses))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"github.com/cert-manager/cert-manager", "github.com/go-logr/logr", "golang.org/x/net/http2"}
+	if !reflect.DeepEqual(packages, expected) {
+		t.Errorf("wanted packages %q but got %q", expected, packages)
+	}
+
+	if _, err := ParseLicenses([]byte(",https://example.com,MIT\n")); err == nil {
+		t.Errorf("expected an error for a line without a package path")
+	}
+}
+
+func TestCompareToModules(t *testing.T) {
+	goMod := `module github.com/cert-manager/cert-manager
+
+go 1.22.0
+
+require (
+	github.com/go-logr/logr v1.4.1
+	github.com/stretchr/testify v1.9.0
+	golang.org/x/net v0.23.0 // indirect
+)
+`
+
+	tests := map[string]struct {
+		packages       []string
+		ignoredModules []string
+
+		expectedViolations int
+	}{
+		"all dependencies licensed": {
+			packages:       []string{"github.com/cert-manager/cert-manager", "github.com/go-logr/logr", "golang.org/x/net/http2"},
+			ignoredModules: []string{"github.com/stretchr/testify"},
+		},
+		"direct dependency missing": {
+			packages:           []string{"github.com/go-logr/logr"},
+			expectedViolations: 1,
+		},
+		"missing indirect dependency is allowed": {
+			packages:       []string{"github.com/go-logr/logr"},
+			ignoredModules: []string{"github.com/stretchr/testify"},
+		},
+		"package not in any required module": {
+			packages:           []string{"github.com/go-logr/logr", "github.com/stretchr/testify", "example.com/removed"},
+			expectedViolations: 1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			f, err := modfile.Parse("go.mod", []byte(goMod), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			violations := CompareToModules(test.packages, []*modfile.File{f}, test.ignoredModules)
+			if len(violations) != test.expectedViolations {
+				t.Errorf("expected %d violations but got %d: %q", test.expectedViolations, len(violations), violations)
+			}
+		})
+	}
+}
+
+func writeTarGz(t *testing.T, files map[string]string) string {
+	buf := &bytes.Buffer{}
+	gzw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gzw)
+
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Size: int64(len(content)), Mode: 0o644, Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "archive.tar.gz")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func writeZip(t *testing.T, files map[string]string) string {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "archive.zip")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
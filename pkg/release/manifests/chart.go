@@ -98,6 +98,10 @@ func (c *Chart) ProvPath() *string {
 	return c.provPath
 }
 
+func (c *Chart) Name() string {
+	return c.meta.Name
+}
+
 func (c *Chart) Version() string {
 	return c.meta.Version
 }
@@ -105,3 +109,41 @@ func (c *Chart) Version() string {
 func (c *Chart) AppVersion() string {
 	return c.meta.AppVersion
 }
+
+// Values returns the contents of the chart's default values.yaml file.
+func (c *Chart) Values() ([]byte, error) {
+	f, err := os.Open(c.path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	defer gzr.Close()
+
+	return tar.ReadSingleFile(c.meta.Name+"/values.yaml", gzr)
+}
+
+// ChartYAML returns the contents of the chart's Chart.yaml file.
+func (c *Chart) ChartYAML() ([]byte, error) {
+	f, err := os.Open(c.path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	defer gzr.Close()
+
+	return tar.ReadSingleFile(c.meta.Name+"/Chart.yaml", gzr)
+}
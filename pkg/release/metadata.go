@@ -16,8 +16,23 @@ limitations under the License.
 
 package release
 
+import (
+	"fmt"
+	"strings"
+)
+
+// CurrentMetadataSchemaVersion is the version of the release metadata schema
+// written by this version of cmrel. Metadata which doesn't specify a schema
+// version predates schema versioning, and is treated as version 0.
+const CurrentMetadataSchemaVersion = 1
+
 // Metadata about a staged release.
 type Metadata struct {
+	// SchemaVersion is the version of the schema this metadata conforms to.
+	// Older metadata can be upgraded to CurrentMetadataSchemaVersion using
+	// MigrateMetadata.
+	SchemaVersion int `json:"schemaVersion,omitempty"`
+
 	// ReleaseVersion, if set, is an explicit version used to build the release
 	// artifacts.
 	// By default, the release version will be computed by the Bazel release
@@ -34,6 +49,10 @@ type Metadata struct {
 	// how they were produced. This is used as part of the migration from Bazel to
 	// Make. An empty BuildSource is assumed to mean Bazel produced the files.
 	BuildSource string `json:"buildSource,omitempty"`
+
+	// StagedBy, if set, identifies the user who requested that the release be
+	// staged.
+	StagedBy string `json:"stagedBy,omitempty"`
 }
 
 type ArtifactMetadata struct {
@@ -43,6 +62,9 @@ type ArtifactMetadata struct {
 	// SHA256 is a hash of the artifact, computed during the staging process.
 	SHA256 string `json:"sha256"`
 
+	// Size, if specified, is the size of the artifact in bytes.
+	Size int64 `json:"size,omitempty"`
+
 	// OS, if specified, is the OS parameter that this artifact was built for.
 	// This could be 'linux', 'darwin', 'windows' etc.
 	OS string `json:"os,omitempty"`
@@ -52,3 +74,63 @@ type ArtifactMetadata struct {
 	// This could be 'amd64', 'arm', 'arm64' etc.
 	Architecture string `json:"architecture,omitempty"`
 }
+
+// architectureIndependentArtifactKinds lists the kinds of artifact which are
+// not built for a particular OS and architecture, and so are allowed to have
+// an empty OS and Architecture in their metadata.
+var architectureIndependentArtifactKinds = []string{"manifests"}
+
+// IsArchitectureIndependent returns true if the artifact is not built for a
+// particular OS and architecture, e.g. the 'manifests' artifact.
+func (a ArtifactMetadata) IsArchitectureIndependent() bool {
+	for _, kind := range architectureIndependentArtifactKinds {
+		if strings.HasPrefix(a.Name, releaseObjectPrefix+kind) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Kind returns the kind of the artifact, such as 'server', 'manifests' or
+// 'cmctl', derived from its name by removing the common prefix, the archive
+// extension and, for architecture specific artifacts, the OS and architecture.
+func (a ArtifactMetadata) Kind() string {
+	kind := strings.TrimPrefix(a.Name, releaseObjectPrefix)
+	for _, ext := range []string{ArchiveFormatTarGz, ArchiveFormatZip} {
+		kind = strings.TrimSuffix(kind, ext)
+	}
+
+	if a.OS != "" && a.Architecture != "" {
+		kind = strings.TrimSuffix(kind, "-"+a.OS+"-"+a.Architecture)
+	}
+
+	return kind
+}
+
+// Validate returns an error if the artifact metadata is incomplete. All
+// artifacts must have a name and a hash, and artifacts which are not
+// architecture independent must specify both an OS and an Architecture.
+func (a ArtifactMetadata) Validate() error {
+	if a.Name == "" {
+		return fmt.Errorf("artifact has an empty name")
+	}
+
+	if a.SHA256 == "" {
+		return fmt.Errorf("artifact %q has an empty sha256", a.Name)
+	}
+
+	if a.IsArchitectureIndependent() {
+		return nil
+	}
+
+	if a.OS == "" {
+		return fmt.Errorf("artifact %q is architecture specific but has an empty os", a.Name)
+	}
+
+	if a.Architecture == "" {
+		return fmt.Errorf("artifact %q is architecture specific but has an empty architecture", a.Name)
+	}
+
+	return nil
+}
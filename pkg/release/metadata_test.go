@@ -0,0 +1,112 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import "testing"
+
+func TestArtifactMetadataValidate(t *testing.T) {
+	tests := map[string]struct {
+		artifact  ArtifactMetadata
+		expectErr bool
+	}{
+		"manifests with empty os and arch": {
+			artifact: ArtifactMetadata{
+				Name:   "cert-manager-manifests.tar.gz",
+				SHA256: "abc",
+			},
+			expectErr: false,
+		},
+		"server with os and arch": {
+			artifact: ArtifactMetadata{
+				Name:         "cert-manager-server-linux-amd64.tar.gz",
+				SHA256:       "abc",
+				OS:           "linux",
+				Architecture: "amd64",
+			},
+			expectErr: false,
+		},
+		"server with empty arch": {
+			artifact: ArtifactMetadata{
+				Name:   "cert-manager-server-linux-amd64.tar.gz",
+				SHA256: "abc",
+				OS:     "linux",
+			},
+			expectErr: true,
+		},
+		"ctl with empty os": {
+			artifact: ArtifactMetadata{
+				Name:         "cert-manager-cmctl-linux-amd64.tar.gz",
+				SHA256:       "abc",
+				Architecture: "amd64",
+			},
+			expectErr: true,
+		},
+		"empty sha256": {
+			artifact: ArtifactMetadata{
+				Name: "cert-manager-manifests.tar.gz",
+			},
+			expectErr: true,
+		},
+		"empty name": {
+			artifact: ArtifactMetadata{
+				SHA256: "abc",
+			},
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.artifact.Validate()
+			if (err != nil) != test.expectErr {
+				t.Errorf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestArtifactMetadataKind(t *testing.T) {
+	tests := map[string]struct {
+		artifact ArtifactMetadata
+		expected string
+	}{
+		"manifests": {
+			artifact: ArtifactMetadata{Name: "cert-manager-manifests.tar.gz"},
+			expected: "manifests",
+		},
+		"server": {
+			artifact: ArtifactMetadata{Name: "cert-manager-server-linux-amd64.tar.gz", OS: "linux", Architecture: "amd64"},
+			expected: "server",
+		},
+		"kind containing a dash": {
+			artifact: ArtifactMetadata{Name: "cert-manager-kubectl-cert_manager-darwin-arm64.tar.gz", OS: "darwin", Architecture: "arm64"},
+			expected: "kubectl-cert_manager",
+		},
+		"zip archive": {
+			artifact: ArtifactMetadata{Name: "cert-manager-cmctl-windows-amd64.zip", OS: "windows", Architecture: "amd64"},
+			expected: "cmctl",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if kind := test.artifact.Kind(); kind != test.expected {
+				t.Errorf("expected kind %q but got %q", test.expected, kind)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"context"
+	"fmt"
+)
+
+// metadataMigration upgrades release metadata from one schema version to the
+// next, modifying meta in place and returning a description of each change.
+type metadataMigration func(ctx context.Context, s *Staged, meta *Metadata) ([]string, error)
+
+// metadataMigrations holds the migration from each schema version to the
+// next, indexed by the version being migrated from.
+var metadataMigrations = []metadataMigration{
+	migrateMetadataV0ToV1,
+}
+
+// MigrateMetadata returns a copy of the staged release's metadata upgraded to
+// CurrentMetadataSchemaVersion, along with a description of each change that
+// was made. If no changes are returned, the metadata is already up to date.
+func MigrateMetadata(ctx context.Context, s *Staged) (*Metadata, []string, error) {
+	meta := s.Metadata()
+	meta.Artifacts = append([]ArtifactMetadata{}, meta.Artifacts...)
+
+	var changes []string
+	for meta.SchemaVersion < CurrentMetadataSchemaVersion {
+		from := meta.SchemaVersion
+
+		migrationChanges, err := metadataMigrations[from](ctx, s, &meta)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to migrate release metadata from schema version %d: %w", from, err)
+		}
+
+		meta.SchemaVersion = from + 1
+
+		changes = append(changes, migrationChanges...)
+		changes = append(changes, fmt.Sprintf("schemaVersion: %d -> %d", from, meta.SchemaVersion))
+	}
+
+	return &meta, changes, nil
+}
+
+// migrateMetadataV0ToV1 makes the implicit Bazel build source of unversioned
+// metadata explicit, and fills in the SHA256 sum and size of any artifacts
+// which are missing them.
+func migrateMetadataV0ToV1(ctx context.Context, s *Staged, meta *Metadata) ([]string, error) {
+	var changes []string
+
+	if meta.BuildSource == "" {
+		meta.BuildSource = BuildSourceBazel
+		changes = append(changes, fmt.Sprintf("buildSource: %q -> %q", "", meta.BuildSource))
+	}
+
+	for i, a := range s.artifacts {
+		if a.Metadata.SHA256 != "" && a.Metadata.Size != 0 {
+			continue
+		}
+
+		sum, size, err := hashObject(ctx, &a)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute checksum of %q: %w", a.Metadata.Name, err)
+		}
+
+		if a.Metadata.SHA256 == "" {
+			meta.Artifacts[i].SHA256 = sum
+			changes = append(changes, fmt.Sprintf("%s: sha256 %q -> %q", a.Metadata.Name, "", sum))
+		}
+
+		if a.Metadata.Size == 0 {
+			meta.Artifacts[i].Size = size
+			changes = append(changes, fmt.Sprintf("%s: size %d -> %d", a.Metadata.Name, 0, size))
+		}
+	}
+
+	return changes, nil
+}
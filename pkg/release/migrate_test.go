@@ -0,0 +1,139 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestMigrateMetadata(t *testing.T) {
+	ctx := context.Background()
+
+	manifestsSum := sha256.Sum256([]byte("manifests"))
+	serverSum := sha256.Sum256([]byte("server"))
+
+	// metadata as written by versions of cmrel before schema versioning
+	objects := stagedReleaseObjects(t, "test-bucket", "stage/gcb/devel/abcdef", MetadataFileName, Metadata{
+		ReleaseVersion: "v1.2.3",
+		GitCommitRef:   "abcdef",
+		Artifacts: []ArtifactMetadata{
+			{
+				Name:         "cert-manager-server-linux-amd64.tar.gz",
+				SHA256:       hex.EncodeToString(serverSum[:]),
+				OS:           "linux",
+				Architecture: "amd64",
+			},
+		},
+	})
+	objects["test-bucket/stage/gcb/devel/abcdef/cert-manager-server-linux-amd64.tar.gz"] = []byte("server")
+
+	_, client := newFakeGCS(t, objects)
+
+	bucket := NewBucket(client.Bucket("test-bucket"), "stage/gcb", BuildTypeDevel)
+
+	staged, err := bucket.GetRelease(ctx, "abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta, changes, err := MigrateMetadata(ctx, staged)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expMeta := &Metadata{
+		SchemaVersion:  CurrentMetadataSchemaVersion,
+		ReleaseVersion: "v1.2.3",
+		GitCommitRef:   "abcdef",
+		BuildSource:    BuildSourceBazel,
+		Artifacts: []ArtifactMetadata{
+			{
+				Name:         "cert-manager-server-linux-amd64.tar.gz",
+				SHA256:       hex.EncodeToString(serverSum[:]),
+				Size:         6,
+				OS:           "linux",
+				Architecture: "amd64",
+			},
+			{
+				Name:   "cert-manager-manifests.tar.gz",
+				SHA256: hex.EncodeToString(manifestsSum[:]),
+				Size:   9,
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(meta, expMeta) {
+		t.Errorf("unexpected migrated metadata:\ngot=%+v\nexp=%+v", meta, expMeta)
+	}
+
+	expChanges := []string{
+		`buildSource: "" -> "bazel"`,
+		"cert-manager-server-linux-amd64.tar.gz: size 0 -> 6",
+		"cert-manager-manifests.tar.gz: size 0 -> 9",
+		"schemaVersion: 0 -> 1",
+	}
+
+	if !reflect.DeepEqual(changes, expChanges) {
+		t.Errorf("unexpected changes:\ngot=%q\nexp=%q", changes, expChanges)
+	}
+
+	if len(staged.Metadata().Artifacts) != 2 || staged.Metadata().Artifacts[0].Size != 0 {
+		t.Errorf("migration should not modify the staged release's metadata")
+	}
+
+	if err := bucket.WriteMetadata(ctx, "abcdef", *meta); err != nil {
+		t.Fatal(err)
+	}
+
+	migrated, err := bucket.GetRelease(ctx, "abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(migrated.Metadata(), *expMeta) {
+		t.Errorf("unexpected metadata after migration:\ngot=%+v\nexp=%+v", migrated.Metadata(), *expMeta)
+	}
+
+	_, changes, err = MigrateMetadata(ctx, migrated)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(changes) != 0 {
+		t.Errorf("expected no changes after migration, got %q", changes)
+	}
+}
+
+func TestLoadMetadataNewerSchemaVersion(t *testing.T) {
+	objects := stagedReleaseObjects(t, "test-bucket", "stage/gcb/devel/abcdef", MetadataFileName, Metadata{
+		SchemaVersion:  CurrentMetadataSchemaVersion + 1,
+		ReleaseVersion: "v1.2.3",
+		GitCommitRef:   "abcdef",
+	})
+
+	_, client := newFakeGCS(t, objects)
+
+	bucket := NewBucket(client.Bucket("test-bucket"), "stage/gcb", BuildTypeDevel)
+
+	if _, err := bucket.GetRelease(context.Background(), "abcdef"); err == nil {
+		t.Errorf("expected an error loading metadata with a newer schema version")
+	}
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package notes builds release notes for cert-manager releases.
+package notes
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Kind is the section of the release notes that a change is listed under.
+type Kind string
+
+const (
+	KindBreaking Kind = "Breaking Changes"
+	KindFeature  Kind = "Features"
+	KindFix      Kind = "Bug Fixes"
+	KindChore    Kind = "Chores"
+	KindOther    Kind = "Other"
+)
+
+// kindOrder is the order in which sections appear in release notes. Breaking
+// changes come first since they're the most important thing for anyone
+// upgrading cert-manager to read.
+var kindOrder = []Kind{KindBreaking, KindFeature, KindFix, KindChore, KindOther}
+
+// conventionalTypeKinds maps conventional commit types to the kind of change
+// they represent. Titles with any other prefix are treated as KindOther.
+var conventionalTypeKinds = map[string]Kind{
+	"feat":     KindFeature,
+	"fix":      KindFix,
+	"chore":    KindChore,
+	"build":    KindChore,
+	"ci":       KindChore,
+	"docs":     KindChore,
+	"refactor": KindChore,
+	"style":    KindChore,
+	"test":     KindChore,
+}
+
+// conventionalPrefixRegexp matches a conventional commit prefix such as
+// "feat: ", "fix(webhook): " or "feat!: ", capturing the type, the optional
+// scope, the optional breaking change marker and the remaining description.
+var conventionalPrefixRegexp = regexp.MustCompile(`^([a-zA-Z]+)(?:\(([^)]*)\))?(!)?:\s*(.*)$`)
+
+// breakingChangePrefixes mark a breaking change, either at the start of a
+// title or at the start of a line in a PR description or commit message.
+var breakingChangePrefixes = []string{"BREAKING CHANGE:", "BREAKING-CHANGE:"}
+
+// Entry is a single change listed in release notes.
+type Entry struct {
+	// Title describes the change, with any conventional commit prefix removed
+	Title string
+
+	// Kind is the section of the release notes the entry is listed under
+	Kind Kind
+
+	// Scope is the optional scope given in a conventional commit prefix,
+	// e.g. "webhook" for "fix(webhook): ..."
+	Scope string
+
+	// Breaking is true if the change is marked as a breaking change. Breaking
+	// changes are always listed under KindBreaking.
+	Breaking bool
+
+	// Number is the number of the PR which made the change, or zero if not
+	// known
+	Number int
+}
+
+// ParseEntry categorises a change from its PR title or commit subject and its
+// PR description or commit message body, using conventional commit prefixes.
+// Changes without a conventional commit prefix are categorised as KindOther.
+func ParseEntry(title, body string, number int) Entry {
+	e := Entry{
+		Title:  strings.TrimSpace(title),
+		Kind:   KindOther,
+		Number: number,
+	}
+
+	for _, prefix := range breakingChangePrefixes {
+		if strings.HasPrefix(e.Title, prefix) {
+			e.Title = strings.TrimSpace(strings.TrimPrefix(e.Title, prefix))
+			e.Breaking = true
+		}
+	}
+
+	// titles such as "Helm: ..." aren't conventional commits, so only known
+	// types are treated as a conventional commit prefix
+	if m := conventionalPrefixRegexp.FindStringSubmatch(e.Title); m != nil {
+		if kind, ok := conventionalTypeKinds[strings.ToLower(m[1])]; ok {
+			e.Kind = kind
+			e.Scope = m[2]
+			e.Breaking = e.Breaking || m[3] == "!"
+			e.Title = m[4]
+		}
+	}
+
+	for _, line := range strings.Split(body, "\n") {
+		for _, prefix := range breakingChangePrefixes {
+			if strings.HasPrefix(strings.TrimSpace(line), prefix) {
+				e.Breaking = true
+			}
+		}
+	}
+
+	if e.Breaking {
+		e.Kind = KindBreaking
+	}
+
+	return e
+}
+
+// String renders the entry as a single line of release notes.
+func (e Entry) String() string {
+	s := e.Title
+	if e.Scope != "" {
+		s = e.Scope + ": " + s
+	}
+
+	if e.Number != 0 {
+		s = fmt.Sprintf("%s (#%d)", s, e.Number)
+	}
+
+	return s
+}
+
+// Section is a group of release note entries of the same kind.
+type Section struct {
+	Kind    Kind
+	Entries []Entry
+}
+
+// Sections groups entries by kind, returning sections in the order they
+// appear in release notes. Empty sections are omitted, and entries keep the
+// order they were given in.
+func Sections(entries []Entry) []Section {
+	byKind := map[Kind][]Entry{}
+	for _, e := range entries {
+		byKind[e.Kind] = append(byKind[e.Kind], e)
+	}
+
+	var sections []Section
+	for _, kind := range kindOrder {
+		if len(byKind[kind]) == 0 {
+			continue
+		}
+
+		sections = append(sections, Section{Kind: kind, Entries: byKind[kind]})
+	}
+
+	return sections
+}
+
+// Markdown renders sections as Markdown suitable for a GitHub release body.
+func Markdown(sections []Section) string {
+	var b strings.Builder
+	for i, section := range sections {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		fmt.Fprintf(&b, "### %s\n\n", section.Kind)
+		for _, e := range section.Entries {
+			fmt.Fprintf(&b, "- %s\n", e)
+		}
+	}
+
+	return b.String()
+}
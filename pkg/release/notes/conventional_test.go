@@ -0,0 +1,125 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notes
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEntry(t *testing.T) {
+	tests := map[string]struct {
+		title    string
+		body     string
+		expected Entry
+	}{
+		"feature": {
+			title:    "feat: add support for ingress-shim on Gateway resources",
+			expected: Entry{Title: "add support for ingress-shim on Gateway resources", Kind: KindFeature},
+		},
+		"fix with scope": {
+			title:    "fix(webhook): don't panic on empty CSR",
+			expected: Entry{Title: "don't panic on empty CSR", Kind: KindFix, Scope: "webhook"},
+		},
+		"chore": {
+			title:    "chore: bump go to 1.23",
+			expected: Entry{Title: "bump go to 1.23", Kind: KindChore},
+		},
+		"uppercase type": {
+			title:    "Fix: correct typo in log message",
+			expected: Entry{Title: "correct typo in log message", Kind: KindFix},
+		},
+		"other conventional types are chores": {
+			title:    "docs: clarify the upgrade guide",
+			expected: Entry{Title: "clarify the upgrade guide", Kind: KindChore},
+		},
+		"breaking change marker": {
+			title:    "feat!: remove the deprecated --feature-gates flag",
+			expected: Entry{Title: "remove the deprecated --feature-gates flag", Kind: KindBreaking, Breaking: true},
+		},
+		"breaking change marker with scope": {
+			title:    "fix(acme)!: stop retrying failed orders forever",
+			expected: Entry{Title: "stop retrying failed orders forever", Kind: KindBreaking, Scope: "acme", Breaking: true},
+		},
+		"breaking change title prefix": {
+			title:    "BREAKING CHANGE: the minimum supported Kubernetes version is now 1.27",
+			expected: Entry{Title: "the minimum supported Kubernetes version is now 1.27", Kind: KindBreaking, Breaking: true},
+		},
+		"breaking change footer in body": {
+			title:    "feat: switch the default private key algorithm",
+			body:     "Switch the default algorithm.\n\nBREAKING CHANGE: certificates without an explicit algorithm will be reissued with ECDSA keys",
+			expected: Entry{Title: "switch the default private key algorithm", Kind: KindBreaking, Breaking: true},
+		},
+		"no conventional prefix": {
+			title:    "Bump the cert-manager chart version",
+			expected: Entry{Title: "Bump the cert-manager chart version", Kind: KindOther},
+		},
+		"unknown conventional type": {
+			title:    "perf: cache parsed certificates",
+			expected: Entry{Title: "perf: cache parsed certificates", Kind: KindOther},
+		},
+		"prefix which isn't a conventional type": {
+			title:    "Helm: allow setting the webhook port",
+			expected: Entry{Title: "Helm: allow setting the webhook port", Kind: KindOther},
+		},
+		"colon without a conventional prefix": {
+			title:    "Helm chart: allow setting the webhook port",
+			expected: Entry{Title: "Helm chart: allow setting the webhook port", Kind: KindOther},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := ParseEntry(test.title, test.body, 0)
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("unexpected entry:\ngot=%+v\nexp=%+v", got, test.expected)
+			}
+		})
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	entries := []Entry{
+		ParseEntry("fix: correct CRD validation", "", 101),
+		ParseEntry("Update README", "", 102),
+		ParseEntry("feat(cmctl): add a check api command", "", 103),
+		ParseEntry("feat!: remove v1alpha2 APIs", "", 104),
+		ParseEntry("fix: handle nil issuer refs", "", 105),
+	}
+
+	expected := `### Breaking Changes
+
+- remove v1alpha2 APIs (#104)
+
+### Features
+
+- cmctl: add a check api command (#103)
+
+### Bug Fixes
+
+- correct CRD validation (#101)
+- handle nil issuer refs (#105)
+
+### Other
+
+- Update README (#102)
+`
+
+	if got := Markdown(Sections(entries)); got != expected {
+		t.Errorf("unexpected markdown:\ngot:\n%s\nexpected:\n%s", got, expected)
+	}
+}
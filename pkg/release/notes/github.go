@@ -0,0 +1,153 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notes
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/google/go-github/v35/github"
+	"golang.org/x/mod/semver"
+)
+
+// PreviousTag returns the highest semver tag in tags which is lower than
+// version, or an empty string if there isn't one. Pre-release tags are only
+// considered if version is itself a pre-release.
+func PreviousTag(tags []string, version string) string {
+	includePrerelease := semver.Prerelease(version) != ""
+
+	previous := ""
+	for _, tag := range tags {
+		if !semver.IsValid(tag) || semver.Compare(tag, version) >= 0 {
+			continue
+		}
+
+		if semver.Prerelease(tag) != "" && !includePrerelease {
+			continue
+		}
+
+		if previous == "" || semver.Compare(tag, previous) > 0 {
+			previous = tag
+		}
+	}
+
+	return previous
+}
+
+// FindPreviousTag lists the tags of the given repository and returns the one
+// which release notes for version should start from, as chosen by
+// PreviousTag.
+func FindPreviousTag(ctx context.Context, client *github.Client, owner, repo, version string) (string, error) {
+	tags, err := ListTags(ctx, client, owner, repo)
+	if err != nil {
+		return "", err
+	}
+
+	previous := PreviousTag(tags, version)
+	if previous == "" {
+		return "", fmt.Errorf("failed to find a tag in %s/%s before %q", owner, repo, version)
+	}
+
+	return previous, nil
+}
+
+// ListTags returns the names of every tag in the given repository.
+func ListTags(ctx context.Context, client *github.Client, owner, repo string) ([]string, error) {
+	var tags []string
+
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := client.Repositories.ListTags(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags of %s/%s: %w", owner, repo, err)
+		}
+
+		for _, tag := range page {
+			tags = append(tags, tag.GetName())
+		}
+
+		if resp.NextPage == 0 {
+			return tags, nil
+		}
+
+		opts.Page = resp.NextPage
+	}
+}
+
+// MergedPullRequests returns the merged PRs which introduced the commits in
+// head that aren't in base, ordered by PR number.
+func MergedPullRequests(ctx context.Context, client *github.Client, owner, repo, base, head string) ([]*github.PullRequest, error) {
+	comparison, _, err := client.Repositories.CompareCommits(ctx, owner, repo, base, head)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %q and %q in %s/%s: %w", base, head, owner, repo, err)
+	}
+
+	if comparison.GetTotalCommits() > len(comparison.Commits) {
+		slog.Warn("not every commit was returned by GitHub; release notes will be incomplete", "returned", len(comparison.Commits), "total", comparison.GetTotalCommits(), "base", base, "head", head)
+	}
+
+	seen := map[int]bool{}
+	var prs []*github.PullRequest
+	for _, commit := range comparison.Commits {
+		commitPRs, _, err := client.PullRequests.ListPullRequestsWithCommit(ctx, owner, repo, commit.GetSHA(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list PRs for commit %q: %w", commit.GetSHA(), err)
+		}
+
+		for _, pr := range commitPRs {
+			if pr.MergedAt == nil || seen[pr.GetNumber()] {
+				continue
+			}
+
+			seen[pr.GetNumber()] = true
+			prs = append(prs, pr)
+		}
+	}
+
+	sort.Slice(prs, func(i, j int) bool {
+		return prs[i].GetNumber() < prs[j].GetNumber()
+	})
+
+	return prs, nil
+}
+
+// Generate builds Markdown release notes from the merged PRs between base and
+// head in the given repository, grouped by the kind of change.
+func Generate(ctx context.Context, client *github.Client, owner, repo, base, head string) (string, error) {
+	prs, err := MergedPullRequests(ctx, client, owner, repo, base, head)
+	if err != nil {
+		return "", err
+	}
+
+	var entries []Entry
+	for _, pr := range prs {
+		var labels []string
+		for _, label := range pr.Labels {
+			labels = append(labels, label.GetName())
+		}
+
+		if e, ok := EntryFromPR(pr.GetTitle(), pr.GetBody(), pr.GetNumber(), labels); ok {
+			entries = append(entries, e)
+		}
+	}
+
+	slog.Info("found PRs to include in release notes", "count", len(entries), "base", base)
+
+	return Markdown(Sections(entries)), nil
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v35/github"
+)
+
+func TestPreviousTag(t *testing.T) {
+	tags := []string{"v1.13.0", "v1.14.0", "v1.14.5", "v1.15.0-alpha.0", "v1.15.0-beta.1", "v1.15.0", "v1.16.0", "not-a-version"}
+
+	tests := map[string]struct {
+		version  string
+		expected string
+	}{
+		"release": {
+			version:  "v1.15.0",
+			expected: "v1.14.5",
+		},
+		"patch release": {
+			version:  "v1.15.1",
+			expected: "v1.15.0",
+		},
+		"pre-release": {
+			version:  "v1.15.0-beta.2",
+			expected: "v1.15.0-beta.1",
+		},
+		"no earlier tag": {
+			version:  "v1.12.0",
+			expected: "",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := PreviousTag(tags, test.version); got != test.expected {
+				t.Errorf("expected previous tag %q but got %q", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	merged := time.Now()
+
+	prsByCommit := map[string][]*github.PullRequest{
+		"aaa": {{Number: github.Int(12), Title: github.String("fix: handle nil issuer refs"), MergedAt: &merged}},
+		"bbb": {{Number: github.Int(10), Title: github.String("Add a check api command"), MergedAt: &merged, Labels: []*github.Label{{Name: github.String("kind/feature")}, {Name: github.String("area/cmctl")}}}},
+		// the merge commit of PR 10 is also part of the comparison
+		"ccc": {{Number: github.Int(10), Title: github.String("Add a check api command"), MergedAt: &merged}},
+		"ddd": {{Number: github.Int(11), Title: github.String("Bump go"), MergedAt: &merged, Labels: []*github.Label{{Name: github.String("release-note-none")}}}},
+		// unmerged PRs containing a commit are ignored
+		"eee": {{Number: github.Int(13), Title: github.String("feat: unmerged experiment")}},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/cert-manager/cert-manager/compare/v1.14.0...abcdef", func(w http.ResponseWriter, r *http.Request) {
+		var commits []*github.RepositoryCommit
+		for _, sha := range []string{"aaa", "bbb", "ccc", "ddd", "eee"} {
+			commits = append(commits, &github.RepositoryCommit{SHA: github.String(sha)})
+		}
+
+		json.NewEncoder(w).Encode(&github.CommitsComparison{Commits: commits, TotalCommits: github.Int(len(commits))})
+	})
+
+	for sha, prs := range prsByCommit {
+		prs := prs
+		mux.HandleFunc("/repos/cert-manager/cert-manager/commits/"+sha+"/pulls", func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(prs)
+		})
+	}
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	got, err := Generate(context.Background(), client, "cert-manager", "cert-manager", "v1.14.0", "abcdef")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `### Features
+
+- cmctl: Add a check api command (#10)
+
+### Bug Fixes
+
+- handle nil issuer refs (#12)
+`
+
+	if got != expected {
+		t.Errorf("unexpected release notes:\ngot:\n%s\nexpected:\n%s", got, expected)
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notes
+
+import "strings"
+
+const (
+	// areaLabelPrefix is the prefix of labels naming the area of cert-manager
+	// a PR changes, e.g. "area/acme".
+	areaLabelPrefix = "area/"
+
+	// releaseNoteNoneLabel marks a PR which shouldn't appear in release notes.
+	releaseNoteNoneLabel = "release-note-none"
+)
+
+// labelKinds maps the kind labels used on cert-manager PRs to the kind of
+// change they represent.
+var labelKinds = map[string]Kind{
+	"kind/feature":       KindFeature,
+	"kind/bug":           KindFix,
+	"kind/regression":    KindFix,
+	"kind/cleanup":       KindChore,
+	"kind/documentation": KindChore,
+	"kind/flake":         KindChore,
+}
+
+// EntryFromPR categorises a merged PR from its title, description and labels.
+// A kind label takes precedence over any conventional commit prefix in the
+// title, and the first area label is used as the scope if the title doesn't
+// give one. Breaking changes are always categorised as KindBreaking.
+// The returned bool is false if the PR is labelled to be left out of release
+// notes.
+func EntryFromPR(title, body string, number int, labels []string) (Entry, bool) {
+	e := ParseEntry(title, body, number)
+
+	for _, label := range labels {
+		if label == releaseNoteNoneLabel {
+			return Entry{}, false
+		}
+	}
+
+	for _, label := range labels {
+		if kind, ok := labelKinds[label]; ok && !e.Breaking {
+			e.Kind = kind
+			break
+		}
+	}
+
+	if e.Scope == "" {
+		for _, label := range labels {
+			if strings.HasPrefix(label, areaLabelPrefix) {
+				e.Scope = strings.TrimPrefix(label, areaLabelPrefix)
+				break
+			}
+		}
+	}
+
+	return e, true
+}
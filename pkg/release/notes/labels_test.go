@@ -0,0 +1,80 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notes
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEntryFromPR(t *testing.T) {
+	tests := map[string]struct {
+		title    string
+		labels   []string
+		expected Entry
+		expectOK bool
+	}{
+		"kind label without a conventional prefix": {
+			title:    "Allow setting the webhook port",
+			labels:   []string{"kind/feature"},
+			expected: Entry{Title: "Allow setting the webhook port", Kind: KindFeature},
+			expectOK: true,
+		},
+		"kind label takes precedence over prefix": {
+			title:    "chore: stop leaking goroutines in the controller",
+			labels:   []string{"kind/bug"},
+			expected: Entry{Title: "stop leaking goroutines in the controller", Kind: KindFix},
+			expectOK: true,
+		},
+		"area label used as scope": {
+			title:    "fix: retry failed orders",
+			labels:   []string{"area/acme", "kind/bug"},
+			expected: Entry{Title: "retry failed orders", Kind: KindFix, Scope: "acme"},
+			expectOK: true,
+		},
+		"scope in title takes precedence over area label": {
+			title:    "fix(webhook): don't panic on empty CSR",
+			labels:   []string{"area/api"},
+			expected: Entry{Title: "don't panic on empty CSR", Kind: KindFix, Scope: "webhook"},
+			expectOK: true,
+		},
+		"breaking change isn't recategorised by kind label": {
+			title:    "feat!: remove v1alpha2 APIs",
+			labels:   []string{"kind/cleanup"},
+			expected: Entry{Title: "remove v1alpha2 APIs", Kind: KindBreaking, Breaking: true},
+			expectOK: true,
+		},
+		"release-note-none": {
+			title:    "Bump go to 1.23",
+			labels:   []string{"kind/cleanup", "release-note-none"},
+			expectOK: false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, ok := EntryFromPR(test.title, "", 0, test.labels)
+			if ok != test.expectOK {
+				t.Fatalf("expectOK=%v but got %v", test.expectOK, ok)
+			}
+
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("unexpected entry:\ngot=%+v\nexp=%+v", got, test.expected)
+			}
+		})
+	}
+}
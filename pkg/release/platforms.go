@@ -51,6 +51,31 @@ var (
 	}
 )
 
+const (
+	// ArchiveFormatTarGz is the file extension of gzipped tar archives.
+	ArchiveFormatTarGz = ".tar.gz"
+
+	// ArchiveFormatZip is the file extension of zip archives.
+	ArchiveFormatZip = ".zip"
+)
+
+// clientArchiveFormats maps OSes to the archive format used for client
+// binaries built for that OS. OSes not listed use ArchiveFormatTarGz.
+var clientArchiveFormats = map[string]string{
+	"windows": ArchiveFormatZip,
+}
+
+// ArchiveFormatForOS returns the file extension of the archive format that
+// client binaries built for the given OS are packaged in, e.g. ".zip" for
+// windows and ".tar.gz" for linux.
+func ArchiveFormatForOS(os string) string {
+	if format, ok := clientArchiveFormats[os]; ok {
+		return format
+	}
+
+	return ArchiveFormatTarGz
+}
+
 // AllOSes returns a slice of all known operating systems which cert-manager targets
 // including both server and client targets.
 func AllOSes() sets.String {
@@ -174,8 +199,13 @@ func IsClientOS(os string) bool {
 	return isClient
 }
 
-// Cmctl is only shipped with v1.14.X and below.
+// Cmctl is only shipped with v1.14.X and below. Versions which are not valid
+// semver are treated as not shipping cmctl.
 func CmctlIsShipped(releaseVersion string) bool {
 	releaseVersion, _ = strings.CutPrefix(releaseVersion, "v")
-	return semver.MustParse(releaseVersion).LT(semver.MustParse("1.15.0-alpha.0"))
+	v, err := semver.Parse(releaseVersion)
+	if err != nil {
+		return false
+	}
+	return v.LT(semver.MustParse("1.15.0-alpha.0"))
 }
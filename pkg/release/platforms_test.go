@@ -191,3 +191,36 @@ func TestCmctlIsShipped(t *testing.T) {
 		})
 	}
 }
+
+func TestArchiveFormatForOS(t *testing.T) {
+	tests := map[string]struct {
+		os             string
+		expectedFormat string
+	}{
+		"linux uses tar.gz": {
+			os:             "linux",
+			expectedFormat: ".tar.gz",
+		},
+		"darwin uses tar.gz": {
+			os:             "darwin",
+			expectedFormat: ".tar.gz",
+		},
+		"windows uses zip": {
+			os:             "windows",
+			expectedFormat: ".zip",
+		},
+		"unknown OS defaults to tar.gz": {
+			os:             "templeos",
+			expectedFormat: ".tar.gz",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			format := ArchiveFormatForOS(test.os)
+			if format != test.expectedFormat {
+				t.Errorf("wanted format %q but got %q", test.expectedFormat, format)
+			}
+		})
+	}
+}
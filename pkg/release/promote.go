@@ -0,0 +1,155 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+// Promote copies every artifact in the staged build s into dst as a release
+// with the given version, without rebuilding it. The promoted release is named
+// for version and the git ref of s, and its metadata is rewritten with the new
+// release version and with checksums recomputed from the copied artifacts.
+// The name of the promoted release is returned.
+// An error is returned if a release of that name already exists in dst, or if
+// any copied artifact doesn't match the checksum recorded for it in s. The
+// metadata is written last, so an interrupted promotion leaves no release
+// behind and can be retried.
+// Artifacts are copied as-is, so callers should check that s was built for
+// version before promoting it; see validation.ValidateVersion.
+func Promote(ctx context.Context, s *Staged, dst *Bucket, version string) (string, error) {
+	if version == "" {
+		return "", fmt.Errorf("a release version is required to promote staged build %q", s.Name())
+	}
+
+	meta := s.Metadata()
+	if meta.ReleaseVersion != "" && meta.ReleaseVersion != version {
+		return "", fmt.Errorf("staged build %q was built with release version %q, so cannot be promoted to version %q", s.Name(), meta.ReleaseVersion, version)
+	}
+
+	name := pathSuffixForVersion(version, meta.GitCommitRef)
+
+	exists, err := dst.releaseExists(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to check for existing release %q: %w", name, err)
+	}
+
+	if exists {
+		return "", fmt.Errorf("release %q already exists, refusing to overwrite it", name)
+	}
+
+	meta.ReleaseVersion = version
+	meta.Artifacts = make([]ArtifactMetadata, len(s.artifacts))
+
+	for i, a := range s.artifacts {
+		slog.Info("copying artifact", "artifact", a.Metadata.Name, "release", name)
+
+		sum, size, err := copyObject(ctx, a.ObjectHandle, dst.bucket.Object(dst.prefix+name+"/"+a.Metadata.Name))
+		if err != nil {
+			return "", fmt.Errorf("failed to copy artifact %q: %w", a.Metadata.Name, err)
+		}
+
+		if sum != a.Metadata.SHA256 {
+			return "", fmt.Errorf("copied artifact %q has checksum %q but the staged build's metadata has %q", a.Metadata.Name, sum, a.Metadata.SHA256)
+		}
+
+		promoted := a.Metadata
+		promoted.SHA256 = sum
+		promoted.Size = size
+		meta.Artifacts[i] = promoted
+	}
+
+	for _, fileName := range promotedReleaseFiles {
+		obj := s.file(fileName)
+		if obj == nil {
+			continue
+		}
+
+		if _, _, err := copyObject(ctx, obj, dst.bucket.Object(dst.prefix+name+"/"+fileName)); err != nil {
+			return "", fmt.Errorf("failed to copy %q: %w", fileName, err)
+		}
+
+		slog.Info("copied release file", "file", fileName, "release", name)
+	}
+
+	if err := dst.WriteMetadata(ctx, name, meta); err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
+// promotedReleaseFiles are the files in the root of a staged build, other than
+// its artifacts and metadata, which are copied to the promoted release if
+// they're present, so that it's published with the same attestations.
+var promotedReleaseFiles = []string{
+	ProvenanceFileName,
+	ProvenanceFileName + MetadataSignatureSuffix,
+}
+
+// releaseExists returns true if the named release has a metadata file, or a
+// gzipped copy of one. A release which has artifacts but no metadata, such as
+// one left behind by an interrupted promotion, isn't treated as existing.
+func (b *Bucket) releaseExists(ctx context.Context, name string) (bool, error) {
+	objs := b.bucket.Objects(ctx, &storage.Query{Prefix: b.prefix + name + "/"})
+	for {
+		objAttr, err := objs.Next()
+		if err == iterator.Done {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		if b.isMetadataObject(name, objAttr.Name) {
+			return true, nil
+		}
+	}
+}
+
+// copyObject streams the content of src to dst, returning the SHA256 sum and
+// size of the copied content.
+func copyObject(ctx context.Context, src, dst *storage.ObjectHandle) (string, int64, error) {
+	r, err := src.NewReader(ctx)
+	if err != nil {
+		return "", 0, err
+	}
+	defer r.Close()
+
+	hasher := sha256.New()
+
+	w := dst.NewWriter(ctx)
+	size, err := io.Copy(io.MultiWriter(w, hasher), r)
+	if err != nil {
+		w.Close()
+		return "", 0, err
+	}
+
+	if err := w.Close(); err != nil {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), size, nil
+}
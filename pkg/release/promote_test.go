@@ -0,0 +1,186 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestPromote(t *testing.T) {
+	tests := map[string]struct {
+		stagedVersion   string
+		version         string
+		existing        bool
+		existingGzipped bool
+		corruptArtifact bool
+		expectErr       bool
+	}{
+		"devel build without a release version": {
+			version: "v1.2.3",
+		},
+		"devel build with a matching release version": {
+			stagedVersion: "v1.2.3",
+			version:       "v1.2.3",
+		},
+		"devel build with a different release version": {
+			stagedVersion: "v1.2.2",
+			version:       "v1.2.3",
+			expectErr:     true,
+		},
+		"no release version": {
+			version:   "",
+			expectErr: true,
+		},
+		"release already exists": {
+			version:   "v1.2.3",
+			existing:  true,
+			expectErr: true,
+		},
+		"release with gzipped metadata already exists": {
+			version:         "v1.2.3",
+			existing:        true,
+			existingGzipped: true,
+			expectErr:       true,
+		},
+		"artifact doesn't match staged checksum": {
+			version:         "v1.2.3",
+			corruptArtifact: true,
+			expectErr:       true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			objects := stagedReleaseObjects(t, "test-bucket", "stage/gcb/devel/abcdef", MetadataFileName, Metadata{
+				ReleaseVersion: test.stagedVersion,
+				GitCommitRef:   "abcdef",
+			})
+
+			if test.existing {
+				existingMetadataFileName := MetadataFileName
+				if test.existingGzipped {
+					existingMetadataFileName += GzippedMetadataSuffix
+				}
+
+				for name, data := range stagedReleaseObjects(t, "test-bucket", "stage/gcb/release/v1.2.3-abcdef", existingMetadataFileName, Metadata{
+					ReleaseVersion: "v1.2.3",
+					GitCommitRef:   "abcdef",
+				}) {
+					objects[name] = data
+				}
+			}
+
+			objects["test-bucket/stage/gcb/devel/abcdef/"+ProvenanceFileName] = []byte("provenance")
+
+			if test.corruptArtifact {
+				objects["test-bucket/stage/gcb/devel/abcdef/cert-manager-manifests.tar.gz"] = []byte("corrupted")
+			}
+
+			fake, client := newFakeGCS(t, objects)
+
+			staged, err := NewBucket(client.Bucket("test-bucket"), "stage/gcb", BuildTypeDevel).GetRelease(ctx, "abcdef")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dst := NewBucket(client.Bucket("test-bucket"), "stage/gcb", BuildTypeRelease)
+
+			name, err := Promote(ctx, staged, dst, test.version)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if test.expectErr {
+				if !test.existing {
+					if _, ok := fake.Object("test-bucket/stage/gcb/release/v1.2.3-abcdef/" + MetadataFileName); ok {
+						t.Errorf("expected no metadata to be written for a failed promotion")
+					}
+				}
+				return
+			}
+
+			if name != "v1.2.3-abcdef" {
+				t.Errorf("unexpected promoted release name %q", name)
+			}
+
+			artifact, ok := fake.Object("test-bucket/stage/gcb/release/v1.2.3-abcdef/cert-manager-manifests.tar.gz")
+			if !ok {
+				t.Fatalf("expected artifact to be copied to the release path")
+			}
+
+			if string(artifact) != "manifests" {
+				t.Errorf("unexpected content of copied artifact %q", artifact)
+			}
+
+			provenance, ok := fake.Object("test-bucket/stage/gcb/release/v1.2.3-abcdef/" + ProvenanceFileName)
+			if !ok {
+				t.Fatalf("expected provenance to be copied to the release path")
+			}
+
+			if string(provenance) != "provenance" {
+				t.Errorf("unexpected content of copied provenance %q", provenance)
+			}
+
+			if _, ok := fake.Object("test-bucket/stage/gcb/release/v1.2.3-abcdef/" + ProvenanceFileName + MetadataSignatureSuffix); ok {
+				t.Errorf("expected no provenance signature to be written when the staged build has none")
+			}
+
+			metaBytes, ok := fake.Object("test-bucket/stage/gcb/release/v1.2.3-abcdef/" + MetadataFileName)
+			if !ok {
+				t.Fatalf("expected metadata to be written to the release path")
+			}
+
+			var meta Metadata
+			if err := json.Unmarshal(metaBytes, &meta); err != nil {
+				t.Fatal(err)
+			}
+
+			if meta.ReleaseVersion != "v1.2.3" {
+				t.Errorf("unexpected release version %q in promoted metadata", meta.ReleaseVersion)
+			}
+
+			if meta.GitCommitRef != "abcdef" {
+				t.Errorf("unexpected git ref %q in promoted metadata", meta.GitCommitRef)
+			}
+
+			if len(meta.Artifacts) != 1 {
+				t.Fatalf("expected 1 artifact in promoted metadata, got %d", len(meta.Artifacts))
+			}
+
+			if meta.Artifacts[0].SHA256 != staged.Metadata().Artifacts[0].SHA256 {
+				t.Errorf("unexpected artifact checksum %q in promoted metadata", meta.Artifacts[0].SHA256)
+			}
+
+			if meta.Artifacts[0].Size != int64(len("manifests")) {
+				t.Errorf("unexpected artifact size %d in promoted metadata", meta.Artifacts[0].Size)
+			}
+
+			promoted, err := dst.GetRelease(ctx, name)
+			if err != nil {
+				t.Fatalf("failed to read promoted release: %v", err)
+			}
+
+			if promoted.Metadata().ReleaseVersion != "v1.2.3" {
+				t.Errorf("unexpected release version %q for promoted release", promoted.Metadata().ReleaseVersion)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package provenance generates SLSA v1 provenance for staged release
+// artifacts, in the form of in-toto statements.
+// See https://slsa.dev/spec/v1.0/provenance
+package provenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cert-manager/release/pkg/release"
+)
+
+const (
+	// StatementType is the type of the in-toto statements generated by this
+	// package.
+	StatementType = "https://in-toto.io/Statement/v1"
+
+	// PredicateType is the type of the SLSA v1 provenance predicate. It's also
+	// passed to 'cosign attest' as the type of the attestation.
+	PredicateType = "https://slsa.dev/provenance/v1"
+
+	// BuildType identifies the build process which produced the artifacts,
+	// being 'cmrel gcb stage'. The external parameters recorded in provenance
+	// are those accepted by that command.
+	BuildType = "https://github.com/cert-manager/release/gcb-stage/v1"
+)
+
+// Statement is an in-toto statement containing SLSA provenance for a set of
+// artifacts, each of which is a subject of the statement.
+type Statement struct {
+	Type          string     `json:"_type"`
+	Subject       []Subject  `json:"subject"`
+	PredicateType string     `json:"predicateType"`
+	Predicate     Provenance `json:"predicate"`
+}
+
+// Subject is an artifact which a Statement is about.
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Provenance is a SLSA v1 provenance predicate.
+type Provenance struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+// BuildDefinition describes the inputs to a build.
+type BuildDefinition struct {
+	BuildType            string               `json:"buildType"`
+	ExternalParameters   map[string]string    `json:"externalParameters"`
+	ResolvedDependencies []ResourceDescriptor `json:"resolvedDependencies,omitempty"`
+}
+
+// ResourceDescriptor identifies an input to a build, such as its source.
+type ResourceDescriptor struct {
+	URI    string            `json:"uri"`
+	Digest map[string]string `json:"digest,omitempty"`
+}
+
+// RunDetails describes the build which produced the artifacts.
+type RunDetails struct {
+	Builder  Builder        `json:"builder"`
+	Metadata *BuildMetadata `json:"metadata,omitempty"`
+}
+
+// Builder identifies the platform which ran a build.
+type Builder struct {
+	ID string `json:"id"`
+}
+
+// BuildMetadata holds information about a particular run of a build.
+type BuildMetadata struct {
+	InvocationID string     `json:"invocationId,omitempty"`
+	StartedOn    *time.Time `json:"startedOn,omitempty"`
+	FinishedOn   *time.Time `json:"finishedOn,omitempty"`
+}
+
+// Options configures the provenance generated by New.
+type Options struct {
+	// BuilderID identifies the Cloud Build job which ran the build.
+	BuilderID string
+
+	// InvocationID, if set, identifies this particular run of the build.
+	InvocationID string
+
+	// SourceRepository is the URL of the git repository which was built.
+	SourceRepository string
+
+	// GitCommitRef is the commit of SourceRepository which was built.
+	GitCommitRef string
+
+	// ExternalParameters are the parameters which the build was started with,
+	// such as the release version.
+	ExternalParameters map[string]string
+
+	// StartedOn and FinishedOn, if set, are the times at which the build
+	// started and finished.
+	StartedOn, FinishedOn time.Time
+}
+
+// New returns a provenance statement whose subjects are the given artifacts,
+// which must have been produced by the build described by opts.
+func New(opts Options, artifacts []release.ArtifactMetadata) (*Statement, error) {
+	if opts.BuilderID == "" {
+		return nil, fmt.Errorf("a builder ID is required to generate provenance")
+	}
+
+	if opts.SourceRepository == "" || opts.GitCommitRef == "" {
+		return nil, fmt.Errorf("a source repository and git commit ref are required to generate provenance")
+	}
+
+	if len(artifacts) == 0 {
+		return nil, fmt.Errorf("cannot generate provenance for no artifacts")
+	}
+
+	var subjects []Subject
+	for _, a := range artifacts {
+		if a.SHA256 == "" {
+			return nil, fmt.Errorf("artifact %q has no sha256, so can't be a subject of provenance", a.Name)
+		}
+
+		subjects = append(subjects, Subject{
+			Name:   a.Name,
+			Digest: map[string]string{"sha256": a.SHA256},
+		})
+	}
+
+	sort.Slice(subjects, func(i, j int) bool {
+		return subjects[i].Name < subjects[j].Name
+	})
+
+	externalParameters := map[string]string{}
+	for k, v := range opts.ExternalParameters {
+		externalParameters[k] = v
+	}
+
+	var metadata *BuildMetadata
+	if opts.InvocationID != "" || !opts.StartedOn.IsZero() || !opts.FinishedOn.IsZero() {
+		metadata = &BuildMetadata{
+			InvocationID: opts.InvocationID,
+			StartedOn:    utcTime(opts.StartedOn),
+			FinishedOn:   utcTime(opts.FinishedOn),
+		}
+	}
+
+	return &Statement{
+		Type:          StatementType,
+		Subject:       subjects,
+		PredicateType: PredicateType,
+		Predicate: Provenance{
+			BuildDefinition: BuildDefinition{
+				BuildType:          BuildType,
+				ExternalParameters: externalParameters,
+				ResolvedDependencies: []ResourceDescriptor{
+					{
+						URI:    sourceURI(opts.SourceRepository),
+						Digest: map[string]string{"gitCommit": opts.GitCommitRef},
+					},
+				},
+			},
+			RunDetails: RunDetails{
+				Builder:  Builder{ID: opts.BuilderID},
+				Metadata: metadata,
+			},
+		},
+	}, nil
+}
+
+// Parse decodes a provenance statement, such as one written by 'gcb stage',
+// returning an error if it isn't a statement containing SLSA v1 provenance.
+func Parse(data []byte) (*Statement, error) {
+	var s Statement
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to decode provenance: %w", err)
+	}
+
+	if s.Type != StatementType {
+		return nil, fmt.Errorf("provenance has statement type %q, expected %q", s.Type, StatementType)
+	}
+
+	if s.PredicateType != PredicateType {
+		return nil, fmt.Errorf("provenance has predicate type %q, expected %q", s.PredicateType, PredicateType)
+	}
+
+	return &s, nil
+}
+
+// CheckSubjects returns an error if any of the given artifacts isn't a
+// subject of s with a matching sha256 digest, in which case s doesn't describe
+// the build which produced them.
+func (s *Statement) CheckSubjects(artifacts []release.ArtifactMetadata) error {
+	digests := map[string]string{}
+	for _, subject := range s.Subject {
+		digests[subject.Name] = subject.Digest["sha256"]
+	}
+
+	var problems []string
+	for _, a := range artifacts {
+		digest, ok := digests[a.Name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("artifact %q is not a subject of the provenance", a.Name))
+			continue
+		}
+
+		if digest != a.SHA256 {
+			problems = append(problems, fmt.Sprintf("artifact %q has sha256 %q but the provenance has %q", a.Name, a.SHA256, digest))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("provenance doesn't match artifacts: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// PredicateJSON returns the provenance predicate of s, encoded as it's
+// expected by 'cosign attest --predicate'.
+func (s *Statement) PredicateJSON() ([]byte, error) {
+	return json.MarshalIndent(s.Predicate, "", "  ")
+}
+
+// sourceURI returns the SLSA resource URI for a git repository URL.
+func sourceURI(repo string) string {
+	if strings.HasPrefix(repo, "git+") {
+		return repo
+	}
+
+	return "git+" + repo
+}
+
+func utcTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	utc := t.UTC()
+	return &utc
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provenance
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/release/pkg/release"
+)
+
+func TestNew(t *testing.T) {
+	artifacts := []release.ArtifactMetadata{
+		{Name: "cert-manager-server-linux-amd64.tar.gz", SHA256: "def456", OS: "linux", Architecture: "amd64"},
+		{Name: "cert-manager-manifests.tar.gz", SHA256: "abc123"},
+	}
+
+	opts := Options{
+		BuilderID:          "https://cloudbuild.googleapis.com/projects/cert-manager-release/builds/1234",
+		InvocationID:       "1234",
+		SourceRepository:   "https://github.com/cert-manager/cert-manager.git",
+		GitCommitRef:       "614438aed00e1060870b273f2238794ef69b60ab",
+		ExternalParameters: map[string]string{"releaseVersion": "v1.3.1"},
+		StartedOn:          time.Date(2021, 5, 12, 10, 0, 0, 0, time.UTC),
+		FinishedOn:         time.Date(2021, 5, 12, 10, 30, 0, 0, time.UTC),
+	}
+
+	tests := map[string]struct {
+		modify    func(*Options)
+		artifacts []release.ArtifactMetadata
+		expectErr bool
+	}{
+		"valid provenance": {
+			artifacts: artifacts,
+		},
+		"no builder ID": {
+			modify:    func(o *Options) { o.BuilderID = "" },
+			artifacts: artifacts,
+			expectErr: true,
+		},
+		"no git commit ref": {
+			modify:    func(o *Options) { o.GitCommitRef = "" },
+			artifacts: artifacts,
+			expectErr: true,
+		},
+		"no artifacts": {
+			expectErr: true,
+		},
+		"artifact without a sha256": {
+			artifacts: []release.ArtifactMetadata{{Name: "cert-manager-manifests.tar.gz"}},
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			o := opts
+			if test.modify != nil {
+				test.modify(&o)
+			}
+
+			s, err := New(o, test.artifacts)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if test.expectErr {
+				return
+			}
+
+			expectedSubjects := []Subject{
+				{Name: "cert-manager-manifests.tar.gz", Digest: map[string]string{"sha256": "abc123"}},
+				{Name: "cert-manager-server-linux-amd64.tar.gz", Digest: map[string]string{"sha256": "def456"}},
+			}
+
+			if !reflect.DeepEqual(s.Subject, expectedSubjects) {
+				t.Errorf("unexpected subjects:\ngot=%+v\nexp=%+v", s.Subject, expectedSubjects)
+			}
+
+			expectedSource := []ResourceDescriptor{
+				{URI: "git+https://github.com/cert-manager/cert-manager.git", Digest: map[string]string{"gitCommit": "614438aed00e1060870b273f2238794ef69b60ab"}},
+			}
+
+			if !reflect.DeepEqual(s.Predicate.BuildDefinition.ResolvedDependencies, expectedSource) {
+				t.Errorf("unexpected resolved dependencies:\ngot=%+v\nexp=%+v", s.Predicate.BuildDefinition.ResolvedDependencies, expectedSource)
+			}
+
+			if s.Predicate.RunDetails.Builder.ID != o.BuilderID {
+				t.Errorf("unexpected builder ID %q", s.Predicate.RunDetails.Builder.ID)
+			}
+
+			if s.Predicate.BuildDefinition.ExternalParameters["releaseVersion"] != "v1.3.1" {
+				t.Errorf("unexpected external parameters %v", s.Predicate.BuildDefinition.ExternalParameters)
+			}
+
+			data, err := json.Marshal(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parsed, err := Parse(data)
+			if err != nil {
+				t.Fatalf("failed to parse generated provenance: %v", err)
+			}
+
+			if !reflect.DeepEqual(parsed, s) {
+				t.Errorf("provenance did not round trip:\ngot=%+v\nexp=%+v", parsed, s)
+			}
+
+			if err := parsed.CheckSubjects(test.artifacts); err != nil {
+				t.Errorf("expected provenance to match the artifacts it was generated for: %v", err)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		data      string
+		expectErr bool
+	}{
+		"slsa v1 provenance": {
+			data: `{"_type":"https://in-toto.io/Statement/v1","predicateType":"https://slsa.dev/provenance/v1","subject":[]}`,
+		},
+		"other predicate type": {
+			data:      `{"_type":"https://in-toto.io/Statement/v1","predicateType":"https://slsa.dev/provenance/v0.2","subject":[]}`,
+			expectErr: true,
+		},
+		"other statement type": {
+			data:      `{"_type":"https://example.com/Statement","predicateType":"https://slsa.dev/provenance/v1","subject":[]}`,
+			expectErr: true,
+		},
+		"invalid JSON": {
+			data:      `{`,
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse([]byte(test.data))
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckSubjects(t *testing.T) {
+	s := &Statement{
+		Subject: []Subject{
+			{Name: "cert-manager-manifests.tar.gz", Digest: map[string]string{"sha256": "abc123"}},
+		},
+	}
+
+	tests := map[string]struct {
+		artifacts []release.ArtifactMetadata
+		expectErr bool
+	}{
+		"matching artifact": {
+			artifacts: []release.ArtifactMetadata{{Name: "cert-manager-manifests.tar.gz", SHA256: "abc123"}},
+		},
+		"artifact with a different digest": {
+			artifacts: []release.ArtifactMetadata{{Name: "cert-manager-manifests.tar.gz", SHA256: "def456"}},
+			expectErr: true,
+		},
+		"artifact which isn't a subject": {
+			artifacts: []release.ArtifactMetadata{{Name: "cert-manager-server-linux-amd64.tar.gz", SHA256: "abc123"}},
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := s.CheckSubjects(test.artifacts)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+		})
+	}
+}
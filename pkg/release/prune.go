@@ -0,0 +1,129 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+// ReleaseDirectory is a directory in a bucket which may contain a staged
+// release. Builds which failed part way through staging, or promotions which
+// were interrupted, can leave behind directories without release metadata,
+// so a ReleaseDirectory isn't necessarily a valid staged release.
+type ReleaseDirectory struct {
+	// Name is the name of the release which the directory would contain.
+	Name string
+
+	// LastModified is the most recent time at which an object in the
+	// directory was written.
+	LastModified time.Time
+
+	// Objects is the number of objects in the directory.
+	Objects int
+
+	// Size is the total size in bytes of the objects in the directory.
+	Size int64
+}
+
+// ListReleaseDirectories returns every release directory in the bucket,
+// sorted by name, whether or not it contains a valid staged release.
+func (b *Bucket) ListReleaseDirectories(ctx context.Context) ([]ReleaseDirectory, error) {
+	dirs := map[string]*ReleaseDirectory{}
+	objs := b.bucket.Objects(ctx, &storage.Query{Prefix: b.prefix})
+	for {
+		objAttr, err := objs.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := NameForObjectPath(objAttr.Name, b.prefix)
+		dir, ok := dirs[name]
+		if !ok {
+			dir = &ReleaseDirectory{Name: name}
+			dirs[name] = dir
+		}
+
+		dir.Objects++
+		dir.Size += objAttr.Size
+		if objAttr.Updated.After(dir.LastModified) {
+			dir.LastModified = objAttr.Updated
+		}
+	}
+
+	var list []ReleaseDirectory
+	for _, dir := range dirs {
+		list = append(list, *dir)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return list, nil
+}
+
+// DeleteRelease deletes every object in the directory of the named release.
+// The metadata file is deleted first, so that a release which is only
+// partially deleted is no longer treated as a staged release.
+func (b *Bucket) DeleteRelease(ctx context.Context, name string) error {
+	if name == "" || strings.Contains(name, "/") {
+		return fmt.Errorf("invalid release name %q", name)
+	}
+
+	var objNames []string
+	objs := b.bucket.Objects(ctx, &storage.Query{Prefix: b.prefix + name + "/"})
+	for {
+		objAttr, err := objs.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		objNames = append(objNames, objAttr.Name)
+	}
+
+	if len(objNames) == 0 {
+		return fmt.Errorf("no release found with name %q: %w", name, ErrReleaseNotFound)
+	}
+
+	sort.SliceStable(objNames, func(i, j int) bool {
+		return b.isMetadataObject(name, objNames[i]) && !b.isMetadataObject(name, objNames[j])
+	})
+
+	for _, objName := range objNames {
+		err := b.bucket.Object(objName).Delete(ctx)
+		if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+			return fmt.Errorf("failed to delete %q from release %q: %w", path.Base(objName), name, err)
+		}
+	}
+
+	return nil
+}
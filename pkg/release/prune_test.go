@@ -0,0 +1,122 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestListReleaseDirectories(t *testing.T) {
+	ctx := context.Background()
+
+	objects := stagedReleaseObjects(t, "test-bucket", "stage/gcb/devel/abcdef", MetadataFileName, Metadata{GitCommitRef: "abcdef"})
+
+	// a build which failed before writing its metadata
+	objects["test-bucket/stage/gcb/devel/123456/cert-manager-manifests.tar.gz"] = []byte("partial")
+
+	// a release of another type
+	objects["test-bucket/stage/gcb/release/v1.2.3-abcdef/cert-manager-manifests.tar.gz"] = []byte("manifests")
+
+	fake, client := newFakeGCS(t, objects)
+
+	older := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+	fake.SetUpdated("test-bucket/stage/gcb/devel/abcdef/"+MetadataFileName, newer)
+	fake.SetUpdated("test-bucket/stage/gcb/devel/abcdef/cert-manager-manifests.tar.gz", older)
+	fake.SetUpdated("test-bucket/stage/gcb/devel/123456/cert-manager-manifests.tar.gz", older)
+
+	dirs, err := NewBucket(client.Bucket("test-bucket"), "stage/gcb", BuildTypeDevel).ListReleaseDirectories(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []ReleaseDirectory{
+		{Name: "123456", LastModified: older, Objects: 1, Size: int64(len("partial"))},
+		{Name: "abcdef", LastModified: newer, Objects: 2, Size: int64(len(objects["test-bucket/stage/gcb/devel/abcdef/"+MetadataFileName]) + len("manifests"))},
+	}
+
+	if !reflect.DeepEqual(dirs, expected) {
+		t.Errorf("unexpected release directories:\ngot=%+v\nexp=%+v", dirs, expected)
+	}
+}
+
+func TestDeleteRelease(t *testing.T) {
+	tests := map[string]struct {
+		name      string
+		expectErr bool
+	}{
+		"existing release": {
+			name: "abcdef",
+		},
+		"release doesn't exist": {
+			name:      "123456",
+			expectErr: true,
+		},
+		"empty name": {
+			name:      "",
+			expectErr: true,
+		},
+		"name containing a path separator": {
+			name:      "abcdef/cert-manager-manifests.tar.gz",
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			objects := stagedReleaseObjects(t, "test-bucket", "stage/gcb/devel/abcdef", MetadataFileName, Metadata{GitCommitRef: "abcdef"})
+			for name, data := range stagedReleaseObjects(t, "test-bucket", "stage/gcb/devel/abcdef0", MetadataFileName, Metadata{GitCommitRef: "abcdef0"}) {
+				objects[name] = data
+			}
+
+			fake, client := newFakeGCS(t, objects)
+
+			bucket := NewBucket(client.Bucket("test-bucket"), "stage/gcb", BuildTypeDevel)
+
+			err := bucket.DeleteRelease(ctx, test.name)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if test.name == "123456" && !errors.Is(err, ErrReleaseNotFound) {
+				t.Errorf("expected a missing release to return ErrReleaseNotFound, got %v", err)
+			}
+
+			// a release whose name has the deleted release's name as a prefix
+			// must be left alone
+			if _, ok := fake.Object("test-bucket/stage/gcb/devel/abcdef0/" + MetadataFileName); !ok {
+				t.Errorf("expected other releases to be left intact")
+			}
+
+			if test.expectErr {
+				return
+			}
+
+			for _, obj := range []string{MetadataFileName, "cert-manager-manifests.tar.gz"} {
+				if _, ok := fake.Object("test-bucket/stage/gcb/devel/abcdef/" + obj); ok {
+					t.Errorf("expected %q to be deleted", obj)
+				}
+			}
+		})
+	}
+}
@@ -17,41 +17,52 @@ limitations under the License.
 package registry
 
 import (
-	"context"
 	"fmt"
-	"log"
+	"log/slog"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/types"
 
 	"github.com/cert-manager/release/pkg/release/docker"
 	"github.com/cert-manager/release/pkg/release/images"
 )
 
-func CreateManifestList(ctx context.Context, name string, tars []*images.Tar) error {
-	imageNames := make([]string, len(tars))
-	for i, t := range tars {
-		if t.PublishedTag == "" {
-			return fmt.Errorf("image %q has no PublishedTag", t.RawImageName())
-		}
-
-		imageNames[i] = t.PublishedTag
+// BuildManifestList assembles a multi-arch docker manifest list from the
+// given per-architecture image tars of a single component, annotating each
+// entry with the os, architecture and variant of its image.
+// The manifest list refers to images by digest rather than by tag, so the same
+// manifest list can be pushed to every repository the images were pushed to.
+func BuildManifestList(tars []*images.Tar) (v1.ImageIndex, error) {
+	if len(tars) == 0 {
+		return nil, fmt.Errorf("cannot create a manifest list with no images")
 	}
 
-	log.Printf("Creating manifest list %q", name)
-	if err := docker.CreateManifestList(ctx, name, imageNames); err != nil {
-		log.Printf("Failed to create manifest list with name %q - ensure no existing manifest list exists with the same name, and ensure all member images are pushed to the remote registry.", name)
-		return err
-	}
+	idx := mutate.IndexMediaType(empty.Index, types.DockerManifestList)
 
 	for _, t := range tars {
-		a := manifestListAnnotationsForOSArch(t.OS(), t.Architecture())
-		log.Printf("Annotating image %q with os=%q, arch=%q, variant=%q", t.PublishedTag, a.os, a.arch, a.variant)
-		if err := docker.AnnotateManifestList(ctx, name, t.PublishedTag, a.os, a.arch, a.variant); err != nil {
-			log.Printf("Failed to annotate manifest list with os/arch information.")
-			return err
+		img, err := docker.Load(t.Filepath())
+		if err != nil {
+			return nil, err
 		}
+
+		a := manifestListAnnotationsForOSArch(t.OS(), t.Architecture())
+		slog.Info("adding image to manifest list", "image", t.RawImageName(), "os", a.os, "arch", a.arch, "variant", a.variant)
+
+		idx = mutate.AppendManifests(idx, mutate.IndexAddendum{
+			Add: img,
+			Descriptor: v1.Descriptor{
+				Platform: &v1.Platform{
+					OS:           a.os,
+					Architecture: a.arch,
+					Variant:      a.variant,
+				},
+			},
+		})
 	}
 
-	log.Printf("Created manifest list %q", name)
-	return nil
+	return idx, nil
 }
 
 type manifestAnnotation struct {
@@ -0,0 +1,158 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/cert-manager/release/pkg/release/docker"
+	"github.com/cert-manager/release/pkg/release/images"
+)
+
+func writeFixtureImage(t *testing.T, arch string) *images.Tar {
+	t.Helper()
+
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag, err := name.NewTag("quay.io/jetstack/cert-manager-controller-" + arch + ":v1.15.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "image.tar")
+	if err := tarball.WriteToFile(path, tag, img); err != nil {
+		t.Fatal(err)
+	}
+
+	imageTar, err := images.NewTar(path, "linux", arch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return imageTar
+}
+
+func TestBuildManifestList(t *testing.T) {
+	ctx := context.Background()
+
+	server := httptest.NewServer(ggcrregistry.New())
+	t.Cleanup(server.Close)
+
+	repo := strings.TrimPrefix(server.URL, "http://") + "/jetstack"
+
+	tars := []*images.Tar{
+		writeFixtureImage(t, "amd64"),
+		writeFixtureImage(t, "arm"),
+		writeFixtureImage(t, "arm64"),
+	}
+
+	// push each image straight from its tarball, as 'gcb publish' does
+	pushedDigests := map[string]v1.Hash{}
+	for _, tar := range tars {
+		img, err := docker.Load(tar.Filepath())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tag := repo + "/cert-manager-controller-" + tar.Architecture() + ":v1.15.0"
+		if _, err := docker.Push(ctx, img, tag); err != nil {
+			t.Fatalf("failed to push image: %v", err)
+		}
+
+		ref, err := name.ParseReference(tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		desc, err := remote.Head(ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pushedDigests[tar.Architecture()] = desc.Digest
+	}
+
+	idx, err := BuildManifestList(tars)
+	if err != nil {
+		t.Fatalf("failed to build manifest list: %v", err)
+	}
+
+	manifestListName := repo + "/cert-manager-controller:v1.15.0"
+	if _, err := docker.PushManifestList(ctx, idx, manifestListName); err != nil {
+		t.Fatalf("failed to push manifest list: %v", err)
+	}
+
+	ref, err := name.ParseReference(manifestListName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pushed, err := remote.Index(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mediaType, err := pushed.MediaType()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mediaType != types.DockerManifestList {
+		t.Errorf("unexpected media type %q for manifest list", mediaType)
+	}
+
+	manifest, err := pushed.IndexManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var platforms []string
+	for _, desc := range manifest.Manifests {
+		platforms = append(platforms, desc.Platform.String())
+
+		if desc.Digest != pushedDigests[desc.Platform.Architecture] {
+			t.Errorf("manifest list entry for %s has digest %s, but the pushed image has digest %s", desc.Platform, desc.Digest, pushedDigests[desc.Platform.Architecture])
+		}
+	}
+
+	expectedPlatforms := []string{"linux/amd64", "linux/arm/v7", "linux/arm64/v8"}
+	if !reflect.DeepEqual(platforms, expectedPlatforms) {
+		t.Errorf("expected platforms %v but got %v", expectedPlatforms, platforms)
+	}
+}
+
+func TestBuildManifestListNoImages(t *testing.T) {
+	if _, err := BuildManifestList(nil); err == nil {
+		t.Fatalf("expected an error when building a manifest list with no images")
+	}
+}
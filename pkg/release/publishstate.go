@@ -0,0 +1,140 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/storage"
+)
+
+// PublishState records the progress of publishing a staged release, so that a
+// publish which fails part way through can be resumed without repeating the
+// steps which had already completed.
+type PublishState struct {
+	// Actions records the progress of each publish action, keyed by the
+	// action name.
+	Actions map[string]*PublishActionState `json:"actions"`
+
+	// ManualActions lists the manual actions which must be taken after the
+	// most recent successful publish, one per line.
+	ManualActions string `json:"manualActions,omitempty"`
+}
+
+// PublishActionState records the progress of a single publish action.
+type PublishActionState struct {
+	// Completed is true once every step of the action has completed.
+	Completed bool `json:"completed"`
+
+	// Items records the individual items, such as images, charts or release
+	// assets, which the action has already published. Each item maps to an
+	// optional value needed when resuming, such as the ID of a created
+	// GitHub release.
+	Items map[string]string `json:"items,omitempty"`
+}
+
+// NewPublishState returns an empty PublishState.
+func NewPublishState() *PublishState {
+	return &PublishState{
+		Actions: map[string]*PublishActionState{},
+	}
+}
+
+// ActionCompleted returns true if the named action has completed.
+func (s *PublishState) ActionCompleted(action string) bool {
+	a, ok := s.Actions[action]
+	return ok && a.Completed
+}
+
+// CompleteAction marks the named action as completed.
+func (s *PublishState) CompleteAction(action string) {
+	s.action(action).Completed = true
+}
+
+// Item returns the value recorded for the given item of the named action, and
+// whether the item has been completed.
+func (s *PublishState) Item(action, item string) (string, bool) {
+	a, ok := s.Actions[action]
+	if !ok {
+		return "", false
+	}
+
+	value, ok := a.Items[item]
+	return value, ok
+}
+
+// CompleteItem marks the given item of the named action as completed,
+// recording the given value for it.
+func (s *PublishState) CompleteItem(action, item, value string) {
+	a := s.action(action)
+	if a.Items == nil {
+		a.Items = map[string]string{}
+	}
+
+	a.Items[item] = value
+}
+
+func (s *PublishState) action(action string) *PublishActionState {
+	if s.Actions == nil {
+		s.Actions = map[string]*PublishActionState{}
+	}
+
+	a, ok := s.Actions[action]
+	if !ok {
+		a = &PublishActionState{}
+		s.Actions[action] = a
+	}
+
+	return a
+}
+
+// ReadPublishState reads the publish state of the named release. If the
+// release has no publish state, an empty state is returned.
+func (b *Bucket) ReadPublishState(ctx context.Context, name string) (*PublishState, error) {
+	data, err := b.ReadFile(ctx, name, PublishStateFileName)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return NewPublishState(), nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	state := NewPublishState()
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to decode publish state of release %q: %w", name, err)
+	}
+
+	return state, nil
+}
+
+// WritePublishState overwrites the publish state of the named release.
+func (b *Bucket) WritePublishState(ctx context.Context, name string, state *PublishState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode publish state: %w", err)
+	}
+
+	if err := b.WriteFile(ctx, name, PublishStateFileName, data); err != nil {
+		return fmt.Errorf("failed to write publish state: %w", err)
+	}
+
+	return nil
+}
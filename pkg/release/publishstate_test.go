@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestPublishState(t *testing.T) {
+	state := NewPublishState()
+
+	if state.ActionCompleted("pushcontainerimages") {
+		t.Errorf("expected action to be incomplete in a new state")
+	}
+
+	if _, ok := state.Item("pushcontainerimages", "image:controller"); ok {
+		t.Errorf("expected item to be incomplete in a new state")
+	}
+
+	state.CompleteItem("githubrelease", "release", "12345")
+	if value, ok := state.Item("githubrelease", "release"); !ok || value != "12345" {
+		t.Errorf("expected item to be completed with value %q, got completed=%t value=%q", "12345", ok, value)
+	}
+
+	if state.ActionCompleted("githubrelease") {
+		t.Errorf("completing an item should not complete its action")
+	}
+
+	state.CompleteAction("githubrelease")
+	if !state.ActionCompleted("githubrelease") {
+		t.Errorf("expected action to be completed")
+	}
+
+	if state.ActionCompleted("helmchartpr") {
+		t.Errorf("completing one action should not complete others")
+	}
+
+	// the zero value can also be used, e.g. after decoding "actions": null
+	var zero PublishState
+	zero.CompleteItem("helmchartoci", "chart:cert-manager-v1.15.0.tgz", "")
+	if _, ok := zero.Item("helmchartoci", "chart:cert-manager-v1.15.0.tgz"); !ok {
+		t.Errorf("expected item to be completed in a zero value state")
+	}
+}
+
+func TestBucketPublishState(t *testing.T) {
+	ctx := context.Background()
+
+	fake, client := newFakeGCS(t, nil)
+	bucket := NewBucket(client.Bucket("test-bucket"), DefaultBucketPathPrefix, BuildTypeRelease)
+
+	const releaseName = "v1.15.0-614438aed00e1060870b273f2238794ef69b60ab"
+
+	state, err := bucket.ReadPublishState(ctx, releaseName)
+	if err != nil {
+		t.Fatalf("expected a missing publish state to be treated as empty, got: %v", err)
+	}
+
+	if !reflect.DeepEqual(state, NewPublishState()) {
+		t.Errorf("expected an empty publish state, got %+v", state)
+	}
+
+	state.CompleteItem("pushcontainerimages", "image:quay.io/jetstack/cert-manager-controller-amd64:v1.15.0", "")
+	state.CompleteItem("githubrelease", "release", "12345")
+	state.CompleteAction("githubrelease")
+	state.ManualActions = "* Update the GitHub release with release notes and hit PUBLISH!\n"
+
+	if err := bucket.WritePublishState(ctx, releaseName, state); err != nil {
+		t.Fatal(err)
+	}
+
+	objectName := "test-bucket/" + DefaultBucketPathPrefix + "/" + BuildTypeRelease + "/" + releaseName + "/" + PublishStateFileName
+	if _, ok := fake.Object(objectName); !ok {
+		t.Fatalf("expected publish state to be written to %q", objectName)
+	}
+
+	read, err := bucket.ReadPublishState(ctx, releaseName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(read, state) {
+		t.Errorf("publish state did not round trip:\ngot=%+v\nexp=%+v", read, state)
+	}
+}
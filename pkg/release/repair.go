@@ -0,0 +1,78 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// RecomputeArtifactMetadata downloads each artifact in the staged release and
+// returns a copy of the release metadata in which the SHA256 sum and size of
+// each artifact have been recomputed from the artifact's content. All other
+// metadata, such as the version, git ref, OS and architecture, is preserved.
+// The returned changes describe each value which differs from the staged
+// release's current metadata; if there are no changes, the metadata is
+// already consistent with the staged artifacts.
+func RecomputeArtifactMetadata(ctx context.Context, s *Staged) (*Metadata, []string, error) {
+	meta := s.Metadata()
+	meta.Artifacts = make([]ArtifactMetadata, len(s.artifacts))
+
+	var changes []string
+	for i, a := range s.artifacts {
+		sum, size, err := hashObject(ctx, &a)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to recompute checksum of %q: %w", a.Metadata.Name, err)
+		}
+
+		recomputed := a.Metadata
+		recomputed.SHA256 = sum
+		recomputed.Size = size
+
+		if recomputed.SHA256 != a.Metadata.SHA256 {
+			changes = append(changes, fmt.Sprintf("%s: sha256 %q -> %q", a.Metadata.Name, a.Metadata.SHA256, recomputed.SHA256))
+		}
+
+		if recomputed.Size != a.Metadata.Size {
+			changes = append(changes, fmt.Sprintf("%s: size %d -> %d", a.Metadata.Name, a.Metadata.Size, recomputed.Size))
+		}
+
+		meta.Artifacts[i] = recomputed
+	}
+
+	return &meta, changes, nil
+}
+
+// hashObject returns the SHA256 sum and size of a staged artifact's content.
+func hashObject(ctx context.Context, a *StagedArtifact) (string, int64, error) {
+	r, err := a.ObjectHandle.NewReader(ctx)
+	if err != nil {
+		return "", 0, err
+	}
+	defer r.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, r)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), size, nil
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestRecomputeArtifactMetadata(t *testing.T) {
+	ctx := context.Background()
+
+	manifestsSum := sha256.Sum256([]byte("manifests"))
+	serverSum := sha256.Sum256([]byte("reuploaded server"))
+
+	objects := stagedReleaseObjects(t, "test-bucket", "stage/gcb/devel/abcdef", MetadataFileName, Metadata{
+		ReleaseVersion: "v1.2.3",
+		GitCommitRef:   "abcdef",
+		Artifacts: []ArtifactMetadata{
+			{
+				Name:         "cert-manager-server-linux-amd64.tar.gz",
+				SHA256:       "stale",
+				OS:           "linux",
+				Architecture: "amd64",
+			},
+		},
+	})
+	objects["test-bucket/stage/gcb/devel/abcdef/cert-manager-server-linux-amd64.tar.gz"] = []byte("reuploaded server")
+
+	_, client := newFakeGCS(t, objects)
+
+	bucket := NewBucket(client.Bucket("test-bucket"), "stage/gcb", BuildTypeDevel)
+
+	staged, err := bucket.GetRelease(ctx, "abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta, changes, err := RecomputeArtifactMetadata(ctx, staged)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expMeta := &Metadata{
+		ReleaseVersion: "v1.2.3",
+		GitCommitRef:   "abcdef",
+		Artifacts: []ArtifactMetadata{
+			{
+				Name:         "cert-manager-server-linux-amd64.tar.gz",
+				SHA256:       hex.EncodeToString(serverSum[:]),
+				Size:         17,
+				OS:           "linux",
+				Architecture: "amd64",
+			},
+			{
+				Name:   "cert-manager-manifests.tar.gz",
+				SHA256: hex.EncodeToString(manifestsSum[:]),
+				Size:   9,
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(meta, expMeta) {
+		t.Errorf("unexpected recomputed metadata:\ngot=%+v\nexp=%+v", meta, expMeta)
+	}
+
+	expChanges := []string{
+		`cert-manager-server-linux-amd64.tar.gz: sha256 "stale" -> "` + hex.EncodeToString(serverSum[:]) + `"`,
+		"cert-manager-server-linux-amd64.tar.gz: size 0 -> 17",
+		"cert-manager-manifests.tar.gz: size 0 -> 9",
+	}
+
+	if !reflect.DeepEqual(changes, expChanges) {
+		t.Errorf("unexpected changes:\ngot=%q\nexp=%q", changes, expChanges)
+	}
+
+	if err := bucket.WriteMetadata(ctx, "abcdef", *meta); err != nil {
+		t.Fatal(err)
+	}
+
+	repaired, err := bucket.GetRelease(ctx, "abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(repaired.Metadata(), *expMeta) {
+		t.Errorf("unexpected metadata after repair:\ngot=%+v\nexp=%+v", repaired.Metadata(), *expMeta)
+	}
+
+	_, changes, err = RecomputeArtifactMetadata(ctx, repaired)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(changes) != 0 {
+		t.Errorf("expected no changes after repair, got %q", changes)
+	}
+}
+
+func TestRecomputeArtifactMetadataSignedRelease(t *testing.T) {
+	ctx := context.Background()
+
+	objects := stagedReleaseObjects(t, "test-bucket", "stage/gcb/release/v1.2.3-abcdef", MetadataFileName, Metadata{
+		ReleaseVersion: "v1.2.3",
+		GitCommitRef:   "abcdef",
+	})
+	const releaseDir = "test-bucket/stage/gcb/release/v1.2.3-abcdef/"
+
+	signature, err := fakeMetadataSigner("secret")(ctx, objects[releaseDir+MetadataFileName])
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects[releaseDir+MetadataFileName+MetadataSignatureSuffix] = signature
+	objects[releaseDir+"cert-manager-manifests.tar.gz"] = []byte("reuploaded manifests")
+
+	_, client := newFakeGCS(t, objects)
+
+	signing := NewBucket(client.Bucket("test-bucket"), "stage/gcb", BuildTypeRelease).
+		WithMetadataSigner(fakeMetadataSigner("secret")).
+		WithMetadataVerifier(fakeMetadataVerifier("secret"))
+
+	staged, err := signing.GetRelease(ctx, "v1.2.3-abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta, _, err := RecomputeArtifactMetadata(ctx, staged)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := signing.WriteMetadata(ctx, staged.Name(), *meta); err != nil {
+		t.Fatal(err)
+	}
+
+	repaired, err := signing.GetRelease(ctx, "v1.2.3-abcdef")
+	if err != nil {
+		t.Fatalf("expected repaired release to verify, got: %v", err)
+	}
+
+	if !reflect.DeepEqual(repaired.Metadata(), *meta) {
+		t.Errorf("unexpected metadata after repair:\ngot=%+v\nexp=%+v", repaired.Metadata(), *meta)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sbom generates software bills of materials (SBOMs) for release
+// artifacts using syft.
+package sbom
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/cert-manager/release/pkg/shell"
+)
+
+const (
+	// FormatSPDXJSON is the syft output format for SPDX JSON documents.
+	FormatSPDXJSON = "spdx-json"
+
+	// FormatCycloneDXJSON is the syft output format for CycloneDX JSON
+	// documents.
+	FormatCycloneDXJSON = "cyclonedx-json"
+)
+
+// Formats returns the supported SBOM formats.
+func Formats() []string {
+	return []string{FormatSPDXJSON, FormatCycloneDXJSON}
+}
+
+// ValidateFormat returns an error if format isn't a supported SBOM format.
+func ValidateFormat(format string) error {
+	for _, f := range Formats() {
+		if f == format {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unsupported SBOM format %q; options: %s", format, strings.Join(Formats(), ", "))
+}
+
+// CosignType returns the value of the --type flag which 'cosign attach sbom'
+// expects for SBOMs of the given format.
+func CosignType(format string) string {
+	switch format {
+	case FormatCycloneDXJSON:
+		return "cyclonedx"
+	default:
+		return "spdx"
+	}
+}
+
+// FileName returns the name of the SBOM file for the artifact with the given
+// file name, e.g. "cmctl-linux-amd64.tar.gz.spdx.json".
+func FileName(artifactName, format string) string {
+	switch format {
+	case FormatCycloneDXJSON:
+		return artifactName + ".cdx.json"
+	default:
+		return artifactName + ".spdx.json"
+	}
+}
+
+// DockerArchiveSource returns the syft source for the image tarball at path.
+func DockerArchiveSource(path string) string {
+	return "docker-archive:" + path
+}
+
+// FileSource returns the syft source for the file or archive at path.
+func FileSource(path string) string {
+	return "file:" + path
+}
+
+// Generate calls out to syft to catalog the given source, writing an SBOM
+// in the given format to outputPath. The name of the SBOM's subject is set to
+// the base name of the source.
+func Generate(ctx context.Context, syftPath, source, format, outputPath string) error {
+	if err := ValidateFormat(format); err != nil {
+		return err
+	}
+
+	_, path, _ := strings.Cut(source, ":")
+
+	args := []string{
+		"scan",
+		source,
+		"--output",
+		format + "=" + outputPath,
+		"--source-name",
+		filepath.Base(path),
+	}
+
+	return shell.Command(ctx, "", syftPath, args...)
+}
+
+// Version calls "syft version", both for informational purposes and as a
+// check that the binary exists.
+func Version(ctx context.Context, syftPath string) error {
+	return shell.Command(ctx, "", syftPath, "version")
+}
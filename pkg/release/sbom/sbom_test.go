@@ -0,0 +1,94 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sbom
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileName(t *testing.T) {
+	tests := map[string]struct {
+		format    string
+		expected  string
+		expectErr bool
+	}{
+		"spdx": {
+			format:   FormatSPDXJSON,
+			expected: "cmctl-linux-amd64.tar.gz.spdx.json",
+		},
+		"cyclonedx": {
+			format:   FormatCycloneDXJSON,
+			expected: "cmctl-linux-amd64.tar.gz.cdx.json",
+		},
+		"unsupported format": {
+			format:    "syft-table",
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateFormat(test.format)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if test.expectErr {
+				return
+			}
+
+			if got := FileName("cmctl-linux-amd64.tar.gz", test.format); got != test.expected {
+				t.Errorf("expected file name %q but got %q", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+
+	// fake syft records its arguments in the output file it's asked to write
+	syftPath := filepath.Join(dir, "syft")
+	script := "#!/bin/sh\nfor arg; do case \"$arg\" in *=*) out=\"${arg#*=}\";; esac; done\necho \"$@\" > \"$out\"\n"
+	if err := os.WriteFile(syftPath, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	outputPath := filepath.Join(dir, "image.spdx.json")
+
+	if err := Generate(context.Background(), syftPath, DockerArchiveSource("/tmp/images/controller.tar"), FormatSPDXJSON, outputPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "scan docker-archive:/tmp/images/controller.tar --output spdx-json=" + outputPath + " --source-name controller.tar"
+	if strings.TrimSpace(string(got)) != expected {
+		t.Errorf("expected syft to be called with %q but got %q", expected, strings.TrimSpace(string(got)))
+	}
+
+	if err := Generate(context.Background(), syftPath, FileSource("/tmp/cmctl.tar.gz"), "table", outputPath); err == nil {
+		t.Errorf("expected an error for an unsupported format")
+	}
+}
@@ -17,11 +17,17 @@ limitations under the License.
 package release
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"cloud.google.com/go/storage"
 )
@@ -34,6 +40,16 @@ type Staged struct {
 	prefix    string
 	meta      Metadata
 	artifacts []StagedArtifact
+
+	// objects holds every object in the release, keyed by object name.
+	objects map[string]*storage.ObjectHandle
+
+	// unreferenced holds the names of objects in the release which aren't
+	// named in the release metadata, relative to the root of the release.
+	unreferenced []string
+
+	// stagedAt is the time at which the release metadata was last written.
+	stagedAt time.Time
 }
 
 // StagedArtifact represents a single artifact within a release, with some
@@ -43,25 +59,56 @@ type StagedArtifact struct {
 	ObjectHandle *storage.ObjectHandle
 }
 
+// NewStagedRelease loads a staged release from the given objects, reading
+// release metadata from the file named MetadataFileName.
 func NewStagedRelease(ctx context.Context, name, prefix string, objects ...*storage.ObjectHandle) (*Staged, error) {
-	meta, err := loadReleaseMetadataFile(ctx, objects...)
+	return NewStagedReleaseWithMetadataFile(ctx, name, prefix, MetadataFileName, objects...)
+}
+
+// NewStagedReleaseWithMetadataFile loads a staged release from the given
+// objects, reading release metadata from the file named metadataFileName.
+func NewStagedReleaseWithMetadataFile(ctx context.Context, name, prefix, metadataFileName string, objects ...*storage.ObjectHandle) (*Staged, error) {
+	return newStagedRelease(ctx, name, prefix, metadataFileName, false, nil, objects...)
+}
+
+// newStagedRelease loads a staged release from the given objects, reading
+// release metadata from the file named metadataFileName. If strict is true,
+// metadata containing unknown fields is rejected. If verify is set, the
+// metadata is only trusted if its signature verifies.
+func newStagedRelease(ctx context.Context, name, prefix, metadataFileName string, strict bool, verify MetadataVerifier, objects ...*storage.ObjectHandle) (*Staged, error) {
+	meta, err := loadReleaseMetadataFile(ctx, metadataFileName, strict, verify, objects...)
 	if err != nil {
 		return nil, err
 	}
 
-	artifacts, err := crossReferenceArtifactMetadata(*meta, name, prefix, objects...)
+	artifacts, unreferenced, err := crossReferenceArtifactMetadata(*meta, name, prefix, metadataFileName, objects...)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Staged{
-		name:      name,
-		prefix:    prefix,
-		meta:      *meta,
-		artifacts: artifacts,
+		name:         name,
+		prefix:       prefix,
+		meta:         *meta,
+		artifacts:    artifacts,
+		objects:      mapifyObjectHandles(objects...),
+		unreferenced: unreferenced,
 	}, nil
 }
 
+// file returns the object holding the file with the given name in the root of
+// the release, or nil if there is no such file.
+func (s Staged) file(fileName string) *storage.ObjectHandle {
+	return s.objects[s.prefix+s.name+"/"+fileName]
+}
+
+// UnreferencedObjects returns the names of objects in the release, relative
+// to its root, which aren't artifacts named in the release metadata or files
+// holding metadata about the release. They are never published.
+func (s Staged) UnreferencedObjects() []string {
+	return s.unreferenced
+}
+
 // Name will return the name of the release in the GCS bucket
 func (s Staged) Name() string {
 	return s.name
@@ -72,6 +119,20 @@ func (s Staged) Metadata() Metadata {
 	return s.meta
 }
 
+// StagedAt returns the time at which the release metadata was last written.
+// Metadata is written once every artifact has been uploaded, so this is the
+// time at which the release finished being staged. It's zero if the release
+// wasn't listed from a bucket.
+func (s Staged) StagedAt() time.Time {
+	return s.stagedAt
+}
+
+// Artifacts returns every artifact in the staged release, in the order they
+// are listed in the release metadata.
+func (s Staged) Artifacts() []StagedArtifact {
+	return s.artifacts
+}
+
 // ArtifactsOfKind returns a list of staged artifacts of the type denoted by
 // `kind`. A kind may be 'server', 'manifests', 'test' etc.
 func (s Staged) ArtifactsOfKind(kind string) []StagedArtifact {
@@ -85,48 +146,203 @@ func (s Staged) ArtifactsOfKind(kind string) []StagedArtifact {
 	return objs
 }
 
-func loadReleaseMetadataFile(ctx context.Context, objs ...*storage.ObjectHandle) (*Metadata, error) {
-	var metadataObj *storage.ObjectHandle
+// loadReleaseMetadataFile reads release metadata from the object named
+// metadataFileName. If that object isn't present but a gzipped copy of it
+// (with a ".gz" suffix) is, the gzipped copy is read instead.
+// If strict is true, an error is returned if the metadata contains any fields
+// which are unknown to this version of cmrel.
+// If verify is set, the uncompressed metadata is checked against the detached
+// signature in the object named metadataFileName with a ".sig" suffix, and an
+// error wrapping ErrMetadataSignature is returned if it doesn't verify.
+func loadReleaseMetadataFile(ctx context.Context, metadataFileName string, strict bool, verify MetadataVerifier, objs ...*storage.ObjectHandle) (*Metadata, error) {
+	var metadataObj, gzippedMetadataObj, signatureObj *storage.ObjectHandle
 	for _, f := range objs {
-		if filepath.Base(f.ObjectName()) == MetadataFileName {
+		switch filepath.Base(f.ObjectName()) {
+		case metadataFileName:
 			metadataObj = f
-			break
+		case metadataFileName + GzippedMetadataSuffix:
+			gzippedMetadataObj = f
+		case metadataFileName + MetadataSignatureSuffix:
+			signatureObj = f
 		}
 	}
 
+	gzipped := false
 	if metadataObj == nil {
-		return nil, fmt.Errorf("release metadata not found")
+		metadataObj = gzippedMetadataObj
+		gzipped = true
 	}
 
-	r, err := metadataObj.NewReader(ctx)
+	if metadataObj == nil {
+		return nil, fmt.Errorf("release metadata file %q not found", metadataFileName)
+	}
+
+	objReader, err := metadataObj.NewReader(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer r.Close()
+	defer objReader.Close()
+
+	var r io.Reader = objReader
+	if gzipped {
+		gzr, err := gzip.NewReader(objReader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress release metadata: %w", err)
+		}
+		defer gzr.Close()
+
+		r = gzr
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read release metadata: %w", err)
+	}
+
+	if verify != nil {
+		if err := verifyReleaseMetadata(ctx, verify, data, signatureObj); err != nil {
+			return nil, err
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		dec.DisallowUnknownFields()
+	}
 
 	var m Metadata
-	if err := json.NewDecoder(r).Decode(&m); err != nil {
-		return nil, err
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to decode release metadata: %w", err)
+	}
+
+	if m.SchemaVersion > CurrentMetadataSchemaVersion {
+		return nil, fmt.Errorf("release metadata has schema version %d, but the newest version supported by this version of cmrel is %d", m.SchemaVersion, CurrentMetadataSchemaVersion)
 	}
 
 	return &m, nil
 }
 
-func crossReferenceArtifactMetadata(meta Metadata, name, prefix string, objs ...*storage.ObjectHandle) ([]StagedArtifact, error) {
+// verifyReleaseMetadata checks the metadata against the detached signature in
+// signatureObj using verify.
+func verifyReleaseMetadata(ctx context.Context, verify MetadataVerifier, metadata []byte, signatureObj *storage.ObjectHandle) error {
+	if signatureObj == nil {
+		return fmt.Errorf("release metadata has no signature: %w", ErrMetadataSignature)
+	}
+
+	r, err := signatureObj.NewReader(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read release metadata signature: %w", err)
+	}
+	defer r.Close()
+
+	signature, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read release metadata signature: %w", err)
+	}
+
+	if err := verify(ctx, metadata, signature); err != nil {
+		return fmt.Errorf("%w: %v", ErrMetadataSignature, err)
+	}
+
+	return nil
+}
+
+// crossReferenceArtifactMetadata matches each artifact listed in the release
+// metadata with its GCS object. An error is returned if any artifact is
+// missing or has a malformed name. The names of any objects other than the
+// release metadata which aren't referenced by the metadata are also returned,
+// relative to the root of the release, so that they can be reported without
+// making the release unusable.
+func crossReferenceArtifactMetadata(meta Metadata, name, prefix, metadataFileName string, objs ...*storage.ObjectHandle) ([]StagedArtifact, []string, error) {
 	var artifacts []StagedArtifact
 	objectMap := mapifyObjectHandles(objs...)
 	objPrefix := prefix + name + "/"
+	referenced := map[string]bool{}
 	for _, a := range meta.Artifacts {
+		if err := a.Validate(); err != nil {
+			return nil, nil, fmt.Errorf("invalid artifact metadata: %w", err)
+		}
+
+		if err := validateArtifactName(a); err != nil {
+			return nil, nil, fmt.Errorf("invalid artifact metadata: %w", err)
+		}
+
 		obj, ok := objectMap[objPrefix+a.Name]
 		if !ok {
-			return nil, fmt.Errorf("artifact %q named in manifest file but not present in list of GCS objects (path tested: %s)", a.Name, objPrefix+a.Name)
+			return nil, nil, fmt.Errorf("artifact %q named in manifest file but not present in list of GCS objects (path tested: %s)", a.Name, objPrefix+a.Name)
 		}
+		referenced[obj.ObjectName()] = true
 		artifacts = append(artifacts, StagedArtifact{
 			Metadata:     a,
 			ObjectHandle: obj,
 		})
 	}
-	return artifacts, nil
+
+	// files holding metadata about the release aren't artifacts
+	for _, fileName := range []string{metadataFileName, MetadataFileName, PublishedDigestsFileName, PublishStateFileName, ProvenanceFileName} {
+		referenced[objPrefix+fileName] = true
+		referenced[objPrefix+fileName+GzippedMetadataSuffix] = true
+		referenced[objPrefix+fileName+MetadataSignatureSuffix] = true
+	}
+
+	var unreferenced []string
+	for _, obj := range objs {
+		if referenced[obj.ObjectName()] {
+			continue
+		}
+		unreferenced = append(unreferenced, strings.TrimPrefix(obj.ObjectName(), objPrefix))
+	}
+	sort.Strings(unreferenced)
+
+	return artifacts, unreferenced, nil
+}
+
+// artifactPlatformPattern matches the "<os>-<arch>" part of the name of an
+// artifact which is built for a particular platform.
+const artifactPlatformPattern = `([a-z0-9]+)-([a-z0-9]+)`
+
+// artifactNamePatterns maps each kind of artifact to the pattern which the
+// names of artifacts of that kind must match. Patterns for artifacts built for
+// a particular platform capture the OS and architecture from the name.
+var artifactNamePatterns = func() map[string]*regexp.Regexp {
+	patterns := map[string]*regexp.Regexp{
+		"server":    regexp.MustCompile(`^` + releaseObjectPrefix + `server-` + artifactPlatformPattern + `\.tar\.gz$`),
+		"manifests": regexp.MustCompile(`^` + releaseObjectPrefix + `manifests\.tar\.gz$`),
+	}
+
+	for _, name := range ClientBinaryComponentNames() {
+		patterns[name] = regexp.MustCompile(`^` + regexp.QuoteMeta(releaseObjectPrefix+name) + `-` + artifactPlatformPattern + `\.(?:tar\.gz|zip)$`)
+	}
+
+	return patterns
+}()
+
+// validateArtifactName returns an error if the name of the artifact does not
+// match the expected pattern for its kind, or if the OS and architecture in
+// the name don't agree with the artifact's metadata.
+func validateArtifactName(a ArtifactMetadata) error {
+	var pattern *regexp.Regexp
+	for kind, p := range artifactNamePatterns {
+		if strings.HasPrefix(a.Name, releaseObjectPrefix+kind+"-") || strings.HasPrefix(a.Name, releaseObjectPrefix+kind+".") {
+			pattern = p
+			break
+		}
+	}
+
+	if pattern == nil {
+		return fmt.Errorf("artifact %q is not of a known kind", a.Name)
+	}
+
+	matches := pattern.FindStringSubmatch(a.Name)
+	if matches == nil {
+		return fmt.Errorf("artifact name %q does not match expected pattern %q", a.Name, pattern.String())
+	}
+
+	if len(matches) == 3 && (matches[1] != a.OS || matches[2] != a.Architecture) {
+		return fmt.Errorf("artifact %q is named for %s/%s but its metadata specifies %s/%s", a.Name, matches[1], matches[2], a.OS, a.Architecture)
+	}
+
+	return nil
 }
 
 func mapifyObjectHandles(objs ...*storage.ObjectHandle) map[string]*storage.ObjectHandle {
@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/storage"
+)
+
+func TestCrossReferenceArtifactMetadata(t *testing.T) {
+	const prefix = "stage/gcb/release/"
+	const name = "v1.2.3-abcdef"
+
+	artifact := func(name, os, arch string) ArtifactMetadata {
+		return ArtifactMetadata{Name: name, SHA256: "abc123", OS: os, Architecture: arch}
+	}
+
+	tests := map[string]struct {
+		artifacts []ArtifactMetadata
+		// objects are the names of objects in the release, relative to its
+		// root. If nil, one object is created for each artifact along with
+		// the metadata file.
+		objects []string
+
+		expectUnreferenced []string
+		expectErr          bool
+	}{
+		"well-formed names": {
+			artifacts: []ArtifactMetadata{
+				artifact("cert-manager-server-linux-amd64.tar.gz", "linux", "amd64"),
+				artifact("cert-manager-server-linux-arm64.tar.gz", "linux", "arm64"),
+				artifact("cert-manager-cmctl-linux-amd64.tar.gz", "linux", "amd64"),
+				artifact("cert-manager-cmctl-windows-amd64.zip", "windows", "amd64"),
+				artifact("cert-manager-kubectl-cert_manager-darwin-arm64.tar.gz", "darwin", "arm64"),
+				artifact("cert-manager-manifests.tar.gz", "", ""),
+			},
+		},
+		"unknown kind": {
+			artifacts: []ArtifactMetadata{artifact("cert-manager-unknown-linux-amd64.tar.gz", "linux", "amd64")},
+			expectErr: true,
+		},
+		"server artifact missing architecture in name": {
+			artifacts: []ArtifactMetadata{artifact("cert-manager-server-linux.tar.gz", "linux", "amd64")},
+			expectErr: true,
+		},
+		"server artifact with wrong extension": {
+			artifacts: []ArtifactMetadata{artifact("cert-manager-server-linux-amd64.zip", "linux", "amd64")},
+			expectErr: true,
+		},
+		"server artifact name disagrees with metadata architecture": {
+			artifacts: []ArtifactMetadata{artifact("cert-manager-server-linux-arm64.tar.gz", "linux", "amd64")},
+			expectErr: true,
+		},
+		"ctl artifact name disagrees with metadata os": {
+			artifacts: []ArtifactMetadata{artifact("cert-manager-cmctl-darwin-amd64.tar.gz", "linux", "amd64")},
+			expectErr: true,
+		},
+		"manifests artifact with unexpected suffix": {
+			artifacts: []ArtifactMetadata{artifact("cert-manager-manifests-extra.tar.gz", "", "")},
+			expectErr: true,
+		},
+		"metadata and published digests aren't artifacts": {
+			artifacts: []ArtifactMetadata{artifact("cert-manager-manifests.tar.gz", "", "")},
+			objects:   []string{"cert-manager-manifests.tar.gz", "custom-metadata.json.gz", MetadataFileName, PublishedDigestsFileName},
+		},
+		"provenance isn't an artifact": {
+			artifacts: []ArtifactMetadata{artifact("cert-manager-manifests.tar.gz", "", "")},
+			objects:   []string{"cert-manager-manifests.tar.gz", "custom-metadata.json.gz", ProvenanceFileName},
+		},
+		"object not referenced by metadata is reported": {
+			artifacts:          []ArtifactMetadata{artifact("cert-manager-manifests.tar.gz", "", "")},
+			objects:            []string{"cert-manager-manifests.tar.gz", "custom-metadata.json", "cert-manager-server-linux-amd64.tar.gz", "build.log"},
+			expectUnreferenced: []string{"build.log", "cert-manager-server-linux-amd64.tar.gz"},
+		},
+		"artifact missing from objects": {
+			artifacts: []ArtifactMetadata{
+				artifact("cert-manager-server-linux-amd64.tar.gz", "linux", "amd64"),
+				artifact("cert-manager-manifests.tar.gz", "", ""),
+			},
+			objects:   []string{"cert-manager-manifests.tar.gz", "custom-metadata.json"},
+			expectErr: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			_, client := newFakeGCS(t, nil)
+			bucket := client.Bucket("test-bucket")
+
+			objectNames := test.objects
+			if objectNames == nil {
+				objectNames = []string{"custom-metadata.json"}
+				for _, a := range test.artifacts {
+					objectNames = append(objectNames, a.Name)
+				}
+			}
+
+			var objs []*storage.ObjectHandle
+			for _, objectName := range objectNames {
+				objs = append(objs, bucket.Object(prefix+name+"/"+objectName))
+			}
+
+			artifacts, unreferenced, err := crossReferenceArtifactMetadata(Metadata{Artifacts: test.artifacts}, name, prefix, "custom-metadata.json", objs...)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if len(artifacts) != len(test.artifacts) {
+				t.Errorf("expected %d artifacts but got %d", len(test.artifacts), len(artifacts))
+			}
+
+			if !reflect.DeepEqual(unreferenced, test.expectUnreferenced) {
+				t.Errorf("expected unreferenced objects %q but got %q", test.expectUnreferenced, unreferenced)
+			}
+		})
+	}
+}
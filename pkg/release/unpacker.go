@@ -22,10 +22,13 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
+
+	"golang.org/x/sync/errgroup"
 
 	"github.com/cert-manager/release/pkg/release/binaries"
 	"github.com/cert-manager/release/pkg/release/images"
@@ -41,18 +44,43 @@ type Unpacked struct {
 	ReleaseName           string
 	ReleaseVersion        string
 	GitCommitRef          string
+	BuildSource           string
+	UnreferencedObjects   []string
 	Charts                []manifests.Chart
 	YAMLs                 []manifests.YAML
 	CtlBinaryBundles      []binaries.Archive // Only in v1.14.X and below.
 	ComponentImageBundles map[string][]*images.Tar
 }
 
+// UnpackOptions configures how a staged release is unpacked.
+type UnpackOptions struct {
+	// ImageTarPrefix and ImageTarSuffix are trimmed from the file names of
+	// image tars in server artifacts, after removing the ".tar" extension, to
+	// determine the name of the component each image belongs to. For
+	// example, an ImageTarPrefix of "cert-manager-" maps
+	// "cert-manager-controller.tar" to the "controller" component.
+	// By default, nothing is trimmed.
+	ImageTarPrefix string
+	ImageTarSuffix string
+
+	// Parallelism is the number of artifacts of each kind which are
+	// downloaded and extracted concurrently. Values less than 1 are treated
+	// as 1, so that artifacts are unpacked one at a time.
+	Parallelism int
+}
+
 // Unpack takes a staged release, inspects its metadata, fetches referenced
 // artifacts and extracts them to disk.
 func Unpack(ctx context.Context, s *Staged) (*Unpacked, error) {
-	log.Printf("Unpacking staged release %q", s.Name())
+	return UnpackWithOptions(ctx, s, UnpackOptions{})
+}
+
+// UnpackWithOptions is like Unpack, but allows configuring how the staged
+// release is unpacked.
+func UnpackWithOptions(ctx context.Context, s *Staged, opts UnpackOptions) (*Unpacked, error) {
+	slog.Info("unpacking staged release", "release", s.Name())
 
-	log.Printf("Unpacking 'manifests' type artifact")
+	slog.Info("unpacking artifact", "type", "manifests")
 	manifestsA, err := manifestArtifactForStaged(s)
 	if err != nil {
 		return nil, err
@@ -61,7 +89,7 @@ func Unpack(ctx context.Context, s *Staged) (*Unpacked, error) {
 	if err != nil {
 		return nil, err
 	}
-	log.Printf("Unpacked 'manifests' artifact to directory: %s", manifestsDir)
+	slog.Info("unpacked artifact", "type", "manifests", "dir", manifestsDir)
 
 	// chart packages have a .tgz file extension
 	chartPaths, err := recursiveFindWithExt(manifestsDir, ".tgz")
@@ -76,7 +104,7 @@ func Unpack(ctx context.Context, s *Staged) (*Unpacked, error) {
 		}
 		charts = append(charts, *c)
 	}
-	log.Printf("Extracted %d Helm charts from manifests archive", len(charts))
+	slog.Info("extracted Helm charts from manifests archive", "count", len(charts))
 
 	// static manifests have a .yaml file extension
 	yamlPaths, err := recursiveFindWithExt(manifestsDir, ".yaml")
@@ -87,29 +115,31 @@ func Unpack(ctx context.Context, s *Staged) (*Unpacked, error) {
 	for _, path := range yamlPaths {
 		yamls = append(yamls, *manifests.NewYAML(path))
 	}
-	log.Printf("Extracted %d YAML manifests from manifests archive", len(yamls))
+	slog.Info("extracted YAML manifests from manifests archive", "count", len(yamls))
 
-	bundles, err := unpackServerImagesFromRelease(ctx, s)
+	bundles, err := unpackServerImagesFromRelease(ctx, s, opts)
 	if err != nil {
 		return nil, err
 	}
-	log.Printf("Extracted %d component bundles from images archive", len(bundles))
+	slog.Info("extracted component bundles from images archive", "count", len(bundles))
 
 	var ctlBinaryBundles []binaries.Archive
-	if CmctlIsShipped(s.meta.ReleaseVersion) {
+	if CmctlIsShipped(s.meta.ReleaseVersion) || hasCtlArtifacts(s) {
 		var err error
-		ctlBinaryBundles, err = unpackCtlFromRelease(ctx, s)
+		ctlBinaryBundles, err = unpackCtlFromRelease(ctx, s, opts.Parallelism)
 		if err != nil {
 			return nil, err
 		}
 
-		log.Printf("Extracted %d multi arch ctl bundles from cmctl and kubectl-cert_manager archives", len(ctlBinaryBundles))
+		slog.Info("extracted multi arch ctl bundles from cmctl and kubectl-cert_manager archives", "count", len(ctlBinaryBundles))
 	}
 
 	return &Unpacked{
 		ReleaseName:           s.Name(),
 		ReleaseVersion:        s.Metadata().ReleaseVersion,
 		GitCommitRef:          s.Metadata().GitCommitRef,
+		BuildSource:           s.Metadata().BuildSource,
+		UnreferencedObjects:   s.UnreferencedObjects(),
 		YAMLs:                 yamls,
 		Charts:                charts,
 		CtlBinaryBundles:      ctlBinaryBundles,
@@ -120,25 +150,48 @@ func Unpack(ctx context.Context, s *Staged) (*Unpacked, error) {
 // unpackServerImagesFromRelease will extract all 'image-like' tar archives
 // from the various 'server' .tar.gz files and return a map of component name
 // to a slice of images.Tar for each image in the bundle.
-func unpackServerImagesFromRelease(ctx context.Context, s *Staged) (map[string][]*images.Tar, error) {
-	log.Printf("Unpacking 'server' type artifacts")
+func unpackServerImagesFromRelease(ctx context.Context, s *Staged, opts UnpackOptions) (map[string][]*images.Tar, error) {
+	slog.Info("unpacking artifacts", "type", "server")
 	serverA := s.ArtifactsOfKind("server")
-	return unpackImages(ctx, serverA, "")
+	return unpackImages(ctx, serverA, opts.ImageTarPrefix, opts.ImageTarSuffix, opts.Parallelism)
+}
+
+// hasCtlArtifacts returns true if s contains any CLI binary archives. Releases
+// which don't ship cmctl themselves may still contain them, to be published to
+// the cmctl repository instead.
+func hasCtlArtifacts(s *Staged) bool {
+	for _, name := range ClientBinaryComponentNames() {
+		if len(s.ArtifactsOfKind(name)) > 0 {
+			return true
+		}
+	}
+
+	return false
 }
 
 // unpackCtlFromRelease extracts all ctl archives from the various 'ctl' .tar.gz / .zip files
 // a slice of binaries.Archive holding each ctl binary in the bundle.
-func unpackCtlFromRelease(ctx context.Context, s *Staged) ([]binaries.Archive, error) {
-	log.Printf("Unpacking 'cmctl' and 'kubectl-cert_manager' type artifacts")
+func unpackCtlFromRelease(ctx context.Context, s *Staged, parallelism int) ([]binaries.Archive, error) {
+	slog.Info("unpacking artifacts", "type", "cmctl,kubectl-cert_manager")
+
+	// names holds the component name of each artifact in ctlA
+	var names []string
+	var ctlA []StagedArtifact
+	for _, name := range ClientBinaryComponentNames() {
+		for _, a := range s.ArtifactsOfKind(name) {
+			names = append(names, name)
+			ctlA = append(ctlA, a)
+		}
+	}
 
 	if s.Metadata().BuildSource == BuildSourceMake {
-		return unpackCtlFromMakeRelease(ctx, s)
+		return unpackCtlFromMakeRelease(ctx, names, ctlA, parallelism)
 	} else {
-		return unpackCtlFromBazelRelease(ctx, s)
+		return unpackCtlFromBazelRelease(ctx, names, ctlA, parallelism)
 	}
 }
 
-func unpackCtlFromMakeRelease(ctx context.Context, s *Staged) ([]binaries.Archive, error) {
+func unpackCtlFromMakeRelease(ctx context.Context, names []string, ctlA []StagedArtifact, parallelism int) ([]binaries.Archive, error) {
 	// Example layouts of make ctl archives, containing just the binary + license file
 	// cert-manager-cmctl-linux-amd64.tar.gz
 	//   ├── cmctl
@@ -146,28 +199,35 @@ func unpackCtlFromMakeRelease(ctx context.Context, s *Staged) ([]binaries.Archiv
 	// cert-manager-cmctl-windows-amd64.zip
 	//   ├── cmctl
 	//   └── LICENSE
-	var binaryBundles []binaries.Archive
+	for i, a := range ctlA {
+		ext := ArchiveFormatForOS(a.Metadata.OS)
+		if !strings.HasSuffix(a.Metadata.Name, ext) {
+			return nil, fmt.Errorf("expected %s archive %q for os=%s to have extension %q", names[i], a.Metadata.Name, a.Metadata.OS, ext)
+		}
+	}
 
-	for _, name := range []string{"kubectl-cert_manager", "cmctl"} {
-		ctlA := s.ArtifactsOfKind(name)
-		for _, a := range ctlA {
-			f, err := downloadStagedArtifact(ctx, &a)
-			if err != nil {
-				return nil, fmt.Errorf("failed to download %q: %w", a.Metadata.Name, err)
-			}
+	binaryBundles := make([]binaries.Archive, len(ctlA))
+	if err := forEachArtifact(ctx, ctlA, parallelism, func(ctx context.Context, i int, a *StagedArtifact) error {
+		f, err := downloadStagedArtifact(ctx, a)
+		if err != nil {
+			return fmt.Errorf("failed to download %q: %w", a.Metadata.Name, err)
+		}
+		f.Close()
 
-			binaryArchive := binaries.NewArchive(name, f.Name(), a.Metadata.OS, a.Metadata.Architecture, a.Metadata.Name)
+		binaryArchive := binaries.NewArchive(names[i], f.Name(), a.Metadata.OS, a.Metadata.Architecture, ArchiveFormatForOS(a.Metadata.OS))
 
-			log.Printf("Found %s CLI binary archive for os=%s, arch=%s", name, binaryArchive.OS(), binaryArchive.Architecture())
+		slog.Info("found CLI binary archive", "name", names[i], "os", binaryArchive.OS(), "arch", binaryArchive.Architecture())
 
-			binaryBundles = append(binaryBundles, *binaryArchive)
-		}
+		binaryBundles[i] = *binaryArchive
+		return nil
+	}); err != nil {
+		return nil, err
 	}
 
 	return binaryBundles, nil
 }
 
-func unpackCtlFromBazelRelease(ctx context.Context, s *Staged) ([]binaries.Archive, error) {
+func unpackCtlFromBazelRelease(ctx context.Context, names []string, ctlA []StagedArtifact, parallelism int) ([]binaries.Archive, error) {
 	// Example layout of a bazel ctl archive, containing another embedded archive
 	// cert-manager-cmctl-linux-amd64.tar.gz
 	//   ├── cmctl-linux-amd64.tar.gz
@@ -175,39 +235,52 @@ func unpackCtlFromBazelRelease(ctx context.Context, s *Staged) ([]binaries.Archi
 	//   │   └── LICENSES
 	//   └── version
 
-	var binaryBundles []binaries.Archive
-
-	for _, name := range []string{"kubectl-cert_manager", "cmctl"} {
-		ctlA := s.ArtifactsOfKind(name)
-		for _, a := range ctlA {
-			dir, err := extractStagedArtifactToTempDir(ctx, &a)
-			if err != nil {
-				return nil, err
-			}
+	// found holds the binary archives embedded in each artifact in ctlA
+	found := make([][]binaries.Archive, len(ctlA))
+	if err := forEachArtifact(ctx, ctlA, parallelism, func(ctx context.Context, i int, a *StagedArtifact) error {
+		dir, err := extractStagedArtifactToTempDir(ctx, a)
+		if err != nil {
+			return err
+		}
 
-			binaryArchives, err := recursiveFindWithExt(dir, ".gz")
-			if err != nil {
-				return nil, err
-			}
+		// Bazel embeds a .tar.gz archive for every OS, including windows
+		binaryArchives, err := recursiveFindWithExt(dir, ".gz")
+		if err != nil {
+			return err
+		}
 
-			for _, archive := range binaryArchives {
-				binaryArchive := binaries.NewArchive(name, archive, a.Metadata.OS, a.Metadata.Architecture, a.Metadata.Name)
+		for _, archive := range binaryArchives {
+			binaryArchive := binaries.NewArchive(names[i], archive, a.Metadata.OS, a.Metadata.Architecture, ArchiveFormatTarGz)
 
-				log.Printf("Found %s CLI binary archive for os=%s, arch=%s", name, binaryArchive.OS(), binaryArchive.Architecture())
+			slog.Info("found CLI binary archive", "name", names[i], "os", binaryArchive.OS(), "arch", binaryArchive.Architecture())
 
-				binaryBundles = append(binaryBundles, *binaryArchive)
-			}
+			found[i] = append(found[i], *binaryArchive)
 		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	var binaryBundles []binaries.Archive
+	for _, archives := range found {
+		binaryBundles = append(binaryBundles, archives...)
 	}
 
 	return binaryBundles, nil
 }
 
-func unpackImages(ctx context.Context, artifacts []StagedArtifact, trimSuffix string) (map[string][]*images.Tar, error) {
-	// tarBundles is a map from component name to slices of images.Tar
-	tarBundles := make(map[string][]*images.Tar)
+func unpackImages(ctx context.Context, artifacts []StagedArtifact, trimPrefix, trimSuffix string, parallelism int) (map[string][]*images.Tar, error) {
+	// found holds the component name and image tar of each image found in
+	// each artifact, so that images are added to the bundles in the same
+	// order regardless of which artifacts finish unpacking first
+	type componentImage struct {
+		component string
+		tar       *images.Tar
+	}
+	found := make([][]componentImage, len(artifacts))
 
-	for _, a := range artifacts {
+	if err := forEachArtifact(ctx, artifacts, parallelism, func(ctx context.Context, i int, a *StagedArtifact) error {
 		// each server bundle is a .tar.gz file which looks like this:
 		// cert-manager-server-<os>-<arch>
 		// ├── LICENSES
@@ -227,33 +300,164 @@ func unpackImages(ctx context.Context, artifacts []StagedArtifact, trimSuffix st
 
 		// Each .tar file is a separate container
 
-		dir, err := extractStagedArtifactToTempDir(ctx, &a)
+		dir, err := extractStagedArtifactToTempDir(ctx, a)
 		if err != nil {
-			return nil, err
+			return err
+		}
+
+		if err := validateServerArtifactLayout(dir); err != nil {
+			return fmt.Errorf("server artifact %q has an unexpected layout: %w", a.Metadata.Name, err)
 		}
 
 		// imageArchives becomes a list of each container packaged in this artifact
 		imageArchives, err := recursiveFindWithExt(dir, ".tar")
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		for _, archive := range imageArchives {
 			imageTar, err := images.NewTar(archive, a.Metadata.OS, a.Metadata.Architecture)
 			if err != nil {
-				return nil, fmt.Errorf("failed to inspect image tar at path %q: %w", archive, err)
+				return fmt.Errorf("failed to inspect image tar at path %q: %w", archive, err)
+			}
+
+			componentName, err := imageComponentName(archive, trimPrefix, trimSuffix)
+			if err != nil {
+				return err
 			}
 
-			baseName := filepath.Base(archive)
-			componentName := strings.TrimSuffix(baseName[:len(baseName)-len(filepath.Ext(baseName))], trimSuffix)
-			log.Printf("Found image for component %q with name %q", componentName, imageTar.RawImageName())
-			tarBundles[componentName] = append(tarBundles[componentName], imageTar)
+			slog.Info("found image for component", "component", componentName, "image", imageTar.RawImageName())
+			found[i] = append(found[i], componentImage{component: componentName, tar: imageTar})
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	// tarBundles is a map from component name to slices of images.Tar
+	tarBundles := make(map[string][]*images.Tar)
+	for _, imgs := range found {
+		for _, image := range imgs {
+			tarBundles[image.component] = append(tarBundles[image.component], image.tar)
 		}
 	}
 
 	return tarBundles, nil
 }
 
+// forEachArtifact calls fn for each of the given artifacts, with at most
+// parallelism calls running at once, logging progress as each call completes.
+// fn is passed the index of the artifact, so that results can be collected in
+// the same order as the artifacts. If any call fails, the context passed to
+// the remaining calls is cancelled and the first error is returned.
+func forEachArtifact(ctx context.Context, artifacts []StagedArtifact, parallelism int, fn func(ctx context.Context, i int, a *StagedArtifact) error) error {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(parallelism)
+
+	var completed atomic.Int64
+	for i := range artifacts {
+		a := &artifacts[i]
+		g.Go(func() error {
+			if err := fn(ctx, i, a); err != nil {
+				return err
+			}
+
+			slog.Info("unpacked artifact", "artifact", a.Metadata.Name, "completed", completed.Add(1), "total", len(artifacts))
+			return nil
+		})
+	}
+
+	return g.Wait()
+}
+
+// imageComponentName returns the name of the component which the image tar at
+// path belongs to, by trimming its extension followed by the given prefix and
+// suffix from its file name. An error is returned if the result isn't the name
+// of a known image component.
+func imageComponentName(path, trimPrefix, trimSuffix string) (string, error) {
+	baseName := filepath.Base(path)
+	name := strings.TrimSuffix(baseName, filepath.Ext(baseName))
+	name = strings.TrimSuffix(strings.TrimPrefix(name, trimPrefix), trimSuffix)
+
+	for _, known := range ImageComponentNames() {
+		if name == known {
+			return name, nil
+		}
+	}
+
+	return "", fmt.Errorf("image tar %q maps to unknown component %q; expected one of %s", baseName, name, strings.Join(ImageComponentNames(), ", "))
+}
+
+// validateServerArtifactLayout checks that the extracted server artifact at
+// dir has the expected top-level layout, containing at least one image tar in
+// "server/images" and a "version" file. The layout may optionally be nested
+// inside a single top-level directory.
+// A missing "LICENSES" file is logged as a warning rather than an error, since
+// it isn't needed to publish a release.
+func validateServerArtifactLayout(dir string) error {
+	root, err := serverArtifactRoot(dir)
+	if err != nil {
+		return err
+	}
+
+	imagesDir := filepath.Join(root, "server", "images")
+	info, err := os.Stat(imagesDir)
+	switch {
+	case os.IsNotExist(err):
+		return fmt.Errorf("missing \"server/images\" directory")
+	case err != nil:
+		return err
+	case !info.IsDir():
+		return fmt.Errorf("expected \"server/images\" to be a directory")
+	}
+
+	imageArchives, err := recursiveFindWithExt(imagesDir, ".tar")
+	if err != nil {
+		return err
+	}
+
+	if len(imageArchives) == 0 {
+		return fmt.Errorf("\"server/images\" directory contains no image tars")
+	}
+
+	info, err = os.Stat(filepath.Join(root, "version"))
+	switch {
+	case os.IsNotExist(err):
+		return fmt.Errorf("missing \"version\" file")
+	case err != nil:
+		return err
+	case !info.Mode().IsRegular():
+		return fmt.Errorf("expected \"version\" to be a regular file")
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "LICENSES")); os.IsNotExist(err) {
+		slog.Warn("server artifact is missing a \"LICENSES\" file")
+	}
+
+	return nil
+}
+
+// serverArtifactRoot returns the directory within dir which contains the
+// server artifact layout. This is dir itself, unless dir contains only a
+// single directory in which case that directory is used.
+func serverArtifactRoot(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	if len(entries) == 1 && entries[0].IsDir() && entries[0].Name() != "server" {
+		return filepath.Join(dir, entries[0].Name()), nil
+	}
+
+	return dir, nil
+}
+
 // recursiveFindWithExt will recursively Walk a directory searching for files
 // that have the given extension and return their path.
 func recursiveFindWithExt(path, ext string) ([]string, error) {
@@ -331,17 +535,54 @@ func downloadStagedArtifact(ctx context.Context, a *StagedArtifact) (*os.File, e
 		return nil, fmt.Errorf("artifact %q has a mismatching checksum - refusing to extract", a.Metadata.Name)
 	}
 
-	log.Printf("Validated sha256sum of artifact %q: %s", a.Metadata.Name, downloadedSum)
+	slog.Info("validated sha256sum of artifact", "artifact", a.Metadata.Name, "sha256", downloadedSum)
 
 	return f, nil
 }
 
+// DownloadArtifact downloads the staged artifact to the file at path. An
+// error is returned, and the file removed, if the downloaded content doesn't
+// match the checksum recorded for the artifact in the release metadata.
+func DownloadArtifact(ctx context.Context, a *StagedArtifact, path string) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+
+		if err != nil {
+			os.Remove(path)
+		}
+	}()
+
+	r, err := a.ObjectHandle.NewReader(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, hasher), r); err != nil {
+		return err
+	}
+
+	if sum := hex.EncodeToString(hasher.Sum(nil)); sum != a.Metadata.SHA256 {
+		return fmt.Errorf("artifact %q has checksum %q but the release metadata has %q", a.Metadata.Name, sum, a.Metadata.SHA256)
+	}
+
+	return nil
+}
+
 func extractStagedArtifactToTempDir(ctx context.Context, a *StagedArtifact) (string, error) {
 	dest, err := os.MkdirTemp("", "extracted-artifact-")
 	if err != nil {
 		return "", err
 	}
-	log.Printf("Extracting artifact file: %q", a.Metadata.Name)
+	slog.Info("extracting artifact file", "artifact", a.Metadata.Name)
 	return dest, extractStagedArtifact(ctx, a, dest)
 }
 
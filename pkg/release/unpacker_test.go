@@ -0,0 +1,393 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	releasetar "github.com/cert-manager/release/pkg/release/tar"
+)
+
+// serverTarball returns a gzipped tar archive containing the given files.
+// Names ending in "/" are added as directories.
+func serverTarball(t *testing.T, names ...string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+
+	for _, name := range names {
+		if strings.HasSuffix(name, "/") {
+			if err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeDir, Mode: 0755}); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		contents := []byte("contents of " + name)
+		if err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(contents))}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := tw.Write(contents); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestValidateServerArtifactLayout(t *testing.T) {
+	tests := map[string]struct {
+		files     []string
+		expectErr string
+	}{
+		"correctly structured": {
+			files: []string{"LICENSES", "version", "server/", "server/images/", "server/images/controller.tar", "server/images/controller.docker_tag", "server/images/webhook.tar"},
+		},
+		"correctly structured inside a top-level directory": {
+			files: []string{"cert-manager-server-linux-amd64/", "cert-manager-server-linux-amd64/LICENSES", "cert-manager-server-linux-amd64/version", "cert-manager-server-linux-amd64/server/", "cert-manager-server-linux-amd64/server/images/", "cert-manager-server-linux-amd64/server/images/controller.tar"},
+		},
+		"missing LICENSES is allowed": {
+			files: []string{"version", "server/", "server/images/", "server/images/controller.tar"},
+		},
+		"missing server/images": {
+			files:     []string{"LICENSES", "version", "server/", "server/controller.tar"},
+			expectErr: `missing "server/images" directory`,
+		},
+		"images moved to the root": {
+			files:     []string{"LICENSES", "version", "images/", "images/controller.tar"},
+			expectErr: `missing "server/images" directory`,
+		},
+		"no image tars": {
+			files:     []string{"LICENSES", "version", "server/", "server/images/", "server/images/controller.docker_tag"},
+			expectErr: `contains no image tars`,
+		},
+		"missing version": {
+			files:     []string{"LICENSES", "server/", "server/images/", "server/images/controller.tar"},
+			expectErr: `missing "version" file`,
+		},
+		"version is a directory": {
+			files:     []string{"LICENSES", "version/", "server/", "server/images/", "server/images/controller.tar"},
+			expectErr: `expected "version" to be a regular file`,
+		},
+		"empty archive": {
+			expectErr: `missing "server/images" directory`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := releasetar.UntarGz(dir, bytes.NewReader(serverTarball(t, test.files...))); err != nil {
+				t.Fatal(err)
+			}
+
+			err := validateServerArtifactLayout(dir)
+			if (err != nil) != (test.expectErr != "") {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr != "", err)
+			}
+
+			if err != nil && !strings.Contains(err.Error(), test.expectErr) {
+				t.Errorf("expected error to contain %q but got: %v", test.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestImageComponentName(t *testing.T) {
+	tests := map[string]struct {
+		path       string
+		trimPrefix string
+		trimSuffix string
+		expected   string
+		expectErr  bool
+	}{
+		"default convention": {
+			path:     "/tmp/extracted/server/images/controller.tar",
+			expected: "controller",
+		},
+		"default convention with a multi-word component": {
+			path:     "server/images/startupapicheck.tar",
+			expected: "startupapicheck",
+		},
+		"prefixed convention": {
+			path:       "server/images/cert-manager-controller.tar",
+			trimPrefix: "cert-manager-",
+			expected:   "controller",
+		},
+		"suffixed convention": {
+			path:       "server/images/webhook-linux-amd64.tar",
+			trimSuffix: "-linux-amd64",
+			expected:   "webhook",
+		},
+		"prefixed and suffixed convention": {
+			path:       "server/images/cert-manager-cainjector-image.tar",
+			trimPrefix: "cert-manager-",
+			trimSuffix: "-image",
+			expected:   "cainjector",
+		},
+		"prefixed file with the default convention": {
+			path:      "server/images/cert-manager-controller.tar",
+			expectErr: true,
+		},
+		"unknown component": {
+			path:      "server/images/not-a-component.tar",
+			expectErr: true,
+		},
+		"client binary component is not an image": {
+			path:      "server/images/cmctl.tar",
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			componentName, err := imageComponentName(test.path, test.trimPrefix, test.trimSuffix)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if componentName != test.expected {
+				t.Errorf("expected component name %q but got %q", test.expected, componentName)
+			}
+		})
+	}
+}
+
+func TestForEachArtifact(t *testing.T) {
+	tests := map[string]struct {
+		parallelism int
+		failIndex   int
+		expectErr   bool
+	}{
+		"sequential": {
+			parallelism: 1,
+			failIndex:   -1,
+		},
+		"parallelism less than 1 is treated as sequential": {
+			parallelism: 0,
+			failIndex:   -1,
+		},
+		"parallel": {
+			parallelism: 3,
+			failIndex:   -1,
+		},
+		"parallelism greater than the number of artifacts": {
+			parallelism: 100,
+			failIndex:   -1,
+		},
+		"failing artifact": {
+			parallelism: 3,
+			failIndex:   4,
+			expectErr:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			artifacts := make([]StagedArtifact, 10)
+			for i := range artifacts {
+				artifacts[i].Metadata.Name = fmt.Sprintf("artifact-%d.tar.gz", i)
+			}
+
+			expectedLimit := max(test.parallelism, 1)
+
+			var running, maxRunning atomic.Int64
+			results := make([]string, len(artifacts))
+
+			err := forEachArtifact(context.Background(), artifacts, test.parallelism, func(ctx context.Context, i int, a *StagedArtifact) error {
+				n := running.Add(1)
+				defer running.Add(-1)
+
+				for {
+					m := maxRunning.Load()
+					if n <= m || maxRunning.CompareAndSwap(m, n) {
+						break
+					}
+				}
+
+				// give other calls a chance to start, so that exceeding the
+				// limit would be noticed
+				time.Sleep(5 * time.Millisecond)
+
+				if i == test.failIndex {
+					return errors.New("failed")
+				}
+
+				results[i] = a.Metadata.Name
+				return nil
+			})
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if maxRunning.Load() > int64(expectedLimit) {
+				t.Errorf("expected at most %d concurrent calls but saw %d", expectedLimit, maxRunning.Load())
+			}
+
+			if test.expectErr {
+				return
+			}
+
+			for i, result := range results {
+				if result != artifacts[i].Metadata.Name {
+					t.Errorf("expected result %d to be %q but got %q", i, artifacts[i].Metadata.Name, result)
+				}
+			}
+		})
+	}
+}
+
+func TestDownloadArtifact(t *testing.T) {
+	tests := map[string]struct {
+		corrupt   bool
+		expectErr bool
+	}{
+		"matching checksum": {},
+		"mismatching checksum": {
+			corrupt:   true,
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			objects := stagedReleaseObjects(t, "test-bucket", "stage/gcb/release/v1.2.3-abcdef", MetadataFileName, Metadata{
+				ReleaseVersion: "v1.2.3",
+				GitCommitRef:   "abcdef",
+			})
+
+			_, client := newFakeGCS(t, objects)
+
+			staged, err := NewBucket(client.Bucket("test-bucket"), "stage/gcb", BuildTypeRelease).GetRelease(ctx, "v1.2.3-abcdef")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			artifacts := staged.Artifacts()
+			if len(artifacts) != 1 {
+				t.Fatalf("expected 1 artifact but got %d", len(artifacts))
+			}
+
+			if test.corrupt {
+				artifacts[0].Metadata.SHA256 = "0000"
+			}
+
+			path := filepath.Join(t.TempDir(), artifacts[0].Metadata.Name)
+
+			err = DownloadArtifact(ctx, &artifacts[0], path)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			data, readErr := os.ReadFile(path)
+			if test.expectErr {
+				if !os.IsNotExist(readErr) {
+					t.Errorf("expected file to be removed after a failed download, got: %v", readErr)
+				}
+				return
+			}
+
+			if readErr != nil {
+				t.Fatal(readErr)
+			}
+
+			if string(data) != "manifests" {
+				t.Errorf("unexpected downloaded content %q", data)
+			}
+		})
+	}
+}
+
+func TestUnpackCtlFromBazelRelease(t *testing.T) {
+	ctx := context.Background()
+
+	// Bazel ctl artifacts embed a .tar.gz archive for every OS, including
+	// windows
+	artifacts := map[string]ArtifactMetadata{
+		"cmctl-linux-amd64.tar.gz":   {Name: "cert-manager-cmctl-linux-amd64.tar.gz", OS: "linux", Architecture: "amd64"},
+		"cmctl-windows-amd64.tar.gz": {Name: "cert-manager-cmctl-windows-amd64.tar.gz", OS: "windows", Architecture: "amd64"},
+	}
+
+	objects := map[string][]byte{}
+	meta := Metadata{
+		ReleaseVersion: "v1.14.0",
+		GitCommitRef:   "abcdef",
+		BuildSource:    BuildSourceBazel,
+	}
+
+	for _, embedded := range []string{"cmctl-linux-amd64.tar.gz", "cmctl-windows-amd64.tar.gz"} {
+		a := artifacts[embedded]
+		data := serverTarball(t, embedded, "version")
+		sum := sha256.Sum256(data)
+		a.SHA256 = hex.EncodeToString(sum[:])
+		meta.Artifacts = append(meta.Artifacts, a)
+		objects["test-bucket/stage/gcb/release/v1.14.0-abcdef/"+a.Name] = data
+	}
+
+	for name, data := range stagedReleaseObjects(t, "test-bucket", "stage/gcb/release/v1.14.0-abcdef", MetadataFileName, meta) {
+		objects[name] = data
+	}
+
+	_, client := newFakeGCS(t, objects)
+
+	staged, err := NewBucket(client.Bucket("test-bucket"), "stage/gcb", BuildTypeRelease).GetRelease(ctx, "v1.14.0-abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archives, err := unpackCtlFromRelease(ctx, staged, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, archive := range archives {
+		names = append(names, archive.ArtifactFilename())
+	}
+
+	expNames := []string{"cmctl-linux-amd64.tar.gz", "cmctl-windows-amd64.tar.gz"}
+	if !reflect.DeepEqual(names, expNames) {
+		t.Errorf("unexpected ctl archives: got=%q, exp=%q", names, expNames)
+	}
+}
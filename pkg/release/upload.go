@@ -0,0 +1,90 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+
+	"cloud.google.com/go/storage"
+
+	"github.com/cert-manager/release/pkg/retry"
+)
+
+// UploadFile uploads the file at path to obj, retrying failed uploads
+// according to retryOpts. The CRC32C and MD5 of the file are sent with the
+// upload so that GCS rejects content which was corrupted in transit, and are
+// compared with those GCS reports for the written object so that a truncated
+// upload is detected immediately rather than when the object is next read.
+func UploadFile(ctx context.Context, obj *storage.ObjectHandle, path string, retryOpts retry.Options) error {
+	crc, md5Sum, err := fileChecksums(path)
+	if err != nil {
+		return fmt.Errorf("failed to compute checksums of %q: %w", path, err)
+	}
+
+	return retry.DoWithOptions(ctx, retryOpts, func() error {
+		f, err := os.Open(path)
+		if err != nil {
+			return retry.Permanent(err)
+		}
+		defer f.Close()
+
+		w := obj.NewWriter(ctx)
+		w.CRC32C = crc
+		w.SendCRC32C = true
+		w.MD5 = md5Sum
+
+		if _, err := io.Copy(w, f); err != nil {
+			w.Close()
+			return fmt.Errorf("failed to upload %q: %w", path, err)
+		}
+
+		if err := w.Close(); err != nil {
+			return fmt.Errorf("failed to upload %q: %w", path, err)
+		}
+
+		attrs := w.Attrs()
+		if attrs.CRC32C != crc || !bytes.Equal(attrs.MD5, md5Sum) {
+			return fmt.Errorf("uploaded object %q has CRC32C %d and MD5 %x, but %q has CRC32C %d and MD5 %x", obj.ObjectName(), attrs.CRC32C, attrs.MD5, path, crc, md5Sum)
+		}
+
+		return nil
+	})
+}
+
+// fileChecksums returns the CRC32C (Castagnoli) checksum and MD5 hash of the
+// file at path, as used by GCS to verify object integrity.
+func fileChecksums(path string) (uint32, []byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+
+	crcHasher := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	md5Hasher := md5.New()
+	if _, err := io.Copy(io.MultiWriter(crcHasher, md5Hasher), f); err != nil {
+		return 0, nil, err
+	}
+
+	return crcHasher.Sum32(), md5Hasher.Sum(nil), nil
+}
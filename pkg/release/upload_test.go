@@ -0,0 +1,81 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/release/pkg/retry"
+)
+
+func TestUploadFile(t *testing.T) {
+	tests := map[string]struct {
+		truncatedUploads int
+		expectErr        bool
+	}{
+		"successful upload": {},
+		"truncated upload is retried": {
+			truncatedUploads: 2,
+		},
+		"upload which is always truncated fails": {
+			truncatedUploads: 3,
+			expectErr:        true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			path := filepath.Join(t.TempDir(), "artifact.tar.gz")
+			if err := os.WriteFile(path, []byte("artifact content"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			fake, client := newFakeGCS(t, nil)
+			fake.TruncateUploads(test.truncatedUploads)
+
+			retryOpts := retry.Options{
+				InitialInterval: time.Millisecond,
+				MaxInterval:     time.Millisecond,
+				MaxTries:        3,
+			}
+
+			err := UploadFile(ctx, client.Bucket("test-bucket").Object("stage/artifact.tar.gz"), path, retryOpts)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if test.expectErr {
+				return
+			}
+
+			data, ok := fake.Object("test-bucket/stage/artifact.tar.gz")
+			if !ok {
+				t.Fatalf("expected object to be uploaded")
+			}
+
+			if string(data) != "artifact content" {
+				t.Errorf("unexpected uploaded content %q", data)
+			}
+		})
+	}
+}
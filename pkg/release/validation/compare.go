@@ -0,0 +1,189 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/blang/semver"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/cert-manager/release/pkg/release"
+)
+
+// CompareToPrevious compares a release against the release which preceded it
+// in order to catch accidental regressions, such as components or
+// architectures silently being dropped from a release.
+// Violations are anomalies which should block publishing the release, while
+// warnings are anomalies which should be reviewed but which could be expected,
+// such as a new component being added.
+func CompareToPrevious(current *release.Unpacked, previous *release.Unpacked) (violations []string, warnings []string) {
+	if err := compareVersions(current.ReleaseVersion, previous.ReleaseVersion); err != nil {
+		violations = append(violations, err.Error())
+	}
+
+	currentComponents := sets.StringKeySet(current.ComponentImageBundles)
+	previousComponents := sets.StringKeySet(previous.ComponentImageBundles)
+
+	for _, name := range previousComponents.Difference(currentComponents).List() {
+		violations = append(violations, fmt.Sprintf("Component %q was present in previous release %q but is missing", name, previous.ReleaseVersion))
+	}
+
+	for _, name := range currentComponents.Difference(previousComponents).List() {
+		warnings = append(warnings, fmt.Sprintf("Component %q is new since previous release %q", name, previous.ReleaseVersion))
+	}
+
+	for _, name := range currentComponents.Intersection(previousComponents).List() {
+		currentPlatforms := sets.NewString()
+		for _, tar := range current.ComponentImageBundles[name] {
+			currentPlatforms.Insert(tar.OS() + "/" + tar.Architecture())
+		}
+
+		previousPlatforms := sets.NewString()
+		for _, tar := range previous.ComponentImageBundles[name] {
+			previousPlatforms.Insert(tar.OS() + "/" + tar.Architecture())
+		}
+
+		for _, platform := range previousPlatforms.Difference(currentPlatforms).List() {
+			violations = append(violations, fmt.Sprintf("Component %q was built for %s in previous release %q but is missing for that platform", name, platform, previous.ReleaseVersion))
+		}
+	}
+
+	currentCtlBinaries := sets.NewString()
+	for _, archive := range current.CtlBinaryBundles {
+		currentCtlBinaries.Insert(strings.Join([]string{archive.Name(), archive.OS(), archive.Architecture()}, "/"))
+	}
+
+	previousCtlBinaries := sets.NewString()
+	for _, archive := range previous.CtlBinaryBundles {
+		previousCtlBinaries.Insert(strings.Join([]string{archive.Name(), archive.OS(), archive.Architecture()}, "/"))
+	}
+
+	for _, binary := range previousCtlBinaries.Difference(currentCtlBinaries).List() {
+		if release.CmctlIsShipped(current.ReleaseVersion) {
+			violations = append(violations, fmt.Sprintf("Binary %s was present in previous release %q but is missing", binary, previous.ReleaseVersion))
+		} else {
+			warnings = append(warnings, fmt.Sprintf("Binary %s was present in previous release %q but is no longer shipped", binary, previous.ReleaseVersion))
+		}
+	}
+
+	if len(current.YAMLs) < len(previous.YAMLs) {
+		warnings = append(warnings, fmt.Sprintf("Release contains %d static manifests, fewer than the %d in previous release %q", len(current.YAMLs), len(previous.YAMLs), previous.ReleaseVersion))
+	}
+
+	if len(current.Charts) < len(previous.Charts) {
+		violations = append(violations, fmt.Sprintf("Release contains %d Helm charts, fewer than the %d in previous release %q", len(current.Charts), len(previous.Charts), previous.ReleaseVersion))
+	}
+
+	return violations, warnings
+}
+
+// CompareCharts compares the Helm charts in two separate builds of the same
+// release version and git ref. Since charts are signed, a chart should be
+// packaged identically by every build; a violation is returned for each chart
+// whose content differs between the builds or which is missing from either.
+func CompareCharts(current *release.Unpacked, other *release.Unpacked) ([]string, error) {
+	// devel builds don't record an explicit release version, in which case
+	// the version is instead compared via the charts' package file names
+	versionsDiffer := current.ReleaseVersion != "" && other.ReleaseVersion != "" && current.ReleaseVersion != other.ReleaseVersion
+	if versionsDiffer || current.GitCommitRef != other.GitCommitRef {
+		return nil, fmt.Errorf("cannot compare charts of release %q at %q with release %q at %q: builds must be of the same version and git ref", current.ReleaseVersion, current.GitCommitRef, other.ReleaseVersion, other.GitCommitRef)
+	}
+
+	currentHashes, err := chartHashes(current)
+	if err != nil {
+		return nil, err
+	}
+
+	otherHashes, err := chartHashes(other)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []string
+	for _, name := range sets.StringKeySet(currentHashes).Union(sets.StringKeySet(otherHashes)).List() {
+		currentHash, inCurrent := currentHashes[name]
+		otherHash, inOther := otherHashes[name]
+
+		switch {
+		case !inOther:
+			violations = append(violations, fmt.Sprintf("Helm chart %q is missing from build %q", name, other.ReleaseName))
+		case !inCurrent:
+			violations = append(violations, fmt.Sprintf("Helm chart %q is missing from build %q", name, current.ReleaseName))
+		case currentHash != otherHash:
+			violations = append(violations, fmt.Sprintf("Helm chart %q has sha256 %s in build %q but %s in build %q; chart packaging is not reproducible", name, currentHash, current.ReleaseName, otherHash, other.ReleaseName))
+		}
+	}
+
+	return violations, nil
+}
+
+// chartHashes returns the sha256 sum of each chart in the release, keyed by
+// the chart's package file name.
+func chartHashes(rel *release.Unpacked) (map[string]string, error) {
+	hashes := map[string]string{}
+	for _, chart := range rel.Charts {
+		sum, err := sha256SumFile(chart.Path())
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash Helm chart %q: %w", chart.Path(), err)
+		}
+
+		hashes[chart.PackageFileName()] = sum
+	}
+
+	return hashes, nil
+}
+
+func sha256SumFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// compareVersions returns an error if current is not a greater semver version
+// than previous.
+func compareVersions(current string, previous string) error {
+	currentVersion, err := semver.Parse(strings.TrimPrefix(current, "v"))
+	if err != nil {
+		return fmt.Errorf("Release version %q is not semver compliant: %v", current, err)
+	}
+
+	previousVersion, err := semver.Parse(strings.TrimPrefix(previous, "v"))
+	if err != nil {
+		return fmt.Errorf("Previous release version %q is not semver compliant: %v", previous, err)
+	}
+
+	if !currentVersion.GT(previousVersion) {
+		return fmt.Errorf("Release version %q is not greater than previous release version %q", current, previous)
+	}
+
+	return nil
+}
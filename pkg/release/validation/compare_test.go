@@ -0,0 +1,310 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cert-manager/release/pkg/release"
+	"github.com/cert-manager/release/pkg/release/binaries"
+	"github.com/cert-manager/release/pkg/release/images"
+	"github.com/cert-manager/release/pkg/release/manifests"
+)
+
+// writeImageTar writes a minimal docker image tarball containing only a
+// manifest.json file, and returns an images.Tar which refers to it.
+func writeImageTar(t *testing.T, imageName, osStr, arch string) *images.Tar {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "image.tar")
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	manifest := []byte(fmt.Sprintf(`[{"RepoTags":[%q]}]`, imageName))
+
+	tw := tar.NewWriter(f)
+	if err := tw.WriteHeader(&tar.Header{Name: "manifest.json", Mode: 0644, Size: int64(len(manifest))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	imageTar, err := images.NewTar(path, osStr, arch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return imageTar
+}
+
+// writeChart writes a minimal packaged Helm chart with the given version,
+// containing a Chart.yaml and a values.yaml with the given content.
+func writeChart(t *testing.T, version, values string) manifests.Chart {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "cert-manager-"+version+".tgz")
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	files := []struct {
+		name    string
+		content []byte
+	}{
+		{"cert-manager/Chart.yaml", []byte(fmt.Sprintf("name: cert-manager\nversion: %s\nappVersion: %s\n", version, version))},
+		{"cert-manager/values.yaml", []byte(values)},
+	}
+
+	gzw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gzw)
+	for _, file := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: file.name, Mode: 0644, Size: int64(len(file.content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(file.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	chart, err := manifests.NewChart(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return *chart
+}
+
+func TestCompareToPrevious(t *testing.T) {
+	// fixture returns a release with the given version, containing the named
+	// components each built for the given linux architectures.
+	fixture := func(version string, arches []string, components ...string) *release.Unpacked {
+		rel := &release.Unpacked{
+			ReleaseVersion:        version,
+			Charts:                []manifests.Chart{{}},
+			ComponentImageBundles: map[string][]*images.Tar{},
+		}
+
+		for _, component := range components {
+			for _, arch := range arches {
+				rel.ComponentImageBundles[component] = append(rel.ComponentImageBundles[component], writeImageTar(t, "quay.io/jetstack/cert-manager-"+component+"-"+arch+":"+version, "linux", arch))
+			}
+		}
+
+		return rel
+	}
+
+	tests := map[string]struct {
+		current  *release.Unpacked
+		previous *release.Unpacked
+
+		expViolations []string
+		expWarnings   []string
+	}{
+		"identical components and arches with a greater version": {
+			current:  fixture("v1.1.0", []string{"amd64", "arm64"}, "controller", "webhook"),
+			previous: fixture("v1.0.0", []string{"amd64", "arm64"}, "controller", "webhook"),
+		},
+		"greater prerelease version": {
+			current:  fixture("v1.1.0", []string{"amd64"}, "controller"),
+			previous: fixture("v1.1.0-beta.0", []string{"amd64"}, "controller"),
+		},
+		"version not incremented": {
+			current:       fixture("v1.0.0", []string{"amd64"}, "controller"),
+			previous:      fixture("v1.0.0", []string{"amd64"}, "controller"),
+			expViolations: []string{`Release version "v1.0.0" is not greater than previous release version "v1.0.0"`},
+		},
+		"version decreased": {
+			current:       fixture("v1.0.0", []string{"amd64"}, "controller"),
+			previous:      fixture("v1.1.0", []string{"amd64"}, "controller"),
+			expViolations: []string{`Release version "v1.0.0" is not greater than previous release version "v1.1.0"`},
+		},
+		"previous version is not semver": {
+			current:       fixture("v1.0.0", []string{"amd64"}, "controller"),
+			previous:      fixture("v1.0", []string{"amd64"}, "controller"),
+			expViolations: []string{`Previous release version "v1.0" is not semver compliant: No Major.Minor.Patch elements found`},
+		},
+		"component removed": {
+			current:       fixture("v1.1.0", []string{"amd64"}, "controller"),
+			previous:      fixture("v1.0.0", []string{"amd64"}, "controller", "webhook"),
+			expViolations: []string{`Component "webhook" was present in previous release "v1.0.0" but is missing`},
+		},
+		"component added": {
+			current:     fixture("v1.1.0", []string{"amd64"}, "controller", "webhook"),
+			previous:    fixture("v1.0.0", []string{"amd64"}, "controller"),
+			expWarnings: []string{`Component "webhook" is new since previous release "v1.0.0"`},
+		},
+		"arch removed": {
+			current:       fixture("v1.1.0", []string{"amd64"}, "controller"),
+			previous:      fixture("v1.0.0", []string{"amd64", "arm64"}, "controller"),
+			expViolations: []string{`Component "controller" was built for linux/arm64 in previous release "v1.0.0" but is missing for that platform`},
+		},
+		"arch added": {
+			current:  fixture("v1.1.0", []string{"amd64", "arm64"}, "controller"),
+			previous: fixture("v1.0.0", []string{"amd64"}, "controller"),
+		},
+		"chart removed": {
+			current: func() *release.Unpacked {
+				rel := fixture("v1.1.0", []string{"amd64"}, "controller")
+				rel.Charts = nil
+				return rel
+			}(),
+			previous:      fixture("v1.0.0", []string{"amd64"}, "controller"),
+			expViolations: []string{`Release contains 0 Helm charts, fewer than the 1 in previous release "v1.0.0"`},
+		},
+		"static manifests removed": {
+			current: fixture("v1.1.0", []string{"amd64"}, "controller"),
+			previous: func() *release.Unpacked {
+				rel := fixture("v1.0.0", []string{"amd64"}, "controller")
+				rel.YAMLs = []manifests.YAML{{}}
+				return rel
+			}(),
+			expWarnings: []string{`Release contains 0 static manifests, fewer than the 1 in previous release "v1.0.0"`},
+		},
+		"ctl binary removed while still shipped": {
+			current: fixture("v1.14.1", []string{"amd64"}, "controller"),
+			previous: func() *release.Unpacked {
+				rel := fixture("v1.14.0", []string{"amd64"}, "controller")
+				rel.CtlBinaryBundles = []binaries.Archive{*binaries.NewArchive("cmctl", "", "linux", "amd64", release.ArchiveFormatForOS("linux"))}
+				return rel
+			}(),
+			expViolations: []string{`Binary cmctl/linux/amd64 was present in previous release "v1.14.0" but is missing`},
+		},
+		"ctl binary removed once no longer shipped": {
+			current: fixture("v1.15.0", []string{"amd64"}, "controller"),
+			previous: func() *release.Unpacked {
+				rel := fixture("v1.14.0", []string{"amd64"}, "controller")
+				rel.CtlBinaryBundles = []binaries.Archive{*binaries.NewArchive("cmctl", "", "linux", "amd64", release.ArchiveFormatForOS("linux"))}
+				return rel
+			}(),
+			expWarnings: []string{`Binary cmctl/linux/amd64 was present in previous release "v1.14.0" but is no longer shipped`},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			violations, warnings := CompareToPrevious(test.current, test.previous)
+			if !reflect.DeepEqual(violations, test.expViolations) {
+				t.Errorf("unexpected violations: got=%v, exp=%v", violations, test.expViolations)
+			}
+			if !reflect.DeepEqual(warnings, test.expWarnings) {
+				t.Errorf("unexpected warnings: got=%v, exp=%v", warnings, test.expWarnings)
+			}
+		})
+	}
+}
+
+func TestCompareCharts(t *testing.T) {
+	build := func(name, version, gitRef string, charts ...manifests.Chart) *release.Unpacked {
+		return &release.Unpacked{
+			ReleaseName:    name,
+			ReleaseVersion: version,
+			GitCommitRef:   gitRef,
+			Charts:         charts,
+		}
+	}
+
+	tests := map[string]struct {
+		current *release.Unpacked
+		other   *release.Unpacked
+
+		expViolations []string
+		expectErr     bool
+	}{
+		"identical charts": {
+			current: build("first", "v1.1.0", "abcdef", writeChart(t, "v1.1.0", "replicaCount: 1\n")),
+			other:   build("second", "v1.1.0", "abcdef", writeChart(t, "v1.1.0", "replicaCount: 1\n")),
+		},
+		"differing chart contents": {
+			current: build("first", "v1.1.0", "abcdef", writeChart(t, "v1.1.0", "replicaCount: 1\n")),
+			other:   build("second", "v1.1.0", "abcdef", writeChart(t, "v1.1.0", "replicaCount: 2\n")),
+			expViolations: []string{
+				`Helm chart "cert-manager-v1.1.0.tgz" has sha256 <current> in build "first" but <other> in build "second"; chart packaging is not reproducible`,
+			},
+		},
+		"chart missing from other build": {
+			current:       build("first", "v1.1.0", "abcdef", writeChart(t, "v1.1.0", "replicaCount: 1\n")),
+			other:         build("second", "v1.1.0", "abcdef"),
+			expViolations: []string{`Helm chart "cert-manager-v1.1.0.tgz" is missing from build "second"`},
+		},
+		"devel build without a release version": {
+			current: build("first", "v1.1.0", "abcdef", writeChart(t, "v1.1.0", "replicaCount: 1\n")),
+			other:   build("abcdef", "", "abcdef", writeChart(t, "v1.1.0", "replicaCount: 1\n")),
+		},
+		"different versions": {
+			current:   build("first", "v1.1.0", "abcdef", writeChart(t, "v1.1.0", "replicaCount: 1\n")),
+			other:     build("second", "v1.0.0", "abcdef", writeChart(t, "v1.0.0", "replicaCount: 1\n")),
+			expectErr: true,
+		},
+		"different git refs": {
+			current:   build("first", "v1.1.0", "abcdef", writeChart(t, "v1.1.0", "replicaCount: 1\n")),
+			other:     build("second", "v1.1.0", "123456", writeChart(t, "v1.1.0", "replicaCount: 1\n")),
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			violations, err := CompareCharts(test.current, test.other)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			// fill in the hashes of the fixture charts, which aren't known
+			// until they're written
+			var expViolations []string
+			for _, v := range test.expViolations {
+				if len(test.current.Charts) > 0 {
+					sum, _ := sha256SumFile(test.current.Charts[0].Path())
+					v = strings.Replace(v, "<current>", sum, 1)
+				}
+				if len(test.other.Charts) > 0 {
+					sum, _ := sha256SumFile(test.other.Charts[0].Path())
+					v = strings.Replace(v, "<other>", sum, 1)
+				}
+				expViolations = append(expViolations, v)
+			}
+
+			if !reflect.DeepEqual(violations, expViolations) {
+				t.Errorf("unexpected violations:\ngot=%q\nexp=%q", violations, expViolations)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/cert-manager/release/pkg/release"
+)
+
+// manifestImageRegexps match image references in static manifests, either
+// as a container's image or as a flag passed to a component, such as
+// --acme-http01-solver-image.
+var manifestImageRegexps = []*regexp.Regexp{
+	regexp.MustCompile(`(?m)^\s*(?:-\s+)?image:\s*["']?([^"'\s]+)["']?\s*$`),
+	regexp.MustCompile(`-image=["']?([^"'\s,]+)`),
+}
+
+// validateImageRepositories checks that every image referenced by the static
+// manifests and by the default values of the Helm charts in the release is
+// in the expected image repository. Otherwise, images would be pushed to one
+// repository while installs try to pull them from another.
+// If opts.ImageRepository is empty, no checks are performed.
+func validateImageRepositories(opts Options, rel *release.Unpacked) ([]string, error) {
+	if opts.ImageRepository == "" {
+		return nil, nil
+	}
+
+	var violations []string
+	for _, y := range rel.YAMLs {
+		data, err := os.ReadFile(y.Path())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read static manifest %q: %w", y.Path(), err)
+		}
+
+		for _, image := range manifestImages(data) {
+			if !inImageRepository(image, opts.ImageRepository) {
+				violations = append(violations, fmt.Sprintf("Static manifest %q references image %q which is not in image repository %q", y.Variant(), image, opts.ImageRepository))
+			}
+		}
+	}
+
+	for _, ch := range rel.Charts {
+		data, err := ch.Values()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read values of Helm chart %q: %w", ch.Path(), err)
+		}
+
+		values := map[string]interface{}{}
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("failed to decode values of Helm chart %q: %w", ch.Path(), err)
+		}
+
+		repos := chartImageRepositories("", values)
+
+		for _, path := range sets.StringKeySet(repos).List() {
+			if !inImageRepository(repos[path], opts.ImageRepository) {
+				violations = append(violations, fmt.Sprintf("Helm chart %q defaults %q to %q which is not in image repository %q", ch.PackageFileName(), path, repos[path], opts.ImageRepository))
+			}
+		}
+	}
+
+	return violations, nil
+}
+
+// manifestImages returns the unique image references found in a static
+// manifest, in the order they first appear.
+func manifestImages(data []byte) []string {
+	type match struct {
+		offset int
+		image  string
+	}
+
+	var matches []match
+	for _, re := range manifestImageRegexps {
+		for _, m := range re.FindAllSubmatchIndex(data, -1) {
+			matches = append(matches, match{offset: m[2], image: string(data[m[2]:m[3]])})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].offset < matches[j].offset
+	})
+
+	seen := map[string]bool{}
+	var images []string
+	for _, m := range matches {
+		if seen[m.image] {
+			continue
+		}
+
+		seen[m.image] = true
+		images = append(images, m.image)
+	}
+
+	return images
+}
+
+// chartImageRepositories walks Helm chart values, returning every non-empty
+// "image.repository" value keyed by its dotted path in the values.
+func chartImageRepositories(prefix string, values map[string]interface{}) map[string]string {
+	repos := map[string]string{}
+	for key, value := range values {
+		child, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		if key == "image" {
+			if repo, ok := child["repository"].(string); ok && repo != "" {
+				repos[path+".repository"] = repo
+			}
+		}
+
+		for k, v := range chartImageRepositories(path, child) {
+			repos[k] = v
+		}
+	}
+
+	return repos
+}
+
+// inImageRepository returns true if the given image reference, which may
+// include a tag or digest, is in the given image repository.
+func inImageRepository(image, imageRepository string) bool {
+	return strings.HasPrefix(image, strings.TrimSuffix(imageRepository, "/")+"/")
+}
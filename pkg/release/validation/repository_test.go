@@ -0,0 +1,142 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cert-manager/release/pkg/release"
+	"github.com/cert-manager/release/pkg/release/manifests"
+)
+
+const testStaticManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: cert-manager
+spec:
+  template:
+    spec:
+      containers:
+        - name: cert-manager-controller
+          image: "%s/cert-manager-controller:v1.15.0"
+          args:
+          - --v=2
+          - --acme-http01-solver-image=%s/cert-manager-acmesolver:v1.15.0
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: cert-manager-webhook
+spec:
+  template:
+    spec:
+      containers:
+        - name: cert-manager-webhook
+          image: %s/cert-manager-webhook:v1.15.0
+`
+
+const testChartValues = `image:
+  repository: %s/cert-manager-controller
+  pullPolicy: IfNotPresent
+webhook:
+  image:
+    repository: %s/cert-manager-webhook
+acmesolver:
+  image:
+    repository: %s/cert-manager-acmesolver
+cainjector:
+  image:
+    # an empty repository is defaulted by the chart templates
+    repository: ""
+`
+
+func writeStaticManifest(t *testing.T, controllerRepo, acmesolverRepo, webhookRepo string) manifests.YAML {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "cert-manager.yaml")
+	if err := os.WriteFile(path, []byte(fmt.Sprintf(testStaticManifest, controllerRepo, acmesolverRepo, webhookRepo)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return *manifests.NewYAML(path)
+}
+
+func TestValidate_ImageRepositories(t *testing.T) {
+	const repo = "quay.io/jetstack"
+
+	tests := map[string]struct {
+		imageRepository string
+		manifestRepos   [3]string
+		chartRepos      [3]string
+		violations      []string
+	}{
+		"consistent repositories": {
+			imageRepository: repo,
+			manifestRepos:   [3]string{repo, repo, repo},
+			chartRepos:      [3]string{repo, repo, repo},
+		},
+		"trailing slash on image repository is ignored": {
+			imageRepository: repo + "/",
+			manifestRepos:   [3]string{repo, repo, repo},
+			chartRepos:      [3]string{repo, repo, repo},
+		},
+		"no image repository skips validation": {
+			manifestRepos: [3]string{"example.com/other", repo, repo},
+			chartRepos:    [3]string{repo, "example.com/other", repo},
+		},
+		"static manifest references another repository": {
+			imageRepository: repo,
+			manifestRepos:   [3]string{"example.com/other", "example.com/other", repo},
+			chartRepos:      [3]string{repo, repo, repo},
+			violations: []string{
+				`Static manifest "cert-manager" references image "example.com/other/cert-manager-controller:v1.15.0" which is not in image repository "quay.io/jetstack"`,
+				`Static manifest "cert-manager" references image "example.com/other/cert-manager-acmesolver:v1.15.0" which is not in image repository "quay.io/jetstack"`,
+			},
+		},
+		"chart defaults to another repository": {
+			imageRepository: repo,
+			manifestRepos:   [3]string{repo, repo, repo},
+			chartRepos:      [3]string{repo, "example.com/other", "quay.io/jetstack-other"},
+			violations: []string{
+				`Helm chart "cert-manager-v1.15.0.tgz" defaults "acmesolver.image.repository" to "quay.io/jetstack-other/cert-manager-acmesolver" which is not in image repository "quay.io/jetstack"`,
+				`Helm chart "cert-manager-v1.15.0.tgz" defaults "webhook.image.repository" to "example.com/other/cert-manager-webhook" which is not in image repository "quay.io/jetstack"`,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			rel := &release.Unpacked{
+				ReleaseVersion: "v1.15.0",
+				YAMLs:          []manifests.YAML{writeStaticManifest(t, test.manifestRepos[0], test.manifestRepos[1], test.manifestRepos[2])},
+				Charts:         []manifests.Chart{writeChart(t, "v1.15.0", fmt.Sprintf(testChartValues, test.chartRepos[0], test.chartRepos[1], test.chartRepos[2]))},
+			}
+
+			v, err := validateImageRepositories(Options{ImageRepository: test.imageRepository}, rel)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(v, test.violations) {
+				t.Errorf("unexpected violations: got=%v, exp=%v", v, test.violations)
+			}
+		})
+	}
+}
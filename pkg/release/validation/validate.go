@@ -21,9 +21,11 @@ import (
 	"strings"
 
 	"github.com/blang/semver"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/cert-manager/release/pkg/release"
 	"github.com/cert-manager/release/pkg/release/images"
+	"github.com/cert-manager/release/pkg/release/manifests"
 )
 
 type Options struct {
@@ -34,6 +36,22 @@ type Options struct {
 	// ImageRepository is used to ensure that the artifacts in a staged release
 	// all use the specified image repository prefix.
 	ImageRepository string
+
+	// ComponentArchitectures maps image component names to the architectures
+	// that images for the component are expected to be built for. Components
+	// which are not listed are expected to be built for every server
+	// architecture.
+	ComponentArchitectures map[string][]string
+}
+
+// expectedArchitectures returns the architectures that images for the named
+// component are expected to be built for.
+func (o Options) expectedArchitectures(component string) []string {
+	if arches, ok := o.ComponentArchitectures[component]; ok {
+		return arches
+	}
+
+	return release.ServerPlatforms["linux"]
 }
 
 func ValidateUnpackedRelease(opts Options, rel *release.Unpacked) ([]string, error) {
@@ -42,12 +60,27 @@ func ValidateUnpackedRelease(opts Options, rel *release.Unpacked) ([]string, err
 		violations = append(violations, fmt.Sprintf("Release version %q is not semver compliant: %v", rel.ReleaseVersion, err))
 	}
 	violations = append(violations, validateImageBundles(rel.ComponentImageBundles, opts)...)
-	for _, ch := range rel.Charts {
-		if ch.Version() != opts.ReleaseVersion {
-			violations = append(violations, fmt.Sprintf("Helm chart sets 'version' to %q, expected %q", ch.Version(), opts.ReleaseVersion))
+	violations = append(violations, validateChartVersions(rel.Charts, opts.ReleaseVersion)...)
+
+	repositoryViolations, err := validateImageRepositories(opts, rel)
+	if err != nil {
+		return nil, err
+	}
+	violations = append(violations, repositoryViolations...)
+
+	for _, name := range rel.UnreferencedObjects {
+		violations = append(violations, fmt.Sprintf("Object %q is present in the staged release but not named in its metadata, so it won't be published", name))
+	}
+
+	// Bazel builds package ctl binaries as .tar.gz for every OS, so only
+	// releases built by make are expected to use the archive format for the OS
+	for _, archive := range rel.CtlBinaryBundles {
+		if rel.BuildSource != release.BuildSourceMake {
+			break
 		}
-		if ch.AppVersion() != opts.ReleaseVersion {
-			violations = append(violations, fmt.Sprintf("Helm chart sets 'appVersion' to %q, expected %q", ch.AppVersion(), opts.ReleaseVersion))
+
+		if expected := release.ArchiveFormatForOS(archive.OS()); archive.Extension() != expected {
+			violations = append(violations, fmt.Sprintf("Binary archive %q for os=%s has extension %q, expected %q", archive.Name(), archive.OS(), archive.Extension(), expected))
 		}
 	}
 
@@ -71,13 +104,78 @@ func validateSemver(v string) error {
 func validateImageBundles(bundles map[string][]*images.Tar, opts Options) []string {
 	var violations []string
 
+	knownComponents := sets.NewString(release.ImageComponentNames()...)
+
+	for _, name := range sets.StringKeySet(bundles).List() {
+		if !knownComponents.Has(name) {
+			violations = append(violations, fmt.Sprintf("Found images for unknown component %q", name))
+		}
+	}
+
+	for _, name := range sets.StringKeySet(bundles).List() {
+		if !knownComponents.Has(name) {
+			continue
+		}
+
+		built := sets.NewString()
+		for _, tar := range bundles[name] {
+			built.Insert(tar.Architecture())
+		}
+
+		expected := sets.NewString(opts.expectedArchitectures(name)...)
+
+		if missing := expected.Difference(built); missing.Len() > 0 {
+			violations = append(violations, fmt.Sprintf("Component %q is missing images for architectures %q", name, missing.List()))
+		}
+
+		if unexpected := built.Difference(expected); unexpected.Len() > 0 {
+			violations = append(violations, fmt.Sprintf("Component %q has images for unexpected architectures %q", name, unexpected.List()))
+		}
+	}
+
+	violations = append(violations, validateImageTags(bundles, opts.ReleaseVersion)...)
+	return violations
+}
+
+// ValidateVersion checks that the Helm charts and images in rel were built
+// for the given release version. Since artifacts are never rebuilt after
+// they're staged, this can be used to check that a staged build is able to
+// be published as a release with that version.
+func ValidateVersion(version string, rel *release.Unpacked) []string {
+	var violations []string
+	violations = append(violations, validateImageTags(rel.ComponentImageBundles, version)...)
+	violations = append(violations, validateChartVersions(rel.Charts, version)...)
+	return violations
+}
+
+func validateImageTags(bundles map[string][]*images.Tar, version string) []string {
+	var violations []string
 	for _, tars := range bundles {
 		// TODO: check that every tar in tars has the same OS + arch
 		for _, tar := range tars {
-			if tar.ImageTag() != opts.ReleaseVersion {
-				violations = append(violations, fmt.Sprintf("Image %q does not have expected tag %q", tar.RawImageName(), opts.ReleaseVersion))
+			// an image without an explicit tag would be published as 'latest'
+			if tag := tar.ImageTag(); tag == "" || tag == "latest" {
+				violations = append(violations, fmt.Sprintf("Image %q does not have an explicit version tag", tar.RawImageName()))
+				continue
+			}
+
+			if tar.ImageTag() != version {
+				violations = append(violations, fmt.Sprintf("Image %q does not have expected tag %q", tar.RawImageName(), version))
 			}
 		}
 	}
 	return violations
 }
+
+func validateChartVersions(charts []manifests.Chart, version string) []string {
+	var violations []string
+	for _, ch := range charts {
+		if ch.Version() != version {
+			violations = append(violations, fmt.Sprintf("Helm chart sets 'version' to %q, expected %q", ch.Version(), version))
+		}
+		if ch.AppVersion() != version {
+			violations = append(violations, fmt.Sprintf("Helm chart sets 'appVersion' to %q, expected %q", ch.AppVersion(), version))
+		}
+	}
+	return violations
+}
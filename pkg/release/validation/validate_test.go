@@ -21,6 +21,9 @@ import (
 	"testing"
 
 	"github.com/cert-manager/release/pkg/release"
+	"github.com/cert-manager/release/pkg/release/binaries"
+	"github.com/cert-manager/release/pkg/release/images"
+	"github.com/cert-manager/release/pkg/release/manifests"
 )
 
 func TestValidate_Semver(t *testing.T) {
@@ -67,3 +70,261 @@ func TestValidate_Semver(t *testing.T) {
 		})
 	}
 }
+
+func TestValidate_ImageComponents(t *testing.T) {
+	tests := map[string]struct {
+		components []string
+		violations []string
+	}{
+		"known components": {
+			components: []string{"controller", "webhook", "cainjector"},
+		},
+		"unknown component": {
+			components: []string{"controller", "notacomponent"},
+			violations: []string{`Found images for unknown component "notacomponent"`},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			bundles := map[string][]*images.Tar{}
+			for _, component := range test.components {
+				bundles[component] = []*images.Tar{
+					writeImageTar(t, "quay.io/jetstack/cert-manager-"+component+"-amd64:v1.15.0", "linux", "amd64"),
+				}
+			}
+
+			arches := map[string][]string{}
+			for _, component := range test.components {
+				arches[component] = []string{"amd64"}
+			}
+
+			v, err := ValidateUnpackedRelease(Options{ReleaseVersion: "v1.15.0", ComponentArchitectures: arches}, &release.Unpacked{
+				ReleaseVersion:        "v1.15.0",
+				ComponentImageBundles: bundles,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(v, test.violations) {
+				t.Errorf("unexpected violations: got=%v, exp=%v", v, test.violations)
+			}
+		})
+	}
+}
+
+func TestValidate_CtlArchiveFormats(t *testing.T) {
+	tests := map[string]struct {
+		archive     *binaries.Archive
+		buildSource string
+		violations  []string
+	}{
+		"windows zip": {
+			archive:     binaries.NewArchive("cmctl", "", "windows", "amd64", ".zip"),
+			buildSource: release.BuildSourceMake,
+		},
+		"linux tar.gz": {
+			archive:     binaries.NewArchive("cmctl", "", "linux", "amd64", ".tar.gz"),
+			buildSource: release.BuildSourceMake,
+		},
+		"windows tar.gz": {
+			archive:     binaries.NewArchive("cmctl", "", "windows", "amd64", ".tar.gz"),
+			buildSource: release.BuildSourceMake,
+			violations:  []string{`Binary archive "cmctl" for os=windows has extension ".tar.gz", expected ".zip"`},
+		},
+		"windows tar.gz built by bazel": {
+			archive:     binaries.NewArchive("cmctl", "", "windows", "amd64", ".tar.gz"),
+			buildSource: release.BuildSourceBazel,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			v, err := ValidateUnpackedRelease(Options{ReleaseVersion: "v1.14.0"}, &release.Unpacked{
+				ReleaseVersion:   "v1.14.0",
+				BuildSource:      test.buildSource,
+				CtlBinaryBundles: []binaries.Archive{*test.archive},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(v, test.violations) {
+				t.Errorf("unexpected violations: got=%v, exp=%v", v, test.violations)
+			}
+		})
+	}
+}
+
+func TestValidate_UnreferencedObjects(t *testing.T) {
+	v, err := ValidateUnpackedRelease(Options{ReleaseVersion: "v1.14.0"}, &release.Unpacked{
+		ReleaseVersion:      "v1.14.0",
+		UnreferencedObjects: []string{"build.log"},
+		CtlBinaryBundles:    []binaries.Archive{*binaries.NewArchive("cmctl", "", "linux", "amd64", ".tar.gz")},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{`Object "build.log" is present in the staged release but not named in its metadata, so it won't be published`}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("unexpected violations: got=%v, exp=%v", v, expected)
+	}
+}
+
+func TestValidate_ImageTags(t *testing.T) {
+	tests := map[string]struct {
+		imageName  string
+		violations []string
+	}{
+		"tagged with the release version": {
+			imageName: "quay.io/jetstack/cert-manager-controller-amd64:v1.15.0",
+		},
+		"tagged with a different version": {
+			imageName:  "quay.io/jetstack/cert-manager-controller-amd64:v1.14.0",
+			violations: []string{`Image "quay.io/jetstack/cert-manager-controller-amd64:v1.14.0" does not have expected tag "v1.15.0"`},
+		},
+		"no tag defaults to latest": {
+			imageName:  "quay.io/jetstack/cert-manager-controller-amd64",
+			violations: []string{`Image "quay.io/jetstack/cert-manager-controller-amd64" does not have an explicit version tag`},
+		},
+		"explicitly tagged latest": {
+			imageName:  "quay.io/jetstack/cert-manager-controller-amd64:latest",
+			violations: []string{`Image "quay.io/jetstack/cert-manager-controller-amd64:latest" does not have an explicit version tag`},
+		},
+		"empty tag": {
+			imageName:  "quay.io/jetstack/cert-manager-controller-amd64:",
+			violations: []string{`Image "quay.io/jetstack/cert-manager-controller-amd64:" does not have an explicit version tag`},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts := Options{
+				ReleaseVersion:         "v1.15.0",
+				ComponentArchitectures: map[string][]string{"controller": {"amd64"}},
+			}
+
+			v, err := ValidateUnpackedRelease(opts, &release.Unpacked{
+				ReleaseVersion: "v1.15.0",
+				ComponentImageBundles: map[string][]*images.Tar{
+					"controller": {writeImageTar(t, test.imageName, "linux", "amd64")},
+				},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(v, test.violations) {
+				t.Errorf("unexpected violations: got=%v, exp=%v", v, test.violations)
+			}
+		})
+	}
+}
+
+func TestValidate_ComponentArchitectures(t *testing.T) {
+	allArches := []string{"amd64", "arm", "arm64", "ppc64le", "s390x"}
+
+	tests := map[string]struct {
+		componentArches map[string][]string
+		built           map[string][]string
+		violations      []string
+	}{
+		"all components built for every server architecture by default": {
+			built: map[string][]string{
+				"controller": allArches,
+				"webhook":    allArches,
+			},
+		},
+		"component missing an architecture by default": {
+			built: map[string][]string{
+				"controller": allArches,
+				"webhook":    {"amd64", "arm", "arm64", "ppc64le"},
+			},
+			violations: []string{`Component "webhook" is missing images for architectures ["s390x"]`},
+		},
+		"component which legitimately omits an architecture": {
+			componentArches: map[string][]string{
+				"webhook": {"amd64", "arm", "arm64", "ppc64le"},
+			},
+			built: map[string][]string{
+				"controller": allArches,
+				"webhook":    {"amd64", "arm", "arm64", "ppc64le"},
+			},
+		},
+		"component built for an architecture it shouldn't be": {
+			componentArches: map[string][]string{
+				"webhook": {"amd64", "arm", "arm64", "ppc64le"},
+			},
+			built: map[string][]string{
+				"controller": {"amd64", "arm", "arm64", "ppc64le"},
+				"webhook":    allArches,
+			},
+			violations: []string{
+				`Component "controller" is missing images for architectures ["s390x"]`,
+				`Component "webhook" has images for unexpected architectures ["s390x"]`,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			bundles := map[string][]*images.Tar{}
+			for component, arches := range test.built {
+				for _, arch := range arches {
+					bundles[component] = append(bundles[component], writeImageTar(t, "quay.io/jetstack/cert-manager-"+component+"-"+arch+":v1.15.0", "linux", arch))
+				}
+			}
+
+			opts := Options{
+				ReleaseVersion:         "v1.15.0",
+				ComponentArchitectures: test.componentArches,
+			}
+
+			v, err := ValidateUnpackedRelease(opts, &release.Unpacked{
+				ReleaseVersion:        "v1.15.0",
+				ComponentImageBundles: bundles,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(v, test.violations) {
+				t.Errorf("unexpected violations: got=%v, exp=%v", v, test.violations)
+			}
+		})
+	}
+}
+
+func TestValidateVersion(t *testing.T) {
+	tests := map[string]struct {
+		imageName    string
+		chartVersion string
+		violations   []string
+	}{
+		"built for the release version": {
+			imageName:    "quay.io/jetstack/cert-manager-controller-amd64:v1.15.0",
+			chartVersion: "v1.15.0",
+		},
+		"built for a devel version": {
+			imageName:    "quay.io/jetstack/cert-manager-controller-amd64:v1.15.0-alpha.0-12-g0123456789ab",
+			chartVersion: "v1.15.0-alpha.0-12-g0123456789ab",
+			violations: []string{
+				`Image "quay.io/jetstack/cert-manager-controller-amd64:v1.15.0-alpha.0-12-g0123456789ab" does not have expected tag "v1.15.0"`,
+				`Helm chart sets 'version' to "v1.15.0-alpha.0-12-g0123456789ab", expected "v1.15.0"`,
+				`Helm chart sets 'appVersion' to "v1.15.0-alpha.0-12-g0123456789ab", expected "v1.15.0"`,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			v := ValidateVersion("v1.15.0", &release.Unpacked{
+				Charts: []manifests.Chart{writeChart(t, test.chartVersion, "")},
+				ComponentImageBundles: map[string][]*images.Tar{
+					"controller": {writeImageTar(t, test.imageName, "linux", "amd64")},
+				},
+			})
+			if !reflect.DeepEqual(v, test.violations) {
+				t.Errorf("unexpected violations: got=%v, exp=%v", v, test.violations)
+			}
+		})
+	}
+}
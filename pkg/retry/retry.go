@@ -0,0 +1,116 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package retry retries operations which can fail transiently, such as
+// pushing to an image registry or calling the GitHub API.
+package retry
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+)
+
+const (
+	// InitialInterval is the delay before the first retry. Later retries
+	// back off exponentially from it.
+	InitialInterval = 2 * time.Second
+
+	// MaxInterval is the longest delay between any two attempts.
+	MaxInterval = 30 * time.Second
+
+	// MaxTries is the number of attempts made before giving up.
+	MaxTries = 5
+)
+
+// Options configures how an operation is retried.
+type Options struct {
+	// InitialInterval is the delay before the first retry. Later retries
+	// back off exponentially from it.
+	InitialInterval time.Duration
+
+	// MaxInterval is the longest delay between any two attempts.
+	MaxInterval time.Duration
+
+	// MaxTries is the number of attempts made before giving up.
+	MaxTries uint
+}
+
+// DefaultOptions returns the options used by Do.
+func DefaultOptions() Options {
+	return Options{
+		InitialInterval: InitialInterval,
+		MaxInterval:     MaxInterval,
+		MaxTries:        MaxTries,
+	}
+}
+
+// newBackOff returns the backoff policy used between attempts. It's replaced
+// in tests to avoid waiting.
+var newBackOff = func(opts Options) backoff.BackOff {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = opts.InitialInterval
+	b.MaxInterval = opts.MaxInterval
+	b.Multiplier = 2
+
+	// add jitter so that concurrent retries don't all hit a struggling
+	// service at the same moment
+	b.RandomizationFactor = 0.5
+
+	return b
+}
+
+// Do calls f until it succeeds, up to MaxTries times, waiting with
+// exponential backoff and jitter between attempts. Retrying stops early if
+// ctx is cancelled or f returns an error wrapped with Permanent.
+// The error from the last attempt is returned if every attempt fails.
+func Do(ctx context.Context, f func() error) error {
+	return DoWithOptions(ctx, DefaultOptions(), f)
+}
+
+// DoWithOptions is like Do, but retries according to the given options rather
+// than the defaults.
+func DoWithOptions(ctx context.Context, opts Options, f func() error) error {
+	operation := func() (struct{}, error) {
+		return struct{}{}, f()
+	}
+
+	notify := func(err error, next time.Duration) {
+		slog.Warn("retrying after error", "delay", next.Round(time.Millisecond), "error", err)
+	}
+
+	_, err := backoff.Retry(ctx, operation,
+		backoff.WithBackOff(newBackOff(opts)),
+		backoff.WithMaxTries(opts.MaxTries),
+		backoff.WithNotify(notify),
+	)
+
+	var permanent *backoff.PermanentError
+	if errors.As(err, &permanent) {
+		return permanent.Err
+	}
+
+	return err
+}
+
+// Permanent wraps err to signal to Do that the operation can't succeed if
+// retried, such as when a request is rejected as invalid.
+func Permanent(err error) error {
+	return backoff.Permanent(err)
+}
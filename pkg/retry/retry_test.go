@@ -0,0 +1,121 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cenkalti/backoff/v5"
+)
+
+func TestDo(t *testing.T) {
+	newBackOff = func(Options) backoff.BackOff { return &backoff.ZeroBackOff{} }
+
+	errTransient := errors.New("transient")
+	errInvalid := errors.New("invalid")
+
+	tests := map[string]struct {
+		errs []error
+
+		expectedCalls int
+		expectedErr   error
+	}{
+		"succeeds first time": {
+			expectedCalls: 1,
+		},
+		"succeeds after transient failures": {
+			errs:          []error{errTransient, errTransient},
+			expectedCalls: 3,
+		},
+		"gives up after max tries": {
+			errs:          []error{errTransient, errTransient, errTransient, errTransient, errTransient, errTransient},
+			expectedCalls: MaxTries,
+			expectedErr:   errTransient,
+		},
+		"permanent error isn't retried": {
+			errs:          []error{Permanent(errInvalid)},
+			expectedCalls: 1,
+			expectedErr:   errInvalid,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			calls := 0
+			err := Do(context.Background(), func() error {
+				calls++
+				if calls <= len(test.errs) {
+					return test.errs[calls-1]
+				}
+
+				return nil
+			})
+
+			if err != test.expectedErr {
+				t.Errorf("expected err=%v but got err=%v", test.expectedErr, err)
+			}
+
+			if calls != test.expectedCalls {
+				t.Errorf("expected %d calls but got %d", test.expectedCalls, calls)
+			}
+		})
+	}
+}
+
+func TestDoCancelled(t *testing.T) {
+	newBackOff = func(Options) backoff.BackOff { return &backoff.ZeroBackOff{} }
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := 0
+	err := Do(ctx, func() error {
+		calls++
+		cancel()
+		return errors.New("transient")
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a context cancelled error but got %v", err)
+	}
+
+	if calls != 1 {
+		t.Errorf("expected no retries after cancellation, but got %d calls", calls)
+	}
+}
+
+func TestDoWithOptions(t *testing.T) {
+	newBackOff = func(Options) backoff.BackOff { return &backoff.ZeroBackOff{} }
+
+	opts := DefaultOptions()
+	opts.MaxTries = 2
+
+	calls := 0
+	err := DoWithOptions(context.Background(), opts, func() error {
+		calls++
+		return errors.New("transient")
+	})
+
+	if err == nil {
+		t.Errorf("expected an error after exhausting retries")
+	}
+
+	if calls != 2 {
+		t.Errorf("expected %d calls but got %d", opts.MaxTries, calls)
+	}
+}
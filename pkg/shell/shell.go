@@ -18,20 +18,67 @@ package shell
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
 	"os"
 	"os/exec"
+	"strings"
 )
 
+// maxCapturedOutput is the maximum number of bytes of a command's output which
+// are kept to be included in the error returned if the command fails.
+const maxCapturedOutput = 16 * 1024
+
 // Command runs the given command with the given args
 func Command(ctx context.Context, workDir string, cmd string, args ...string) error {
+	return CommandWithEnv(ctx, workDir, nil, cmd, args...)
+}
+
+// CommandWithEnv runs the given command with the given args and environment.
+// If env is nil, the command inherits the environment of the current process.
+//
+// If debug logging is enabled, the command's output is streamed to stderr.
+// Otherwise the end of its output is captured and included in the returned
+// error if the command fails.
+func CommandWithEnv(ctx context.Context, workDir string, env []string, cmd string, args ...string) error {
 	c := exec.CommandContext(ctx, cmd, args...)
+	c.Env = env
+	c.Dir = workDir
 
-	// redirect all output
-	// TODO: honour --debug flag
-	c.Stdout = os.Stdout
-	c.Stderr = os.Stderr
+	slog.Debug("running command", "command", cmd, "args", strings.Join(args, " "), "dir", workDir)
 
-	c.Dir = workDir
+	if slog.Default().Enabled(ctx, slog.LevelDebug) {
+		c.Stdout = os.Stderr
+		c.Stderr = os.Stderr
+		return c.Run()
+	}
+
+	out := &tailWriter{max: maxCapturedOutput}
+	c.Stdout = out
+	c.Stderr = out
+
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("command %q failed: %w; output:\n%s", cmd, err, out.String())
+	}
+
+	return nil
+}
+
+// tailWriter keeps the last max bytes written to it.
+type tailWriter struct {
+	max int
+	buf []byte
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	if len(w.buf) > w.max {
+		w.buf = w.buf[len(w.buf)-w.max:]
+	}
+
+	return len(p), nil
+}
 
-	return c.Run()
+func (w *tailWriter) String() string {
+	return string(w.buf)
 }
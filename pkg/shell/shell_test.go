@@ -0,0 +1,107 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shell
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestCommandWithEnv(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is required for this test")
+	}
+
+	tests := map[string]struct {
+		script       string
+		expectErr    bool
+		expectOutput string
+	}{
+		"successful command": {
+			script: "echo hello",
+		},
+		"failing command includes its output in the error": {
+			script:       "echo something went wrong >&2; exit 3",
+			expectErr:    true,
+			expectOutput: "something went wrong",
+		},
+		"environment is passed to the command": {
+			script:       `echo "value is $TEST_VALUE" >&2; exit 1`,
+			expectErr:    true,
+			expectOutput: "value is abc",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := CommandWithEnv(context.Background(), t.TempDir(), []string{"TEST_VALUE=abc"}, "sh", "-c", test.script)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if !test.expectErr {
+				return
+			}
+
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				t.Errorf("expected error to wrap an *exec.ExitError but got %T", errors.Unwrap(err))
+			}
+
+			if !strings.Contains(err.Error(), test.expectOutput) {
+				t.Errorf("expected error to contain %q but got %q", test.expectOutput, err.Error())
+			}
+		})
+	}
+}
+
+func TestCommandWithEnvDebug(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is required for this test")
+	}
+
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	err := CommandWithEnv(context.Background(), "", nil, "sh", "-c", "echo streamed output; exit 1")
+	if err == nil {
+		t.Fatal("expected an error from a failing command")
+	}
+
+	if strings.Contains(err.Error(), "streamed output") {
+		t.Errorf("expected output to be streamed rather than captured in debug mode, but got %q", err.Error())
+	}
+}
+
+func TestTailWriter(t *testing.T) {
+	w := &tailWriter{max: 5}
+
+	for _, s := range []string{"abc", "defg", "h"} {
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if w.String() != "defgh" {
+		t.Errorf("expected the last 5 bytes to be kept but got %q", w.String())
+	}
+}
@@ -18,12 +18,15 @@ package cosign
 
 import (
 	"context"
+	"os"
 
+	"github.com/cert-manager/release/pkg/retry"
 	"github.com/cert-manager/release/pkg/shell"
 	"github.com/cert-manager/release/pkg/sign"
 )
 
-// Sign calls out to cosign to sign a given container using the provided GCP key.
+// Sign calls out to cosign to sign a given container using the provided GCP key,
+// retrying on failure.
 func Sign(ctx context.Context, cosignPath string, containers []string, key sign.GCPKMSKey) error {
 	args := append([]string{
 		"sign",
@@ -31,10 +34,144 @@ func Sign(ctx context.Context, cosignPath string, containers []string, key sign.
 		key.CosignFormat(),
 	}, containers...)
 
-	return shell.Command(ctx, "", cosignPath, args...)
+	return retry.Do(ctx, func() error {
+		return shell.Command(ctx, "", cosignPath, args...)
+	})
+}
+
+// KeylessOptions configures keyless signing, in which cosign signs using a
+// short-lived certificate issued by Fulcio for an OIDC identity and records
+// the signature in the Rekor transparency log.
+type KeylessOptions struct {
+	// FulcioURL is the URL of the Fulcio instance to request certificates
+	// from. If empty, cosign's default public instance is used.
+	FulcioURL string
+
+	// RekorURL is the URL of the Rekor transparency log to upload signatures
+	// to. If empty, cosign's default public instance is used.
+	RekorURL string
+
+	// ServiceAccount, if set, is the email of a GCP service account to
+	// impersonate to get an OIDC identity token, such as the service account
+	// a GCB build runs as. If empty, cosign looks for an identity token using
+	// its usual ambient credential providers.
+	ServiceAccount string
+}
+
+// SignKeyless calls out to cosign to sign the given containers using keyless
+// signing, retrying on failure. Signatures are pushed alongside the
+// containers and uploaded to the Rekor transparency log.
+func SignKeyless(ctx context.Context, cosignPath string, containers []string, opts KeylessOptions) error {
+	var env []string
+	if opts.ServiceAccount != "" {
+		// cosign's Google identity token provider impersonates this service account
+		env = append(os.Environ(), "GOOGLE_SERVICE_ACCOUNT_NAME="+opts.ServiceAccount)
+	}
+
+	args := keylessSignArgs(containers, opts)
+
+	return retry.Do(ctx, func() error {
+		return shell.CommandWithEnv(ctx, "", env, cosignPath, args...)
+	})
+}
+
+// keylessSignArgs returns the arguments to "cosign" which sign containers
+// using keyless signing.
+func keylessSignArgs(containers []string, opts KeylessOptions) []string {
+	args := []string{
+		"sign",
+		// skip the interactive confirmation that signatures will be uploaded
+		// to a public transparency log
+		"--yes",
+		"--tlog-upload=true",
+	}
+
+	if opts.FulcioURL != "" {
+		args = append(args, "--fulcio-url", opts.FulcioURL)
+	}
+
+	if opts.RekorURL != "" {
+		args = append(args, "--rekor-url", opts.RekorURL)
+	}
+
+	return append(args, containers...)
 }
 
 // Version calls "cosign version", both for informational purposes and as a check that the binary exists
 func Version(ctx context.Context, cosignPath string) error {
 	return shell.Command(ctx, "", cosignPath, []string{"version"}...)
 }
+
+// SignBlob calls out to cosign to sign the file at payloadPath using the
+// provided GCP key, writing the signature to signaturePath.
+func SignBlob(ctx context.Context, cosignPath string, payloadPath, signaturePath string, key sign.GCPKMSKey) error {
+	args := []string{
+		"sign-blob",
+		"--key",
+		key.CosignFormat(),
+		"--output-signature",
+		signaturePath,
+		payloadPath,
+	}
+
+	return shell.Command(ctx, "", cosignPath, args...)
+}
+
+// VerifyBlob calls out to cosign to verify that the signature at
+// signaturePath is a valid signature of the file at payloadPath made using
+// the provided GCP key.
+func VerifyBlob(ctx context.Context, cosignPath string, payloadPath, signaturePath string, key sign.GCPKMSKey) error {
+	args := []string{
+		"verify-blob",
+		"--key",
+		key.CosignFormat(),
+		"--signature",
+		signaturePath,
+		payloadPath,
+	}
+
+	return shell.Command(ctx, "", cosignPath, args...)
+}
+
+// AttachSBOM calls out to cosign to attach the SBOM at sbomPath, of the given
+// cosign SBOM type (e.g. "spdx"), to a container image.
+func AttachSBOM(ctx context.Context, cosignPath string, container string, sbomPath string, sbomType string) error {
+	args := []string{
+		"attach",
+		"sbom",
+		"--sbom",
+		sbomPath,
+		"--type",
+		sbomType,
+		container,
+	}
+
+	return shell.Command(ctx, "", cosignPath, args...)
+}
+
+// Attest calls out to cosign to create an attestation for a container image,
+// signed using the provided GCP key. The attestation contains the predicate
+// at predicatePath, which is of the given predicate type. This is retried on
+// failure.
+func Attest(ctx context.Context, cosignPath string, container string, predicatePath string, predicateType string, key sign.GCPKMSKey) error {
+	args := []string{
+		"attest",
+		"--key",
+		key.CosignFormat(),
+		"--predicate",
+		predicatePath,
+		"--type",
+		predicateType,
+		container,
+	}
+
+	return retry.Do(ctx, func() error {
+		return shell.Command(ctx, "", cosignPath, args...)
+	})
+}
+
+// SignatureFileName returns the name of the detached signature created by
+// SignBlob for the file with the given name.
+func SignatureFileName(name string) string {
+	return name + ".sig"
+}
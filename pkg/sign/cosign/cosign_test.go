@@ -0,0 +1,54 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cosign
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeylessSignArgs(t *testing.T) {
+	tests := map[string]struct {
+		opts     KeylessOptions
+		expected []string
+	}{
+		"default public instances": {
+			opts:     KeylessOptions{},
+			expected: []string{"sign", "--yes", "--tlog-upload=true", "example.com/image:v1.2.3"},
+		},
+		"custom fulcio and rekor": {
+			opts: KeylessOptions{
+				FulcioURL: "https://fulcio.example.com",
+				RekorURL:  "https://rekor.example.com",
+			},
+			expected: []string{"sign", "--yes", "--tlog-upload=true", "--fulcio-url", "https://fulcio.example.com", "--rekor-url", "https://rekor.example.com", "example.com/image:v1.2.3"},
+		},
+		"service account doesn't change args": {
+			opts:     KeylessOptions{ServiceAccount: "builder@example.iam.gserviceaccount.com"},
+			expected: []string{"sign", "--yes", "--tlog-upload=true", "example.com/image:v1.2.3"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := keylessSignArgs([]string{"example.com/image:v1.2.3"}, test.opts)
+			if !reflect.DeepEqual(args, test.expected) {
+				t.Errorf("expected args %q but got %q", test.expected, args)
+			}
+		})
+	}
+}
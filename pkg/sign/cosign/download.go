@@ -0,0 +1,140 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cosign
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Downloader fetches the content at the given URL. It is used to abstract the
+// download of the cosign binary so that it can be tested.
+type Downloader func(ctx context.Context, url string) (io.ReadCloser, error)
+
+// HTTPDownloader is a Downloader which fetches content using the default HTTP
+// client.
+func HTTPDownloader(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected response code %d when downloading %q", resp.StatusCode, url)
+	}
+
+	return resp.Body, nil
+}
+
+// ReleaseURL returns the URL of the cosign binary published on GitHub for the
+// given version, OS and architecture.
+func ReleaseURL(version, osStr, arch string) string {
+	return fmt.Sprintf("https://github.com/sigstore/cosign/releases/download/%s/cosign-%s-%s", version, osStr, arch)
+}
+
+// DefaultCacheDir returns the directory that downloaded cosign binaries are
+// cached in by default.
+func DefaultCacheDir() string {
+	return filepath.Join(os.TempDir(), "cmrel-cosign")
+}
+
+// Install downloads the cosign binary for the given version and the current
+// OS and architecture into cacheDir, verifies that its SHA256 sum matches
+// expectedSHA256 and returns the path to the binary. If a binary with the
+// expected sum has already been downloaded into cacheDir, it is reused.
+func Install(ctx context.Context, download Downloader, cacheDir, version, expectedSHA256 string) (string, error) {
+	if expectedSHA256 == "" {
+		return "", fmt.Errorf("a sha256 sum must be provided to download cosign %s", version)
+	}
+
+	binaryPath := filepath.Join(cacheDir, fmt.Sprintf("cosign-%s-%s-%s", version, runtime.GOOS, runtime.GOARCH))
+
+	if sum, err := sha256SumFile(binaryPath); err == nil && sum == expectedSHA256 {
+		return binaryPath, nil
+	}
+
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create cosign cache directory: %w", err)
+	}
+
+	url := ReleaseURL(version, runtime.GOOS, runtime.GOARCH)
+
+	r, err := download(ctx, url)
+	if err != nil {
+		return "", fmt.Errorf("failed to download cosign from %q: %w", url, err)
+	}
+	defer r.Close()
+
+	f, err := os.CreateTemp(cacheDir, "cosign-download-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, hasher), r); err != nil {
+		return "", fmt.Errorf("failed to download cosign from %q: %w", url, err)
+	}
+
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	sum := hex.EncodeToString(hasher.Sum(nil))
+	if sum != expectedSHA256 {
+		return "", fmt.Errorf("downloaded cosign binary from %q has sha256 %q, expected %q", url, sum, expectedSHA256)
+	}
+
+	if err := os.Chmod(f.Name(), 0o755); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(f.Name(), binaryPath); err != nil {
+		return "", err
+	}
+
+	return binaryPath, nil
+}
+
+func sha256SumFile(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cosign
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInstall(t *testing.T) {
+	fixture := []byte("#!/bin/sh\necho cosign\n")
+	fixtureSum := sha256.Sum256(fixture)
+	fixtureSHA256 := hex.EncodeToString(fixtureSum[:])
+
+	tests := map[string]struct {
+		expectedSHA256 string
+		cached         []byte
+
+		expectErr       bool
+		expectDownloads int
+	}{
+		"matching checksum": {
+			expectedSHA256:  fixtureSHA256,
+			expectDownloads: 1,
+		},
+		"mismatched checksum": {
+			expectedSHA256:  "0000000000000000000000000000000000000000000000000000000000000000",
+			expectErr:       true,
+			expectDownloads: 1,
+		},
+		"missing checksum": {
+			expectedSHA256:  "",
+			expectErr:       true,
+			expectDownloads: 0,
+		},
+		"valid cached binary is reused": {
+			expectedSHA256:  fixtureSHA256,
+			cached:          fixture,
+			expectDownloads: 0,
+		},
+		"invalid cached binary is replaced": {
+			expectedSHA256:  fixtureSHA256,
+			cached:          []byte("corrupted"),
+			expectDownloads: 1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			cacheDir := t.TempDir()
+
+			downloads := 0
+			download := func(_ context.Context, url string) (io.ReadCloser, error) {
+				downloads++
+				return io.NopCloser(bytes.NewReader(fixture)), nil
+			}
+
+			if test.cached != nil {
+				// install once to find the cached binary path, then overwrite it
+				path, err := Install(context.TODO(), download, cacheDir, "v2.0.0", fixtureSHA256)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if err := os.WriteFile(path, test.cached, 0o755); err != nil {
+					t.Fatal(err)
+				}
+
+				downloads = 0
+			}
+
+			path, err := Install(context.TODO(), download, cacheDir, "v2.0.0", test.expectedSHA256)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if downloads != test.expectDownloads {
+				t.Errorf("expected %d downloads but got %d", test.expectDownloads, downloads)
+			}
+
+			if test.expectErr {
+				entries, err := os.ReadDir(cacheDir)
+				if err != nil && !os.IsNotExist(err) {
+					t.Fatal(err)
+				}
+
+				if len(entries) != 0 {
+					t.Errorf("expected no files to be left in the cache after a failed install, got %d", len(entries))
+				}
+
+				return
+			}
+
+			if filepath.Dir(path) != cacheDir {
+				t.Errorf("expected binary to be installed in %q but got %q", cacheDir, path)
+			}
+
+			installed, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(installed, fixture) {
+				t.Errorf("installed binary does not match the downloaded binary")
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if info.Mode().Perm()&0o100 == 0 {
+				t.Errorf("expected installed binary to be executable, got mode %o", info.Mode().Perm())
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cosign
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/cert-manager/release/pkg/shell"
+)
+
+// maxConcurrentVerifications bounds the number of signature verifications
+// run at once by VerifyWithAnyKey.
+const maxConcurrentVerifications = 8
+
+// Verifier returns an error unless ref has a valid signature made by key.
+type Verifier func(ctx context.Context, ref string, key string) error
+
+// CLIVerifier returns a Verifier which calls out to the cosign binary at
+// cosignPath. Keys can be in any format accepted by "cosign verify --key",
+// such as the path to a public key or a "gcpkms://" reference.
+func CLIVerifier(cosignPath string) Verifier {
+	return func(ctx context.Context, ref string, key string) error {
+		return shell.Command(ctx, "", cosignPath, "verify", "--key", key, ref)
+	}
+}
+
+// VerifyResult records which trusted key validated the signature of a
+// reference.
+type VerifyResult struct {
+	Ref string
+	Key string
+}
+
+// VerifyWithAnyKey verifies the signature of each of refs against every key
+// in trustedKeys concurrently. A reference passes if its signature validates
+// against at least one trusted key, which allows references signed before and
+// after a key rotation to be verified together.
+// The matching key is returned for each reference, in the same order as refs.
+// If more than one key validates a reference, the first in trustedKeys is
+// reported. An error is returned listing every reference which could not be
+// validated by any trusted key.
+func VerifyWithAnyKey(ctx context.Context, verify Verifier, refs []string, trustedKeys []string) ([]VerifyResult, error) {
+	if len(trustedKeys) == 0 {
+		return nil, fmt.Errorf("no trusted keys given to verify signatures against")
+	}
+
+	// matched[i][j] is true if refs[i] validates against trustedKeys[j]
+	matched := make([][]bool, len(refs))
+	for i := range matched {
+		matched[i] = make([]bool, len(trustedKeys))
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentVerifications)
+
+	for i, ref := range refs {
+		for j, key := range trustedKeys {
+			wg.Add(1)
+			go func(i, j int, ref, key string) {
+				defer wg.Done()
+
+				sem <- struct{}{}
+				defer func() { <-sem }()
+
+				// each goroutine writes to its own element, so no lock is needed
+				matched[i][j] = verify(ctx, ref, key) == nil
+			}(i, j, ref, key)
+		}
+	}
+
+	wg.Wait()
+
+	var results []VerifyResult
+	var unverified []string
+	for i, ref := range refs {
+		found := false
+		for j, key := range trustedKeys {
+			if matched[i][j] {
+				results = append(results, VerifyResult{Ref: ref, Key: key})
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			unverified = append(unverified, ref)
+		}
+	}
+
+	if len(unverified) > 0 {
+		return nil, fmt.Errorf("no trusted key validated the signatures of: %s", strings.Join(unverified, ", "))
+	}
+
+	return results, nil
+}
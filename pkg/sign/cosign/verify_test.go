@@ -0,0 +1,165 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cosign
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestVerifyWithAnyKey(t *testing.T) {
+	// signedBy maps each reference to the keys which have signed it
+	signedBy := map[string][]string{
+		"quay.io/jetstack/cert-manager-controller:v1.14.0": {"old-key"},
+		"quay.io/jetstack/cert-manager-webhook:v1.14.0":    {"old-key"},
+		"quay.io/jetstack/cert-manager-controller:v1.15.0": {"new-key"},
+		"quay.io/jetstack/cert-manager-webhook:v1.15.0":    {"old-key", "new-key"},
+		"quay.io/jetstack/cert-manager-cainjector:v1.15.0": {"untrusted-key"},
+	}
+
+	tests := map[string]struct {
+		refs        []string
+		trustedKeys []string
+		expected    []VerifyResult
+		expectErr   bool
+	}{
+		"references signed by different trusted keys": {
+			refs: []string{
+				"quay.io/jetstack/cert-manager-controller:v1.14.0",
+				"quay.io/jetstack/cert-manager-controller:v1.15.0",
+			},
+			trustedKeys: []string{"old-key", "new-key"},
+			expected: []VerifyResult{
+				{Ref: "quay.io/jetstack/cert-manager-controller:v1.14.0", Key: "old-key"},
+				{Ref: "quay.io/jetstack/cert-manager-controller:v1.15.0", Key: "new-key"},
+			},
+		},
+		"reference signed by several trusted keys reports the first": {
+			refs:        []string{"quay.io/jetstack/cert-manager-webhook:v1.15.0"},
+			trustedKeys: []string{"new-key", "old-key"},
+			expected: []VerifyResult{
+				{Ref: "quay.io/jetstack/cert-manager-webhook:v1.15.0", Key: "new-key"},
+			},
+		},
+		"reference signed only by a key which is no longer trusted": {
+			refs: []string{
+				"quay.io/jetstack/cert-manager-webhook:v1.14.0",
+				"quay.io/jetstack/cert-manager-controller:v1.15.0",
+			},
+			trustedKeys: []string{"new-key"},
+			expectErr:   true,
+		},
+		"reference signed by an untrusted key": {
+			refs:        []string{"quay.io/jetstack/cert-manager-cainjector:v1.15.0"},
+			trustedKeys: []string{"old-key", "new-key"},
+			expectErr:   true,
+		},
+		"unsigned reference": {
+			refs:        []string{"quay.io/jetstack/cert-manager-acmesolver:v1.15.0"},
+			trustedKeys: []string{"old-key", "new-key"},
+			expectErr:   true,
+		},
+		"no trusted keys": {
+			refs:      []string{"quay.io/jetstack/cert-manager-controller:v1.15.0"},
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var lock sync.Mutex
+			calls := map[string]bool{}
+
+			verify := func(_ context.Context, ref string, key string) error {
+				lock.Lock()
+				calls[ref+"@"+key] = true
+				lock.Unlock()
+
+				for _, signer := range signedBy[ref] {
+					if signer == key {
+						return nil
+					}
+				}
+
+				return fmt.Errorf("no signature for %q made by %q", ref, key)
+			}
+
+			results, err := VerifyWithAnyKey(context.Background(), verify, test.refs, test.trustedKeys)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr, err)
+			}
+
+			if !reflect.DeepEqual(results, test.expected) {
+				t.Errorf("unexpected results:\ngot=%+v\nexp=%+v", results, test.expected)
+			}
+
+			if test.expectErr {
+				return
+			}
+
+			// every reference should have been checked against every key
+			for _, ref := range test.refs {
+				for _, key := range test.trustedKeys {
+					if !calls[ref+"@"+key] {
+						t.Errorf("expected %q to be verified against key %q", ref, key)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyWithAnyKeyConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+
+	verify := func(context.Context, string, string) error {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+
+		for {
+			cur := atomic.LoadInt32(&maxInFlight)
+			if n <= cur || atomic.CompareAndSwapInt32(&maxInFlight, cur, n) {
+				break
+			}
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}
+
+	var refs []string
+	for i := 0; i < 10; i++ {
+		refs = append(refs, fmt.Sprintf("ref-%d", i))
+	}
+
+	if _, err := VerifyWithAnyKey(context.Background(), verify, refs, []string{"key-1", "key-2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if maxInFlight < 2 {
+		t.Errorf("expected verifications to run concurrently, but at most %d ran at once", maxInFlight)
+	}
+
+	if maxInFlight > maxConcurrentVerifications {
+		t.Errorf("expected at most %d concurrent verifications, but %d ran at once", maxConcurrentVerifications, maxInFlight)
+	}
+}
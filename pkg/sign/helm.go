@@ -17,50 +17,120 @@ limitations under the License.
 package sign
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"strings"
+	"os"
+	"path/filepath"
 
-	helmsign "helm.sh/helm/v3/pkg/provenance"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/clearsign"
+	"golang.org/x/crypto/openpgp/packet"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"sigs.k8s.io/yaml"
 )
 
 // HelmChart signs a given packaged helm chart (usually a .tgz file) using the given
 // KMS key, returning the human-readable signature bytes.
+// The chart's file name is recorded in the signature, so it must match the name
+// the chart is published under.
 func HelmChart(ctx context.Context, key GCPKMSKey, chartPath string) ([]byte, error) {
-	signatory, err := signatoryFromKMS(ctx, key)
+	chartData, err := os.ReadFile(chartPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create KMS signer: %w", err)
+		return nil, fmt.Errorf("failed to read %q: %w", chartPath, err)
 	}
 
-	signature, err := signatory.ClearSign(chartPath)
+	signature, err := HelmChartData(ctx, key, filepath.Base(chartPath), chartData)
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign %q: %w", chartPath, err)
 	}
 
-	// signatory.ClearSign doesn't necessarily return an error on failure; it can silently
-	// return an empty signature and no error, so we check signature after checking the
-	// error to confirm that a signature was actually made.
+	return signature, nil
+}
 
-	// This can happen when an incorrect KMS key type is used, which helm can't handle
-	if len(strings.TrimSpace(signature)) == 0 {
-		return nil, fmt.Errorf("got empty signature from helm signing process; this can indicate a KMS key of an incorrect type")
+// HelmChartData is like HelmChart, but signs a packaged helm chart held in
+// memory. fileName is the name the chart will be published under.
+func HelmChartData(ctx context.Context, key GCPKMSKey, fileName string, chartData []byte) ([]byte, error) {
+	entity, cfg, err := deriveEntity(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create KMS signer: %w", err)
 	}
 
-	return []byte(signature), nil
+	return helmProvenance(entity, cfg, fileName, chartData)
 }
 
-// signatoryFromKMS creates a Helm Signatory backed by a KMS key. The Signatory can then
-// be used to sign helm charts, but won't also be usable for validating signatures.
-func signatoryFromKMS(ctx context.Context, key GCPKMSKey) (*helmsign.Signatory, error) {
-	entity, _, err := deriveEntity(ctx, key)
+// helmProvenance returns the contents of a Helm provenance (.prov) file for
+// the given chart, clearsigned by entity. The result can be verified with
+// 'helm verify' given the entity's public key.
+func helmProvenance(entity *openpgp.Entity, cfg *packet.Config, fileName string, chartData []byte) ([]byte, error) {
+	message, err := helmProvenanceMessage(fileName, chartData)
 	if err != nil {
 		return nil, err
 	}
 
-	return &helmsign.Signatory{
-		Entity: entity,
-		// KeyRing is used for verification, which we don't support or care about
-		// in cmrel since we expect end-users to use the helm CLI for verification
-		KeyRing: nil,
-	}, nil
+	out := &bytes.Buffer{}
+	w, err := clearsign.Encode(out, entity.PrivateKey, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clearsign encoder: %w", err)
+	}
+
+	// w is deliberately not closed if writing fails, since closing it is what
+	// creates the signature and there's no point signing an incomplete message
+	if _, err := w.Write(message); err != nil {
+		return nil, fmt.Errorf("failed to write to clearsign encoder: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to create PGP signature: %w", err)
+	}
+
+	// a signer backed by an incorrect KMS key type can silently produce an
+	// empty signature, so check that a signature was actually made
+	if !bytes.Contains(out.Bytes(), []byte("-----BEGIN PGP SIGNATURE-----")) {
+		return nil, fmt.Errorf("got empty signature from signing process; this can indicate a KMS key of an incorrect type")
+	}
+
+	return out.Bytes(), nil
+}
+
+// helmProvenanceSums is the list of checksums of the signed chart in a Helm
+// provenance file.
+type helmProvenanceSums struct {
+	Files map[string]string `json:"files"`
+}
+
+// helmProvenanceMessage returns the message which is signed in a Helm
+// provenance file for the given chart: the chart's metadata followed by the
+// checksum of the packaged chart, in the same format as 'helm package --sign'.
+func helmProvenanceMessage(fileName string, chartData []byte) ([]byte, error) {
+	chart, err := loader.LoadArchive(bytes.NewReader(chartData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load helm chart: %w", err)
+	}
+
+	metadata, err := yaml.Marshal(chart.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode chart metadata: %w", err)
+	}
+
+	sum := sha256.Sum256(chartData)
+	sums, err := yaml.Marshal(helmProvenanceSums{
+		Files: map[string]string{
+			fileName: "sha256:" + hex.EncodeToString(sum[:]),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode chart checksums: %w", err)
+	}
+
+	// YAML's "---" document start marker isn't allowed in a PGP clearsigned
+	// message, so the metadata and checksums are separated by the "..."
+	// document end marker instead, as helm does
+	message := bytes.NewBuffer(metadata)
+	message.WriteString("\n...\n")
+	message.Write(sums)
+
+	return message.Bytes(), nil
 }
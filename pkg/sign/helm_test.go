@@ -0,0 +1,108 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sign
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/packet"
+	"helm.sh/helm/v3/pkg/provenance"
+)
+
+func TestHelmProvenanceVerifiesWithHelm(t *testing.T) {
+	cfg := &packet.Config{DefaultHash: crypto.SHA512, RSABits: 2048}
+
+	// a locally generated entity stands in for one backed by a KMS key
+	entity, err := openpgp.NewEntity("test", "", "test@example.com", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chartData := packageTestChart(t, "mychart", "1.2.3")
+
+	signature, err := helmProvenance(entity, cfg, "mychart-v1.2.3.tgz", chartData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	chartPath := filepath.Join(dir, "mychart-v1.2.3.tgz")
+	if err := os.WriteFile(chartPath, chartData, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(chartPath+".prov", signature, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	signatory := &provenance.Signatory{KeyRing: openpgp.EntityList{entity}}
+	verification, err := signatory.Verify(chartPath, chartPath+".prov")
+	if err != nil {
+		t.Fatalf("expected provenance file to be verified by helm but got: %v", err)
+	}
+
+	if verification.FileName != "mychart-v1.2.3.tgz" {
+		t.Errorf("unexpected file name %q in verified provenance", verification.FileName)
+	}
+
+	if err := os.WriteFile(chartPath, packageTestChart(t, "mychart", "1.2.4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := signatory.Verify(chartPath, chartPath+".prov"); err == nil {
+		t.Errorf("expected provenance file to fail verification for a different chart")
+	}
+}
+
+// packageTestChart returns a minimal packaged helm chart with the given name
+// and version.
+func packageTestChart(t *testing.T, name string, version string) []byte {
+	chartYAML := []byte("apiVersion: v2\nname: " + name + "\nversion: " + version + "\n")
+
+	tarData := &bytes.Buffer{}
+	tw := tar.NewWriter(tarData)
+	if err := tw.WriteHeader(&tar.Header{Name: name + "/Chart.yaml", Size: int64(len(chartYAML)), Mode: 0o644}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tw.Write(chartYAML); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	gzw := gzip.NewWriter(buf)
+	if _, err := gzw.Write(tarData.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
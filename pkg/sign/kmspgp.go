@@ -26,10 +26,8 @@ import (
 	"golang.org/x/crypto/openpgp"
 	"golang.org/x/crypto/openpgp/armor"
 	"golang.org/x/crypto/openpgp/packet"
-	"golang.org/x/oauth2/google"
-	"google.golang.org/api/cloudkms/v1"
-	"google.golang.org/api/option"
 
+	"github.com/cert-manager/release/pkg/gcp"
 	"github.com/cert-manager/release/pkg/sign/internal/kmssigner"
 )
 
@@ -130,12 +128,7 @@ func deriveEntity(ctx context.Context, key GCPKMSKey) (*openpgp.Entity, *packet.
 		DefaultHash: crypto.SHA512,
 	}
 
-	oauthClient, err := google.DefaultClient(ctx, cloudkms.CloudPlatformScope)
-	if err != nil {
-		return nil, nil, fmt.Errorf("could not create GCP OAuth2 client: %w", err)
-	}
-
-	svc, err := cloudkms.NewService(ctx, option.WithHTTPClient(oauthClient))
+	svc, err := gcp.NewKMSService(ctx)
 	if err != nil {
 		return nil, nil, fmt.Errorf("could not create GCP KMS client: %w", err)
 	}
@@ -23,64 +23,55 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 
-	tarball "github.com/cert-manager/release/pkg/release/tar"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/packet"
+	"helm.sh/helm/v3/pkg/chart/loader"
 )
 
-const manifestLocation = "deploy/chart/cert-manager.tgz"
-
 // CertManagerManifests takes a path to a cert-manager-manifests.tar.gz file, loads it into
 // memory and signs anything inside the archive which is signable; currently,
-// the helm chart located at "deploy/chart/cert-manager.tgz" is signed, and a
-// signature "deploy/chart/cert-manager.tgz.prov" will be added.
+// every packaged helm chart (any file with a ".tgz" extension, such as
+// "deploy/chart/cert-manager.tgz") is signed, and a signature with a ".prov"
+// suffix (such as "deploy/chart/cert-manager.tgz.prov") is added alongside it.
 // The cert-manifests.tar.gz file is changed in-place.
 func CertManagerManifests(ctx context.Context, key GCPKMSKey, path string, releaseVersion string) error {
-	// 1. Create temp dir for chart archive to be extracted to
-	// (Helm signing requires a filename, not a reader, so we have to write to disk here)
-	tmpDest, err := os.MkdirTemp("", "cmrel-extracted-manifests-")
-	if err != nil {
-		return fmt.Errorf("failed to create temporary directory for extracting manifests: %w", err)
+	// 0. Check the archive has the expected structure before doing any signing work
+	if err := ValidateManifestArchive(path); err != nil {
+		return err
 	}
 
-	defer func() {
-		err := os.RemoveAll(tmpDest)
-		if err != nil {
-			log.Printf("failed to remove temporary directory %q: %v", tmpDest, err)
-		}
-	}()
-
-	// 2. Read + ungzip the manifests archive into memory
+	// 1. Read + ungzip the manifests archive into memory
 	tarData, originalMode, err := ungzipManifestArchive(path)
 	if err != nil {
 		return err
 	}
-	chartFileData, err := tarball.ReadSingleFile(manifestLocation, bytes.NewReader(tarData))
+
+	charts, err := findCharts(bytes.NewReader(tarData))
 	if err != nil {
-		return fmt.Errorf("failed to read %q from %q: %w", manifestLocation, path, err)
+		return fmt.Errorf("failed to find helm charts in %q: %w", path, err)
 	}
 
-	// The name of the helm chart on-disk is set to include the given releaseVersion, because the name of the chart
-	// is encoded into the signature we generate and needs to match. In other words, the chart's name when signed must be the same name it appears as
-	// when ultimately published to the helm repo.
-	versionedChartFileName := fmt.Sprintf("cert-manager-%s.tgz", releaseVersion)
-
-	// Write file to temp location (for helm's sake)
-	chartPath := filepath.Join(tmpDest, versionedChartFileName)
-	err = os.WriteFile(chartPath, chartFileData, 0o644)
+	entity, cfg, err := deriveEntity(ctx, key)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to create KMS signer: %w", err)
 	}
 
-	// 3. Sign chart
-	signatureBytes, err := HelmChart(ctx, key, chartPath)
-	if err != nil {
-		return fmt.Errorf("failed to sign helm chart at %q: %w", chartPath, err)
+	// 2. Sign each chart
+	var signatures []archiveFile
+	for _, chart := range charts {
+		signature, err := signManifestChart(entity, cfg, chart, releaseVersion)
+		if err != nil {
+			return fmt.Errorf("failed to sign helm chart %q: %w", chart.name, err)
+		}
+
+		signatures = append(signatures, archiveFile{name: chart.name + ".prov", data: signature})
 	}
 
-	// 4. Chmod the archive if needed so that it's writable, with a defer to reset its permissions
+	// 3. Chmod the archive if needed so that it's writable, with a defer to reset its permissions
 	// after we're done. This is required because bazel forces the mode to 0o555.
 	modeResetFunc, err := ensureWritable(path, originalMode)
 	if err != nil {
@@ -89,18 +80,16 @@ func CertManagerManifests(ctx context.Context, key GCPKMSKey, path string, relea
 
 	defer modeResetFunc()
 
-	// 5. Append file to original tar archive
+	// 4. Append files to original tar archive
 
 	// The tar spec requires that the end include two full empty blocks, so any valid tar file
 	// will have exactly 512 * 2 bytes of empty space at the end.
-	// We copy until the beginning of these empty blocks, then add our new header and our file,
+	// We copy until the beginning of these empty blocks, then add our new headers and our files,
 	// and then close the tar.Writer to complete the tar archive.
 	// See https://stackoverflow.com/a/18330903/1615417 for more details
 
 	// NB: We can't just open the file as O_APPEND and seek back 1024 bytes because it's gzipped
-	provPath := manifestLocation + ".prov"
-
-	newTar, err := signatureToTar(signatureBytes, provPath, 0o644)
+	newTar, err := filesToTar(signatures, 0o644)
 	if err != nil {
 		return err
 	}
@@ -110,7 +99,7 @@ func CertManagerManifests(ctx context.Context, key GCPKMSKey, path string, relea
 
 	_, err = gzipWriter.Write(append(tarData[:len(tarData)-1024], newTar...))
 	if err != nil {
-		return fmt.Errorf("failed to compress tar output with helm signature: %w", err)
+		return fmt.Errorf("failed to compress tar output with helm signatures: %w", err)
 	}
 
 	err = gzipWriter.Close()
@@ -123,7 +112,97 @@ func CertManagerManifests(ctx context.Context, key GCPKMSKey, path string, relea
 		return fmt.Errorf("failed to write output tar file: %w", err)
 	}
 
-	log.Printf("successfully signed helm chart %q and added signature to %q as %q", chartPath, path, provPath)
+	for _, signature := range signatures {
+		slog.Info("successfully signed helm chart and added signature", "path", path, "signature", signature.name)
+	}
+
+	return nil
+}
+
+// archiveFile is a regular file read from or written to a tar archive.
+type archiveFile struct {
+	name string
+	data []byte
+}
+
+// findCharts returns every packaged helm chart in the given tar archive, which
+// are the regular files with a ".tgz" extension, in the order they appear.
+func findCharts(r io.Reader) ([]archiveFile, error) {
+	var charts []archiveFile
+
+	tarReader := tar.NewReader(r)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if header.Typeflag != tar.TypeReg || filepath.Ext(header.Name) != ".tgz" {
+			continue
+		}
+
+		data, err := io.ReadAll(tarReader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %q: %w", header.Name, err)
+		}
+
+		charts = append(charts, archiveFile{name: header.Name, data: data})
+	}
+
+	return charts, nil
+}
+
+// signManifestChart returns a provenance file for the given chart, signed by
+// entity.
+// The name of the helm chart is set to include the given releaseVersion, because the name of the chart
+// is encoded into the signature we generate and needs to match. In other words, the chart's name when signed must be the same name it appears as
+// when ultimately published to the helm repo.
+func signManifestChart(entity *openpgp.Entity, cfg *packet.Config, chart archiveFile, releaseVersion string) ([]byte, error) {
+	c, err := loader.LoadArchive(bytes.NewReader(chart.data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load helm chart: %w", err)
+	}
+
+	versionedChartFileName := fmt.Sprintf("%s-%s.tgz", c.Name(), releaseVersion)
+
+	return helmProvenance(entity, cfg, versionedChartFileName, chart.data)
+}
+
+// ValidateManifestArchive checks that the file at path is a gzipped tar
+// archive which contains at least one packaged Helm chart, as expected by
+// CertManagerManifests, returning an error describing the problem if not.
+func ValidateManifestArchive(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open packaged manifest file %q: %w", path, err)
+	}
+
+	defer f.Close()
+
+	gzipReader, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("manifests archive %q is not a gzip file: %w", path, err)
+	}
+
+	defer gzipReader.Close()
+
+	charts, err := findCharts(gzipReader)
+	if err != nil {
+		return fmt.Errorf("manifests archive %q does not contain a valid tar archive: %w", path, err)
+	}
+
+	// read any remaining data so that the gzip checksum is verified
+	if _, err := io.Copy(io.Discard, gzipReader); err != nil {
+		return fmt.Errorf("manifests archive %q is not a valid gzip file: %w", path, err)
+	}
+
+	if len(charts) == 0 {
+		return fmt.Errorf("manifests archive %q does not contain a Helm chart (a file with a \".tgz\" extension)", path)
+	}
 
 	return nil
 }
@@ -179,28 +258,32 @@ func ungzipManifestArchive(path string) ([]byte, os.FileMode, error) {
 	return tarData, mode, nil
 }
 
-func signatureToTar(signatureBytes []byte, filename string, mode os.FileMode) ([]byte, error) {
+// filesToTar returns a tar archive containing the given files, each with the
+// given mode.
+func filesToTar(files []archiveFile, mode os.FileMode) ([]byte, error) {
 	newTar := &bytes.Buffer{}
 	tarWriter := tar.NewWriter(newTar)
 
-	err := tarWriter.WriteHeader(&tar.Header{
-		Name: filename,
-		Size: int64(len(signatureBytes)),
-		Mode: int64(mode),
-	})
+	for _, f := range files {
+		err := tarWriter.WriteHeader(&tar.Header{
+			Name: f.name,
+			Size: int64(len(f.data)),
+			Mode: int64(mode),
+		})
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to write tar header for new prov file %q: %w", filename, err)
-	}
+		if err != nil {
+			return nil, fmt.Errorf("failed to write tar header for new file %q: %w", f.name, err)
+		}
 
-	_, err = tarWriter.Write(signatureBytes)
-	if err != nil {
-		return nil, fmt.Errorf("failed to add signature file %q to tar archive: %w", filename, err)
+		_, err = tarWriter.Write(f.data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add file %q to tar archive: %w", f.name, err)
+		}
 	}
 
-	err = tarWriter.Close()
+	err := tarWriter.Close()
 	if err != nil {
-		return nil, fmt.Errorf("failed to finish writing and close tar archive data for %q: %w", filename, err)
+		return nil, fmt.Errorf("failed to finish writing and close tar archive: %w", err)
 	}
 
 	return newTar.Bytes(), nil
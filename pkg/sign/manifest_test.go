@@ -17,7 +17,12 @@ limitations under the License.
 package sign
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -50,3 +55,124 @@ func TestSetOwnerWritable(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateManifestArchive(t *testing.T) {
+	type tarFile struct {
+		name     string
+		typeflag byte
+	}
+
+	makeTar := func(t *testing.T, files ...tarFile) []byte {
+		buf := &bytes.Buffer{}
+		tw := tar.NewWriter(buf)
+		for _, f := range files {
+			content := []byte("content")
+			hdr := &tar.Header{Name: f.name, Typeflag: f.typeflag, Mode: 0o644}
+			if f.typeflag == tar.TypeReg {
+				hdr.Size = int64(len(content))
+			}
+
+			if err := tw.WriteHeader(hdr); err != nil {
+				t.Fatal(err)
+			}
+
+			if f.typeflag == tar.TypeReg {
+				if _, err := tw.Write(content); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		return buf.Bytes()
+	}
+
+	gzipData := func(t *testing.T, data []byte) []byte {
+		buf := &bytes.Buffer{}
+		gzw := gzip.NewWriter(buf)
+		if _, err := gzw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := gzw.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		return buf.Bytes()
+	}
+
+	tests := map[string]struct {
+		content   func(t *testing.T) []byte
+		expectErr string
+	}{
+		"valid manifests archive": {
+			content: func(t *testing.T) []byte {
+				return gzipData(t, makeTar(t,
+					tarFile{name: "deploy/manifests/cert-manager.yaml", typeflag: tar.TypeReg},
+					tarFile{name: "deploy/chart/cert-manager.tgz", typeflag: tar.TypeReg},
+				))
+			},
+		},
+		"manifests archive with multiple charts": {
+			content: func(t *testing.T) []byte {
+				return gzipData(t, makeTar(t,
+					tarFile{name: "deploy/manifests/cert-manager.yaml", typeflag: tar.TypeReg},
+					tarFile{name: "deploy/chart/cert-manager.tgz", typeflag: tar.TypeReg},
+					tarFile{name: "deploy/chart/cert-manager-approver-policy.tgz", typeflag: tar.TypeReg},
+				))
+			},
+		},
+		"not gzip": {
+			content: func(t *testing.T) []byte {
+				return makeTar(t, tarFile{name: "deploy/chart/cert-manager.tgz", typeflag: tar.TypeReg})
+			},
+			expectErr: "is not a gzip file",
+		},
+		"gzip but not tar": {
+			content: func(t *testing.T) []byte {
+				return gzipData(t, bytes.Repeat([]byte("not a tar archive\n"), 100))
+			},
+			expectErr: "does not contain a valid tar archive",
+		},
+		"truncated gzip": {
+			content: func(t *testing.T) []byte {
+				data := gzipData(t, makeTar(t, tarFile{name: "deploy/chart/cert-manager.tgz", typeflag: tar.TypeReg}))
+				return data[:len(data)-8]
+			},
+			expectErr: "manifests archive",
+		},
+		"tar missing the chart": {
+			content: func(t *testing.T) []byte {
+				return gzipData(t, makeTar(t, tarFile{name: "deploy/manifests/cert-manager.yaml", typeflag: tar.TypeReg}))
+			},
+			expectErr: "does not contain a Helm chart",
+		},
+		"chart is a directory": {
+			content: func(t *testing.T) []byte {
+				return gzipData(t, makeTar(t, tarFile{name: "deploy/chart/cert-manager.tgz", typeflag: tar.TypeDir}))
+			},
+			expectErr: "does not contain a Helm chart",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cert-manager-manifests.tar.gz")
+			if err := os.WriteFile(path, test.content(t), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := ValidateManifestArchive(path)
+			if (err != nil) != (test.expectErr != "") {
+				t.Fatalf("expectErr=%v but got err=%v", test.expectErr != "", err)
+			}
+
+			if err != nil && !strings.Contains(err.Error(), test.expectErr) {
+				t.Errorf("expected error to contain %q but got %q", test.expectErr, err)
+			}
+		})
+	}
+}
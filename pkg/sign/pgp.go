@@ -0,0 +1,78 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sign
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+// PGPDetachSign creates an ASCII-armored, detached PGP signature of payload
+// using the given KMS key.
+func PGPDetachSign(ctx context.Context, key GCPKMSKey, payload []byte) ([]byte, error) {
+	entity, cfg, err := deriveEntity(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get an entity from key %q: %w", key, err)
+	}
+
+	return pgpDetachSign(entity, cfg, payload)
+}
+
+// PGPVerifyDetached checks that signature is a valid ASCII-armored, detached
+// PGP signature of payload made by the given KMS key.
+func PGPVerifyDetached(ctx context.Context, key GCPKMSKey, payload []byte, signature []byte) error {
+	entity, _, err := deriveEntity(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to get an entity from key %q: %w", key, err)
+	}
+
+	return pgpVerifyDetached(entity, payload, signature)
+}
+
+func pgpDetachSign(entity *openpgp.Entity, cfg *packet.Config, payload []byte) ([]byte, error) {
+	out := &bytes.Buffer{}
+	if err := openpgp.ArmoredDetachSign(out, entity, bytes.NewReader(payload), cfg); err != nil {
+		return nil, fmt.Errorf("failed to create PGP signature: %w", err)
+	}
+
+	// as with helm signing, a signer backed by an incorrect KMS key type
+	// can silently produce an empty signature
+	if out.Len() == 0 {
+		return nil, fmt.Errorf("got empty PGP signature; this can indicate a KMS key of an incorrect type")
+	}
+
+	return out.Bytes(), nil
+}
+
+func pgpVerifyDetached(entity *openpgp.Entity, payload []byte, signature []byte) error {
+	keyring := openpgp.EntityList{entity}
+
+	signer, err := openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(payload), bytes.NewReader(signature))
+	if err != nil {
+		return fmt.Errorf("failed to verify PGP signature: %w", err)
+	}
+
+	if signer.PrimaryKey.KeyId != entity.PrimaryKey.KeyId {
+		return fmt.Errorf("PGP signature was made by key %X, expected %X", signer.PrimaryKey.KeyId, entity.PrimaryKey.KeyId)
+	}
+
+	return nil
+}
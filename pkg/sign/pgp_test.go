@@ -0,0 +1,59 @@
+/*
+Copyright 2021 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sign
+
+import (
+	"crypto"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+func TestPGPDetachSignRoundTrip(t *testing.T) {
+	cfg := &packet.Config{DefaultHash: crypto.SHA512, RSABits: 2048}
+
+	// a locally generated entity stands in for one backed by a KMS key
+	entity, err := openpgp.NewEntity("test", "", "test@example.com", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := openpgp.NewEntity("other", "", "other@example.com", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := []byte("cmrel signing self-test")
+
+	signature, err := pgpDetachSign(entity, cfg, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pgpVerifyDetached(entity, payload, signature); err != nil {
+		t.Errorf("expected signature to verify but got: %v", err)
+	}
+
+	if err := pgpVerifyDetached(entity, []byte("tampered payload"), signature); err == nil {
+		t.Errorf("expected signature of a tampered payload to fail verification")
+	}
+
+	if err := pgpVerifyDetached(other, payload, signature); err == nil {
+		t.Errorf("expected signature to fail verification with a different key")
+	}
+}
@@ -0,0 +1,17 @@
+module example.com/startupapicheck
+
+go 1.21.3
+
+// The toolchain here is intentionally set when the core module has none,
+// denieddependency is on the deny-list and someotherdependency is older than
+// the minimum version. These should all error.
+
+toolchain go1.21.4
+
+replace example.com/core => ../../
+
+require (
+	example.com/core v0.0.0-00010101000000-000000000000
+	example.org/denieddependency v1.0.0
+	example.org/someotherdependency v1.5.0
+)